@@ -1,20 +1,480 @@
 // Package api defines shared data structures between the SpaceNet server and client
 package api
 
+import "time"
+
 // ClaimResponse represents the JSON response for a claim
 type ClaimResponse struct {
-	Name       string `json:"name,omitempty"`
-	Difficulty uint8  `json:"difficulty,omitempty"`
+	Name          string     `json:"name,omitempty"`
+	Difficulty    uint8      `json:"difficulty,omitempty"`    // Proof of work difficulty to take the address over
+	ClaimedAt     *time.Time `json:"claimedAt,omitempty"`     // When the current claimant took the address
+	TakeoverCount int        `json:"takeoverCount,omitempty"` // Times the address has changed hands
+	Fortification int        `json:"fortification,omitempty"` // Defense level the owner bought, included in the difficulty
+}
+
+// AdminClaimResponse describes the claim on an address to operators,
+// including where it came from
+type AdminClaimResponse struct {
+	ClaimResponse
+	IP     string       `json:"ip"`
+	Source *ClaimSource `json:"source,omitempty"` // Omitted if the source was not collected
+}
+
+// ClaimSource describes where the latest claim on an address came from
+type ClaimSource struct {
+	Channel       string `json:"channel"`                 // How the claim arrived: "http", "session", "batch", "udp" or "bot"
+	RemoteIP      string `json:"remoteIP,omitempty"`      // Address of the client that sent the claim
+	UserAgent     string `json:"userAgent,omitempty"`     // User-Agent of an HTTP client
+	Client        string `json:"client,omitempty"`        // Product of the user agent, such as "spacenet-tui"
+	ClientVersion string `json:"clientVersion,omitempty"` // Version of the product
+}
+
+// ClientsResponse counts the current claims by the client that made them
+type ClientsResponse struct {
+	Clients []ClientUsage `json:"clients"` // Most claims first
+	Unknown int           `json:"unknown"` // Claims whose source was not collected
+}
+
+// ClientUsage is the number of current claims made by one version of a client
+type ClientUsage struct {
+	Channel       string `json:"channel"`
+	Client        string `json:"client,omitempty"`
+	ClientVersion string `json:"clientVersion,omitempty"`
+	Claims        int    `json:"claims"`
+}
+
+// AnomalyEvent is a suspicious pattern of claims, and what was done about it
+type AnomalyEvent struct {
+	Detector  string    `json:"detector"` // "spread", "hashRate" or a custom detector
+	Claimant  string    `json:"claimant"`
+	RemoteIP  string    `json:"remoteIP,omitempty"` // Address of the client that made the claim completing the pattern
+	Detail    string    `json:"detail"`
+	Action    string    `json:"action"` // "log", "difficulty" or "quarantine"
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FlaggedClaimant is a claimant penalized or quarantined after an anomaly
+type FlaggedClaimant struct {
+	Claimant    string     `json:"claimant"`
+	Quarantined bool       `json:"quarantined,omitempty"` // Claims are refused until an admin releases the claimant
+	Penalty     int        `json:"penalty,omitempty"`     // Difficulty added to the claimant's claims
+	Since       time.Time  `json:"since"`
+	Until       *time.Time `json:"until,omitempty"` // When the penalty lifts
+}
+
+// AnomaliesResponse lists the recent anomalies and the claimants flagged for review
+type AnomaliesResponse struct {
+	Events  []AnomalyEvent    `json:"events"` // Newest first
+	Flagged []FlaggedClaimant `json:"flagged"`
 }
 
 // SubnetResponse represents the JSON response for subnet statistics
 type SubnetResponse struct {
+	Owner        string          `json:"owner,omitempty"`
+	Percentage   float64         `json:"percentage,omitempty"`
+	AllClaimants []ClaimantShare `json:"allClaimants,omitempty"`
+	Artifact     bool            `json:"artifact,omitempty"` // Subnet smaller than a /48 contains an artifact
+	Granted      bool            `json:"granted,omitempty"`  // Owner was granted the subnet rather than claiming it
+	Name         string          `json:"name,omitempty"`     // Sector name set by the operator, overriding the generated name
+}
+
+// ClaimantShare represents a single claimant's share of a subnet
+type ClaimantShare struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+}
+
+// SubnetListEntry represents a single claimed subnet in a subnet listing
+type SubnetListEntry struct {
+	Subnet     string  `json:"subnet"`
 	Owner      string  `json:"owner,omitempty"`
 	Percentage float64 `json:"percentage,omitempty"`
+	Claims     int64   `json:"claims,omitempty"`   // Addresses claimed in the subnet
+	Artifact   bool    `json:"artifact,omitempty"` // Subnet smaller than a /48 contains an artifact
+	Name       string  `json:"name,omitempty"`     // Sector name set by the operator, overriding the generated name
+}
+
+// OwnersResponse summarizes who owns the subnets of a level within a parent subnet
+type OwnersResponse struct {
+	Parent    string         `json:"parent"`    // CIDR notation
+	Level     int            `json:"level"`     // Prefix length of the subnets counted
+	Subnets   int            `json:"subnets"`   // Subnets of the level with a claim
+	Contested int            `json:"contested"` // Subnets with claims but no owner
+	Owners    []OwnerSummary `json:"owners"`    // Most subnets owned first
+}
+
+// OwnerSummary is how much of a parent subnet one claimant holds
+type OwnerSummary struct {
+	Name      string `json:"name"`
+	Subnets   int    `json:"subnets"`   // Subnets of the level the claimant owns
+	Addresses string `json:"addresses"` // Addresses in those subnets, a decimal string since they can exceed 64-bit integers
+	Claims    int64  `json:"claims"`    // Addresses the claimant claimed in the parent, owned subnets or not
+}
+
+// Sector is a subnet named by the operator, overriding its generated name
+type Sector struct {
+	Subnet string `json:"subnet"` // CIDR notation
+	Name   string `json:"name"`
+}
+
+// ResolveResponse represents the subnets that carry a generated name.
+// Names are not unique, so a name can resolve to several subnets.
+type ResolveResponse struct {
+	Name    string   `json:"name"`
+	Subnets []string `json:"subnets"` // CIDR notation, widest first
+}
+
+// HealthResponse reports the health of the server and the checks behind it
+type HealthResponse struct {
+	Status  string         `json:"status"` // "ok", or "unavailable" if a check failed
+	Checks  []HealthCheck  `json:"checks,omitempty"`
+	Startup *StartupStatus `json:"startup,omitempty"` // Progress of loading the claims, until the server is ready
+}
+
+// StartupStatus reports how far the server is through loading its claims on startup
+type StartupStatus struct {
+	Phase     string    `json:"phase"`           // What is being loaded: "database", "journal", "snapshot", "tree", "names", or "done"
+	Loaded    int       `json:"loaded"`          // Entries loaded so far in this phase
+	Total     int       `json:"total,omitempty"` // Entries to load in this phase, if known
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// HealthCheck is the result of checking one dependency
+type HealthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"` // Why the check failed
+}
+
+// FederationSummary is the dominance of the subnets a server is
+// authoritative for, shared with the other servers of its federation
+type FederationSummary struct {
+	Server       string            `json:"server"`
+	Prefixes     []string          `json:"prefixes"`     // Prefixes the server is authoritative for
+	PrefixLength int               `json:"prefixLength"` // Prefix length the subnets are listed at
+	GeneratedAt  time.Time         `json:"generatedAt"`
+	Subnets      []SubnetListEntry `json:"subnets"`
+}
+
+// FederatedSubnet is a claimed subnet in the combined universe of a federation
+type FederatedSubnet struct {
+	Subnet     string  `json:"subnet"`
+	Owner      string  `json:"owner,omitempty"`
+	Percentage float64 `json:"percentage,omitempty"`
+	Server     string  `json:"server"` // Server authoritative for the subnet
+}
+
+// FederationPeerStatus describes a federation peer and its last fetched summary
+type FederationPeerStatus struct {
+	Name     string     `json:"name"`
+	URL      string     `json:"url"`
+	Prefixes []string   `json:"prefixes"`
+	Subnets  int        `json:"subnets"`            // Claimed subnets in the last summary
+	LastSync *time.Time `json:"lastSync,omitempty"` // When a summary was last fetched
+	Error    string     `json:"error,omitempty"`    // Why the last fetch failed
+}
+
+// ErrorResponse is the body of error responses that explain why a request failed
+type ErrorResponse struct {
+	Code      string `json:"code"`                // Machine-readable kind of failure, such as not_found
+	Message   string `json:"message"`             // Human-readable explanation
+	RequestID string `json:"requestId,omitempty"` // ID of the request, also in the X-Request-ID header
+	Error     string `json:"error"`               // Same as Message, for clients predating the envelope
 }
 
 // ClaimRequest represents a request to claim an IPv6 address
 type ClaimRequest struct {
+	Nonce   string `json:"nonce"`
+	Name    string `json:"name"`
+	Session string `json:"session,omitempty"` // Session of the session challenge solved, if any
+}
+
+// BatchClaimRequest represents a request to claim several addresses at once, all or none
+type BatchClaimRequest struct {
+	Claims []BatchClaimItem `json:"claims"`
+}
+
+// BatchClaimItem is one claim in a batch, with the proof of work for its address
+type BatchClaimItem struct {
+	IP    string `json:"ip"`
 	Nonce string `json:"nonce"`
 	Name  string `json:"name"`
 }
+
+// BatchClaimResponse represents the result of a batch claim
+type BatchClaimResponse struct {
+	Claimed int `json:"claimed"`
+}
+
+// SubnetActivityResponse summarizes recent claims in a subnet over a rolling window
+type SubnetActivityResponse struct {
+	Subnet          string           `json:"subnet"`
+	WindowHours     int              `json:"windowHours"`
+	Claims          int              `json:"claims"`    // Claims that changed the owner of an address
+	Takeovers       int              `json:"takeovers"` // Claims that took an address from another claimant
+	UniqueClaimants int              `json:"uniqueClaimants"`
+	ClaimsPerHour   float64          `json:"claimsPerHour"`
+	Hourly          []ActivityBucket `json:"hourly"`              // Oldest hour first, the last is the current hour
+	Truncated       bool             `json:"truncated,omitempty"` // Server forgot the oldest claims in the window
+}
+
+// ClaimHistoryResponse lists the recent claims on an address, newest first
+type ClaimHistoryResponse struct {
+	IP        string              `json:"ip"`
+	Claims    []ClaimHistoryEntry `json:"claims"`
+	Truncated bool                `json:"truncated,omitempty"` // Server forgot older claims on the address
+}
+
+// ClaimHistoryEntry is a claim that changed the owner of an address
+type ClaimHistoryEntry struct {
+	Claimant         string    `json:"claimant"`
+	PreviousClaimant string    `json:"previousClaimant,omitempty"`
+	ClaimedAt        time.Time `json:"claimedAt"`
+	Difficulty       uint8     `json:"difficulty,omitempty"` // Proof of work difficulty of the address when claimed, zero if unknown
+}
+
+// ViewportSubscription declares the rows of a table a client is watching,
+// sent over the /api/v1/subscribe WebSocket. The rows are the subnets of a
+// prefix length within a parent subnet, numbered in address order. Sending
+// another subscription replaces the previous one.
+type ViewportSubscription struct {
+	Seq    int    `json:"seq"`    // Echoed in the updates of this subscription
+	Parent string `json:"parent"` // Subnet holding the rows, such as 2001:db8::/32
+	Prefix int    `json:"prefix"` // Prefix length of the rows, such as 48
+	Start  int    `json:"start"`  // First row watched
+	End    int    `json:"end"`    // Row after the last one watched
+}
+
+// ViewportRow is the claim on one row of a viewport
+type ViewportRow struct {
+	Row    int            `json:"row"`
+	Subnet string         `json:"subnet"`
+	Claim  SubnetResponse `json:"claim"`
+}
+
+// ViewportUpdate carries the claims on the rows of a viewport subscription:
+// every row when the subscription starts, then the rows whose claims change
+type ViewportUpdate struct {
+	Seq     int           `json:"seq"`
+	Initial bool          `json:"initial,omitempty"` // Rows hold the whole viewport
+	Rows    []ViewportRow `json:"rows,omitempty"`
+	Error   string        `json:"error,omitempty"` // Why the subscription was refused
+}
+
+// ActivityBucket counts the claims in a subnet during one hour
+type ActivityBucket struct {
+	Start     time.Time `json:"start"`
+	Claims    int       `json:"claims"`
+	Takeovers int       `json:"takeovers"`
+}
+
+// AddressDifficulty represents the required difficulty for a single address
+type AddressDifficulty struct {
+	Address    string `json:"address"`
+	Difficulty uint8  `json:"difficulty"`
+}
+
+// DifficultyBucket represents the number of addresses requiring a given difficulty.
+// Count is a decimal string since large subnets exceed 64-bit integers.
+type DifficultyBucket struct {
+	Difficulty uint8  `json:"difficulty"`
+	Count      string `json:"count"`
+}
+
+// SubnetDifficultyResponse represents the JSON response for a subnet difficulty preview.
+// Small subnets list every address, larger subnets are summarized as a histogram.
+type SubnetDifficultyResponse struct {
+	Subnet    string              `json:"subnet"`
+	Addresses []AddressDifficulty `json:"addresses,omitempty"`
+	Histogram []DifficultyBucket  `json:"histogram,omitempty"`
+}
+
+// Event types published on the live event feed
+const (
+	EventTypeClaim      = "claim"       // An address changed owner
+	EventTypeUnclaim    = "unclaim"     // An owner released an address
+	EventTypeEventStart = "event_start" // A game event scheduled by an admin started
+	EventTypeEventEnd   = "event_end"   // A game event ended or was cancelled
+)
+
+// ClaimEvent represents an event published on the live event feed
+type ClaimEvent struct {
+	Type             string     `json:"type"`
+	IP               string     `json:"ip"`
+	Claimant         string     `json:"claimant"`
+	PreviousClaimant string     `json:"previousClaimant,omitempty"`
+	Timestamp        time.Time  `json:"timestamp"`
+	Difficulty       uint8      `json:"difficulty,omitempty"` // Proof of work difficulty of the address when claimed, zero if unknown
+	GameEvent        *GameEvent `json:"gameEvent,omitempty"`  // Game event that started or ended, for event_start and event_end
+}
+
+// GameEvent is an event scheduled by an admin, multiplying the points
+// earned in a subnet while it runs
+type GameEvent struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`      // Shown to players, such as "Double score in the aa block"
+	Subnet     string    `json:"subnet"`     // CIDR notation
+	Multiplier float64   `json:"multiplier"` // Multiplies the points of addresses and dominated subnets inside the subnet
+	StartsAt   time.Time `json:"startsAt"`
+	EndsAt     time.Time `json:"endsAt"`
+}
+
+// GameEventRequest schedules a game event
+type GameEventRequest struct {
+	Title      string     `json:"title"`
+	Subnet     string     `json:"subnet"`             // CIDR notation
+	Multiplier float64    `json:"multiplier"`         // Multiplies the points earned in the subnet, such as 2 for double score
+	StartsAt   *time.Time `json:"startsAt,omitempty"` // Starts immediately if unset
+	Duration   string     `json:"duration"`           // How long the event lasts, such as "1h"
+}
+
+// LeaderboardEntry represents a claimant's position on the leaderboard
+type LeaderboardEntry struct {
+	Name      string `json:"name"`
+	Addresses int    `json:"addresses"`
+	Blocks124 int    `json:"blocks124,omitempty"` // Complete /124s held, counting those of complete /120s
+	Blocks120 int    `json:"blocks120,omitempty"` // Complete /120s held
+}
+
+// StatsResponse represents global game statistics
+type StatsResponse struct {
+	TotalClaims int `json:"totalClaims"`
+	Claimants   int `json:"claimants"`
+}
+
+// LevelStats summarizes the dominance of the subnets with a claim at one
+// prefix length. Dominance is the share of a subnet held by the claimant
+// holding the most of it, as a percentage.
+type LevelStats struct {
+	Level           int     `json:"level"`     // Prefix length of the subnets
+	Subnets         int     `json:"subnets"`   // Subnets with a claim
+	Owned           int     `json:"owned"`     // Subnets whose dominant claimant holds a majority
+	Contested       int     `json:"contested"` // Subnets with claims but no owner
+	MeanDominance   float64 `json:"meanDominance"`
+	P25Dominance    float64 `json:"p25Dominance"`
+	MedianDominance float64 `json:"medianDominance"`
+	P75Dominance    float64 `json:"p75Dominance"`
+	P90Dominance    float64 `json:"p90Dominance"`
+}
+
+// GameConfig describes the rules of a game that clients adapt to
+type GameConfig struct {
+	Levels      []int           `json:"levels"`                // Prefix lengths of the subnet hierarchy, widest first
+	NamePack    string          `json:"namePack,omitempty"`    // ID of the name pack served at /name-pack, empty for the built-in names
+	SupplyLines *SupplyLineRule `json:"supplyLines,omitempty"` // Rule keeping new claims near a player's holdings, if enabled
+}
+
+// SupplyLineRule describes the supply lines rule: once a player holds
+// FreeClaims addresses, claims on addresses they do not hold must be within
+// Distance addresses of one they do
+type SupplyLineRule struct {
+	FreeClaims int `json:"freeClaims"`
+	Distance   int `json:"distance"`
+}
+
+// ScoreEntry represents a player's accumulated score
+type ScoreEntry struct {
+	Name  string `json:"name"`
+	Score int64  `json:"score"`
+}
+
+// ScorePoint represents a player's score at a scoring tick
+type ScorePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Points    int64     `json:"points"` // Points awarded at this tick
+	Score     int64     `json:"score"`  // Total score after this tick
+}
+
+// SeasonResponse describes the current game season
+type SeasonResponse struct {
+	Number    int        `json:"number"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndsAt    *time.Time `json:"endsAt,omitempty"` // Unset if seasons only end manually
+}
+
+// SeasonArchive records the final state of a finished season
+type SeasonArchive struct {
+	Number      int                `json:"number"`
+	StartedAt   time.Time          `json:"startedAt"`
+	EndedAt     time.Time          `json:"endedAt"`
+	Leaderboard []LeaderboardEntry `json:"leaderboard"`
+	Scores      []ScoreEntry       `json:"scores,omitempty"`
+	Claims      map[string]string  `json:"claims"`
+}
+
+// Artifact represents a special address that awards bonus score while held
+type Artifact struct {
+	Address string `json:"address"`
+	Holder  string `json:"holder,omitempty"`
+}
+
+// ClaimQueueStats describes the state of the claim worker pool
+type ClaimQueueStats struct {
+	Workers       int    `json:"workers"`
+	QueueDepth    int    `json:"queueDepth"`    // Claims waiting for a worker
+	QueueCapacity int    `json:"queueCapacity"` // Claims that can wait before new claims are rejected
+	Processed     uint64 `json:"processed"`     // Claims processed since startup
+	Rejected      uint64 `json:"rejected"`      // Claims rejected because the queue was full
+}
+
+// StoreUsage describes the size of the claim store against its limits
+type StoreUsage struct {
+	Claims       int    `json:"claims"`
+	MaxClaims    int    `json:"maxClaims,omitempty"` // Omitted if unlimited
+	TreeNodes    int    `json:"treeNodes"`           // Subnet nodes in the tree
+	MaxTreeNodes int    `json:"maxTreeNodes,omitempty"`
+	PrunedNodes  uint64 `json:"prunedNodes"`      // Subnet nodes removed after their last claim was released
+	Policy       string `json:"policy,omitempty"` // What happens to claims on new addresses at a limit
+	Rejected     uint64 `json:"rejected"`         // Claims rejected because the store was full
+	Evicted      uint64 `json:"evicted"`          // Claims released to make room for newer ones
+}
+
+// ICMPChallenge holds the token a claimant must return when the server pings
+// the claimed address. Echo replies must carry the request payload followed by the token.
+type ICMPChallenge struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+	Token   string `json:"token"`
+}
+
+// SubnetChallenge holds the TXT record a claimant must publish in the reverse
+// DNS zone of a subnet to claim it
+type SubnetChallenge struct {
+	Subnet      string `json:"subnet"`
+	Name        string `json:"name"`
+	RecordName  string `json:"recordName"`
+	RecordValue string `json:"recordValue"`
+}
+
+// SubnetClaimRequest represents a request to claim a subnet verified through DNS
+type SubnetClaimRequest struct {
+	Subnet string `json:"subnet"`
+	Name   string `json:"name"`
+}
+
+// DelegationTokenRequest represents a request to mint a token that lets a bot claim on a player's behalf
+type DelegationTokenRequest struct {
+	Name      string `json:"name"`                // Claimant the bot's claims are attributed to
+	Prefix    string `json:"prefix"`              // Subnet the bot may claim in, in CIDR notation
+	MaxClaims int    `json:"maxClaims"`           // Number of claims the token allows
+	ExpiresIn string `json:"expiresIn,omitempty"` // Token lifetime such as "24h", the server maximum if empty
+}
+
+// DelegationToken describes a delegation token. Bots present the token as a
+// bearer token when claiming, and may leave the claimant name empty.
+type DelegationToken struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token,omitempty"` // Secret, only returned when the token is minted
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	MaxClaims int       `json:"maxClaims"`
+	Claims    int       `json:"claims"` // Claims made with the token
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// RIRImportResponse summarizes an import of RIR allocations as NPC factions
+type RIRImportResponse struct {
+	Imported int `json:"imported"` // Allocations granted to a faction
+	Skipped  int `json:"skipped"`  // Allocations whose faction name was rejected
+	Factions int `json:"factions"` // Distinct factions granted allocations
+}
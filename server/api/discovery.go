@@ -0,0 +1,29 @@
+package api
+
+import "strings"
+
+// DNS-SD service servers advertise over mDNS, so clients on the local network
+// can find them without typing an address
+const (
+	DiscoveryService = "_spacenet._tcp"
+	DiscoveryDomain  = "local."
+)
+
+// discoveryNameKey is the TXT record key of the game name
+const discoveryNameKey = "name"
+
+// DiscoveryText returns the TXT record of an advertised server
+func DiscoveryText(gameName string) []string {
+	return []string{discoveryNameKey + "=" + gameName}
+}
+
+// DiscoveredName returns the game name in the TXT record of an advertised
+// server, empty if there is none
+func DiscoveredName(text []string) string {
+	for _, entry := range text {
+		if name, ok := strings.CutPrefix(entry, discoveryNameKey+"="); ok {
+			return name
+		}
+	}
+	return ""
+}
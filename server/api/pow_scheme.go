@@ -0,0 +1,123 @@
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Proof of work scheme names
+const (
+	SchemeSHA256   = "sha256"
+	SchemeArgon2id = "argon2id"
+)
+
+// Challenge types, the ways a claim may be admitted. Clients ask for the one
+// they can solve, and the server answers with the one they must.
+const (
+	ChallengePoW     = "pow"     // The full proof of work of the address
+	ChallengeSession = "session" // A lighter proof of work, limited by the claims left to a session
+)
+
+// argon2Salt salts argon2id proofs of work. The hashed data already binds
+// the target, claimant and nonce, so a fixed salt is enough.
+var argon2Salt = []byte("spacenet proof of work")
+
+// PoWScheme is the hash function of a proof of work. Every scheme hashes the
+// same data, the target address followed by the claimant name and the nonce,
+// and a solution's difficulty is the number of leading zero bits of the hash.
+type PoWScheme interface {
+	// Name returns the scheme name announced in challenges
+	Name() string
+
+	// Sum hashes the proof of work data
+	Sum(data []byte) [32]byte
+}
+
+// SHA256Scheme hashes proofs of work with SHA-256, the default scheme
+type SHA256Scheme struct{}
+
+func (SHA256Scheme) Name() string {
+	return SchemeSHA256
+}
+
+func (SHA256Scheme) Sum(data []byte) [32]byte {
+	return sha256.Sum256(data)
+}
+
+// Argon2idScheme hashes proofs of work with the memory-hard Argon2id function,
+// so solving speed depends on memory bandwidth rather than raw hashing power
+type Argon2idScheme struct {
+	Time      uint32 `json:"time"`      // Passes over memory
+	MemoryKiB uint32 `json:"memoryKiB"` // Memory per hash
+	Threads   uint8  `json:"threads"`   // Lanes, which solvers may compute in parallel
+}
+
+func (Argon2idScheme) Name() string {
+	return SchemeArgon2id
+}
+
+func (s Argon2idScheme) Sum(data []byte) [32]byte {
+	var sum [32]byte
+	copy(sum[:], argon2.IDKey(data, argon2Salt, s.Time, s.MemoryKiB, s.Threads, uint32(len(sum))))
+	return sum
+}
+
+// Validate checks that the Argon2id parameters are usable
+func (s Argon2idScheme) Validate() error {
+	if s.Time == 0 || s.Threads == 0 {
+		return fmt.Errorf("argon2id time and threads must be positive")
+	}
+	if s.MemoryKiB < 8*uint32(s.Threads) {
+		return fmt.Errorf("argon2id memory must be at least 8 KiB per thread, got %d KiB", s.MemoryKiB)
+	}
+	return nil
+}
+
+// PoWChallenge represents the proof of work required to claim an address
+type PoWChallenge struct {
+	Target     string          `json:"target"`
+	Type       string          `json:"type,omitempty"` // "pow" or "session", the full proof of work if empty
+	Scheme     string          `json:"scheme"`         // "sha256" or "argon2id"
+	Difficulty uint8           `json:"difficulty"`
+	Argon2id   *Argon2idScheme `json:"argon2id,omitempty"` // Parameters of the argon2id scheme
+
+	// The session a session challenge is solved for, to send with the claim
+	Session          string     `json:"session,omitempty"`
+	SessionClaims    int        `json:"sessionClaims,omitempty"`    // Claims left to the session in the current window
+	SessionResetsAt  *time.Time `json:"sessionResetsAt,omitempty"`  // When the session's claims are next replenished
+	SessionExpiresAt *time.Time `json:"sessionExpiresAt,omitempty"` // When a new session must be asked for
+}
+
+// NewPoWChallenge describes the proof of work required by a scheme
+func NewPoWChallenge(target string, scheme PoWScheme, difficulty uint8) PoWChallenge {
+	challenge := PoWChallenge{
+		Target:     target,
+		Type:       ChallengePoW,
+		Scheme:     scheme.Name(),
+		Difficulty: difficulty,
+	}
+	if argon2id, ok := scheme.(Argon2idScheme); ok {
+		challenge.Argon2id = &argon2id
+	}
+	return challenge
+}
+
+// PoWScheme returns the scheme a challenge must be solved with
+func (c PoWChallenge) PoWScheme() (PoWScheme, error) {
+	switch c.Scheme {
+	case SchemeSHA256, "":
+		return SHA256Scheme{}, nil
+	case SchemeArgon2id:
+		if c.Argon2id == nil {
+			return nil, fmt.Errorf("argon2id challenge without parameters")
+		}
+		if err := c.Argon2id.Validate(); err != nil {
+			return nil, err
+		}
+		return *c.Argon2id, nil
+	}
+	return nil, fmt.Errorf("unsupported proof of work scheme %q", c.Scheme)
+}
@@ -2,9 +2,19 @@
 package api
 
 import (
+	"context"
 	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
 	"fmt"
+	"hash"
+	"math/bits"
 	"net"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ProofOfWork represents a proof of work challenge and solution
@@ -14,8 +24,8 @@ type ProofOfWork struct {
 	Nonce  string // Nonce used to solve the challenge
 }
 
-// Hash computes the SHA-256 hash of the proof of work data
-func (pow *ProofOfWork) Hash() [32]byte {
+// data returns the hashed proof of work data
+func (pow *ProofOfWork) data() []byte {
 	// Create input data: target_ip + claimant + nonce
 	data := make([]byte, 0, 16+len(pow.Name)+8)
 
@@ -28,51 +38,178 @@ func (pow *ProofOfWork) Hash() [32]byte {
 	// Add nonce as string bytes
 	data = append(data, []byte(pow.Nonce)...)
 
-	return sha256.Sum256(data)
+	return data
+}
+
+// Hash computes the SHA-256 hash of the proof of work data
+func (pow *ProofOfWork) Hash() [32]byte {
+	return sha256.Sum256(pow.data())
 }
 
-// IsValid checks if the proof of work satisfies the difficulty requirement
+// IsValid checks if the SHA-256 proof of work satisfies the difficulty requirement
 func (pow *ProofOfWork) IsValid(difficulty uint8) bool {
 	hash := pow.Hash()
+	return leadingZeroBits(&hash) >= int(difficulty)
+}
 
-	// Count leading zero bits
-	leadingZeros := 0
-	for i := range 32 {
-		b := hash[i]
-		if b == 0 {
-			leadingZeros += 8
-		} else {
-			// Count leading zeros in this byte
-			for j := 7; j >= 0; j-- {
-				if (b>>j)&1 == 0 {
-					leadingZeros++
-				} else {
-					break
-				}
-			}
-			break
-		}
-		if leadingZeros >= int(difficulty) {
-			return true
+// IsValidFor checks if the proof of work satisfies the difficulty requirement
+// when hashed with a scheme
+func (pow *ProofOfWork) IsValidFor(scheme PoWScheme, difficulty uint8) bool {
+	hash := scheme.Sum(pow.data())
+	return leadingZeroBits(&hash) >= int(difficulty)
+}
+
+// leadingZeroBits counts the leading zero bits of a hash
+func leadingZeroBits(hash *[32]byte) int {
+	zeros := 0
+	for i := 0; i < len(hash); i += 8 {
+		word := binary.BigEndian.Uint64(hash[i:])
+		if word != 0 {
+			return zeros + bits.LeadingZeros64(word)
 		}
+		zeros += 64
 	}
+	return zeros
+}
 
-	return leadingZeros >= int(difficulty)
+// nonceHasher hashes the proof of work data of successive nonces for one
+// target and claimant. With SHA-256, the full blocks of the target and name
+// are hashed once and the digest state restored for each nonce, so long
+// names cost no more to solve than short ones.
+type nonceHasher struct {
+	scheme   PoWScheme // Scheme other than SHA-256, nil for SHA-256
+	digest   hash.Hash
+	midstate []byte // Marshaled digest state after the prefix's full blocks, nil if none
+	tail     []byte // Prefix bytes after the full blocks, followed by the nonce
+	tailLen  int    // Length of the tail without a nonce
+	sum      [32]byte
 }
 
-// SolveProofOfWork attempts to solve a proof of work challenge (for client use)
-func SolveProofOfWork(target net.IP, claimant string, difficulty uint8, maxAttempts uint64) (*ProofOfWork, error) {
-	pow := &ProofOfWork{
-		Target: target,
-		Name:   claimant,
+// newNonceHasher prepares to hash nonces for a target and claimant
+func newNonceHasher(scheme PoWScheme, target net.IP, claimant string) *nonceHasher {
+	prefix := append(append(make([]byte, 0, 16+len(claimant)), target.To16()...), claimant...)
+
+	if _, ok := scheme.(SHA256Scheme); !ok {
+		return &nonceHasher{scheme: scheme, tail: prefix, tailLen: len(prefix)}
+	}
+
+	full := len(prefix) - len(prefix)%sha256.BlockSize
+	h := &nonceHasher{digest: sha256.New()}
+	if full > 0 {
+		h.digest.Write(prefix[:full])
+		// SHA-256 digests always support marshaling
+		h.midstate, _ = h.digest.(encoding.BinaryMarshaler).MarshalBinary()
+	}
+	h.tail = append(make([]byte, 0, len(prefix)-full+20), prefix[full:]...)
+	h.tailLen = len(h.tail)
+	return h
+}
+
+// hash returns the proof of work hash of a nonce
+func (h *nonceHasher) hash(nonce uint64) *[32]byte {
+	h.tail = strconv.AppendUint(h.tail[:h.tailLen], nonce, 10)
+	if h.scheme != nil {
+		h.sum = h.scheme.Sum(h.tail)
+		return &h.sum
+	}
+	if h.midstate == nil {
+		h.sum = sha256.Sum256(h.tail)
+		return &h.sum
 	}
 
+	_ = h.digest.(encoding.BinaryUnmarshaler).UnmarshalBinary(h.midstate)
+	h.digest.Write(h.tail)
+	h.digest.Sum(h.sum[:0])
+	return &h.sum
+}
+
+// SolveProofOfWork attempts to solve a proof of work challenge (for client use),
+// returning the solution with the lowest nonce
+func SolveProofOfWork(target net.IP, claimant string, difficulty uint8, maxAttempts uint64) (*ProofOfWork, error) {
+	hasher := newNonceHasher(SHA256Scheme{}, target, claimant)
 	for nonce := range maxAttempts {
-		pow.Nonce = fmt.Sprintf("%d", nonce)
-		if pow.IsValid(difficulty) {
-			return pow, nil
+		if leadingZeroBits(hasher.hash(nonce)) >= int(difficulty) {
+			return &ProofOfWork{Target: target, Name: claimant, Nonce: strconv.FormatUint(nonce, 10)}, nil
 		}
 	}
 
 	return nil, fmt.Errorf("could not solve proof of work within %d attempts", maxAttempts)
 }
+
+// SolveStats reports the work done to solve a proof of work
+type SolveStats struct {
+	Hashes  uint64        // Nonces tried
+	Elapsed time.Duration // Time spent solving
+}
+
+// HashRate returns the hashes computed per second
+func (s SolveStats) HashRate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Hashes) / s.Elapsed.Seconds()
+}
+
+// SolveProofOfWorkParallel solves a SHA-256 proof of work challenge on several
+// goroutines, one per CPU if workers is not positive. Any valid nonce may be
+// returned, not necessarily the lowest.
+func SolveProofOfWorkParallel(target net.IP, claimant string, difficulty uint8, maxAttempts uint64, workers int) (*ProofOfWork, SolveStats, error) {
+	return SolveProofOfWorkWith(SHA256Scheme{}, target, claimant, difficulty, maxAttempts, workers)
+}
+
+// SolveProofOfWorkWith solves a proof of work challenge hashed with a scheme
+// on several goroutines, like SolveProofOfWorkParallel
+func SolveProofOfWorkWith(scheme PoWScheme, target net.IP, claimant string, difficulty uint8, maxAttempts uint64, workers int) (*ProofOfWork, SolveStats, error) {
+	return SolveProofOfWorkContext(context.Background(), scheme, target, claimant, difficulty, maxAttempts, workers)
+}
+
+// SolveProofOfWorkContext solves a proof of work challenge like
+// SolveProofOfWorkWith, giving up with the context's error once it is done
+func SolveProofOfWorkContext(ctx context.Context, scheme PoWScheme, target net.IP, claimant string, difficulty uint8, maxAttempts uint64, workers int) (*ProofOfWork, SolveStats, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	start := time.Now()
+	var solved atomic.Bool
+	var hashes atomic.Uint64
+	var wg sync.WaitGroup
+	solutions := make(chan uint64, workers)
+
+	// Worker i tries nonces i, i+workers, i+2*workers, ...
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			hasher := newNonceHasher(scheme, target, claimant)
+			tried := uint64(0)
+			defer func() { hashes.Add(tried) }()
+
+			for nonce := uint64(worker); nonce < maxAttempts; nonce += uint64(workers) {
+				// Checking for other workers' solutions every SHA-256 hash would slow the search
+				if (hasher.scheme != nil || tried%1024 == 0) && (solved.Load() || ctx.Err() != nil) {
+					return
+				}
+				tried++
+				if leadingZeroBits(hasher.hash(nonce)) >= int(difficulty) {
+					solved.Store(true)
+					solutions <- nonce
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(solutions)
+
+	stats := SolveStats{Hashes: hashes.Load(), Elapsed: time.Since(start)}
+	nonce, ok := <-solutions
+	if !ok && ctx.Err() != nil {
+		return nil, stats, ctx.Err()
+	}
+	if !ok {
+		return nil, stats, fmt.Errorf("could not solve proof of work within %d attempts", maxAttempts)
+	}
+	return &ProofOfWork{Target: target, Name: claimant, Nonce: strconv.FormatUint(nonce, 10)}, stats, nil
+}
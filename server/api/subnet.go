@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// CanonicalSubnet returns the subnet of prefixLen bits containing an IPv6
+// address in canonical CIDR notation, with the host bits cleared and the
+// address compressed as in RFC 5952, such as 2001:db8::/32. It returns an
+// empty string for IPv4 addresses and prefix lengths outside 0 to 128.
+func CanonicalSubnet(ip net.IP, prefixLen int) string {
+	if ip.To16() == nil || ip.To4() != nil || prefixLen < 0 || prefixLen > 128 {
+		return ""
+	}
+	mask := net.CIDRMask(prefixLen, 128)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// ParseSubnet parses an IPv6 subnet in CIDR notation, in any of the forms
+// of an IPv6 address, returning it with the host bits cleared. Use String
+// on the result for its canonical form.
+func ParseSubnet(s string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet %q", s)
+	}
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("subnet %q is not IPv6", s)
+	}
+	return ipNet, nil
+}
+
+// StripZone removes the zone of a scoped IPv6 address, such as the %eth0 of
+// fe80::1%eth0, keeping any prefix length after it
+func StripZone(s string) string {
+	zone := strings.IndexByte(s, '%')
+	if zone < 0 {
+		return s
+	}
+	if slash := strings.IndexByte(s[zone:], '/'); slash >= 0 {
+		return s[:zone] + s[zone+slash:]
+	}
+	return s[:zone]
+}
+
+// ParseAddress parses an IPv6 address in any of its forms, as typed or
+// pasted by a player: surrounding whitespace, brackets and a zone are
+// ignored.
+func ParseAddress(s string) (net.IP, error) {
+	trimmed := strings.TrimSpace(s)
+	if inner, ok := strings.CutPrefix(trimmed, "["); ok {
+		trimmed, ok = strings.CutSuffix(inner, "]")
+		if !ok {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+	}
+	ip := net.ParseIP(StripZone(trimmed))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("address %q is not IPv6", s)
+	}
+	return ip, nil
+}
+
+// ExpandAddress writes out every group of an IPv6 address in four hex
+// digits, such as 2001:0db8:0000:0000:0000:0000:0000:0001 for 2001:db8::1.
+// It returns an empty string for IPv4 addresses.
+func ExpandAddress(ip net.IP) string {
+	if ip.To16() == nil || ip.To4() != nil {
+		return ""
+	}
+	ip = ip.To16()
+	groups := make([]string, net.IPv6len/2)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%02x%02x", ip[2*i], ip[2*i+1])
+	}
+	return strings.Join(groups, ":")
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCanonicalSubnet tests that subnets are masked and compressed the same way whatever the input form
+func TestCanonicalSubnet(t *testing.T) {
+	tests := []struct {
+		ip        string
+		prefixLen int
+		want      string
+	}{
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", 32, "2001:db8::/32"},
+		{"2001:db8::1", 128, "2001:db8::1/128"},
+		{"2001:db8:0:0:1:0:0:1", 128, "2001:db8::1:0:0:1/128"}, // First of equal zero runs is compressed
+		{"2001:db8:0:1:0:0:0:0", 64, "2001:db8:0:1::/64"},
+		{"2001:DB8:ABCD::", 48, "2001:db8:abcd::/48"},
+		{"2001:db8:ffff:ffff::", 33, "2001:db8:8000::/33"},
+		{"::1", 0, "::/0"},
+		{"::", 128, "::/128"},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 16, "ffff::/16"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CanonicalSubnet(net.ParseIP(tt.ip), tt.prefixLen), "%s/%d", tt.ip, tt.prefixLen)
+	}
+
+	assert.Empty(t, CanonicalSubnet(net.ParseIP("192.0.2.1"), 24), "IPv4 addresses should be rejected")
+	assert.Empty(t, CanonicalSubnet(net.ParseIP("::ffff:192.0.2.1"), 120), "IPv4-mapped addresses should be rejected")
+	assert.Empty(t, CanonicalSubnet(nil, 64), "Missing addresses should be rejected")
+	assert.Empty(t, CanonicalSubnet(net.ParseIP("2001:db8::"), 129), "Prefixes longer than 128 bits should be rejected")
+	assert.Empty(t, CanonicalSubnet(net.ParseIP("2001:db8::"), -1), "Negative prefixes should be rejected")
+}
+
+// TestParseSubnet tests parsing subnets into their canonical form
+func TestParseSubnet(t *testing.T) {
+	valid := map[string]string{
+		"2001:0db8:0000:0000:0000:0000:0000:0000/32": "2001:db8::/32",
+		"2001:db8::1/32":       "2001:db8::/32",
+		"2001:db8:1234::/48":   "2001:db8:1234::/48",
+		"2001:db8::1/128":      "2001:db8::1/128",
+		"::/0":                 "::/0",
+		"2001:DB8::/32":        "2001:db8::/32",
+		"2001:db8::ffff:1/127": "2001:db8::ffff:0/127",
+	}
+	for input, want := range valid {
+		subnet, err := ParseSubnet(input)
+		require.NoError(t, err, "%s should parse", input)
+		assert.Equal(t, want, subnet.String(), "%s should be canonicalized", input)
+		ones, bits := subnet.Mask.Size()
+		assert.Equal(t, 128, bits, "%s should have an IPv6 mask", input)
+		assert.Equal(t, CanonicalSubnet(subnet.IP, ones), subnet.String(), "%s should agree with CanonicalSubnet", input)
+	}
+
+	for _, input := range []string{
+		"", "2001:db8::", "2001:db8::/", "2001:db8::/129", "2001:db8::/-1", "2001:db8::/32/64",
+		"10.0.0.0/8", "::ffff:192.0.2.0/120", "2001:db8::%eth0/64", "not-a-subnet", " 2001:db8::/32",
+	} {
+		_, err := ParseSubnet(input)
+		assert.Error(t, err, "%q should be rejected", input)
+	}
+}
+
+// TestStripZone tests removing the zone of scoped addresses
+func TestStripZone(t *testing.T) {
+	tests := map[string]string{
+		"fe80::1%eth0":   "fe80::1",
+		"fe80::%eth0/64": "fe80::/64",
+		"fe80::1%25en0":  "fe80::1",
+		"2001:db8::1":    "2001:db8::1",
+		"2001:db8::/32":  "2001:db8::/32",
+		"fe80::1%":       "fe80::1",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, StripZone(input), input)
+	}
+}
+
+// TestParseAddress tests parsing addresses as players type or paste them
+func TestParseAddress(t *testing.T) {
+	valid := map[string]string{
+		"2001:db8::1": "2001:db8::1",
+		"2001:0db8:0000:0000:0000:0000:0000:0001": "2001:db8::1",
+		"2001:DB8::1":    "2001:db8::1",
+		" 2001:db8::1\n": "2001:db8::1",
+		"[2001:db8::1]":  "2001:db8::1",
+		"fe80::1%eth0":   "fe80::1",
+		"[fe80::1%eth0]": "fe80::1",
+	}
+	for input, want := range valid {
+		ip, err := ParseAddress(input)
+		require.NoError(t, err, "%q should parse", input)
+		assert.Equal(t, want, ip.String(), "%q should parse", input)
+	}
+
+	for _, input := range []string{"", "2001:db8::/32", "2001:db8:::1", "192.0.2.1", "::ffff:192.0.2.1", "[2001:db8::1", "not-an-address"} {
+		_, err := ParseAddress(input)
+		assert.Error(t, err, "%q should be rejected", input)
+	}
+}
+
+// TestExpandAddress tests writing out every group of addresses
+func TestExpandAddress(t *testing.T) {
+	assert.Equal(t, "2001:0db8:0000:0000:0000:0000:0000:0001", ExpandAddress(net.ParseIP("2001:db8::1")))
+	assert.Equal(t, "0000:0000:0000:0000:0000:0000:0000:0000", ExpandAddress(net.ParseIP("::")))
+	assert.Equal(t, "fe80:0000:0000:0000:abcd:0000:0000:00ff", ExpandAddress(net.ParseIP("FE80::ABCD:0:0:FF")))
+	assert.Empty(t, ExpandAddress(net.ParseIP("192.0.2.1")), "IPv4 addresses should not be expanded")
+	assert.Empty(t, ExpandAddress(nil), "Missing addresses should not be expanded")
+}
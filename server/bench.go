@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/spf13/cobra"
+)
+
+// benchOptions configures a load test
+type benchOptions struct {
+	target     string
+	rate       int
+	workers    int
+	duration   time.Duration
+	claimRatio float64
+	prefix     string
+	name       string
+}
+
+// benchOp is a kind of request made by the load test
+type benchOp string
+
+const (
+	benchClaim     benchOp = "claim"
+	benchChallenge benchOp = "challenge"
+	benchSubnet    benchOp = "subnet"
+)
+
+// benchResult is the outcome of one request
+type benchResult struct {
+	op      benchOp
+	latency time.Duration
+	err     bool
+}
+
+// newBenchCommand creates the load-testing command
+func newBenchCommand() *cobra.Command {
+	opts := benchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load test a running server",
+		Long: "Send valid proof of work claims and subnet queries to a server at a fixed rate, " +
+			"reporting latency percentiles and error rates per request type. Proof of work is solved " +
+			"before each claim is timed, so latencies measure the server alone.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBench(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.target, "target", "localhost:8080", "Server host[:port] or base URL")
+	cmd.Flags().IntVar(&opts.rate, "rate", 50, "Claims and queries started per second, each claim also fetches its challenge")
+	cmd.Flags().IntVar(&opts.workers, "workers", 8, "Concurrent workers")
+	cmd.Flags().DurationVar(&opts.duration, "duration", 30*time.Second, "How long to send requests")
+	cmd.Flags().Float64Var(&opts.claimRatio, "claims", 0.5, "Fraction of requests that are claims, the rest are subnet queries")
+	cmd.Flags().StringVar(&opts.prefix, "prefix", "2001:db8:be00::/40", "Subnet to claim and query addresses in")
+	cmd.Flags().StringVar(&opts.name, "name", "bench", "Claimant name for benchmark claims")
+
+	return cmd
+}
+
+// runBench runs a load test and prints its report
+func runBench(opts benchOptions) error {
+	if opts.rate <= 0 || opts.workers <= 0 || opts.duration <= 0 {
+		return fmt.Errorf("rate, workers and duration must be positive")
+	}
+	if opts.claimRatio < 0 || opts.claimRatio > 1 {
+		return fmt.Errorf("claims must be between 0 and 1")
+	}
+	_, prefix, err := net.ParseCIDR(opts.prefix)
+	if err != nil || prefix.IP.To4() != nil {
+		return fmt.Errorf("prefix must be an IPv6 subnet in CIDR notation")
+	}
+
+	baseURL := strings.TrimSuffix(opts.target, "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Get(baseURL + "/health")
+	if err != nil {
+		return fmt.Errorf("server unreachable: %w", err)
+	}
+	_ = resp.Body.Close()
+
+	fmt.Printf("Benchmarking %s at %d req/s with %d workers for %s\n", baseURL, opts.rate, opts.workers, opts.duration)
+
+	// Requests that find every worker busy are dropped rather than queued, so
+	// a saturated server shows up as a shortfall in the achieved rate
+	jobs := make(chan struct{}, opts.workers)
+	results := make(chan benchResult, opts.workers*4)
+
+	var workers sync.WaitGroup
+	for range opts.workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+			for range jobs {
+				addr := randomBenchAddress(rng, prefix)
+				if rng.Float64() < opts.claimRatio {
+					benchClaimAddress(client, baseURL, addr, opts.name, results)
+				} else {
+					results <- benchQuerySubnet(client, baseURL, addr, rng)
+				}
+			}
+		}()
+	}
+
+	collected := make(map[benchOp][]benchResult)
+	collectorDone := make(chan struct{})
+	go func() {
+		defer close(collectorDone)
+		for result := range results {
+			collected[result.op] = append(collected[result.op], result)
+		}
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(time.Second / time.Duration(opts.rate))
+	deadline := time.After(opts.duration)
+	dropped := 0
+loop:
+	for {
+		select {
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+			select {
+			case jobs <- struct{}{}:
+			default:
+				dropped++
+			}
+		}
+	}
+	ticker.Stop()
+	close(jobs)
+	workers.Wait()
+	close(results)
+	<-collectorDone
+	elapsed := time.Since(start)
+
+	printBenchReport(os.Stdout, collected, elapsed, dropped)
+	return nil
+}
+
+// benchClaimAddress fetches the challenge for an address, solves it and claims the address
+func benchClaimAddress(client *http.Client, baseURL string, addr net.IP, name string, results chan<- benchResult) {
+	var challenge api.PoWChallenge
+	start := time.Now()
+	err := benchRequest(client, http.MethodGet, baseURL+"/api/v1/challenge/"+addr.String(), nil, &challenge)
+	results <- benchResult{op: benchChallenge, latency: time.Since(start), err: err != nil}
+	if err != nil {
+		return
+	}
+
+	scheme, err := challenge.PoWScheme()
+	if err != nil {
+		results <- benchResult{op: benchClaim, err: true}
+		return
+	}
+	pow, _, err := api.SolveProofOfWorkWith(scheme, addr, name, challenge.Difficulty, 1<<28, 1)
+	if err != nil {
+		results <- benchResult{op: benchClaim, err: true}
+		return
+	}
+
+	body, _ := json.Marshal(api.ClaimRequest{Name: name, Nonce: pow.Nonce})
+	start = time.Now()
+	err = benchRequest(client, http.MethodPost, baseURL+"/api/v1/claim/"+addr.String(), body, nil)
+	results <- benchResult{op: benchClaim, latency: time.Since(start), err: err != nil}
+}
+
+// benchQuerySubnet queries the statistics of a random subnet containing an address
+func benchQuerySubnet(client *http.Client, baseURL string, addr net.IP, rng *rand.Rand) benchResult {
+	prefixLen := []int{48, 64, 80, 96, 112}[rng.IntN(5)]
+	subnet := addr.Mask(net.CIDRMask(prefixLen, 128))
+
+	start := time.Now()
+	err := benchRequest(client, http.MethodGet, fmt.Sprintf("%s/api/v1/subnet/%s/%d", baseURL, subnet, prefixLen), nil, nil)
+	return benchResult{op: benchSubnet, latency: time.Since(start), err: err != nil}
+}
+
+// benchRequest makes a request, decoding the response into out if it is not nil.
+// Responses other than 2xx are errors.
+func benchRequest(client *http.Client, method, url string, body []byte, out any) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// randomBenchAddress returns a random address within a subnet
+func randomBenchAddress(rng *rand.Rand, subnet *net.IPNet) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	for i, mask := range subnet.Mask {
+		ip[i] = subnet.IP[i]&mask | byte(rng.UintN(256))&^mask
+	}
+	return ip
+}
+
+// printBenchReport prints throughput, error rates and latency percentiles per request type
+func printBenchReport(w io.Writer, results map[benchOp][]benchResult, elapsed time.Duration, dropped int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "request\tcount\treq/s\terrors\tp50\tp90\tp99\tmax\t")
+
+	for _, op := range []benchOp{benchChallenge, benchClaim, benchSubnet} {
+		opResults := results[op]
+		if len(opResults) == 0 {
+			continue
+		}
+
+		var latencies []time.Duration
+		errors := 0
+		for _, result := range opResults {
+			if result.err {
+				errors++
+			} else {
+				latencies = append(latencies, result.latency)
+			}
+		}
+		slices.Sort(latencies)
+
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f%%\t%s\t%s\t%s\t%s\t\n", op, len(opResults),
+			float64(len(opResults))/elapsed.Seconds(),
+			100*float64(errors)/float64(len(opResults)),
+			percentile(latencies, 0.50), percentile(latencies, 0.90), percentile(latencies, 0.99), percentile(latencies, 1))
+	}
+	_ = tw.Flush()
+
+	if dropped > 0 {
+		fmt.Fprintf(w, "%d requests dropped because every worker was busy, add workers or lower the rate\n", dropped)
+	}
+}
+
+// percentile returns the latency at a quantile of sorted latencies
+func percentile(sorted []time.Duration, q float64) string {
+	if len(sorted) == 0 {
+		return "-"
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+	return sorted[i].Round(10 * time.Microsecond).String()
+}
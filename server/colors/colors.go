@@ -0,0 +1,52 @@
+// Package colors assigns every claimant a color from a hash of their name,
+// so all SpaceNet clients show a player in the same color.
+package colors
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Palette is a list of hex colors claimants are assigned from
+type Palette []string
+
+// Standard is the default palette, bright colors readable on dark backgrounds
+var Standard = Palette{
+	"#00afff", "#ff8700", "#d75fd7", "#5fd700", "#ffd700", "#0087ff",
+	"#ff5f5f", "#af87ff", "#00d7af", "#ffaf00", "#875fff", "#87ff00",
+}
+
+// ColorBlind is the Okabe-Ito palette without black, told apart with the
+// common forms of color blindness
+var ColorBlind = Palette{
+	"#E69F00", "#56B4E9", "#009E73", "#F0E442", "#0072B2", "#D55E00", "#CC79A7",
+}
+
+// Palette names, as given in client settings
+const (
+	NameStandard   = "standard"
+	NameColorBlind = "color-blind"
+)
+
+// Lookup returns the palette of a name
+func Lookup(name string) (Palette, error) {
+	switch name {
+	case NameStandard:
+		return Standard, nil
+	case NameColorBlind:
+		return ColorBlind, nil
+	}
+	return nil, fmt.Errorf("unknown palette %q, choose %s or %s", name, NameStandard, NameColorBlind)
+}
+
+// Color returns the color of a claimant, picked by the FNV-1a hash of their
+// name. Clients in other languages must hash the UTF-8 bytes of the name the
+// same way. Nobody, the empty name, has no color.
+func (p Palette) Color(claimant string) string {
+	if claimant == "" || len(p) == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(claimant))
+	return p[h.Sum32()%uint32(len(p))]
+}
@@ -0,0 +1,33 @@
+package colors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestColor tests that claimants keep the colors other clients compute for
+// them, including names beyond ASCII
+func TestColor(t *testing.T) {
+	for claimant, want := range map[string][2]string{
+		"alice":   {"#87ff00", "#CC79A7"},
+		"bob":     {"#00d7af", "#F0E442"},
+		"Ünïcode": {"#5fd700", "#F0E442"},
+	} {
+		assert.Equal(t, want[0], Standard.Color(claimant), "Standard color of %q should not change", claimant)
+		assert.Equal(t, want[1], ColorBlind.Color(claimant), "Color-blind color of %q should not change", claimant)
+	}
+	assert.Empty(t, Standard.Color(""), "Nobody should have no color")
+	assert.Empty(t, Palette(nil).Color("alice"), "An empty palette should have no colors")
+}
+
+// TestLookup tests that palettes are found by name
+func TestLookup(t *testing.T) {
+	palette, err := Lookup(NameColorBlind)
+	require.NoError(t, err, "Should find a known palette")
+	assert.Equal(t, ColorBlind, palette, "Should return the named palette")
+
+	_, err = Lookup("sepia")
+	assert.Error(t, err, "Should reject an unknown palette")
+}
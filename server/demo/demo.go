@@ -0,0 +1,143 @@
+// Package demo runs a self-contained SpaceNet server in-process, populated
+// with synthetic claims and bots, so clients can be shown without a network
+// or a real server
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bjia56/spacenet/server/internal/server"
+	"github.com/gorilla/mux"
+)
+
+// Prefix is the documentation subnet the demo's claims are made in
+const Prefix = "2001:db8::/32"
+
+// players are the synthetic claimants seeded into the demo, each holding
+// some clusters of addresses
+var players = []struct {
+	name     string
+	clusters int // /48s the player has claims in
+	claims   int // Claims per cluster
+}{
+	{"aurora", 6, 120},
+	{"nebula", 5, 100},
+	{"quasar", 4, 90},
+	{"pulsar", 3, 80},
+	{"comet", 3, 40},
+	{"nova", 2, 30},
+}
+
+// Server is a demo server listening on the loopback interface
+type Server struct {
+	Host string // Loopback address the API is served on
+	Port int    // Ephemeral port the API is served on
+
+	httpServer *http.Server
+	bots       *server.BotSimulator
+}
+
+// Start seeds a memory store with synthetic claims, serves the API on an
+// ephemeral loopback port and starts bots claiming in the background, so
+// ownership keeps changing. Claims are seeded the same way every time.
+func Start() (*Server, error) {
+	store := server.NewClaimStore()
+	if err := seed(context.Background(), store, rand.New(rand.NewPCG(1, 2))); err != nil {
+		return nil, fmt.Errorf("failed to seed demo claims: %w", err)
+	}
+
+	bots, err := server.NewBotSimulator(store, server.BotOptions{
+		Count:      4,
+		Strategies: []string{server.BotStrategyCluster, server.BotStrategyContest},
+		Interval:   2 * time.Second,
+		Prefix:     Prefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := listenLoopback()
+	if err != nil {
+		return nil, err
+	}
+	router := mux.NewRouter()
+	server.NewHTTPHandler(store).RegisterRoutes(router)
+
+	// Cancel request contexts on shutdown so event streams end
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	s := &Server{
+		Host:       listener.Addr().(*net.TCPAddr).IP.String(),
+		Port:       listener.Addr().(*net.TCPAddr).Port,
+		httpServer: &http.Server{Handler: router, BaseContext: func(net.Listener) context.Context { return baseCtx }},
+		bots:       bots,
+	}
+	s.httpServer.RegisterOnShutdown(cancelRequests)
+
+	go func() {
+		if err := s.httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Demo server error", "error", err)
+		}
+	}()
+	bots.Start()
+	return s, nil
+}
+
+// Stop stops the bots and the API
+func (s *Server) Stop() {
+	s.bots.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		slog.Error("Error shutting down demo server", "error", err)
+	}
+}
+
+// listenLoopback listens on an ephemeral port of the IPv6 loopback address,
+// or the IPv4 one where IPv6 is unavailable
+func listenLoopback() (net.Listener, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort("::1", "0"))
+	if err == nil {
+		return listener, nil
+	}
+	listener, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on loopback: %w", err)
+	}
+	return listener, nil
+}
+
+// seed claims clusters of addresses for the synthetic players. Clusters are
+// /48s of the demo prefix, and addresses within them fall in a handful of
+// subnets at each deeper level, so every table shows busy and quiet subnets.
+func seed(ctx context.Context, store server.Store, rng *rand.Rand) error {
+	_, prefix, _ := net.ParseCIDR(Prefix)
+	for _, player := range players {
+		var batch []server.BatchClaim
+		for range player.clusters {
+			// Players share 16 /48s, so some clusters overlap
+			cluster := rng.IntN(16)
+			for range player.claims {
+				addr := make(net.IP, net.IPv6len)
+				copy(addr, prefix.IP)
+				addr[5] = byte(cluster)
+				for block := 3; block < 7; block++ {
+					addr[2*block+1] = byte(rng.IntN(4))
+				}
+				addr[14], addr[15] = byte(rng.IntN(256)), byte(rng.IntN(256))
+				batch = append(batch, server.BatchClaim{IP: addr.String(), Claimant: player.name})
+			}
+		}
+		if err := store.ProcessClaims(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
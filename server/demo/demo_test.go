@@ -0,0 +1,36 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStart tests that the demo server serves its synthetic claims
+func TestStart(t *testing.T) {
+	s, err := Start()
+	require.NoError(t, err)
+	defer s.Stop()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/subnets/48", net.JoinHostPort(s.Host, strconv.Itoa(s.Port))))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var subnets []api.SubnetListEntry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&subnets))
+	assert.NotEmpty(t, subnets, "Synthetic claims should be seeded")
+	for _, entry := range subnets {
+		ipNet, err := api.ParseSubnet(entry.Subnet)
+		require.NoError(t, err)
+		_, prefix, _ := net.ParseCIDR(Prefix)
+		assert.True(t, prefix.Contains(ipNet.IP), "Claims should be in the demo prefix")
+	}
+}
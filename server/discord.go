@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bjia56/spacenet/server/discord"
+	"github.com/spf13/cobra"
+)
+
+// discordTokenEnv names the environment variable holding the Discord bot token,
+// kept off the command line where other users could read it
+const discordTokenEnv = "SPACENET_DISCORD_TOKEN"
+
+// newDiscordCommand creates the Discord bot command
+func newDiscordCommand() *cobra.Command {
+	opts := discord.DefaultOptions()
+
+	cmd := &cobra.Command{
+		Use:   "discord",
+		Short: "Relay a server's activity to a Discord channel",
+		Long: "Run a Discord bot that announces takeovers from a server's event feed, posts the leaderboard " +
+			"when it changes, and answers !owner <address or subnet> and !stats in its channel. The bot token " +
+			"is read from " + discordTokenEnv + ", and the bot needs the Message Content intent.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Token = os.Getenv(discordTokenEnv)
+			bot, err := discord.New(opts)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			slog.Info("Starting Discord bot", "server", opts.Server, "channel", opts.ChannelID)
+			return bot.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Server, "target", "localhost:8080", "Server host[:port] or base URL")
+	cmd.Flags().StringVar(&opts.ChannelID, "channel", "", "ID of the channel to post in and answer commands from")
+	cmd.Flags().DurationVar(&opts.AnnounceInterval, "announce-interval", opts.AnnounceInterval, "Time takeovers are gathered for before they are posted together")
+	cmd.Flags().DurationVar(&opts.LeaderboardInterval, "leaderboard-interval", opts.LeaderboardInterval, "Time between leaderboard checks, 0 to never post it")
+	cmd.Flags().IntVar(&opts.LeaderboardSize, "leaderboard-size", opts.LeaderboardSize, "Claimants shown on the leaderboard")
+	cmd.Flags().StringVar(&opts.CommandPrefix, "prefix", opts.CommandPrefix, "Prefix of commands")
+
+	return cmd
+}
@@ -0,0 +1,233 @@
+// Package discord runs a Discord bot for a SpaceNet server. The bot follows the
+// server's event feed to announce takeovers, posts the leaderboard when it
+// changes, and answers !owner and !stats commands in its channel.
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Options configures a bot
+type Options struct {
+	Token               string        // Discord bot token
+	ChannelID           string        // Channel announcements are posted to
+	Server              string        // SpaceNet server host[:port] or base URL
+	AnnounceInterval    time.Duration // Time takeovers are gathered for before they are posted together
+	LeaderboardInterval time.Duration // Time between leaderboard checks, disabled if zero
+	LeaderboardSize     int           // Claimants shown on the leaderboard
+	CommandPrefix       string        // Prefix of commands, such as "!"
+}
+
+// DefaultOptions returns the standard bot options, without a token, channel or server
+func DefaultOptions() Options {
+	return Options{
+		AnnounceInterval:    5 * time.Second,
+		LeaderboardInterval: 10 * time.Minute,
+		LeaderboardSize:     5,
+		CommandPrefix:       "!",
+	}
+}
+
+// Validate checks that the bot options are usable
+func (o Options) Validate() error {
+	if o.Token == "" || o.ChannelID == "" || o.Server == "" {
+		return errors.New("discord token, channel and server are required")
+	}
+	if o.AnnounceInterval <= 0 || o.LeaderboardSize <= 0 {
+		return errors.New("discord announce interval and leaderboard size must be positive")
+	}
+	if o.LeaderboardInterval < 0 {
+		return errors.New("discord leaderboard interval must not be negative")
+	}
+	if o.CommandPrefix == "" {
+		return errors.New("discord command prefix must not be empty")
+	}
+	return nil
+}
+
+// maxAnnouncedTakeovers bounds the takeovers listed in one announcement
+const maxAnnouncedTakeovers = 10
+
+// Bot relays a SpaceNet server's activity to a Discord channel
+type Bot struct {
+	opts    Options
+	game    *gameClient
+	discord *restClient
+	logger  *slog.Logger
+
+	mu        sync.Mutex
+	takeovers []api.ClaimEvent // Takeovers waiting to be announced
+	leaders   []string         // Names on the last leaderboard posted
+}
+
+// New creates a bot
+func New(opts Options) (*Bot, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Bot{
+		opts:    opts,
+		game:    newGameClient(opts.Server),
+		discord: newRESTClient(opts.Token),
+		logger:  slog.Default().With("component", "discord"),
+	}, nil
+}
+
+// Run runs the bot until the context is canceled
+func (b *Bot) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	run := func(f func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(ctx)
+		}()
+	}
+
+	run(func(ctx context.Context) { b.game.followEvents(ctx, b.logger, b.handleEvent) })
+	run(b.announceTakeovers)
+	if b.opts.LeaderboardInterval > 0 {
+		run(b.postLeaderboardUpdates)
+	}
+	run(func(ctx context.Context) {
+		gw := &gateway{token: b.opts.Token, logger: b.logger}
+		gw.run(ctx, b.handleMessage)
+	})
+
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+// handleEvent queues takeovers from the event feed for the next announcement
+func (b *Bot) handleEvent(event api.ClaimEvent) {
+	if event.Type != api.EventTypeClaim || event.PreviousClaimant == "" || event.PreviousClaimant == event.Claimant {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.takeovers = append(b.takeovers, event)
+}
+
+// announceTakeovers posts the takeovers gathered in each interval as one
+// message, so bursts stay within Discord's rate limits
+func (b *Bot) announceTakeovers(ctx context.Context) {
+	ticker := time.NewTicker(b.opts.AnnounceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+			takeovers := b.takeovers
+			b.takeovers = nil
+			b.mu.Unlock()
+
+			if len(takeovers) == 0 {
+				continue
+			}
+			if err := b.discord.sendMessage(ctx, b.opts.ChannelID, formatTakeovers(takeovers)); err != nil {
+				b.logger.Warn("Failed to announce takeovers", "takeovers", len(takeovers), "error", err)
+			}
+		}
+	}
+}
+
+// postLeaderboardUpdates posts the leaderboard whenever its top claimants change
+func (b *Bot) postLeaderboardUpdates(ctx context.Context) {
+	ticker := time.NewTicker(b.opts.LeaderboardInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.updateLeaderboard(ctx); err != nil {
+				b.logger.Warn("Failed to update leaderboard", "error", err)
+			}
+		}
+	}
+}
+
+// updateLeaderboard posts the leaderboard if its order changed since it was last posted
+func (b *Bot) updateLeaderboard(ctx context.Context) error {
+	var entries []api.LeaderboardEntry
+	if err := b.game.get(ctx, fmt.Sprintf("/leaderboard?limit=%d", b.opts.LeaderboardSize), &entries); err != nil {
+		return err
+	}
+
+	leaders := make([]string, len(entries))
+	for i, entry := range entries {
+		leaders[i] = entry.Name
+	}
+
+	b.mu.Lock()
+	changed := !slices.Equal(leaders, b.leaders)
+	b.leaders = leaders
+	b.mu.Unlock()
+
+	if !changed || len(entries) == 0 {
+		return nil
+	}
+	return b.discord.sendMessage(ctx, b.opts.ChannelID, "**Leaderboard update**\n"+formatLeaderboard(entries))
+}
+
+// handleMessage answers a command posted in the bot's channel
+func (b *Bot) handleMessage(ctx context.Context, msg message) {
+	if msg.Author.Bot || msg.ChannelID != b.opts.ChannelID {
+		return
+	}
+	reply, ok := b.command(ctx, msg.Content)
+	if !ok {
+		return
+	}
+	if err := b.discord.sendMessage(ctx, msg.ChannelID, reply); err != nil {
+		b.logger.Warn("Failed to reply to command", "command", msg.Content, "error", err)
+	}
+}
+
+// formatTakeovers describes takeovers in one announcement
+func formatTakeovers(takeovers []api.ClaimEvent) string {
+	var sb strings.Builder
+	for i, event := range takeovers {
+		if i == maxAnnouncedTakeovers {
+			fmt.Fprintf(&sb, "…and %d more takeovers\n", len(takeovers)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "⚔️ **%s** took `%s` from **%s**\n",
+			escapeMarkdown(event.Claimant), event.IP, escapeMarkdown(event.PreviousClaimant))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// formatLeaderboard lists leaderboard entries, best first
+func formatLeaderboard(entries []api.LeaderboardEntry) string {
+	var sb strings.Builder
+	for i, entry := range entries {
+		fmt.Fprintf(&sb, "%d. **%s**: %d addresses\n", i+1, escapeMarkdown(entry.Name), entry.Addresses)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// markdownEscaper escapes the characters Discord treats as formatting
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`, "#", `\#`,
+)
+
+// escapeMarkdown makes a claimant name display literally
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,270 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// fakeDiscord records the messages posted to the Discord REST API
+type fakeDiscord struct {
+	mu       sync.Mutex
+	messages []createMessage
+	limited  int // Posts to answer as rate limited before accepting one
+}
+
+func (d *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bot token" || r.URL.Path != "/channels/channel/messages" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if d.limited > 0 {
+		d.limited--
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"retry_after": 0.01}`))
+		return
+	}
+
+	var msg createMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	d.messages = append(d.messages, msg)
+}
+
+// posted returns the contents of the messages posted so far
+func (d *fakeDiscord) posted() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	contents := make([]string, len(d.messages))
+	for i, msg := range d.messages {
+		contents[i] = msg.Content
+	}
+	return contents
+}
+
+// fakeGame serves the parts of the SpaceNet API the bot reads
+func fakeGame(leaderboard *[]api.LeaderboardEntry) http.Handler {
+	claimedAt := time.Unix(1700000000, 0)
+	routes := map[string]any{
+		"/api/v1/ip/2001:db8::1":       api.ClaimResponse{Name: "alice_*", Difficulty: 12, ClaimedAt: &claimedAt},
+		"/api/v1/subnet/2001:db8::/32": api.SubnetResponse{Owner: "bob", Percentage: 62.5},
+		"/api/v1/subnet/2001:db9::/32": api.SubnetResponse{},
+		"/api/v1/stats":                api.StatsResponse{TotalClaims: 42, Claimants: 3},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response any = routes[r.URL.Path]
+		if r.URL.Path == "/api/v1/leaderboard" {
+			response = *leaderboard
+		}
+		if response == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+// newTestBot creates a bot for fake SpaceNet and Discord servers
+func newTestBot(t *testing.T, leaderboard *[]api.LeaderboardEntry) (*Bot, *fakeDiscord) {
+	t.Helper()
+	game := httptest.NewServer(fakeGame(leaderboard))
+	t.Cleanup(game.Close)
+	discord := &fakeDiscord{}
+	rest := httptest.NewServer(discord)
+	t.Cleanup(rest.Close)
+
+	opts := DefaultOptions()
+	opts.Token = "token"
+	opts.ChannelID = "channel"
+	opts.Server = game.URL
+	bot, err := New(opts)
+	require.NoError(t, err)
+	bot.discord.baseURL = rest.URL
+	return bot, discord
+}
+
+// TestBot_Commands tests the replies to commands
+func TestBot_Commands(t *testing.T) {
+	leaderboard := []api.LeaderboardEntry{{Name: "bob", Addresses: 30}, {Name: "alice_*", Addresses: 12}}
+	bot, _ := newTestBot(t, &leaderboard)
+
+	tests := []struct {
+		content string
+		want    string // Substring of the reply, no reply if empty
+	}{
+		{"!owner 2001:db8::1", `is held by **alice\_\*** since <t:1700000000:R> (difficulty 12`},
+		{"!owner 2001:db8:0:0::2", "`2001:db8::2` is unclaimed."},
+		{"!owner 2001:db8::1/32", "`2001:db8::/32` is held by **bob** with 62.5%"},
+		{"!owner 2001:db9::/32", "`2001:db9::/32` is unclaimed."},
+		{"!owner 192.0.2.1", "is not an IPv6 address"},
+		{"!owner 10.0.0.0/8", "is not an IPv6 subnet"},
+		{"!owner", "Usage: `!owner"},
+		{"!stats", "**42** addresses claimed by **3** claimants.\n1. **bob**: 30 addresses\n2. **alice\\_\\***: 12"},
+		{"!help", ""},
+		{"owner 2001:db8::1", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			reply, ok := bot.command(context.Background(), tt.content)
+			if tt.want == "" {
+				assert.False(t, ok, "Message should not be a command")
+				return
+			}
+			require.True(t, ok)
+			assert.Contains(t, reply, tt.want)
+		})
+	}
+}
+
+// TestBot_Takeovers tests that only takeovers are announced, together
+func TestBot_Takeovers(t *testing.T) {
+	bot, discord := newTestBot(t, &[]api.LeaderboardEntry{})
+	bot.opts.AnnounceInterval = 10 * time.Millisecond
+
+	bot.handleEvent(api.ClaimEvent{Type: api.EventTypeClaim, IP: "2001:db8::1", Claimant: "alice"})
+	bot.handleEvent(api.ClaimEvent{Type: api.EventTypeUnclaim, IP: "2001:db8::2", Claimant: "bob"})
+	for i := range maxAnnouncedTakeovers + 2 {
+		bot.handleEvent(api.ClaimEvent{
+			Type:             api.EventTypeClaim,
+			IP:               fmt.Sprintf("2001:db8::%x", i),
+			Claimant:         "alice",
+			PreviousClaimant: "~bob~",
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.announceTakeovers(ctx)
+
+	require.Eventually(t, func() bool { return len(discord.posted()) > 0 }, 5*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	posted := discord.posted()
+	require.Len(t, posted, 1, "Takeovers should be announced in one message")
+
+	lines := strings.Split(posted[0], "\n")
+	assert.Len(t, lines, maxAnnouncedTakeovers+1)
+	assert.Equal(t, "⚔️ **alice** took `2001:db8::0` from **\\~bob\\~**", lines[0])
+	assert.Equal(t, "…and 2 more takeovers", lines[maxAnnouncedTakeovers])
+}
+
+// TestBot_Leaderboard tests that the leaderboard is posted only when its order changes
+func TestBot_Leaderboard(t *testing.T) {
+	leaderboard := []api.LeaderboardEntry{{Name: "alice", Addresses: 3}, {Name: "bob", Addresses: 2}}
+	bot, discord := newTestBot(t, &leaderboard)
+	ctx := context.Background()
+
+	require.NoError(t, bot.updateLeaderboard(ctx))
+	require.Len(t, discord.posted(), 1)
+	assert.Equal(t, "**Leaderboard update**\n1. **alice**: 3 addresses\n2. **bob**: 2 addresses", discord.posted()[0])
+
+	leaderboard[1].Addresses = 1
+	require.NoError(t, bot.updateLeaderboard(ctx))
+	assert.Len(t, discord.posted(), 1, "Counts alone should not be posted")
+
+	leaderboard[0], leaderboard[1] = leaderboard[1], leaderboard[0]
+	require.NoError(t, bot.updateLeaderboard(ctx))
+	assert.Len(t, discord.posted(), 2, "New order should be posted")
+}
+
+// TestRESTClient_SendMessage tests that messages wait out rate limits and notify no one
+func TestRESTClient_SendMessage(t *testing.T) {
+	discord := &fakeDiscord{limited: 1}
+	rest := httptest.NewServer(discord)
+	defer rest.Close()
+
+	client := newRESTClient("token")
+	client.baseURL = rest.URL
+	require.NoError(t, client.sendMessage(context.Background(), "channel", strings.Repeat("x", 3000)))
+
+	require.Len(t, discord.messages, 1)
+	assert.Len(t, []rune(discord.messages[0].Content), maxMessageLength, "Long messages should be truncated")
+	assert.NotNil(t, discord.messages[0].AllowedMentions.Parse)
+	assert.Empty(t, discord.messages[0].AllowedMentions.Parse, "Mentions should not notify anyone")
+
+	discord.limited = maxMessageAttempts
+	assert.Error(t, client.sendMessage(context.Background(), "channel", "hello"), "Posts should give up when rate limited")
+}
+
+// TestGateway_Session tests that a session identifies, heartbeats and passes on messages
+func TestGateway_Session(t *testing.T) {
+	identified := make(chan identifyData, 1)
+	heartbeats := make(chan struct{}, 1)
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		_ = websocket.JSON.Send(conn, gatewayCommand{Op: opHello, Data: helloData{HeartbeatInterval: 20}})
+
+		var identify struct {
+			Op   int          `json:"op"`
+			Data identifyData `json:"d"`
+		}
+		if err := websocket.JSON.Receive(conn, &identify); err != nil || identify.Op != opIdentify {
+			return
+		}
+		identified <- identify.Data
+
+		_ = websocket.JSON.Send(conn, map[string]any{
+			"op": opDispatch, "s": 1, "t": "MESSAGE_CREATE",
+			"d": map[string]any{"channel_id": "channel", "content": "!stats", "author": map[string]any{"id": "1"}},
+		})
+		for {
+			var payload gatewayPayload
+			if err := websocket.JSON.Receive(conn, &payload); err != nil {
+				return
+			}
+			if payload.Op == opHeartbeat {
+				assert.JSONEq(t, "1", string(payload.Data), "Heartbeats should carry the last sequence number")
+				select {
+				case heartbeats <- struct{}{}:
+				default:
+				}
+				_ = websocket.JSON.Send(conn, gatewayCommand{Op: opHeartbeatACK})
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := make(chan message, 1)
+	gw := &gateway{url: "ws" + strings.TrimPrefix(server.URL, "http"), token: "token", logger: slog.Default()}
+	go gw.run(ctx, func(_ context.Context, msg message) { messages <- msg })
+
+	select {
+	case identify := <-identified:
+		assert.Equal(t, "token", identify.Token)
+		assert.NotZero(t, identify.Intents&intentMessageContent, "Commands need the message content intent")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Gateway did not identify")
+	}
+	select {
+	case msg := <-messages:
+		assert.Equal(t, "channel", msg.ChannelID)
+		assert.Equal(t, "!stats", msg.Content)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Message was not passed on")
+	}
+	select {
+	case <-heartbeats:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Gateway did not heartbeat")
+	}
+}
@@ -0,0 +1,111 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// command answers a command, reporting whether the message was one
+func (b *Bot) command(ctx context.Context, content string) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(fields[0], b.opts.CommandPrefix)
+	if !ok {
+		return "", false
+	}
+	args := fields[1:]
+
+	var reply string
+	var err error
+	switch name {
+	case "owner":
+		if len(args) != 1 {
+			return fmt.Sprintf("Usage: `%sowner <address or subnet>`", b.opts.CommandPrefix), true
+		}
+		reply, err = b.owner(ctx, args[0])
+	case "stats":
+		reply, err = b.stats(ctx)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		b.logger.Warn("Failed to answer command", "command", name, "error", err)
+		return "Could not reach the SpaceNet server, try again later.", true
+	}
+	return reply, true
+}
+
+// owner describes who holds an address or subnet
+func (b *Bot) owner(ctx context.Context, target string) (string, error) {
+	if strings.Contains(target, "/") {
+		subnet, err := api.ParseSubnet(target)
+		if err != nil {
+			return fmt.Sprintf("`%s` is not an IPv6 subnet.", escapeCode(target)), nil
+		}
+		prefixLen, _ := subnet.Mask.Size()
+		cidr := api.CanonicalSubnet(subnet.IP, prefixLen)
+
+		var stats api.SubnetResponse
+		err = b.game.get(ctx, fmt.Sprintf("/subnet/%s/%d", url.PathEscape(subnet.IP.String()), prefixLen), &stats)
+		if err != nil {
+			return "", err
+		}
+		if stats.Owner == "" {
+			return fmt.Sprintf("`%s` is unclaimed.", cidr), nil
+		}
+		return fmt.Sprintf("`%s` is held by **%s** with %.1f%% of its addresses.",
+			cidr, escapeMarkdown(stats.Owner), stats.Percentage), nil
+	}
+
+	ip := net.ParseIP(target)
+	if ip == nil || ip.To4() != nil {
+		return fmt.Sprintf("`%s` is not an IPv6 address.", escapeCode(target)), nil
+	}
+
+	var claim api.ClaimResponse
+	err := b.game.get(ctx, "/ip/"+url.PathEscape(ip.String()), &claim)
+	if errors.Is(err, errNotFound) {
+		return fmt.Sprintf("`%s` is unclaimed.", ip), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	reply := fmt.Sprintf("`%s` is held by **%s**", ip, escapeMarkdown(claim.Name))
+	if claim.ClaimedAt != nil {
+		reply += fmt.Sprintf(" since <t:%d:R>", claim.ClaimedAt.Unix())
+	}
+	return reply + fmt.Sprintf(" (difficulty %d to take over).", claim.Difficulty), nil
+}
+
+// stats summarizes the game and its leaders
+func (b *Bot) stats(ctx context.Context) (string, error) {
+	var stats api.StatsResponse
+	if err := b.game.get(ctx, "/stats", &stats); err != nil {
+		return "", err
+	}
+	var entries []api.LeaderboardEntry
+	if err := b.game.get(ctx, fmt.Sprintf("/leaderboard?limit=%d", b.opts.LeaderboardSize), &entries); err != nil {
+		return "", err
+	}
+
+	reply := fmt.Sprintf("**%d** addresses claimed by **%d** claimants.", stats.TotalClaims, stats.Claimants)
+	if len(entries) > 0 {
+		reply += "\n" + formatLeaderboard(entries)
+	}
+	return reply, nil
+}
+
+// escapeCode keeps user input from closing an inline code span
+func escapeCode(s string) string {
+	return strings.ReplaceAll(s, "`", "'")
+}
@@ -0,0 +1,222 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Discord gateway the bot receives messages from
+const (
+	gatewayURL    = "wss://gateway.discord.gg/?v=10&encoding=json"
+	gatewayOrigin = "https://discord.com"
+)
+
+// Gateway opcodes the bot uses
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatACK   = 11
+)
+
+// Gateway intents for reading commands posted in guild channels
+const (
+	intentGuildMessages  = 1 << 9
+	intentMessageContent = 1 << 15
+)
+
+// Backoff bounds for reconnecting to the gateway
+const (
+	minGatewayReconnectDelay = time.Second
+	maxGatewayReconnectDelay = time.Minute
+)
+
+// gatewayPayload is a message received from the gateway
+type gatewayPayload struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+	Seq  *int64          `json:"s"`
+	Type string          `json:"t"`
+}
+
+// gatewayCommand is a message sent to the gateway
+type gatewayCommand struct {
+	Op   int `json:"op"`
+	Data any `json:"d"`
+}
+
+// helloData starts a gateway session
+type helloData struct {
+	HeartbeatInterval int64 `json:"heartbeat_interval"` // Milliseconds
+}
+
+// identifyData authenticates a gateway session
+type identifyData struct {
+	Token      string            `json:"token"`
+	Intents    int               `json:"intents"`
+	Properties map[string]string `json:"properties"`
+}
+
+// message is a message posted in a channel
+type message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID  string `json:"id"`
+		Bot bool   `json:"bot"`
+	} `json:"author"`
+}
+
+// gateway receives messages posted in the channels the bot can read
+type gateway struct {
+	url    string // Gateway URL, gatewayURL if empty
+	token  string
+	logger *slog.Logger
+}
+
+// run passes messages to handle until the context is canceled, reconnecting
+// with exponential backoff whenever the session ends
+func (g *gateway) run(ctx context.Context, handle func(context.Context, message)) {
+	delay := minGatewayReconnectDelay
+	for {
+		identified, err := g.session(ctx, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if identified {
+			delay = minGatewayReconnectDelay
+		}
+		g.logger.Warn("Gateway session ended", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxGatewayReconnectDelay)
+	}
+}
+
+// session runs one gateway session until it ends, reporting whether the bot identified
+func (g *gateway) session(ctx context.Context, handle func(context.Context, message)) (bool, error) {
+	url := g.url
+	if url == "" {
+		url = gatewayURL
+	}
+	config, err := websocket.NewConfig(url, gatewayOrigin)
+	if err != nil {
+		return false, err
+	}
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	var hello gatewayPayload
+	if err := websocket.JSON.Receive(conn, &hello); err != nil {
+		return false, err
+	}
+	if hello.Op != opHello {
+		return false, fmt.Errorf("expected hello, got opcode %d", hello.Op)
+	}
+	var data helloData
+	if err := json.Unmarshal(hello.Data, &data); err != nil || data.HeartbeatInterval <= 0 {
+		return false, errors.New("invalid hello")
+	}
+
+	var mu sync.Mutex // Guards writes and the state below
+	var seq *int64
+	acked := true
+	send := func(op int, data any) error {
+		return websocket.JSON.Send(conn, gatewayCommand{Op: op, Data: data})
+	}
+	heartbeat := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		acked = false
+		return send(opHeartbeat, seq)
+	}
+
+	mu.Lock()
+	err = send(opIdentify, identifyData{
+		Token:      g.token,
+		Intents:    intentGuildMessages | intentMessageContent,
+		Properties: map[string]string{"os": "linux", "browser": "spacenet", "device": "spacenet"},
+	})
+	mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(data.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				zombie := !acked
+				mu.Unlock()
+				// A connection that stopped acknowledging heartbeats is dead
+				if zombie || heartbeat() != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var payload gatewayPayload
+		if err := websocket.JSON.Receive(conn, &payload); err != nil {
+			return true, err
+		}
+
+		switch payload.Op {
+		case opDispatch:
+			mu.Lock()
+			seq = payload.Seq
+			mu.Unlock()
+			if payload.Type != "MESSAGE_CREATE" {
+				continue
+			}
+			var msg message
+			if err := json.Unmarshal(payload.Data, &msg); err != nil {
+				g.logger.Debug("Error decoding message", "error", err)
+				continue
+			}
+			go handle(ctx, msg)
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return true, err
+			}
+		case opHeartbeatACK:
+			mu.Lock()
+			acked = true
+			mu.Unlock()
+		case opReconnect:
+			return true, errors.New("gateway asked to reconnect")
+		case opInvalidSession:
+			return true, errors.New("gateway invalidated the session")
+		}
+	}
+}
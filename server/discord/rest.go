@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Discord REST API the bot posts messages with
+const restURL = "https://discord.com/api/v10"
+
+// Limits of posting a message
+const (
+	maxMessageLength   = 2000 // Characters Discord accepts in a message
+	maxMessageAttempts = 3    // Attempts to post a rate limited message
+)
+
+// restClient posts messages through the Discord REST API
+type restClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// newRESTClient creates a client authenticated as a bot
+func newRESTClient(token string) *restClient {
+	return &restClient{baseURL: restURL, token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// createMessage is the body of a message post
+type createMessage struct {
+	Content         string          `json:"content"`
+	AllowedMentions allowedMentions `json:"allowed_mentions"`
+}
+
+// allowedMentions lists the mentions in a message that notify anyone
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// rateLimited is the body of a rate limited response
+type rateLimited struct {
+	RetryAfter float64 `json:"retry_after"` // Seconds
+}
+
+// sendMessage posts a message to a channel, waiting out rate limits. Claimant
+// names are chosen by players, so no mention in a message notifies anyone.
+func (c *restClient) sendMessage(ctx context.Context, channelID, content string) error {
+	if runes := []rune(content); len(runes) > maxMessageLength {
+		content = string(runes[:maxMessageLength-1]) + "…"
+	}
+	body, err := json.Marshal(createMessage{Content: content, AllowedMentions: allowedMentions{Parse: []string{}}})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/channels/"+channelID+"/messages", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+c.token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		var limited rateLimited
+		if resp.StatusCode == http.StatusTooManyRequests {
+			_ = json.NewDecoder(resp.Body).Decode(&limited)
+		}
+		_ = resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode != http.StatusTooManyRequests || attempt == maxMessageAttempts:
+			return fmt.Errorf("discord returned status: %d", resp.StatusCode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(limited.RetryAfter * float64(time.Second))):
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package discord
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Backoff bounds for reconnecting to the event feed
+const (
+	minEventReconnectDelay = time.Second
+	maxEventReconnectDelay = 30 * time.Second
+)
+
+// errNotFound marks requests for something the server does not have
+var errNotFound = errors.New("not found")
+
+// gameClient reads from the API of a SpaceNet server
+type gameClient struct {
+	baseURL string       // URL of the versioned API
+	client  *http.Client // Client for requests, without a timeout for the event feed
+}
+
+// newGameClient creates a client for a server given as host[:port] or base URL
+func newGameClient(server string) *gameClient {
+	baseURL := strings.TrimSuffix(server, "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &gameClient{baseURL: baseURL + "/api/v1", client: &http.Client{}}
+}
+
+// get decodes the JSON response to a GET of an API path
+func (c *gameClient) get(ctx context.Context, path string, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("server returned status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// followEvents passes events from the server's event feed to handle until the
+// context is canceled, reconnecting with exponential backoff whenever the
+// stream drops
+func (c *gameClient) followEvents(ctx context.Context, logger *slog.Logger, handle func(api.ClaimEvent)) {
+	delay := minEventReconnectDelay
+	for {
+		connected, err := c.readEventStream(ctx, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minEventReconnectDelay
+		}
+		logger.Warn("Event stream disconnected", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxEventReconnectDelay)
+	}
+}
+
+// readEventStream reads server-sent events until the connection ends,
+// reporting whether the connection was established
+func (c *gameClient) readEventStream(ctx context.Context, handle func(api.ClaimEvent)) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events", nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("server returned status: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event api.ClaimEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		handle(event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, errors.New("stream closed by server")
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+)
+
+// Activity query failures
+var (
+	ErrActivityDisabled = errors.New("activity statistics are disabled")
+	errInvalidSubnet    = errors.New("invalid subnet")
+)
+
+// ActivityOptions configures the recent claim history behind subnet activity statistics
+type ActivityOptions struct {
+	Window   time.Duration `yaml:"window"`   // Rolling window statistics cover, in whole hours
+	Capacity int           `yaml:"capacity"` // Claims remembered, disabled if zero
+}
+
+// DefaultActivityOptions returns the standard activity options
+func DefaultActivityOptions() ActivityOptions {
+	return ActivityOptions{
+		Window:   24 * time.Hour,
+		Capacity: 100000,
+	}
+}
+
+// Enabled reports whether activity statistics are kept
+func (o ActivityOptions) Enabled() bool {
+	return o.Capacity > 0
+}
+
+// hours returns the length of the window in whole hours, rounding up
+func (o ActivityOptions) hours() int {
+	return max(1, int((o.Window+time.Hour-1)/time.Hour))
+}
+
+// maxHistoryClaims is the most claims on an address a history lists
+const maxHistoryClaims = 50
+
+// activityRecord is a claim that changed the owner of an address
+type activityRecord struct {
+	ip         [16]byte
+	claimant   string
+	previous   string // Claimant the address was taken from, empty if unclaimed
+	at         time.Time
+	difficulty uint8
+}
+
+// ActivityLog is a ring buffer of recent claims, summarized per subnet into
+// an hourly time series. Once the buffer is full the oldest claims are
+// overwritten, so under heavy load it covers less than the whole window.
+type ActivityLog struct {
+	mutex   sync.RWMutex
+	opts    ActivityOptions
+	records []activityRecord
+	next    int  // Index the next claim is written to
+	full    bool // Whether the buffer has wrapped around
+}
+
+// NewActivityLog creates an empty activity log
+func NewActivityLog(opts ActivityOptions) *ActivityLog {
+	return &ActivityLog{opts: opts}
+}
+
+// record remembers a claim event, ignoring releases
+func (l *ActivityLog) record(event api.ClaimEvent) {
+	if l == nil || event.Type != api.EventTypeClaim {
+		return
+	}
+	ip := net.ParseIP(event.IP)
+	if ip == nil {
+		return
+	}
+
+	rec := activityRecord{claimant: event.Claimant, previous: event.PreviousClaimant, at: event.Timestamp, difficulty: event.Difficulty}
+	copy(rec.ip[:], ip.To16())
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// The buffer grows up to its capacity, then wraps around
+	if !l.full && len(l.records) < l.opts.Capacity {
+		l.records = append(l.records, rec)
+	} else {
+		l.records[l.next] = rec
+	}
+	l.next++
+	if l.next == l.opts.Capacity {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// clear forgets every claim
+func (l *ActivityLog) clear() {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.records = nil
+	l.next = 0
+	l.full = false
+}
+
+// subnetActivity summarizes the claims in a subnet over the window ending at now
+func (l *ActivityLog) subnetActivity(subnet *net.IPNet, now time.Time) *api.SubnetActivityResponse {
+	hours := l.opts.hours()
+	start := now.Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+
+	response := &api.SubnetActivityResponse{
+		Subnet:      subnet.String(),
+		WindowHours: hours,
+		Hourly:      make([]api.ActivityBucket, hours),
+	}
+	for i := range response.Hourly {
+		response.Hourly[i].Start = start.Add(time.Duration(i) * time.Hour)
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	claimants := make(map[string]struct{})
+	oldest := now
+	for _, rec := range l.records {
+		oldest = minTime(oldest, rec.at)
+		if rec.at.Before(start) || !subnet.Contains(rec.ip[:]) {
+			continue
+		}
+
+		bucket := &response.Hourly[min(int(rec.at.Sub(start)/time.Hour), hours-1)]
+		bucket.Claims++
+		response.Claims++
+		if rec.previous != "" {
+			bucket.Takeovers++
+			response.Takeovers++
+		}
+		claimants[rec.claimant] = struct{}{}
+	}
+
+	response.UniqueClaimants = len(claimants)
+	response.ClaimsPerHour = float64(response.Claims) / float64(hours)
+	// Claims in the window were overwritten if the buffer wrapped within it
+	response.Truncated = l.full && oldest.After(start)
+
+	return response
+}
+
+// claimHistory lists the recent claims on an address, newest first
+func (l *ActivityLog) claimHistory(ip net.IP) *api.ClaimHistoryResponse {
+	var key [16]byte
+	copy(key[:], ip.To16())
+	response := &api.ClaimHistoryResponse{IP: ip.String(), Claims: make([]api.ClaimHistoryEntry, 0)}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	// Walk back from the newest record, wrapping around once full
+	for i := range len(l.records) {
+		rec := l.records[(l.next-1-i+len(l.records))%len(l.records)]
+		if rec.ip != key {
+			continue
+		}
+		if len(response.Claims) == maxHistoryClaims {
+			response.Truncated = true
+			break
+		}
+		response.Claims = append(response.Claims, api.ClaimHistoryEntry{
+			Claimant:         rec.claimant,
+			PreviousClaimant: rec.previous,
+			ClaimedAt:        rec.at,
+			Difficulty:       rec.difficulty,
+		})
+	}
+
+	// Claims on the address were overwritten if the oldest one listed was a takeover
+	if n := len(response.Claims); l.full && n > 0 && response.Claims[n-1].PreviousClaimant != "" {
+		response.Truncated = true
+	}
+	return response
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// SetActivityOptions replaces the activity log, forgetting recent claims.
+// Activity statistics are disabled if the capacity is zero.
+func (cs *ClaimStore) SetActivityOptions(opts ActivityOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.activity = nil
+	if opts.Enabled() {
+		cs.activity = NewActivityLog(opts)
+	}
+}
+
+// GetSubnetActivity summarizes recent claims in a subnet over the activity window
+func (cs *ClaimStore) GetSubnetActivity(ctx context.Context, subnet string) (*api.SubnetActivityResponse, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() != nil {
+		return nil, errInvalidSubnet
+	}
+
+	cs.mutex.RLock()
+	activity := cs.activity
+	cs.mutex.RUnlock()
+
+	if activity == nil {
+		return nil, ErrActivityDisabled
+	}
+	return activity.subnetActivity(ipNet, time.Now().UTC()), nil
+}
+
+// GetClaimHistory lists the recent claims on an address, newest first
+func (cs *ClaimStore) GetClaimHistory(ctx context.Context, ipAddr string) (*api.ClaimHistoryResponse, error) {
+	ip := net.ParseIP(ipAddr)
+	if ip == nil || ip.To4() != nil {
+		return nil, ErrInvalidAddress
+	}
+
+	cs.mutex.RLock()
+	activity := cs.activity
+	cs.mutex.RUnlock()
+
+	if activity == nil {
+		return nil, ErrActivityDisabled
+	}
+	return activity.claimHistory(ip), nil
+}
+
+// handleGetClaimHistory returns the recent claims on an address, so players
+// can gauge how contested it is
+func (h *HTTPHandler) handleGetClaimHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	history, err := h.store.GetClaimHistory(ctx, mux.Vars(r)["ip"])
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrActivityDisabled):
+		writeError(w, r, notFound(err.Error()))
+		return
+	default:
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// handleGetSubnetActivity returns recent claim activity in a subnet, so
+// players can find contested regions
+func (h *HTTPHandler) handleGetSubnetActivity(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	activity, err := h.store.GetSubnetActivity(ctx, vars["address"]+"/"+vars["prefix"])
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrActivityDisabled):
+		writeError(w, r, notFound(err.Error()))
+		return
+	default:
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(activity); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
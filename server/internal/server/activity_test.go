@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestActivityLog_SubnetActivity tests summarizing recent claims into an hourly series
+func TestActivityLog_SubnetActivity(t *testing.T) {
+	activityLog := NewActivityLog(ActivityOptions{Window: 3 * time.Hour, Capacity: 10})
+	now := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	claim := func(ip, claimant, previous string, at time.Time) {
+		activityLog.record(api.ClaimEvent{Type: api.EventTypeClaim, IP: ip, Claimant: claimant, PreviousClaimant: previous, Timestamp: at})
+	}
+
+	claim("2001:db8::1", "alice", "", now.Add(-5*time.Hour))
+	claim("2001:db8::1", "bob", "alice", now.Add(-2*time.Hour))
+	claim("2001:db8::2", "alice", "", now.Add(-time.Hour))
+	claim("2001:db8::1", "alice", "bob", now)
+	claim("2001:db9::1", "carol", "", now)
+	activityLog.record(api.ClaimEvent{Type: api.EventTypeUnclaim, IP: "2001:db8::2", PreviousClaimant: "alice", Timestamp: now})
+
+	_, subnet, _ := net.ParseCIDR("2001:db8::/32")
+	activity := activityLog.subnetActivity(subnet, now)
+	assert.Equal(t, 3, activity.WindowHours)
+	assert.Equal(t, 3, activity.Claims, "Claims outside the window or subnet should not count")
+	assert.Equal(t, 2, activity.Takeovers)
+	assert.Equal(t, 2, activity.UniqueClaimants)
+	assert.InDelta(t, 1.0, activity.ClaimsPerHour, 1e-9)
+	assert.False(t, activity.Truncated)
+
+	require.Len(t, activity.Hourly, 3)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), activity.Hourly[0].Start, "Series should start at the oldest hour")
+	for i, bucket := range activity.Hourly {
+		assert.Equal(t, 1, bucket.Claims, "Hour %d should have one claim", i)
+	}
+	assert.Equal(t, 0, activity.Hourly[1].Takeovers)
+	assert.Equal(t, 1, activity.Hourly[2].Takeovers)
+}
+
+// TestActivityLog_Wraps tests that the ring buffer forgets the oldest claims
+func TestActivityLog_Wraps(t *testing.T) {
+	activityLog := NewActivityLog(ActivityOptions{Window: time.Hour, Capacity: 3})
+	now := time.Now().UTC()
+	for i := range 5 {
+		activityLog.record(api.ClaimEvent{Type: api.EventTypeClaim, IP: "2001:db8::1", Claimant: string(rune('a' + i)), Timestamp: now})
+	}
+
+	_, subnet, _ := net.ParseCIDR("2001:db8::/64")
+	activity := activityLog.subnetActivity(subnet, now)
+	assert.Equal(t, 3, activity.Claims, "Only the newest claims should be kept")
+	assert.Equal(t, 3, activity.UniqueClaimants)
+	assert.True(t, activity.Truncated, "Forgotten claims in the window should be reported")
+
+	activityLog.clear()
+	assert.Zero(t, activityLog.subnetActivity(subnet, now).Claims, "Cleared log should be empty")
+}
+
+// TestHTTPHandler_SubnetActivity tests the subnet activity endpoint
+func TestHTTPHandler_SubnetActivity(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::1", Claimant: "bob"}}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/api/v1/subnet/2001:db8::/48/activity")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var activity api.SubnetActivityResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&activity))
+	assert.Equal(t, "2001:db8::/48", activity.Subnet)
+	assert.Equal(t, 2, activity.Claims, "Single and batch claims should be recorded")
+	assert.Equal(t, 1, activity.Takeovers)
+	assert.Equal(t, 2, activity.UniqueClaimants)
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/subnet/10.0.0.0/8/activity").Code, "IPv4 subnets should be rejected")
+
+	store.SetActivityOptions(ActivityOptions{})
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/subnet/2001:db8::/48/activity").Code, "Disabled activity should not be found")
+}
+
+// TestActivityLog_ClaimHistory tests listing the claims on an address newest first
+func TestActivityLog_ClaimHistory(t *testing.T) {
+	activityLog := NewActivityLog(ActivityOptions{Window: time.Hour, Capacity: 4})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	claim := func(ip, claimant, previous string, at time.Time, difficulty uint8) {
+		activityLog.record(api.ClaimEvent{Type: api.EventTypeClaim, IP: ip, Claimant: claimant, PreviousClaimant: previous, Timestamp: at, Difficulty: difficulty})
+	}
+
+	claim("2001:db8::1", "alice", "", now, 16)
+	claim("2001:db8::2", "carol", "", now, 16)
+	claim("2001:db8::1", "bob", "alice", now.Add(time.Minute), 24)
+	history := activityLog.claimHistory(net.ParseIP("2001:db8::1"))
+	assert.Equal(t, "2001:db8::1", history.IP)
+	assert.Equal(t, []api.ClaimHistoryEntry{
+		{Claimant: "bob", PreviousClaimant: "alice", ClaimedAt: now.Add(time.Minute), Difficulty: 24},
+		{Claimant: "alice", ClaimedAt: now, Difficulty: 16},
+	}, history.Claims, "Claims on the address should be listed newest first")
+	assert.False(t, history.Truncated)
+
+	// Wrapping around overwrites alice's first claim
+	claim("2001:db8::1", "alice", "bob", now.Add(2*time.Minute), 25)
+	claim("2001:db8::1", "bob", "alice", now.Add(3*time.Minute), 26)
+	history = activityLog.claimHistory(net.ParseIP("2001:0db8:0000::1"))
+	require.Len(t, history.Claims, 3)
+	assert.Equal(t, []string{"bob", "alice", "bob"}, []string{history.Claims[0].Claimant, history.Claims[1].Claimant, history.Claims[2].Claimant})
+	assert.True(t, history.Truncated, "Forgotten claims on the address should be reported")
+
+	assert.Empty(t, activityLog.claimHistory(net.ParseIP("2001:db8::3")).Claims, "Unclaimed addresses should have no history")
+}
+
+// TestHTTPHandler_ClaimHistory tests the claim history endpoint
+func TestHTTPHandler_ClaimHistory(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::1", Claimant: "bob"}}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/api/v1/ip/2001:db8::1/history")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var history api.ClaimHistoryResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&history))
+	require.Len(t, history.Claims, 2, "Single and batch claims should be recorded")
+	assert.Equal(t, "bob", history.Claims[0].Claimant)
+	assert.Equal(t, "alice", history.Claims[0].PreviousClaimant)
+	base := DefaultDifficultyParams().Base
+	assert.Equal(t, uint8(base+DefaultDifficultyParams().ClaimBonus), history.Claims[0].Difficulty, "Takeovers should cost the claim bonus")
+	assert.Equal(t, uint8(base), history.Claims[1].Difficulty, "Unclaimed addresses should cost the base difficulty")
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/ip/192.0.2.1/history").Code, "IPv4 addresses should be rejected")
+
+	store.SetActivityOptions(ActivityOptions{})
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/ip/2001:db8::1/history").Code, "Disabled activity should not be found")
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// requireAdmin wraps a handler, only allowing requests bearing a configured admin token
+func (h *HTTPHandler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(h.adminTokens) == 0 {
+			// Admin endpoints are disabled without configured tokens
+			writeError(w, r, forbidden("admin endpoints are disabled"))
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok || !h.isAdminToken(token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, unauthorized("missing or invalid admin token"))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// isAdminToken reports whether token matches one of the configured admin tokens
+func (h *HTTPHandler) isAdminToken(token string) bool {
+	valid := false
+	for _, adminToken := range h.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// handleAdminGetAllClaims returns every claim in the store
+func (h *HTTPHandler) handleAdminGetAllClaims(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	claims := h.store.GetAllClaims(ctx)
+
+	stream := newStreamJSON(w)
+	for ip, claimant := range claims {
+		stream.field(ip, claimant)
+	}
+	if err := stream.closeObject(); err != nil {
+		h.logger.Error("Error streaming JSON response", "error", err)
+	}
+}
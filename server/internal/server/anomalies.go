@@ -0,0 +1,506 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+)
+
+// Actions taken on claimants whose claims look like abuse
+const (
+	AnomalyActionLog        = "log"        // Record the anomaly only
+	AnomalyActionDifficulty = "difficulty" // Raise the difficulty of the claimant's claims for a while
+	AnomalyActionQuarantine = "quarantine" // Refuse the claimant's claims until an admin releases them
+)
+
+// AnomalyOptions configures watching claims for patterns of abuse, such as
+// one client claiming across the whole space or proofs of work solved faster
+// than any player could, and what is done to the claimants responsible
+type AnomalyOptions struct {
+	Enabled         bool          `yaml:"enabled"`
+	Action          string        `yaml:"action"`          // "log", "difficulty" or "quarantine"
+	Window          time.Duration `yaml:"window"`          // Time over which claims are watched
+	MaxSpread       int           `yaml:"maxSpread"`       // Most subnets one client address may claim in within the window, 0 disables
+	SpreadPrefix    int           `yaml:"spreadPrefix"`    // Prefix length of the subnets counted by maxSpread
+	MaxHashRate     int           `yaml:"maxHashRate"`     // Most hashes per second a claimant's proofs of work may imply, 0 disables
+	Penalty         int           `yaml:"penalty"`         // Difficulty added by the difficulty action
+	PenaltyDuration time.Duration `yaml:"penaltyDuration"` // How long the difficulty action lasts
+	Capacity        int           `yaml:"capacity"`        // Anomalies remembered for the admin API
+}
+
+// DefaultAnomalyOptions returns the standard anomaly options, disabled until enabled
+func DefaultAnomalyOptions() AnomalyOptions {
+	return AnomalyOptions{
+		Action:          AnomalyActionDifficulty,
+		Window:          10 * time.Minute,
+		MaxSpread:       64,
+		SpreadPrefix:    32,
+		MaxHashRate:     1_000_000_000,
+		Penalty:         4,
+		PenaltyDuration: time.Hour,
+		Capacity:        1000,
+	}
+}
+
+// Validate checks that the anomaly options are usable
+func (o AnomalyOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	switch o.Action {
+	case AnomalyActionLog, AnomalyActionQuarantine:
+	case AnomalyActionDifficulty:
+		if o.Penalty <= 0 || o.Penalty > 64 || o.PenaltyDuration <= 0 {
+			return errors.New("anomalies penalty must be between 1 and 64 and penaltyDuration positive")
+		}
+	default:
+		return fmt.Errorf("unknown anomalies action %q, expected log, difficulty or quarantine", o.Action)
+	}
+	if o.Window <= 0 || o.Capacity <= 0 {
+		return errors.New("anomalies window and capacity must be positive")
+	}
+	if o.MaxSpread < 0 || o.MaxHashRate < 0 {
+		return errors.New("anomalies maxSpread and maxHashRate must not be negative")
+	}
+	if o.SpreadPrefix < 1 || o.SpreadPrefix > 128 {
+		return fmt.Errorf("anomalies spreadPrefix must be between 1 and 128, got %d", o.SpreadPrefix)
+	}
+	return nil
+}
+
+// maxBonus returns the most difficulty anomalies can add
+func (o AnomalyOptions) maxBonus() int {
+	if !o.Enabled || o.Action != AnomalyActionDifficulty {
+		return 0
+	}
+	return o.Penalty
+}
+
+// ErrQuarantined refuses the claims of a claimant held for review after an anomaly
+var ErrQuarantined = errors.New("claimant is quarantined pending review")
+
+// ClaimObservation is a claim applied to a store, shown to anomaly detectors
+type ClaimObservation struct {
+	IP         string
+	Claimant   string
+	Source     ClaimSource // Empty if sources are not recorded
+	Difficulty uint8       // Of the address before the claim
+	At         time.Time
+}
+
+// AnomalyDetector watches the claims applied to a store for a suspicious
+// pattern. Detectors are shown one claim at a time, so need no locks of their
+// own, while the claim holds the store, so must be quick.
+type AnomalyDetector interface {
+	// Name identifies the detector in anomaly events
+	Name() string
+
+	// Observe records a claim, describing the anomaly it completes, if any
+	Observe(claim ClaimObservation) (string, bool)
+}
+
+// anomalySweepInterval is the number of claims between sweeps of state detectors no longer need
+const anomalySweepInterval = 1024
+
+// spreadDetector flags client addresses claiming in more subnets within a
+// window than a player could, as scripts sweeping the space do
+type spreadDetector struct {
+	window   time.Duration
+	max      int
+	prefix   int
+	sources  map[string]map[[net.IPv6len]byte]time.Time // Subnets claimed in by client address, when last
+	observed int                                        // Claims since the last sweep
+}
+
+// newSpreadDetector creates a detector of clients claiming in more than limit subnets within a window
+func newSpreadDetector(window time.Duration, limit int, prefix int) *spreadDetector {
+	return &spreadDetector{
+		window:  window,
+		max:     limit,
+		prefix:  prefix,
+		sources: make(map[string]map[[net.IPv6len]byte]time.Time),
+	}
+}
+
+// Name identifies the detector
+func (d *spreadDetector) Name() string {
+	return "spread"
+}
+
+// Observe records the subnet a client claimed in
+func (d *spreadDetector) Observe(claim ClaimObservation) (string, bool) {
+	d.sweep(claim.At)
+	ip := net.ParseIP(claim.IP)
+	if claim.Source.RemoteIP == "" || ip == nil {
+		return "", false
+	}
+
+	var subnet [net.IPv6len]byte
+	copy(subnet[:], ip.Mask(net.CIDRMask(d.prefix, 128)))
+	subnets := d.sources[claim.Source.RemoteIP]
+	if subnets == nil {
+		subnets = make(map[[net.IPv6len]byte]time.Time)
+		d.sources[claim.Source.RemoteIP] = subnets
+	}
+	subnets[subnet] = claim.At
+	for key, at := range subnets {
+		if claim.At.Sub(at) > d.window {
+			delete(subnets, key)
+		}
+	}
+	if len(subnets) <= d.max {
+		return "", false
+	}
+
+	// Start counting again, so the client is flagged again only for as many more
+	delete(d.sources, claim.Source.RemoteIP)
+	return fmt.Sprintf("%s claimed in %d /%d subnets within %s", claim.Source.RemoteIP, len(subnets), d.prefix, d.window), true
+}
+
+// sweep forgets clients that have not claimed within the window, now and then
+func (d *spreadDetector) sweep(now time.Time) {
+	if d.observed++; d.observed < anomalySweepInterval {
+		return
+	}
+	d.observed = 0
+	for source, subnets := range d.sources {
+		for key, at := range subnets {
+			if now.Sub(at) > d.window {
+				delete(subnets, key)
+			}
+		}
+		if len(subnets) == 0 {
+			delete(d.sources, source)
+		}
+	}
+}
+
+// claimantWork is the work a claimant's proofs of work took, decaying over time
+type claimantWork struct {
+	hashes float64
+	at     time.Time
+}
+
+// hashRateDetector flags claimants whose proofs of work imply more hashes
+// per second than a player could compute. The work of each claimant decays
+// with a half-life of the window, so it settles at the hash rate times the
+// window over ln 2.
+type hashRateDetector struct {
+	window    time.Duration
+	max       float64
+	claimants map[string]claimantWork
+	observed  int // Claims since the last sweep
+}
+
+// newHashRateDetector creates a detector of claimants solving more than limit hashes per second
+func newHashRateDetector(window time.Duration, limit int) *hashRateDetector {
+	return &hashRateDetector{window: window, max: float64(limit), claimants: make(map[string]claimantWork)}
+}
+
+// Name identifies the detector
+func (d *hashRateDetector) Name() string {
+	return "hashRate"
+}
+
+// current returns the work of a claimant at a time, after decay
+func (d *hashRateDetector) current(work claimantWork, now time.Time) float64 {
+	halfLives := now.Sub(work.at).Seconds() / d.window.Seconds()
+	return work.hashes * math.Exp2(-max(halfLives, 0))
+}
+
+// Observe adds the work of a claim to its claimant's
+func (d *hashRateDetector) Observe(claim ClaimObservation) (string, bool) {
+	d.sweep(claim.At)
+	// Claims over UDP carry no proof of work, and claims admitted by a session
+	// challenge a discounted one, which the session's quota bounds instead
+	if claim.Source.Channel == claimChannelUDP || claim.Source.Channel == claimChannelSession {
+		return "", false
+	}
+
+	hashes := d.current(d.claimants[claim.Claimant], claim.At) + math.Exp2(float64(claim.Difficulty))
+	rate := hashes * math.Ln2 / d.window.Seconds()
+	if rate <= d.max {
+		d.claimants[claim.Claimant] = claimantWork{hashes: hashes, at: claim.At}
+		return "", false
+	}
+
+	delete(d.claimants, claim.Claimant)
+	return fmt.Sprintf("proofs of work imply %.3g hashes per second, more than %.3g", rate, d.max), true
+}
+
+// sweep forgets claimants whose work has decayed away, now and then
+func (d *hashRateDetector) sweep(now time.Time) {
+	if d.observed++; d.observed < anomalySweepInterval {
+		return
+	}
+	d.observed = 0
+	for claimant, work := range d.claimants {
+		if d.current(work, now) < 1 {
+			delete(d.claimants, claimant)
+		}
+	}
+}
+
+// flaggedClaimant is a claimant penalized or quarantined after an anomaly
+type flaggedClaimant struct {
+	claimant    string
+	quarantined bool
+	penalty     int
+	since       time.Time
+	until       time.Time // When the penalty lifts, zero for quarantines
+}
+
+// active reports whether the flag still applies at a time
+func (f flaggedClaimant) active(now time.Time) bool {
+	return f.quarantined || now.Before(f.until)
+}
+
+// anomalyMonitor shows claims to anomaly detectors, acting on the claimants
+// of the anomalies they find and remembering them for admins to review
+type anomalyMonitor struct {
+	mutex     sync.Mutex
+	opts      AnomalyOptions
+	detectors []AnomalyDetector
+	events    []api.AnomalyEvent         // Oldest first, up to the capacity
+	flagged   map[string]flaggedClaimant // By canonical skeleton of the claimant's name
+	logger    *slog.Logger
+}
+
+// newAnomalyMonitor creates a monitor with the detectors the options enable
+func newAnomalyMonitor(opts AnomalyOptions) *anomalyMonitor {
+	m := &anomalyMonitor{
+		opts:    opts,
+		flagged: make(map[string]flaggedClaimant),
+		logger:  componentLogger("anomalies"),
+	}
+	if opts.MaxSpread > 0 {
+		m.detectors = append(m.detectors, newSpreadDetector(opts.Window, opts.MaxSpread, opts.SpreadPrefix))
+	}
+	if opts.MaxHashRate > 0 {
+		m.detectors = append(m.detectors, newHashRateDetector(opts.Window, opts.MaxHashRate))
+	}
+	return m
+}
+
+// observe shows a claim to every detector, acting on the anomalies found. A
+// nil monitor watches nothing.
+func (m *anomalyMonitor) observe(claim ClaimObservation) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, detector := range m.detectors {
+		detail, found := detector.Observe(claim)
+		if !found {
+			continue
+		}
+		// Claimants already flagged are not flagged again until the flag lifts
+		skeleton := nameSkeleton(claim.Claimant)
+		if flag, ok := m.flagged[skeleton]; ok && flag.active(claim.At) {
+			continue
+		}
+
+		switch m.opts.Action {
+		case AnomalyActionDifficulty:
+			m.flagged[skeleton] = flaggedClaimant{
+				claimant: claim.Claimant,
+				penalty:  m.opts.Penalty,
+				since:    claim.At,
+				until:    claim.At.Add(m.opts.PenaltyDuration),
+			}
+		case AnomalyActionQuarantine:
+			m.flagged[skeleton] = flaggedClaimant{claimant: claim.Claimant, quarantined: true, since: claim.At}
+		}
+
+		event := api.AnomalyEvent{
+			Detector:  detector.Name(),
+			Claimant:  claim.Claimant,
+			RemoteIP:  claim.Source.RemoteIP,
+			Detail:    detail,
+			Action:    m.opts.Action,
+			Timestamp: claim.At,
+		}
+		m.events = append(m.events, event)
+		if len(m.events) > m.opts.Capacity {
+			m.events = slices.Delete(m.events, 0, len(m.events)-m.opts.Capacity)
+		}
+		m.logger.Warn("Anomalous claims", "detector", event.Detector, "claimant", event.Claimant,
+			"remote_ip", event.RemoteIP, "detail", event.Detail, "action", event.Action)
+	}
+}
+
+// check refuses the claims of quarantined claimants
+func (m *anomalyMonitor) check(claimant string) error {
+	if m == nil {
+		return nil
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.flagged[nameSkeleton(claimant)].quarantined {
+		return ErrQuarantined
+	}
+	return nil
+}
+
+// penalty returns the difficulty added to a claimant's claims at a time
+func (m *anomalyMonitor) penalty(claimant string, now time.Time) int {
+	if m == nil {
+		return 0
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if flag := m.flagged[nameSkeleton(claimant)]; now.Before(flag.until) {
+		return flag.penalty
+	}
+	return 0
+}
+
+// report lists the recent anomalies, newest first, and the claimants still flagged at a time
+func (m *anomalyMonitor) report(now time.Time) api.AnomaliesResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := api.AnomaliesResponse{Events: slices.Clone(m.events), Flagged: []api.FlaggedClaimant{}}
+	slices.Reverse(response.Events)
+	if response.Events == nil {
+		response.Events = []api.AnomalyEvent{}
+	}
+	for skeleton, flag := range m.flagged {
+		if !flag.active(now) {
+			delete(m.flagged, skeleton)
+			continue
+		}
+		flagged := api.FlaggedClaimant{Claimant: flag.claimant, Quarantined: flag.quarantined, Penalty: flag.penalty, Since: flag.since}
+		if !flag.quarantined {
+			flagged.Until = &flag.until
+		}
+		response.Flagged = append(response.Flagged, flagged)
+	}
+	slices.SortFunc(response.Flagged, func(a, b api.FlaggedClaimant) int {
+		return cmp.Or(a.Since.Compare(b.Since), cmp.Compare(a.Claimant, b.Claimant))
+	})
+	return response
+}
+
+// release lifts the penalty or quarantine of a claimant, reporting whether they were flagged
+func (m *anomalyMonitor) release(claimant string, now time.Time) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	skeleton := nameSkeleton(claimant)
+	flag, ok := m.flagged[skeleton]
+	delete(m.flagged, skeleton)
+	return ok && flag.active(now)
+}
+
+// SetAnomalyOptions replaces the options used to watch claims for abuse,
+// forgetting past anomalies and flags. Detectors added by AddAnomalyDetector
+// must be added again.
+func (cs *ClaimStore) SetAnomalyOptions(opts AnomalyOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.anomalies = nil
+	if opts.Enabled {
+		cs.anomalies = newAnomalyMonitor(opts)
+	}
+}
+
+// AddAnomalyDetector adds a detector to those watching claims, acted on as
+// the built-in ones are. It is ignored unless anomaly detection is enabled.
+func (cs *ClaimStore) AddAnomalyDetector(detector AnomalyDetector) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if cs.anomalies == nil {
+		return
+	}
+	cs.anomalies.mutex.Lock()
+	defer cs.anomalies.mutex.Unlock()
+
+	cs.anomalies.detectors = append(cs.anomalies.detectors, detector)
+}
+
+// DifficultyPenalty returns the difficulty added to a claimant's claims after an anomaly
+func (cs *ClaimStore) DifficultyPenalty(claimant string) int {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	return cs.anomalies.penalty(claimant, time.Now().UTC())
+}
+
+// Anomalies lists the recent anomalies and flagged claimants, reporting
+// whether anomaly detection is enabled
+func (cs *ClaimStore) Anomalies() (api.AnomaliesResponse, bool) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	if cs.anomalies == nil {
+		return api.AnomaliesResponse{}, false
+	}
+	return cs.anomalies.report(time.Now().UTC()), true
+}
+
+// ReleaseClaimant lifts the penalty or quarantine of a claimant, reporting whether they were flagged
+func (cs *ClaimStore) ReleaseClaimant(claimant string) bool {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	if cs.anomalies == nil {
+		return false
+	}
+	return cs.anomalies.release(claimant, time.Now().UTC())
+}
+
+// anomalyReviewer is implemented by stores that watch claims for abuse
+type anomalyReviewer interface {
+	DifficultyPenalty(claimant string) int
+	Anomalies() (api.AnomaliesResponse, bool)
+	ReleaseClaimant(claimant string) bool
+}
+
+// handleAdminGetAnomalies lists the recent anomalies and flagged claimants
+func (h *HTTPHandler) handleAdminGetAnomalies(w http.ResponseWriter, r *http.Request) {
+	reviewer, ok := h.store.(anomalyReviewer)
+	if !ok {
+		writeError(w, r, notFound("anomaly detection is disabled"))
+		return
+	}
+	response, enabled := reviewer.Anomalies()
+	if !enabled {
+		writeError(w, r, notFound("anomaly detection is disabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminReleaseClaimant lifts the penalty or quarantine of a claimant after review
+func (h *HTTPHandler) handleAdminReleaseClaimant(w http.ResponseWriter, r *http.Request) {
+	reviewer, ok := h.store.(anomalyReviewer)
+	if !ok || !reviewer.ReleaseClaimant(mux.Vars(r)["name"]) {
+		writeError(w, r, notFound("claimant is not flagged"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anomalyStore creates a store watching claims for anomalies with modified default options
+func anomalyStore(t *testing.T, modify func(*AnomalyOptions)) *ClaimStore {
+	t.Helper()
+	opts := DefaultAnomalyOptions()
+	opts.Enabled = true
+	modify(&opts)
+	require.NoError(t, opts.Validate())
+
+	store := NewClaimStore()
+	store.SetAnomalyOptions(opts)
+	return store
+}
+
+// TestAnomalies_Spread tests that a client claiming across too many subnets
+// gets its claimant quarantined until an admin releases them
+func TestAnomalies_Spread(t *testing.T) {
+	store := anomalyStore(t, func(o *AnomalyOptions) {
+		o.Action = AnomalyActionQuarantine
+		o.MaxSpread = 3
+		o.SpreadPrefix = 48
+		o.MaxHashRate = 0
+	})
+	script := withClaimSource(t.Context(), ClaimSource{Channel: claimChannelHTTP, RemoteIP: "2001:db8:ffff::1"})
+	player := withClaimSource(t.Context(), ClaimSource{Channel: claimChannelHTTP, RemoteIP: "2001:db8:ffff::2"})
+
+	for i := range 3 {
+		require.NoError(t, store.ProcessClaim(script, fmt.Sprintf("2001:db8:%x::1", i), "mallory"))
+		require.NoError(t, store.ProcessClaim(player, fmt.Sprintf("2001:db9::%x", i+1), "alice"), "Claims within one subnet are fine")
+	}
+	require.NoError(t, store.ProcessClaim(script, "2001:db8:ffff::1", "mallory"),
+		"The claim completing the pattern should be applied")
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::2", "mallory"), ErrQuarantined)
+	assert.ErrorIs(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::3", Claimant: "Mallory"}}), ErrQuarantined,
+		"Quarantines should cover names that look alike")
+	require.NoError(t, store.ProcessClaim(player, "2001:db9::9", "alice"), "Other claimants should be unaffected")
+
+	report, enabled := store.Anomalies()
+	require.True(t, enabled)
+	require.Len(t, report.Events, 1)
+	assert.Equal(t, "spread", report.Events[0].Detector)
+	assert.Equal(t, "mallory", report.Events[0].Claimant)
+	assert.Equal(t, "2001:db8:ffff::1", report.Events[0].RemoteIP)
+	assert.Equal(t, AnomalyActionQuarantine, report.Events[0].Action)
+	require.Len(t, report.Flagged, 1)
+	assert.True(t, report.Flagged[0].Quarantined)
+	assert.Nil(t, report.Flagged[0].Until, "Quarantines should last until released")
+
+	assert.True(t, store.ReleaseClaimant("mallory"))
+	assert.False(t, store.ReleaseClaimant("mallory"), "Released claimants are no longer flagged")
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "mallory"), "Released claimants should claim again")
+}
+
+// TestAnomalies_HashRate tests that claimants solving proofs of work faster
+// than possible must solve harder ones for a while
+func TestAnomalies_HashRate(t *testing.T) {
+	store := anomalyStore(t, func(o *AnomalyOptions) {
+		o.MaxSpread = 0
+		o.Window = time.Hour
+		o.MaxHashRate = 1 // Each claim at the base difficulty of 8 implies 256 ln 2 / 3600 ≈ 0.049 hashes per second
+	})
+	udp := withClaimSource(t.Context(), ClaimSource{Channel: claimChannelUDP, RemoteIP: "2001:db8::100"})
+	for i := range 40 {
+		require.NoError(t, store.ProcessClaim(udp, fmt.Sprintf("2001:db8::%x", i+0x100), "bob"))
+	}
+	assert.Zero(t, store.DifficultyPenalty("bob"), "Claims over UDP carry no proof of work")
+
+	for i := range 20 {
+		require.NoError(t, store.ProcessClaim(t.Context(), fmt.Sprintf("2001:db8::%x", i+1), "alice"))
+	}
+	assert.Zero(t, store.DifficultyPenalty("alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::99", "alice"))
+	assert.Equal(t, DefaultAnomalyOptions().Penalty, store.DifficultyPenalty("alice"), "Alice should be penalized")
+	assert.Equal(t, DefaultAnomalyOptions().Penalty, store.DifficultyPenalty("ALICE"))
+
+	// Proofs of work at the address's difficulty no longer do
+	difficulty := store.CalculateDifficulty(t.Context(), "2001:db8::1:1")
+	pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::1:1"), "alice", difficulty, 1000000)
+	require.NoError(t, err)
+	if !pow.IsValid(difficulty + uint8(store.DifficultyPenalty("alice"))) {
+		assert.Error(t, store.ValidateProofOfWork(t.Context(), pow), "Penalized claimants should solve harder proofs of work")
+	}
+	pow, err = api.SolveProofOfWork(net.ParseIP("2001:db8::1:1"), "alice", difficulty+uint8(store.DifficultyPenalty("alice")), 10000000)
+	require.NoError(t, err)
+	assert.NoError(t, store.ValidateProofOfWork(t.Context(), pow))
+
+	report, _ := store.Anomalies()
+	require.Len(t, report.Events, 1)
+	assert.Equal(t, "hashRate", report.Events[0].Detector)
+	require.Len(t, report.Flagged, 1)
+	require.NotNil(t, report.Flagged[0].Until)
+	assert.Equal(t, report.Flagged[0].Since.Add(time.Hour), *report.Flagged[0].Until)
+}
+
+// customDetector flags every claim by one claimant
+type customDetector struct {
+	claimant string
+}
+
+func (d customDetector) Name() string { return "custom" }
+
+func (d customDetector) Observe(claim ClaimObservation) (string, bool) {
+	return "watched claimant", claim.Claimant == d.claimant
+}
+
+// TestAnomalies_CustomDetector tests that added detectors are acted on, and
+// anomalies are only logged by the log action
+func TestAnomalies_CustomDetector(t *testing.T) {
+	store := anomalyStore(t, func(o *AnomalyOptions) { o.Action = AnomalyActionLog; o.Capacity = 2 })
+	store.AddAnomalyDetector(customDetector{claimant: "eve"})
+
+	for i := range 3 {
+		require.NoError(t, store.ProcessClaim(t.Context(), fmt.Sprintf("2001:db8::%x", i+1), "eve"))
+	}
+	report, _ := store.Anomalies()
+	assert.Len(t, report.Events, 2, "Only the latest anomalies should be kept")
+	assert.Equal(t, "custom", report.Events[0].Detector)
+	assert.Empty(t, report.Flagged, "Logged anomalies should not flag the claimant")
+
+	disabled := NewClaimStore()
+	disabled.AddAnomalyDetector(customDetector{claimant: "eve"})
+	require.NoError(t, disabled.ProcessClaim(t.Context(), "2001:db8::1", "eve"))
+	_, enabled := disabled.Anomalies()
+	assert.False(t, enabled)
+}
+
+// TestAnomalyOptions_Validate tests that unusable anomaly options are rejected
+func TestAnomalyOptions_Validate(t *testing.T) {
+	valid := DefaultAnomalyOptions()
+	valid.Enabled = true
+	require.NoError(t, valid.Validate())
+	require.NoError(t, AnomalyOptions{}.Validate(), "Disabled anomaly detection should be valid")
+
+	testCases := map[string]func(*AnomalyOptions){
+		"unknown action":        func(o *AnomalyOptions) { o.Action = "ban" },
+		"no window":             func(o *AnomalyOptions) { o.Window = 0 },
+		"negative spread":       func(o *AnomalyOptions) { o.MaxSpread = -1 },
+		"spread prefix too big": func(o *AnomalyOptions) { o.SpreadPrefix = 129 },
+		"no penalty":            func(o *AnomalyOptions) { o.Penalty = 0 },
+		"no penalty duration":   func(o *AnomalyOptions) { o.PenaltyDuration = 0 },
+		"no capacity":           func(o *AnomalyOptions) { o.Capacity = 0 },
+	}
+	for name, modify := range testCases {
+		t.Run(name, func(t *testing.T) {
+			opts := valid
+			modify(&opts)
+			assert.Error(t, opts.Validate())
+		})
+	}
+}
+
+// TestHTTPHandler_Anomalies tests that quarantined claimants are refused and
+// admins review and release them
+func TestHTTPHandler_Anomalies(t *testing.T) {
+	store := anomalyStore(t, func(o *AnomalyOptions) {
+		o.Action = AnomalyActionQuarantine
+		o.MaxSpread = 1
+		o.SpreadPrefix = 48
+		o.MaxHashRate = 0
+	})
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	do := func(method, path string, body []byte, admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if admin {
+			req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	claim := func(ip string) *httptest.ResponseRecorder {
+		pow, err := api.SolveProofOfWork(net.ParseIP(ip), "mallory", store.CalculateDifficulty(t.Context(), ip), 1000000)
+		require.NoError(t, err, "Should solve proof of work")
+		data, err := json.Marshal(api.ClaimRequest{Name: "mallory", Nonce: pow.Nonce})
+		require.NoError(t, err)
+		return do(http.MethodPost, "/api/v1/claim/"+ip, data, false)
+	}
+
+	require.Equal(t, http.StatusCreated, claim("2001:db8:1::1").Code)
+	require.Equal(t, http.StatusCreated, claim("2001:db8:2::1").Code, "The claim completing the pattern should be applied")
+	rr := claim("2001:db8:3::1")
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Quarantined claimants should be refused")
+	assert.Contains(t, rr.Body.String(), ErrQuarantined.Error())
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/admin/anomalies", nil, false).Code)
+	rr = do(http.MethodGet, "/api/v1/admin/anomalies", nil, true)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var report api.AnomaliesResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	require.Len(t, report.Flagged, 1)
+	assert.Equal(t, "mallory", report.Flagged[0].Claimant)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/v1/admin/anomalies/claimants/mallory", nil, true).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/admin/anomalies/claimants/mallory", nil, true).Code)
+	assert.Equal(t, http.StatusCreated, claim("2001:db8:3::1").Code, "Released claimants should claim again")
+
+	disabled := NewHTTPHandler(NewClaimStore())
+	disabled.adminTokens = handler.adminTokens
+	router = mux.NewRouter()
+	disabled.RegisterRoutes(router)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v1/admin/anomalies", nil, true).Code)
+}
+
+// TestHTTPHandler_ChallengePenalty tests that challenges for a penalized claimant include the penalty
+func TestHTTPHandler_ChallengePenalty(t *testing.T) {
+	store := anomalyStore(t, func(o *AnomalyOptions) { o.MaxSpread = 0 })
+	store.AddAnomalyDetector(customDetector{claimant: "eve"})
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "eve"))
+
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	challenge := func(query string) api.PoWChallenge {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/challenge/2001:db8::2"+query, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		var challenge api.PoWChallenge
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&challenge))
+		return challenge
+	}
+
+	base := challenge("").Difficulty
+	assert.Equal(t, base, challenge("?name=alice").Difficulty)
+	assert.Equal(t, base+uint8(DefaultAnomalyOptions().Penalty), challenge("?name=eve").Difficulty)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// artifactPrefixLen is the size of the subnets artifacts are placed in
+const artifactPrefixLen = 48
+
+// ArtifactOptions configures the special artifact addresses placed in every /48
+type ArtifactOptions struct {
+	PerSubnet int    `yaml:"perSubnet"` // Artifacts per /48, zero disables artifacts
+	Seed      string `yaml:"seed"`      // Secret placement seed, random per run if empty
+}
+
+// DefaultArtifactOptions returns the standard artifact options
+func DefaultArtifactOptions() ArtifactOptions {
+	return ArtifactOptions{PerSubnet: 1}
+}
+
+// ArtifactSet places artifact addresses pseudo-randomly but deterministically
+// in every /48, so they never need to be stored
+type ArtifactSet struct {
+	perSubnet int
+	key       []byte
+}
+
+// NewArtifactSet creates an artifact set, returning nil if artifacts are disabled
+func NewArtifactSet(opts ArtifactOptions) *ArtifactSet {
+	if opts.PerSubnet <= 0 {
+		return nil
+	}
+
+	key := []byte(opts.Seed)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("failed to generate artifact seed: " + err.Error())
+		}
+		componentLogger("artifacts").Warn("No artifact seed configured, artifacts will move when the server restarts")
+	}
+
+	return &ArtifactSet{perSubnet: opts.PerSubnet, key: key}
+}
+
+// InSubnet returns the artifact addresses in the /48 containing ip
+func (a *ArtifactSet) InSubnet(ip net.IP) []net.IP {
+	prefix := ip.To16().Mask(net.CIDRMask(artifactPrefixLen, 128))
+
+	artifacts := make([]net.IP, 0, a.perSubnet)
+	for i := range a.perSubnet {
+		mac := hmac.New(sha256.New, a.key)
+		mac.Write(prefix)
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+
+		artifact := make(net.IP, net.IPv6len)
+		copy(artifact, prefix[:artifactPrefixLen/8])
+		copy(artifact[artifactPrefixLen/8:], mac.Sum(nil))
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts
+}
+
+// IsArtifact reports whether an address is an artifact
+func (a *ArtifactSet) IsArtifact(ip net.IP) bool {
+	for _, artifact := range a.InSubnet(ip) {
+		if artifact.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether a subnet smaller than a /48 contains an artifact.
+// Every /48 and larger subnet contains artifacts, so it reports false for those.
+func (a *ArtifactSet) Contains(subnet *net.IPNet) bool {
+	if prefixLength(subnet) <= artifactPrefixLen {
+		return false
+	}
+
+	for _, artifact := range a.InSubnet(subnet.IP) {
+		if subnet.Contains(artifact) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixLength returns the prefix length of a subnet
+func prefixLength(subnet *net.IPNet) int {
+	prefixLen, _ := subnet.Mask.Size()
+	return prefixLen
+}
+
+// containsCIDR is Contains for a subnet in CIDR notation
+func (a *ArtifactSet) containsCIDR(cidr string) bool {
+	_, subnet, err := net.ParseCIDR(cidr)
+	return err == nil && a.Contains(subnet)
+}
+
+// handleGetArtifacts lists the artifacts in a subnet, or in every claimed /48 if none is given
+func (h *HTTPHandler) handleGetArtifacts(w http.ResponseWriter, r *http.Request) {
+	if h.artifacts == nil {
+		writeError(w, r, notFound("artifacts are disabled"))
+		return
+	}
+
+	var filter *net.IPNet
+	if subnet := r.URL.Query().Get("subnet"); subnet != "" {
+		var err error
+		_, filter, err = net.ParseCIDR(subnet)
+		if err != nil || filter.IP.To4() != nil {
+			writeError(w, r, badRequest("invalid subnet"))
+			return
+		}
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	// Collect the /48s to list artifacts for
+	var prefixes []net.IP
+	if filter != nil && prefixLength(filter) >= artifactPrefixLen {
+		prefixes = append(prefixes, filter.IP)
+	} else {
+		subnets, _ := h.store.GetAllSubnets(ctx, artifactPrefixLen)
+		for _, entry := range subnets {
+			ip, _, err := net.ParseCIDR(entry.Subnet)
+			if err != nil || (filter != nil && !filter.Contains(ip)) {
+				continue
+			}
+			prefixes = append(prefixes, ip)
+		}
+	}
+
+	artifacts := make([]api.Artifact, 0, len(prefixes)*h.artifacts.perSubnet)
+	for _, prefix := range prefixes {
+		for _, ip := range h.artifacts.InSubnet(prefix) {
+			if filter != nil && !filter.Contains(ip) {
+				continue
+			}
+			holder, _ := h.store.GetClaim(ctx, ip.String())
+			artifacts = append(artifacts, api.Artifact{
+				Address: ip.String(),
+				Holder:  holder,
+			})
+		}
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Address < artifacts[j].Address
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(artifacts); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
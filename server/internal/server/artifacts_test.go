@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArtifactSet_Placement tests that artifacts are placed deterministically inside each /48
+func TestArtifactSet_Placement(t *testing.T) {
+	assert.Nil(t, NewArtifactSet(ArtifactOptions{}), "Artifacts should be disabled without any per subnet")
+
+	artifacts := NewArtifactSet(ArtifactOptions{PerSubnet: 3, Seed: "test-seed"})
+	_, subnet, _ := net.ParseCIDR("2001:db8:1::/48")
+
+	placed := artifacts.InSubnet(net.ParseIP("2001:db8:1:2::3"))
+	require.Len(t, placed, 3, "Each /48 should have the configured number of artifacts")
+	for _, ip := range placed {
+		assert.True(t, subnet.Contains(ip), "Artifact %s should be inside its /48", ip)
+		assert.True(t, artifacts.IsArtifact(ip), "Artifact %s should be recognized", ip)
+
+		_, host, _ := net.ParseCIDR(ip.String() + "/128")
+		assert.True(t, artifacts.Contains(host), "Artifact /128 should contain an artifact")
+	}
+
+	same := NewArtifactSet(ArtifactOptions{PerSubnet: 3, Seed: "test-seed"})
+	assert.Equal(t, placed, same.InSubnet(subnet.IP), "Same seed should place the same artifacts")
+
+	other := NewArtifactSet(ArtifactOptions{PerSubnet: 3, Seed: "other-seed"})
+	assert.NotEqual(t, placed, other.InSubnet(subnet.IP), "Different seeds should place different artifacts")
+
+	assert.False(t, artifacts.IsArtifact(net.ParseIP("2001:db8:1::1")), "Ordinary address should not be an artifact")
+	assert.False(t, artifacts.Contains(subnet), "A /48 is not marked since every /48 has artifacts")
+}
+
+// TestScoringEngine_ArtifactBonus tests that held artifacts award bonus points
+func TestScoringEngine_ArtifactBonus(t *testing.T) {
+	artifacts := NewArtifactSet(ArtifactOptions{PerSubnet: 1, Seed: "test-seed"})
+	artifact := artifacts.InSubnet(net.ParseIP("2001:db8::"))[0]
+
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), artifact.String(), "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+
+	engine, err := NewScoringEngine(store, ScoringOptions{Interval: time.Minute, AddressPoints: 1, ArtifactPoints: 50})
+	require.NoError(t, err)
+	engine.artifacts = artifacts
+	engine.Tick(time.Now())
+
+	assert.Equal(t, []api.ScoreEntry{
+		{Name: "alice", Score: 51},
+		{Name: "bob", Score: 1},
+	}, engine.Scores(), "Artifact holder should earn the bonus")
+}
+
+// TestHTTPHandler_GetArtifacts tests listing artifacts and marking them in subnet listings
+func TestHTTPHandler_GetArtifacts(t *testing.T) {
+	artifacts := NewArtifactSet(ArtifactOptions{PerSubnet: 1, Seed: "test-seed"})
+	artifact := artifacts.InSubnet(net.ParseIP("2001:db8::"))[0]
+
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), artifact.String(), "alice"))
+
+	handler := NewHTTPHandler(store)
+	handler.artifacts = artifacts
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/artifacts", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Artifacts should be listed")
+
+	var listed []api.Artifact
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	assert.Equal(t, []api.Artifact{{Address: artifact.String(), Holder: "alice"}}, listed,
+		"Artifacts of claimed /48s should be listed with their holders")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/artifacts?subnet=2001:db9::/48", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Artifacts in a subnet should be listed")
+	listed = nil
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	assert.Len(t, listed, 1, "Unclaimed /48 should still have artifacts")
+	assert.Empty(t, listed[0].Holder, "Unclaimed artifact should have no holder")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/subnets/128", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Subnets should be listed")
+
+	var subnets []api.SubnetListEntry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&subnets))
+	require.Len(t, subnets, 1, "Only the claimed address should be listed")
+	assert.True(t, subnets[0].Artifact, "Artifact address should be marked")
+}
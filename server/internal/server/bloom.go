@@ -0,0 +1,72 @@
+package server
+
+import (
+	"hash/maphash"
+	"math"
+)
+
+// bloomFalsePositiveRate is the target false positive rate of subnet filters
+const bloomFalsePositiveRate = 0.01
+
+// bloomInitialCapacity is the number of subnets a new filter is sized for
+const bloomInitialCapacity = 1 << 12
+
+// bloomFilter is a probabilistic set that may report false positives but never
+// false negatives. Entries cannot be removed.
+type bloomFilter struct {
+	bits     []uint64
+	hashes   int
+	count    int // Entries added
+	capacity int // Entries the filter is sized for at the target false positive rate
+	seed     maphash.Seed
+}
+
+// newBloomFilter creates a filter sized for capacity entries at the target false positive rate
+func newBloomFilter(capacity int) *bloomFilter {
+	// Optimal sizing: m = -n ln(p) / ln(2)^2 bits and k = m/n ln(2) hashes
+	bits := int(math.Ceil(-float64(capacity) * math.Log(bloomFalsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := max(int(math.Round(float64(bits)/float64(capacity)*math.Ln2)), 1)
+
+	return &bloomFilter{
+		bits:     make([]uint64, (bits+63)/64),
+		hashes:   hashes,
+		capacity: capacity,
+		seed:     maphash.MakeSeed(),
+	}
+}
+
+// add inserts a key into the filter
+func (f *bloomFilter) add(key []byte) {
+	h1, h2 := f.hash(key)
+	size := uint64(len(f.bits) * 64)
+	for i := range f.hashes {
+		bit := (h1 + uint64(i)*h2) % size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// mayContain reports whether a key may have been added to the filter
+func (f *bloomFilter) mayContain(key []byte) bool {
+	h1, h2 := f.hash(key)
+	size := uint64(len(f.bits) * 64)
+	for i := range f.hashes {
+		bit := (h1 + uint64(i)*h2) % size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// full reports whether the filter holds more entries than it was sized for
+func (f *bloomFilter) full() bool {
+	return f.count > f.capacity
+}
+
+// hash derives the two hashes used for double hashing from a single 64-bit hash
+func (f *bloomFilter) hash(key []byte) (uint64, uint64) {
+	h := maphash.Bytes(f.seed, key)
+	// Forcing the step odd keeps it nonzero so the hash functions differ
+	return h & 0xffffffff, h>>32 | 1
+}
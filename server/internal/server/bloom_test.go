@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBloomFilter tests that added keys are always found and false positives stay rare
+func TestBloomFilter(t *testing.T) {
+	filter := newBloomFilter(1000)
+
+	key := func(i int) []byte {
+		return binary.BigEndian.AppendUint64(nil, uint64(i))
+	}
+
+	for i := range 1000 {
+		filter.add(key(i))
+	}
+	for i := range 1000 {
+		require.True(t, filter.mayContain(key(i)), "Added key %d should be found", i)
+	}
+	assert.False(t, filter.full(), "Filter should not be full at capacity")
+
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if filter.mayContain(key(i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300, "False positive rate should be near the 1%% target")
+}
+
+// TestIPTree_SubnetFilterGrowth tests that subnet queries stay correct as filters grow
+func TestIPTree_SubnetFilterGrowth(t *testing.T) {
+	tree := NewIPTree()
+
+	// More /128s than the initial filter capacity forces a rebuild
+	claims := bloomInitialCapacity + 100
+	for i := range claims {
+		tree.processClaim(fmt.Sprintf("2001:db8::%x:%x", i>>16, i&0xffff), "alice", "")
+	}
+	assert.Greater(t, tree.claimed[128].capacity, bloomInitialCapacity, "Filter should have grown")
+
+	for i := range claims {
+		stats, ok := tree.GetSubnetStats(fmt.Sprintf("2001:db8::%x:%x/128", i>>16, i&0xffff), 0)
+		require.True(t, ok, "Subnet query should succeed")
+		require.Equal(t, "alice", stats.Owner, "Claimed address %d should be found after growth", i)
+	}
+
+	stats, ok := tree.GetSubnetStats("2001:db9::/32", 0)
+	require.True(t, ok, "Empty subnet query should succeed")
+	assert.Empty(t, stats.Owner, "Empty subnet should have no owner")
+}
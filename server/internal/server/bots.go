@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Strategies bots choose addresses to claim with
+const (
+	BotStrategyRandom  = "random"  // Claim anywhere in the bot prefix
+	BotStrategyCluster = "cluster" // Claim next to the bot's own territory
+	BotStrategyContest = "contest" // Take over the leader's addresses
+)
+
+// botStrategies lists every bot strategy
+var botStrategies = []string{BotStrategyRandom, BotStrategyCluster, BotStrategyContest}
+
+// botMaxAttempts bounds the proof of work a bot spends on one target before giving up its turn
+const botMaxAttempts = 1 << 24
+
+// botMemory is how many of its own claims a bot remembers to cluster around
+const botMemory = 64
+
+// botClusterBits is how many low bits of an owned address a clustering bot varies
+const botClusterBits = 16
+
+// BotOptions configures simulated claimants for demos, load testing and single-player practice
+type BotOptions struct {
+	Count      int           `yaml:"count"`      // Simulated claimants, zero disables bots
+	Strategies []string      `yaml:"strategies"` // Strategies assigned to bots in turn
+	Interval   time.Duration `yaml:"interval"`   // Time between each bot's claims
+	Prefix     string        `yaml:"prefix"`     // Subnet bots claim in, in CIDR notation
+}
+
+// DefaultBotOptions returns the standard bot options, with bots disabled
+func DefaultBotOptions() BotOptions {
+	return BotOptions{
+		Strategies: botStrategies,
+		Interval:   5 * time.Second,
+		Prefix:     "2001:db8::/32",
+	}
+}
+
+// Validate checks that the bot options are usable
+func (o BotOptions) Validate() error {
+	if o.Count < 0 {
+		return fmt.Errorf("bots count must not be negative, got %d", o.Count)
+	}
+	if o.Count == 0 {
+		return nil
+	}
+	if o.Interval <= 0 {
+		return errors.New("bots interval must be positive")
+	}
+	if len(o.Strategies) == 0 {
+		return errors.New("bots need at least one strategy")
+	}
+	for _, strategy := range o.Strategies {
+		if !slices.Contains(botStrategies, strategy) {
+			return fmt.Errorf("unknown bot strategy %q", strategy)
+		}
+	}
+	if _, prefix, err := net.ParseCIDR(o.Prefix); err != nil || prefix.IP.To4() != nil {
+		return fmt.Errorf("bots prefix must be an IPv6 subnet in CIDR notation, got %q", o.Prefix)
+	}
+	return nil
+}
+
+// bot is a simulated claimant
+type bot struct {
+	name     string
+	strategy string
+	rng      *rand.Rand
+	claimed  []net.IP // Recent claims, oldest first
+}
+
+// remember records a claim for the cluster strategy
+func (b *bot) remember(ip net.IP) {
+	if len(b.claimed) == botMemory {
+		b.claimed = b.claimed[1:]
+	}
+	b.claimed = append(b.claimed, ip)
+}
+
+// BotSimulator runs simulated claimants against a store. Each bot claims on
+// its own schedule, solving the same proof of work as players.
+type BotSimulator struct {
+	store  Store
+	opts   BotOptions
+	prefix *net.IPNet
+	bots   []*bot
+
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+// NewBotSimulator creates the bots described by the options
+func NewBotSimulator(store Store, opts BotOptions) (*BotSimulator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	_, prefix, _ := net.ParseCIDR(opts.Prefix)
+
+	s := &BotSimulator{
+		store:  store,
+		opts:   opts,
+		prefix: prefix,
+		logger: componentLogger("bots"),
+	}
+	for i := range opts.Count {
+		s.bots = append(s.bots, &bot{
+			name:     fmt.Sprintf("bot-%d", i+1),
+			strategy: opts.Strategies[i%len(opts.Strategies)],
+			rng:      rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		})
+	}
+	return s, nil
+}
+
+// Start runs every bot in the background until Stop is called. Bots start at
+// random offsets within the interval, so their claims are spread out.
+func (s *BotSimulator) Start() {
+	s.stop = make(chan struct{})
+
+	for _, b := range s.bots {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(time.Duration(b.rng.Int64N(int64(s.opts.Interval)))):
+			}
+
+			ticker := time.NewTicker(s.opts.Interval)
+			defer ticker.Stop()
+
+			for {
+				s.turn(b)
+				select {
+				case <-s.stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	s.logger.Info("Started bots", "count", len(s.bots), "prefix", s.prefix.String(), "interval", s.opts.Interval)
+}
+
+// Stop stops the bots, waiting for claims in progress
+func (s *BotSimulator) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+}
+
+// turn makes one claim for a bot
+func (s *BotSimulator) turn(b *bot) {
+	// Bots stop between turns, so a turn runs to completion
+	ctx := withClaimSource(context.Background(), ClaimSource{Channel: claimChannelBot})
+
+	target := s.pickTarget(ctx, b)
+	difficulty := s.store.CalculateDifficulty(ctx, target.String())
+
+	pow, _, err := api.SolveProofOfWorkWith(s.store.PoWScheme(ctx), target, b.name, difficulty, botMaxAttempts, 1)
+	if err != nil {
+		s.logger.Debug("Bot gave up on proof of work", "bot", b.name, "target", target.String(), "difficulty", difficulty)
+		return
+	}
+	if err := s.store.ValidateProofOfWork(ctx, pow); err != nil {
+		// The difficulty rose while solving, try elsewhere next turn
+		return
+	}
+	if err := s.store.ProcessClaim(ctx, target.String(), b.name); err != nil {
+		s.logger.Warn("Bot claim failed", "bot", b.name, "target", target.String(), "error", err)
+		return
+	}
+	b.remember(target)
+}
+
+// pickTarget chooses the next address a bot claims by its strategy,
+// falling back to a random address when the strategy has nothing to go on
+func (s *BotSimulator) pickTarget(ctx context.Context, b *bot) net.IP {
+	switch b.strategy {
+	case BotStrategyCluster:
+		if len(b.claimed) > 0 {
+			owned := b.claimed[b.rng.IntN(len(b.claimed))]
+			return randomAddress(b.rng, &net.IPNet{IP: owned, Mask: net.CIDRMask(128-botClusterBits, 128)})
+		}
+	case BotStrategyContest:
+		if ip := s.leaderAddress(ctx, b); ip != nil {
+			return ip
+		}
+	}
+	return randomAddress(b.rng, s.prefix)
+}
+
+// leaderAddress returns an address within the bot prefix held by the leading
+// claimant other than the bot, or nil if there is none
+func (s *BotSimulator) leaderAddress(ctx context.Context, b *bot) net.IP {
+	var leader string
+	for _, entry := range s.store.GetLeaderboard(ctx, 2) {
+		if entry.Name != b.name {
+			leader = entry.Name
+			break
+		}
+	}
+	if leader == "" {
+		return nil
+	}
+
+	// Map iteration order is random, so the first match is a random pick
+	for addr, claimant := range s.store.GetAllClaims(ctx) {
+		if claimant != leader {
+			continue
+		}
+		if ip := net.ParseIP(addr); ip != nil && s.prefix.Contains(ip) {
+			return ip
+		}
+	}
+	return nil
+}
+
+// randomAddress returns a random address within a subnet
+func randomAddress(rng *rand.Rand, subnet *net.IPNet) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	base := subnet.IP.To16()
+	for i, mask := range subnet.Mask {
+		ip[i] = base[i]&mask | byte(rng.UintN(256))&^mask
+	}
+	return ip
+}
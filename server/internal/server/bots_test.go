@@ -0,0 +1,103 @@
+package server
+
+import (
+	"math/rand/v2"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBotSimulator_Strategies tests that each strategy claims where it should
+func TestBotSimulator_Strategies(t *testing.T) {
+	store := NewClaimStore()
+	for _, ip := range []string{"2001:db8:1:2::1", "2001:db8:1:2::2", "2001:db8:1:2::3", "2001:db8:1:2::4", "2001:db8:1:2::5",
+		"2001:db8:1:2::6", "2001:db8:1:2::7", "2001:db8:1:2::8", "2001:db8:1:2::9", "2001:db8:1:2::a"} {
+		require.NoError(t, store.ProcessClaim(t.Context(), ip, "alice"))
+	}
+
+	opts := DefaultBotOptions()
+	opts.Count = 3
+	sim, err := NewBotSimulator(store, opts)
+	require.NoError(t, err, "Should create bots")
+	require.Len(t, sim.bots, 3)
+
+	_, prefix, _ := net.ParseCIDR(opts.Prefix)
+	for _, b := range sim.bots {
+		for range 3 {
+			sim.turn(b)
+		}
+		require.NotEmpty(t, b.claimed, "%s should claim", b.name)
+		for _, ip := range b.claimed {
+			assert.True(t, prefix.Contains(ip), "%s should claim in the bot prefix", b.name)
+			claimant, _ := store.GetClaim(t.Context(), ip.String())
+			assert.Equal(t, b.name, claimant, "Claims should be attributed to the bot")
+		}
+	}
+
+	cluster := sim.bots[1]
+	require.Equal(t, BotStrategyCluster, cluster.strategy)
+	near := &net.IPNet{IP: cluster.claimed[0], Mask: net.CIDRMask(128-botClusterBits, 128)}
+	for _, ip := range cluster.claimed {
+		assert.True(t, near.Contains(ip), "Clustering bot should claim next to its territory")
+	}
+
+	contest := sim.bots[2]
+	require.Equal(t, BotStrategyContest, contest.strategy)
+	ip := sim.leaderAddress(t.Context(), contest)
+	require.NotNil(t, ip, "Contesting bot should find the leader's territory")
+	claimant, _ := store.GetClaim(t.Context(), ip.String())
+	assert.Equal(t, "alice", claimant, "Contested address should belong to the leader")
+	for _, ip := range contest.claimed {
+		assert.Equal(t, "2001:db8:1:2::/64", (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String(),
+			"Contesting bot should take over the leader's addresses")
+	}
+}
+
+// TestBotOptions_Validate tests that unusable bot options are rejected
+func TestBotOptions_Validate(t *testing.T) {
+	assert.NoError(t, DefaultBotOptions().Validate(), "Disabled bots should be valid")
+
+	opts := DefaultBotOptions()
+	opts.Count = 1
+	assert.NoError(t, opts.Validate(), "Default bots should be valid")
+
+	opts.Strategies = []string{"hoard"}
+	assert.Error(t, opts.Validate(), "Unknown strategies should be rejected")
+
+	opts = DefaultBotOptions()
+	opts.Count = 1
+	opts.Prefix = "192.0.2.0/24"
+	assert.Error(t, opts.Validate(), "IPv4 prefixes should be rejected")
+
+	opts.Prefix = DefaultBotOptions().Prefix
+	opts.Interval = 0
+	assert.Error(t, opts.Validate(), "Interval should be positive")
+}
+
+// TestBotSimulator_StartStop tests that running bots claim and stop cleanly
+func TestBotSimulator_StartStop(t *testing.T) {
+	store := NewClaimStore()
+	opts := DefaultBotOptions()
+	opts.Count = 2
+	opts.Interval = 10 * time.Millisecond
+	sim, err := NewBotSimulator(store, opts)
+	require.NoError(t, err)
+
+	sim.Start()
+	assert.Eventually(t, func() bool { return store.GetStats(t.Context()).Claimants == 2 }, 5*time.Second, 10*time.Millisecond,
+		"Every bot should claim")
+	sim.Stop()
+	sim.Stop()
+}
+
+// TestRandomAddress tests that random addresses stay within their subnet
+func TestRandomAddress(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	_, subnet, _ := net.ParseCIDR("2001:db8:ab::/52")
+	for range 100 {
+		assert.True(t, subnet.Contains(randomAddress(rng, subnet)), "Address should be in the subnet")
+	}
+}
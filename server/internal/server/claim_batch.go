@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// maxBatchClaims bounds the number of claims in one batch request
+const maxBatchClaims = 256
+
+// stagedClaim is a claim in a batch with the state of its address before it
+type stagedClaim struct {
+	BatchClaim
+	oldClaimant string
+	existed     bool
+	metadata    ClaimMetadata
+	difficulty  uint8 // Of the address before the batch
+}
+
+// ProcessClaims applies a batch of claims atomically. Every address and
+// claimant name is checked and every claim persisted in one transaction before
+// memory and the tree are updated, so either all claims are applied or, if an
+// address or name is rejected or persisting fails, none is. Later claims on
+// an address in the batch apply after earlier ones.
+func (cs *ClaimStore) ProcessClaims(ctx context.Context, batch []BatchClaim) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	for i, claim := range batch {
+		if err := cs.checkClaimableLocked(claim.IP); err != nil {
+			return fmt.Errorf("claims[%d]: %w", i, err)
+		}
+		if err := cs.anomalies.check(claim.Claimant); err != nil {
+			return fmt.Errorf("claims[%d]: %w", i, err)
+		}
+	}
+	if err := cs.supply.checkBatch(cs.claims, batch); err != nil {
+		return err
+	}
+
+	// Check names against registered ones and each other before changing anything
+	newNames := make(map[string]string)
+	for _, claim := range batch {
+		skeleton := nameSkeleton(claim.Claimant)
+		display, exists := cs.names[skeleton]
+		if !exists {
+			display, exists = newNames[skeleton]
+		}
+		if !exists {
+			newNames[skeleton] = claim.Claimant
+		} else if display != claim.Claimant {
+			return fmt.Errorf("%w %q", ErrNameConfusable, display)
+		}
+	}
+
+	// Make room for addresses not yet claimed, keeping the ones in the batch
+	inBatch := make(map[string]bool, len(batch))
+	var newAddresses []string
+	for _, claim := range batch {
+		if _, exists := cs.claims[claim.IP]; !exists && !inBatch[claim.IP] {
+			newAddresses = append(newAddresses, claim.IP)
+		}
+		inBatch[claim.IP] = true
+	}
+	if err := cs.admitLocked(ctx, newAddresses, inBatch); err != nil {
+		return err
+	}
+
+	// Stage each claim against the state left by the claims before it
+	now := time.Now().UTC()
+	source := cs.claimSourceLocked(ctx)
+	staged := make([]stagedClaim, 0, len(batch))
+	latest := make(map[string]int)
+	for _, claim := range batch {
+		oldClaimant, exists := cs.claims[claim.IP]
+		oldMetadata := cs.metadata[claim.IP]
+		if i, ok := latest[claim.IP]; ok {
+			oldClaimant, exists, oldMetadata = staged[i].Claimant, true, staged[i].metadata
+		}
+
+		metadata := nextClaimMetadata(oldClaimant, exists, oldMetadata, claim.Claimant, now)
+		metadata.Source = source
+		staged = append(staged, stagedClaim{
+			BatchClaim:  claim,
+			oldClaimant: oldClaimant,
+			existed:     exists,
+			metadata:    metadata,
+			difficulty:  cs.difficultyLocked(claim.IP, now),
+		})
+		latest[claim.IP] = len(staged) - 1
+	}
+
+	if cs.db != nil {
+		if err := cs.persistClaimBatch(ctx, newNames, staged); err != nil {
+			cs.logger.Error("Failed to persist claim batch", "claims", len(batch), "error", err)
+			return backendError(err)
+		}
+	}
+	if err := cs.journal.append(batchEntry(newNames, staged)); err != nil {
+		cs.logger.Error("Failed to journal claim batch", "claims", len(batch), "error", err)
+		return backendError(err)
+	}
+
+	for skeleton, display := range newNames {
+		cs.names[skeleton] = display
+	}
+	for _, claim := range staged {
+		cs.claims[claim.IP] = claim.Claimant
+		cs.metadata[claim.IP] = claim.metadata
+
+		if claim.existed {
+			cs.ipTree.processClaim(claim.IP, claim.Claimant, claim.oldClaimant)
+		} else {
+			cs.ipTree.processClaim(claim.IP, claim.Claimant, "")
+			cs.indexSubnetNames(claim.IP)
+		}
+		if claim.oldClaimant != claim.Claimant {
+			cs.blocks.update(cs.claims, claim.IP)
+			cs.supply.moved(claim.oldClaimant, claim.Claimant)
+		}
+
+		if claim.oldClaimant != claim.Claimant {
+			cs.limits.touch(claim.IP)
+			event := api.ClaimEvent{
+				Type:             api.EventTypeClaim,
+				IP:               claim.IP,
+				Claimant:         claim.Claimant,
+				PreviousClaimant: claim.oldClaimant,
+				Timestamp:        now,
+				Difficulty:       claim.difficulty,
+			}
+			cs.activity.record(event)
+			cs.heat.record(event)
+			cs.events.Publish(event)
+		}
+		cs.anomalies.observe(ClaimObservation{
+			IP:         claim.IP,
+			Claimant:   claim.Claimant,
+			Source:     claim.metadata.Source,
+			Difficulty: claim.difficulty,
+			At:         now,
+		})
+	}
+
+	return nil
+}
+
+// persistClaimBatch writes new claimant names and staged claims to SQLite in one transaction
+func (cs *ClaimStore) persistClaimBatch(ctx context.Context, newNames map[string]string, staged []stagedClaim) error {
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			cs.logger.Error("Error rolling back claim batch", "error", rbErr)
+		}
+		return err
+	}
+
+	for skeleton, display := range newNames {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO claimant_names (canonical, display) VALUES (?, ?)",
+			skeleton, display,
+		); err != nil {
+			return rollback(err)
+		}
+	}
+
+	for _, claim := range staged {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO claims (ip_address, claimant, claimed_at, takeover_count, fortification, fortified_at,
+				source_channel, source_ip, source_user_agent)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(ip_address) DO UPDATE SET claimant = excluded.claimant, claimed_at = excluded.claimed_at,
+				takeover_count = excluded.takeover_count, fortification = excluded.fortification,
+				fortified_at = excluded.fortified_at, source_channel = excluded.source_channel,
+				source_ip = excluded.source_ip, source_user_agent = excluded.source_user_agent,
+				released_at = NULL, updated_at = CURRENT_TIMESTAMP`,
+			claim.IP, claim.Claimant, claim.metadata.ClaimedAt, claim.metadata.TakeoverCount,
+			claim.metadata.Fortification, nullTime(claim.metadata.FortifiedAt),
+			claim.metadata.Source.Channel, claim.metadata.Source.RemoteIP, claim.metadata.Source.UserAgent,
+		); err != nil {
+			return rollback(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// handleSubmitClaims claims several addresses at once. Every claim must carry
+// a valid proof of work, and the claims are applied all together or not at all.
+func (h *HTTPHandler) handleSubmitClaims(w http.ResponseWriter, r *http.Request) {
+	var batchReq api.BatchClaimRequest
+	if err := h.decodeBody(w, r, &batchReq); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(batchReq.Claims) == 0 || len(batchReq.Claims) > maxBatchClaims {
+		writeError(w, r, badRequest(fmt.Sprintf("a batch must have between 1 and %d claims", maxBatchClaims)))
+		return
+	}
+
+	// Every claim in the batch counts against the claim rate limit
+	if h.rateLimiter != nil && !h.rateLimiter.AllowN(clientAddress(r), len(batchReq.Claims)) {
+		writeError(w, r, ErrRateLimited)
+		return
+	}
+
+	batch := make([]BatchClaim, len(batchReq.Claims))
+	proofs := make([]*api.ProofOfWork, len(batchReq.Claims))
+	for i, claim := range batchReq.Claims {
+		targetIP := net.ParseIP(claim.IP)
+		if targetIP == nil {
+			writeError(w, r, badRequest(fmt.Sprintf("claims[%d]: invalid address", i)))
+			return
+		}
+		if err := h.federation.checkAuthoritativeIP(targetIP); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		name, err := h.names.Normalize(claim.Name)
+		if err != nil {
+			writeError(w, r, badRequest(fmt.Sprintf("claims[%d]: %v", i, err)))
+			return
+		}
+
+		batch[i] = BatchClaim{IP: claim.IP, Claimant: name}
+		proofs[i] = &api.ProofOfWork{Target: targetIP, Name: claim.Name, Nonce: claim.Nonce}
+	}
+
+	// Verify every claim, then apply the batch, on the worker pool if there is one
+	process := func() error {
+		for i, pow := range proofs {
+			if h.icmp != nil {
+				if err := h.icmp.Verify(r.Context(), pow.Target, batch[i].Claimant); err != nil {
+					return fmt.Errorf("claims[%d]: %w", i, err)
+				}
+			} else if err := h.store.ValidateProofOfWork(r.Context(), pow); err != nil {
+				return fmt.Errorf("claims[%d]: %w: %v", i, errInvalidProofOfWork, err)
+			}
+		}
+		ctx, cancel := h.storeContext(r)
+		defer cancel()
+		return h.store.ProcessClaims(withClaimSource(ctx, httpClaimSource(r, claimChannelBatch)), batch)
+	}
+
+	var err error
+	if h.claimPool != nil {
+		err = h.claimPool.Submit(r.Context(), process)
+	} else {
+		err = process()
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errInvalidProofOfWork), errors.Is(err, errClaimNotVerified):
+		writeError(w, r, unprocessable(err.Error()))
+		return
+	case errors.Is(err, ErrNameConfusable):
+		writeError(w, r, conflict(err.Error()))
+		return
+	case errors.Is(err, ErrClaimQueueFull):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, r, unavailable(err.Error()))
+		return
+	case errors.Is(err, ErrClaimPoolStopped):
+		writeError(w, r, unavailable(err.Error()))
+		return
+	case errors.Is(err, ErrStoreFull):
+		writeError(w, r, unavailable(err.Error()))
+		return
+	default:
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(api.BatchClaimResponse{Claimed: len(batch)}); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// batchEntry records the new claimant names and staged claims of a batch as
+// one journal entry, so a crash cannot leave part of the batch applied
+func batchEntry(newNames map[string]string, staged []stagedClaim) journalEntry {
+	entry := journalEntry{Op: journalBatch, Entries: make([]journalEntry, 0, len(newNames)+len(staged))}
+	for skeleton, display := range newNames {
+		entry.Entries = append(entry.Entries, journalEntry{Op: journalName, Canonical: skeleton, Claimant: display})
+	}
+	for _, claim := range staged {
+		entry.Entries = append(entry.Entries, claimEntry(claim.IP, claim.Claimant, claim.metadata))
+	}
+	return entry
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_ProcessClaims tests that batches are applied and persisted together
+func TestClaimStore_ProcessClaims(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "batch.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::1", Claimant: "bob"},
+		{IP: "2001:db8::2", Claimant: "bob"},
+		{IP: "2001:db8::2", Claimant: "carol"},
+	}), "Should apply a batch")
+
+	claimant, _ := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.Equal(t, "bob", claimant, "Batch should take over claims")
+	claimant, _ = store.GetClaim(t.Context(), "2001:db8::2")
+	assert.Equal(t, "carol", claimant, "Later claims in a batch should apply after earlier ones")
+	metadata, _ := store.GetClaimMetadata(t.Context(), "2001:db8::2")
+	assert.Equal(t, 1, metadata.TakeoverCount, "Takeovers within a batch should be counted")
+
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/64", 10)
+	require.True(t, ok)
+	require.Len(t, stats.AllClaimants, 2, "Tree should reflect the batch")
+	assert.ElementsMatch(t, []string{"bob", "carol"}, []string{stats.AllClaimants[0].Name, stats.AllClaimants[1].Name},
+		"Tree should drop claims taken over in the batch")
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	claimant, _ = reopened.GetClaim(t.Context(), "2001:db8::2")
+	assert.Equal(t, "carol", claimant, "Batch should be persisted")
+	assert.ErrorIs(t, reopened.ProcessClaim(t.Context(), "2001:db8::3", "Carol"), ErrNameConfusable, "Batch names should be registered")
+}
+
+// TestClaimStore_ProcessClaimsAtomic tests that a rejected batch changes nothing
+func TestClaimStore_ProcessClaimsAtomic(t *testing.T) {
+	store, err := NewClaimStoreWithSQLite(filepath.Join(t.TempDir(), "batch.db"))
+	require.NoError(t, err, "Should create SQLite store")
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	err = store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::1", Claimant: "dave"},
+		{IP: "2001:db8::2", Claimant: "Alice"},
+	})
+	assert.ErrorIs(t, err, ErrNameConfusable, "Confusable names should reject the batch")
+
+	err = store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::3", Claimant: "erin"},
+		{IP: "2001:db8::4", Claimant: "ERIN"},
+	})
+	assert.ErrorIs(t, err, ErrNameConfusable, "Names confusable within the batch should reject it")
+
+	claimant, _ := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.Equal(t, "alice", claimant, "Rejected batches should not apply any claim")
+	assert.Equal(t, 1, store.GetStats(t.Context()).TotalClaims, "Rejected batches should not add claims")
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::5", "dave"), "Rejected batches should not register names")
+}
+
+// TestClaimStore_BackendUnavailable tests that failed writes are reported as a failing backend and undone
+func TestClaimStore_BackendUnavailable(t *testing.T) {
+	store, err := NewClaimStoreWithSQLite(filepath.Join(t.TempDir(), "batch.db"))
+	require.NoError(t, err, "Should create SQLite store")
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.db.Close())
+
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"), ErrBackendUnavailable)
+	assert.ErrorIs(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::2", Claimant: "alice"}}), ErrBackendUnavailable)
+	assert.ErrorIs(t, store.Unclaim(t.Context(), "2001:db8::1", "alice"), ErrBackendUnavailable)
+
+	claimant, exists := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.True(t, exists, "Failed writes should leave claims in place")
+	assert.Equal(t, "alice", claimant)
+	_, exists = store.GetClaim(t.Context(), "2001:db8::2")
+	assert.False(t, exists, "Failed batches should not be applied")
+}
+
+// TestHTTPHandler_SubmitClaims tests the batch claim endpoint
+func TestHTTPHandler_SubmitClaims(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	item := func(ip, name string) api.BatchClaimItem {
+		pow, err := api.SolveProofOfWork(net.ParseIP(ip), name, store.CalculateDifficulty(t.Context(), ip), 1000000)
+		require.NoError(t, err, "Should solve proof of work")
+		return api.BatchClaimItem{IP: ip, Name: name, Nonce: pow.Nonce}
+	}
+	submit := func(items ...api.BatchClaimItem) *httptest.ResponseRecorder {
+		data, err := json.Marshal(api.BatchClaimRequest{Claims: items})
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/claims", bytes.NewReader(data)))
+		return rr
+	}
+
+	invalid := item("2001:db8::2", "alice")
+	invalid.Nonce = "invalid"
+	rr := submit(item("2001:db8::1", "alice"), invalid)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "Invalid proofs of work should reject the batch")
+	assert.Contains(t, rr.Body.String(), "claims[1]", "Error should identify the claim")
+	assert.Zero(t, store.GetStats(t.Context()).TotalClaims, "Rejected batches should not apply any claim")
+
+	rr = submit(item("2001:db8::1", "alice"), item("2001:db8::2", "alice"))
+	require.Equal(t, http.StatusCreated, rr.Code, "Valid batch should be accepted")
+	var resp api.BatchClaimResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, 2, resp.Claimed)
+	assert.Equal(t, 2, store.GetStats(t.Context()).TotalClaims, "Every claim should be applied")
+
+	assert.Equal(t, http.StatusBadRequest, submit().Code, "Empty batches should be rejected")
+	assert.Equal(t, http.StatusBadRequest, submit(api.BatchClaimItem{IP: "nonsense", Name: "alice"}).Code,
+		"Invalid addresses should be rejected")
+}
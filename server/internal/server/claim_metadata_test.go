@@ -0,0 +1,81 @@
+package server
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_ClaimMetadata tests that takeovers restart claims and are counted
+func TestClaimStore_ClaimMetadata(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "metadata.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+
+	before := time.Now().UTC().Add(-time.Second)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	metadata, exists := store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	require.True(t, exists, "Claim should have metadata")
+	assert.True(t, metadata.ClaimedAt.After(before), "Claim time should be recorded")
+	assert.Zero(t, metadata.TakeoverCount, "New claim should have no takeovers")
+
+	claimedAt := metadata.ClaimedAt
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	metadata, _ = store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, claimedAt, metadata.ClaimedAt, "Reclaiming by the owner should not restart the claim")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	metadata, _ = store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, 2, metadata.TakeoverCount, "Each change of owner should count as a takeover")
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+
+	persisted, exists := reopened.GetClaimMetadata(t.Context(), "2001:db8::1")
+	require.True(t, exists, "Metadata should persist")
+	assert.Equal(t, metadata.TakeoverCount, persisted.TakeoverCount, "Takeover count should persist")
+	assert.True(t, metadata.ClaimedAt.Equal(persisted.ClaimedAt), "Claim time should persist")
+}
+
+// TestClaimStore_MetadataMigration tests opening a database from before claim metadata was stored
+func TestClaimStore_MetadataMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE claims (
+			ip_address TEXT PRIMARY KEY,
+			claimant TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO claims (ip_address, claimant, updated_at) VALUES ('2001:db8::1', 'alice', '2024-01-02 03:04:05');
+	`)
+	require.NoError(t, err, "Should create legacy schema")
+	require.NoError(t, db.Close())
+
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should migrate legacy database")
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+
+	metadata, exists := store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	require.True(t, exists, "Legacy claim should have metadata")
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), metadata.ClaimedAt.UTC(),
+		"Legacy claims should be dated by their last update")
+	assert.Zero(t, metadata.TakeoverCount, "Legacy claims should start without takeovers")
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// ErrClaimQueueFull is returned when a claim is submitted to a saturated worker pool
+var ErrClaimQueueFull = errors.New("claim queue is full")
+
+// ErrClaimPoolStopped is returned when a claim is submitted after the worker pool stops
+var ErrClaimPoolStopped = errors.New("claim pool is stopped")
+
+// ClaimPoolOptions configures the claim processing worker pool
+type ClaimPoolOptions struct {
+	Workers   int `yaml:"workers"`   // Number of claim workers, zero processes claims on the request goroutine
+	QueueSize int `yaml:"queueSize"` // Claims waiting for a worker before new claims are rejected
+}
+
+// DefaultClaimPoolOptions returns the standard worker pool options
+func DefaultClaimPoolOptions() ClaimPoolOptions {
+	return ClaimPoolOptions{
+		Workers:   runtime.NumCPU(),
+		QueueSize: 1024,
+	}
+}
+
+// Enabled reports whether claims are processed by a worker pool
+func (o ClaimPoolOptions) Enabled() bool {
+	return o.Workers > 0
+}
+
+// claimJob is a queued claim and the channel its result is sent on
+type claimJob struct {
+	process func() error
+	result  chan error
+}
+
+// ClaimPool processes claims on a bounded set of workers, so bursts of claims
+// queue up to a limit instead of running proof of work validation on every
+// request goroutine at once
+type ClaimPool struct {
+	mu      sync.RWMutex // Held for reading across sends, so the queue never closes under one
+	stopped bool
+	jobs    chan claimJob
+	workers int
+	wg      sync.WaitGroup
+
+	processed atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+// NewClaimPool creates a worker pool and starts its workers
+func NewClaimPool(opts ClaimPoolOptions) *ClaimPool {
+	pool := &ClaimPool{
+		jobs:    make(chan claimJob, opts.QueueSize),
+		workers: opts.Workers,
+	}
+
+	for range opts.Workers {
+		pool.wg.Add(1)
+		go pool.work()
+	}
+
+	return pool
+}
+
+// work processes queued claims until the pool is stopped
+func (p *ClaimPool) work() {
+	defer p.wg.Done()
+
+	for job := range p.jobs {
+		job.result <- job.process()
+		p.processed.Add(1)
+	}
+}
+
+// Submit queues a claim and waits for its result. It returns ErrClaimQueueFull
+// without queueing if the queue is full, ErrClaimPoolStopped if the pool has
+// stopped, or the context error if the caller gives up waiting; the claim is
+// still processed in that case.
+func (p *ClaimPool) Submit(ctx context.Context, process func() error) error {
+	// Time spent waiting for a worker is traced separately from processing
+	_, wait := tracer.Start(ctx, "claim.queue")
+	job := claimJob{
+		process: func() error {
+			wait.End()
+			return process()
+		},
+		result: make(chan error, 1), // Buffered so workers never block on abandoned claims
+	}
+
+	if err := p.enqueue(job); err != nil {
+		if errors.Is(err, ErrClaimQueueFull) {
+			p.rejected.Add(1)
+		}
+		endSpan(wait, err)
+		return err
+	}
+
+	select {
+	case err := <-job.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue queues a claim without waiting for room in the queue
+func (p *ClaimPool) enqueue(job claimJob) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrClaimPoolStopped
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrClaimQueueFull
+	}
+}
+
+// Stop refuses new claims, processes the remaining queued claims and stops
+// the workers
+func (p *ClaimPool) Stop() {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// Stats returns the current state of the pool
+func (p *ClaimPool) Stats() api.ClaimQueueStats {
+	return api.ClaimQueueStats{
+		Workers:       p.workers,
+		QueueDepth:    len(p.jobs),
+		QueueCapacity: cap(p.jobs),
+		Processed:     p.processed.Load(),
+		Rejected:      p.rejected.Load(),
+	}
+}
+
+// handleAdminGetClaimQueue returns the state of the claim worker pool
+func (h *HTTPHandler) handleAdminGetClaimQueue(w http.ResponseWriter, r *http.Request) {
+	if h.claimPool == nil {
+		writeError(w, r, notFound("claim worker pool is disabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.claimPool.Stats()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
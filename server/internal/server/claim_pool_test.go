@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimPool_Backpressure tests that claims are processed and rejected when the queue is full
+func TestClaimPool_Backpressure(t *testing.T) {
+	pool := NewClaimPool(ClaimPoolOptions{Workers: 1, QueueSize: 1})
+	defer pool.Stop()
+
+	errFailed := errors.New("failed")
+	assert.NoError(t, pool.Submit(context.Background(), func() error { return nil }), "Claim should be processed")
+	assert.ErrorIs(t, pool.Submit(context.Background(), func() error { return errFailed }), errFailed,
+		"Claim errors should be returned to the submitter")
+
+	// Occupy the worker, then fill the queue
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = pool.Submit(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error, 1)
+	go func() { queued <- pool.Submit(ctx, func() error { return nil }) }()
+	require.Eventually(t, func() bool { return pool.Stats().QueueDepth == 1 }, time.Second, time.Millisecond,
+		"Claim should be queued behind the busy worker")
+
+	assert.ErrorIs(t, pool.Submit(context.Background(), func() error { return nil }), ErrClaimQueueFull,
+		"Claims should be rejected when the queue is full")
+
+	cancel()
+	assert.ErrorIs(t, <-queued, context.Canceled, "Submitter should stop waiting when its context ends")
+	close(release)
+
+	stats := pool.Stats()
+	assert.Equal(t, 1, stats.Workers, "Stats should report the worker count")
+	assert.Equal(t, 1, stats.QueueCapacity, "Stats should report the queue capacity")
+	assert.Equal(t, uint64(1), stats.Rejected, "Stats should count rejected claims")
+}
+
+// TestClaimPool_Stop tests that stopping the pool finishes queued claims and
+// refuses new ones
+func TestClaimPool_Stop(t *testing.T) {
+	pool := NewClaimPool(ClaimPoolOptions{Workers: 1, QueueSize: 1})
+
+	// Occupy the worker, then queue a claim behind it
+	started, release := make(chan struct{}), make(chan struct{})
+	busy := make(chan error, 1)
+	go func() {
+		busy <- pool.Submit(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	queued := make(chan error, 1)
+	go func() { queued <- pool.Submit(context.Background(), func() error { return nil }) }()
+	require.Eventually(t, func() bool { return pool.Stats().QueueDepth == 1 }, time.Second, time.Millisecond,
+		"Claim should be queued behind the busy worker")
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+	require.Eventually(t, func() bool {
+		return errors.Is(pool.Submit(context.Background(), func() error { return nil }), ErrClaimPoolStopped)
+	}, time.Second, time.Millisecond, "Claims submitted while stopping should be refused")
+
+	close(release)
+	<-stopped
+	assert.NoError(t, <-busy, "Claim being processed should finish")
+	assert.NoError(t, <-queued, "Queued claim should be processed before the pool stops")
+
+	rejected := pool.Stats().Rejected
+	assert.ErrorIs(t, pool.Submit(context.Background(), func() error { return nil }), ErrClaimPoolStopped)
+	assert.Equal(t, rejected, pool.Stats().Rejected, "Refused claims should not count as rejected by a full queue")
+
+	pool.Stop() // Stopping again is harmless
+}
+
+// TestHTTPHandler_ClaimQueueFull tests that claims get 503 when the worker pool is saturated
+func TestHTTPHandler_ClaimQueueFull(t *testing.T) {
+	pool := NewClaimPool(ClaimPoolOptions{Workers: 1, QueueSize: 1})
+	defer pool.Stop()
+
+	handler := NewHTTPHandler(NewClaimStore())
+	handler.claimPool = pool
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	// Occupy the only worker, then fill the queue. Both claims finish before
+	// the pool stops.
+	var submitting sync.WaitGroup
+	defer submitting.Wait()
+	release := make(chan struct{})
+	defer close(release)
+	block := func() error {
+		<-release
+		return nil
+	}
+	submit := func(process func() error) {
+		submitting.Add(1)
+		go func() {
+			defer submitting.Done()
+			_ = pool.Submit(context.Background(), process)
+		}()
+	}
+	started := make(chan struct{})
+	submit(func() error {
+		close(started)
+		return block()
+	})
+	<-started
+	submit(block)
+	require.Eventually(t, func() bool { return pool.Stats().QueueDepth == 1 }, time.Second, time.Millisecond,
+		"Second claim should be queued")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/claim/2001:db8::1", strings.NewReader(`{"nonce":"0","name":"alice"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "Claim should be rejected while the pool is saturated")
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"), "Rejection should tell the client when to retry")
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+)
+
+// Channels claims arrive through
+const (
+	claimChannelHTTP    = "http"    // A single claim over the HTTP API
+	claimChannelSession = "session" // A single claim over the HTTP API, admitted by a session challenge
+	claimChannelBatch   = "batch"   // A batch of claims over the HTTP API
+	claimChannelUDP     = "udp"     // A UDP packet from the claimed address
+	claimChannelBot     = "bot"     // A simulated claimant
+)
+
+// maxUserAgentLength bounds the user agents recorded with claims
+const maxUserAgentLength = 256
+
+// ClaimSourceOptions configures recording where claims come from, such as the
+// client's address and user agent, which operators may want to turn off for
+// their players' privacy
+type ClaimSourceOptions struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// DefaultClaimSourceOptions returns the standard claim source options, recording sources
+func DefaultClaimSourceOptions() ClaimSourceOptions {
+	return ClaimSourceOptions{Enabled: true}
+}
+
+// SetClaimSourceOptions replaces the options used to record where claims come
+// from. Sources already recorded are kept until their addresses are claimed again.
+func (cs *ClaimStore) SetClaimSourceOptions(opts ClaimSourceOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.claimSources = opts
+}
+
+// claimSourceKey is the context key of the source of a claim
+type claimSourceKey struct{}
+
+// withClaimSource returns a context carrying where the claims made with it come from
+func withClaimSource(ctx context.Context, source ClaimSource) context.Context {
+	return context.WithValue(ctx, claimSourceKey{}, source)
+}
+
+// claimSourceLocked returns the source of a claim made with a context, empty
+// if it has none or sources are not recorded (assumes lock is held)
+func (cs *ClaimStore) claimSourceLocked(ctx context.Context) ClaimSource {
+	if !cs.claimSources.Enabled {
+		return ClaimSource{}
+	}
+	source, _ := ctx.Value(claimSourceKey{}).(ClaimSource)
+	return source
+}
+
+// httpClaimSource returns the source of claims made by an HTTP request
+func httpClaimSource(r *http.Request, channel string) ClaimSource {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	return ClaimSource{Channel: channel, RemoteIP: clientAddress(r), UserAgent: userAgent}
+}
+
+// parseUserAgent returns the product and version a user agent starts with,
+// such as "spacenet-tui" and "1.2.0" of "spacenet-tui/1.2.0 (linux)"
+func parseUserAgent(userAgent string) (string, string) {
+	product, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	client, version, _ := strings.Cut(product, "/")
+	return client, version
+}
+
+// sourceResponse describes a claim source to operators, nil if it is empty
+func sourceResponse(source ClaimSource) *api.ClaimSource {
+	if source == (ClaimSource{}) {
+		return nil
+	}
+	client, version := parseUserAgent(source.UserAgent)
+	return &api.ClaimSource{
+		Channel:       source.Channel,
+		RemoteIP:      source.RemoteIP,
+		UserAgent:     source.UserAgent,
+		Client:        client,
+		ClientVersion: version,
+	}
+}
+
+// SummarizeClients counts the current claims by the channel and client that made them
+func (cs *ClaimStore) SummarizeClients() api.ClientsResponse {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	response := api.ClientsResponse{Clients: []api.ClientUsage{}}
+	counts := make(map[api.ClientUsage]int)
+	for ipAddr := range cs.claims {
+		source := cs.metadata[ipAddr].Source
+		if source == (ClaimSource{}) {
+			response.Unknown++
+			continue
+		}
+		client, version := parseUserAgent(source.UserAgent)
+		counts[api.ClientUsage{Channel: source.Channel, Client: client, ClientVersion: version}]++
+	}
+
+	for usage, claims := range counts {
+		usage.Claims = claims
+		response.Clients = append(response.Clients, usage)
+	}
+	slices.SortFunc(response.Clients, func(a, b api.ClientUsage) int {
+		return cmp.Or(
+			cmp.Compare(b.Claims, a.Claims),
+			cmp.Compare(a.Channel, b.Channel),
+			cmp.Compare(a.Client, b.Client),
+			cmp.Compare(a.ClientVersion, b.ClientVersion),
+		)
+	})
+	return response
+}
+
+// clientSummarizer is implemented by stores that count claims by the client that made them
+type clientSummarizer interface {
+	SummarizeClients() api.ClientsResponse
+}
+
+// handleAdminGetClaim returns the claim on an address with where it came from
+func (h *HTTPHandler) handleAdminGetClaim(w http.ResponseWriter, r *http.Request) {
+	ipAddr := mux.Vars(r)["ip"]
+	if net.ParseIP(ipAddr) == nil {
+		writeError(w, r, badRequest("invalid address"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	claimant, exists := h.store.GetClaim(ctx, ipAddr)
+	if !exists {
+		writeError(w, r, notFound("address is not claimed"))
+		return
+	}
+	response := api.AdminClaimResponse{
+		ClaimResponse: h.claimResponse(ctx, ipAddr, claimant),
+		IP:            ipAddr,
+	}
+	if metadata, exists := h.store.GetClaimMetadata(ctx, ipAddr); exists {
+		response.Source = sourceResponse(metadata.Source)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminGetClients counts the current claims by the client that made them
+func (h *HTTPHandler) handleAdminGetClients(w http.ResponseWriter, r *http.Request) {
+	summarizer, ok := h.store.(clientSummarizer)
+	if !ok {
+		writeError(w, r, notFound("store does not record claim sources"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summarizer.SummarizeClients()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_ClaimSources tests that the source of the latest claim on an
+// address is recorded and persisted, unless collection is disabled
+func TestClaimStore_ClaimSources(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sources.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+
+	tui := ClaimSource{Channel: claimChannelHTTP, RemoteIP: "2001:db8:ffff::1", UserAgent: "spacenet-tui/1.2.0"}
+	require.NoError(t, store.ProcessClaim(withClaimSource(t.Context(), tui), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	batch := ClaimSource{Channel: claimChannelBatch, RemoteIP: "2001:db8:ffff::2", UserAgent: "curl/8.5.0"}
+	require.NoError(t, store.ProcessClaims(withClaimSource(t.Context(), batch), []BatchClaim{
+		{IP: "2001:db8::3", Claimant: "bob"},
+		{IP: "2001:db8::4", Claimant: "bob"},
+	}))
+
+	metadata, _ := store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, tui, metadata.Source, "Claims should record their source")
+	metadata, _ = store.GetClaimMetadata(t.Context(), "2001:db8::2")
+	assert.Empty(t, metadata.Source, "Claims without a source should record none")
+	metadata, _ = store.GetClaimMetadata(t.Context(), "2001:db8::4")
+	assert.Equal(t, batch, metadata.Source, "Every claim of a batch should record its source")
+
+	clients := store.SummarizeClients()
+	assert.Equal(t, []api.ClientUsage{
+		{Channel: claimChannelBatch, Client: "curl", ClientVersion: "8.5.0", Claims: 2},
+		{Channel: claimChannelHTTP, Client: "spacenet-tui", ClientVersion: "1.2.0", Claims: 1},
+	}, clients.Clients, "Claims should be counted by client, most first")
+	assert.Equal(t, 1, clients.Unknown)
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	metadata, _ = reopened.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, tui, metadata.Source, "Sources should persist")
+	metadata, _ = reopened.GetClaimMetadata(t.Context(), "2001:db8::3")
+	assert.Equal(t, batch, metadata.Source, "Sources of batches should persist")
+
+	reopened.SetClaimSourceOptions(ClaimSourceOptions{Enabled: false})
+	require.NoError(t, reopened.ProcessClaim(withClaimSource(t.Context(), tui), "2001:db8::1", "carol"))
+	metadata, _ = reopened.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Empty(t, metadata.Source, "Nothing should be recorded once collection is disabled")
+}
+
+// TestClaimStore_JournalClaimSources tests that sources are replayed from the journal
+func TestClaimStore_JournalClaimSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.journal")
+	source := ClaimSource{Channel: claimChannelUDP, RemoteIP: "2001:db8::1"}
+	require.NoError(t, openJournaled(t, path).ProcessClaim(withClaimSource(t.Context(), source), "2001:db8::1", "alice"))
+
+	metadata, _ := openJournaled(t, path).GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, source, metadata.Source)
+}
+
+// TestHTTPHandler_AdminClaimSources tests that operators, and only operators,
+// see where claims submitted over HTTP came from
+func TestHTTPHandler_AdminClaimSources(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::1"), "alice", store.CalculateDifficulty(t.Context(), "2001:db8::1"), 1000000)
+	require.NoError(t, err, "Should solve proof of work")
+	data, err := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: pow.Nonce})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/claim/2001:db8::1", bytes.NewReader(data))
+	req.Header.Set("User-Agent", "spacenet-tui/1.2.0 "+strings.Repeat("x", maxUserAgentLength))
+	req.RemoteAddr = "[2001:db8:ffff::1]:40000"
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code, "Claim should be accepted")
+
+	get := func(path string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.NotContains(t, get("/api/v1/claim/2001:db8::1", "").Body.String(), "2001:db8:ffff::1",
+		"Players should not see where claims came from")
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v1/admin/claims/2001:db8::1", "").Code)
+
+	rr = get("/api/v1/admin/claims/2001:db8::1", "0123456789abcdef")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var claim api.AdminClaimResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&claim))
+	assert.Equal(t, "alice", claim.Name)
+	require.NotNil(t, claim.Source, "Operators should see where claims came from")
+	assert.Equal(t, claimChannelHTTP, claim.Source.Channel)
+	assert.Equal(t, "2001:db8:ffff::1", claim.Source.RemoteIP)
+	assert.Len(t, claim.Source.UserAgent, maxUserAgentLength, "Long user agents should be truncated")
+	assert.Equal(t, "spacenet-tui", claim.Source.Client)
+	assert.Equal(t, "1.2.0", claim.Source.ClientVersion)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/admin/claims/2001:db8::2", "0123456789abcdef").Code)
+
+	rr = get("/api/v1/admin/clients", "0123456789abcdef")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var clients api.ClientsResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&clients))
+	assert.Equal(t, []api.ClientUsage{{Channel: claimChannelHTTP, Client: "spacenet-tui", ClientVersion: "1.2.0", Claims: 1}},
+		clients.Clients)
+}
+
+// TestParseUserAgent tests extracting the client and version of user agents
+func TestParseUserAgent(t *testing.T) {
+	testCases := map[string][2]string{
+		"spacenet-tui/1.2.0":                  {"spacenet-tui", "1.2.0"},
+		"Mozilla/5.0 (X11; Linux x86_64)":     {"Mozilla", "5.0"},
+		"  Go-http-client/1.1":                {"Go-http-client", "1.1"},
+		"custom-bot":                          {"custom-bot", ""},
+		"":                                    {"", ""},
+		"spacenet-tui/v0.0.0-20260101 (beta)": {"spacenet-tui", "v0.0.0-20260101"},
+	}
+	for userAgent, want := range testCases {
+		client, version := parseUserAgent(userAgent)
+		assert.Equal(t, want, [2]string{client, version}, "User agent %q", userAgent)
+	}
+}
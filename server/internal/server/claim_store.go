@@ -1,21 +1,52 @@
 package server
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ClaimStore is an in-memory store for IP address claims
 // It can optionally use SQLite as a backend store
 type ClaimStore struct {
-	mutex    sync.RWMutex
-	claims   map[string]string // map[ipAddress]claimantName
-	ipTree   *IPTree           // Hierarchical tree for subnet-based queries
-	db       *sql.DB           // Optional SQLite database for persistence
-	dbPath   string            // Path to SQLite database file
+	mutex         sync.RWMutex
+	claims        map[string]string        // map[ipAddress]claimantName
+	metadata      map[string]ClaimMetadata // Claim history by IP address
+	ipTree        *IPTree                  // Hierarchical tree for subnet-based queries
+	blocks        *blockIndex              // Complete /124s and /120s by claimant
+	db            *sql.DB                  // Optional SQLite database for persistence
+	dbPath        string                   // Path to SQLite database file
+	difficulty    DifficultyParams         // Parameters for proof of work difficulty
+	powScheme     api.PoWScheme            // Hash function of proofs of work
+	fortification FortificationOptions     // Defense levels bought with extra proof of work
+	claimSources  ClaimSourceOptions       // Whether where claims come from is recorded
+	anomalies     *anomalyMonitor          // Watches claims for abuse, nil if disabled
+	heat          *claimHeat               // Heat of recently contested addresses, nil if disabled
+	events        *EventBroker             // Live feed of claim events
+	activity      *ActivityLog             // Recent claims for activity statistics, nil if disabled
+	grants        map[string]subnetGrant   // Granted subnets by CIDR
+	names         map[string]string        // Claimant display names by canonical skeleton
+	subnets       *names.Index             // Generated names of claimed and granted subnets
+	readOnly      bool                     // Replica of a primary's database, loaded by Refresh
+	refreshMutex  sync.Mutex               // Serializes refreshes of a replica
+	watermark     string                   // Latest claim update time a replica has loaded
+	limits        *claimLimits             // Size limits of the store, nil if unlimited
+	supply        *supplyLines             // Supply lines rule, nil if disabled
+	protected     []*net.IPNet             // Prefixes nobody may claim addresses in
+	journal       *journal                 // Append-only file of changes to an in-memory store, nil if disabled
+	loading       *Lifecycle               // Reports progress while claims are loaded on startup, nil if untracked
+	logger        *slog.Logger
 }
 
 // Verify ClaimStore implements Store interface
@@ -24,13 +55,37 @@ var _ Store = (*ClaimStore)(nil)
 // NewClaimStore creates a new in-memory claim store without SQLite
 func NewClaimStore() *ClaimStore {
 	return &ClaimStore{
-		claims: make(map[string]string),
-		ipTree: NewIPTree(),
+		claims:       make(map[string]string),
+		metadata:     make(map[string]ClaimMetadata),
+		ipTree:       NewIPTree(),
+		blocks:       newBlockIndex(),
+		difficulty:   DefaultDifficultyParams(),
+		powScheme:    api.SHA256Scheme{},
+		claimSources: DefaultClaimSourceOptions(),
+		events:       NewEventBroker(),
+		activity:     NewActivityLog(DefaultActivityOptions()),
+		grants:       make(map[string]subnetGrant),
+		names:        make(map[string]string),
+		subnets:      names.NewIndex(),
+		logger:       componentLogger("store"),
 	}
 }
 
 // NewClaimStoreWithSQLite creates a claim store with SQLite backend
 func NewClaimStoreWithSQLite(dbPath string) (*ClaimStore, error) {
+	store, err := openClaimStoreWithSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.loadSQLite(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// openClaimStoreWithSQLite creates a claim store with SQLite backend without
+// loading its claims, which servers do once they are listening
+func openClaimStoreWithSQLite(dbPath string) (*ClaimStore, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, err
@@ -42,10 +97,21 @@ func NewClaimStoreWithSQLite(dbPath string) (*ClaimStore, error) {
 	}
 
 	store := &ClaimStore{
-		claims: make(map[string]string),
-		ipTree: NewIPTree(),
-		db:     db,
-		dbPath: dbPath,
+		claims:       make(map[string]string),
+		metadata:     make(map[string]ClaimMetadata),
+		ipTree:       NewIPTree(),
+		blocks:       newBlockIndex(),
+		db:           db,
+		dbPath:       dbPath,
+		difficulty:   DefaultDifficultyParams(),
+		powScheme:    api.SHA256Scheme{},
+		claimSources: DefaultClaimSourceOptions(),
+		events:       NewEventBroker(),
+		activity:     NewActivityLog(DefaultActivityOptions()),
+		grants:       make(map[string]subnetGrant),
+		names:        make(map[string]string),
+		subnets:      names.NewIndex(),
+		logger:       componentLogger("store"),
 	}
 
 	// Initialize database schema
@@ -53,12 +119,36 @@ func NewClaimStoreWithSQLite(dbPath string) (*ClaimStore, error) {
 		return nil, err
 	}
 
-	// Load existing claims from SQLite
-	if err := store.loadFromSQLite(); err != nil {
-		return nil, err
+	return store, nil
+}
+
+// loadSQLite loads the existing names, claims and grants from SQLite
+func (cs *ClaimStore) loadSQLite() error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	var total int
+	if err := cs.db.QueryRow("SELECT COUNT(*) FROM claims WHERE released_at IS NULL").Scan(&total); err != nil {
+		return err
 	}
+	cs.loading.begin(startupDatabase, total)
 
-	return store, nil
+	// Sized up front, so the maps don't grow through millions of claims
+	cs.claims = make(map[string]string, total)
+	cs.metadata = make(map[string]ClaimMetadata, total)
+
+	if err := cs.loadNamesFromSQLite(); err != nil {
+		return err
+	}
+	if err := cs.loadFromSQLite(); err != nil {
+		return err
+	}
+	if err := cs.loadGrantsFromSQLite(); err != nil {
+		return err
+	}
+	cs.rebuildLocked()
+	cs.logger.Info("Loaded claims from SQLite", "path", cs.dbPath, "claims", len(cs.claims), "grants", len(cs.grants))
+	return nil
 }
 
 // initSchema creates the database schema if it doesn't exist
@@ -71,92 +161,394 @@ func (cs *ClaimStore) initSchema() error {
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
 		CREATE INDEX IF NOT EXISTS idx_claimant ON claims(claimant);
+		CREATE TABLE IF NOT EXISTS score_history (
+			name TEXT NOT NULL,
+			tick TIMESTAMP NOT NULL,
+			points INTEGER NOT NULL,
+			score INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_score_history_name ON score_history(name, tick);
+		CREATE TABLE IF NOT EXISTS claimant_names (
+			canonical TEXT PRIMARY KEY,
+			display TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS subnet_grants (
+			subnet TEXT PRIMARY KEY,
+			claimant TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS delegation_tokens (
+			id TEXT PRIMARY KEY,
+			hash TEXT NOT NULL UNIQUE,
+			claimant TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			max_claims INTEGER NOT NULL,
+			claims INTEGER NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		);
 	`
-	_, err := cs.db.Exec(schema)
-	return err
+	if _, err := cs.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Claim metadata columns were added after the claims table
+	added, err := cs.addColumnIfMissing("claims", "claimed_at", "TIMESTAMP")
+	if err != nil {
+		return err
+	}
+	if added {
+		if _, err := cs.db.Exec("UPDATE claims SET claimed_at = updated_at"); err != nil {
+			return err
+		}
+	}
+	if _, err := cs.addColumnIfMissing("claims", "takeover_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := cs.addColumnIfMissing("claims", "fortification", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if _, err := cs.addColumnIfMissing("claims", "fortified_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	for _, column := range []string{"source_channel", "source_ip", "source_user_agent"} {
+		if _, err := cs.addColumnIfMissing("claims", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	// Released claims are kept for their history
+	if _, err := cs.addColumnIfMissing("claims", "released_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+
+	return cs.initEventLog()
+}
+
+// addColumnIfMissing adds a column to an existing table, reporting whether it was added
+func (cs *ClaimStore) addColumnIfMissing(table string, column string, definition string) (bool, error) {
+	rows, err := cs.db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	_, err = cs.db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err == nil, err
+}
+
+// nullTime converts a time to a nullable column value, NULL if it is zero
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
 
 // loadFromSQLite loads all claims from SQLite into memory
 func (cs *ClaimStore) loadFromSQLite() error {
-	rows, err := cs.db.Query("SELECT ip_address, claimant FROM claims")
+	rows, err := cs.db.Query(
+		`SELECT ip_address, claimant, claimed_at, takeover_count, fortification, fortified_at,
+			source_channel, source_ip, source_user_agent
+		FROM claims WHERE released_at IS NULL`,
+	)
 	if err != nil {
 		return err
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
-			log.Printf("Error closing rows: %v", err)
+			cs.logger.Error("Error closing rows", "error", err)
 		}
 	}()
 
+	// Claimants hold many addresses each, so their names are checked once
+	checked := make(map[string]string)
+	userAgents := make(map[string]string)
 	for rows.Next() {
 		var ipAddr, claimant string
-		if err := rows.Scan(&ipAddr, &claimant); err != nil {
+		var metadata ClaimMetadata
+		var fortifiedAt sql.NullTime
+		source := &metadata.Source
+		if err := rows.Scan(&ipAddr, &claimant, &metadata.ClaimedAt, &metadata.TakeoverCount, &metadata.Fortification, &fortifiedAt,
+			&source.Channel, &source.RemoteIP, &source.UserAgent); err != nil {
 			return err
 		}
+		metadata.FortifiedAt = fortifiedAt.Time
 
-		// Store in memory
+		// Most claims come from a few clients, which share one copy of their user agent
+		if userAgent, ok := userAgents[source.UserAgent]; ok {
+			source.UserAgent = userAgent
+		} else {
+			userAgents[source.UserAgent] = source.UserAgent
+		}
+
+		// Share one copy of each claimant's name between their claims
+		if name, ok := checked[claimant]; ok {
+			claimant = name
+		} else {
+			checked[claimant] = claimant
+			// Claims from before names were registered keep their names
+			if skeleton := nameSkeleton(claimant); cs.names[skeleton] == "" {
+				cs.names[skeleton] = claimant
+			}
+		}
+
+		// Store in memory, building the tree once every claim is loaded
 		cs.claims[ipAddr] = claimant
-		// Update the tree
-		cs.ipTree.processClaim(ipAddr, claimant, "")
+		cs.metadata[ipAddr] = metadata
+		cs.loading.advance(1)
 	}
 
 	return rows.Err()
 }
 
-// ProcessClaim processes a claim request and updates the store
-// Note: Updated to overwrite existing claims as per new requirements
-func (cs *ClaimStore) ProcessClaim(ipAddr string, claimant string) error {
+// ProcessClaim processes a claim as a child of the span in ctx, tracing
+// persistence and the tree update separately. Existing claims are overwritten.
+func (cs *ClaimStore) ProcessClaim(ctx context.Context, ipAddr string, claimant string) (err error) {
+	ctx, span := tracer.Start(ctx, "store.processClaim", trace.WithAttributes(
+		attribute.String("spacenet.ip", ipAddr),
+		attribute.String("spacenet.claimant", claimant),
+	))
+	defer func() { endSpan(span, err) }()
+
 	cs.mutex.Lock()
 	defer cs.mutex.Unlock()
 
+	if err := cs.checkClaimableLocked(ipAddr); err != nil {
+		return err
+	}
+	if err := cs.anomalies.check(claimant); err != nil {
+		return err
+	}
+	if err := cs.checkSupplyLocked(ipAddr, claimant); err != nil {
+		return err
+	}
+	// Refused names must not evict anything to make room
+	if err := cs.checkNameLocked(claimant); err != nil {
+		return err
+	}
+	if _, exists := cs.claims[ipAddr]; !exists {
+		if err := cs.admitLocked(ctx, []string{ipAddr}, nil); err != nil {
+			return err
+		}
+	}
+	if err := cs.registerNameLocked(claimant); err != nil {
+		return err
+	}
+
 	// Get existing claimant if any
 	oldClaimant, exists := cs.claims[ipAddr]
+	oldMetadata := cs.metadata[ipAddr]
+
+	now := time.Now().UTC()
+	difficulty := cs.difficultyLocked(ipAddr, now)
+	metadata := nextClaimMetadata(oldClaimant, exists, oldMetadata, claimant, now)
+	metadata.Source = cs.claimSourceLocked(ctx)
 
 	// Store new claim in memory
 	cs.claims[ipAddr] = claimant
+	cs.metadata[ipAddr] = metadata
 
-	// If SQLite is enabled, write through to SQLite
-	if cs.db != nil {
-		var err error
-		if exists {
+	// If SQLite or the journal is enabled, write through to it
+	if cs.db != nil || cs.journal != nil {
+		_, persist := tracer.Start(ctx, "store.persist")
+		if cs.journal != nil {
+			err = cs.journal.append(claimEntry(ipAddr, claimant, metadata))
+		} else if exists {
 			// Update existing claim
-			_, err = cs.db.Exec(
-				"UPDATE claims SET claimant = ?, updated_at = CURRENT_TIMESTAMP WHERE ip_address = ?",
-				claimant, ipAddr,
+			_, err = cs.db.ExecContext(ctx,
+				`UPDATE claims SET claimant = ?, claimed_at = ?, takeover_count = ?, fortification = ?, fortified_at = ?,
+					source_channel = ?, source_ip = ?, source_user_agent = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE ip_address = ?`,
+				claimant, metadata.ClaimedAt, metadata.TakeoverCount, metadata.Fortification, nullTime(metadata.FortifiedAt),
+				metadata.Source.Channel, metadata.Source.RemoteIP, metadata.Source.UserAgent, ipAddr,
 			)
 		} else {
-			// Insert new claim
-			_, err = cs.db.Exec(
-				"INSERT INTO claims (ip_address, claimant) VALUES (?, ?)",
-				ipAddr, claimant,
+			// Insert new claim, replacing any released one
+			_, err = cs.db.ExecContext(ctx,
+				`INSERT INTO claims (ip_address, claimant, claimed_at, source_channel, source_ip, source_user_agent)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT(ip_address) DO UPDATE SET claimant = excluded.claimant, claimed_at = excluded.claimed_at,
+					takeover_count = 0, fortification = 0, fortified_at = NULL, source_channel = excluded.source_channel,
+					source_ip = excluded.source_ip, source_user_agent = excluded.source_user_agent,
+					released_at = NULL, updated_at = CURRENT_TIMESTAMP`,
+				ipAddr, claimant, metadata.ClaimedAt, metadata.Source.Channel, metadata.Source.RemoteIP, metadata.Source.UserAgent,
 			)
 		}
+		endSpan(persist, err)
 
 		if err != nil {
-			// If SQLite fails, revert the in-memory change and propagate error
+			cs.logger.Error("Failed to persist claim", "ip", ipAddr, "claimant", claimant, "error", err)
+			// If persisting fails, revert the in-memory change and propagate error
 			if exists {
 				cs.claims[ipAddr] = oldClaimant
+				cs.metadata[ipAddr] = oldMetadata
 			} else {
 				delete(cs.claims, ipAddr)
+				delete(cs.metadata, ipAddr)
 			}
-			return err
+			return backendError(err)
 		}
 	}
 
 	// Update tree with hierarchical information
+	_, update := tracer.Start(ctx, "tree.update")
 	if exists {
 		// We're updating an existing claim
 		cs.ipTree.processClaim(ipAddr, claimant, oldClaimant)
 	} else {
 		// New claim
 		cs.ipTree.processClaim(ipAddr, claimant, "")
+		cs.indexSubnetNames(ipAddr)
+	}
+	if oldClaimant != claimant {
+		cs.blocks.update(cs.claims, ipAddr)
+		cs.supply.moved(oldClaimant, claimant)
+	}
+	update.End()
+
+	// Publish ownership changes, duplicate claims by the owner are not events
+	if oldClaimant != claimant {
+		cs.limits.touch(ipAddr)
+		event := api.ClaimEvent{
+			Type:             api.EventTypeClaim,
+			IP:               ipAddr,
+			Claimant:         claimant,
+			PreviousClaimant: oldClaimant,
+			Timestamp:        now,
+			Difficulty:       difficulty,
+		}
+		cs.activity.record(event)
+		cs.heat.record(event)
+		cs.events.Publish(event)
 	}
+	cs.anomalies.observe(ClaimObservation{IP: ipAddr, Claimant: claimant, Source: metadata.Source, Difficulty: difficulty, At: now})
 
 	return nil
 }
 
+// rebuildLocked builds the subnet tree, names, limits and supply lines from
+// the claims and grants after loading them in bulk, on every CPU (assumes
+// lock is held)
+func (cs *ClaimStore) rebuildLocked() {
+	cs.loading.begin(startupTree, len(cs.claims))
+	cs.ipTree.build(cs.claims, cs.loading.advance)
+	cs.blocks = buildBlockIndex(cs.claims)
+
+	subnets := make([]string, 0, len(cs.claims)+len(cs.grants))
+	for ipAddr := range cs.claims {
+		subnets = append(subnets, ipAddr)
+	}
+	for subnet := range cs.grants {
+		subnets = append(subnets, subnet)
+	}
+	cs.loading.begin(startupNames, 0)
+	if err := cs.subnets.AddAll(subnets, cs.loading.advance); err != nil {
+		cs.logger.Warn("Failed to index subnet names", "error", err)
+	}
+
+	// Limits and supply lines may have been set before the claims loaded
+	cs.indexLimitsLocked()
+	cs.countSupplyLocked()
+}
+
+// indexSubnetNames makes the names of an address or subnet and of the subnets
+// containing it resolvable
+func (cs *ClaimStore) indexSubnetNames(subnet string) {
+	if err := cs.subnets.Add(subnet); err != nil {
+		cs.logger.Warn("Failed to index subnet names", "subnet", subnet, "error", err)
+	}
+}
+
+// ResolveName returns the claimed or granted subnets with a generated name
+func (cs *ClaimStore) ResolveName(ctx context.Context, name string) []string {
+	return cs.subnets.Resolve(name)
+}
+
+// SubscribeEvents subscribes to the live feed of claim events
+func (cs *ClaimStore) SubscribeEvents(ctx context.Context) (<-chan api.ClaimEvent, func()) {
+	return cs.events.Subscribe()
+}
+
+// GetLeaderboard returns claimants ranked by the number of addresses they hold,
+// limited to the top limit entries when limit is positive
+func (cs *ClaimStore) GetLeaderboard(ctx context.Context, limit int) []api.LeaderboardEntry {
+	cs.mutex.RLock()
+	counts := make(map[string]int)
+	for _, claimant := range cs.claims {
+		counts[claimant]++
+	}
+	blocks := cs.blocksHeldLocked()
+	cs.mutex.RUnlock()
+
+	leaderboard := make([]api.LeaderboardEntry, 0, len(counts))
+	for name, addresses := range counts {
+		leaderboard = append(leaderboard, api.LeaderboardEntry{
+			Name:      name,
+			Addresses: addresses,
+			Blocks124: blocks[name][block124],
+			Blocks120: blocks[name][block120],
+		})
+	}
+
+	// Most addresses first, ties broken by name for a stable ordering
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Addresses != leaderboard[j].Addresses {
+			return leaderboard[i].Addresses > leaderboard[j].Addresses
+		}
+		return leaderboard[i].Name < leaderboard[j].Name
+	})
+
+	if limit > 0 && len(leaderboard) > limit {
+		leaderboard = leaderboard[:limit]
+	}
+
+	return leaderboard
+}
+
+// GetStats returns global statistics about the game
+func (cs *ClaimStore) GetStats(ctx context.Context) api.StatsResponse {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	claimants := make(map[string]struct{})
+	for _, claimant := range cs.claims {
+		claimants[claimant] = struct{}{}
+	}
+
+	return api.StatsResponse{
+		TotalClaims: len(cs.claims),
+		Claimants:   len(claimants),
+	}
+}
+
+// nextClaimMetadata returns the metadata of an address after a claim. A change
+// of owner restarts the claim, razing any fortification, and counts as a takeover.
+func nextClaimMetadata(oldClaimant string, exists bool, oldMetadata ClaimMetadata, claimant string, now time.Time) ClaimMetadata {
+	if !exists {
+		return ClaimMetadata{ClaimedAt: now}
+	}
+	if oldClaimant != claimant {
+		return ClaimMetadata{ClaimedAt: now, TakeoverCount: oldMetadata.TakeoverCount + 1}
+	}
+	return oldMetadata
+}
+
 // GetClaim retrieves the claimant for an IP address
-func (cs *ClaimStore) GetClaim(ipAddr string) (string, bool) {
+func (cs *ClaimStore) GetClaim(ctx context.Context, ipAddr string) (string, bool) {
 	cs.mutex.RLock()
 	defer cs.mutex.RUnlock()
 
@@ -164,13 +556,56 @@ func (cs *ClaimStore) GetClaim(ipAddr string) (string, bool) {
 	return claimant, exists
 }
 
-// GetSubnetStats retrieves statistics for a specific subnet
-func (cs *ClaimStore) GetSubnetStats(subnet string) (*SubnetStats, bool) {
-	return cs.ipTree.GetSubnetStats(subnet)
+// GetSubnetStats retrieves statistics for a specific subnet,
+// including the top N claimants when topN is positive
+func (cs *ClaimStore) GetSubnetStats(ctx context.Context, subnet string, topN int) (*SubnetStats, bool) {
+	stats, ok := cs.ipTree.GetSubnetStats(subnet, topN)
+	if ok && stats.Owner == "" {
+		// Granted subnets are owned by the grantee unless someone holds a majority by claims
+		if grantee, granted := cs.subnetGrantee(subnet); granted {
+			stats.Owner = grantee
+			stats.Percentage = 100
+			stats.Granted = true
+		}
+	}
+	return stats, ok
+}
+
+// GetLevelStats returns the distribution of dominance over the claimed subnets
+// at each tracked prefix length, kept up to date by the tree on every claim
+func (cs *ClaimStore) GetLevelStats(ctx context.Context) []api.LevelStats {
+	return cs.ipTree.LevelStats()
+}
+
+// GetAllSubnets retrieves statistics for all claimed subnets at a standard prefix length
+func (cs *ClaimStore) GetAllSubnets(ctx context.Context, prefixLen int) ([]api.SubnetListEntry, bool) {
+	return cs.ipTree.GetAllSubnets(prefixLen)
+}
+
+// ListSubnets retrieves a sorted, filtered page of the claimed subnets at a
+// standard prefix length, with the number matching before paging
+func (cs *ClaimStore) ListSubnets(ctx context.Context, prefixLen int, opts SubnetListOptions) ([]api.SubnetListEntry, int, bool) {
+	return cs.ipTree.ListSubnets(prefixLen, opts)
+}
+
+// SummarizeOwners counts the subnets at a standard prefix length within a
+// parent subnet that each claimant owns, and the addresses each claimed there
+func (cs *ClaimStore) SummarizeOwners(ctx context.Context, parent *net.IPNet, prefixLen int) (*api.OwnersResponse, bool) {
+	return cs.ipTree.SummarizeOwners(parent, prefixLen)
+}
+
+// GetClaimMetadata retrieves the history of the claim on an IP address
+func (cs *ClaimStore) GetClaimMetadata(ctx context.Context, ipAddr string) (ClaimMetadata, bool) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	metadata, exists := cs.metadata[ipAddr]
+	metadata.Fortification = cs.fortification.level(metadata, time.Now().UTC())
+	return metadata, exists
 }
 
 // GetAllClaims returns all claims in the store
-func (cs *ClaimStore) GetAllClaims() map[string]string {
+func (cs *ClaimStore) GetAllClaims(ctx context.Context) map[string]string {
 	cs.mutex.RLock()
 	defer cs.mutex.RUnlock()
 
@@ -183,10 +618,43 @@ func (cs *ClaimStore) GetAllClaims() map[string]string {
 	return claims
 }
 
+// Reset removes every claim from the store
+func (cs *ClaimStore) Reset(ctx context.Context) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if cs.db != nil {
+		if _, err := cs.db.ExecContext(ctx, "DELETE FROM claims; DELETE FROM subnet_grants; DELETE FROM claimant_names"); err != nil {
+			return backendError(err)
+		}
+	}
+	if err := cs.journal.append(journalEntry{Op: journalReset}); err != nil {
+		return backendError(err)
+	}
+
+	cs.claims = make(map[string]string)
+	cs.metadata = make(map[string]ClaimMetadata)
+	cs.grants = make(map[string]subnetGrant)
+	cs.names = make(map[string]string)
+	cs.subnets.Reset()
+	cs.ipTree.reset()
+	cs.blocks = newBlockIndex()
+	cs.activity.clear()
+	cs.heat.clear()
+	cs.limits.clear()
+	cs.supply.clear()
+
+	return nil
+}
+
 // Close releases any resources held by the store
 func (cs *ClaimStore) Close() error {
+	var err error
+	if cs.journal != nil {
+		err = cs.journal.close()
+	}
 	if cs.db != nil {
-		return cs.db.Close()
+		err = errors.Join(err, cs.db.Close())
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchmarkSizes are the numbers of existing claims benchmarks run against
+var benchmarkSizes = []int{10_000, 100_000, 1_000_000}
+
+// benchmarkStores caches populated stores across benchmarks, since populating
+// the larger sizes dominates the run time. Benchmarks only change owners of
+// existing addresses, so the shape of the tree stays the same.
+var benchmarkStores = make(map[int]*ClaimStore)
+
+// benchmarkAddress returns a distinct address for i, spread over many /48s and /64s
+// so every level of the tree is exercised
+func benchmarkAddress(i int) string {
+	return fmt.Sprintf("2001:db8:%x:%x::%x", i>>16&0xffff, i>>8&0xff, i&0xff)
+}
+
+// benchmarkStore returns a store holding size claims by 100 claimants
+func benchmarkStore(b *testing.B, size int) *ClaimStore {
+	if store, exists := benchmarkStores[size]; exists {
+		return store
+	}
+
+	store := NewClaimStore()
+	for i := range size {
+		if err := store.ProcessClaim(b.Context(), benchmarkAddress(i), fmt.Sprintf("player%d", i%100)); err != nil {
+			b.Fatalf("Failed to populate store: %v", err)
+		}
+	}
+	benchmarkStores[size] = store
+	return store
+}
+
+// runSizes runs a benchmark against each store size, skipping the largest in short mode
+func runSizes(b *testing.B, bench func(b *testing.B, size int)) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("claims=%d", size), func(b *testing.B) {
+			if testing.Short() && size > 100_000 {
+				b.Skip("Skipping large store in short mode")
+			}
+			bench(b, size)
+		})
+	}
+}
+
+func BenchmarkClaimStore_ProcessClaim(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		store := benchmarkStore(b, size)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			// Alternate claimants so every claim is a takeover that updates the tree
+			if err := store.ProcessClaim(b.Context(), benchmarkAddress(i%size), fmt.Sprintf("bench%d", i%2)); err != nil {
+				b.Fatalf("Failed to process claim: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkClaimStore_GetSubnetStats(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		store := benchmarkStore(b, size)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if _, ok := store.GetSubnetStats(b.Context(), "2001:db8::/32", 0); !ok {
+				b.Fatal("Failed to get subnet stats")
+			}
+		}
+	})
+}
+
+func BenchmarkClaimStore_GetSubnetStatsEmpty(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		store := benchmarkStore(b, size)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			// Unclaimed subnets, as seen when scrolling the TUI past the claimed area
+			if _, ok := store.GetSubnetStats(b.Context(), fmt.Sprintf("2001:db9:%x::/48", i&0xffff), 0); !ok {
+				b.Fatal("Failed to get subnet stats")
+			}
+		}
+	})
+}
+
+func BenchmarkClaimStore_GetAllSubnets(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		store := benchmarkStore(b, size)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if _, ok := store.GetAllSubnets(b.Context(), 64); !ok {
+				b.Fatal("Failed to get subnets")
+			}
+		}
+	})
+}
+
+func BenchmarkIPTree_Build(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		claims := make(map[string]string, size)
+		for i := range size {
+			claims[benchmarkAddress(i)] = fmt.Sprintf("player%d", i%100)
+		}
+		tree := NewIPTree()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.build(claims, nil)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrNameConfusable is returned when a claimant name looks like a name already in use
+var ErrNameConfusable = errors.New("name is confusable with an existing claimant")
+
+// confusables maps characters that look like Latin letters or digits to the
+// character they imitate. It covers the common Cyrillic and Greek homoglyphs
+// from Unicode TR39 after case folding, not the full confusables table.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'н': 'h', 'і': 'i',
+	'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's',
+	'т': 't', 'у': 'y', 'ԝ': 'w', 'х': 'x',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'h', 'ι': 'i', 'κ': 'k', 'μ': 'm', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ζ': 'z',
+	// Digits and symbols
+	'0': 'o', '1': 'l', '|': 'l',
+}
+
+// nameSkeleton returns the canonical form of a claimant name used to detect
+// impersonation: compatibility decomposed, case folded, stripped of combining
+// marks and invisible characters, with homoglyphs replaced by what they imitate.
+// Names with the same skeleton look alike, e.g. "alice", "ALICE" and "аlice"
+// with a Cyrillic а.
+func nameSkeleton(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if mapped, exists := confusables[r]; exists {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// checkNameLocked rejects claimant names that look like another claimant's,
+// changing nothing. The caller must hold the lock.
+func (cs *ClaimStore) checkNameLocked(claimant string) error {
+	if display, exists := cs.names[nameSkeleton(claimant)]; exists && display != claimant {
+		return fmt.Errorf("%w %q", ErrNameConfusable, display)
+	}
+	return nil
+}
+
+// registerNameLocked records the display and canonical forms of a claimant
+// name, rejecting names that look like another claimant's. The caller must
+// hold the write lock.
+func (cs *ClaimStore) registerNameLocked(claimant string) error {
+	if err := cs.checkNameLocked(claimant); err != nil {
+		return err
+	}
+	skeleton := nameSkeleton(claimant)
+	if _, exists := cs.names[skeleton]; exists {
+		return nil
+	}
+
+	if cs.db != nil {
+		if _, err := cs.db.Exec(
+			"INSERT INTO claimant_names (canonical, display) VALUES (?, ?)",
+			skeleton, claimant,
+		); err != nil {
+			return backendError(err)
+		}
+	}
+	if err := cs.journal.append(journalEntry{Op: journalName, Canonical: skeleton, Claimant: claimant}); err != nil {
+		return backendError(err)
+	}
+
+	cs.names[skeleton] = claimant
+	return nil
+}
+
+// loadNamesFromSQLite loads the registered claimant names from SQLite into memory
+func (cs *ClaimStore) loadNamesFromSQLite() error {
+	rows, err := cs.db.Query("SELECT canonical, display FROM claimant_names")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var canonical, display string
+		if err := rows.Scan(&canonical, &display); err != nil {
+			return err
+		}
+		cs.names[canonical] = display
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,660 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/names"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported storage backends
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+)
+
+// envPrefix is the prefix for environment variables overriding config values
+const envPrefix = "SPACENET_"
+
+// Config holds the server configuration as read from a YAML config file
+type Config struct {
+	HTTPPort      int                  `yaml:"httpPort"`
+	Backend       string               `yaml:"backend"`   // Storage backend, "memory" or "sqlite"
+	Database      string               `yaml:"database"`  // Path to SQLite database file
+	Levels        []int                `yaml:"levels"`    // Prefix lengths of the subnet hierarchy, widest first
+	Protected     []string             `yaml:"protected"` // IPv6 prefixes nobody may claim addresses in
+	Log           LogConfig            `yaml:"log"`
+	Difficulty    DifficultyParams     `yaml:"difficulty"`
+	Fortification FortificationOptions `yaml:"fortification"`
+	Heat          HeatOptions          `yaml:"heat"`
+	ClaimSources  ClaimSourceOptions   `yaml:"claimSources"`
+	Anomalies     AnomalyOptions       `yaml:"anomalies"`
+	SupplyLines   SupplyLineOptions    `yaml:"supplyLines"`
+	PoW           PoWOptions           `yaml:"pow"`
+	RateLimit     RateLimitConfig      `yaml:"rateLimit"`
+	TLS           TLSConfig            `yaml:"tls"`
+	AdminTokens   []string             `yaml:"adminTokens"`
+	CORS          CORSConfig           `yaml:"cors"`
+	Scoring       ScoringOptions       `yaml:"scoring"`
+	Season        SeasonOptions        `yaml:"season"`
+	Artifacts     ArtifactOptions      `yaml:"artifacts"`
+	Pprof         bool                 `yaml:"pprof"`       // Serve profiling endpoints under /debug/pprof
+	Compression   bool                 `yaml:"compression"` // Gzip responses for clients that accept it
+	ClaimPool     ClaimPoolOptions     `yaml:"claimPool"`
+	UDP           UDPOptions           `yaml:"udp"`
+	ICMP          ICMPOptions          `yaml:"icmp"`
+	DNSClaims     DNSClaimOptions      `yaml:"dnsClaims"`
+	Names         NamePolicyOptions    `yaml:"names"`
+	NamePack      string               `yaml:"namePack"` // JSON or YAML word lists replacing the built-in subnet names
+	Sectors       SectorOptions        `yaml:"sectors"`
+	GameEvents    GameEventOptions     `yaml:"gameEvents"`
+	Delegation    DelegationOptions    `yaml:"delegation"`
+	WebSessions   WebSessionOptions    `yaml:"webSessions"`
+	Bots          BotOptions           `yaml:"bots"`
+	Activity      ActivityOptions      `yaml:"activity"`
+	Health        HealthOptions        `yaml:"health"`
+	Tracing       TracingOptions       `yaml:"tracing"`
+	Federation    FederationOptions    `yaml:"federation"`
+	Replica       ReplicaOptions       `yaml:"replica"`
+	Limits        LimitOptions         `yaml:"limits"`
+	Tree          TreeOptions          `yaml:"tree"`
+	Journal       JournalOptions       `yaml:"journal"`
+	Snapshots     SnapshotOptions      `yaml:"snapshots"`
+	HTTP          HTTPOptions          `yaml:"http"`
+	Webhooks      WebhookOptions       `yaml:"webhooks"`
+	Discovery     DiscoveryOptions     `yaml:"discovery"`
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// RateLimitConfig holds per-client claim rate limits, zero disables limiting
+type RateLimitConfig struct {
+	ClaimsPerMinute int `yaml:"claimsPerMinute"`
+	Burst           int `yaml:"burst"`
+}
+
+// TLSConfig holds the certificate and key used to serve the API over HTTPS
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// Enabled reports whether TLS is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// DefaultConfig returns the configuration used when no config file is given
+func DefaultConfig() Config {
+	return Config{
+		HTTPPort:      8080,
+		Compression:   true,
+		Levels:        DefaultLevels(),
+		Log:           LogConfig{Level: "info", Format: "text"},
+		Difficulty:    DefaultDifficultyParams(),
+		Fortification: DefaultFortificationOptions(),
+		Heat:          DefaultHeatOptions(),
+		ClaimSources:  DefaultClaimSourceOptions(),
+		Anomalies:     DefaultAnomalyOptions(),
+		SupplyLines:   DefaultSupplyLineOptions(),
+		PoW:           DefaultPoWOptions(),
+		Scoring:       DefaultScoringOptions(),
+		Artifacts:     DefaultArtifactOptions(),
+		ClaimPool:     DefaultClaimPoolOptions(),
+		UDP:           DefaultUDPOptions(),
+		ICMP:          DefaultICMPOptions(),
+		DNSClaims:     DefaultDNSClaimOptions(),
+		Names:         DefaultNamePolicyOptions(),
+		GameEvents:    DefaultGameEventOptions(),
+		Delegation:    DefaultDelegationOptions(),
+		WebSessions:   DefaultWebSessionOptions(),
+		Bots:          DefaultBotOptions(),
+		Activity:      DefaultActivityOptions(),
+		Health:        DefaultHealthOptions(),
+		Tracing:       DefaultTracingOptions(),
+		Federation:    DefaultFederationOptions(),
+		Replica:       DefaultReplicaOptions(),
+		Limits:        DefaultLimitOptions(),
+		Tree:          DefaultTreeOptions(),
+		Journal:       DefaultJournalOptions(),
+		Snapshots:     DefaultSnapshotOptions(),
+		HTTP:          DefaultHTTPOptions(),
+		Webhooks:      DefaultWebhookOptions(),
+		Discovery:     DefaultDiscoveryOptions(),
+	}
+}
+
+// LoadConfig builds the configuration from defaults, an optional YAML file
+// and SPACENET_* environment variable overrides, in increasing precedence
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read config file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overrides config values from environment variables
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	stringFields := map[string]*string{
+		"BACKEND":              &c.Backend,
+		"DATABASE":             &c.Database,
+		"JOURNAL_PATH":         &c.Journal.Path,
+		"SNAPSHOTS_ENDPOINT":   &c.Snapshots.Endpoint,
+		"SNAPSHOTS_BUCKET":     &c.Snapshots.Bucket,
+		"SNAPSHOTS_KEY":        &c.Snapshots.Key,
+		"SNAPSHOTS_REGION":     &c.Snapshots.Region,
+		"SNAPSHOTS_ACCESS_KEY": &c.Snapshots.AccessKey,
+		"SNAPSHOTS_SECRET_KEY": &c.Snapshots.SecretKey,
+		"LOG_LEVEL":            &c.Log.Level,
+		"LOG_FORMAT":           &c.Log.Format,
+		"TLS_CERT_FILE":        &c.TLS.CertFile,
+		"TLS_KEY_FILE":         &c.TLS.KeyFile,
+		"SEASON_ARCHIVE_DIR":   &c.Season.ArchiveDir,
+		"ARTIFACTS_SEED":       &c.Artifacts.Seed,
+		"ICMP_SECRET":          &c.ICMP.Secret,
+		"DNS_CLAIMS_SECRET":    &c.DNSClaims.Secret,
+		"NAMES_CHARSET":        &c.Names.Charset,
+		"POW_SCHEME":           &c.PoW.Scheme,
+		"BOTS_PREFIX":          &c.Bots.Prefix,
+		"TRACING_ENDPOINT":     &c.Tracing.Endpoint,
+		"TRACING_SERVICE_NAME": &c.Tracing.ServiceName,
+		"FEDERATION_NAME":      &c.Federation.Name,
+		"REPLICA_PRIMARY":      &c.Replica.Primary,
+		"LIMITS_POLICY":        &c.Limits.Policy,
+		"WEBHOOKS_SECRET":      &c.Webhooks.Secret,
+		"SECTORS_FILE":         &c.Sectors.File,
+		"GAME_EVENTS_FILE":     &c.GameEvents.File,
+		"NAME_PACK":            &c.NamePack,
+		"DISCOVERY_NAME":       &c.Discovery.Name,
+		"ANOMALIES_ACTION":     &c.Anomalies.Action,
+	}
+	for name, field := range stringFields {
+		if value, ok := lookup(envPrefix + name); ok {
+			*field = value
+		}
+	}
+
+	intFields := map[string]*int{
+		"HTTP_PORT":                    &c.HTTPPort,
+		"DIFFICULTY_BASE":              &c.Difficulty.Base,
+		"DIFFICULTY_CLAIM_BONUS":       &c.Difficulty.ClaimBonus,
+		"DIFFICULTY_MAX_CONTIGUITY":    &c.Difficulty.MaxContiguity,
+		"DIFFICULTY_CONTIGUITY_BONUS":  &c.Difficulty.ContiguityBonus,
+		"DIFFICULTY_MAX":               &c.Difficulty.Max,
+		"RATE_LIMIT_CLAIMS_PER_MINUTE": &c.RateLimit.ClaimsPerMinute,
+		"RATE_LIMIT_BURST":             &c.RateLimit.Burst,
+		"ARTIFACTS_PER_SUBNET":         &c.Artifacts.PerSubnet,
+		"CLAIM_POOL_WORKERS":           &c.ClaimPool.Workers,
+		"CLAIM_POOL_QUEUE_SIZE":        &c.ClaimPool.QueueSize,
+		"UDP_PORT":                     &c.UDP.Port,
+		"UDP_CLAIMS_PER_MINUTE":        &c.UDP.ClaimsPerMinute,
+		"UDP_BURST":                    &c.UDP.Burst,
+		"NAMES_MAX_LENGTH":             &c.Names.MaxLength,
+		"POW_ARGON2_TIME":              &c.PoW.Argon2.Time,
+		"POW_ARGON2_MEMORY_KIB":        &c.PoW.Argon2.MemoryKiB,
+		"POW_ARGON2_THREADS":           &c.PoW.Argon2.Threads,
+		"DELEGATION_MAX_CLAIMS":        &c.Delegation.MaxClaims,
+		"DELEGATION_MAX_PER_CLAIMANT":  &c.Delegation.MaxPerClaimant,
+		"BOTS_COUNT":                   &c.Bots.Count,
+		"FORTIFICATION_MAX_LEVEL":      &c.Fortification.MaxLevel,
+		"FORTIFICATION_LEVEL_BONUS":    &c.Fortification.LevelBonus,
+		"HEAT_BONUS_PER_TAKEOVER":      &c.Heat.BonusPerTakeover,
+		"HEAT_MAX_BONUS":               &c.Heat.MaxBonus,
+		"ACTIVITY_CAPACITY":            &c.Activity.Capacity,
+		"HEALTH_MAX_GOROUTINES":        &c.Health.MaxGoroutines,
+		"FEDERATION_SUMMARY_PREFIX":    &c.Federation.SummaryPrefix,
+		"LIMITS_MAX_CLAIMS":            &c.Limits.MaxClaims,
+		"LIMITS_MAX_TREE_NODES":        &c.Limits.MaxTreeNodes,
+		"HTTP_MAX_BODY_BYTES":          &c.HTTP.MaxBodyBytes,
+		"WEBHOOKS_MAX_ATTEMPTS":        &c.Webhooks.MaxAttempts,
+		"WEBHOOKS_QUEUE_SIZE":          &c.Webhooks.QueueSize,
+		"ANOMALIES_MAX_SPREAD":         &c.Anomalies.MaxSpread,
+		"ANOMALIES_SPREAD_PREFIX":      &c.Anomalies.SpreadPrefix,
+		"ANOMALIES_MAX_HASH_RATE":      &c.Anomalies.MaxHashRate,
+		"ANOMALIES_PENALTY":            &c.Anomalies.Penalty,
+		"ANOMALIES_CAPACITY":           &c.Anomalies.Capacity,
+		"WEB_SESSIONS_DISCOUNT":        &c.WebSessions.Discount,
+		"WEB_SESSIONS_MIN_DIFFICULTY":  &c.WebSessions.MinDifficulty,
+		"WEB_SESSIONS_CLAIMS":          &c.WebSessions.Claims,
+		"WEB_SESSIONS_MAX_PER_CLIENT":  &c.WebSessions.MaxPerClient,
+		"SUPPLY_LINES_FREE_CLAIMS":     &c.SupplyLines.FreeClaims,
+		"SUPPLY_LINES_DISTANCE":        &c.SupplyLines.Distance,
+		"GAME_EVENTS_MAX_MULTIPLIER":   &c.GameEvents.MaxMultiplier,
+	}
+	for name, field := range intFields {
+		value, ok := lookup(envPrefix + name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s%s: %w", envPrefix, name, err)
+		}
+		*field = parsed
+	}
+
+	durationFields := map[string]*time.Duration{
+		"SCORING_INTERVAL":             &c.Scoring.Interval,
+		"SEASON_LENGTH":                &c.Season.Length,
+		"ICMP_TIMEOUT":                 &c.ICMP.Timeout,
+		"DNS_CLAIMS_TIMEOUT":           &c.DNSClaims.Timeout,
+		"DELEGATION_MAX_LIFETIME":      &c.Delegation.MaxLifetime,
+		"BOTS_INTERVAL":                &c.Bots.Interval,
+		"FORTIFICATION_DECAY_INTERVAL": &c.Fortification.DecayInterval,
+		"HEAT_HALF_LIFE":               &c.Heat.HalfLife,
+		"ACTIVITY_WINDOW":              &c.Activity.Window,
+		"HEALTH_TIMEOUT":               &c.Health.Timeout,
+		"FEDERATION_SYNC_INTERVAL":     &c.Federation.SyncInterval,
+		"FEDERATION_TIMEOUT":           &c.Federation.Timeout,
+		"REPLICA_REFRESH_INTERVAL":     &c.Replica.RefreshInterval,
+		"TREE_COMPACT_INTERVAL":        &c.Tree.CompactInterval,
+		"JOURNAL_COMPACT_INTERVAL":     &c.Journal.CompactInterval,
+		"SNAPSHOTS_INTERVAL":           &c.Snapshots.Interval,
+		"SNAPSHOTS_TIMEOUT":            &c.Snapshots.Timeout,
+		"HTTP_READ_HEADER_TIMEOUT":     &c.HTTP.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":            &c.HTTP.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":           &c.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":            &c.HTTP.IdleTimeout,
+		"HTTP_STORE_TIMEOUT":           &c.HTTP.StoreTimeout,
+		"HTTP_DRAIN_DELAY":             &c.HTTP.DrainDelay,
+		"HTTP_SHUTDOWN_TIMEOUT":        &c.HTTP.ShutdownTimeout,
+		"WEBHOOKS_TIMEOUT":             &c.Webhooks.Timeout,
+		"WEBHOOKS_BACKOFF":             &c.Webhooks.Backoff,
+		"ANOMALIES_WINDOW":             &c.Anomalies.Window,
+		"ANOMALIES_PENALTY_DURATION":   &c.Anomalies.PenaltyDuration,
+		"WEB_SESSIONS_WINDOW":          &c.WebSessions.Window,
+		"WEB_SESSIONS_LIFETIME":        &c.WebSessions.Lifetime,
+		"GAME_EVENTS_MAX_DURATION":     &c.GameEvents.MaxDuration,
+	}
+	for name, field := range durationFields {
+		value, ok := lookup(envPrefix + name)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s%s: %w", envPrefix, name, err)
+		}
+		*field = parsed
+	}
+
+	boolFields := map[string]*bool{
+		"PPROF":                 &c.Pprof,
+		"COMPRESSION":           &c.Compression,
+		"UDP_ENABLED":           &c.UDP.Enabled,
+		"ICMP_ENABLED":          &c.ICMP.Enabled,
+		"ICMP_PRIVILEGED":       &c.ICMP.Privileged,
+		"DNS_CLAIMS_ENABLED":    &c.DNSClaims.Enabled,
+		"DELEGATION_ENABLED":    &c.Delegation.Enabled,
+		"FORTIFICATION_ENABLED": &c.Fortification.Enabled,
+		"HEAT_ENABLED":          &c.Heat.Enabled,
+		"READ_ONLY":             &c.Replica.ReadOnly,
+		"DISCOVERY_ENABLED":     &c.Discovery.Enabled,
+		"JOURNAL_SYNC":          &c.Journal.Sync,
+		"CLAIM_SOURCES_ENABLED": &c.ClaimSources.Enabled,
+		"ANOMALIES_ENABLED":     &c.Anomalies.Enabled,
+		"WEB_SESSIONS_ENABLED":  &c.WebSessions.Enabled,
+		"SUPPLY_LINES_ENABLED":  &c.SupplyLines.Enabled,
+	}
+	for name, field := range boolFields {
+		value, ok := lookup(envPrefix + name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s%s: %w", envPrefix, name, err)
+		}
+		*field = parsed
+	}
+
+	if value, ok := lookup(envPrefix + "LEVELS"); ok {
+		levels := make([]int, 0)
+		for _, item := range splitList(value) {
+			parsed, err := strconv.Atoi(item)
+			if err != nil {
+				return fmt.Errorf("invalid value for %sLEVELS: %w", envPrefix, err)
+			}
+			levels = append(levels, parsed)
+		}
+		c.Levels = levels
+	}
+	if value, ok := lookup(envPrefix + "PROTECTED"); ok {
+		c.Protected = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "ADMIN_TOKENS"); ok {
+		c.AdminTokens = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "CORS_ALLOWED_ORIGINS"); ok {
+		c.CORS.AllowedOrigins = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "NAMES_RESERVED"); ok {
+		c.Names.Reserved = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "NAMES_BLOCKLIST"); ok {
+		c.Names.Blocklist = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "BOTS_STRATEGIES"); ok {
+		c.Bots.Strategies = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "FEDERATION_PREFIXES"); ok {
+		c.Federation.Prefixes = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "WEBHOOKS_URLS"); ok {
+		c.Webhooks.URLs = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "WEBHOOKS_EVENTS"); ok {
+		c.Webhooks.Events = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "DISCOVERY_INTERFACES"); ok {
+		c.Discovery.Interfaces = splitList(value)
+	}
+	if value, ok := lookup(envPrefix + "TRACING_SAMPLE_RATIO"); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value for %sTRACING_SAMPLE_RATIO: %w", envPrefix, err)
+		}
+		c.Tracing.SampleRatio = parsed
+	}
+
+	return nil
+}
+
+// splitList splits a comma-separated environment value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate checks the configuration for invalid or inconsistent values
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.HTTPPort < 0 || c.HTTPPort > 65535 {
+		errs = append(errs, fmt.Errorf("httpPort must be between 0 and 65535, got %d", c.HTTPPort))
+	}
+
+	switch c.Backend {
+	case "":
+		// Inferred from whether a database path is set
+	case BackendMemory:
+		if c.Database != "" {
+			errs = append(errs, errors.New("database must not be set with the memory backend"))
+		}
+	case BackendSQLite:
+		if c.Database == "" {
+			errs = append(errs, errors.New("database is required with the sqlite backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown backend %q", c.Backend))
+	}
+
+	if err := ValidateLevels(c.Levels); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := ParseProtectedPrefixes(c.Protected); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := NewLogger(os.Stderr, c.Log.Level, c.Log.Format); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Difficulty.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Fortification.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Heat.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Anomalies.Validate(); err != nil {
+		errs = append(errs, err)
+	} else if c.Anomalies.Enabled && c.Anomalies.MaxHashRate > 0 && c.ICMP.Enabled {
+		errs = append(errs, errors.New("anomalies maxHashRate needs proofs of work, which icmp verification replaces"))
+	}
+
+	if _, err := NewPoWScheme(c.PoW); err != nil {
+		errs = append(errs, err)
+	} else if bonus := c.Fortification.maxBonus() + c.Heat.maxBonus() + c.Anomalies.maxBonus(); c.PoW.Scheme == PoWSchemeArgon2id && c.Difficulty.Max+bonus > maxArgon2Difficulty {
+		errs = append(errs, fmt.Errorf("difficulty max plus fortification, heat and anomaly penalties must be at most %d with the argon2id scheme, got %d",
+			maxArgon2Difficulty, c.Difficulty.Max+bonus))
+	}
+
+	if c.RateLimit.ClaimsPerMinute < 0 || c.RateLimit.Burst < 0 {
+		errs = append(errs, errors.New("rate limits must not be negative"))
+	}
+
+	if c.Scoring.Interval < 0 || c.Scoring.AddressPoints < 0 || c.Scoring.BlockMultiplier < 0 || c.Scoring.SubnetPoints < 0 ||
+		c.Scoring.ArtifactPoints < 0 || c.Scoring.HistoryLength < 0 {
+		errs = append(errs, errors.New("scoring options must not be negative"))
+	}
+
+	if c.UDP.Enabled && (c.UDP.Port < 0 || c.UDP.Port > 65535) {
+		errs = append(errs, fmt.Errorf("udp port must be between 0 and 65535, got %d", c.UDP.Port))
+	}
+	if c.UDP.ClaimsPerMinute < 0 || c.UDP.Burst < 0 {
+		errs = append(errs, errors.New("udp rate limits must not be negative"))
+	}
+
+	if c.ICMP.Enabled && c.ICMP.Timeout <= 0 {
+		errs = append(errs, errors.New("icmp timeout must be positive"))
+	}
+
+	if _, err := NewNamePolicy(c.Names); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.NamePack != "" {
+		if _, err := names.LoadPackFile(c.NamePack); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.DNSClaims.Enabled && c.DNSClaims.Timeout <= 0 {
+		errs = append(errs, errors.New("dnsClaims timeout must be positive"))
+	}
+
+	if c.Delegation.Enabled && (c.Delegation.MaxLifetime <= 0 || c.Delegation.MaxClaims <= 0 || c.Delegation.MaxPerClaimant <= 0) {
+		errs = append(errs, errors.New("delegation maxLifetime, maxClaims and maxPerClaimant must be positive"))
+	}
+
+	if err := c.SupplyLines.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.GameEvents.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.WebSessions.Validate(); err != nil {
+		errs = append(errs, err)
+	} else if c.WebSessions.Enabled && c.ICMP.Enabled {
+		errs = append(errs, errors.New("webSessions discount proofs of work, which icmp verification replaces"))
+	}
+
+	if err := c.Bots.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Activity.Capacity < 0 || (c.Activity.Enabled() && c.Activity.Window < time.Hour) {
+		errs = append(errs, errors.New("activity capacity must not be negative and window must be at least an hour"))
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Federation.Validate(); err != nil {
+		errs = append(errs, err)
+	} else if c.Federation.Enabled() && !slices.Contains(c.Levels, c.Federation.SummaryPrefix) {
+		errs = append(errs, fmt.Errorf("federation summaryPrefix must be one of the levels %v, got %d", c.Levels, c.Federation.SummaryPrefix))
+	}
+
+	if err := c.Replica.Validate(); err != nil {
+		errs = append(errs, err)
+	} else if c.Replica.ReadOnly && (c.Database == "" || c.Backend == BackendMemory) {
+		errs = append(errs, errors.New("replica readOnly requires the sqlite database of a primary"))
+	}
+
+	if err := c.Limits.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Tree.CompactInterval < 0 {
+		errs = append(errs, errors.New("tree compactInterval must not be negative"))
+	}
+
+	if err := c.Journal.Validate(); err != nil {
+		errs = append(errs, err)
+	} else if c.Journal.Enabled() && c.Database != "" {
+		errs = append(errs, errors.New("journal is only kept for the memory backend"))
+	}
+
+	if err := c.Snapshots.Validate(); err != nil {
+		errs = append(errs, err)
+	} else if c.Snapshots.Enabled() && c.Database != "" {
+		errs = append(errs, errors.New("snapshots are only taken of the memory backend"))
+	}
+
+	if err := c.HTTP.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Webhooks.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.Discovery.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Health.Timeout <= 0 || c.Health.MaxGoroutines < 0 {
+		errs = append(errs, errors.New("health timeout must be positive and maxGoroutines must not be negative"))
+	}
+
+	if c.ClaimPool.Workers < 0 || c.ClaimPool.QueueSize < 0 {
+		errs = append(errs, errors.New("claimPool workers and queueSize must not be negative"))
+	}
+
+	if c.Artifacts.PerSubnet < 0 {
+		errs = append(errs, errors.New("artifacts perSubnet must not be negative"))
+	}
+
+	if c.Season.Length < 0 {
+		errs = append(errs, errors.New("season length must not be negative"))
+	}
+
+	if c.TLS.Enabled() {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			errs = append(errs, errors.New("tls requires both certFile and keyFile"))
+		}
+		for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				errs = append(errs, fmt.Errorf("tls file: %w", err))
+			}
+		}
+	}
+
+	for _, token := range c.AdminTokens {
+		if len(token) < 16 {
+			errs = append(errs, errors.New("admin tokens must be at least 16 characters"))
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ServerOptions converts the configuration into options for NewServerWithOptions
+func (c *Config) ServerOptions() ServerOptions {
+	dbPath := c.Database
+	if c.Backend == BackendMemory {
+		dbPath = ""
+	}
+
+	return ServerOptions{
+		HTTPPort:           c.HTTPPort,
+		DBPath:             dbPath,
+		Levels:             c.Levels,
+		Protected:          c.Protected,
+		Difficulty:         &c.Difficulty,
+		Fortification:      c.Fortification,
+		Heat:               c.Heat,
+		ClaimSources:       c.ClaimSources,
+		Anomalies:          c.Anomalies,
+		SupplyLines:        c.SupplyLines,
+		PoW:                c.PoW,
+		RateLimit:          c.RateLimit,
+		TLS:                c.TLS,
+		AdminTokens:        c.AdminTokens,
+		CORS:               c.CORS,
+		Scoring:            c.Scoring,
+		Season:             c.Season,
+		Artifacts:          c.Artifacts,
+		Pprof:              c.Pprof,
+		DisableCompression: !c.Compression,
+		ClaimPool:          c.ClaimPool,
+		UDP:                c.UDP,
+		ICMP:               c.ICMP,
+		DNSClaims:          c.DNSClaims,
+		Names:              &c.Names,
+		Sectors:            c.Sectors,
+		GameEvents:         c.GameEvents,
+		NamePack:           c.NamePack,
+		Delegation:         c.Delegation,
+		WebSessions:        c.WebSessions,
+		Bots:               c.Bots,
+		Activity:           c.Activity,
+		Health:             c.Health,
+		Tracing:            c.Tracing,
+		Federation:         c.Federation,
+		Replica:            c.Replica,
+		Limits:             c.Limits,
+		Tree:               c.Tree,
+		Journal:            c.Journal,
+		Snapshots:          c.Snapshots,
+		HTTP:               c.HTTP,
+		Webhooks:           c.Webhooks,
+		Discovery:          c.Discovery,
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadConfig_FileAndEnv tests that file values override defaults and env overrides the file
+func TestLoadConfig_FileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+httpPort: 9000
+backend: sqlite
+database: claims.db
+difficulty:
+  base: 10
+rateLimit:
+  claimsPerMinute: 30
+`), 0o600)
+	require.NoError(t, err, "Writing config file should succeed")
+
+	t.Setenv("SPACENET_HTTP_PORT", "9001")
+	t.Setenv("SPACENET_ADMIN_TOKENS", "0123456789abcdef, fedcba9876543210")
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err, "Config should load")
+	require.NoError(t, cfg.Validate(), "Config should be valid")
+
+	assert.Equal(t, 9001, cfg.HTTPPort, "Env should override file")
+	assert.Equal(t, "claims.db", cfg.Database, "File value should be used")
+	assert.Equal(t, 10, cfg.Difficulty.Base, "File value should be used")
+	assert.Equal(t, DefaultDifficultyParams().Max, cfg.Difficulty.Max, "Unset values should keep defaults")
+	assert.Equal(t, 30, cfg.RateLimit.ClaimsPerMinute, "File value should be used")
+	assert.Equal(t, []string{"0123456789abcdef", "fedcba9876543210"}, cfg.AdminTokens, "Tokens should be split and trimmed")
+	assert.Equal(t, "claims.db", cfg.ServerOptions().DBPath, "Options should use the database path")
+}
+
+// TestLoadConfig_UnknownField tests that typos in the config file are rejected
+func TestLoadConfig_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("httpport: 9000\n"), 0o600))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err, "Unknown fields should be rejected")
+}
+
+// TestConfig_Validate tests startup validation of config values
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"invalid port", func(c *Config) { c.HTTPPort = 70000 }},
+		{"unknown backend", func(c *Config) { c.Backend = "postgres" }},
+		{"sqlite without database", func(c *Config) { c.Backend = BackendSQLite }},
+		{"invalid log level", func(c *Config) { c.Log.Level = "loud" }},
+		{"max below base", func(c *Config) { c.Difficulty.Max = c.Difficulty.Base - 1 }},
+		{"negative rate limit", func(c *Config) { c.RateLimit.ClaimsPerMinute = -1 }},
+		{"tls without key", func(c *Config) { c.TLS.CertFile = "cert.pem" }},
+		{"short admin token", func(c *Config) { c.AdminTokens = []string{"secret"} }},
+		{"unknown name charset", func(c *Config) { c.Names.Charset = "emoji" }},
+		{"invalid name block pattern", func(c *Config) { c.Names.BlockPatterns = []string{"("} }},
+		{"unknown pow scheme", func(c *Config) { c.PoW.Scheme = "scrypt" }},
+		{"argon2id without memory", func(c *Config) { c.PoW.Scheme = PoWSchemeArgon2id; c.PoW.Argon2.MemoryKiB = 0 }},
+		{"argon2id with sha256 difficulty", func(c *Config) { c.PoW.Scheme = PoWSchemeArgon2id }},
+		{"delegation without claims", func(c *Config) { c.Delegation.Enabled = true; c.Delegation.MaxClaims = 0 }},
+		{"delegation without tokens per claimant", func(c *Config) { c.Delegation.Enabled = true; c.Delegation.MaxPerClaimant = 0 }},
+		{"session challenges without discount", func(c *Config) { c.WebSessions.Enabled = true; c.WebSessions.Discount = 0 }},
+		{"session challenges with icmp", func(c *Config) { c.WebSessions.Enabled = true; c.ICMP.Enabled = true }},
+		{"unknown bot strategy", func(c *Config) { c.Bots.Count = 1; c.Bots.Strategies = []string{"hoard"} }},
+		{"tracing endpoint without scheme", func(c *Config) { c.Tracing.Endpoint = "localhost:4318" }},
+		{"overlapping federation prefixes", func(c *Config) {
+			c.Federation.Name = "east"
+			c.Federation.Prefixes = []string{"2001:db8::/32"}
+			c.Federation.Peers = []FederationPeer{{Name: "west", URL: "http://west", Prefixes: []string{"2001:db8:1::/48"}}}
+		}},
+		{"read-only without database", func(c *Config) { c.Replica.ReadOnly = true }},
+		{"unknown limits policy", func(c *Config) { c.Limits.MaxClaims = 10; c.Limits.Policy = "drop" }},
+		{"negative tree compaction", func(c *Config) { c.Tree.CompactInterval = -1 }},
+		{"negative http timeout", func(c *Config) { c.HTTP.WriteTimeout = -1 }},
+		{"negative store timeout", func(c *Config) { c.HTTP.StoreTimeout = -1 }},
+		{"negative drain delay", func(c *Config) { c.HTTP.DrainDelay = -1 }},
+		{"webhook without scheme", func(c *Config) { c.Webhooks.URLs = []string{"hooks.example.org/spacenet"} }},
+		{"unknown discovery interface", func(c *Config) { c.Discovery.Enabled = true; c.Discovery.Interfaces = []string{"nonexistent0"} }},
+		{"health without timeout", func(c *Config) { c.Health.Timeout = 0 }},
+		{"activity without window", func(c *Config) { c.Activity.Window = 0 }},
+		{"fortification without decay", func(c *Config) { c.Fortification.Enabled = true; c.Fortification.DecayInterval = 0 }},
+		{"heat without half-life", func(c *Config) { c.Heat.Enabled = true; c.Heat.HalfLife = 0 }},
+		{"unknown anomaly action", func(c *Config) { c.Anomalies.Enabled = true; c.Anomalies.Action = "ban" }},
+		{"anomaly hash rate with icmp", func(c *Config) { c.Anomalies.Enabled = true; c.ICMP.Enabled = true }},
+		{"negative game event multiplier", func(c *Config) { c.GameEvents.MaxMultiplier = -1 }},
+		{"supply lines beyond the distance limit", func(c *Config) { c.SupplyLines.Enabled = true; c.SupplyLines.Distance = 5000 }},
+		{"levels out of order", func(c *Config) { c.Levels = []int{64, 32} }},
+		{"ipv4 protected prefix", func(c *Config) { c.Protected = []string{"192.0.2.0/24"} }},
+		{"journal with sqlite", func(c *Config) { c.Database = "spacenet.db"; c.Journal.Path = "spacenet.journal" }},
+		{"journal without compaction", func(c *Config) { c.Journal.Path = "spacenet.journal"; c.Journal.CompactInterval = 0 }},
+	}
+
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate(), "Default config should be valid")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tc.modify(&cfg)
+			assert.Error(t, cfg.Validate(), "Config should be rejected")
+		})
+	}
+}
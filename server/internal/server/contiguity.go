@@ -0,0 +1,142 @@
+package server
+
+import (
+	"maps"
+	"net"
+)
+
+// Blocks of contiguous addresses a claimant is rewarded for holding completely
+const (
+	block124 = iota // A /124, 16 addresses
+	block120        // A /120, sixteen /124s
+	blockSizes
+)
+
+// completeBlocks counts the blocks a claimant holds completely, by block size.
+// The /124s of a complete /120 are complete too, and counted as such.
+type completeBlocks [blockSizes]int
+
+// blockBase is the first address of a block
+type blockBase [net.IPv6len]byte
+
+// blockIndex tracks the /124s and /120s held completely by one claimant.
+// Only complete blocks are kept, so a claim is checked against the rest of
+// its /124, and only a /124 completed or broken against the rest of its /120.
+type blockIndex struct {
+	owners [blockSizes]map[blockBase]string // Claimant holding each complete block, by block size
+	counts map[string]completeBlocks        // Complete blocks by claimant
+}
+
+// newBlockIndex creates an index without complete blocks
+func newBlockIndex() *blockIndex {
+	b := &blockIndex{counts: make(map[string]completeBlocks)}
+	for size := range b.owners {
+		b.owners[size] = make(map[blockBase]string)
+	}
+	return b
+}
+
+// base124 returns the first address of the /124 containing an IPv6 address
+func base124(ipAddr string) (blockBase, bool) {
+	ip := net.ParseIP(ipAddr)
+	if ip == nil || ip.To4() != nil {
+		return blockBase{}, false
+	}
+	var base blockBase
+	copy(base[:], ip.To16())
+	base[15] &= 0xf0
+	return base, true
+}
+
+// update rechecks the blocks containing an address after its claim changed
+func (b *blockIndex) update(claims map[string]string, ipAddr string) {
+	base, ok := base124(ipAddr)
+	if !ok || !b.set(block124, base, owner124(claims, base)) {
+		return
+	}
+	base[15] = 0
+	b.set(block120, base, b.owner120(base))
+}
+
+// set records the claimant holding a block completely, empty if nobody
+// does, reporting whether that changed
+func (b *blockIndex) set(size int, base blockBase, owner string) bool {
+	old := b.owners[size][base]
+	if old == owner {
+		return false
+	}
+	if old != "" {
+		counts := b.counts[old]
+		counts[size]--
+		if counts == (completeBlocks{}) {
+			delete(b.counts, old)
+		} else {
+			b.counts[old] = counts
+		}
+		delete(b.owners[size], base)
+	}
+	if owner != "" {
+		counts := b.counts[owner]
+		counts[size]++
+		b.counts[owner] = counts
+		b.owners[size][base] = owner
+	}
+	return true
+}
+
+// owner124 returns the claimant holding every address of a /124, if any
+func owner124(claims map[string]string, base blockBase) string {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, base[:])
+	var owner string
+	for i := range 16 {
+		ip[15] = base[15] | byte(i)
+		claimant, exists := claims[ip.String()]
+		if !exists || (owner != "" && claimant != owner) {
+			return ""
+		}
+		owner = claimant
+	}
+	return owner
+}
+
+// owner120 returns the claimant holding every /124 of a /120, if any
+func (b *blockIndex) owner120(base blockBase) string {
+	var owner string
+	for i := range 16 {
+		base[15] = byte(i << 4)
+		claimant, exists := b.owners[block124][base]
+		if !exists || (owner != "" && claimant != owner) {
+			return ""
+		}
+		owner = claimant
+	}
+	return owner
+}
+
+// buildBlockIndex indexes the complete blocks of claims loaded in bulk
+func buildBlockIndex(claims map[string]string) *blockIndex {
+	b := newBlockIndex()
+	checked := make(map[blockBase]bool)
+	for ipAddr := range claims {
+		base, ok := base124(ipAddr)
+		if !ok || checked[base] {
+			continue
+		}
+		checked[base] = true
+		b.set(block124, base, owner124(claims, base))
+	}
+
+	for base := range b.owners[block124] {
+		base[15] = 0
+		if _, exists := b.owners[block120][base]; !exists {
+			b.set(block120, base, b.owner120(base))
+		}
+	}
+	return b
+}
+
+// blocksHeldLocked returns the blocks each claimant holds completely (assumes lock is held)
+func (cs *ClaimStore) blocksHeldLocked() map[string]completeBlocks {
+	return maps.Clone(cs.blocks.counts)
+}
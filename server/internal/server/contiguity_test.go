@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blocksOf returns the complete /124s and /120s a claimant holds on the leaderboard
+func blocksOf(t *testing.T, store Store, claimant string) [2]int {
+	t.Helper()
+	for _, entry := range store.GetLeaderboard(t.Context(), 0) {
+		if entry.Name == claimant {
+			return [2]int{entry.Blocks124, entry.Blocks120}
+		}
+	}
+	return [2]int{}
+}
+
+// TestClaimStore_CompleteBlocks tests that complete blocks are counted as
+// claims complete and break them, and after reloading the claims
+func TestClaimStore_CompleteBlocks(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "blocks.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+
+	for i := range 15 {
+		require.NoError(t, store.ProcessClaim(t.Context(), fmt.Sprintf("2001:db8::%x", 0x10+i), "alice"))
+	}
+	assert.Equal(t, [2]int{0, 0}, blocksOf(t, store, "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1f", "alice"))
+	assert.Equal(t, [2]int{1, 0}, blocksOf(t, store, "alice"), "The last address should complete the /124")
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1f", "alice"))
+	assert.Equal(t, [2]int{1, 0}, blocksOf(t, store, "alice"), "Claiming an address again should change nothing")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::15", "bob"))
+	assert.Equal(t, [2]int{0, 0}, blocksOf(t, store, "alice"), "Takeovers should break blocks")
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::15", "alice"))
+
+	batch := make([]BatchClaim, 0, 256)
+	for i := range 256 {
+		batch = append(batch, BatchClaim{IP: fmt.Sprintf("2001:db8::1:%x", i), Claimant: "alice"})
+	}
+	require.NoError(t, store.ProcessClaims(t.Context(), batch))
+	assert.Equal(t, [2]int{17, 1}, blocksOf(t, store, "alice"), "The /124s of a complete /120 should count too")
+
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::1:ff", ""))
+	assert.Equal(t, [2]int{16, 0}, blocksOf(t, store, "alice"), "Releasing an address should break its blocks")
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	assert.Equal(t, [2]int{16, 0}, blocksOf(t, reopened, "alice"), "Blocks should be counted when claims are loaded")
+	reopened.SetLevels([]int{64, 128})
+	assert.Equal(t, [2]int{16, 0}, blocksOf(t, reopened, "alice"), "Blocks should be counted whatever the levels")
+}
@@ -0,0 +1,193 @@
+"use strict";
+
+// Prefix lengths tracked by the server, replaced by its configured levels on load
+let levels = [16, 32, 48, 64, 80, 96, 112, 128];
+const MAX_FEED_ITEMS = 50;
+const REFRESH_INTERVAL_MS = 10000;
+
+// Subnets selected in the explorer, one per level
+const path = [];
+
+async function fetchJSON(url) {
+  const response = await fetch(url);
+  if (!response.ok) {
+    throw new Error(`${url}: ${response.status}`);
+  }
+  return response.json();
+}
+
+function text(tag, content, className) {
+  const el = document.createElement(tag);
+  el.textContent = content;
+  if (className) {
+    el.className = className;
+  }
+  return el;
+}
+
+async function loadConfig() {
+  const config = await fetchJSON("/api/v1/config");
+  levels = config.levels;
+}
+
+async function refreshStats() {
+  const stats = await fetchJSON("/api/v1/stats");
+  document.getElementById("stat-claims").textContent = stats.totalClaims;
+  document.getElementById("stat-claimants").textContent = stats.claimants;
+}
+
+async function refreshLeaderboard() {
+  const entries = await fetchJSON("/api/v1/leaderboard?limit=10");
+  const list = document.getElementById("leaderboard");
+  list.replaceChildren(
+    ...entries.map((entry) => text("li", `${entry.name} — ${entry.addresses}`))
+  );
+}
+
+// Formats a dominance percentage, which is tiny for the widest levels
+function formatDominance(percentage) {
+  if (percentage === 0 || percentage >= 0.01) {
+    return `${percentage.toFixed(2)}%`;
+  }
+  return `${percentage.toExponential(1)}%`;
+}
+
+async function refreshUniverse() {
+  const stats = await fetchJSON("/api/v1/stats/levels");
+  const body = document.getElementById("levels");
+  body.replaceChildren(
+    ...stats.map((level) => {
+      const row = document.createElement("tr");
+      row.append(
+        text("td", `/${level.level}`),
+        text("td", level.subnets),
+        text("td", level.owned),
+        text("td", level.contested),
+        text("td", formatDominance(level.meanDominance)),
+        text("td", formatDominance(level.medianDominance)),
+        text("td", formatDominance(level.p90Dominance))
+      );
+      return row;
+    })
+  );
+}
+
+// Returns whether subnet lies within parent, both in CIDR notation
+function withinParent(subnet, parent) {
+  if (!parent) {
+    return true;
+  }
+  const [parentAddr, parentPrefix] = parent.split("/");
+  const groups = Number(parentPrefix) / 16;
+  const expand = (addr) => {
+    const [head, tail = ""] = addr.split("::");
+    const h = head ? head.split(":") : [];
+    const t = tail ? tail.split(":") : [];
+    const fill = addr.includes("::") ? Array(8 - h.length - t.length).fill("0") : [];
+    return [...h, ...fill, ...t].map((g) => parseInt(g, 16));
+  };
+  const a = expand(subnet.split("/")[0]);
+  const b = expand(parentAddr);
+  return a.slice(0, groups).every((g, i) => g === b[i]);
+}
+
+async function refreshExplorer() {
+  const level = path.length;
+  const parent = path[level - 1];
+  const subnets = await fetchJSON(`/api/v1/subnets/${levels[level]}`);
+  const rows = subnets
+    .filter((entry) => withinParent(entry.subnet, parent))
+    .sort((a, b) => (b.percentage || 0) - (a.percentage || 0));
+
+  const body = document.getElementById("subnets");
+  body.replaceChildren(
+    ...rows.map((entry) => {
+      const row = document.createElement("tr");
+      row.append(
+        text("td", entry.subnet),
+        text("td", entry.owner || "—"),
+        text("td", entry.percentage ? `${entry.percentage.toFixed(2)}%` : "")
+      );
+      if (level < levels.length - 1) {
+        row.addEventListener("click", () => {
+          path.push(entry.subnet);
+          refreshExplorer();
+        });
+      }
+      return row;
+    })
+  );
+  document.getElementById("explorer-empty").hidden = rows.length > 0;
+  renderBreadcrumbs();
+}
+
+function renderBreadcrumbs() {
+  const nav = document.getElementById("breadcrumbs");
+  const crumbs = [{ label: "::/0", depth: 0 }].concat(
+    path.map((subnet, i) => ({ label: subnet, depth: i + 1 }))
+  );
+  nav.replaceChildren();
+  crumbs.forEach((crumb, i) => {
+    if (i > 0) {
+      nav.append(" › ");
+    }
+    const link = text("a", crumb.label);
+    link.addEventListener("click", () => {
+      path.length = crumb.depth;
+      refreshExplorer();
+    });
+    nav.append(link);
+  });
+}
+
+function connectFeed() {
+  const status = document.getElementById("feed-status");
+  const feed = document.getElementById("feed");
+  const source = new EventSource("/api/v1/events");
+
+  source.onopen = () => {
+    status.textContent = "live";
+    status.classList.add("live");
+  };
+  source.onerror = () => {
+    status.textContent = "reconnecting…";
+    status.classList.remove("live");
+  };
+  source.addEventListener("claim", (message) => {
+    const event = JSON.parse(message.data);
+    const time = new Date(event.timestamp).toLocaleTimeString();
+    const summary = event.previousClaimant
+      ? `${event.claimant} took ${event.ip} from ${event.previousClaimant}`
+      : `${event.claimant} claimed ${event.ip}`;
+    feed.prepend(text("li", `[${time}] ${summary}`));
+    while (feed.children.length > MAX_FEED_ITEMS) {
+      feed.lastChild.remove();
+    }
+  });
+
+  source.addEventListener("unclaim", (message) => {
+    const event = JSON.parse(message.data);
+    const time = new Date(event.timestamp).toLocaleTimeString();
+    feed.prepend(text("li", `[${time}] ${event.previousClaimant} released ${event.ip}`));
+    while (feed.children.length > MAX_FEED_ITEMS) {
+      feed.lastChild.remove();
+    }
+  });
+}
+
+function refreshAll() {
+  Promise.all([
+    refreshStats(),
+    refreshLeaderboard(),
+    refreshUniverse(),
+    refreshExplorer(),
+  ]).catch((err) => console.error(err));
+}
+
+connectFeed();
+loadConfig()
+  .catch((err) => console.error(err))
+  .finally(() => {
+    refreshAll();
+    setInterval(refreshAll, REFRESH_INTERVAL_MS);
+  });
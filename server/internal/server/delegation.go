@@ -0,0 +1,465 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// delegationTokenPrefix marks delegation token secrets, so they are easy to spot in logs and configs
+const delegationTokenPrefix = "snd_"
+
+// delegationSweepInterval is the number of tokens minted between sweeps of expired ones
+const delegationSweepInterval = 256
+
+// Delegation token failures, reported to the bot presenting the token
+var (
+	errDelegationTokenInvalid   = errors.New("delegation token is invalid or expired")
+	errDelegationTokenScope     = errors.New("address is outside the delegation token's prefix")
+	errDelegationTokenExhausted = errors.New("delegation token has no claims left")
+	errDelegationTokenName      = errors.New("claimant name does not match the delegation token")
+	errDelegationTokenLimit     = errors.New("too many unexpired delegation tokens for this name")
+)
+
+// DelegationOptions configures the tokens players mint to let bots claim on their behalf
+type DelegationOptions struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxLifetime    time.Duration `yaml:"maxLifetime"`    // Longest lifetime of a token, and the default
+	MaxClaims      int           `yaml:"maxClaims"`      // Most claims a single token may allow
+	MaxPerClaimant int           `yaml:"maxPerClaimant"` // Unexpired tokens one claimant may hold
+}
+
+// DefaultDelegationOptions returns the standard delegation token options
+func DefaultDelegationOptions() DelegationOptions {
+	return DelegationOptions{
+		MaxLifetime:    7 * 24 * time.Hour,
+		MaxClaims:      10000,
+		MaxPerClaimant: 16,
+	}
+}
+
+// delegationToken is a minted token, identified by the hash of its secret
+type delegationToken struct {
+	id        string
+	hash      string
+	claimant  string
+	prefix    *net.IPNet
+	maxClaims int
+	claims    int // Claims made or in progress
+	expiresAt time.Time
+}
+
+// info describes the token without its secret
+func (t *delegationToken) info() api.DelegationToken {
+	return api.DelegationToken{
+		ID:        t.id,
+		Name:      t.claimant,
+		Prefix:    t.prefix.String(),
+		MaxClaims: t.maxClaims,
+		Claims:    t.claims,
+		ExpiresAt: t.expiresAt,
+	}
+}
+
+// delegationPersistence stores delegation tokens across restarts
+type delegationPersistence interface {
+	// loadDelegationTokens returns the unexpired tokens
+	loadDelegationTokens() ([]*delegationToken, error)
+
+	// saveDelegationToken records a newly minted token
+	saveDelegationToken(token *delegationToken) error
+
+	// saveDelegationClaims records the claims made with a token
+	saveDelegationClaims(id string, claims int) error
+
+	// deleteExpiredDelegationTokens drops the tokens expired by now
+	deleteExpiredDelegationTokens(now time.Time) error
+}
+
+// DelegationTokens mints and validates the scoped tokens bots claim with.
+// A token attributes claims to the player who minted it, limited to a prefix,
+// a number of claims and a lifetime. Claimant names are not secret, so a
+// token scopes what a bot may do rather than proving who the player is.
+type DelegationTokens struct {
+	opts        DelegationOptions
+	persistence delegationPersistence // Optional, nil keeps tokens in memory only
+
+	mutex     sync.Mutex
+	tokens    map[string]*delegationToken   // By secret hash
+	claimants map[string][]*delegationToken // Tokens by claimant, bounding how many each may mint
+	minted    int                           // Tokens minted since the last sweep
+
+	logger *slog.Logger
+}
+
+// NewDelegationTokens creates a token manager, restoring persisted tokens if the store supports it
+func NewDelegationTokens(store Store, opts DelegationOptions) (*DelegationTokens, error) {
+	d := &DelegationTokens{
+		opts:      opts,
+		tokens:    make(map[string]*delegationToken),
+		claimants: make(map[string][]*delegationToken),
+		logger:    componentLogger("delegation"),
+	}
+
+	if persistence, ok := store.(delegationPersistence); ok {
+		tokens, err := persistence.loadDelegationTokens()
+		if err != nil {
+			return nil, err
+		}
+		if tokens != nil {
+			d.persistence = persistence
+			for _, token := range tokens {
+				d.tokens[token.hash] = token
+				d.claimants[token.claimant] = append(d.claimants[token.claimant], token)
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// hashDelegationSecret returns the hex SHA-256 hash a token is stored under
+func hashDelegationSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Mint creates a token letting a bot claim up to maxClaims addresses in prefix
+// for claimant, returning it with its secret. A zero lifetime uses the maximum.
+func (d *DelegationTokens) Mint(claimant string, prefix *net.IPNet, maxClaims int, lifetime time.Duration) (api.DelegationToken, error) {
+	if maxClaims <= 0 || maxClaims > d.opts.MaxClaims {
+		return api.DelegationToken{}, fmt.Errorf("maxClaims must be between 1 and %d", d.opts.MaxClaims)
+	}
+	if lifetime == 0 {
+		lifetime = d.opts.MaxLifetime
+	}
+	if lifetime < 0 || lifetime > d.opts.MaxLifetime {
+		return api.DelegationToken{}, fmt.Errorf("expiresIn must be positive and at most %s", d.opts.MaxLifetime)
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return api.DelegationToken{}, err
+	}
+	secret := delegationTokenPrefix + hex.EncodeToString(random)
+	hash := hashDelegationSecret(secret)
+
+	now := time.Now().UTC()
+	token := &delegationToken{
+		id:        hash[:16],
+		hash:      hash,
+		claimant:  claimant,
+		prefix:    prefix,
+		maxClaims: maxClaims,
+		expiresAt: now.Add(lifetime).Truncate(time.Second),
+	}
+
+	// The token is held while it is saved, so concurrent mints count it
+	d.mutex.Lock()
+	swept := false
+	if d.minted++; d.minted >= delegationSweepInterval {
+		d.minted = 0
+		d.sweepLocked(now)
+		swept = true
+	}
+	if len(d.liveLocked(claimant, now)) >= d.opts.MaxPerClaimant {
+		d.mutex.Unlock()
+		return api.DelegationToken{}, errDelegationTokenLimit
+	}
+	d.tokens[hash] = token
+	d.claimants[claimant] = append(d.claimants[claimant], token)
+	d.mutex.Unlock()
+
+	if d.persistence != nil {
+		if swept {
+			if err := d.persistence.deleteExpiredDelegationTokens(now); err != nil {
+				d.logger.Error("Failed to delete expired delegation tokens", "error", err)
+			}
+		}
+		if err := d.persistence.saveDelegationToken(token); err != nil {
+			d.mutex.Lock()
+			d.forgetLocked(token)
+			d.mutex.Unlock()
+			return api.DelegationToken{}, err
+		}
+	}
+
+	info := token.info()
+	info.Token = secret
+	return info, nil
+}
+
+// lookup returns the unexpired token with a secret
+func (d *DelegationTokens) lookup(secret string) (*delegationToken, error) {
+	token, exists := d.tokens[hashDelegationSecret(secret)]
+	if !exists {
+		return nil, errDelegationTokenInvalid
+	}
+	if time.Now().After(token.expiresAt) {
+		d.forgetLocked(token)
+		return nil, errDelegationTokenInvalid
+	}
+	return token, nil
+}
+
+// forgetLocked drops a token (assumes lock is held)
+func (d *DelegationTokens) forgetLocked(token *delegationToken) {
+	delete(d.tokens, token.hash)
+	tokens := d.claimants[token.claimant]
+	for i, held := range tokens {
+		if held == token {
+			tokens = append(tokens[:i], tokens[i+1:]...)
+			break
+		}
+	}
+	if len(tokens) == 0 {
+		delete(d.claimants, token.claimant)
+	} else {
+		d.claimants[token.claimant] = tokens
+	}
+}
+
+// liveLocked returns the unexpired tokens of a claimant, dropping expired
+// ones (assumes lock is held)
+func (d *DelegationTokens) liveLocked(claimant string, now time.Time) []*delegationToken {
+	tokens := d.claimants[claimant]
+	live := tokens[:0]
+	for _, token := range tokens {
+		if now.After(token.expiresAt) {
+			delete(d.tokens, token.hash)
+			continue
+		}
+		live = append(live, token)
+	}
+	if len(live) == 0 {
+		delete(d.claimants, claimant)
+	} else {
+		d.claimants[claimant] = live
+	}
+	return live
+}
+
+// sweepLocked drops every expired token (assumes lock is held)
+func (d *DelegationTokens) sweepLocked(now time.Time) {
+	for _, token := range d.tokens {
+		if now.After(token.expiresAt) {
+			d.forgetLocked(token)
+		}
+	}
+}
+
+// Info describes the token with a secret
+func (d *DelegationTokens) Info(secret string) (api.DelegationToken, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	token, err := d.lookup(secret)
+	if err != nil {
+		return api.DelegationToken{}, err
+	}
+	return token.info(), nil
+}
+
+// reserve takes one of a token's claims for an address, which must be
+// returned with release once the claim succeeds or fails
+func (d *DelegationTokens) reserve(secret string, ip net.IP) (*delegationToken, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	token, err := d.lookup(secret)
+	if err != nil {
+		return nil, err
+	}
+	if !token.prefix.Contains(ip) {
+		return nil, errDelegationTokenScope
+	}
+	if token.claims >= token.maxClaims {
+		return nil, errDelegationTokenExhausted
+	}
+
+	token.claims++
+	return token, nil
+}
+
+// release settles a reserved claim, giving it back to the token if the claim failed
+func (d *DelegationTokens) release(token *delegationToken, claimed bool) {
+	d.mutex.Lock()
+	if !claimed {
+		token.claims--
+	}
+	claims := token.claims
+	d.mutex.Unlock()
+
+	if claimed && d.persistence != nil {
+		if err := d.persistence.saveDelegationClaims(token.id, claims); err != nil {
+			d.logger.Error("Failed to persist delegation token claims", "id", token.id, "error", err)
+		}
+	}
+}
+
+// bearerToken returns the token of a request's bearer authorization
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// writeDelegationError responds to a claim whose delegation token was rejected
+func writeDelegationError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errDelegationTokenInvalid) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, r, unauthorized(err.Error()))
+		return
+	}
+	writeError(w, r, forbidden(err.Error()))
+}
+
+// handleMintDelegationToken mints a token letting a bot claim on a player's behalf
+func (h *HTTPHandler) handleMintDelegationToken(w http.ResponseWriter, r *http.Request) {
+	if h.delegation == nil {
+		writeError(w, r, notFound("delegation is disabled"))
+		return
+	}
+
+	var req api.DelegationTokenRequest
+	if err := h.decodeBody(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	name, err := h.names.Normalize(req.Name)
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	_, prefix, err := net.ParseCIDR(req.Prefix)
+	if err != nil || prefix.IP.To4() != nil {
+		writeError(w, r, badRequest("prefix must be an IPv6 subnet in CIDR notation"))
+		return
+	}
+
+	var lifetime time.Duration
+	if req.ExpiresIn != "" {
+		if lifetime, err = time.ParseDuration(req.ExpiresIn); err != nil {
+			writeError(w, r, badRequest("expiresIn must be a duration such as 24h"))
+			return
+		}
+	}
+
+	token, err := h.delegation.Mint(name, prefix, req.MaxClaims, lifetime)
+	if errors.Is(err, errDelegationTokenLimit) {
+		writeError(w, r, tooManyRequests(err.Error()))
+		return
+	} else if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// handleGetDelegationToken describes the delegation token a bot authenticates with
+func (h *HTTPHandler) handleGetDelegationToken(w http.ResponseWriter, r *http.Request) {
+	if h.delegation == nil {
+		writeError(w, r, notFound("delegation is disabled"))
+		return
+	}
+
+	secret, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, r, unauthorized("missing delegation token"))
+		return
+	}
+
+	token, err := h.delegation.Info(secret)
+	if err != nil {
+		writeDelegationError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Verify ClaimStore can persist delegation tokens
+var _ delegationPersistence = (*ClaimStore)(nil)
+
+// loadDelegationTokens returns the unexpired delegation tokens from SQLite,
+// dropping expired ones. It returns nil without SQLite, leaving tokens in memory only.
+func (cs *ClaimStore) loadDelegationTokens() ([]*delegationToken, error) {
+	if cs.db == nil {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	if _, err := cs.db.Exec("DELETE FROM delegation_tokens WHERE expires_at <= ?", now); err != nil {
+		return nil, err
+	}
+
+	rows, err := cs.db.Query("SELECT id, hash, claimant, prefix, max_claims, claims, expires_at FROM delegation_tokens")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	tokens := []*delegationToken{}
+	for rows.Next() {
+		token := &delegationToken{}
+		var prefix string
+		if err := rows.Scan(&token.id, &token.hash, &token.claimant, &prefix, &token.maxClaims, &token.claims, &token.expiresAt); err != nil {
+			return nil, err
+		}
+		if _, token.prefix, err = net.ParseCIDR(prefix); err != nil {
+			cs.logger.Warn("Ignoring delegation token with invalid prefix", "id", token.id, "prefix", prefix)
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// saveDelegationToken records a newly minted delegation token in SQLite
+func (cs *ClaimStore) saveDelegationToken(token *delegationToken) error {
+	_, err := cs.db.Exec(
+		`INSERT INTO delegation_tokens (id, hash, claimant, prefix, max_claims, claims, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		token.id, token.hash, token.claimant, token.prefix.String(), token.maxClaims, token.claims, token.expiresAt,
+	)
+	return err
+}
+
+// saveDelegationClaims records the claims made with a delegation token in SQLite
+func (cs *ClaimStore) saveDelegationClaims(id string, claims int) error {
+	_, err := cs.db.Exec("UPDATE delegation_tokens SET claims = ? WHERE id = ?", claims, id)
+	return err
+}
+
+// deleteExpiredDelegationTokens drops the delegation tokens expired by now from SQLite
+func (cs *ClaimStore) deleteExpiredDelegationTokens(now time.Time) error {
+	_, err := cs.db.Exec("DELETE FROM delegation_tokens WHERE expires_at <= ?", now)
+	return err
+}
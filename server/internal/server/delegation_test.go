@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDelegationTokens_Reserve tests that tokens are limited to their prefix, claim count and lifetime
+func TestDelegationTokens_Reserve(t *testing.T) {
+	tokens, err := NewDelegationTokens(NewClaimStore(), DefaultDelegationOptions())
+	require.NoError(t, err, "Should create token manager")
+
+	_, prefix, _ := net.ParseCIDR("2001:db8::/64")
+	minted, err := tokens.Mint("alice", prefix, 1, time.Hour)
+	require.NoError(t, err, "Should mint a token")
+	assert.Contains(t, minted.Token, delegationTokenPrefix, "Secret should be returned when minting")
+
+	_, err = tokens.reserve(minted.Token, net.ParseIP("2001:db8:1::1"))
+	assert.ErrorIs(t, err, errDelegationTokenScope, "Addresses outside the prefix should be rejected")
+
+	token, err := tokens.reserve(minted.Token, net.ParseIP("2001:db8::1"))
+	require.NoError(t, err, "Should reserve a claim in the prefix")
+	_, err = tokens.reserve(minted.Token, net.ParseIP("2001:db8::2"))
+	assert.ErrorIs(t, err, errDelegationTokenExhausted, "Reserved claims should count against the token")
+
+	tokens.release(token, false)
+	token, err = tokens.reserve(minted.Token, net.ParseIP("2001:db8::2"))
+	require.NoError(t, err, "Failed claims should be returned to the token")
+	tokens.release(token, true)
+
+	info, err := tokens.Info(minted.Token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Claims, "Successful claims should be counted")
+	assert.Empty(t, info.Token, "Info should not reveal the secret")
+
+	_, err = tokens.reserve("snd_unknown", net.ParseIP("2001:db8::1"))
+	assert.ErrorIs(t, err, errDelegationTokenInvalid, "Unknown tokens should be rejected")
+
+	token.expiresAt = time.Now().Add(-time.Second)
+	_, err = tokens.Info(minted.Token)
+	assert.ErrorIs(t, err, errDelegationTokenInvalid, "Expired tokens should be rejected")
+
+	_, err = tokens.Mint("alice", prefix, 0, time.Hour)
+	assert.Error(t, err, "Tokens should allow at least one claim")
+	_, err = tokens.Mint("alice", prefix, 1, 30*24*time.Hour)
+	assert.Error(t, err, "Lifetimes beyond the maximum should be rejected")
+}
+
+// TestDelegationTokens_PerClaimant tests that claimants hold a bounded
+// number of unexpired tokens, and expired ones are swept
+func TestDelegationTokens_PerClaimant(t *testing.T) {
+	store, err := NewClaimStoreWithSQLite(filepath.Join(t.TempDir(), "delegation.db"))
+	require.NoError(t, err, "Should create SQLite store")
+	defer store.Close()
+	opts := DefaultDelegationOptions()
+	opts.MaxPerClaimant = 2
+	tokens, err := NewDelegationTokens(store, opts)
+	require.NoError(t, err, "Should create token manager")
+
+	_, prefix, _ := net.ParseCIDR("2001:db8::/64")
+	first, err := tokens.Mint("alice", prefix, 1, time.Hour)
+	require.NoError(t, err)
+	_, err = tokens.Mint("alice", prefix, 1, time.Hour)
+	require.NoError(t, err)
+	_, err = tokens.Mint("alice", prefix, 1, time.Hour)
+	assert.ErrorIs(t, err, errDelegationTokenLimit, "Claimants should hold a bounded number of tokens")
+	_, err = tokens.Mint("bob", prefix, 1, time.Hour)
+	assert.NoError(t, err, "Other claimants should not be limited")
+
+	tokens.mutex.Lock()
+	tokens.tokens[hashDelegationSecret(first.Token)].expiresAt = time.Now().Add(-time.Second)
+	tokens.mutex.Unlock()
+	_, err = tokens.Mint("alice", prefix, 1, time.Hour)
+	assert.NoError(t, err, "Expired tokens should not count against the claimant")
+	assert.Len(t, tokens.tokens, 3, "Expired tokens should be dropped")
+
+	// Expired tokens are deleted from SQLite every sweep
+	_, err = store.db.Exec("UPDATE delegation_tokens SET expires_at = ? WHERE claimant = 'bob'", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	tokens.minted = delegationSweepInterval - 1
+	_, err = tokens.Mint("carol", prefix, 1, time.Hour)
+	require.NoError(t, err)
+	var rows int
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM delegation_tokens WHERE claimant = 'bob'").Scan(&rows))
+	assert.Zero(t, rows, "Swept tokens should be deleted")
+}
+
+// TestDelegationTokens_Persistence tests that tokens and their claims survive a restart with SQLite
+func TestDelegationTokens_Persistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "delegation.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+
+	tokens, err := NewDelegationTokens(store, DefaultDelegationOptions())
+	require.NoError(t, err, "Should create token manager")
+
+	_, prefix, _ := net.ParseCIDR("2001:db8::/48")
+	minted, err := tokens.Mint("alice", prefix, 5, 0)
+	require.NoError(t, err, "Should mint a token")
+	token, err := tokens.reserve(minted.Token, net.ParseIP("2001:db8::1"))
+	require.NoError(t, err)
+	tokens.release(token, true)
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+
+	restored, err := NewDelegationTokens(reopened, DefaultDelegationOptions())
+	require.NoError(t, err, "Should load persisted tokens")
+	info, err := restored.Info(minted.Token)
+	require.NoError(t, err, "Persisted token should still be valid")
+	assert.Equal(t, "alice", info.Name, "Claimant should be restored")
+	assert.Equal(t, "2001:db8::/48", info.Prefix, "Prefix should be restored")
+	assert.Equal(t, 1, info.Claims, "Claims made should be restored")
+}
+
+// TestHTTPHandler_DelegatedClaim tests minting a token and claiming with it on the issuer's behalf
+func TestHTTPHandler_DelegatedClaim(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body, err := json.Marshal(api.DelegationTokenRequest{Name: "alice", Prefix: "2001:db8::/64", MaxClaims: 1, ExpiresIn: "1h"})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/tokens", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Tokens should not be minted while disabled")
+
+	handler.delegation, err = NewDelegationTokens(store, DefaultDelegationOptions())
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/tokens", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rr.Code, "Token should be minted")
+	var minted api.DelegationToken
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&minted))
+
+	claim := func(ip, name string) *httptest.ResponseRecorder {
+		pow, err := api.SolveProofOfWork(net.ParseIP(ip), "alice", store.CalculateDifficulty(t.Context(), ip), 1000000)
+		require.NoError(t, err, "Should solve proof of work")
+		data, err := json.Marshal(api.ClaimRequest{Name: name, Nonce: pow.Nonce})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/claim/"+ip, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+minted.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusForbidden, claim("2001:db8:1::1", "").Code, "Claims outside the prefix should be forbidden")
+	assert.Equal(t, http.StatusBadRequest, claim("2001:db8::1", "bob").Code, "Claims for another player should be rejected")
+	require.Equal(t, http.StatusCreated, claim("2001:db8::1", "").Code, "Bot should claim for the issuer")
+
+	claimant, _ := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.Equal(t, "alice", claimant, "Territory should be attributed to the issuer")
+	assert.Equal(t, http.StatusForbidden, claim("2001:db8::2", "alice").Code, "Exhausted tokens should be forbidden")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tokens/self", nil)
+	req.Header.Set("Authorization", "Bearer "+minted.Token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Bot should see its token")
+	var info api.DelegationToken
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&info))
+	assert.Equal(t, 1, info.Claims, "Token should count the successful claim")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tokens/self", nil)
+	req.Header.Set("Authorization", "Bearer snd_unknown")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Unknown tokens should be unauthorized")
+}
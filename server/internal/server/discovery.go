@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/grandcat/zeroconf"
+)
+
+// maxDiscoveryName is the longest game name advertised, the length of a DNS label
+const maxDiscoveryName = 63
+
+// DiscoveryOptions configures advertising the server on the local network
+// over mDNS, so players at LAN parties can pick it from a list instead of
+// typing its address
+type DiscoveryOptions struct {
+	Enabled    bool     `yaml:"enabled"`
+	Name       string   `yaml:"name"`       // Game name shown to players, the host name if empty
+	Interfaces []string `yaml:"interfaces"` // Network interfaces advertised on, every multicast one if empty
+}
+
+// DefaultDiscoveryOptions returns the standard discovery options, advertising nothing
+func DefaultDiscoveryOptions() DiscoveryOptions {
+	return DiscoveryOptions{}
+}
+
+// Validate checks that the discovery options are usable
+func (o DiscoveryOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if len(o.Name) > maxDiscoveryName {
+		return fmt.Errorf("discovery name must be at most %d bytes, got %d", maxDiscoveryName, len(o.Name))
+	}
+	for _, name := range o.Interfaces {
+		if _, err := net.InterfaceByName(name); err != nil {
+			return fmt.Errorf("discovery interface %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Advertiser announces the server's API on the local network
+type Advertiser struct {
+	name       string
+	interfaces []net.Interface
+	mu         sync.Mutex
+	zeroconf   *zeroconf.Server // Answers queries while advertising, nil otherwise
+	logger     *slog.Logger
+}
+
+// NewAdvertiser creates an advertiser for the game named in the options
+func NewAdvertiser(opts DiscoveryOptions) (*Advertiser, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	advertiser := &Advertiser{name: opts.Name, logger: componentLogger("discovery")}
+	if advertiser.name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to name the game after the host: %w", err)
+		}
+		advertiser.name = hostname
+	}
+	for _, name := range opts.Interfaces {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, err
+		}
+		advertiser.interfaces = append(advertiser.interfaces, *iface)
+	}
+	return advertiser, nil
+}
+
+// Name returns the game name advertised
+func (a *Advertiser) Name() string {
+	return a.name
+}
+
+// Advertise starts answering mDNS queries for the API on a port until Stop
+// is called
+func (a *Advertiser) Advertise(port int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.zeroconf != nil {
+		return errors.New("already advertising")
+	}
+
+	server, err := zeroconf.Register(a.name, api.DiscoveryService, api.DiscoveryDomain, port, api.DiscoveryText(a.name), a.interfaces)
+	if err != nil {
+		return fmt.Errorf("failed to advertise over mDNS: %w", err)
+	}
+	a.zeroconf = server
+	a.logger.Info("Advertising server on the local network", "name", a.name, "service", api.DiscoveryService, "port", port)
+	return nil
+}
+
+// Stop withdraws the advertisement, telling clients the server is gone
+func (a *Advertiser) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.zeroconf != nil {
+		a.zeroconf.Shutdown()
+		a.zeroconf = nil
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/grandcat/zeroconf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewAdvertiser tests naming the advertised game
+func TestNewAdvertiser(t *testing.T) {
+	advertiser, err := NewAdvertiser(DiscoveryOptions{Enabled: true, Name: "LAN party"})
+	require.NoError(t, err)
+	assert.Equal(t, "LAN party", advertiser.Name(), "The configured name should be advertised")
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	advertiser, err = NewAdvertiser(DiscoveryOptions{Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, hostname, advertiser.Name(), "The host name should be advertised without a name")
+
+	_, err = NewAdvertiser(DiscoveryOptions{Enabled: true, Name: strings.Repeat("x", maxDiscoveryName+1)})
+	assert.Error(t, err, "Names longer than a DNS label should be rejected")
+}
+
+// TestAdvertiser_Browse tests that an advertised server is found by browsing
+func TestAdvertiser_Browse(t *testing.T) {
+	advertiser, err := NewAdvertiser(DiscoveryOptions{Enabled: true, Name: "spacenet-test"})
+	require.NoError(t, err)
+	if err := advertiser.Advertise(8080); err != nil {
+		t.Skipf("Multicast is unavailable: %v", err)
+	}
+	defer advertiser.Stop()
+	assert.Error(t, advertiser.Advertise(8080), "Advertising twice should fail")
+
+	resolver, err := zeroconf.NewResolver(nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	entries := make(chan *zeroconf.ServiceEntry)
+	require.NoError(t, resolver.Browse(ctx, api.DiscoveryService, api.DiscoveryDomain, entries))
+
+	for entry := range entries {
+		if entry.Instance == "spacenet-test" {
+			assert.Equal(t, 8080, entry.Port, "The API port should be advertised")
+			assert.Equal(t, "spacenet-test", api.DiscoveredName(entry.Text), "The game name should be in the TXT record")
+			return
+		}
+	}
+	t.Skip("Multicast is not looped back on this host")
+}
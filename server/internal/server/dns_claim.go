@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Subnet claims must be nibble aligned, so they map to an ip6.arpa zone,
+// and between these prefix lengths
+const (
+	minDNSClaimPrefix = 16
+	maxDNSClaimPrefix = 124
+)
+
+// dnsClaimRecordPrefix is the label under the ip6.arpa zone holding claim tokens
+const dnsClaimRecordPrefix = "_spacenet"
+
+// errSubnetNotVerified marks subnet claims whose TXT record could not be verified
+var errSubnetNotVerified = errors.New("subnet TXT record does not contain the claim token")
+
+// DNSClaimOptions configures claiming whole subnets by proving control of their
+// reverse DNS zone with a TXT record
+type DNSClaimOptions struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"` // How long to wait for DNS lookups
+	Secret  string        `yaml:"secret"`  // Token signing secret, random per run if empty
+}
+
+// DefaultDNSClaimOptions returns the standard DNS subnet claim options
+func DefaultDNSClaimOptions() DNSClaimOptions {
+	return DNSClaimOptions{Timeout: 5 * time.Second}
+}
+
+// txtResolver looks up TXT records
+type txtResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DNSClaimVerifier verifies subnet claims against TXT records in the ip6.arpa zone
+type DNSClaimVerifier struct {
+	resolver txtResolver
+	key      []byte
+	timeout  time.Duration
+}
+
+// NewDNSClaimVerifier creates a verifier using the system resolver
+func NewDNSClaimVerifier(opts DNSClaimOptions) *DNSClaimVerifier {
+	key := []byte(opts.Secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("failed to generate DNS claim secret: " + err.Error())
+		}
+	}
+
+	return &DNSClaimVerifier{
+		resolver: net.DefaultResolver,
+		key:      key,
+		timeout:  opts.Timeout,
+	}
+}
+
+// parseClaimSubnet parses a subnet that can be claimed through DNS
+func parseClaimSubnet(subnet string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() != nil {
+		return nil, fmt.Errorf("invalid IPv6 subnet %q", subnet)
+	}
+
+	prefixLen := prefixLength(ipNet)
+	if prefixLen%4 != 0 || prefixLen < minDNSClaimPrefix || prefixLen > maxDNSClaimPrefix {
+		return nil, fmt.Errorf("prefix length must be a multiple of 4 between %d and %d", minDNSClaimPrefix, maxDNSClaimPrefix)
+	}
+
+	return ipNet, nil
+}
+
+// RecordName returns the name of the TXT record holding the claim token for a subnet,
+// for example _spacenet.8.b.d.0.1.0.0.2.ip6.arpa for 2001:db8::/32
+func (v *DNSClaimVerifier) RecordName(subnet *net.IPNet) string {
+	hexIP := hex.EncodeToString(subnet.IP.To16())
+	nibbles := prefixLength(subnet) / 4
+
+	labels := make([]string, 0, nibbles+2)
+	labels = append(labels, dnsClaimRecordPrefix)
+	for i := nibbles - 1; i >= 0; i-- {
+		labels = append(labels, hexIP[i:i+1])
+	}
+	labels = append(labels, "ip6.arpa")
+
+	return strings.Join(labels, ".")
+}
+
+// RecordValue returns the TXT record value proving a claimant controls a subnet
+func (v *DNSClaimVerifier) RecordValue(subnet *net.IPNet, name string) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(subnet.String()))
+	mac.Write([]byte(name))
+	return "spacenet-claim=" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Verify checks that the TXT record of a subnet contains the claimant's token
+func (v *DNSClaimVerifier) Verify(ctx context.Context, subnet *net.IPNet, name string) error {
+	if v.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.timeout)
+		defer cancel()
+	}
+
+	records, err := v.resolver.LookupTXT(ctx, v.RecordName(subnet))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSubnetNotVerified, err)
+	}
+
+	expected := v.RecordValue(subnet, name)
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			return nil
+		}
+	}
+	return errSubnetNotVerified
+}
+
+// handleGetSubnetChallenge returns the TXT record a claimant must publish to claim a subnet
+func (h *HTTPHandler) handleGetSubnetChallenge(w http.ResponseWriter, r *http.Request) {
+	if h.dnsClaims == nil {
+		writeError(w, r, notFound("subnet claims are disabled"))
+		return
+	}
+
+	subnet, err := parseClaimSubnet(r.URL.Query().Get("subnet"))
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+	name, err := h.names.Normalize(r.URL.Query().Get("name"))
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(api.SubnetChallenge{
+		Subnet:      subnet.String(),
+		Name:        name,
+		RecordName:  h.dnsClaims.RecordName(subnet),
+		RecordValue: h.dnsClaims.RecordValue(subnet, name),
+	}); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleClaimSubnet grants dominance of a subnet to a claimant who published its TXT record
+func (h *HTTPHandler) handleClaimSubnet(w http.ResponseWriter, r *http.Request) {
+	if h.dnsClaims == nil {
+		writeError(w, r, notFound("subnet claims are disabled"))
+		return
+	}
+
+	var claimReq api.SubnetClaimRequest
+	if err := h.decodeBody(w, r, &claimReq); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	subnet, err := parseClaimSubnet(claimReq.Subnet)
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+	if err := h.federation.checkAuthoritative(subnet); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name, err := h.names.Normalize(claimReq.Name)
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	if err := h.dnsClaims.Verify(r.Context(), subnet, name); err != nil {
+		h.logger.Debug("Subnet claim not verified", "subnet", subnet.String(), "claimant", name, "error", err)
+		writeError(w, r, unprocessable("TXT record not found"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	if err := h.store.GrantSubnet(ctx, subnet.String(), name); errors.Is(err, ErrNameConfusable) {
+		writeError(w, r, conflict(err.Error()))
+		return
+	} else if err != nil {
+		writeError(w, r, fmt.Errorf("granting subnet %s: %w", subnet, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticResolver serves TXT records from a fixed map
+type staticResolver struct {
+	records map[string][]string
+}
+
+func (r *staticResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, exists := r.records[name]
+	if !exists {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+// TestParseClaimSubnet tests which subnets can be claimed through DNS
+func TestParseClaimSubnet(t *testing.T) {
+	for _, subnet := range []string{"2001:db8::/32", "2001:db8:1::/48", "2001:db8::/124"} {
+		_, err := parseClaimSubnet(subnet)
+		assert.NoError(t, err, "Nibble-aligned subnet %s should be accepted", subnet)
+	}
+	for _, subnet := range []string{"2001:db8::/33", "::/0", "2001:db8::/128", "10.0.0.0/8", "not-a-subnet"} {
+		_, err := parseClaimSubnet(subnet)
+		assert.Error(t, err, "Subnet %s should be rejected", subnet)
+	}
+}
+
+// TestDNSClaimVerifier_Verify tests record naming and token verification
+func TestDNSClaimVerifier_Verify(t *testing.T) {
+	verifier := NewDNSClaimVerifier(DNSClaimOptions{Enabled: true, Secret: "test-secret"})
+	subnet, err := parseClaimSubnet("2001:db8::/32")
+	require.NoError(t, err)
+
+	recordName := verifier.RecordName(subnet)
+	assert.Equal(t, "_spacenet.8.b.d.0.1.0.0.2.ip6.arpa", recordName, "Record should live in the subnet's ip6.arpa zone")
+	assert.NotEqual(t, verifier.RecordValue(subnet, "alice"), verifier.RecordValue(subnet, "bob"),
+		"Tokens should differ per claimant")
+
+	verifier.resolver = &staticResolver{records: map[string][]string{
+		recordName: {"v=spf1 -all", verifier.RecordValue(subnet, "alice")},
+	}}
+	assert.NoError(t, verifier.Verify(context.Background(), subnet, "alice"), "Published token should be verified")
+	assert.ErrorIs(t, verifier.Verify(context.Background(), subnet, "bob"), errSubnetNotVerified,
+		"Another claimant's token should be rejected")
+
+	other, err := parseClaimSubnet("2001:db9::/32")
+	require.NoError(t, err)
+	assert.ErrorIs(t, verifier.Verify(context.Background(), other, "alice"), errSubnetNotVerified,
+		"Subnet without a record should be rejected")
+}
+
+// TestClaimStore_GrantSubnet tests that granted subnets are owned unless claims hold a majority
+func TestClaimStore_GrantSubnet(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "grants.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+
+	require.NoError(t, store.GrantSubnet(t.Context(), "2001:db8::/32", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:1::1", "bob"))
+
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/32", 0)
+	require.True(t, ok)
+	assert.Equal(t, "alice", stats.Owner, "Grantee should own the subnet")
+	assert.True(t, stats.Granted, "Ownership should be marked as granted")
+
+	stats, ok = store.GetSubnetStats(t.Context(), "2001:db8:1::1/128", 0)
+	require.True(t, ok)
+	assert.Equal(t, "bob", stats.Owner, "Majority claimant should own a subnet inside the grant")
+	assert.False(t, stats.Granted, "Ownership by claims should not be marked as granted")
+
+	stats, ok = store.GetSubnetStats(t.Context(), "2001:db8:2::/48", 0)
+	require.True(t, ok)
+	assert.Equal(t, "alice", stats.Owner, "Grant should cover unclaimed subnets within it")
+
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+
+	stats, ok = reopened.GetSubnetStats(t.Context(), "2001:db8::/32", 0)
+	require.True(t, ok)
+	assert.Equal(t, "alice", stats.Owner, "Grants should persist in SQLite")
+
+	require.NoError(t, reopened.Reset(t.Context()))
+	stats, _ = reopened.GetSubnetStats(t.Context(), "2001:db8::/32", 0)
+	assert.Empty(t, stats.Owner, "Reset should clear grants")
+}
+
+// TestHTTPHandler_ClaimSubnet tests claiming a subnet through the DNS challenge endpoints
+func TestHTTPHandler_ClaimSubnet(t *testing.T) {
+	store := NewClaimStore()
+	verifier := NewDNSClaimVerifier(DNSClaimOptions{Enabled: true, Secret: "test-secret"})
+	handler := NewHTTPHandler(store)
+	handler.dnsClaims = verifier
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/claim-subnet/challenge?subnet=2001:db8::/32&name=alice", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Challenge should be issued")
+
+	var challenge api.SubnetChallenge
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&challenge))
+	verifier.resolver = &staticResolver{records: map[string][]string{challenge.RecordName: {challenge.RecordValue}}}
+
+	claim := func(subnet string, name string) int {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"subnet":"` + subnet + `","name":"` + name + `"}`)
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/claim-subnet", body))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, claim("2001:db8::/30", "alice"), "Unaligned subnet should be rejected")
+	assert.Equal(t, http.StatusUnprocessableEntity, claim("2001:db8::/32", "bob"), "Claimant without the record should be rejected")
+	assert.Equal(t, http.StatusCreated, claim("2001:db8::/32", "alice"), "Verified subnet claim should be granted")
+
+	stats, _ := store.GetSubnetStats(t.Context(), "2001:db8::/32", 0)
+	assert.Equal(t, "alice", stats.Owner, "Granted subnet should be owned by the claimant")
+
+	handler.dnsClaims = nil
+	assert.Equal(t, http.StatusNotFound, claim("2001:db8::/32", "alice"), "Disabled subnet claims should return 404")
+}
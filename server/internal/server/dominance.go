@@ -0,0 +1,116 @@
+package server
+
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// levelDominance tallies the subnets with a claim at one prefix length by the
+// addresses their dominant claimant holds. Dominance is that count over the
+// size of the subnet, the same for every subnet of the level, so the tally
+// gives the distribution of dominance without visiting any subnet.
+type levelDominance struct {
+	subnets map[int64]int // Subnets by the addresses held by their dominant claimant
+	count   int           // Subnets with a claim
+	sum     int64         // Addresses held by the dominant claimants of every subnet
+}
+
+// newLevelDominance creates an empty tally for each tracked prefix
+func newLevelDominance(prefixes []int) map[int]*levelDominance {
+	tallies := make(map[int]*levelDominance, len(prefixes))
+	for _, prefixLen := range prefixes {
+		tallies[prefixLen] = &levelDominance{subnets: make(map[int64]int)}
+	}
+	return tallies
+}
+
+// move retallies a subnet whose dominant claimant went from holding old
+// addresses to holding held, where zero means the subnet has no claims
+func (d *levelDominance) move(old, held int64) {
+	if old == held {
+		return
+	}
+	if old > 0 {
+		if d.subnets[old]--; d.subnets[old] == 0 {
+			delete(d.subnets, old)
+		}
+		d.count--
+		d.sum -= old
+	}
+	if held > 0 {
+		d.subnets[held]++
+		d.count++
+		d.sum += held
+	}
+}
+
+// stats summarizes the tally of a level, with dominance as a percentage of
+// the size of its subnets
+func (d *levelDominance) stats(prefixLen int) api.LevelStats {
+	stats := api.LevelStats{Level: prefixLen, Subnets: d.count}
+	if d.count == 0 {
+		return stats
+	}
+
+	// A subnet is owned when its dominant claimant holds more than half of it
+	percentage := func(held float64) float64 {
+		return math.Ldexp(held, prefixLen-128) * 100
+	}
+	held := slices.Sorted(maps.Keys(d.subnets))
+	for _, n := range held {
+		if percentage(float64(n)) > 50 {
+			stats.Owned += d.subnets[n]
+		}
+	}
+	stats.Contested = d.count - stats.Owned
+	stats.MeanDominance = percentage(float64(d.sum) / float64(d.count))
+
+	// Nearest-rank percentiles, walking the tally in order
+	percentile := func(p int) float64 {
+		rank := max((p*d.count+99)/100, 1)
+		seen := 0
+		for _, n := range held {
+			if seen += d.subnets[n]; seen >= rank {
+				return percentage(float64(n))
+			}
+		}
+		return percentage(float64(held[len(held)-1]))
+	}
+	stats.P25Dominance = percentile(25)
+	stats.MedianDominance = percentile(50)
+	stats.P75Dominance = percentile(75)
+	stats.P90Dominance = percentile(90)
+	return stats
+}
+
+// maxClaimantCount returns the addresses held by the claimant holding the most of a node
+func (node *IPNode) maxClaimantCount() int64 {
+	var held int64
+	for _, count := range node.claimants {
+		held = max(held, count.Int64())
+	}
+	return held
+}
+
+// setDominantCount records the addresses held by the dominant claimant of a
+// node, retallying the node's level (assumes lock is held)
+func (t *IPTree) setDominantCount(node *IPNode, held int64) {
+	t.dominance[node.prefixLen].move(node.dominantCount, held)
+	node.dominantCount = held
+}
+
+// LevelStats returns the distribution of dominance over the subnets with a
+// claim at each tracked prefix length, widest first
+func (t *IPTree) LevelStats() []api.LevelStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	stats := make([]api.LevelStats, 0, len(t.prefixes))
+	for _, prefixLen := range t.prefixes {
+		stats = append(stats, t.dominance[prefixLen].stats(prefixLen))
+	}
+	return stats
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// levelStats returns the dominance statistics of one level of a store
+func levelStats(t *testing.T, store *ClaimStore, prefixLen int) api.LevelStats {
+	t.Helper()
+	for _, stats := range store.GetLevelStats(t.Context()) {
+		if stats.Level == prefixLen {
+			return stats
+		}
+	}
+	t.Fatalf("Level /%d should be tracked", prefixLen)
+	return api.LevelStats{}
+}
+
+// TestClaimStore_LevelStats tests that dominance statistics follow claims, takeovers and unclaims
+func TestClaimStore_LevelStats(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::3", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "carol"))
+
+	// One address of a /112 is this percentage of it
+	const address = 100.0 / 65536
+
+	stats := levelStats(t, store, 128)
+	assert.Equal(t, 4, stats.Subnets)
+	assert.Equal(t, 4, stats.Owned, "Claimed addresses should be owned")
+	assert.Zero(t, stats.Contested)
+	assert.InDelta(t, 100, stats.MeanDominance, 1e-9)
+	assert.InDelta(t, 100, stats.MedianDominance, 1e-9)
+
+	stats = levelStats(t, store, 112)
+	assert.Equal(t, 2, stats.Subnets)
+	assert.Zero(t, stats.Owned)
+	assert.Equal(t, 2, stats.Contested, "Subnets without a majority should be contested")
+	assert.InDelta(t, 1.5*address, stats.MeanDominance, 1e-12)
+	assert.InDelta(t, address, stats.MedianDominance, 1e-12, "Median should be the lower subnet of two")
+	assert.InDelta(t, 2*address, stats.P90Dominance, 1e-12)
+
+	// Bob takes one of Alice's addresses, becoming dominant with as many
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	stats = levelStats(t, store, 112)
+	assert.InDelta(t, 1.5*address, stats.MeanDominance, 1e-12, "Dominance should not change hands with the count")
+
+	// Bob releases both, leaving Alice with one
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::3", "bob"))
+	stats = levelStats(t, store, 112)
+	assert.InDelta(t, address, stats.MeanDominance, 1e-12, "Dominance should fall to the next claimant")
+	assert.InDelta(t, address, stats.P90Dominance, 1e-12)
+	assert.Equal(t, 2, levelStats(t, store, 128).Subnets, "Released addresses should not be counted")
+
+	require.NoError(t, store.Reset(t.Context()))
+	assert.Equal(t, api.LevelStats{Level: 112}, levelStats(t, store, 112), "Reset should clear the statistics")
+}
+
+// TestHTTPHandler_LevelStats tests that dominance statistics are served for every level
+func TestHTTPHandler_LevelStats(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	router := mux.NewRouter()
+	NewHTTPHandler(store).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/levels", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var stats []api.LevelStats
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&stats))
+	require.Len(t, stats, len(store.Levels(t.Context())), "Every level should be summarized")
+	assert.Equal(t, 16, stats[0].Level, "Widest level should be first")
+	assert.Equal(t, 1, stats[len(stats)-1].Subnets)
+	assert.Equal(t, 1, stats[len(stats)-1].Owned)
+}
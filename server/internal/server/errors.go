@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Error codes of the error envelope, one per kind of failure
+const (
+	codeBadRequest      = "bad_request"
+	codeUnauthorized    = "unauthorized"
+	codeForbidden       = "forbidden"
+	codeNotFound        = "not_found"
+	codeNotAllowed      = "method_not_allowed"
+	codeConflict        = "conflict"
+	codeMisdirected     = "misdirected"
+	codeTooLarge        = "too_large"
+	codeUnprocessable   = "unprocessable"
+	codeTooManyRequests = "rate_limited"
+	codeInternal        = "internal"
+	codeBadGateway      = "bad_gateway"
+	codeUnavailable     = "unavailable"
+)
+
+// apiError is a failure reported to API clients, with the status it is
+// answered with and the code clients can match on
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// badRequest reports a malformed request, such as an invalid address or body
+func badRequest(message string) *apiError {
+	return &apiError{http.StatusBadRequest, codeBadRequest, message}
+}
+
+// unauthorized reports missing or invalid credentials
+func unauthorized(message string) *apiError {
+	return &apiError{http.StatusUnauthorized, codeUnauthorized, message}
+}
+
+// forbidden reports credentials that do not allow the request
+func forbidden(message string) *apiError {
+	return &apiError{http.StatusForbidden, codeForbidden, message}
+}
+
+// notFound reports a missing resource or a disabled feature
+func notFound(message string) *apiError {
+	return &apiError{http.StatusNotFound, codeNotFound, message}
+}
+
+// methodNotAllowed reports a request the server does not accept, such as a write to a replica
+func methodNotAllowed(message string) *apiError {
+	return &apiError{http.StatusMethodNotAllowed, codeNotAllowed, message}
+}
+
+// conflict reports a request that conflicts with the state of the game
+func conflict(message string) *apiError {
+	return &apiError{http.StatusConflict, codeConflict, message}
+}
+
+// misdirected reports a claim on an address another federated server is authoritative for
+func misdirected(message string) *apiError {
+	return &apiError{http.StatusMisdirectedRequest, codeMisdirected, message}
+}
+
+// tooLarge reports a request body over its size limit
+func tooLarge(message string) *apiError {
+	return &apiError{http.StatusRequestEntityTooLarge, codeTooLarge, message}
+}
+
+// unprocessable reports a well-formed claim that failed verification
+func unprocessable(message string) *apiError {
+	return &apiError{http.StatusUnprocessableEntity, codeUnprocessable, message}
+}
+
+// tooManyRequests reports a client over its rate limit
+func tooManyRequests(message string) *apiError {
+	return &apiError{http.StatusTooManyRequests, codeTooManyRequests, message}
+}
+
+// badGateway reports a failure of the server a request was forwarded to
+func badGateway(message string) *apiError {
+	return &apiError{http.StatusBadGateway, codeBadGateway, message}
+}
+
+// unavailable reports a server too busy to take the request, set Retry-After before writing it
+func unavailable(message string) *apiError {
+	return &apiError{http.StatusServiceUnavailable, codeUnavailable, message}
+}
+
+// writeError answers a request with the error envelope. Errors other than
+// apiErrors are logged with the request ID and reported as internal errors,
+// so their details do not leak to clients.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	id := requestID(r.Context())
+
+	// Typed store errors are answered with the status of their kind. Requests
+	// that outlived the store timeout or met a failing backend may succeed
+	// when retried.
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		err = unavailable("the store did not respond in time")
+	case errors.Is(err, ErrBackendUnavailable):
+		componentLogger("http").Error("Store backend failed", "request_id", id, "method", r.Method, "path", r.URL.Path, "error", err)
+		w.Header().Set("Retry-After", "1")
+		err = unavailable(ErrBackendUnavailable.Error())
+	case errors.Is(err, ErrInvalidAddress):
+		err = badRequest(err.Error())
+	case errors.Is(err, ErrProtected), errors.Is(err, ErrQuarantined):
+		err = forbidden(err.Error())
+	case errors.Is(err, ErrRateLimited):
+		err = tooManyRequests(err.Error())
+	case errors.Is(err, ErrOutOfSupply):
+		err = conflict(err.Error())
+	}
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		componentLogger("http").Error("Request failed", "request_id", id, "method", r.Method, "path", r.URL.Path, "error", err)
+		apiErr = &apiError{http.StatusInternalServerError, codeInternal, "internal server error"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.status)
+	_ = json.NewEncoder(w).Encode(api.ErrorResponse{
+		Code:      apiErr.code,
+		Message:   apiErr.message,
+		RequestID: id,
+		Error:     apiErr.message,
+	})
+}
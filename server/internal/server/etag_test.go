@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHTTPHandler_ETags tests conditional requests on the subnet and claim endpoints
+func TestHTTPHandler_ETags(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	get := func(path string, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/api/subnet/2001:db8::1/128", "/api/ip/2001:db8::1"} {
+		rec := get(path, "")
+		require.Equal(t, http.StatusOK, rec.Code, "%s should be served", path)
+		etag := rec.Header().Get("ETag")
+		require.NotEmpty(t, etag, "%s should have an ETag", path)
+
+		rec = get(path, etag)
+		assert.Equal(t, http.StatusNotModified, rec.Code, "%s should not be resent when unchanged", path)
+		assert.Empty(t, rec.Body.String(), "Not modified responses should have no body")
+
+		rec = get(path, `"other", W/`+etag)
+		assert.Equal(t, http.StatusNotModified, rec.Code, "%s should match weak ETags in a list", path)
+
+		require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+		rec = get(path, etag)
+		assert.Equal(t, http.StatusOK, rec.Code, "%s should be resent after a takeover", path)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"), "%s ETag should change with the content", path)
+		require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	}
+}
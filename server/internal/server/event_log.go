@@ -0,0 +1,110 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// ErrNoEventLog marks databases written before claim events were logged
+var ErrNoEventLog = errors.New("database has no event log")
+
+// eventLogSchema logs every change of owner in the claims table. Triggers
+// write the log in the same statement as the claim, so single claims,
+// batches, releases and season resets are all logged without being able to
+// drift from the claims table. Claims by the current owner are not logged.
+const eventLogSchema = `
+	CREATE TABLE IF NOT EXISTS claim_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		ip_address TEXT NOT NULL,
+		claimant TEXT NOT NULL DEFAULT '',
+		previous_claimant TEXT NOT NULL DEFAULT '',
+		at TIMESTAMP NOT NULL
+	);
+	CREATE TRIGGER IF NOT EXISTS log_claim_insert AFTER INSERT ON claims
+	WHEN NEW.released_at IS NULL
+	BEGIN
+		INSERT INTO claim_events (type, ip_address, claimant, at)
+		VALUES ('claim', NEW.ip_address, NEW.claimant, COALESCE(NEW.claimed_at, CURRENT_TIMESTAMP));
+	END;
+	CREATE TRIGGER IF NOT EXISTS log_claim_update AFTER UPDATE OF claimant, released_at ON claims
+	WHEN NEW.released_at IS NULL AND (OLD.released_at IS NOT NULL OR OLD.claimant != NEW.claimant)
+	BEGIN
+		INSERT INTO claim_events (type, ip_address, claimant, previous_claimant, at)
+		VALUES ('claim', NEW.ip_address, NEW.claimant, CASE WHEN OLD.released_at IS NULL THEN OLD.claimant ELSE '' END,
+			COALESCE(NEW.claimed_at, CURRENT_TIMESTAMP));
+	END;
+	CREATE TRIGGER IF NOT EXISTS log_claim_release AFTER UPDATE OF released_at ON claims
+	WHEN OLD.released_at IS NULL AND NEW.released_at IS NOT NULL
+	BEGIN
+		INSERT INTO claim_events (type, ip_address, previous_claimant, at)
+		VALUES ('unclaim', OLD.ip_address, OLD.claimant, NEW.released_at);
+	END;
+	CREATE TRIGGER IF NOT EXISTS log_claim_delete AFTER DELETE ON claims
+	WHEN OLD.released_at IS NULL
+	BEGIN
+		INSERT INTO claim_events (type, ip_address, previous_claimant, at)
+		VALUES ('unclaim', OLD.ip_address, OLD.claimant, CURRENT_TIMESTAMP);
+	END;
+`
+
+// initEventLog creates the event log. Databases written before the log
+// existed start it with a claim for each address held, so replaying the
+// whole log always ends at the current claims.
+func (cs *ClaimStore) initEventLog() error {
+	exists, err := hasEventLog(cs.db)
+	if err != nil {
+		return err
+	}
+	if _, err := cs.db.Exec(eventLogSchema); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = cs.db.Exec(
+		`INSERT INTO claim_events (type, ip_address, claimant, at)
+		SELECT 'claim', ip_address, claimant, COALESCE(claimed_at, updated_at) FROM claims
+		WHERE released_at IS NULL ORDER BY claimed_at`,
+	)
+	return err
+}
+
+// hasEventLog reports whether a database has an event log
+func hasEventLog(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'claim_events'").Scan(&count)
+	return count > 0, err
+}
+
+// readEventLog passes the logged events of a database to apply, oldest first
+func readEventLog(db *sql.DB, apply func(api.ClaimEvent)) error {
+	exists, err := hasEventLog(db)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoEventLog
+	}
+
+	rows, err := db.Query("SELECT type, ip_address, claimant, previous_claimant, at FROM claim_events ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var event api.ClaimEvent
+		var at time.Time
+		if err := rows.Scan(&event.Type, &event.IP, &event.Claimant, &event.PreviousClaimant, &at); err != nil {
+			return err
+		}
+		event.Timestamp = at.UTC()
+		apply(event)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// eventBufferSize is the number of events buffered per subscriber before events are dropped
+const eventBufferSize = 64
+
+// EventBroker fans out claim events to subscribers of the live event feed
+type EventBroker struct {
+	mutex       sync.RWMutex
+	subscribers map[chan api.ClaimEvent]struct{}
+}
+
+// NewEventBroker creates a new event broker with no subscribers
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		subscribers: make(map[chan api.ClaimEvent]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber, returning its event channel and a function
+// that unsubscribes and closes the channel
+func (b *EventBroker) Subscribe() (<-chan api.ClaimEvent, func()) {
+	ch := make(chan api.ClaimEvent, eventBufferSize)
+
+	b.mutex.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subscribers, ch)
+			b.mutex.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to all subscribers without blocking.
+// Subscribers that are not keeping up miss the event.
+func (b *EventBroker) Publish(event api.ClaimEvent) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// sseHeartbeatInterval is how often a comment is sent to keep idle event streams open
+const sseHeartbeatInterval = 15 * time.Second
+
+// handleEvents streams claim events to the client using server-sent events
+func (h *HTTPHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, fmt.Errorf("%T does not support streaming", w))
+		return
+	}
+
+	events, unsubscribe := h.store.SubscribeEvents(r.Context())
+	defer unsubscribe()
+
+	// Streams stay open for as long as the client listens
+	disableWriteTimeout(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.Error("Error encoding event", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
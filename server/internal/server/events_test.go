@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_PublishesEvents tests that ownership changes are published and duplicates are not
+func TestClaimStore_PublishesEvents(t *testing.T) {
+	store := NewClaimStore()
+	events, unsubscribe := store.SubscribeEvents(t.Context())
+	defer unsubscribe()
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+
+	first := <-events
+	assert.Equal(t, api.EventTypeClaim, first.Type, "Event type should be claim")
+	assert.Equal(t, "alice", first.Claimant, "First event should be alice's claim")
+	assert.Empty(t, first.PreviousClaimant, "New claim should have no previous claimant")
+
+	second := <-events
+	assert.Equal(t, "bob", second.Claimant, "Second event should be bob's takeover")
+	assert.Equal(t, "alice", second.PreviousClaimant, "Takeover should record previous claimant")
+
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected event for duplicate claim: %+v", event)
+	default:
+	}
+}
+
+// TestClaimStore_Leaderboard tests leaderboard ranking and global stats
+func TestClaimStore_Leaderboard(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::3", "carol"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::4", "carol"))
+
+	assert.Equal(t, []api.LeaderboardEntry{
+		{Name: "carol", Addresses: 2},
+		{Name: "alice", Addresses: 1},
+	}, store.GetLeaderboard(t.Context(), 2), "Leaderboard should rank by addresses then name")
+
+	assert.Equal(t, api.StatsResponse{TotalClaims: 4, Claimants: 3}, store.GetStats(t.Context()), "Stats should count claims and claimants")
+}
+
+// TestHTTPHandler_EventStream tests streaming claim events over server-sent events
+func TestHTTPHandler_EventStream(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		HTTPPort: 0,
+	})
+
+	err := server.Start()
+	require.NoError(t, err, "Server should start successfully")
+	defer server.Stop()
+
+	httpPort, err := server.WaitForHTTPPort(5 * time.Second)
+	require.NoError(t, err, "HTTP port should be assigned within timeout")
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/api/events", httpPort))
+	require.NoError(t, err, "Event stream request should succeed")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"), "Response should be an event stream")
+
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err, "Reading event stream should succeed")
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			data = strings.TrimSpace(payload)
+		}
+	}
+
+	var event api.ClaimEvent
+	require.NoError(t, json.Unmarshal([]byte(data), &event), "Event should decode")
+	assert.Equal(t, "alice", event.Claimant, "Streamed event should match claim")
+	assert.Equal(t, "2001:db8::1", event.IP, "Streamed event should match claim")
+}
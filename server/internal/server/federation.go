@@ -0,0 +1,458 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// maxFederationSummaryBytes bounds the summaries read from peers
+const maxFederationSummaryBytes = 64 << 20
+
+// FederationOptions configures federation with independent servers, each
+// authoritative for its own disjoint prefixes, that share subnet dominance
+// summaries so any of them can serve the combined universe
+type FederationOptions struct {
+	Name          string           `yaml:"name"`          // Name of this server in combined listings
+	Prefixes      []string         `yaml:"prefixes"`      // IPv6 prefixes this server is authoritative for, disabled if empty
+	Peers         []FederationPeer `yaml:"peers"`         // Servers authoritative for the rest of the universe
+	SummaryPrefix int              `yaml:"summaryPrefix"` // Standard prefix length summaries list subnets at
+	SyncInterval  time.Duration    `yaml:"syncInterval"`  // Time between fetching the summaries of peers
+	Timeout       time.Duration    `yaml:"timeout"`       // Longest a summary fetch may take
+}
+
+// FederationPeer is a server this one shares summaries with
+type FederationPeer struct {
+	Name     string   `yaml:"name"`
+	URL      string   `yaml:"url"`      // Base URL of the peer's API, such as https://spacenet.example.org
+	Prefixes []string `yaml:"prefixes"` // Prefixes the peer is authoritative for, subnets outside them are ignored
+}
+
+// DefaultFederationOptions returns the standard federation options, with federation disabled
+func DefaultFederationOptions() FederationOptions {
+	return FederationOptions{
+		SummaryPrefix: 48,
+		SyncInterval:  time.Minute,
+		Timeout:       10 * time.Second,
+	}
+}
+
+// Enabled reports whether this server is part of a federation
+func (o FederationOptions) Enabled() bool {
+	return len(o.Prefixes) > 0
+}
+
+// Validate checks that the federation options are usable and that no two
+// servers are authoritative for overlapping prefixes
+func (o FederationOptions) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.Name == "" {
+		return errors.New("federation name is required")
+	}
+	if !isStandardPrefix(o.SummaryPrefix) {
+		return fmt.Errorf("federation summaryPrefix must be one of %v, got %d", standardPrefixes, o.SummaryPrefix)
+	}
+	if o.SyncInterval <= 0 || o.Timeout <= 0 {
+		return errors.New("federation syncInterval and timeout must be positive")
+	}
+
+	owners := make(map[*net.IPNet]string)
+	claim := func(server string, prefixes []string) error {
+		if len(prefixes) == 0 {
+			return fmt.Errorf("federation server %q has no prefixes", server)
+		}
+		nets, err := parseFederationPrefixes(prefixes, o.SummaryPrefix)
+		if err != nil {
+			return fmt.Errorf("federation server %q: %w", server, err)
+		}
+		for _, prefix := range nets {
+			for other, owner := range owners {
+				if other.Contains(prefix.IP) || prefix.Contains(other.IP) {
+					return fmt.Errorf("federation prefix %s of %q overlaps %s of %q", prefix, server, other, owner)
+				}
+			}
+			owners[prefix] = server
+		}
+		return nil
+	}
+
+	if err := claim(o.Name, o.Prefixes); err != nil {
+		return err
+	}
+	names := map[string]bool{o.Name: true}
+	for _, peer := range o.Peers {
+		if peer.Name == "" || names[peer.Name] {
+			return fmt.Errorf("federation peer names must be unique and not empty, got %q", peer.Name)
+		}
+		names[peer.Name] = true
+		if u, err := url.Parse(peer.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("federation peer %q url must be an http or https URL, got %q", peer.Name, peer.URL)
+		}
+		if err := claim(peer.Name, peer.Prefixes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFederationPrefixes parses IPv6 prefixes no longer than the summary prefix length
+func parseFederationPrefixes(prefixes []string, summaryPrefix int) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil || ipNet.IP.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 prefix %q", prefix)
+		}
+		if prefixLength(ipNet) > summaryPrefix {
+			return nil, fmt.Errorf("prefix %s is longer than the /%d summaries", prefix, summaryPrefix)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// containsAny reports whether any of the prefixes contains ip
+func containsAny(prefixes []*net.IPNet, ip net.IP) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// federationPeer is a peer and the last summary fetched from it
+type federationPeer struct {
+	FederationPeer
+	prefixes []*net.IPNet
+
+	mutex    sync.RWMutex
+	subnets  []api.SubnetListEntry
+	lastSync time.Time // When a summary was last fetched, zero if never
+	err      error     // Why the last fetch failed, nil if it succeeded
+}
+
+// Federation shares the dominance of this server's prefixes with its peers
+// and keeps the latest summaries of theirs
+type Federation struct {
+	store  Store
+	opts   FederationOptions
+	local  []*net.IPNet
+	peers  []*federationPeer
+	client *http.Client
+
+	stop   chan struct{}
+	done   chan struct{}
+	logger *slog.Logger
+}
+
+// NewFederation creates a federation of this server and its configured peers
+func NewFederation(store Store, opts FederationOptions) (*Federation, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	local, _ := parseFederationPrefixes(opts.Prefixes, opts.SummaryPrefix)
+	f := &Federation{
+		store:  store,
+		opts:   opts,
+		local:  local,
+		client: &http.Client{Timeout: opts.Timeout},
+		logger: componentLogger("federation"),
+	}
+	for _, peer := range opts.Peers {
+		prefixes, _ := parseFederationPrefixes(peer.Prefixes, opts.SummaryPrefix)
+		f.peers = append(f.peers, &federationPeer{FederationPeer: peer, prefixes: prefixes})
+	}
+	return f, nil
+}
+
+// Start fetches the summaries of peers now and then periodically in the background
+func (f *Federation) Start() {
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-f.stop
+		cancel()
+	}()
+
+	go func() {
+		defer close(f.done)
+
+		ticker := time.NewTicker(f.opts.SyncInterval)
+		defer ticker.Stop()
+
+		for {
+			f.Sync(ctx)
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops fetching summaries, abandoning fetches in progress
+func (f *Federation) Stop() {
+	if f.stop == nil {
+		return
+	}
+	close(f.stop)
+	<-f.done
+	f.stop = nil
+}
+
+// Sync fetches the summary of every peer, keeping the previous summary of
+// peers that cannot be reached
+func (f *Federation) Sync(ctx context.Context) {
+	for _, peer := range f.peers {
+		subnets, err := f.fetchSummary(ctx, peer)
+
+		peer.mutex.Lock()
+		peer.err = err
+		if err == nil {
+			peer.subnets = subnets
+			peer.lastSync = time.Now().UTC()
+		}
+		peer.mutex.Unlock()
+
+		if err != nil {
+			f.logger.Warn("Failed to fetch federation summary", "peer", peer.Name, "error", err)
+		}
+	}
+}
+
+// fetchSummary fetches the summary of a peer, keeping only the subnets in the peer's prefixes
+func (f *Federation) fetchSummary(ctx context.Context, peer *federationPeer) ([]api.SubnetListEntry, error) {
+	endpoint := strings.TrimSuffix(peer.URL, "/") + "/api/v1/federation/summary?prefix=" + strconv.Itoa(f.opts.SummaryPrefix)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			f.logger.Debug("Error closing summary response body", "peer", peer.Name, "error", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer returned status %d", resp.StatusCode)
+	}
+
+	var summary api.FederationSummary
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFederationSummaryBytes)).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("invalid summary: %w", err)
+	}
+	if summary.PrefixLength != f.opts.SummaryPrefix {
+		return nil, fmt.Errorf("peer summarized at /%d instead of /%d", summary.PrefixLength, f.opts.SummaryPrefix)
+	}
+
+	// A peer is only trusted with the prefixes it was configured with
+	subnets := make([]api.SubnetListEntry, 0, len(summary.Subnets))
+	ignored := 0
+	for _, entry := range summary.Subnets {
+		ip, ipNet, err := net.ParseCIDR(entry.Subnet)
+		if err != nil || prefixLength(ipNet) != f.opts.SummaryPrefix || !containsAny(peer.prefixes, ip) {
+			ignored++
+			continue
+		}
+		subnets = append(subnets, entry)
+	}
+	if ignored > 0 {
+		f.logger.Warn("Ignored subnets outside a peer's prefixes", "peer", peer.Name, "ignored", ignored)
+	}
+	return subnets, nil
+}
+
+// Summary returns the claimed subnets in this server's prefixes at a standard prefix length
+func (f *Federation) Summary(ctx context.Context, prefixLen int) (*api.FederationSummary, error) {
+	if levels := f.store.Levels(ctx); !slices.Contains(levels, prefixLen) {
+		return nil, fmt.Errorf("prefix must be one of %v", levels)
+	}
+	for _, prefix := range f.local {
+		if prefixLength(prefix) > prefixLen {
+			return nil, fmt.Errorf("prefix must be at least /%d", prefixLength(prefix))
+		}
+	}
+
+	all, _ := f.store.GetAllSubnets(ctx, prefixLen)
+	subnets := make([]api.SubnetListEntry, 0, len(all))
+	for _, entry := range all {
+		if ip, _, err := net.ParseCIDR(entry.Subnet); err == nil && containsAny(f.local, ip) {
+			subnets = append(subnets, entry)
+		}
+	}
+
+	return &api.FederationSummary{
+		Server:       f.opts.Name,
+		Prefixes:     f.opts.Prefixes,
+		PrefixLength: prefixLen,
+		GeneratedAt:  time.Now().UTC(),
+		Subnets:      subnets,
+	}, nil
+}
+
+// Subnets returns the claimed subnets of the whole federation at the summary
+// prefix length, this server's current and its peers' as last fetched
+func (f *Federation) Subnets(ctx context.Context) []api.FederatedSubnet {
+	var subnets []api.FederatedSubnet
+	add := func(server string, entries []api.SubnetListEntry) {
+		for _, entry := range entries {
+			subnets = append(subnets, api.FederatedSubnet{
+				Subnet:     entry.Subnet,
+				Owner:      entry.Owner,
+				Percentage: entry.Percentage,
+				Server:     server,
+			})
+		}
+	}
+
+	if summary, err := f.Summary(ctx, f.opts.SummaryPrefix); err == nil {
+		add(f.opts.Name, summary.Subnets)
+	}
+	for _, peer := range f.peers {
+		peer.mutex.RLock()
+		add(peer.Name, peer.subnets)
+		peer.mutex.RUnlock()
+	}
+
+	sort.Slice(subnets, func(i, j int) bool {
+		return subnets[i].Subnet < subnets[j].Subnet
+	})
+	return subnets
+}
+
+// Peers returns the state of every peer
+func (f *Federation) Peers() []api.FederationPeerStatus {
+	statuses := make([]api.FederationPeerStatus, 0, len(f.peers))
+	for _, peer := range f.peers {
+		peer.mutex.RLock()
+		status := api.FederationPeerStatus{
+			Name:     peer.Name,
+			URL:      peer.URL,
+			Prefixes: peer.Prefixes,
+			Subnets:  len(peer.subnets),
+		}
+		if !peer.lastSync.IsZero() {
+			lastSync := peer.lastSync
+			status.LastSync = &lastSync
+		}
+		if peer.err != nil {
+			status.Error = peer.err.Error()
+		}
+		peer.mutex.RUnlock()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkAuthoritative rejects claims on addresses outside this server's
+// prefixes, naming the peer to claim them at if there is one
+func (f *Federation) checkAuthoritative(subnet *net.IPNet) error {
+	if f == nil {
+		return nil
+	}
+	ones, _ := subnet.Mask.Size()
+	for _, prefix := range f.local {
+		if prefix.Contains(subnet.IP) && ones >= prefixLength(prefix) {
+			return nil
+		}
+	}
+	for _, peer := range f.peers {
+		if containsAny(peer.prefixes, subnet.IP) {
+			return misdirected(fmt.Sprintf("%s belongs to federation peer %s, claim it at %s", subnet.IP, peer.Name, peer.URL))
+		}
+	}
+	return misdirected(fmt.Sprintf("%s is outside the prefixes of this server", subnet.IP))
+}
+
+// checkAuthoritativeIP rejects claims on a single address outside this server's prefixes
+func (f *Federation) checkAuthoritativeIP(ip net.IP) error {
+	return f.checkAuthoritative(&net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+}
+
+// handleGetFederationSummary returns the dominance summary peers fetch from this server
+func (h *HTTPHandler) handleGetFederationSummary(w http.ResponseWriter, r *http.Request) {
+	if h.federation == nil {
+		writeError(w, r, notFound("federation is disabled"))
+		return
+	}
+
+	prefixLen := h.federation.opts.SummaryPrefix
+	if prefixStr := r.URL.Query().Get("prefix"); prefixStr != "" {
+		var err error
+		if prefixLen, err = strconv.Atoi(prefixStr); err != nil {
+			writeError(w, r, badRequest("invalid prefix length"))
+			return
+		}
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	summary, err := h.federation.Summary(ctx, prefixLen)
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// handleGetFederationSubnets returns the claimed subnets of the whole federation
+func (h *HTTPHandler) handleGetFederationSubnets(w http.ResponseWriter, r *http.Request) {
+	if h.federation == nil {
+		writeError(w, r, notFound("federation is disabled"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	stream := newStreamJSON(w)
+	for _, subnet := range h.federation.Subnets(ctx) {
+		stream.element(subnet)
+	}
+	if err := stream.closeArray(); err != nil {
+		h.logger.Error("Error streaming JSON response", "error", err)
+	}
+}
+
+// handleGetFederationPeers returns the peers of this server and when their summaries were fetched
+func (h *HTTPHandler) handleGetFederationPeers(w http.ResponseWriter, r *http.Request) {
+	if h.federation == nil {
+		writeError(w, r, notFound("federation is disabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.federation.Peers()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// federatedHandler creates a handler authoritative for prefixes, federated with peers
+func federatedHandler(t *testing.T, store Store, name string, prefixes []string, peers ...FederationPeer) (*HTTPHandler, *mux.Router) {
+	opts := DefaultFederationOptions()
+	opts.Name = name
+	opts.Prefixes = prefixes
+	opts.Peers = peers
+	federation, err := NewFederation(store, opts)
+	require.NoError(t, err, "Should create federation")
+
+	handler := NewHTTPHandler(store)
+	handler.federation = federation
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	return handler, router
+}
+
+// subnetServers maps federated subnets to the servers authoritative for them
+func subnetServers(subnets []api.FederatedSubnet) map[string]string {
+	servers := make(map[string]string, len(subnets))
+	for _, subnet := range subnets {
+		servers[subnet.Subnet] = subnet.Server
+	}
+	return servers
+}
+
+// TestFederation_Sync tests combining this server's subnets with a peer's summary
+func TestFederation_Sync(t *testing.T) {
+	westStore := NewClaimStore()
+	require.NoError(t, westStore.ProcessClaim(t.Context(), "2001:db8:2000::1", "bob"))
+	// Claimed before the server joined the federation, outside its prefixes
+	require.NoError(t, westStore.ProcessClaim(t.Context(), "2001:db9::1", "mallory"))
+	_, westRouter := federatedHandler(t, westStore, "west", []string{"2001:db8:2000::/36"})
+	west := httptest.NewServer(westRouter)
+	defer west.Close()
+
+	eastStore := NewClaimStore()
+	require.NoError(t, eastStore.ProcessClaim(t.Context(), "2001:db8:1000::1", "alice"))
+	east, eastRouter := federatedHandler(t, eastStore, "east", []string{"2001:db8:1000::/36"},
+		FederationPeer{Name: "west", URL: west.URL, Prefixes: []string{"2001:db8:2000::/36"}})
+
+	peers := east.federation.Peers()
+	require.Len(t, peers, 1)
+	assert.Nil(t, peers[0].LastSync, "Peer should not have been fetched yet")
+
+	east.federation.Sync(context.Background())
+
+	assert.Equal(t, map[string]string{
+		"2001:db8:1000::/48": "east",
+		"2001:db8:2000::/48": "west",
+	}, subnetServers(east.federation.Subnets(t.Context())), "Subnets outside the peer's prefixes should not be listed")
+
+	peers = east.federation.Peers()
+	assert.NotNil(t, peers[0].LastSync)
+	assert.Equal(t, 1, peers[0].Subnets)
+	assert.Empty(t, peers[0].Error)
+
+	rr := httptest.NewRecorder()
+	eastRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/federation/subnets", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var subnets []api.FederatedSubnet
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&subnets), "Should decode combined subnets")
+	assert.Len(t, subnets, 2)
+
+	// An unreachable peer keeps its last summary
+	west.Close()
+	east.federation.Sync(context.Background())
+	peers = east.federation.Peers()
+	assert.NotEmpty(t, peers[0].Error, "Failed fetch should be reported")
+	assert.Len(t, east.federation.Subnets(t.Context()), 2, "Last summary should be kept")
+}
+
+// TestFederation_UntrustedSummary tests that a peer cannot list subnets outside its prefixes
+func TestFederation_UntrustedSummary(t *testing.T) {
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "48", r.URL.Query().Get("prefix"), "Summary should be requested at the summary prefix")
+		_ = json.NewEncoder(w).Encode(api.FederationSummary{
+			Server:       "west",
+			PrefixLength: 48,
+			GeneratedAt:  time.Now(),
+			Subnets: []api.SubnetListEntry{
+				{Subnet: "2001:db8:2000::/48", Owner: "bob", Percentage: 100},
+				{Subnet: "2001:db8:1000::/48", Owner: "mallory", Percentage: 100},
+				{Subnet: "2001:db8:2001::/64", Owner: "mallory", Percentage: 100},
+			},
+		})
+	}))
+	defer peer.Close()
+
+	east, _ := federatedHandler(t, NewClaimStore(), "east", []string{"2001:db8:1000::/36"},
+		FederationPeer{Name: "west", URL: peer.URL, Prefixes: []string{"2001:db8:2000::/36"}})
+	east.federation.Sync(context.Background())
+
+	assert.Equal(t, []api.FederatedSubnet{
+		{Subnet: "2001:db8:2000::/48", Owner: "bob", Percentage: 100, Server: "west"},
+	}, east.federation.Subnets(t.Context()), "Only subnets in the peer's prefixes at the summary prefix should be kept")
+}
+
+// TestFederation_RejectsForeignClaims tests that claims outside this server's prefixes are refused
+func TestFederation_RejectsForeignClaims(t *testing.T) {
+	_, router := federatedHandler(t, NewClaimStore(), "east", []string{"2001:db8:1000::/36"},
+		FederationPeer{Name: "west", URL: "https://west.example.org", Prefixes: []string{"2001:db8:2000::/36"}})
+
+	claim := func(ip string) (int, api.ErrorResponse) {
+		body, _ := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: "0"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/claim/"+ip, bytes.NewReader(body)))
+		var resp api.ErrorResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp
+	}
+
+	code, resp := claim("2001:db8:2000::1")
+	assert.Equal(t, http.StatusMisdirectedRequest, code, "Claims in a peer's prefixes should be refused")
+	assert.Equal(t, "misdirected", resp.Code)
+	assert.Contains(t, resp.Message, "https://west.example.org", "Error should name the peer to claim at")
+
+	code, _ = claim("2001:db9::1")
+	assert.Equal(t, http.StatusMisdirectedRequest, code, "Claims outside the federation should be refused")
+
+	code, _ = claim("2001:db8:1000::1")
+	assert.Equal(t, http.StatusUnprocessableEntity, code, "Claims in this server's prefixes should be verified")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/federation/summary?prefix=32", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Summaries wider than the authoritative prefixes should be refused")
+}
+
+// TestFederation_Disabled tests that federation endpoints are hidden when not federated
+func TestFederation_Disabled(t *testing.T) {
+	handler := NewHTTPHandler(NewClaimStore())
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	for _, path := range []string{"/api/v1/federation/summary", "/api/v1/federation/subnets", "/api/v1/federation/peers"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, "%s should be disabled", path)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+)
+
+// Fortification failures
+var (
+	ErrFortificationDisabled = errors.New("fortification is disabled")
+	ErrFortificationCapped   = errors.New("address is fortified to the maximum level")
+)
+
+// FortificationOptions configures how owners fortify their addresses by
+// spending extra proof of work, raising the difficulty of taking them over
+type FortificationOptions struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxLevel      int           `yaml:"maxLevel"`      // Highest defense level of an address
+	LevelBonus    int           `yaml:"levelBonus"`    // Additional difficulty per defense level
+	DecayInterval time.Duration `yaml:"decayInterval"` // Time for an address to lose a defense level
+}
+
+// DefaultFortificationOptions returns the standard fortification options
+func DefaultFortificationOptions() FortificationOptions {
+	return FortificationOptions{
+		MaxLevel:      4,
+		LevelBonus:    1,
+		DecayInterval: 24 * time.Hour,
+	}
+}
+
+// Validate checks that the fortification options are usable
+func (o FortificationOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.MaxLevel <= 0 || o.LevelBonus <= 0 || o.DecayInterval <= 0 {
+		return fmt.Errorf("fortification maxLevel, levelBonus and decayInterval must be positive")
+	}
+	if o.MaxLevel*o.LevelBonus > 64 {
+		return fmt.Errorf("fortification may add at most 64 to the difficulty, got %d", o.MaxLevel*o.LevelBonus)
+	}
+	return nil
+}
+
+// maxBonus returns the most difficulty fortification can add
+func (o FortificationOptions) maxBonus() int {
+	if !o.Enabled {
+		return 0
+	}
+	return o.MaxLevel * o.LevelBonus
+}
+
+// level returns the defense level of a claim at a time, after decay
+func (o FortificationOptions) level(metadata ClaimMetadata, now time.Time) int {
+	if !o.Enabled || metadata.Fortification <= 0 {
+		return 0
+	}
+	decayed := int(now.Sub(metadata.FortifiedAt) / o.DecayInterval)
+	return max(0, min(metadata.Fortification, o.MaxLevel)-decayed)
+}
+
+// SetFortificationOptions replaces the options used to fortify addresses
+func (cs *ClaimStore) SetFortificationOptions(opts FortificationOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.fortification = opts
+}
+
+// fortificationBonusLocked returns the difficulty an address's defense level
+// adds to taking it over (assumes read lock is held)
+func (cs *ClaimStore) fortificationBonusLocked(ipAddr string, now time.Time) int {
+	return cs.fortification.level(cs.metadata[ipAddr], now) * cs.fortification.LevelBonus
+}
+
+// Fortify raises the defense level of an address held by claimant by one,
+// returning the new level. Levels decay over time, so fortifying also
+// restarts the decay.
+func (cs *ClaimStore) Fortify(ctx context.Context, ipAddr string, claimant string) (int, error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if !cs.fortification.Enabled {
+		return 0, ErrFortificationDisabled
+	}
+	owner, exists := cs.claims[ipAddr]
+	if !exists {
+		return 0, ErrNotClaimed
+	}
+	if owner != claimant {
+		return 0, ErrNotOwner
+	}
+
+	now := time.Now().UTC()
+	metadata := cs.metadata[ipAddr]
+	level := cs.fortification.level(metadata, now)
+	if level >= cs.fortification.MaxLevel {
+		return level, ErrFortificationCapped
+	}
+	metadata.Fortification = level + 1
+	metadata.FortifiedAt = now
+
+	if cs.db != nil {
+		if _, err := cs.db.ExecContext(ctx,
+			"UPDATE claims SET fortification = ?, fortified_at = ?, updated_at = CURRENT_TIMESTAMP WHERE ip_address = ?",
+			metadata.Fortification, metadata.FortifiedAt, ipAddr,
+		); err != nil {
+			cs.logger.Error("Failed to persist fortification", "ip", ipAddr, "claimant", claimant, "error", err)
+			return level, backendError(err)
+		}
+	}
+	if err := cs.journal.append(claimEntry(ipAddr, claimant, metadata)); err != nil {
+		cs.logger.Error("Failed to journal fortification", "ip", ipAddr, "claimant", claimant, "error", err)
+		return level, backendError(err)
+	}
+
+	cs.metadata[ipAddr] = metadata
+	return metadata.Fortification, nil
+}
+
+// handleFortify raises the defense level of an address. The owner proves
+// themselves with a proof of work at the address's current difficulty, the
+// same work a takeover would cost, so each level costs more than the last.
+func (h *HTTPHandler) handleFortify(w http.ResponseWriter, r *http.Request) {
+	ipAddr := mux.Vars(r)["ip"]
+	targetIP := net.ParseIP(ipAddr)
+	if targetIP == nil {
+		writeError(w, r, badRequest("invalid address"))
+		return
+	}
+
+	var fortifyReq api.ClaimRequest
+	if err := h.decodeBody(w, r, &fortifyReq); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name, err := h.names.Normalize(fortifyReq.Name)
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	// Fortification is always bought with proof of work, even when claims are verified by ping
+	pow := &api.ProofOfWork{Target: targetIP, Name: fortifyReq.Name, Nonce: fortifyReq.Nonce}
+	process := func() error {
+		if err := h.store.ValidateProofOfWork(r.Context(), pow); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidProofOfWork, err)
+		}
+		ctx, cancel := h.storeContext(r)
+		defer cancel()
+		_, err := h.store.Fortify(ctx, ipAddr, name)
+		return err
+	}
+
+	if h.claimPool != nil {
+		err = h.claimPool.Submit(r.Context(), process)
+	} else {
+		err = process()
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrFortificationDisabled):
+		writeError(w, r, notFound(err.Error()))
+		return
+	case errors.Is(err, errInvalidProofOfWork):
+		writeError(w, r, unprocessable(err.Error()))
+		return
+	case errors.Is(err, ErrNotClaimed):
+		writeError(w, r, notFound(err.Error()))
+		return
+	case errors.Is(err, ErrNotOwner):
+		writeError(w, r, forbidden(err.Error()))
+		return
+	case errors.Is(err, ErrFortificationCapped):
+		writeError(w, r, conflict(err.Error()))
+		return
+	case errors.Is(err, ErrClaimQueueFull):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, r, unavailable(err.Error()))
+		return
+	case errors.Is(err, ErrClaimPoolStopped):
+		writeError(w, r, unavailable(err.Error()))
+		return
+	default:
+		writeError(w, r, err)
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	claimant, _ := h.store.GetClaim(ctx, ipAddr)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.claimResponse(ctx, ipAddr, claimant)); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
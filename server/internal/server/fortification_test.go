@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFortificationOptions enables fortification with the default levels
+func testFortificationOptions() FortificationOptions {
+	opts := DefaultFortificationOptions()
+	opts.Enabled = true
+	return opts
+}
+
+// TestClaimStore_Fortify tests raising, capping and razing defense levels
+func TestClaimStore_Fortify(t *testing.T) {
+	store := NewClaimStore()
+	_, err := store.Fortify(t.Context(), "2001:db8::1", "alice")
+	assert.ErrorIs(t, err, ErrFortificationDisabled, "Fortification should be disabled by default")
+
+	opts := testFortificationOptions()
+	store.SetFortificationOptions(opts)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	unfortified := store.CalculateDifficulty(t.Context(), "2001:db8::1")
+
+	_, err = store.Fortify(t.Context(), "2001:db8::1", "bob")
+	assert.ErrorIs(t, err, ErrNotOwner, "Only the owner should fortify an address")
+	_, err = store.Fortify(t.Context(), "2001:db8::2", "alice")
+	assert.ErrorIs(t, err, ErrNotClaimed, "Unclaimed addresses cannot be fortified")
+
+	for i := 1; i <= opts.MaxLevel; i++ {
+		level, err := store.Fortify(t.Context(), "2001:db8::1", "alice")
+		require.NoError(t, err, "Owner should fortify an address")
+		assert.Equal(t, i, level, "Each fortification should add a level")
+	}
+	_, err = store.Fortify(t.Context(), "2001:db8::1", "alice")
+	assert.ErrorIs(t, err, ErrFortificationCapped, "Levels should be capped")
+
+	metadata, _ := store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, opts.MaxLevel, metadata.Fortification)
+	assert.Equal(t, unfortified+uint8(opts.MaxLevel*opts.LevelBonus), store.CalculateDifficulty(t.Context(), "2001:db8::1"),
+		"Fortification should add to the difficulty")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	metadata, _ = store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Zero(t, metadata.Fortification, "Takeovers should raze the fortification")
+}
+
+// TestClaimStore_FortifyDecay tests that defense levels decay over time
+func TestClaimStore_FortifyDecay(t *testing.T) {
+	store := NewClaimStore()
+	opts := testFortificationOptions()
+	store.SetFortificationOptions(opts)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	for range 3 {
+		_, err := store.Fortify(t.Context(), "2001:db8::1", "alice")
+		require.NoError(t, err)
+	}
+
+	store.mutex.Lock()
+	metadata := store.metadata["2001:db8::1"]
+	metadata.FortifiedAt = metadata.FortifiedAt.Add(-2*opts.DecayInterval - time.Minute)
+	store.metadata["2001:db8::1"] = metadata
+	store.mutex.Unlock()
+
+	metadata, _ = store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, 1, metadata.Fortification, "A level should be lost every decay interval")
+
+	level, err := store.Fortify(t.Context(), "2001:db8::1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, level, "Fortifying should build on the decayed level")
+
+	subnet, ok := store.CalculateSubnetDifficulty(t.Context(), "2001:db8::/120")
+	require.True(t, ok)
+	assert.Equal(t, store.CalculateDifficulty(t.Context(), "2001:db8::1"), subnet.Addresses[1].Difficulty,
+		"Subnet difficulty should include fortification")
+}
+
+// TestClaimStore_FortifyPersistence tests that defense levels survive restarts
+func TestClaimStore_FortifyPersistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "fortify.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+	store.SetFortificationOptions(testFortificationOptions())
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	_, err = store.Fortify(t.Context(), "2001:db8::1", "alice")
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	reopened.SetFortificationOptions(testFortificationOptions())
+	metadata, _ := reopened.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, 1, metadata.Fortification, "Fortification should be persisted")
+}
+
+// TestHTTPHandler_Fortify tests fortifying addresses through the API
+func TestHTTPHandler_Fortify(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	fortify := func(name, nonce string) *httptest.ResponseRecorder {
+		if nonce == "" {
+			pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::1"), name, store.CalculateDifficulty(t.Context(), "2001:db8::1"), 10000000)
+			require.NoError(t, err, "Should solve proof of work")
+			nonce = pow.Nonce
+		}
+		data, err := json.Marshal(api.ClaimRequest{Name: name, Nonce: nonce})
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/claim/2001:db8::1/fortify", bytes.NewReader(data)))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotFound, fortify("alice", "").Code, "Fortification should be disabled by default")
+
+	opts := testFortificationOptions()
+	opts.MaxLevel = 1
+	store.SetFortificationOptions(opts)
+	assert.Equal(t, http.StatusUnprocessableEntity, fortify("alice", "invalid").Code, "Invalid proofs of work should be rejected")
+	assert.Equal(t, http.StatusForbidden, fortify("bob", "").Code, "Only the owner should fortify an address")
+
+	rr := fortify("alice", "")
+	require.Equal(t, http.StatusOK, rr.Code, "Owner should fortify an address")
+	var resp api.ClaimResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, 1, resp.Fortification, "Response should show the defense level")
+	assert.Equal(t, store.CalculateDifficulty(t.Context(), "2001:db8::1"), resp.Difficulty)
+
+	assert.Equal(t, http.StatusConflict, fortify("alice", "").Code, "Levels should be capped")
+}
@@ -0,0 +1,434 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+)
+
+// maxGameEventTitleLength is the most characters a game event title may have
+const maxGameEventTitleLength = 128
+
+// gameEventCheckInterval is how often the scheduler looks for game events
+// starting or ending
+const gameEventCheckInterval = time.Second
+
+// GameEventOptions configures the game events admins schedule, such as
+// double score in a subnet for the next hour
+type GameEventOptions struct {
+	File          string        `yaml:"file"`          // JSON file of scheduled events, saved on admin changes; memory only if empty
+	MaxMultiplier int           `yaml:"maxMultiplier"` // Highest score multiplier of an event (0 for the default)
+	MaxDuration   time.Duration `yaml:"maxDuration"`   // Longest an event may run (0 for the default)
+}
+
+// DefaultGameEventOptions returns the standard game event options
+func DefaultGameEventOptions() GameEventOptions {
+	return GameEventOptions{
+		MaxMultiplier: 10,
+		MaxDuration:   7 * 24 * time.Hour,
+	}
+}
+
+// Validate checks that the game event options are usable
+func (o GameEventOptions) Validate() error {
+	if o.MaxMultiplier < 0 || o.MaxDuration < 0 {
+		return errors.New("gameEvents maxMultiplier and maxDuration must not be negative")
+	}
+	return nil
+}
+
+// gameEventPublisher publishes game events on the live event feed
+type gameEventPublisher interface {
+	publishEvent(event api.ClaimEvent)
+}
+
+// publishEvent publishes an event on the live event feed
+func (cs *ClaimStore) publishEvent(event api.ClaimEvent) {
+	cs.events.Publish(event)
+}
+
+// GameEvents holds the game events admins scheduled, announcing them on the
+// event feed as they start and end
+type GameEvents struct {
+	opts      GameEventOptions
+	publisher gameEventPublisher // Nil if the store has no event feed
+
+	mutex   sync.RWMutex
+	events  map[string]api.GameEvent // Events not yet ended by ID
+	started map[string]bool          // Events announced as started
+
+	stop   chan struct{}
+	done   chan struct{}
+	logger *slog.Logger
+}
+
+// NewGameEvents creates the game events of a store, loading those not yet
+// ended from the options' file if it exists. Events already running are not
+// announced again.
+func NewGameEvents(store Store, opts GameEventOptions) (*GameEvents, error) {
+	defaults := DefaultGameEventOptions()
+	if opts.MaxMultiplier == 0 {
+		opts.MaxMultiplier = defaults.MaxMultiplier
+	}
+	if opts.MaxDuration == 0 {
+		opts.MaxDuration = defaults.MaxDuration
+	}
+	g := &GameEvents{
+		opts:    opts,
+		events:  make(map[string]api.GameEvent),
+		started: make(map[string]bool),
+		logger:  componentLogger("events"),
+	}
+	if publisher, ok := store.(gameEventPublisher); ok {
+		g.publisher = publisher
+	}
+	if opts.File == "" {
+		return g, nil
+	}
+
+	data, err := os.ReadFile(opts.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read game events: %w", err)
+	}
+	var events []api.GameEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse game events %s: %w", opts.File, err)
+	}
+	now := time.Now()
+	for _, event := range events {
+		if !event.EndsAt.After(now) {
+			continue
+		}
+		if err := g.checkLoaded(&event); err != nil {
+			g.logger.Warn("Ignoring invalid game event", "id", event.ID, "error", err)
+			continue
+		}
+		g.events[event.ID] = event
+		g.started[event.ID] = !event.StartsAt.After(now)
+	}
+	return g, nil
+}
+
+// checkLoaded checks an event loaded from the file, which may have been
+// edited by hand, storing its subnet in canonical form
+func (g *GameEvents) checkLoaded(event *api.GameEvent) error {
+	if event.ID == "" {
+		return errors.New("game events need an id")
+	}
+	subnet, err := api.ParseSubnet(event.Subnet)
+	if err != nil {
+		return err
+	}
+	event.Subnet = api.CanonicalSubnet(subnet.IP, prefixLength(subnet))
+	if event.Multiplier <= 0 || event.Multiplier == 1 || event.Multiplier > float64(g.opts.MaxMultiplier) {
+		return fmt.Errorf("game event multipliers must be above 0, other than 1 and at most %d", g.opts.MaxMultiplier)
+	}
+	if !event.StartsAt.Before(event.EndsAt) {
+		return errors.New("game events must start before they end")
+	}
+	return nil
+}
+
+// Schedule adds a game event, returning it as stored
+func (g *GameEvents) Schedule(req api.GameEventRequest, now time.Time) (api.GameEvent, error) {
+	event, err := g.validate(req, now)
+	if err != nil {
+		return api.GameEvent{}, badRequest(err.Error())
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.events[event.ID] = event
+	if err := g.saveLocked(); err != nil {
+		delete(g.events, event.ID)
+		return api.GameEvent{}, err
+	}
+	return event, nil
+}
+
+// validate checks a request to schedule a game event, returning the event
+func (g *GameEvents) validate(req api.GameEventRequest, now time.Time) (api.GameEvent, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return api.GameEvent{}, errors.New("game events need a title")
+	}
+	if len([]rune(title)) > maxGameEventTitleLength {
+		return api.GameEvent{}, fmt.Errorf("game event titles must be at most %d characters", maxGameEventTitleLength)
+	}
+	if strings.IndexFunc(title, unicode.IsControl) >= 0 {
+		return api.GameEvent{}, errors.New("game event titles must not contain control characters")
+	}
+
+	subnet, err := api.ParseSubnet(req.Subnet)
+	if err != nil {
+		return api.GameEvent{}, fmt.Errorf("invalid subnet %q", req.Subnet)
+	}
+	if req.Multiplier <= 0 || req.Multiplier == 1 || req.Multiplier > float64(g.opts.MaxMultiplier) {
+		return api.GameEvent{}, fmt.Errorf("game event multipliers must be above 0, other than 1 and at most %d", g.opts.MaxMultiplier)
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 || duration > g.opts.MaxDuration {
+		return api.GameEvent{}, fmt.Errorf("game event durations must be positive and at most %s, got %q", g.opts.MaxDuration, req.Duration)
+	}
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if !startsAt.Add(duration).After(now) {
+		return api.GameEvent{}, errors.New("game event would already be over")
+	}
+
+	return api.GameEvent{
+		ID:         newRequestID(),
+		Title:      title,
+		Subnet:     api.CanonicalSubnet(subnet.IP, prefixLength(subnet)),
+		Multiplier: req.Multiplier,
+		StartsAt:   startsAt.UTC(),
+		EndsAt:     startsAt.Add(duration).UTC(),
+	}, nil
+}
+
+// Cancel removes a game event, announcing its end if it started, and
+// reports whether it existed
+func (g *GameEvents) Cancel(id string, now time.Time) (bool, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	event, exists := g.events[id]
+	if !exists {
+		return false, nil
+	}
+	delete(g.events, id)
+	if err := g.saveLocked(); err != nil {
+		g.events[id] = event
+		return false, err
+	}
+	if g.started[id] {
+		g.publishLocked(api.EventTypeEventEnd, event, now)
+	}
+	delete(g.started, id)
+	return true, nil
+}
+
+// List returns every game event not yet ended, soonest first
+func (g *GameEvents) List() []api.GameEvent {
+	events := make([]api.GameEvent, 0)
+	if g == nil {
+		return events
+	}
+
+	g.mutex.RLock()
+	for _, event := range g.events {
+		events = append(events, event)
+	}
+	g.mutex.RUnlock()
+
+	sortGameEvents(events)
+	return events
+}
+
+// Active returns the game events running at a time, soonest started first
+func (g *GameEvents) Active(now time.Time) []api.GameEvent {
+	active := make([]api.GameEvent, 0)
+	for _, event := range g.List() {
+		if gameEventRunning(event, now) {
+			active = append(active, event)
+		}
+	}
+	return active
+}
+
+// gameEventRunning reports whether a game event runs at a time
+func gameEventRunning(event api.GameEvent, now time.Time) bool {
+	return !event.StartsAt.After(now) && event.EndsAt.After(now)
+}
+
+// sortGameEvents sorts game events by start, then by ID
+func sortGameEvents(events []api.GameEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartsAt.Equal(events[j].StartsAt) {
+			return events[i].StartsAt.Before(events[j].StartsAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+}
+
+// Start announces game events as they start and end in the background until
+// Stop is called
+func (g *GameEvents) Start() {
+	g.stop = make(chan struct{})
+	g.done = make(chan struct{})
+
+	go func() {
+		defer close(g.done)
+
+		ticker := time.NewTicker(gameEventCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-g.stop:
+				return
+			case now := <-ticker.C:
+				g.poll(now)
+			}
+		}
+	}()
+}
+
+// Stop stops announcing game events
+func (g *GameEvents) Stop() {
+	if g.stop == nil {
+		return
+	}
+	close(g.stop)
+	<-g.done
+	g.stop = nil
+}
+
+// poll announces the game events started or ended by a time, forgetting the
+// ended ones
+func (g *GameEvents) poll(now time.Time) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	ended := false
+	for id, event := range g.events {
+		if !g.started[id] && gameEventRunning(event, now) {
+			g.started[id] = true
+			g.publishLocked(api.EventTypeEventStart, event, now)
+		}
+		if !event.EndsAt.After(now) {
+			// Events scheduled and over between polls are never announced
+			if g.started[id] {
+				g.publishLocked(api.EventTypeEventEnd, event, now)
+			}
+			delete(g.events, id)
+			delete(g.started, id)
+			ended = true
+		}
+	}
+	if ended {
+		if err := g.saveLocked(); err != nil {
+			g.logger.Error("Failed to save game events", "error", err)
+		}
+	}
+}
+
+// publishLocked announces a game event on the event feed (assumes lock is held)
+func (g *GameEvents) publishLocked(eventType string, event api.GameEvent, now time.Time) {
+	g.logger.Info("Announcing game event", "type", eventType, "id", event.ID, "title", event.Title, "subnet", event.Subnet)
+	if g.publisher == nil {
+		return
+	}
+	g.publisher.publishEvent(api.ClaimEvent{Type: eventType, Timestamp: now.UTC(), GameEvent: &event})
+}
+
+// saveLocked writes the events to the file, if configured (assumes lock is held)
+func (g *GameEvents) saveLocked() error {
+	if g.opts.File == "" {
+		return nil
+	}
+
+	events := make([]api.GameEvent, 0, len(g.events))
+	for _, event := range g.events {
+		events = append(events, event)
+	}
+	sortGameEvents(events)
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves partial events
+	if err := os.WriteFile(g.opts.File+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("saving game events: %w", err)
+	}
+	if err := os.Rename(g.opts.File+".tmp", g.opts.File); err != nil {
+		return fmt.Errorf("saving game events: %w", err)
+	}
+	return nil
+}
+
+// handleGetActiveEvents lists the game events running now
+func (h *HTTPHandler) handleGetActiveEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.gameEvents.Active(time.Now())); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminGetEvents lists the game events scheduled or running
+func (h *HTTPHandler) handleAdminGetEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.gameEvents.List()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminScheduleEvent schedules a game event
+func (h *HTTPHandler) handleAdminScheduleEvent(w http.ResponseWriter, r *http.Request) {
+	if h.gameEvents == nil {
+		writeError(w, r, notFound("game events are disabled"))
+		return
+	}
+
+	var req api.GameEventRequest
+	if err := h.decodeBody(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	event, err := h.gameEvents.Schedule(req, time.Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.logger.Info("Scheduled game event", "id", event.ID, "title", event.Title, "subnet", event.Subnet,
+		"multiplier", event.Multiplier, "starts_at", event.StartsAt, "ends_at", event.EndsAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(event); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// handleAdminCancelEvent cancels a game event, ending it if it is running
+func (h *HTTPHandler) handleAdminCancelEvent(w http.ResponseWriter, r *http.Request) {
+	if h.gameEvents == nil {
+		writeError(w, r, notFound("game events are disabled"))
+		return
+	}
+
+	cancelled, err := h.gameEvents.Cancel(mux.Vars(r)["id"], time.Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !cancelled {
+		writeError(w, r, notFound("game event not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
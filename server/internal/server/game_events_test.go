@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGameEvents_Schedule tests that game events are announced on the event
+// feed as they start and end, and kept in their file until they end
+func TestGameEvents_Schedule(t *testing.T) {
+	store := NewClaimStore()
+	events, unsubscribe := store.SubscribeEvents(t.Context())
+	defer unsubscribe()
+	opts := DefaultGameEventOptions()
+	opts.File = filepath.Join(t.TempDir(), "events.json")
+	gameEvents, err := NewGameEvents(store, opts)
+	require.NoError(t, err)
+
+	// Reloading forgets events already over by the wall clock
+	now := time.Now().UTC().Truncate(time.Second)
+	later := now.Add(time.Hour)
+	doubled, err := gameEvents.Schedule(api.GameEventRequest{
+		Title: " Double score ", Subnet: "2001:db8:aa::1/48", Multiplier: 2, Duration: "1h",
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "Double score", doubled.Title)
+	assert.Equal(t, "2001:db8:aa::/48", doubled.Subnet, "Subnets should be stored in canonical form")
+	assert.Equal(t, now.Add(time.Hour), doubled.EndsAt)
+	upcoming, err := gameEvents.Schedule(api.GameEventRequest{
+		Title: "Triple score", Subnet: "2001:db8:bb::/48", Multiplier: 3, StartsAt: &later, Duration: "30m",
+	}, now)
+	require.NoError(t, err)
+
+	assert.Len(t, gameEvents.List(), 2)
+	assert.Equal(t, []api.GameEvent{doubled}, gameEvents.Active(now), "Events should only be active once they start")
+
+	gameEvents.poll(now)
+	event := <-events
+	assert.Equal(t, api.EventTypeEventStart, event.Type)
+	require.NotNil(t, event.GameEvent)
+	assert.Equal(t, doubled.ID, event.GameEvent.ID)
+
+	reloaded, err := NewGameEvents(store, opts)
+	require.NoError(t, err)
+	assert.Equal(t, gameEvents.List(), reloaded.List(), "Events should be kept across restarts")
+
+	gameEvents.poll(later)
+	types := []string{(<-events).Type, (<-events).Type}
+	assert.ElementsMatch(t, []string{api.EventTypeEventEnd, api.EventTypeEventStart}, types)
+	assert.Equal(t, []api.GameEvent{upcoming}, gameEvents.List(), "Ended events should be forgotten")
+
+	cancelled, err := gameEvents.Cancel(upcoming.ID, later)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Equal(t, api.EventTypeEventEnd, (<-events).Type, "Cancelling a running event should end it")
+	reloaded, err = NewGameEvents(store, opts)
+	require.NoError(t, err)
+	assert.Empty(t, reloaded.List())
+
+	testCases := map[string]api.GameEventRequest{
+		"no title":              {Subnet: "2001:db8::/32", Multiplier: 2, Duration: "1h"},
+		"invalid subnet":        {Title: "Bonus", Subnet: "2001:db8::/129", Multiplier: 2, Duration: "1h"},
+		"multiplier of one":     {Title: "Bonus", Subnet: "2001:db8::/32", Multiplier: 1, Duration: "1h"},
+		"multiplier over limit": {Title: "Bonus", Subnet: "2001:db8::/32", Multiplier: 11, Duration: "1h"},
+		"duration over limit":   {Title: "Bonus", Subnet: "2001:db8::/32", Multiplier: 2, Duration: "200h"},
+		"already over":          {Title: "Bonus", Subnet: "2001:db8::/32", Multiplier: 2, StartsAt: &now, Duration: "1m"},
+	}
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := gameEvents.Schedule(req, later)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestGameEvents_LoadInvalid tests that invalid events in the file are
+// skipped rather than scored
+func TestGameEvents_LoadInvalid(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	events := []api.GameEvent{
+		{ID: "valid", Title: "Bonus", Subnet: "2001:db8:aa::1/48", Multiplier: 2, StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{ID: "subnet", Title: "Bonus", Subnet: "not a subnet", Multiplier: 2, StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{ID: "ipv4", Title: "Bonus", Subnet: "192.0.2.0/24", Multiplier: 2, StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{ID: "multiplier", Title: "Bonus", Subnet: "2001:db8::/32", Multiplier: 1000, StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{Title: "Bonus", Subnet: "2001:db8::/32", Multiplier: 2, StartsAt: now, EndsAt: now.Add(time.Hour)},
+	}
+	data, err := json.Marshal(events)
+	require.NoError(t, err)
+	opts := DefaultGameEventOptions()
+	opts.File = filepath.Join(t.TempDir(), "events.json")
+	require.NoError(t, os.WriteFile(opts.File, data, 0o600))
+
+	gameEvents, err := NewGameEvents(NewClaimStore(), opts)
+	require.NoError(t, err)
+	loaded := gameEvents.List()
+	require.Len(t, loaded, 1, "Invalid events should be skipped")
+	assert.Equal(t, "2001:db8:aa::/48", loaded[0].Subnet, "Loaded subnets should be canonical")
+
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:aa::1", "alice"))
+	engine, err := NewScoringEngine(store, ScoringOptions{Interval: time.Minute, AddressPoints: 1})
+	require.NoError(t, err)
+	engine.events = gameEvents
+	engine.Tick(now)
+	assert.Equal(t, int64(2), engine.Scores()[0].Score)
+}
+
+// TestScoringEngine_GameEvents tests that running game events multiply the
+// points of addresses inside their subnets
+func TestScoringEngine_GameEvents(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:aa::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:bb::1", "bob"))
+
+	gameEvents, err := NewGameEvents(store, DefaultGameEventOptions())
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, err = gameEvents.Schedule(api.GameEventRequest{Title: "Double score", Subnet: "2001:db8:aa::/48", Multiplier: 2, Duration: "1h"}, now)
+	require.NoError(t, err)
+
+	engine, err := NewScoringEngine(store, ScoringOptions{Interval: time.Minute, AddressPoints: 1, SubnetPoints: 10})
+	require.NoError(t, err)
+	engine.events = gameEvents
+	engine.Tick(now)
+	assert.Equal(t, []api.ScoreEntry{
+		{Name: "alice", Score: 2},
+		{Name: "bob", Score: 1},
+	}, engine.Scores(), "Points earned inside the event's subnet should be doubled")
+
+	engine.Tick(now.Add(time.Hour))
+	assert.Equal(t, int64(3), engine.Scores()[0].Score, "Points should not be multiplied once the event ends")
+}
+
+// TestHTTPHandler_GameEvents tests scheduling, listing and cancelling game events
+func TestHTTPHandler_GameEvents(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	gameEvents, err := NewGameEvents(store, DefaultGameEventOptions())
+	require.NoError(t, err)
+	handler.gameEvents = gameEvents
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	request := func(method, path string, body any, token string) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	schedule := api.GameEventRequest{Title: "Double score", Subnet: "2001:db8:aa::/48", Multiplier: 2, Duration: "1h"}
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/v1/admin/events", schedule, "").Code)
+	rr := request(http.MethodPost, "/api/v1/admin/events", schedule, "0123456789abcdef")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var event api.GameEvent
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&event))
+	schedule.Multiplier = 0
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v1/admin/events", schedule, "0123456789abcdef").Code)
+
+	for _, path := range []string{"/api/v1/events/active", "/api/events/active"} {
+		rr = request(http.MethodGet, path, nil, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var active []api.GameEvent
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&active))
+		require.Len(t, active, 1, path)
+		assert.Equal(t, event.ID, active[0].ID)
+	}
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/v1/admin/events/"+event.ID, nil, "0123456789abcdef").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/api/v1/admin/events/"+event.ID, nil, "0123456789abcdef").Code)
+	rr = request(http.MethodGet, "/api/v1/admin/events", nil, "0123456789abcdef")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
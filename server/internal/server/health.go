@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Health check statuses
+const (
+	healthStatusOK          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+// HealthOptions configures the readiness checks behind /health
+type HealthOptions struct {
+	Timeout       time.Duration `yaml:"timeout"`       // Longest a dependency check may take
+	MaxGoroutines int           `yaml:"maxGoroutines"` // Goroutines above which the server is not ready, unchecked if zero
+}
+
+// DefaultHealthOptions returns the standard health check options
+func DefaultHealthOptions() HealthOptions {
+	return HealthOptions{
+		Timeout:       2 * time.Second,
+		MaxGoroutines: 10000,
+	}
+}
+
+// storePinger is implemented by stores with a connection that can be checked
+type storePinger interface {
+	// Ping verifies the store can reach its backing database
+	Ping(ctx context.Context) error
+}
+
+// Ping verifies the SQLite database is reachable, the in-memory store always is
+func (cs *ClaimStore) Ping(ctx context.Context) error {
+	if cs.db == nil {
+		return nil
+	}
+	return cs.db.PingContext(ctx)
+}
+
+// readiness runs every dependency check, reporting whether all of them passed
+func (h *HTTPHandler) readiness(ctx context.Context) (api.HealthResponse, bool) {
+	var checks []api.HealthCheck
+	ready := true
+	check := func(name string, err error) {
+		result := api.HealthCheck{Name: name, OK: err == nil}
+		if err != nil {
+			result.Detail = err.Error()
+			ready = false
+		}
+		checks = append(checks, result)
+	}
+
+	// Not ready until the claims are loaded, nor once shutdown begins
+	var startup *api.StartupStatus
+	if h.lifecycle != nil {
+		var err error
+		startup, err = h.lifecycle.status()
+		check("lifecycle", err)
+	}
+
+	if pinger, ok := h.store.(storePinger); ok {
+		ctx, cancel := context.WithTimeout(ctx, h.health.Timeout)
+		check("database", pinger.Ping(ctx))
+		cancel()
+	}
+
+	if h.claimPool != nil {
+		var err error
+		// Claims are rejected once the queue is full, an unbuffered queue is never full
+		if stats := h.claimPool.Stats(); stats.QueueCapacity > 0 && stats.QueueDepth >= stats.QueueCapacity {
+			err = fmt.Errorf("claim queue is full (%d claims)", stats.QueueDepth)
+		}
+		check("claimQueue", err)
+	}
+
+	if h.health.MaxGoroutines > 0 {
+		var err error
+		if n := runtime.NumGoroutine(); n > h.health.MaxGoroutines {
+			err = fmt.Errorf("%d goroutines, limit is %d", n, h.health.MaxGoroutines)
+		}
+		check("goroutines", err)
+	}
+
+	status := healthStatusOK
+	if !ready {
+		status = healthStatusUnavailable
+	}
+	return api.HealthResponse{Status: status, Checks: checks, Startup: startup}, ready
+}
+
+// handleHealth reports whether the server is ready for traffic, answering
+// 503 and listing the failing checks if a dependency is unhealthy
+func (h *HTTPHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	response, ready := h.readiness(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// handleLiveness reports that the server is running, without checking its
+// dependencies, so a struggling database does not get the process restarted
+func (h *HTTPHandler) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(api.HealthResponse{Status: healthStatusOK}); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+	}
+}
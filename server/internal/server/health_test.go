@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHTTPHandler_Health tests readiness and liveness checks
+func TestHTTPHandler_Health(t *testing.T) {
+	store, err := NewClaimStoreWithSQLite(filepath.Join(t.TempDir(), "health.db"))
+	require.NoError(t, err, "Should create SQLite store")
+	handler := NewHTTPHandler(store)
+	handler.claimPool = NewClaimPool(ClaimPoolOptions{Workers: 1, QueueSize: 1})
+	defer handler.claimPool.Stop()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	get := func(path string) (int, api.HealthResponse) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp api.HealthResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Health responses should be JSON")
+		return rr.Code, resp
+	}
+	failing := func(resp api.HealthResponse) []string {
+		var names []string
+		for _, check := range resp.Checks {
+			if !check.OK {
+				names = append(names, check.Name)
+			}
+		}
+		return names
+	}
+
+	code, resp := get("/health")
+	assert.Equal(t, http.StatusOK, code, "Healthy server should be ready")
+	assert.Equal(t, "ok", resp.Status)
+	assert.Len(t, resp.Checks, 3, "Database, claim queue and goroutines should be checked")
+
+	handler.health.MaxGoroutines = 1
+	code, resp = get("/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "Too many goroutines should fail readiness")
+	assert.Equal(t, []string{"goroutines"}, failing(resp))
+	handler.health.MaxGoroutines = 0
+
+	// Occupy the worker, then fill the queue
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	block := func() error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}
+	var submitting sync.WaitGroup
+	submit := func() {
+		submitting.Add(1)
+		go func() {
+			defer submitting.Done()
+			_ = handler.claimPool.Submit(context.Background(), block)
+		}()
+	}
+	submit()
+	<-started
+	submit()
+	require.Eventually(t, func() bool {
+		stats := handler.claimPool.Stats()
+		return stats.QueueDepth == stats.QueueCapacity
+	}, time.Second, time.Millisecond, "Queue should fill")
+	code, resp = get("/health")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "Full claim queue should fail readiness")
+	assert.Equal(t, []string{"claimQueue"}, failing(resp))
+	close(release)
+	submitting.Wait() // Both claims finish before the pool stops
+
+	require.NoError(t, store.Close())
+	code, resp = get("/health")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "Unreachable database should fail readiness")
+	assert.Contains(t, failing(resp), "database")
+
+	code, resp = get("/health/live")
+	assert.Equal(t, http.StatusOK, code, "Liveness should not check dependencies")
+	assert.Empty(t, resp.Checks)
+}
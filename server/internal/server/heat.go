@@ -0,0 +1,135 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// HeatOptions configures takeover heat, which raises the difficulty of
+// addresses that recently changed hands, damping takeover wars. Each
+// takeover adds one to an address's heat, and heat halves every half-life.
+type HeatOptions struct {
+	Enabled          bool          `yaml:"enabled"`
+	BonusPerTakeover int           `yaml:"bonusPerTakeover"` // Additional difficulty per unit of heat
+	MaxBonus         int           `yaml:"maxBonus"`         // Most difficulty heat can add
+	HalfLife         time.Duration `yaml:"halfLife"`         // Time for an address's heat to halve
+}
+
+// DefaultHeatOptions returns the standard heat options
+func DefaultHeatOptions() HeatOptions {
+	return HeatOptions{
+		BonusPerTakeover: 1,
+		MaxBonus:         6,
+		HalfLife:         2 * time.Hour,
+	}
+}
+
+// Validate checks that the heat options are usable
+func (o HeatOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.BonusPerTakeover <= 0 || o.MaxBonus <= 0 || o.HalfLife <= 0 {
+		return errors.New("heat bonusPerTakeover, maxBonus and halfLife must be positive")
+	}
+	if o.MaxBonus > 64 {
+		return errors.New("heat may add at most 64 to the difficulty")
+	}
+	return nil
+}
+
+// maxBonus returns the most difficulty heat can add
+func (o HeatOptions) maxBonus() int {
+	if !o.Enabled {
+		return 0
+	}
+	return o.MaxBonus
+}
+
+// heatSweepInterval is the number of takeovers between sweeps of cooled addresses
+const heatSweepInterval = 1024
+
+// addressHeat is the heat of an address as of its last takeover
+type addressHeat struct {
+	heat float64
+	at   time.Time
+}
+
+// claimHeat tracks the heat of addresses, guarded by the store's mutex
+type claimHeat struct {
+	opts      HeatOptions
+	addresses map[string]addressHeat
+	takeovers int // Takeovers since the last sweep
+}
+
+// SetHeatOptions replaces the options used to heat contested addresses,
+// forgetting any heat if heat is disabled
+func (cs *ClaimStore) SetHeatOptions(opts HeatOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if !opts.Enabled {
+		cs.heat = nil
+		return
+	}
+	if cs.heat == nil {
+		cs.heat = &claimHeat{addresses: make(map[string]addressHeat)}
+	}
+	cs.heat.opts = opts
+}
+
+// heatBonusLocked returns the difficulty an address's heat adds to taking it
+// over (assumes read lock is held)
+func (cs *ClaimStore) heatBonusLocked(ipAddr string, now time.Time) int {
+	return cs.heat.bonus(ipAddr, now)
+}
+
+// current returns the heat of an address at a time, after cooling
+func (h *claimHeat) current(ipAddr string, now time.Time) float64 {
+	state, exists := h.addresses[ipAddr]
+	if !exists {
+		return 0
+	}
+	halfLives := now.Sub(state.at).Seconds() / h.opts.HalfLife.Seconds()
+	return state.heat * math.Exp2(-max(halfLives, 0))
+}
+
+// bonus returns the difficulty an address's heat adds at a time
+func (h *claimHeat) bonus(ipAddr string, now time.Time) int {
+	if h == nil {
+		return 0
+	}
+	return min(int(math.Round(h.current(ipAddr, now)*float64(h.opts.BonusPerTakeover))), h.opts.MaxBonus)
+}
+
+// record heats an address taken from another claimant
+func (h *claimHeat) record(event api.ClaimEvent) {
+	if h == nil || event.Type != api.EventTypeClaim || event.PreviousClaimant == "" {
+		return
+	}
+	h.addresses[event.IP] = addressHeat{heat: h.current(event.IP, event.Timestamp) + 1, at: event.Timestamp}
+
+	// Addresses that no longer add difficulty are forgotten now and then
+	h.takeovers++
+	if h.takeovers < heatSweepInterval {
+		return
+	}
+	h.takeovers = 0
+	for ipAddr := range h.addresses {
+		if h.bonus(ipAddr, event.Timestamp) == 0 {
+			delete(h.addresses, ipAddr)
+		}
+	}
+}
+
+// clear forgets the heat of every address
+func (h *claimHeat) clear() {
+	if h == nil {
+		return
+	}
+	h.addresses = make(map[string]addressHeat)
+	h.takeovers = 0
+}
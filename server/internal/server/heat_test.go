@@ -0,0 +1,78 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHeatOptions enables heat with the default curve
+func testHeatOptions() HeatOptions {
+	opts := DefaultHeatOptions()
+	opts.Enabled = true
+	return opts
+}
+
+// TestClaimStore_Heat tests that takeovers raise the difficulty of an address until it cools
+func TestClaimStore_Heat(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	base := store.CalculateDifficulty(t.Context(), "2001:db8::1")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	assert.Equal(t, base, store.CalculateDifficulty(t.Context(), "2001:db8::1"), "Heat should be disabled by default")
+
+	store.SetHeatOptions(testHeatOptions())
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::100", "alice"))
+	assert.Zero(t, store.heatBonusLocked("2001:db8::100", time.Now()), "First claims should not heat an address")
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::100", "alice"))
+	assert.Zero(t, store.heatBonusLocked("2001:db8::100", time.Now()), "Claims by the owner should not heat an address")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	assert.Equal(t, base+3, store.CalculateDifficulty(t.Context(), "2001:db8::1"), "Each takeover should add to the difficulty")
+
+	// Heat outlasts releasing the address, but only applies to takeovers
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::1", ""))
+	assert.Equal(t, uint8(DefaultDifficultyParams().Base), store.CalculateDifficulty(t.Context(), "2001:db8::1"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	assert.Equal(t, base+3, store.CalculateDifficulty(t.Context(), "2001:db8::1"))
+
+	halfLife := DefaultHeatOptions().HalfLife
+	assert.Equal(t, 1, store.heatBonusLocked("2001:db8::1", time.Now().Add(2*halfLife)), "Heat should halve every half-life")
+	assert.Zero(t, store.heatBonusLocked("2001:db8::1", time.Now().Add(3*halfLife)))
+
+	for range 10 {
+		require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::1", Claimant: "bob"}, {IP: "2001:db8::1", Claimant: "alice"}}))
+	}
+	assert.Equal(t, DefaultHeatOptions().MaxBonus, store.heatBonusLocked("2001:db8::1", time.Now()), "Heat should be capped")
+
+	difficulties, ok := store.CalculateSubnetDifficulty(t.Context(), "2001:db8::/124")
+	require.True(t, ok)
+	assert.Equal(t, store.CalculateDifficulty(t.Context(), "2001:db8::1"), difficulties.Addresses[1].Difficulty)
+
+	require.NoError(t, store.Reset(t.Context()))
+	assert.Zero(t, store.heatBonusLocked("2001:db8::1", time.Now()), "Resets should forget heat")
+}
+
+// TestClaimHeat_Sweep tests that addresses are forgotten once they cool
+func TestClaimHeat_Sweep(t *testing.T) {
+	store := NewClaimStore()
+	store.SetHeatOptions(testHeatOptions())
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	state := store.heat.addresses["2001:db8::1"]
+	state.at = state.at.Add(-24 * time.Hour)
+	store.heat.addresses["2001:db8::1"] = state
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	for i := range heatSweepInterval {
+		claimant := []string{"bob", "alice"}[i%2]
+		require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", claimant))
+	}
+	assert.NotContains(t, store.heat.addresses, "2001:db8::1", "Cooled addresses should be swept")
+	assert.Contains(t, store.heat.addresses, "2001:db8::2")
+}
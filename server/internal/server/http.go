@@ -1,38 +1,168 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
 	"github.com/gorilla/mux"
 )
 
+const (
+	// detailClaimants is the number of claimants included in detailed subnet statistics
+	detailClaimants = 10
+
+	// defaultLeaderboardLimit is the number of leaderboard entries returned by default
+	defaultLeaderboardLimit = 10
+)
+
+// errInvalidProofOfWork marks claims rejected for an insufficient proof of work
+var errInvalidProofOfWork = errors.New("invalid proof of work")
+
 // HTTPHandler implements HTTP endpoints for claim management
 type HTTPHandler struct {
-	store Store
+	store        Store
+	rateLimiter  *RateLimiter      // Optional per-client limit on claim submissions
+	adminTokens  []string          // Bearer tokens accepted by admin endpoints
+	scoring      *ScoringEngine    // Optional scoring engine, nil if scoring is disabled
+	seasons      *SeasonManager    // Season tracking, nil if not running in a server
+	artifacts    *ArtifactSet      // Artifact placement, nil if artifacts are disabled
+	pprof        bool              // Serve profiling endpoints under /debug/pprof
+	claimPool    *ClaimPool        // Optional worker pool, nil processes claims on the request goroutine
+	icmp         *ICMPVerifier     // Verifies claims by ping instead of proof of work, nil if disabled
+	dnsClaims    *DNSClaimVerifier // Verifies subnet claims through reverse DNS, nil if disabled
+	names        *NamePolicy       // Validates claimant names
+	delegation   *DelegationTokens // Tokens letting bots claim for players, nil if disabled
+	sessions     *WebSessions      // Lighter proofs of work for web clients, nil if disabled
+	health       HealthOptions     // Readiness checks reported by /health
+	lifecycle    *Lifecycle        // Startup and shutdown reported by /health, nil if not running in a server
+	federation   *Federation       // Peers sharing dominance summaries, nil if not federated
+	sectors      *SectorNames      // Names operators gave to subnets, nil if not running in a server
+	gameEvents   *GameEvents       // Game events scheduled by admins, nil if not running in a server
+	namePack     *names.Locale     // Words of generated names, nil for the built-in names
+	supplyLines  SupplyLineOptions // Supply lines rule reported by /config
+	maxBodyBytes int               // Largest claim request body, unlimited if zero
+	storeTimeout time.Duration     // Longest a request may wait on the store, unlimited if zero
+	logger       *slog.Logger
 }
 
 // NewHTTPHandler creates a new HTTP handler with the given store
 func NewHTTPHandler(store Store) *HTTPHandler {
 	return &HTTPHandler{
-		store: store,
+		store:        store,
+		names:        defaultNamePolicy(),
+		health:       DefaultHealthOptions(),
+		maxBodyBytes: DefaultHTTPOptions().MaxBodyBytes,
+		storeTimeout: DefaultHTTPOptions().StoreTimeout,
+		logger:       componentLogger("http"),
 	}
 }
 
+// apiPrefix is the path prefix of the current version of the API
+const apiPrefix = "/api/v1"
+
+// legacyAPIPrefix is the unversioned prefix kept as a deprecated alias of the current version
+const legacyAPIPrefix = "/api"
+
 // RegisterRoutes registers all HTTP routes on the provided router
 func (h *HTTPHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/api/ip/{ip}", h.handleGetClaimByIP).Methods("GET")
-	router.HandleFunc("/api/subnet/{address}/{prefix}", h.handleGetStatsBySubnet).Methods("GET")
-	router.HandleFunc("/api/claim/{ip}", h.handleSubmitClaim).Methods("POST")
+	h.registerAPIRoutes(router.PathPrefix(apiPrefix).Subrouter())
+	router.HandleFunc("/api/openapi.json", h.handleOpenAPI).Methods("GET")
+	router.HandleFunc("/api/docs", h.handleDocs).Methods("GET")
+
+	legacy := router.PathPrefix(legacyAPIPrefix).Subrouter()
+	legacy.Use(deprecatedAPI)
+	h.registerAPIRoutes(legacy)
+
 	router.HandleFunc("/health", h.handleHealth).Methods("GET")
+	router.HandleFunc("/health/ready", h.handleHealth).Methods("GET")
+	router.HandleFunc("/health/live", h.handleLiveness).Methods("GET")
+	if h.pprof {
+		registerPprofRoutes(router)
+	}
+	router.PathPrefix("/").Handler(dashboardHandler()).Methods("GET")
+}
+
+// registerAPIRoutes registers the API endpoints relative to a version prefix
+func (h *HTTPHandler) registerAPIRoutes(router *mux.Router) {
+	router.HandleFunc("/ip/{ip}", h.handleGetClaimByIP).Methods("GET")
+	router.HandleFunc("/ip/{ip}/history", h.handleGetClaimHistory).Methods("GET")
+	router.HandleFunc("/subnet/{address}/{prefix}", h.handleGetStatsBySubnet).Methods("GET")
+	router.HandleFunc("/subnet/{address}/{prefix}/activity", h.handleGetSubnetActivity).Methods("GET")
+	router.HandleFunc("/subnets/{prefix}", h.handleGetAllSubnets).Methods("GET")
+	router.HandleFunc("/owners/{address}/{prefix}/{level}", h.handleGetOwners).Methods("GET")
+	router.HandleFunc("/resolve", h.handleResolveName).Methods("GET")
+	router.HandleFunc("/sectors", h.handleGetSectors).Methods("GET")
+	router.HandleFunc("/difficulty/subnet/{address}/{prefix}", h.handleGetSubnetDifficulty).Methods("GET")
+	router.HandleFunc("/challenge/{ip}", h.handleGetChallenge).Methods("GET")
+	router.HandleFunc("/claim/{ip}", h.limitClaims(h.handleSubmitClaim)).Methods("POST")
+	router.HandleFunc("/claim/{ip}", h.limitClaims(h.handleUnclaim)).Methods("DELETE")
+	router.HandleFunc("/claim/{ip}/fortify", h.limitClaims(h.handleFortify)).Methods("POST")
+	router.HandleFunc("/claims", h.handleSubmitClaims).Methods("POST")
+	router.HandleFunc("/tokens", h.limitClaims(h.handleMintDelegationToken)).Methods("POST")
+	router.HandleFunc("/tokens/self", h.handleGetDelegationToken).Methods("GET")
+	router.HandleFunc("/icmp/challenge/{ip}", h.handleGetICMPChallenge).Methods("GET")
+	router.HandleFunc("/claim-subnet/challenge", h.handleGetSubnetChallenge).Methods("GET")
+	router.HandleFunc("/claim-subnet", h.limitClaims(h.handleClaimSubnet)).Methods("POST")
+	router.HandleFunc("/admin/claims", h.requireAdmin(h.handleAdminGetAllClaims)).Methods("GET")
+	router.HandleFunc("/admin/claims/{ip}", h.requireAdmin(h.handleAdminGetClaim)).Methods("GET")
+	router.HandleFunc("/admin/clients", h.requireAdmin(h.handleAdminGetClients)).Methods("GET")
+	router.HandleFunc("/admin/anomalies", h.requireAdmin(h.handleAdminGetAnomalies)).Methods("GET")
+	router.HandleFunc("/admin/anomalies/claimants/{name}", h.requireAdmin(h.handleAdminReleaseClaimant)).Methods("DELETE")
+	router.HandleFunc("/admin/season/end", h.requireAdmin(h.handleAdminEndSeason)).Methods("POST")
+	router.HandleFunc("/admin/claim-queue", h.requireAdmin(h.handleAdminGetClaimQueue)).Methods("GET")
+	router.HandleFunc("/admin/store", h.requireAdmin(h.handleAdminGetStoreUsage)).Methods("GET")
+	router.HandleFunc("/admin/import/rir", h.requireAdmin(h.handleAdminImportRIR)).Methods("POST")
+	router.HandleFunc("/admin/sectors", h.requireAdmin(h.handleAdminSetSector)).Methods("PUT")
+	router.HandleFunc("/admin/sectors/{address}/{prefix}", h.requireAdmin(h.handleAdminDeleteSector)).Methods("DELETE")
+	router.HandleFunc("/admin/events", h.requireAdmin(h.handleAdminGetEvents)).Methods("GET")
+	router.HandleFunc("/admin/events", h.requireAdmin(h.handleAdminScheduleEvent)).Methods("POST")
+	router.HandleFunc("/admin/events/{id}", h.requireAdmin(h.handleAdminCancelEvent)).Methods("DELETE")
+	router.HandleFunc("/config", h.handleGetConfig).Methods("GET")
+	router.HandleFunc("/name-pack", h.handleGetNamePack).Methods("GET")
+	router.HandleFunc("/season", h.handleGetSeason).Methods("GET")
+	router.HandleFunc("/artifacts", h.handleGetArtifacts).Methods("GET")
+	router.HandleFunc("/stats", h.handleGetStats).Methods("GET")
+	router.HandleFunc("/stats/levels", h.handleGetLevelStats).Methods("GET")
+	router.HandleFunc("/leaderboard", h.handleGetLeaderboard).Methods("GET")
+	router.HandleFunc("/events", h.handleEvents).Methods("GET")
+	router.HandleFunc("/events/active", h.handleGetActiveEvents).Methods("GET")
+	router.HandleFunc("/subscribe", h.handleSubscribe).Methods("GET")
+	router.HandleFunc("/scores", h.handleGetScores).Methods("GET")
+	router.HandleFunc("/scores/{name}/history", h.handleGetScoreHistory).Methods("GET")
+	router.HandleFunc("/federation/summary", h.handleGetFederationSummary).Methods("GET")
+	router.HandleFunc("/federation/subnets", h.handleGetFederationSubnets).Methods("GET")
+	router.HandleFunc("/federation/peers", h.handleGetFederationPeers).Methods("GET")
+}
+
+// deprecatedAPI marks responses from unversioned API paths as deprecated,
+// pointing clients at the same endpoint under the current version
+func deprecatedAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		successor := apiPrefix + strings.TrimPrefix(r.URL.Path, legacyAPIPrefix)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		w.Header().Set("Warning", `299 - "Deprecated API path, use `+successor+`"`)
+		next.ServeHTTP(w, r)
+	})
 }
 
-// handleHealth handles the health check endpoint
-func (h *HTTPHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
+// limitClaims applies the claim rate limit to a handler if one is configured
+func (h *HTTPHandler) limitClaims(next http.HandlerFunc) http.HandlerFunc {
+	if h.rateLimiter == nil {
+		return next
+	}
+	return h.rateLimiter.Middleware(next)
 }
 
 // handleGetClaimByIP returns the claim for a specific IP
@@ -41,31 +171,84 @@ func (h *HTTPHandler) handleGetClaimByIP(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	ipAddr, ok := vars["ip"]
 	if !ok || ipAddr == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, badRequest("missing address"))
 		return
 	}
 
 	// Validate the IP address
 	if net.ParseIP(ipAddr) == nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, badRequest("invalid address"))
 		return
 	}
 
-	claimant, exists := h.store.GetClaim(ipAddr)
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	claimant, exists := h.store.GetClaim(ctx, ipAddr)
 	if !exists {
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, r, notFound("address is not claimed"))
+		return
+	}
+	response := h.claimResponse(ctx, ipAddr, claimant)
+	if checkNotModified(w, r, claimETag(&response)) {
 		return
 	}
-	difficulty := h.store.CalculateDifficulty(ipAddr)
 
 	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// claimResponse describes the claim on an address held by claimant
+func (h *HTTPHandler) claimResponse(ctx context.Context, ipAddr string, claimant string) api.ClaimResponse {
 	response := api.ClaimResponse{
 		Name:       claimant,
-		Difficulty: difficulty,
+		Difficulty: h.store.CalculateDifficulty(ctx, ipAddr),
 	}
+	if metadata, exists := h.store.GetClaimMetadata(ctx, ipAddr); exists {
+		response.ClaimedAt = &metadata.ClaimedAt
+		response.TakeoverCount = metadata.TakeoverCount
+		response.Fortification = metadata.Fortification
+	}
+	return response
+}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+// handleGetChallenge returns the proof of work required to claim an address
+func (h *HTTPHandler) handleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	ipAddr := mux.Vars(r)["ip"]
+	if net.ParseIP(ipAddr) == nil {
+		writeError(w, r, badRequest("invalid address"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	// A claimant flagged by anomaly detection must solve a harder proof of work
+	difficulty := h.store.CalculateDifficulty(ctx, ipAddr)
+	if reviewer, ok := h.store.(anomalyReviewer); ok && r.URL.Query().Has("name") {
+		difficulty = uint8(min(int(difficulty)+reviewer.DifficultyPenalty(r.URL.Query().Get("name")), 255))
+	}
+	challenge := api.NewPoWChallenge(ipAddr, h.store.PoWScheme(ctx), difficulty)
+
+	// Clients that cannot afford the full proof of work ask for a session
+	// challenge, and get the full one if session challenges are disabled
+	if r.URL.Query().Get("type") == api.ChallengeSession && h.sessions != nil {
+		var err error
+		challenge, err = h.sessions.Challenge(challenge, r.URL.Query().Get("session"), clientAddress(r))
+		if err != nil {
+			writeSessionError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(challenge); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -74,25 +257,307 @@ func (h *HTTPHandler) handleGetClaimByIP(w http.ResponseWriter, r *http.Request)
 // handleGetStatsSubnet returns statistics for a specified IPv6 subnet
 func (h *HTTPHandler) handleGetStatsBySubnet(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	var subnetStr string
+	subnet, err := api.ParseSubnet(vars["address"] + "/" + vars["prefix"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid subnet"))
+		return
+	}
+
+	// Include the per-claimant breakdown only when requested
+	topN := 0
+	if detail, _ := strconv.ParseBool(r.URL.Query().Get("detail")); detail {
+		topN = detailClaimants
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	response, ok := h.subnetResponse(ctx, subnet, topN)
+	if !ok {
+		writeError(w, r, badRequest("invalid subnet"))
+		return
+	}
+	if checkNotModified(w, r, subnetETag(response)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// subnetResponse returns the statistics of a subnet with its artifact and
+// sector name, or false if the server does not track its prefix length
+func (h *HTTPHandler) subnetResponse(ctx context.Context, subnet *net.IPNet, topN int) (*SubnetStats, bool) {
+	subnetStr := subnet.String()
+	stats, ok := h.store.GetSubnetStats(ctx, subnetStr, topN)
+	if !ok {
+		return nil, false
+	}
+	if h.artifacts != nil {
+		stats.Artifact = h.artifacts.containsCIDR(subnetStr)
+	}
+	stats.Name = h.sectors.Name(api.CanonicalSubnet(subnet.IP, prefixLength(subnet)))
+	return stats, true
+}
+
+// totalCountHeader reports the number of subnets a paged listing selected
+const totalCountHeader = "X-Total-Count"
+
+// parseSubnetListOptions reads the filters, sort order and page of a subnet
+// listing from its query parameters
+func parseSubnetListOptions(query url.Values) (SubnetListOptions, error) {
+	var opts SubnetListOptions
+	if within := query.Get("within"); within != "" {
+		subnet, err := api.ParseSubnet(within)
+		if err != nil {
+			return opts, err
+		}
+		opts.Within = subnet
+	}
+	if claimed := query.Get("claimed"); claimed != "" {
+		var err error
+		if opts.Owned, err = strconv.ParseBool(claimed); err != nil {
+			return opts, fmt.Errorf("claimed must be true or false")
+		}
+	}
+
+	switch sortKey := SubnetSort(query.Get("sort")); sortKey {
+	case "", SortBySubnet, SortByPercentage, SortByOwner:
+		opts.Sort = sortKey
+	default:
+		return opts, fmt.Errorf("sort must be subnet, percentage or owner")
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, fmt.Errorf("order must be asc or desc")
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		var err error
+		if opts.Offset, err = strconv.Atoi(offset); err != nil || opts.Offset < 0 {
+			return opts, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if opts.Limit, err = strconv.Atoi(limit); err != nil || opts.Limit <= 0 {
+			return opts, fmt.Errorf("limit must be a positive integer")
+		}
+	}
+	return opts, nil
+}
+
+// handleGetAllSubnets returns statistics for the claimed subnets at a prefix length,
+// filtered, sorted and paged by the query parameters
+func (h *HTTPHandler) handleGetAllSubnets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	prefixLen, err := strconv.Atoi(vars["prefix"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid prefix length"))
+		return
+	}
+
+	opts, err := parseSubnetListOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	subnets, total, ok := h.store.ListSubnets(ctx, prefixLen, opts)
+	if !ok {
+		writeError(w, r, badRequest("unsupported prefix length"))
+		return
+	}
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+
+	// Listings of a whole level can be megabytes, so stream them
+	stream := newStreamJSON(w)
+	for _, subnet := range subnets {
+		if h.artifacts != nil {
+			subnet.Artifact = h.artifacts.containsCIDR(subnet.Subnet)
+		}
+		subnet.Name = h.sectors.Name(subnet.Subnet)
+		stream.element(subnet)
+	}
+	if err := stream.closeArray(); err != nil {
+		h.logger.Error("Error streaming JSON response", "error", err)
+	}
+}
+
+// handleGetOwners returns how many subnets of a level within a parent subnet
+// each claimant owns
+func (h *HTTPHandler) handleGetOwners(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	parent, err := api.ParseSubnet(vars["address"] + "/" + vars["prefix"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid subnet"))
+		return
+	}
+	level, err := strconv.Atoi(vars["level"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid prefix length"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	summary, ok := h.store.SummarizeOwners(ctx, parent, level)
+	if !ok {
+		writeError(w, r, badRequest("level must be a tracked prefix length no shorter than the subnet's"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetStats returns global game statistics
+func (h *HTTPHandler) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.store.GetStats(ctx)); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetLevelStats returns the distribution of dominance at each level
+func (h *HTTPHandler) handleGetLevelStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.store.GetLevelStats(ctx)); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetLeaderboard returns claimants ranked by addresses held
+func (h *HTTPHandler) handleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit := defaultLeaderboardLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			writeError(w, r, badRequest("limit must be a positive integer"))
+			return
+		}
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.store.GetLeaderboard(ctx, limit)); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
 
-	address := vars["address"]
-	prefix := vars["prefix"]
-	subnetStr = address + "/" + prefix
+// handleGetScores returns all players ranked by score
+func (h *HTTPHandler) handleGetScores(w http.ResponseWriter, r *http.Request) {
+	if h.scoring == nil {
+		writeError(w, r, notFound("scoring is disabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.scoring.Scores()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetScoreHistory returns the score history of a player
+func (h *HTTPHandler) handleGetScoreHistory(w http.ResponseWriter, r *http.Request) {
+	if h.scoring == nil {
+		writeError(w, r, notFound("scoring is disabled"))
+		return
+	}
 
-	// Get subnet statistics
-	stats, ok := h.store.GetSubnetStats(subnetStr)
+	history, ok := h.scoring.History(mux.Vars(r)["name"])
 	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, notFound("player has no score history"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleResolveName returns the claimed or granted subnets with a generated name
+func (h *HTTPHandler) handleResolveName(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		writeError(w, r, badRequest("missing name"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	// Sector names take precedence over generated names
+	subnets := append(h.sectors.Resolve(name), h.store.ResolveName(ctx, name)...)
+	if len(subnets) == 0 {
+		writeError(w, r, notFound("no claimed subnet has that name"))
 		return
 	}
 
-	// Convert to response format
-	response := stats
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(api.ResolveResponse{Name: name, Subnets: subnets}); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGetSubnetDifficulty returns the required difficulty for every address in a subnet
+func (h *HTTPHandler) handleGetSubnetDifficulty(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subnet, err := api.ParseSubnet(vars["address"] + "/" + vars["prefix"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid subnet"))
+		return
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	response, ok := h.store.CalculateSubnetDifficulty(ctx, subnet.String())
+	if !ok {
+		writeError(w, r, badRequest("invalid subnet"))
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+		h.logger.Error("Error encoding JSON response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -104,27 +569,73 @@ func (h *HTTPHandler) handleSubmitClaim(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	ipAddr, ok := vars["ip"]
 	if !ok || ipAddr == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, badRequest("missing address"))
 		return
 	}
 
 	// Validate IP address
 	targetIP := net.ParseIP(ipAddr)
 	if targetIP == nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, badRequest("invalid address"))
+		return
+	}
+	if err := h.federation.checkAuthoritativeIP(targetIP); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Parse JSON request body
 	var claimReq api.ClaimRequest
-	if err := json.NewDecoder(r.Body).Decode(&claimReq); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if err := h.decodeBody(w, r, &claimReq); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Validate claimant name
-	if len(claimReq.Name) == 0 || len(claimReq.Name) > 24 {
-		w.WriteHeader(http.StatusBadRequest)
+	// A bot claiming with a delegation token claims for the token's issuer
+	var delegated *delegationToken
+	if secret, ok := bearerToken(r); ok && h.delegation != nil {
+		token, err := h.delegation.reserve(secret, targetIP)
+		if err != nil {
+			writeDelegationError(w, r, err)
+			return
+		}
+		delegated = token
+		if claimReq.Name == "" {
+			claimReq.Name = token.claimant
+		}
+	}
+
+	// A web client claiming with a session spends one of the session's claims
+	var session *challengeSession
+	if claimReq.Session != "" {
+		if h.sessions == nil || delegated != nil {
+			if delegated != nil {
+				h.delegation.release(delegated, false)
+			}
+			writeError(w, r, badRequest("session challenges are disabled, or cannot be combined with delegation tokens"))
+			return
+		}
+		reserved, err := h.sessions.reserve(claimReq.Session)
+		if err != nil {
+			writeSessionError(w, r, err)
+			return
+		}
+		session = reserved
+	}
+
+	// Validate claimant name, the proof of work covers the name as submitted
+	name, err := h.names.Normalize(claimReq.Name)
+	if err == nil && delegated != nil && name != delegated.claimant {
+		err = errDelegationTokenName
+	}
+	if err != nil {
+		if delegated != nil {
+			h.delegation.release(delegated, false)
+		}
+		if session != nil {
+			h.sessions.release(session, false)
+		}
+		writeError(w, r, badRequest(err.Error()))
 		return
 	}
 
@@ -135,16 +646,68 @@ func (h *HTTPHandler) handleSubmitClaim(w http.ResponseWriter, r *http.Request)
 		Nonce:  claimReq.Nonce,
 	}
 
-	// Validate proof of work
-	if err := h.store.ValidateProofOfWork(pow); err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		return
+	// Verify the claim and process it, on the worker pool if there is one
+	ctx := r.Context()
+	channel := claimChannelHTTP
+	if session != nil {
+		channel = claimChannelSession
+	}
+	process := func() error {
+		_, verify := tracer.Start(ctx, "claim.verify")
+		var err error
+		switch {
+		case h.icmp != nil:
+			err = h.icmp.Verify(ctx, targetIP, name)
+		case session != nil:
+			if err = h.sessions.validate(ctx, h.store, pow); err != nil {
+				err = fmt.Errorf("%w: %v", errInvalidProofOfWork, err)
+			}
+		default:
+			if err = h.store.ValidateProofOfWork(ctx, pow); err != nil {
+				err = fmt.Errorf("%w: %v", errInvalidProofOfWork, err)
+			}
+		}
+		endSpan(verify, err)
+		if err != nil {
+			return err
+		}
+		storeCtx, cancel := h.storeContext(r)
+		defer cancel()
+		return h.store.ProcessClaim(withClaimSource(storeCtx, httpClaimSource(r, channel)), ipAddr, name)
 	}
 
-	// Process the claim
-	err := h.store.ProcessClaim(ipAddr, claimReq.Name)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if h.claimPool != nil {
+		err = h.claimPool.Submit(ctx, process)
+	} else {
+		err = process()
+	}
+	if delegated != nil {
+		h.delegation.release(delegated, err == nil)
+	}
+	if session != nil {
+		h.sessions.release(session, err == nil)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errInvalidProofOfWork), errors.Is(err, errClaimNotVerified):
+		writeError(w, r, unprocessable(err.Error()))
+		return
+	case errors.Is(err, ErrNameConfusable):
+		writeError(w, r, conflict(err.Error()))
+		return
+	case errors.Is(err, ErrClaimQueueFull):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, r, unavailable(err.Error()))
+		return
+	case errors.Is(err, ErrClaimPoolStopped):
+		writeError(w, r, unavailable(err.Error()))
+		return
+	case errors.Is(err, ErrStoreFull):
+		writeError(w, r, unavailable(err.Error()))
+		return
+	default:
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPOptions bounds how long clients may take and how much they may send,
+// so slow clients and oversized payloads cannot tie up the API
+type HTTPOptions struct {
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"` // Longest time to read request headers
+	ReadTimeout       time.Duration `yaml:"readTimeout"`       // Longest time to read a whole request, including its body
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`      // Longest time to write a response, event streams are exempt
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`       // Longest a keep-alive connection may wait for its next request
+	MaxBodyBytes      int           `yaml:"maxBodyBytes"`      // Largest claim request body, must fit a full batch
+	StoreTimeout      time.Duration `yaml:"storeTimeout"`      // Longest a request may wait on the store, event streams are exempt
+	DrainDelay        time.Duration `yaml:"drainDelay"`        // Time between failing readiness and closing the listener on shutdown
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout"`   // Longest shutdown waits for requests in flight to finish
+}
+
+// DefaultHTTPOptions returns the standard HTTP server limits
+func DefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxBodyBytes:      256 << 10,
+		StoreTimeout:      5 * time.Second,
+		ShutdownTimeout:   5 * time.Second,
+	}
+}
+
+// Validate checks that the HTTP options are usable; zero disables a limit
+func (o HTTPOptions) Validate() error {
+	if o.ReadHeaderTimeout < 0 || o.ReadTimeout < 0 || o.WriteTimeout < 0 || o.IdleTimeout < 0 || o.MaxBodyBytes < 0 || o.StoreTimeout < 0 ||
+		o.DrainDelay < 0 || o.ShutdownTimeout < 0 {
+		return errors.New("http timeouts, drainDelay and maxBodyBytes must not be negative")
+	}
+	return nil
+}
+
+// decodeBody decodes a JSON request body no larger than the handler's limit
+func (h *HTTPHandler) decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	body := r.Body
+	if h.maxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, int64(h.maxBodyBytes))
+	}
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		var tooLong *http.MaxBytesError
+		if errors.As(err, &tooLong) {
+			return tooLarge(fmt.Sprintf("request body is larger than %d bytes", tooLong.Limit))
+		}
+		return badRequest("invalid request body")
+	}
+	return nil
+}
+
+// storeContext returns the context of a request bounded by the store timeout,
+// so a slow store fails the request instead of holding it open
+func (h *HTTPHandler) storeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if h.storeTimeout > 0 {
+		return context.WithTimeout(r.Context(), h.storeTimeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
+// disableWriteTimeout lifts the server's write timeout for a long-lived response
+func disableWriteTimeout(w http.ResponseWriter) {
+	// Fails only if the writer cannot reach the connection, as in tests
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHTTPHandler_MaxBodyBytes tests that oversized claim bodies are refused
+func TestHTTPHandler_MaxBodyBytes(t *testing.T) {
+	handler := NewHTTPHandler(NewClaimStore())
+	handler.maxBodyBytes = 128
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	post := func(path string, body []byte) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+		return rr
+	}
+
+	small, _ := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: "0"})
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/api/v1/claim/2001:db8::1", small).Code,
+		"Bodies under the limit should be read")
+
+	large, _ := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: strings.Repeat("0", 256)})
+	rr := post("/api/v1/claim/2001:db8::1", large)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, "Bodies over the limit should be refused")
+	var resp api.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Errors should have a JSON body")
+	assert.Equal(t, "too_large", resp.Code)
+
+	items := make([]api.BatchClaimItem, 10)
+	for i := range items {
+		items[i] = api.BatchClaimItem{IP: fmt.Sprintf("2001:db8::%x", i), Name: "alice", Nonce: "0"}
+	}
+	batch, _ := json.Marshal(api.BatchClaimRequest{Claims: items})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/api/v1/claims", batch).Code,
+		"Batches over the limit should be refused")
+}
+
+// stalledStore never finishes a claim before its context is done
+type stalledStore struct {
+	*ClaimStore
+}
+
+func (s *stalledStore) ProcessClaim(ctx context.Context, ipAddr string, claimant string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestHTTPHandler_StoreTimeout tests that claims are refused once the store outlives the store timeout
+func TestHTTPHandler_StoreTimeout(t *testing.T) {
+	store := &stalledStore{ClaimStore: NewClaimStore()}
+	handler := NewHTTPHandler(store)
+	handler.storeTimeout = 50 * time.Millisecond
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	const ip = "2001:db8::1"
+	start := time.Now()
+	resp := makeHTTPClaimRequest(t, srv.URL, ip, "alice", store.CalculateDifficulty(t.Context(), ip))
+	defer resp.Body.Close()
+	assert.Less(t, time.Since(start), 5*time.Second, "Claims should not wait on the store past the timeout")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "Timed out claims should be retryable")
+
+	var errResp api.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "unavailable", errResp.Code)
+}
+
+// TestServer_WriteTimeout tests that event streams outlive the write timeout of other responses
+func TestServer_WriteTimeout(t *testing.T) {
+	opts := DefaultHTTPOptions()
+	opts.WriteTimeout = 100 * time.Millisecond
+	server := NewServerWithOptions(ServerOptions{HTTPPort: 0, HTTP: opts})
+	require.NoError(t, server.Start(), "Server should start successfully")
+	defer server.Stop()
+
+	httpPort, err := server.WaitForHTTPPort(5 * time.Second)
+	require.NoError(t, err, "HTTP port should be assigned within timeout")
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/api/v1/events", httpPort))
+	require.NoError(t, err, "Event stream request should succeed")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}()
+
+	time.Sleep(3 * opts.WriteTimeout)
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err, "Stream should stay open past the write timeout")
+		if strings.HasPrefix(line, "data: ") {
+			assert.Contains(t, line, "2001:db8::1")
+			return
+		}
+	}
+}
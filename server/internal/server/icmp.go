@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// errClaimNotVerified marks claims whose address failed ICMP verification
+var errClaimNotVerified = errors.New("address did not answer the ICMP challenge")
+
+// ICMPOptions configures ping-based claim verification. When enabled, claims
+// need no proof of work; instead the server pings the claimed address and only
+// accepts the claim if the echo reply carries the claimant's token, proving
+// they control the host at that address.
+type ICMPOptions struct {
+	Enabled    bool          `yaml:"enabled"`
+	Timeout    time.Duration `yaml:"timeout"`    // How long to wait for an echo reply
+	Secret     string        `yaml:"secret"`     // Token signing secret, random per run if empty
+	Privileged bool          `yaml:"privileged"` // Use raw sockets instead of unprivileged ping sockets
+}
+
+// DefaultICMPOptions returns the standard ICMP verification options
+func DefaultICMPOptions() ICMPOptions {
+	return ICMPOptions{Timeout: 2 * time.Second}
+}
+
+// pinger sends an ICMPv6 echo request and returns the payload of the reply
+type pinger interface {
+	ping(ctx context.Context, ip net.IP, payload []byte) ([]byte, error)
+}
+
+// ICMPVerifier verifies claims by pinging the claimed address
+type ICMPVerifier struct {
+	pinger  pinger
+	key     []byte
+	timeout time.Duration
+}
+
+// NewICMPVerifier creates a verifier using ICMPv6 echo requests
+func NewICMPVerifier(opts ICMPOptions) *ICMPVerifier {
+	key := []byte(opts.Secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("failed to generate ICMP token secret: " + err.Error())
+		}
+	}
+
+	return &ICMPVerifier{
+		pinger:  &icmpPinger{privileged: opts.Privileged},
+		key:     key,
+		timeout: opts.Timeout,
+	}
+}
+
+// Token returns the token a claimant must include in echo replies from an address
+func (v *ICMPVerifier) Token(ip net.IP, name string) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write(ip.To16())
+	mac.Write([]byte(name))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Verify pings an address and checks that the reply carries the claimant's token
+func (v *ICMPVerifier) Verify(ctx context.Context, ip net.IP, name string) error {
+	if v.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.timeout)
+		defer cancel()
+	}
+
+	// A fresh challenge stops replies being replayed from earlier pings
+	challenge := make([]byte, 16)
+	if _, err := rand.Read(challenge); err != nil {
+		return err
+	}
+
+	reply, err := v.pinger.ping(ctx, ip, challenge)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errClaimNotVerified, err)
+	}
+	if !bytes.Contains(reply, challenge) || !bytes.Contains(reply, []byte(v.Token(ip, name))) {
+		return fmt.Errorf("%w: reply is missing the challenge or token", errClaimNotVerified)
+	}
+
+	return nil
+}
+
+// icmpPinger pings addresses over ICMPv6 sockets
+type icmpPinger struct {
+	privileged bool
+}
+
+// ping sends an echo request and waits for the matching reply until the context ends
+func (p *icmpPinger) ping(ctx context.Context, ip net.IP, payload []byte) ([]byte, error) {
+	network := "udp6" // Unprivileged ping socket, see net.ipv4.ping_group_range
+	if p.privileged {
+		network = "ip6:ipv6-icmp"
+	}
+
+	conn, err := icmp.ListenPacket(network, "::")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	seq, err := rand.Int(rand.Reader, big.NewInt(1<<16))
+	if err != nil {
+		return nil, err
+	}
+	request := icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Body: &icmp.Echo{ID: 0, Seq: int(seq.Int64()), Data: payload}, // Ping sockets set the ID
+	}
+	msg, err := request.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if p.privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+	if _, err := conn.WriteTo(msg, dst); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, src, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var srcIP net.IP
+		switch addr := src.(type) {
+		case *net.UDPAddr:
+			srcIP = addr.IP
+		case *net.IPAddr:
+			srcIP = addr.IP
+		}
+		if !srcIP.Equal(ip) {
+			continue
+		}
+
+		reply, err := icmp.ParseMessage(ipv6.ICMPTypeEchoReply.Protocol(), buf[:n])
+		if err != nil || reply.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.Seq == request.Body.(*icmp.Echo).Seq {
+			return echo.Data, nil
+		}
+	}
+}
+
+// handleGetICMPChallenge returns the token a claimant must include in echo replies
+func (h *HTTPHandler) handleGetICMPChallenge(w http.ResponseWriter, r *http.Request) {
+	if h.icmp == nil {
+		writeError(w, r, notFound("ping verification is disabled"))
+		return
+	}
+
+	ip := net.ParseIP(mux.Vars(r)["ip"])
+	if ip == nil {
+		writeError(w, r, badRequest("invalid address"))
+		return
+	}
+	name, err := h.names.Normalize(r.URL.Query().Get("name"))
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(api.ICMPChallenge{
+		Address: ip.String(),
+		Name:    name,
+		Token:   h.icmp.Token(ip, name),
+	}); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// responderPinger simulates hosts that echo the challenge followed by a fixed token
+type responderPinger struct {
+	tokens map[string]string // Address to the token its host replies with
+}
+
+func (p *responderPinger) ping(ctx context.Context, ip net.IP, payload []byte) ([]byte, error) {
+	token, exists := p.tokens[ip.String()]
+	if !exists {
+		return nil, errors.New("no reply")
+	}
+	return append(append([]byte(nil), payload...), token...), nil
+}
+
+// TestICMPVerifier_Verify tests that only hosts replying with the claimant's token are verified
+func TestICMPVerifier_Verify(t *testing.T) {
+	verifier := NewICMPVerifier(ICMPOptions{Enabled: true, Secret: "test-secret"})
+	ip := net.ParseIP("2001:db8::1")
+	token := verifier.Token(ip, "alice")
+
+	assert.Equal(t, token, NewICMPVerifier(ICMPOptions{Secret: "test-secret"}).Token(ip, "alice"),
+		"Tokens should be stable for the same secret")
+	assert.NotEqual(t, token, verifier.Token(ip, "bob"), "Tokens should differ per claimant")
+
+	verifier.pinger = &responderPinger{tokens: map[string]string{"2001:db8::1": token}}
+	assert.NoError(t, verifier.Verify(context.Background(), ip, "alice"), "Host replying with the token should be verified")
+	assert.ErrorIs(t, verifier.Verify(context.Background(), ip, "bob"), errClaimNotVerified,
+		"Reply with another claimant's token should be rejected")
+	assert.ErrorIs(t, verifier.Verify(context.Background(), net.ParseIP("2001:db8::2"), "alice"), errClaimNotVerified,
+		"Unreachable host should be rejected")
+}
+
+// TestHTTPHandler_ICMPClaim tests claiming with ICMP verification instead of proof of work
+func TestHTTPHandler_ICMPClaim(t *testing.T) {
+	store := NewClaimStore()
+	verifier := NewICMPVerifier(ICMPOptions{Enabled: true, Secret: "test-secret"})
+	handler := NewHTTPHandler(store)
+	handler.icmp = verifier
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/icmp/challenge/2001:db8::1?name=alice", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Challenge should be issued")
+
+	var challenge api.ICMPChallenge
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&challenge))
+	verifier.pinger = &responderPinger{tokens: map[string]string{"2001:db8::1": challenge.Token}}
+
+	claim := func(ip string, name string) int {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"name":"` + name + `"}`)
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/claim/"+ip, body))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, claim("2001:db8::2", "alice"), "Unverified address should be rejected")
+	assert.Equal(t, http.StatusUnprocessableEntity, claim("2001:db8::1", "bob"), "Claimant without the token should be rejected")
+	assert.Equal(t, http.StatusCreated, claim("2001:db8::1", "alice"), "Verified claim should need no proof of work")
+
+	claimant, _ := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.Equal(t, "alice", claimant, "Verified claim should be stored")
+}
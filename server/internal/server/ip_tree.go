@@ -1,16 +1,31 @@
 package server
 
 import (
+	"bytes"
+	"cmp"
+	"log/slog"
 	"math/big"
 	"net"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/bjia56/spacenet/server/api"
 )
 
 // IPTree represents a hierarchical structure for managing IPv6 address claims
 // It organizes claims by subnet hierarchy for efficient lookups
 type IPTree struct {
-	mu   sync.RWMutex
-	root *IPNode
+	mu        sync.RWMutex
+	root      *IPNode
+	prefixes  []int                   // Prefix lengths of the subnets tracked, widest first
+	claimed   map[int]*bloomFilter    // Per tracked prefix, filters out subnets that never had a claim
+	stats     *statsCache             // Recently computed subnet statistics, dropped on writes
+	dominance map[int]*levelDominance // Per tracked prefix, tally of subnets by dominance
+	pruned    int                     // Nodes pruned since the tree was last compacted
+	removed   uint64                  // Nodes pruned since the tree was created
+	logger    *slog.Logger
 	// No longer stores its own claims map - uses external map
 }
 
@@ -31,24 +46,108 @@ type IPNode struct {
 	// Map of claimants to their claimed address count in this subnet
 	claimants map[string]*big.Int
 
-	// Dominant claimant in this subnet (with highest percentage)
-	dominantClaimant string
-
-	// Percentage of subnet owned by dominant claimant (0-100)
-	dominantPercentage float64
+	// Addresses claimed by the claimant with the most in this subnet
+	dominantCount int64
 
 	// Child nodes (more specific subnets)
 	children map[string]*IPNode
 }
 
-// Import the shared SubnetStats type from the api package
+// standardPrefixes are the subnet sizes tracked by default, and the sizes a
+// game may choose its levels from
+var standardPrefixes = []int{16, 32, 48, 64, 80, 96, 112, 128}
 
-// NewIPTree creates a new IP tree
+// isStandardPrefix reports whether a prefix length is one of the standard sizes
+func isStandardPrefix(prefixLen int) bool {
+	return slices.Contains(standardPrefixes, prefixLen)
+}
+
+// NewIPTree creates a new IP tree tracking the standard prefixes
 func NewIPTree() *IPTree {
-	// Create root node for the entire IPv6 space
+	return &IPTree{
+		root:      newRootNode(),
+		prefixes:  standardPrefixes,
+		claimed:   newSubnetFilters(standardPrefixes),
+		stats:     newStatsCache(statsCacheSize),
+		dominance: newLevelDominance(standardPrefixes),
+		logger:    componentLogger("tree"),
+	}
+}
+
+// levels returns the prefix lengths tracked by the tree, widest first
+func (t *IPTree) levels() []int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return slices.Clone(t.prefixes)
+}
+
+// isLevel reports whether a prefix length is tracked by the tree
+func (t *IPTree) isLevel(prefixLen int) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return slices.Contains(t.prefixes, prefixLen)
+}
+
+// setLevels empties the tree and tracks the given prefix lengths from then on
+func (t *IPTree) setLevels(prefixes []int) {
+	t.mu.Lock()
+	t.prefixes = slices.Clone(prefixes)
+	t.mu.Unlock()
+
+	t.reset()
+}
+
+// reset removes every claim from the tree
+func (t *IPTree) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.root = newRootNode()
+	t.claimed = newSubnetFilters(t.prefixes)
+	t.dominance = newLevelDominance(t.prefixes)
+	t.stats.clear()
+	t.pruned = 0
+}
+
+// newSubnetFilters creates an empty subnet filter for each tracked prefix
+func newSubnetFilters(prefixes []int) map[int]*bloomFilter {
+	filters := make(map[int]*bloomFilter, len(prefixes))
+	for _, prefixLen := range prefixes {
+		filters[prefixLen] = newBloomFilter(bloomInitialCapacity)
+	}
+	return filters
+}
+
+// markClaimedLocked records that a subnet has a node, growing the filter of its
+// prefix length when it fills up (assumes lock is held)
+func (t *IPTree) markClaimedLocked(subnet *net.IPNet, prefixLen int) {
+	filter := t.claimed[prefixLen]
+	// Subnets pruned and claimed again are already in the filter
+	if filter.mayContain(subnet.IP.To16()) {
+		return
+	}
+	filter.add(subnet.IP.To16())
+	if !filter.full() {
+		return
+	}
+
+	// Rebuild at double the capacity to keep the false positive rate down
+	grown := newBloomFilter(filter.capacity * 2)
+	for _, node := range t.root.children {
+		if node.prefixLen == prefixLen {
+			grown.add(node.subnet.IP.To16())
+		}
+	}
+	t.claimed[prefixLen] = grown
+}
+
+// newRootNode creates an empty root node for the entire IPv6 space
+func newRootNode() *IPNode {
 	_, rootNet, _ := net.ParseCIDR("::/0")
 
-	root := &IPNode{
+	return &IPNode{
 		subnet:         rootNet,
 		prefixLen:      0,
 		claimedCount:   big.NewInt(0),
@@ -56,10 +155,6 @@ func NewIPTree() *IPTree {
 		claimants:      make(map[string]*big.Int),
 		children:       make(map[string]*IPNode),
 	}
-
-	return &IPTree{
-		root: root,
-	}
 }
 
 // processClaim updates the tree with a new claim
@@ -75,23 +170,20 @@ func (t *IPTree) processClaim(ipAddr string, claimant string, oldClaimant string
 	// Update the tree structure
 	ip := net.ParseIP(ipAddr)
 	if ip == nil || ip.To16() == nil {
+		t.logger.Debug("Ignoring claim for invalid IP", "ip", ipAddr)
 		return // Invalid IP
 	}
 
-	// If this is replacing an existing claim, first remove the old one
+	// If this is replacing an existing claim, first remove the old one,
+	// keeping its nodes for the new claimant
 	if oldClaimant != "" && oldClaimant != claimant {
-		t.removeClaimLocked(ipAddr, oldClaimant)
-	}
-
-	// Update tree for standard subnet sizes
-	t.updateSubnet(ip, 16, claimant)
-	t.updateSubnet(ip, 32, claimant)
-	t.updateSubnet(ip, 48, claimant)
-	t.updateSubnet(ip, 64, claimant)
-	t.updateSubnet(ip, 80, claimant)
-	t.updateSubnet(ip, 96, claimant)
-	t.updateSubnet(ip, 112, claimant)
-	t.updateSubnet(ip, 128, claimant)
+		t.removeClaimLocked(ipAddr, oldClaimant, false)
+	}
+
+	// Update tree for tracked subnet sizes
+	for _, prefixLen := range t.prefixes {
+		t.updateSubnet(ip, prefixLen, claimant)
+	}
 }
 
 // updateSubnet updates a specific subnet node for an IP claim
@@ -118,9 +210,12 @@ func (t *IPTree) updateSubnet(ip net.IP, prefixLen int, claimant string) {
 
 	// Increment total claimed count for this subnet
 	node.claimedCount.Add(node.claimedCount, big.NewInt(1))
+	if held := claimantCount.Int64(); held > node.dominantCount {
+		t.setDominantCount(node, held)
+	}
 
-	// Recalculate dominant claimant
-	t.recalculateDominant(node)
+	// Dominance is recalculated lazily on the next read
+	t.stats.invalidate(node)
 }
 
 // findOrCreateNode finds or creates a node for the given subnet
@@ -128,7 +223,7 @@ func (t *IPTree) findOrCreateNode(subnet *net.IPNet, prefixLen int) *IPNode {
 	// Start at the root
 	node := t.root
 
-	subnetStr := subnet.String()
+	subnetStr := api.CanonicalSubnet(subnet.IP, prefixLen)
 
 	// Check if we already have a node for this subnet
 	if child, exists := node.children[subnetStr]; exists {
@@ -150,12 +245,13 @@ func (t *IPTree) findOrCreateNode(subnet *net.IPNet, prefixLen int) *IPNode {
 
 	// Add to children
 	node.children[subnetStr] = newNode
+	t.markClaimedLocked(subnet, prefixLen)
 
 	return newNode
 }
 
-// recalculateDominant recalculates the dominant claimant for a node
-func (t *IPTree) recalculateDominant(node *IPNode) {
+// dominant calculates the dominant claimant of a node and the percentage of the subnet they hold
+func (node *IPNode) dominant() (string, float64) {
 	var maxCount *big.Int
 	var dominantClaimant string
 
@@ -185,37 +281,26 @@ func (t *IPTree) recalculateDominant(node *IPNode) {
 		percentage = ratio * 100.0
 	}
 
-	node.dominantClaimant = dominantClaimant
-	node.dominantPercentage = percentage
+	return dominantClaimant, percentage
 }
 
-// removeClaimLocked removes a claim from the tree (assumes lock is held)
-func (t *IPTree) removeClaimLocked(ipAddr string, claimant string) {
+// removeClaimLocked removes a claim from the tree, pruning the nodes of
+// subnets left without claims if prune is set (assumes lock is held)
+func (t *IPTree) removeClaimLocked(ipAddr string, claimant string, prune bool) {
 	ip := net.ParseIP(ipAddr)
 	if ip == nil || ip.To16() == nil {
 		return // Invalid IP
 	}
 
-	// Update tree for standard subnet sizes
-	t.removeFromSubnet(ip, 16, claimant)
-	t.removeFromSubnet(ip, 32, claimant)
-	t.removeFromSubnet(ip, 48, claimant)
-	t.removeFromSubnet(ip, 64, claimant)
-	t.removeFromSubnet(ip, 80, claimant)
-	t.removeFromSubnet(ip, 96, claimant)
-	t.removeFromSubnet(ip, 112, claimant)
-	t.removeFromSubnet(ip, 128, claimant)
+	// Update tree for tracked subnet sizes
+	for _, prefixLen := range t.prefixes {
+		t.removeFromSubnet(ip, prefixLen, claimant, prune)
+	}
 }
 
 // removeFromSubnet removes a claim from a specific subnet
-func (t *IPTree) removeFromSubnet(ip net.IP, prefixLen int, claimant string) {
-	mask := net.CIDRMask(prefixLen, 128)
-	subnet := &net.IPNet{
-		IP:   ip.Mask(mask),
-		Mask: mask,
-	}
-
-	subnetStr := subnet.String()
+func (t *IPTree) removeFromSubnet(ip net.IP, prefixLen int, claimant string, prune bool) {
+	subnetStr := api.CanonicalSubnet(ip, prefixLen)
 
 	// Find node
 	node := t.root
@@ -227,6 +312,8 @@ func (t *IPTree) removeFromSubnet(ip net.IP, prefixLen int, claimant string) {
 	// Update statistics
 	claimantCount, exists := child.claimants[claimant]
 	if exists {
+		wasDominant := claimantCount.Int64() == child.dominantCount
+
 		// Decrement count
 		claimantCount.Sub(claimantCount, big.NewInt(1))
 
@@ -237,19 +324,30 @@ func (t *IPTree) removeFromSubnet(ip net.IP, prefixLen int, claimant string) {
 
 		// Decrement total claimed count
 		child.claimedCount.Sub(child.claimedCount, big.NewInt(1))
+		if wasDominant {
+			t.setDominantCount(child, child.maxClaimantCount())
+		}
 
-		// Recalculate dominant claimant
-		t.recalculateDominant(child)
+		// Dominance is recalculated lazily on the next read
+		t.stats.invalidate(child)
+	}
+
+	// Drop subnets without claims so churn does not grow the tree forever
+	if prune && child.claimedCount.Sign() <= 0 {
+		delete(node.children, subnetStr)
+		t.pruned++
+		t.removed++
 	}
 }
 
-// GetSubnetStats gets statistics for a subnet
-func (t *IPTree) GetSubnetStats(subnetStr string) (*SubnetStats, bool) {
+// GetSubnetStats gets statistics for a subnet, including up to topN claimants
+// ranked by their share of the subnet when topN is positive
+func (t *IPTree) GetSubnetStats(subnetStr string, topN int) (*SubnetStats, bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
 	// Parse subnet
-	_, subnet, err := net.ParseCIDR(subnetStr)
+	subnet, err := api.ParseSubnet(subnetStr)
 	if err != nil {
 		return nil, false
 	}
@@ -257,39 +355,27 @@ func (t *IPTree) GetSubnetStats(subnetStr string) (*SubnetStats, bool) {
 	// Get prefix length
 	prefixLen, _ := subnet.Mask.Size()
 
-	// Round to nearest standard prefix
-	stdPrefixes := []int{16, 32, 48, 64, 80, 96, 112, 128}
-	exactMatch := false
-
-	for _, stdPrefix := range stdPrefixes {
-		if prefixLen == stdPrefix {
-			exactMatch = true
-			break
-		}
-	}
-
-	if !exactMatch {
-		// Find nearest standard prefix (round up)
-		for _, stdPrefix := range stdPrefixes {
-			if stdPrefix > prefixLen {
-				prefixLen = stdPrefix
+	// Round up to the nearest tracked prefix, or the longest if none is longer
+	if !slices.Contains(t.prefixes, prefixLen) {
+		rounded := t.prefixes[len(t.prefixes)-1]
+		for _, level := range t.prefixes {
+			if level > prefixLen {
+				rounded = level
 				break
 			}
 		}
+		prefixLen = rounded
 
-		// If we couldn't find a larger prefix, use the largest
-		if !exactMatch {
-			prefixLen = 128
-		}
+		// Create new subnet with the tracked prefix
+		subnet.IP = subnet.IP.Mask(net.CIDRMask(prefixLen, 128))
+	}
 
-		// Create new subnet with standard prefix
-		subnet = &net.IPNet{
-			IP:   subnet.IP.Mask(net.CIDRMask(prefixLen, 128)),
-			Mask: net.CIDRMask(prefixLen, 128),
-		}
+	// Most queries are for empty subnets, skip the lookup if the subnet never had a claim
+	if !t.claimed[prefixLen].mayContain(subnet.IP.To16()) {
+		return &SubnetStats{}, true
 	}
 
-	subnetStr = subnet.String()
+	subnetStr = api.CanonicalSubnet(subnet.IP, prefixLen)
 
 	// Find node
 	node := t.root
@@ -302,16 +388,260 @@ func (t *IPTree) GetSubnetStats(subnetStr string) (*SubnetStats, bool) {
 		}, true
 	}
 
-	if child.dominantPercentage <= 50.0 {
+	stats := t.nodeStats(child)
+	if topN > 0 {
+		stats.AllClaimants = child.topClaimants(topN)
+	}
+
+	return stats, true
+}
+
+// topClaimants returns up to n claimants of a node ranked by their share of the subnet
+func (node *IPNode) topClaimants(n int) []api.ClaimantShare {
+	names := make([]string, 0, len(node.claimants))
+	for claimant := range node.claimants {
+		names = append(names, claimant)
+	}
+
+	// Highest count first, ties broken lexicographically like dominant
+	sort.Slice(names, func(i, j int) bool {
+		cmp := node.claimants[names[i]].Cmp(node.claimants[names[j]])
+		if cmp != 0 {
+			return cmp > 0
+		}
+		return names[i] < names[j]
+	})
+
+	if len(names) > n {
+		names = names[:n]
+	}
+
+	totalFloat := new(big.Float).SetInt(node.totalAddresses)
+	shares := make([]api.ClaimantShare, 0, len(names))
+	for _, name := range names {
+		countFloat := new(big.Float).SetInt(node.claimants[name])
+		ratio, _ := new(big.Float).Quo(countFloat, totalFloat).Float64()
+		shares = append(shares, api.ClaimantShare{
+			Name:       name,
+			Percentage: ratio * 100.0,
+		})
+	}
+
+	return shares
+}
+
+// stats calculates the public statistics for a node, hiding owners without a majority
+func (node *IPNode) stats() *SubnetStats {
+	dominantClaimant, dominantPercentage := node.dominant()
+	if dominantPercentage <= 50.0 {
 		// If no dominant claimant, return empty stats
 		return &SubnetStats{
 			Owner:      "",
 			Percentage: 0,
-		}, true
+		}
 	}
 
 	return &SubnetStats{
-		Owner:      child.dominantClaimant,
-		Percentage: child.dominantPercentage,
-	}, true
+		Owner:      dominantClaimant,
+		Percentage: dominantPercentage,
+	}
+}
+
+// nodeStats returns the statistics for a node from the cache, calculating and
+// caching them if the node changed since they were last read (assumes read lock is held)
+func (t *IPTree) nodeStats(node *IPNode) *SubnetStats {
+	if stats, cached := t.stats.get(node); cached {
+		return stats
+	}
+
+	stats := node.stats()
+	t.stats.add(node, *stats)
+	return stats
+}
+
+// GetAllSubnets returns every subnet with at least one claim at the given tracked prefix length
+func (t *IPTree) GetAllSubnets(prefixLen int) ([]api.SubnetListEntry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !slices.Contains(t.prefixes, prefixLen) {
+		return nil, false
+	}
+
+	listed := t.listSubnets(prefixLen, SubnetListOptions{})
+	subnets := make([]api.SubnetListEntry, len(listed))
+	for i, subnet := range listed {
+		subnets[i] = subnet.entry
+	}
+	return subnets, true
+}
+
+// SubnetSort is the key a subnet listing is sorted by
+type SubnetSort string
+
+// Keys subnet listings can be sorted by
+const (
+	SortBySubnet     SubnetSort = "subnet"     // Address order
+	SortByPercentage SubnetSort = "percentage" // Owner's share of the subnet
+	SortByOwner      SubnetSort = "owner"      // Owner's name ignoring case, subnets without an owner last
+)
+
+// SubnetListOptions filters, sorts and pages a listing of the subnets at a
+// prefix length. The zero value lists every subnet with a claim in address
+// order.
+type SubnetListOptions struct {
+	Within     *net.IPNet // Only subnets inside this one, if set
+	Owned      bool       // Only subnets with an owner, leaving out contested ones
+	Sort       SubnetSort // Address order if empty
+	Descending bool       // Reverse the sort key, keeping ties in address order
+	Offset     int        // Subnets skipped from the start of the sorted listing
+	Limit      int        // Most subnets listed, every one after the offset if zero
+}
+
+// listedSubnet is a subnet in a listing with its node, for sorting by address
+type listedSubnet struct {
+	node  *IPNode
+	entry api.SubnetListEntry
+}
+
+// ListSubnets returns a page of the subnets at a tracked prefix length
+// selected and sorted by the options, along with the number selected before
+// paging
+func (t *IPTree) ListSubnets(prefixLen int, opts SubnetListOptions) ([]api.SubnetListEntry, int, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !slices.Contains(t.prefixes, prefixLen) {
+		return nil, 0, false
+	}
+
+	listed := t.listSubnets(prefixLen, opts)
+	slices.SortFunc(listed, opts.compare)
+
+	total := len(listed)
+	listed = listed[min(max(opts.Offset, 0), total):]
+	if opts.Limit > 0 && opts.Limit < len(listed) {
+		listed = listed[:opts.Limit]
+	}
+	subnets := make([]api.SubnetListEntry, len(listed))
+	for i, subnet := range listed {
+		subnets[i] = subnet.entry
+	}
+	return subnets, total, true
+}
+
+// compare orders two listed subnets by the sort key, then by address
+func (opts SubnetListOptions) compare(a, b listedSubnet) int {
+	c := 0
+	switch opts.Sort {
+	case SortByPercentage:
+		c = cmp.Compare(a.entry.Percentage, b.entry.Percentage)
+	case SortByOwner:
+		// Subnets without an owner go last in either order
+		if (a.entry.Owner == "") != (b.entry.Owner == "") {
+			if a.entry.Owner == "" {
+				return 1
+			}
+			return -1
+		}
+		c = strings.Compare(strings.ToLower(a.entry.Owner), strings.ToLower(b.entry.Owner))
+	}
+	if opts.Descending {
+		c = -c
+	}
+	if c != 0 {
+		return c
+	}
+	return bytes.Compare(a.node.subnet.IP.To16(), b.node.subnet.IP.To16())
+}
+
+// listSubnets returns the subnets with a claim at a prefix length that pass
+// the filters of the options, unsorted (assumes read lock is held)
+func (t *IPTree) listSubnets(prefixLen int, opts SubnetListOptions) []listedSubnet {
+	withinLen := 0
+	if opts.Within != nil {
+		withinLen = prefixLength(opts.Within)
+	}
+
+	subnets := make([]listedSubnet, 0)
+	for subnetStr, node := range t.root.children {
+		if node.prefixLen != prefixLen || node.claimedCount.Sign() <= 0 {
+			continue
+		}
+		if opts.Within != nil && (withinLen > prefixLen || !opts.Within.Contains(node.subnet.IP)) {
+			continue
+		}
+
+		// Listings touch every subnet, so only use cached statistics rather than
+		// evicting the subnets being browsed
+		stats, cached := t.stats.get(node)
+		if !cached {
+			stats = node.stats()
+		}
+		if opts.Owned && stats.Owner == "" {
+			continue
+		}
+		subnets = append(subnets, listedSubnet{node: node, entry: api.SubnetListEntry{
+			Subnet:     subnetStr,
+			Owner:      stats.Owner,
+			Percentage: stats.Percentage,
+			Claims:     node.claimedCount.Int64(),
+		}})
+	}
+	return subnets
+}
+
+// SummarizeOwners counts the subnets at a tracked prefix length within a
+// parent subnet that each claimant owns, along with the addresses each
+// claimed there
+func (t *IPTree) SummarizeOwners(parent *net.IPNet, prefixLen int) (*api.OwnersResponse, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	parentLen := prefixLength(parent)
+	if !slices.Contains(t.prefixes, prefixLen) || parentLen > prefixLen {
+		return nil, false
+	}
+
+	summary := &api.OwnersResponse{
+		Parent: api.CanonicalSubnet(parent.IP, parentLen),
+		Level:  prefixLen,
+		Owners: []api.OwnerSummary{},
+	}
+	owners := make(map[string]*api.OwnerSummary)
+	owner := func(name string) *api.OwnerSummary {
+		if _, exists := owners[name]; !exists {
+			owners[name] = &api.OwnerSummary{Name: name}
+		}
+		return owners[name]
+	}
+	for _, subnet := range t.listSubnets(prefixLen, SubnetListOptions{Within: parent}) {
+		summary.Subnets++
+		for claimant, count := range subnet.node.claimants {
+			owner(claimant).Claims += count.Int64()
+		}
+		if subnet.entry.Owner == "" {
+			summary.Contested++
+			continue
+		}
+		owner(subnet.entry.Owner).Subnets++
+	}
+
+	subnetSize := new(big.Int).Lsh(big.NewInt(1), uint(128-prefixLen))
+	for _, o := range owners {
+		o.Addresses = new(big.Int).Mul(subnetSize, big.NewInt(int64(o.Subnets))).String()
+		summary.Owners = append(summary.Owners, *o)
+	}
+
+	// Most subnets first, then most claims, ties broken by name
+	slices.SortFunc(summary.Owners, func(a, b api.OwnerSummary) int {
+		if c := cmp.Compare(b.Subnets, a.Subnets); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Claims, a.Claims); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return summary, true
 }
@@ -0,0 +1,369 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalOptions configures the append-only journal that lets an in-memory
+// store survive a crash without SQLite
+type JournalOptions struct {
+	Path            string        `yaml:"path"`            // Journal file, disabled if empty
+	CompactInterval time.Duration `yaml:"compactInterval"` // Time between rewrites of the journal as a snapshot
+	Sync            bool          `yaml:"sync"`            // Flush every change to disk, surviving power loss as well as crashes
+}
+
+// DefaultJournalOptions returns the standard journal options, disabled until a path is set
+func DefaultJournalOptions() JournalOptions {
+	return JournalOptions{CompactInterval: time.Hour}
+}
+
+// Enabled reports whether changes are journaled
+func (o JournalOptions) Enabled() bool {
+	return o.Path != ""
+}
+
+// Validate checks the options are usable
+func (o JournalOptions) Validate() error {
+	if o.Enabled() && o.CompactInterval <= 0 {
+		return errors.New("journal compactInterval must be positive")
+	}
+	return nil
+}
+
+// Operations recorded in the journal
+const (
+	journalClaim   = "claim"   // An address is held, with its claim metadata
+	journalRelease = "release" // An address is released
+	journalGrant   = "grant"   // A subnet is granted
+	journalName    = "name"    // A claimant name is registered
+	journalBatch   = "batch"   // Entries applied together, or not at all
+	journalReset   = "reset"   // Everything is removed
+)
+
+// journalEntry is one change to the store, written as one line of JSON
+type journalEntry struct {
+	Op            string         `json:"op"`
+	IP            string         `json:"ip,omitempty"`
+	Subnet        string         `json:"subnet,omitempty"`
+	Claimant      string         `json:"claimant,omitempty"`
+	Canonical     string         `json:"canonical,omitempty"`
+	ClaimedAt     time.Time      `json:"claimedAt,omitzero"`
+	TakeoverCount int            `json:"takeoverCount,omitempty"`
+	Fortification int            `json:"fortification,omitempty"`
+	FortifiedAt   time.Time      `json:"fortifiedAt,omitzero"`
+	Source        ClaimSource    `json:"source,omitzero"`
+	Entries       []journalEntry `json:"entries,omitempty"`
+}
+
+// claimEntry records an address held by claimant
+func claimEntry(ipAddr string, claimant string, metadata ClaimMetadata) journalEntry {
+	return journalEntry{
+		Op:            journalClaim,
+		IP:            ipAddr,
+		Claimant:      claimant,
+		ClaimedAt:     metadata.ClaimedAt,
+		TakeoverCount: metadata.TakeoverCount,
+		Fortification: metadata.Fortification,
+		FortifiedAt:   metadata.FortifiedAt,
+		Source:        metadata.Source,
+	}
+}
+
+// metadata returns the claim metadata of a claim entry
+func (e journalEntry) metadata() ClaimMetadata {
+	return ClaimMetadata{
+		ClaimedAt:     e.ClaimedAt,
+		TakeoverCount: e.TakeoverCount,
+		Fortification: e.Fortification,
+		FortifiedAt:   e.FortifiedAt,
+		Source:        e.Source,
+	}
+}
+
+// journal is an append-only file of the changes to an in-memory store. Every
+// change is written before it is applied, so the store can be rebuilt by
+// replaying the file after a crash.
+type journal struct {
+	mutex sync.Mutex // Serializes writes and compactions
+	path  string
+	file  *os.File
+	sync  bool
+}
+
+// append writes an entry to the journal. A nil journal records nothing.
+func (j *journal) append(entry journalEntry) error {
+	if j == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	// One write per entry, so a crash can only tear the last line
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if j.sync {
+		return j.file.Sync()
+	}
+	return nil
+}
+
+// rewrite replaces the journal with the given entries, through a temporary
+// file so a crash during the rewrite leaves the old journal in place
+func (j *journal) rewrite(entries []journalEntry) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return errors.Join(err, tmp.Close(), os.Remove(tmpPath))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmpPath))
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmpPath))
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			componentLogger("journal").Error("Error closing old journal", "error", err)
+		}
+	}
+	j.file = file
+	return nil
+}
+
+// close flushes the journal to disk and closes it
+func (j *journal) close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return errors.Join(j.file.Sync(), j.file.Close())
+}
+
+// OpenJournal rebuilds the store from the journal at the path in opts, if it
+// exists, then journals every change. The store must be in-memory and empty.
+func (cs *ClaimStore) OpenJournal(opts JournalOptions) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if cs.db != nil {
+		return errors.New("only in-memory stores are journaled")
+	}
+
+	cs.loading.begin(startupJournal, 0)
+	entries, err := cs.replayJournal(opts.Path)
+	if err != nil {
+		return err
+	}
+
+	// Start from a snapshot, dropping replayed history and any torn entry
+	cs.journal = &journal{path: opts.Path, sync: opts.Sync}
+	if err := cs.journal.rewrite(cs.journalSnapshotLocked()); err != nil {
+		cs.journal = nil
+		return err
+	}
+	cs.logger.Info("Loaded claims from journal", "path", opts.Path, "entries", entries, "claims", len(cs.claims), "grants", len(cs.grants))
+	return nil
+}
+
+// replayJournal applies the entries of the journal at path to the store,
+// returning how many were applied. A missing journal is empty.
+func (cs *ClaimStore) replayJournal(path string) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			cs.logger.Error("Error closing journal", "error", err)
+		}
+	}()
+
+	return cs.loadJournalEntriesLocked(file, path)
+}
+
+// loadJournalEntriesLocked applies the journal entries read from r to the
+// store, then rebuilds the tree, returning how many were applied. A torn entry
+// at the end, left by a crash mid-write, is ignored. The caller must hold the
+// write lock.
+func (cs *ClaimStore) loadJournalEntriesLocked(r io.Reader, source string) (int, error) {
+	reader := bufio.NewReader(r)
+	entries := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				cs.logger.Warn("Ignoring torn journal entry", "source", source, "entry", entries+1)
+			}
+			break
+		} else if err != nil {
+			return entries, err
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return entries, fmt.Errorf("journal entry %d: %w", entries+1, err)
+		}
+		cs.applyJournalEntry(entry)
+		cs.loading.advance(1)
+		entries++
+	}
+
+	// The tree and subnet names are rebuilt once every entry is applied
+	cs.rebuildLocked()
+	return entries, nil
+}
+
+// applyJournalEntry applies one entry to the claims, grants and names of the store
+func (cs *ClaimStore) applyJournalEntry(entry journalEntry) {
+	switch entry.Op {
+	case journalClaim:
+		cs.claims[entry.IP] = entry.Claimant
+		cs.metadata[entry.IP] = entry.metadata()
+	case journalRelease:
+		delete(cs.claims, entry.IP)
+		delete(cs.metadata, entry.IP)
+	case journalGrant:
+		_, ipNet, err := net.ParseCIDR(entry.Subnet)
+		if err != nil {
+			cs.logger.Warn("Ignoring invalid subnet grant", "subnet", entry.Subnet)
+			return
+		}
+		cs.grants[entry.Subnet] = subnetGrant{subnet: ipNet, claimant: entry.Claimant}
+	case journalName:
+		cs.names[entry.Canonical] = entry.Claimant
+	case journalBatch:
+		for _, child := range entry.Entries {
+			cs.applyJournalEntry(child)
+		}
+	case journalReset:
+		cs.claims = make(map[string]string)
+		cs.metadata = make(map[string]ClaimMetadata)
+		cs.grants = make(map[string]subnetGrant)
+		cs.names = make(map[string]string)
+	default:
+		cs.logger.Warn("Ignoring unknown journal entry", "op", entry.Op)
+	}
+}
+
+// journalSnapshotLocked returns the entries recreating the current state of
+// the store. The caller must hold the lock.
+func (cs *ClaimStore) journalSnapshotLocked() []journalEntry {
+	entries := make([]journalEntry, 0, len(cs.names)+len(cs.grants)+len(cs.claims))
+	for canonical, display := range cs.names {
+		entries = append(entries, journalEntry{Op: journalName, Canonical: canonical, Claimant: display})
+	}
+	for subnet, grant := range cs.grants {
+		entries = append(entries, journalEntry{Op: journalGrant, Subnet: subnet, Claimant: grant.claimant})
+	}
+	for ipAddr, claimant := range cs.claims {
+		entries = append(entries, claimEntry(ipAddr, claimant, cs.metadata[ipAddr]))
+	}
+	return entries
+}
+
+// CompactJournal rewrites the journal as a snapshot of the store, dropping
+// the history of changes that led to it
+func (cs *ClaimStore) CompactJournal() error {
+	// Changes take the write lock, so none is journaled during the rewrite
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	if cs.journal == nil {
+		return nil
+	}
+	return cs.journal.rewrite(cs.journalSnapshotLocked())
+}
+
+// JournalCompactor periodically compacts the journal of a store
+type JournalCompactor struct {
+	store    *ClaimStore
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+// NewJournalCompactor creates a compactor for the journal of a store
+func NewJournalCompactor(store *ClaimStore, interval time.Duration) *JournalCompactor {
+	return &JournalCompactor{
+		store:    store,
+		interval: interval,
+		logger:   componentLogger("journal"),
+	}
+}
+
+// Start begins compacting in the background
+func (c *JournalCompactor) Start() {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				if err := c.store.CompactJournal(); err != nil {
+					c.logger.Error("Error compacting journal", "error", err)
+				} else {
+					c.logger.Debug("Compacted journal")
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops compacting
+func (c *JournalCompactor) Stop() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+	c.stop = nil
+}
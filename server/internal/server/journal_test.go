@@ -0,0 +1,104 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openJournaled creates an in-memory store journaled to path, closed when the test ends
+func openJournaled(t *testing.T, path string) *ClaimStore {
+	t.Helper()
+	store := NewClaimStore()
+	require.NoError(t, store.OpenJournal(JournalOptions{Path: path, CompactInterval: DefaultJournalOptions().CompactInterval}),
+		"Should open the journal")
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// TestClaimStore_Journal tests that a journaled store is rebuilt from its journal
+func TestClaimStore_Journal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.journal")
+	store := openJournaled(t, path)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::3", Claimant: "carol"},
+		{IP: "2001:db8::4", Claimant: "carol"},
+	}))
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.GrantSubnet(t.Context(), "2001:db8:1::/48", "dave"))
+	stats := store.GetStats(t.Context())
+	metadata, _ := store.GetClaimMetadata(t.Context(), "2001:db8::1")
+
+	// A crash leaves the journal unclosed
+	restored := openJournaled(t, path)
+
+	assert.Equal(t, store.GetAllClaims(t.Context()), restored.GetAllClaims(t.Context()), "Claims should be replayed")
+	assert.Equal(t, stats, restored.GetStats(t.Context()), "The subnet tree should be rebuilt")
+	restoredMetadata, _ := restored.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, 1, restoredMetadata.TakeoverCount, "Claim metadata should be replayed")
+	assert.True(t, metadata.ClaimedAt.Equal(restoredMetadata.ClaimedAt))
+	assert.ErrorIs(t, restored.ProcessClaim(t.Context(), "2001:db8::5", "Alice"), ErrNameConfusable,
+		"Names of released claims should stay registered")
+	grantName, err := names.GenerateName("2001:db8:1::", 48)
+	require.NoError(t, err)
+	assert.Contains(t, restored.ResolveName(t.Context(), grantName), "2001:db8:1::/48", "Grants should be replayed")
+
+	require.NoError(t, restored.Reset(t.Context()))
+	require.NoError(t, restored.Close())
+	assert.Empty(t, openJournaled(t, path).GetAllClaims(t.Context()), "Reset should be replayed")
+}
+
+// TestClaimStore_JournalTornEntry tests that an entry torn by a crash mid-write is ignored
+func TestClaimStore_JournalTornEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.journal")
+	store := openJournaled(t, path)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.Close())
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"op":"claim","ip":"2001:db8::2","cla`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restored := openJournaled(t, path)
+	assert.Equal(t, map[string]string{"2001:db8::1": "alice"}, restored.GetAllClaims(t.Context()))
+
+	// The torn entry is dropped, so later entries are not appended to it
+	require.NoError(t, restored.ProcessClaim(t.Context(), "2001:db8::3", "alice"))
+	assert.Len(t, openJournaled(t, path).GetAllClaims(t.Context()), 2)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json\n"), 0o644))
+	assert.Error(t, NewClaimStore().OpenJournal(JournalOptions{Path: path}), "Corrupt entries should not be skipped silently")
+}
+
+// TestClaimStore_CompactJournal tests that compaction drops the history behind the current claims
+func TestClaimStore_CompactJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.journal")
+	store := openJournaled(t, path)
+	for _, claimant := range []string{"alice", "bob", "alice", "bob"} {
+		require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", claimant))
+	}
+
+	lines := func() int {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return bytes.Count(data, []byte("\n"))
+	}
+	assert.Equal(t, 6, lines(), "Every name and claim should be journaled")
+
+	require.NoError(t, store.CompactJournal())
+	assert.Equal(t, 3, lines(), "Only the names and the current claim should be kept")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	assert.Equal(t, 4, lines(), "Changes should be appended to the compacted journal")
+	assert.Len(t, openJournaled(t, path).GetAllClaims(t.Context()), 2)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// DefaultLevels returns the standard subnet hierarchy, every /16 step from /16 to /128
+func DefaultLevels() []int {
+	return slices.Clone(standardPrefixes)
+}
+
+// ValidateLevels checks that levels are usable as a subnet hierarchy: standard
+// prefix lengths in increasing order. Shallower games, such as /32 to /64 for a
+// small event, track only some of the standard prefixes.
+func ValidateLevels(levels []int) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("levels must list at least one of %v", standardPrefixes)
+	}
+	for i, prefixLen := range levels {
+		if !isStandardPrefix(prefixLen) {
+			return fmt.Errorf("levels must be chosen from %v, got %d", standardPrefixes, prefixLen)
+		}
+		if i > 0 && prefixLen <= levels[i-1] {
+			return fmt.Errorf("levels must be in increasing order, got %v", levels)
+		}
+	}
+	return nil
+}
+
+// SetLevels replaces the prefix lengths of the subnets tracked by the store,
+// rebuilding the subnet tree from the current claims
+func (cs *ClaimStore) SetLevels(levels []int) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.ipTree.setLevels(levels)
+	cs.ipTree.build(cs.claims, nil)
+}
+
+// Levels returns the prefix lengths of the subnets tracked by the store, widest first
+func (cs *ClaimStore) Levels(ctx context.Context) []int {
+	return cs.ipTree.levels()
+}
+
+// handleGetConfig returns the rules of the game clients adapt to
+func (h *HTTPHandler) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	config := api.GameConfig{Levels: h.store.Levels(ctx)}
+	if h.namePack != nil {
+		config.NamePack = h.namePack.PackID()
+	}
+	if h.supplyLines.Enabled {
+		config.SupplyLines = &api.SupplyLineRule{FreeClaims: h.supplyLines.FreeClaims, Distance: h.supplyLines.Distance}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
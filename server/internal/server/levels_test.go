@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateLevels tests which subnet hierarchies are accepted
+func TestValidateLevels(t *testing.T) {
+	tests := []struct {
+		levels []int
+		valid  bool
+	}{
+		{DefaultLevels(), true},
+		{[]int{32, 48, 64}, true},
+		{[]int{16, 64, 128}, true},
+		{nil, false},
+		{[]int{32, 40}, false},
+		{[]int{48, 32}, false},
+		{[]int{32, 32}, false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateLevels(tt.levels)
+		if tt.valid {
+			assert.NoError(t, err, "Levels %v should be valid", tt.levels)
+		} else {
+			assert.Error(t, err, "Levels %v should be rejected", tt.levels)
+		}
+	}
+}
+
+// TestClaimStore_SetLevels tests that a shallower hierarchy tracks only its levels
+func TestClaimStore_SetLevels(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	store.SetLevels([]int{32, 48, 64})
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	assert.Equal(t, []int{32, 48, 64}, store.Levels(t.Context()))
+
+	subnets, ok := store.GetAllSubnets(t.Context(), 64)
+	require.True(t, ok, "Tracked levels should be listed")
+	require.Len(t, subnets, 1, "Claims made before the change should be kept")
+	assert.Equal(t, "2001:db8::/64", subnets[0].Subnet)
+	assert.Equal(t, int64(2), subnets[0].Claims, "Listings should count the claimed addresses")
+
+	_, ok = store.GetAllSubnets(t.Context(), 16)
+	assert.False(t, ok, "Levels no longer tracked should not be listed")
+	_, ok = store.GetAllSubnets(t.Context(), 128)
+	assert.False(t, ok, "Levels no longer tracked should not be listed")
+
+	// Untracked prefixes round up to the next level, or the longest
+	want, ok := store.GetSubnetStats(t.Context(), "2001:db8::/64", 1)
+	require.True(t, ok)
+	require.Len(t, want.AllClaimants, 1)
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/56", 1)
+	require.True(t, ok)
+	assert.Equal(t, want, stats, "/56 should read the /64")
+	stats, ok = store.GetSubnetStats(t.Context(), "2001:db8::/112", 1)
+	require.True(t, ok)
+	assert.Equal(t, want, stats, "Prefixes past the last level should read the last level")
+}
+
+// TestHTTPHandler_GetConfig tests that clients can discover the subnet hierarchy
+func TestHTTPHandler_GetConfig(t *testing.T) {
+	store := NewClaimStore()
+	store.SetLevels([]int{32, 48, 64})
+	router := mux.NewRouter()
+	NewHTTPHandler(store).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/config", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var config api.GameConfig
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&config))
+	assert.Equal(t, []int{32, 48, 64}, config.Levels)
+}
+
+// TestClaimStore_ListSubnets tests filtering, sorting and paging subnet listings
+func TestClaimStore_ListSubnets(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::3", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "Carol"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "dave"))
+
+	listed := func(subnets []api.SubnetListEntry) []string {
+		names := make([]string, len(subnets))
+		for i, subnet := range subnets {
+			names[i] = subnet.Subnet
+		}
+		return names
+	}
+
+	subnets, total, ok := store.ListSubnets(t.Context(), 128, SubnetListOptions{})
+	require.True(t, ok, "Tracked levels should be listed")
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"2001:db8::1/128", "2001:db8::2/128", "2001:db8::3/128", "2001:db9::1/128"}, listed(subnets), "Listings should default to address order")
+
+	subnets, _, _ = store.ListSubnets(t.Context(), 128, SubnetListOptions{Sort: SortByOwner})
+	assert.Equal(t, []string{"2001:db8::2/128", "2001:db8::3/128", "2001:db8::1/128", "2001:db9::1/128"}, listed(subnets), "Owners should sort ignoring case")
+
+	subnets, _, _ = store.ListSubnets(t.Context(), 128, SubnetListOptions{Sort: SortByOwner, Descending: true})
+	assert.Equal(t, []string{"2001:db9::1/128", "2001:db8::1/128", "2001:db8::3/128", "2001:db8::2/128"}, listed(subnets), "Descending should reverse the owners")
+
+	subnets, _, _ = store.ListSubnets(t.Context(), 128, SubnetListOptions{Sort: SortByPercentage, Descending: true})
+	assert.Equal(t, []string{"2001:db8::1/128", "2001:db8::2/128", "2001:db8::3/128", "2001:db9::1/128"}, listed(subnets), "Ties should stay in address order")
+
+	within, err := api.ParseSubnet("2001:db8::/32")
+	require.NoError(t, err)
+	subnets, total, _ = store.ListSubnets(t.Context(), 128, SubnetListOptions{Within: within, Offset: 1, Limit: 1})
+	assert.Equal(t, 3, total, "Total should count the subnets within before paging")
+	assert.Equal(t, []string{"2001:db8::2/128"}, listed(subnets), "Page should start after the offset")
+
+	subnets, total, _ = store.ListSubnets(t.Context(), 128, SubnetListOptions{Offset: 10})
+	assert.Empty(t, subnets, "Offsets past the end should list nothing")
+	assert.Equal(t, 4, total)
+
+	subnets, _, _ = store.ListSubnets(t.Context(), 112, SubnetListOptions{})
+	assert.Len(t, subnets, 2, "Contested subnets should be listed")
+	subnets, total, _ = store.ListSubnets(t.Context(), 112, SubnetListOptions{Owned: true})
+	assert.Empty(t, subnets, "Subnets without an owner should be left out")
+	assert.Zero(t, total)
+
+	_, _, ok = store.ListSubnets(t.Context(), 20, SubnetListOptions{})
+	assert.False(t, ok, "Untracked levels should not be listed")
+}
+
+// TestClaimStore_SummarizeOwners tests counting the subnets each claimant owns within a parent
+func TestClaimStore_SummarizeOwners(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::3", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "dave"))
+
+	parent, err := api.ParseSubnet("2001:db8::/96")
+	require.NoError(t, err)
+
+	summary, ok := store.SummarizeOwners(t.Context(), parent, 128)
+	require.True(t, ok, "Tracked levels should be summarized")
+	assert.Equal(t, "2001:db8::/96", summary.Parent)
+	assert.Equal(t, 3, summary.Subnets, "Subnets outside the parent should not be counted")
+	assert.Zero(t, summary.Contested)
+	assert.Equal(t, []api.OwnerSummary{
+		{Name: "alice", Subnets: 2, Addresses: "2", Claims: 2},
+		{Name: "bob", Subnets: 1, Addresses: "1", Claims: 1},
+	}, summary.Owners, "Owners should be ranked by subnets owned")
+
+	summary, _ = store.SummarizeOwners(t.Context(), parent, 112)
+	assert.Equal(t, 1, summary.Subnets)
+	assert.Equal(t, 1, summary.Contested, "Subnets without a majority should be contested")
+	assert.Equal(t, []api.OwnerSummary{
+		{Name: "alice", Subnets: 0, Addresses: "0", Claims: 2},
+		{Name: "bob", Subnets: 0, Addresses: "0", Claims: 1},
+	}, summary.Owners, "Claimants owning nothing should be ranked by claims")
+
+	_, ok = store.SummarizeOwners(t.Context(), parent, 64)
+	assert.False(t, ok, "Levels wider than the parent should not be summarized")
+	_, ok = store.SummarizeOwners(t.Context(), parent, 120)
+	assert.False(t, ok, "Untracked levels should not be summarized")
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Phases of loading the claims on startup
+const (
+	startupDatabase = "database" // Claims are read from SQLite
+	startupJournal  = "journal"  // The journal is replayed
+	startupSnapshot = "snapshot" // The latest snapshot is restored
+	startupTree     = "tree"     // The subnet tree is built from the loaded claims
+	startupNames    = "names"    // The generated names of the claimed subnets are indexed
+	startupDone     = "done"
+)
+
+// startupLogInterval is how many entries are loaded between progress logs
+const startupLogInterval = 100000
+
+// errShuttingDown fails readiness once shutdown begins
+var errShuttingDown = errors.New("shutting down")
+
+// Lifecycle tracks whether the server should receive traffic: not until its
+// claims are loaded, and not once it begins shutting down. Orchestrators such
+// as Kubernetes follow it through the readiness check, which reports how far
+// a long load has got.
+type Lifecycle struct {
+	mutex     sync.Mutex
+	phase     string
+	loaded    int
+	total     int
+	startedAt time.Time
+	ready     bool
+	draining  bool
+	logger    *slog.Logger
+}
+
+// NewLifecycle creates the lifecycle of a server that is loading its claims
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		startedAt: time.Now(),
+		logger:    componentLogger("lifecycle"),
+	}
+}
+
+// begin starts a loading phase of total entries, zero if unknown. A nil
+// lifecycle tracks nothing.
+func (l *Lifecycle) begin(phase string, total int) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.phase, l.loaded, l.total = phase, 0, total
+	l.logger.Info("Loading claims", "phase", phase, "total", total)
+}
+
+// advance records n more entries loaded in the current phase
+func (l *Lifecycle) advance(n int) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.loaded += n
+	if l.loaded/startupLogInterval != (l.loaded-n)/startupLogInterval {
+		l.logger.Info("Loading claims", "phase", l.phase, "loaded", l.loaded, "total", l.total)
+	}
+}
+
+// finish marks the claims loaded, making the server ready
+func (l *Lifecycle) finish() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.phase, l.ready = startupDone, true
+	l.logger.Info("Server is ready", "startup", time.Since(l.startedAt).Round(time.Millisecond))
+}
+
+// drain marks the server as shutting down, failing readiness so load
+// balancers stop sending it requests
+func (l *Lifecycle) drain() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.draining = true
+}
+
+// Ready reports whether the claims are loaded and shutdown has not begun
+func (l *Lifecycle) Ready() bool {
+	if l == nil {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.ready && !l.draining
+}
+
+// status returns the progress of loading the claims, nil once they are
+// loaded, and why the server is not ready, if it is not
+func (l *Lifecycle) status() (*api.StartupStatus, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.draining {
+		return nil, errShuttingDown
+	}
+	if l.ready {
+		return nil, nil
+	}
+
+	status := &api.StartupStatus{Phase: l.phase, Loaded: l.loaded, Total: l.total, StartedAt: l.startedAt}
+	switch {
+	case l.phase == "":
+		return status, errors.New("starting")
+	case l.total > 0:
+		return status, fmt.Errorf("loading claims (%s): %d of %d", l.phase, l.loaded, l.total)
+	default:
+		return status, fmt.Errorf("loading claims (%s): %d", l.phase, l.loaded)
+	}
+}
+
+// gate answers requests other than health checks with 503 until the claims
+// are loaded, so nothing reads or changes a partly loaded store
+func (l *Lifecycle) gate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/health") {
+			l.mutex.Lock()
+			ready := l.ready
+			l.mutex.Unlock()
+
+			if !ready {
+				w.Header().Set("Retry-After", "5")
+				writeError(w, r, unavailable("the server is loading its claims"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLifecycle_Readiness tests that the API waits for the claims to load and
+// readiness fails until they have, and again once shutdown begins
+func TestLifecycle_Readiness(t *testing.T) {
+	lifecycle := NewLifecycle()
+	handler := NewHTTPHandler(NewClaimStore())
+	handler.lifecycle = lifecycle
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	gated := lifecycle.gate(router)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		gated.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	ready := func() (int, api.HealthResponse) {
+		rr := get("/health/ready")
+		var resp api.HealthResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Health responses should be JSON")
+		return rr.Code, resp
+	}
+
+	lifecycle.begin(startupDatabase, 3)
+	lifecycle.advance(1)
+	lifecycle.advance(1)
+
+	rr := get("/api/v1/stats")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "The API should wait for the claims to load")
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	var errResp api.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+	assert.Equal(t, codeUnavailable, errResp.Code)
+	assert.Equal(t, http.StatusOK, get("/health/live").Code, "Liveness should not wait for the claims")
+
+	code, resp := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code, "Readiness should fail while loading")
+	require.NotNil(t, resp.Startup, "Readiness should report the load progress")
+	assert.Equal(t, startupDatabase, resp.Startup.Phase)
+	assert.Equal(t, 2, resp.Startup.Loaded)
+	assert.Equal(t, 3, resp.Startup.Total)
+	assert.Contains(t, resp.Checks, api.HealthCheck{Name: "lifecycle", Detail: "loading claims (database): 2 of 3"})
+
+	lifecycle.finish()
+	assert.True(t, lifecycle.Ready())
+	assert.Equal(t, http.StatusOK, get("/api/v1/stats").Code)
+	code, resp = ready()
+	assert.Equal(t, http.StatusOK, code, "Loaded server should be ready")
+	assert.Nil(t, resp.Startup)
+
+	lifecycle.drain()
+	assert.False(t, lifecycle.Ready())
+	code, resp = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code, "Readiness should fail once shutdown begins")
+	assert.Contains(t, resp.Checks, api.HealthCheck{Name: "lifecycle", Detail: "shutting down"})
+	assert.Equal(t, http.StatusOK, get("/api/v1/stats").Code, "Requests should be served while draining")
+}
+
+// TestServer_LoadsClaimsOnStart tests that a server loads its database once
+// started, reporting the progress, and is ready until it stops
+func TestServer_LoadsClaimsOnStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.db")
+	seed, err := NewClaimStoreWithSQLite(path)
+	require.NoError(t, err)
+	require.NoError(t, seed.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, seed.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+	require.NoError(t, seed.Close())
+
+	server := NewServerWithOptions(ServerOptions{HTTPPort: 0, DBPath: path})
+	assert.False(t, server.lifecycle.Ready(), "Server should not be ready before loading")
+	assert.Empty(t, server.store.GetAllClaims(t.Context()), "Claims should be loaded by Start")
+
+	require.NoError(t, server.Start(), "Server should start successfully")
+	assert.True(t, server.lifecycle.Ready(), "Server should be ready once started")
+	assert.Len(t, server.store.GetAllClaims(t.Context()), 2)
+	status, err := server.lifecycle.status()
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+	server.lifecycle.mutex.Lock()
+	assert.Equal(t, server.store.(*ClaimStore).subnets.Len(), server.lifecycle.loaded,
+		"Every named subnet should be counted in the last phase")
+	server.lifecycle.mutex.Unlock()
+
+	server.Stop()
+	assert.False(t, server.lifecycle.Ready(), "Server should not be ready once stopped")
+}
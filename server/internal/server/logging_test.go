@@ -0,0 +1,32 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewLogger tests logger construction from level and format flags
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "warn", "json")
+	require.NoError(t, err, "Valid level and format should be accepted")
+
+	logger.Info("filtered")
+	logger.With("component", "store").Warn("kept", "ip", "2001:db8::1")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), "Output should be a single JSON object")
+	assert.Equal(t, "kept", entry["msg"], "Only warn-level message should be logged")
+	assert.Equal(t, "store", entry["component"], "Component attribute should be included")
+	assert.Equal(t, "2001:db8::1", entry["ip"], "Structured attributes should be included")
+
+	_, err = NewLogger(&buf, "verbose", "text")
+	assert.Error(t, err, "Unknown level should be rejected")
+
+	_, err = NewLogger(&buf, "info", "xml")
+	assert.Error(t, err, "Unknown format should be rejected")
+}
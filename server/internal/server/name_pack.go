@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/bjia56/spacenet/server/names"
+)
+
+// SetNamePack resolves subnets by the names of a name pack instead of the
+// embedded locales, reindexing the claimed and granted subnets
+func (cs *ClaimStore) SetNamePack(pack *names.Locale) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.subnets = names.NewIndexFor(pack)
+	for ipAddr := range cs.claims {
+		cs.indexSubnetNames(ipAddr)
+	}
+	for subnet := range cs.grants {
+		cs.indexSubnetNames(subnet)
+	}
+}
+
+// handleGetNamePack returns the server's name pack as JSON, for clients to
+// generate the same names
+func (h *HTTPHandler) handleGetNamePack(w http.ResponseWriter, r *http.Request) {
+	if h.namePack == nil {
+		writeError(w, r, notFound("server uses the built-in names"))
+		return
+	}
+	if checkNotModified(w, r, `"`+h.namePack.PackID()+`"`) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(h.namePack.Pack()); err != nil {
+		h.logger.Error("Error writing name pack", "error", err)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNamePack returns a name pack with one word per list at every level
+func testNamePack(t *testing.T) *names.Locale {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(`{"name": "ocean"`)
+	for _, list := range []string{"adjectives", "nouns", "celestialTypes"} {
+		fmt.Fprintf(&b, `, %q: {`, list)
+		for i, size := range names.Levels {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, `"%d": ["%s%d"]`, size, list, size)
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("}")
+
+	pack, err := names.LoadPack([]byte(b.String()))
+	require.NoError(t, err)
+	return pack
+}
+
+// TestClaimStore_SetNamePack tests that claimed subnets resolve by the names of the pack
+func TestClaimStore_SetNamePack(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	builtin, err := names.GenerateName("2001:db8::", 32)
+	require.NoError(t, err)
+	require.NotEmpty(t, store.ResolveName(t.Context(), builtin))
+
+	pack := testNamePack(t)
+	store.SetNamePack(pack)
+	themed, err := pack.GenerateName("2001:db8::", 32)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8::/32"}, store.ResolveName(t.Context(), themed), "Existing claims should be reindexed")
+	assert.Empty(t, store.ResolveName(t.Context(), builtin), "Built-in names should no longer resolve")
+}
+
+// TestHTTPHandler_GetNamePack tests that clients can fetch the pack named by the config
+func TestHTTPHandler_GetNamePack(t *testing.T) {
+	handler := NewHTTPHandler(NewClaimStore())
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/name-pack", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "Servers with the built-in names have no pack")
+
+	handler.namePack = testNamePack(t)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	var config api.GameConfig
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&config))
+	assert.Equal(t, handler.namePack.PackID(), config.NamePack)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/name-pack", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	fetched, err := names.LoadPack(rec.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, config.NamePack, fetched.PackID(), "The served pack should load as the same version")
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxNameBytes bounds the encoded size of a name however few characters it has
+const maxNameBytes = 128
+
+// Character sets claimant names may be restricted to
+const (
+	CharsetPrintable    = "printable"    // Any printable Unicode character or emoji sequence
+	CharsetASCII        = "ascii"        // Printable ASCII, including spaces
+	CharsetAlphanumeric = "alphanumeric" // ASCII letters and digits plus - _ and .
+)
+
+// ErrInvalidName marks claimant names rejected by the name policy
+var ErrInvalidName = errors.New("invalid claimant name")
+
+// NamePolicyOptions configures which claimant names are accepted
+type NamePolicyOptions struct {
+	MaxLength     int      `yaml:"maxLength"`     // Maximum name length in user-perceived characters
+	Charset       string   `yaml:"charset"`       // printable, ascii or alphanumeric
+	Reserved      []string `yaml:"reserved"`      // Names nobody may claim as, compared case-insensitively
+	Blocklist     []string `yaml:"blocklist"`     // Words names may not contain, compared case-insensitively
+	BlockPatterns []string `yaml:"blockPatterns"` // Regular expressions names may not match
+}
+
+// DefaultNamePolicyOptions returns the standard name policy
+func DefaultNamePolicyOptions() NamePolicyOptions {
+	return NamePolicyOptions{
+		MaxLength: 24,
+		Charset:   CharsetPrintable,
+		Reserved:  []string{"admin", "server", "spacenet"},
+	}
+}
+
+// NamePolicy validates claimant names at every claim entry point
+type NamePolicy struct {
+	maxLength     int
+	charset       string
+	reserved      map[string]bool
+	blocklist     []string
+	blockPatterns []*regexp.Regexp
+}
+
+// NewNamePolicy compiles a name policy from its options
+func NewNamePolicy(opts NamePolicyOptions) (*NamePolicy, error) {
+	if opts.MaxLength <= 0 {
+		return nil, fmt.Errorf("names maxLength must be positive, got %d", opts.MaxLength)
+	}
+
+	switch opts.Charset {
+	case CharsetPrintable, CharsetASCII, CharsetAlphanumeric:
+	default:
+		return nil, fmt.Errorf("unknown names charset %q", opts.Charset)
+	}
+
+	policy := &NamePolicy{
+		maxLength: opts.MaxLength,
+		charset:   opts.Charset,
+		reserved:  make(map[string]bool, len(opts.Reserved)),
+	}
+	for _, name := range opts.Reserved {
+		policy.reserved[strings.ToLower(name)] = true
+	}
+	for _, word := range opts.Blocklist {
+		if word != "" {
+			policy.blocklist = append(policy.blocklist, strings.ToLower(word))
+		}
+	}
+	for _, pattern := range opts.BlockPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid names block pattern %q: %w", pattern, err)
+		}
+		policy.blockPatterns = append(policy.blockPatterns, re)
+	}
+
+	return policy, nil
+}
+
+// defaultNamePolicy returns the policy used when none is configured
+func defaultNamePolicy() *NamePolicy {
+	policy, err := NewNamePolicy(DefaultNamePolicyOptions())
+	if err != nil {
+		panic("invalid default name policy: " + err.Error())
+	}
+	return policy
+}
+
+// Normalize returns the NFC form of a name to display and store, or an error
+// wrapping ErrInvalidName that explains why the name is rejected
+func (p *NamePolicy) Normalize(name string) (string, error) {
+	if err := p.check(name); err != nil {
+		return "", err
+	}
+	return norm.NFC.String(name), nil
+}
+
+// check applies the policy rules to a name
+func (p *NamePolicy) check(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidName)
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("%w: name must be valid UTF-8", ErrInvalidName)
+	}
+	name = norm.NFC.String(name)
+	if len(name) > maxNameBytes || uniseg.GraphemeClusterCount(name) > p.maxLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidName, p.maxLength)
+	}
+
+	for _, r := range name {
+		if !p.allowedRune(r) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidName, r)
+		}
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("%w: name must not start or end with whitespace", ErrInvalidName)
+	}
+
+	lower := strings.ToLower(name)
+	if p.reserved[lower] {
+		return fmt.Errorf("%w: name %q is reserved", ErrInvalidName, name)
+	}
+	for _, word := range p.blocklist {
+		if strings.Contains(lower, word) {
+			return fmt.Errorf("%w: name contains a blocked word", ErrInvalidName)
+		}
+	}
+	for _, re := range p.blockPatterns {
+		if re.MatchString(name) {
+			return fmt.Errorf("%w: name is not allowed", ErrInvalidName)
+		}
+	}
+
+	return nil
+}
+
+// allowedRune reports whether a character is in the policy's character set
+func (p *NamePolicy) allowedRune(r rune) bool {
+	switch p.charset {
+	case CharsetASCII:
+		return r >= ' ' && r <= '~'
+	case CharsetAlphanumeric:
+		return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.", r))
+	default:
+		// Zero width joiners are invisible but hold emoji sequences together
+		return unicode.IsPrint(r) || r == '\u200d'
+	}
+}
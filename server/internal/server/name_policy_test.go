@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNamePolicy_Check tests each rule of the name policy
+func TestNamePolicy_Check(t *testing.T) {
+	policy, err := NewNamePolicy(NamePolicyOptions{
+		MaxLength:     12,
+		Charset:       CharsetASCII,
+		Reserved:      []string{"admin"},
+		Blocklist:     []string{"darn"},
+		BlockPatterns: []string{`^[0-9]+$`},
+	})
+	require.NoError(t, err, "Policy should compile")
+
+	testCases := []struct {
+		name  string
+		valid bool
+	}{
+		{"alice", true},
+		{"alice smith", true},
+		{"", false},
+		{"a-very-long-name", false},
+		{"al\tice", false},
+		{"ålice", false},
+		{" alice", false},
+		{"ADMIN", false},
+		{"DarnedPlayer", false},
+		{"12345", false},
+		{"player1", true},
+	}
+
+	for _, tc := range testCases {
+		_, err := policy.Normalize(tc.name)
+		if tc.valid {
+			assert.NoError(t, err, "Name %q should be accepted", tc.name)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidName, "Name %q should be rejected", tc.name)
+		}
+	}
+}
+
+// TestNamePolicy_Charsets tests the character set restrictions
+func TestNamePolicy_Charsets(t *testing.T) {
+	printable := defaultNamePolicy()
+	check := func(policy *NamePolicy, name string) error {
+		_, err := policy.Normalize(name)
+		return err
+	}
+	assert.NoError(t, check(printable, "ålice"), "Printable charset should accept Unicode letters")
+	assert.Error(t, check(printable, "al\x00ice"), "Printable charset should reject control characters")
+	assert.Error(t, check(printable, "\xffalice"), "Invalid UTF-8 should be rejected")
+
+	alphanumeric, err := NewNamePolicy(NamePolicyOptions{MaxLength: 24, Charset: CharsetAlphanumeric})
+	require.NoError(t, err)
+	assert.NoError(t, check(alphanumeric, "alice_01.b-c"), "Alphanumeric charset should accept - _ and .")
+	assert.Error(t, check(alphanumeric, "alice smith"), "Alphanumeric charset should reject spaces")
+	assert.Error(t, check(alphanumeric, "ålice"), "Alphanumeric charset should reject non-ASCII letters")
+
+	_, err = NewNamePolicy(NamePolicyOptions{MaxLength: 24, Charset: "emoji"})
+	assert.Error(t, err, "Unknown charset should be rejected")
+}
+
+// TestHTTPHandler_InvalidNameBody tests that rejected names are explained in the response body
+func TestHTTPHandler_InvalidNameBody(t *testing.T) {
+	handler := NewHTTPHandler(NewClaimStore())
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"name":"spacenet","nonce":"0"}`)
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/claim/2001:db8::1", body))
+	require.Equal(t, http.StatusBadRequest, rec.Code, "Reserved name should be rejected")
+
+	var errResp api.ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp), "Error body should be JSON")
+	assert.Contains(t, errResp.Error, "reserved", "Error should explain why the name was rejected")
+}
+
+// TestNamePolicy_Unicode tests normalization and grapheme-based length limits
+func TestNamePolicy_Unicode(t *testing.T) {
+	policy, err := NewNamePolicy(NamePolicyOptions{MaxLength: 5, Charset: CharsetPrintable})
+	require.NoError(t, err)
+
+	name, err := policy.Normalize("éclair")
+	require.Error(t, err, "Six characters should exceed the limit")
+
+	name, err = policy.Normalize("élan")
+	require.NoError(t, err, "Combining accent should count with its base character")
+	assert.Equal(t, "élan", name, "Name should be NFC normalized")
+
+	_, err = policy.Normalize("👩‍🚀👩‍🚀👩‍🚀👩‍🚀👩‍🚀")
+	assert.NoError(t, err, "Emoji sequences should count as one character each")
+}
+
+// TestNameSkeleton tests that lookalike names share a skeleton
+func TestNameSkeleton(t *testing.T) {
+	alice := nameSkeleton("alice")
+	for _, name := range []string{"ALICE", "аlice", "ａｌｉｃｅ", "al​ice", "a1ice", "álice"} {
+		assert.Equal(t, alice, nameSkeleton(name), "%q should be confusable with alice", name)
+	}
+	assert.NotEqual(t, alice, nameSkeleton("alicia"), "Different names should not be confusable")
+}
+
+// TestClaimStore_ConfusableNames tests that claims cannot impersonate existing claimants
+func TestClaimStore_ConfusableNames(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "names.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"), "Same name should claim again")
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::3", "аlice"), ErrNameConfusable,
+		"Cyrillic lookalike should be rejected")
+	_, exists := store.GetClaim(t.Context(), "2001:db8::3")
+	assert.False(t, exists, "Rejected claim should not be stored")
+	assert.ErrorIs(t, store.GrantSubnet(t.Context(), "2001:db8::/32", "Alice"), ErrNameConfusable,
+		"Grants should not impersonate claimants either")
+
+	// Taking over every address doesn't free the name
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	assert.ErrorIs(t, reopened.ProcessClaim(t.Context(), "2001:db8::3", "ALICE"), ErrNameConfusable,
+		"Registered names should persist")
+
+	require.NoError(t, reopened.Reset(t.Context()))
+	assert.NoError(t, reopened.ProcessClaim(t.Context(), "2001:db8::3", "ALICE"), "Reset should free names")
+}
+
+// TestHTTPHandler_ConfusableName tests that impersonating claims are rejected with a conflict
+func TestHTTPHandler_ConfusableName(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::2"), "аlice", store.CalculateDifficulty(t.Context(), "2001:db8::2"), 1000000)
+	require.NoError(t, err, "Should solve proof of work")
+	data, err := json.Marshal(api.ClaimRequest{Name: pow.Name, Nonce: pow.Nonce})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/claim/2001:db8::2", bytes.NewReader(data)))
+	assert.Equal(t, http.StatusConflict, rec.Code, "Lookalike name should be rejected")
+	assert.Contains(t, rec.Body.String(), "confusable", "Error should explain the conflict")
+}
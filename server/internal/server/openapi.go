@@ -0,0 +1,767 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
+)
+
+// apiOperation describes a single API endpoint for the OpenAPI document
+type apiOperation struct {
+	Method      string
+	Path        string
+	Summary     string
+	PathParams  []apiParam
+	QueryParams []apiParam
+	Request     any            // Zero value of the request body type, if any
+	Response    any            // Zero value of the success response body type, if any
+	Responses   map[int]string // Status code to description
+	ErrorBody   any            // Zero value of the error response body type, api.ErrorResponse if nil
+	Admin       bool           // Requires an admin bearer token
+	Delegated   bool           // Requires a delegation bearer token
+}
+
+// apiParam describes a path or query parameter
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// apiOperations lists the documented endpoints; keep in sync with RegisterRoutes
+var apiOperations = []apiOperation{
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/ip/{ip}",
+		Summary:    "Get the claim for an IPv6 address",
+		PathParams: []apiParam{{"ip", "string", "IPv6 address"}},
+		Response:   api.ClaimResponse{},
+		Responses:  map[int]string{200: "Current claim", 304: "Claim matches If-None-Match", 400: "Invalid address", 404: "Address is unclaimed"},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/ip/{ip}/history",
+		Summary:    "List the recent claims on an IPv6 address, newest first",
+		PathParams: []apiParam{{"ip", "string", "IPv6 address"}},
+		Response:   api.ClaimHistoryResponse{},
+		Responses:  map[int]string{200: "Recent claims", 400: "Invalid address", 404: "Activity statistics are disabled"},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/subnet/{address}/{prefix}",
+		Summary: "Get dominance statistics for a subnet",
+		PathParams: []apiParam{
+			{"address", "string", "Subnet address"},
+			{"prefix", "integer", "Prefix length"},
+		},
+		QueryParams: []apiParam{{"detail", "boolean", "Include the top claimants of the subnet"}},
+		Response:    api.SubnetResponse{},
+		Responses:   map[int]string{200: "Subnet statistics", 304: "Statistics match If-None-Match", 400: "Invalid subnet"},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/subnet/{address}/{prefix}/activity",
+		Summary: "Get claims per hour, unique claimants and takeovers in a subnet over a rolling window",
+		PathParams: []apiParam{
+			{"address", "string", "Subnet address"},
+			{"prefix", "integer", "Prefix length"},
+		},
+		Response:  api.SubnetActivityResponse{},
+		Responses: map[int]string{200: "Subnet activity", 400: "Invalid subnet", 404: "Activity statistics are disabled"},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/subnets/{prefix}",
+		Summary:    "List the claimed subnets at a standard prefix length, with the number listed before paging in X-Total-Count",
+		PathParams: []apiParam{{"prefix", "integer", "Standard prefix length (16, 32, ..., 128)"}},
+		QueryParams: []apiParam{
+			{"within", "string", "Only list subnets inside this subnet"},
+			{"claimed", "boolean", "Only list subnets with an owner, leaving out contested ones"},
+			{"sort", "string", "Sort by subnet (default), percentage or owner; subnets without an owner sort last by owner"},
+			{"order", "string", "asc (default) or desc; ties are listed in address order"},
+			{"offset", "integer", "Subnets skipped from the start of the sorted listing"},
+			{"limit", "integer", "Most subnets listed (default: all)"},
+		},
+		Response:  []api.SubnetListEntry{},
+		Responses: map[int]string{200: "Claimed subnets", 400: "Invalid prefix length or query parameter"},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/owners/{address}/{prefix}/{level}",
+		Summary: "Count the subnets of a level within a subnet that each claimant owns",
+		PathParams: []apiParam{
+			{"address", "string", "Subnet address"},
+			{"prefix", "integer", "Prefix length"},
+			{"level", "integer", "Standard prefix length of the subnets counted, no shorter than the subnet's"},
+		},
+		Response:  api.OwnersResponse{},
+		Responses: map[int]string{200: "Subnets and claims of each claimant", 400: "Invalid subnet or level"},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/resolve",
+		Summary:     "Find the claimed or granted subnets with a generated name",
+		QueryParams: []apiParam{{"name", "string", "Generated subnet name in any locale, case insensitive"}},
+		Response:    api.ResolveResponse{},
+		Responses:   map[int]string{200: "Subnets with the name", 400: "Missing name", 404: "No claimed subnet has the name"},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/difficulty/subnet/{address}/{prefix}",
+		Summary: "Preview the required difficulty for every address in a subnet",
+		PathParams: []apiParam{
+			{"address", "string", "Subnet address"},
+			{"prefix", "integer", "Prefix length"},
+		},
+		Response:  api.SubnetDifficultyResponse{},
+		Responses: map[int]string{200: "Per-address difficulties or a histogram for large subnets", 400: "Invalid subnet"},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/challenge/{ip}",
+		Summary:    "Get the proof of work scheme and difficulty required to claim an address",
+		PathParams: []apiParam{{"ip", "string", "IPv6 address to claim"}},
+		QueryParams: []apiParam{
+			{"name", "string", "Claimant name, to include any difficulty added to their claims after an anomaly"},
+			{"type", "string", "Challenge the client can solve: pow, or session for a lighter proof of work with a claim quota, answered with pow if disabled"},
+			{"session", "string", "Session to continue with a session challenge, a new one is issued if missing or expired"},
+		},
+		Response:  api.PoWChallenge{},
+		Responses: map[int]string{200: "Proof of work challenge", 400: "Invalid address", 429: "Too many sessions for the client address"},
+	},
+	{
+		Method:     http.MethodPost,
+		Path:       "/api/v1/claim/{ip}",
+		Summary:    "Claim an IPv6 address with a proof of work",
+		PathParams: []apiParam{{"ip", "string", "IPv6 address to claim"}},
+		Request:    api.ClaimRequest{},
+		Responses: map[int]string{
+			201: "Claim accepted",
+			400: "Invalid address, claimant name or request body",
+			409: "Name looks like another claimant's name, or address is beyond the claimant's supply lines",
+			401: "Invalid or expired delegation token or session",
+			403: "Address is protected, or outside the delegation token's prefix, or no claims left, or claimant is quarantined",
+			422: "Insufficient proof of work, or the address failed ICMP verification",
+			429: "Rate limit exceeded, or the session has no claims left in this window",
+			503: "Claim queue is full or the store is unavailable, retry later",
+		},
+	},
+	{
+		Method:     http.MethodDelete,
+		Path:       "/api/v1/claim/{ip}",
+		Summary:    "Release a claim, as its owner with a proof of work over the owner's name, or as an admin with a bearer token",
+		PathParams: []apiParam{{"ip", "string", "Claimed IPv6 address"}},
+		Request:    api.ClaimRequest{},
+		Responses: map[int]string{
+			204: "Claim released",
+			400: "Invalid address, claimant name or request body",
+			403: "Address is claimed by someone else",
+			404: "Address is not claimed",
+			422: "Insufficient proof of work, or the address failed ICMP verification",
+			429: "Rate limit exceeded",
+		},
+	},
+	{
+		Method:     http.MethodPost,
+		Path:       "/api/v1/claim/{ip}/fortify",
+		Summary:    "Raise the defense level of an owned address with a proof of work at its current difficulty",
+		PathParams: []apiParam{{"ip", "string", "Claimed IPv6 address"}},
+		Request:    api.ClaimRequest{},
+		Response:   api.ClaimResponse{},
+		Responses: map[int]string{
+			200: "Address fortified",
+			400: "Invalid address, claimant name or request body",
+			403: "Address is claimed by someone else",
+			404: "Address is not claimed, or fortification is disabled",
+			409: "Address is fortified to the maximum level",
+			422: "Insufficient proof of work",
+			429: "Rate limit exceeded",
+			503: "Claim queue is full, retry later",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/claims",
+		Summary:  "Claim several IPv6 addresses at once, each with its own proof of work; either every claim is applied or none",
+		Request:  api.BatchClaimRequest{},
+		Response: api.BatchClaimResponse{},
+		Responses: map[int]string{
+			201: "Every claim accepted",
+			400: "Invalid address, claimant name or request body, or too many claims",
+			403: "An address is protected or a claimant is quarantined",
+			409: "Name looks like another claimant's name, or an address is beyond its claimant's supply lines",
+			422: "Insufficient proof of work, or an address failed ICMP verification",
+			429: "Rate limit exceeded, every claim in the batch counts",
+			503: "Claim queue is full or the store is unavailable, retry later",
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/tokens",
+		Summary:  "Mint a delegation token letting a bot claim within a prefix on a player's behalf",
+		Request:  api.DelegationTokenRequest{},
+		Response: api.DelegationToken{},
+		Responses: map[int]string{
+			201: "Token minted, the secret is only returned here",
+			400: "Invalid name, prefix, claim count, lifetime or request body",
+			404: "Delegation tokens are disabled",
+			429: "Rate limit exceeded, or too many unexpired tokens for the name",
+		},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/tokens/self",
+		Summary:   "Describe the delegation token sent as the bearer token",
+		Response:  api.DelegationToken{},
+		Responses: map[int]string{200: "Token scope and claims made", 401: "Missing, invalid or expired token", 404: "Delegation tokens are disabled"},
+		Delegated: true,
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/icmp/challenge/{ip}",
+		Summary:     "Get the token to return in echo replies when claims are verified by ping",
+		PathParams:  []apiParam{{"ip", "string", "IPv6 address to claim"}},
+		QueryParams: []apiParam{{"name", "string", "Claimant name"}},
+		Response:    api.ICMPChallenge{},
+		Responses:   map[int]string{200: "Challenge token", 400: "Invalid address or name", 404: "ICMP verification is disabled"},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/claim-subnet/challenge",
+		Summary: "Get the TXT record to publish in a subnet's ip6.arpa zone to claim it",
+		QueryParams: []apiParam{
+			{"subnet", "string", "Nibble-aligned IPv6 subnet in CIDR notation"},
+			{"name", "string", "Claimant name"},
+		},
+		Response:  api.SubnetChallenge{},
+		Responses: map[int]string{200: "TXT record to publish", 400: "Invalid subnet or name", 404: "DNS subnet claims are disabled"},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/v1/claim-subnet",
+		Summary: "Claim dominance of a subnet by verifying its TXT record",
+		Request: api.SubnetClaimRequest{},
+		Responses: map[int]string{
+			201: "Subnet granted",
+			400: "Invalid subnet, name or request body",
+			404: "DNS subnet claims are disabled",
+			409: "Name looks like another claimant's name",
+			422: "TXT record is missing or does not contain the claim token",
+			429: "Rate limit exceeded",
+		},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/leaderboard",
+		Summary:     "Get claimants ranked by addresses held, with the complete /124 and /120 blocks they hold",
+		QueryParams: []apiParam{{"limit", "integer", "Maximum number of entries (default 10)"}},
+		Response:    []api.LeaderboardEntry{},
+		Responses:   map[int]string{200: "Leaderboard", 400: "Invalid limit"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/stats",
+		Summary:   "Get global game statistics",
+		Response:  api.StatsResponse{},
+		Responses: map[int]string{200: "Global statistics"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/stats/levels",
+		Summary:   "Get the distribution of dominance over the claimed subnets at each level",
+		Response:  []api.LevelStats{},
+		Responses: map[int]string{200: "Dominance statistics per level, widest first"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/scores",
+		Summary:   "Get players ranked by score",
+		Response:  []api.ScoreEntry{},
+		Responses: map[int]string{200: "Scores", 404: "Scoring is disabled"},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/scores/{name}/history",
+		Summary:    "Get the score history of a player",
+		PathParams: []apiParam{{"name", "string", "Player name"}},
+		Response:   []api.ScorePoint{},
+		Responses:  map[int]string{200: "Score history, oldest first", 404: "Unknown player or scoring is disabled"},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/federation/summary",
+		Summary:     "Get the claimed subnets in the prefixes this server is authoritative for, as fetched by federation peers",
+		QueryParams: []apiParam{{"prefix", "integer", "Standard prefix length to list subnets at, the configured summary prefix by default"}},
+		Response:    api.FederationSummary{},
+		Responses:   map[int]string{200: "Dominance summary", 400: "Invalid prefix length", 404: "Federation is disabled"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/federation/subnets",
+		Summary:   "List the claimed subnets of every server in the federation, with the server authoritative for each",
+		Response:  []api.FederatedSubnet{},
+		Responses: map[int]string{200: "Combined subnets, peers' as last fetched", 404: "Federation is disabled"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/federation/peers",
+		Summary:   "List the federation peers and when their summaries were last fetched",
+		Response:  []api.FederationPeerStatus{},
+		Responses: map[int]string{200: "Peers", 404: "Federation is disabled"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/sectors",
+		Summary:   "List the subnets the operator named, whose names override the generated ones",
+		Response:  []api.Sector{},
+		Responses: map[int]string{200: "Sectors in address order"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/config",
+		Summary:   "Get the rules of the game, such as the prefix lengths of its subnet hierarchy and any supply lines rule",
+		Response:  api.GameConfig{},
+		Responses: map[int]string{200: "Game configuration"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/name-pack",
+		Summary:   "Get the word lists of the server's name pack, which clients generate subnet names with",
+		Response:  names.IPv6Names{},
+		Responses: map[int]string{200: "Name pack as canonical JSON", 304: "Pack matches If-None-Match", 404: "Server uses the built-in names"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/season",
+		Summary:   "Get the current season",
+		Response:  api.SeasonResponse{},
+		Responses: map[int]string{200: "Current season"},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/api/v1/artifacts",
+		Summary:     "List artifact addresses, which award bonus score while held",
+		QueryParams: []apiParam{{"subnet", "string", "Only list artifacts in this subnet (default: every claimed /48)"}},
+		Response:    []api.Artifact{},
+		Responses:   map[int]string{200: "Artifacts", 400: "Invalid subnet", 404: "Artifacts are disabled"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/events",
+		Summary:   "Stream claim events, and game events starting and ending, as server-sent events (text/event-stream of ClaimEvent)",
+		Responses: map[int]string{200: "Event stream"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/events/active",
+		Summary:   "List the game events running now, which multiply the points earned in their subnets",
+		Response:  []api.GameEvent{},
+		Responses: map[int]string{200: "Running game events, soonest started first"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/subscribe",
+		Summary:   "Watch rows of subnets over a WebSocket: send ViewportSubscription messages, receive ViewportUpdate messages with the claims on every watched row, then on the rows that change",
+		Responses: map[int]string{101: "Switched to a WebSocket", 400: "Not a WebSocket handshake"},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/admin/claims",
+		Summary:   "Dump every claim",
+		Response:  map[string]string{},
+		Responses: map[int]string{200: "Map of address to claimant", 401: "Missing or invalid token", 403: "Admin API disabled"},
+		Admin:     true,
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/api/v1/admin/claims/{ip}",
+		Summary:    "Get the claim on an address with where it came from: the channel, client address and user agent",
+		PathParams: []apiParam{{"ip", "string", "IPv6 address"}},
+		Response:   api.AdminClaimResponse{},
+		Responses: map[int]string{
+			200: "Claim and its source, omitted if not recorded",
+			400: "Invalid address",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+			404: "Address not claimed",
+		},
+		Admin: true,
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/admin/clients",
+		Summary:   "Count the current claims by the channel, client and client version that made them",
+		Response:  api.ClientsResponse{},
+		Responses: map[int]string{200: "Claims by client", 401: "Missing or invalid token", 403: "Admin API disabled", 404: "Store does not record claim sources"},
+		Admin:     true,
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/admin/anomalies",
+		Summary:   "List the recent anomalies found in claims, newest first, and the claimants penalized or quarantined for review",
+		Response:  api.AnomaliesResponse{},
+		Responses: map[int]string{200: "Anomalies and flagged claimants", 401: "Missing or invalid token", 403: "Admin API disabled", 404: "Anomaly detection is disabled"},
+		Admin:     true,
+	},
+	{
+		Method:     http.MethodDelete,
+		Path:       "/api/v1/admin/anomalies/claimants/{name}",
+		Summary:    "Lift the difficulty penalty or quarantine of a claimant after review",
+		PathParams: []apiParam{{"name", "string", "Claimant name"}},
+		Responses: map[int]string{
+			204: "Claimant released",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+			404: "Claimant is not flagged",
+		},
+		Admin: true,
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/admin/claim-queue",
+		Summary:   "Get the state of the claim worker pool",
+		Response:  api.ClaimQueueStats{},
+		Responses: map[int]string{200: "Worker pool state", 401: "Missing or invalid token", 403: "Admin API disabled", 404: "Worker pool is disabled"},
+		Admin:     true,
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/admin/store",
+		Summary:   "Get the size of the claim store against its limits",
+		Response:  api.StoreUsage{},
+		Responses: map[int]string{200: "Store usage", 401: "Missing or invalid token", 403: "Admin API disabled", 404: "Store does not report its usage"},
+		Admin:     true,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/admin/season/end",
+		Summary:  "End the current season, archiving its final state and resetting all claims",
+		Response: api.SeasonArchive{},
+		Responses: map[int]string{
+			200: "Archive of the ended season",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+			500: "Archiving or reset failed",
+		},
+		Admin: true,
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/api/v1/admin/sectors",
+		Summary:  "Name a subnet, overriding its generated name in API responses and clients",
+		Request:  api.Sector{},
+		Response: api.Sector{},
+		Responses: map[int]string{
+			200: "Sector as stored, with the subnet in canonical form",
+			400: "Invalid subnet, name or request body",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+		},
+		Admin: true,
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/api/v1/admin/sectors/{address}/{prefix}",
+		Summary: "Remove the name of a subnet, restoring its generated name",
+		PathParams: []apiParam{
+			{"address", "string", "Subnet address"},
+			{"prefix", "integer", "Prefix length"},
+		},
+		Responses: map[int]string{
+			204: "Sector name removed",
+			400: "Invalid subnet",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+			404: "Subnet has no sector name",
+		},
+		Admin: true,
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/api/v1/admin/events",
+		Summary:   "List the game events scheduled or running",
+		Response:  []api.GameEvent{},
+		Responses: map[int]string{200: "Game events not yet ended, soonest first", 401: "Missing or invalid token", 403: "Admin API disabled"},
+		Admin:     true,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/admin/events",
+		Summary:  "Schedule a game event multiplying the points earned in a subnet, announced on the event feed as it starts and ends",
+		Request:  api.GameEventRequest{},
+		Response: api.GameEvent{},
+		Responses: map[int]string{
+			201: "Game event as scheduled, with the subnet in canonical form",
+			400: "Invalid title, subnet, multiplier, start, duration or request body",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+		},
+		Admin: true,
+	},
+	{
+		Method:     http.MethodDelete,
+		Path:       "/api/v1/admin/events/{id}",
+		Summary:    "Cancel a game event, ending it if it is running",
+		PathParams: []apiParam{{"id", "string", "Game event ID"}},
+		Responses: map[int]string{
+			204: "Game event cancelled",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+			404: "Game event not found",
+		},
+		Admin: true,
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/api/v1/admin/import/rir",
+		Summary:     "Seed NPC factions by granting the IPv6 prefixes in an RIR delegation file sent as the request body",
+		QueryParams: []apiParam{{"factions", "string", "Group allocations into factions by registry, country or both (default)"}},
+		Response:    api.RIRImportResponse{},
+		Responses: map[int]string{
+			200: "Import summary",
+			400: "Invalid delegation file or faction grouping",
+			401: "Missing or invalid token",
+			403: "Admin API disabled",
+		},
+		Admin: true,
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/health",
+		Summary:   "Readiness check of startup, the database, claim queue and goroutine count, alias of /health/ready",
+		Response:  api.HealthResponse{},
+		Responses: map[int]string{200: "Server is ready", 503: "Claims are loading, shutdown began or a dependency check failed, listed in the body"},
+		ErrorBody: api.HealthResponse{},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/health/ready",
+		Summary:   "Readiness check of startup, the database, claim queue and goroutine count",
+		Response:  api.HealthResponse{},
+		Responses: map[int]string{200: "Server is ready", 503: "Claims are loading, shutdown began or a dependency check failed, listed in the body"},
+		ErrorBody: api.HealthResponse{},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/health/live",
+		Summary:   "Liveness check that does not check dependencies",
+		Response:  api.HealthResponse{},
+		Responses: map[int]string{200: "Server is running"},
+	},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+)
+
+// openAPISpec returns the OpenAPI document, generated once from apiOperations
+func openAPISpec() []byte {
+	openAPIOnce.Do(func() {
+		doc, err := json.Marshal(buildOpenAPISpec(apiOperations))
+		if err != nil {
+			panic("failed to encode OpenAPI document: " + err.Error())
+		}
+		openAPIDoc = doc
+	})
+	return openAPIDoc
+}
+
+// buildOpenAPISpec generates an OpenAPI 3 document for the given operations,
+// deriving schemas from the api package types by reflection
+func buildOpenAPISpec(operations []apiOperation) map[string]any {
+	schemas := make(map[string]any)
+	paths := make(map[string]any)
+
+	for _, op := range operations {
+		var params []any
+		for _, p := range op.PathParams {
+			params = append(params, map[string]any{
+				"name": p.Name, "in": "path", "required": true,
+				"description": p.Description, "schema": map[string]any{"type": p.Type},
+			})
+		}
+		for _, p := range op.QueryParams {
+			params = append(params, map[string]any{
+				"name": p.Name, "in": "query",
+				"description": p.Description, "schema": map[string]any{"type": p.Type},
+			})
+		}
+
+		responses := make(map[string]any)
+		for status, description := range op.Responses {
+			response := map[string]any{"description": description}
+			if status/100 == 2 && op.Response != nil {
+				response["content"] = jsonContent(schemaFor(reflect.TypeOf(op.Response), schemas))
+			}
+			if status >= 400 {
+				errorBody := op.ErrorBody
+				if errorBody == nil {
+					errorBody = api.ErrorResponse{}
+				}
+				response["content"] = jsonContent(schemaFor(reflect.TypeOf(errorBody), schemas))
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+
+		operation := map[string]any{
+			"summary":   op.Summary,
+			"responses": responses,
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaFor(reflect.TypeOf(op.Request), schemas)),
+			}
+		}
+		if op.Admin {
+			operation["security"] = []any{map[string]any{"adminToken": []any{}}}
+		}
+		if op.Delegated {
+			operation["security"] = []any{map[string]any{"delegationToken": []any{}}}
+		}
+
+		pathItem, ok := paths[op.Path].(map[string]any)
+		if !ok {
+			pathItem = make(map[string]any)
+			paths[op.Path] = pathItem
+		}
+		pathItem[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title": "SpaceNet API",
+			"description": "A space-themed network control game where players claim IPv6 addresses. " +
+				"Unversioned /api paths are deprecated aliases of /api/v1.",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"adminToken":      map[string]any{"type": "http", "scheme": "bearer"},
+				"delegationToken": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// jsonContent wraps a schema as an application/json media type
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemaFor returns the JSON schema for a Go type, registering named structs as components
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), schemas)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, exists := schemas[t.Name()]; exists {
+			return ref
+		}
+		// Register before recursing so self-referencing types terminate
+		schemas[t.Name()] = nil
+
+		properties := make(map[string]any)
+		var required []string
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaFor(field.Type, schemas)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		schemas[t.Name()] = schema
+		return ref
+	default:
+		return map[string]any{}
+	}
+}
+
+// swaggerUIDist is the exact swagger-ui-dist release the docs page loads.
+// Published npm versions are immutable, so pinning one keeps the CDN from
+// serving a different release from the API origin.
+const swaggerUIDist = "https://unpkg.com/swagger-ui-dist@5.17.14"
+
+// swaggerUIPage renders the Swagger UI from a CDN against the served OpenAPI document
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="referrer" content="no-referrer">
+  <title>SpaceNet API</title>
+  <link rel="stylesheet" href="` + swaggerUIDist + `/swagger-ui.css" crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="` + swaggerUIDist + `/swagger-ui-bundle.js" crossorigin="anonymous"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// handleOpenAPI serves the OpenAPI document
+func (h *HTTPHandler) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPISpec()); err != nil {
+		h.logger.Error("Error writing OpenAPI document", "error", err)
+	}
+}
+
+// handleDocs serves the Swagger UI
+func (h *HTTPHandler) handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(swaggerUIPage)); err != nil {
+		h.logger.Error("Error writing API docs page", "error", err)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPISpec_DocumentsAllRoutes tests that every API route appears in the OpenAPI document
+func TestOpenAPISpec_DocumentsAllRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(openAPISpec(), &spec), "OpenAPI document should be valid JSON")
+	assert.Equal(t, "3.0.3", spec.OpenAPI, "Document should be OpenAPI 3")
+
+	router := mux.NewRouter()
+	NewHTTPHandler(NewClaimStore()).RegisterRoutes(router)
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		if path == "/api/openapi.json" || path == "/api/docs" || path == apiPrefix || path == legacyAPIPrefix ||
+			!strings.HasPrefix(path, "/api") && path != "/health" {
+			return nil
+		}
+		// Deprecated aliases are documented under the current version
+		if !strings.HasPrefix(path, apiPrefix) && strings.HasPrefix(path, legacyAPIPrefix) {
+			path = apiPrefix + strings.TrimPrefix(path, legacyAPIPrefix)
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			_, documented := spec.Paths[path][strings.ToLower(method)]
+			assert.True(t, documented, "Route %s %s should be documented", method, path)
+		}
+		return nil
+	})
+	require.NoError(t, err, "Walking routes should succeed")
+}
+
+// TestHandleDocs tests that the docs page loads an exact release of the
+// Swagger UI, not whichever one the CDN serves for a major version
+func TestHandleDocs(t *testing.T) {
+	router := mux.NewRouter()
+	NewHTTPHandler(NewClaimStore()).RegisterRoutes(router)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	page := rr.Body.String()
+	assert.Regexp(t, `swagger-ui-dist@\d+\.\d+\.\d+/`, page)
+	assert.NotRegexp(t, `swagger-ui-dist@\d+/`, page, "Assets should not float with the major version")
+	assert.Equal(t, 2, strings.Count(page, `crossorigin="anonymous"`), "Assets should be fetched without credentials")
+}
+
+// TestCORSMiddleware tests CORS headers and preflight handling
+func TestCORSMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	NewHTTPHandler(NewClaimStore()).RegisterRoutes(router)
+	handler := corsMiddleware(CORSConfig{AllowedOrigins: []string{"https://spacenet.example"}}, router)
+
+	// Preflight from an allowed origin
+	req := httptest.NewRequest(http.MethodOptions, "/api/claim/2001:db8::1", nil)
+	req.Header.Set("Origin", "https://spacenet.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code, "Preflight should succeed")
+	assert.Equal(t, "https://spacenet.example", rec.Header().Get("Access-Control-Allow-Origin"), "Origin should be allowed")
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), http.MethodPost, "POST should be allowed")
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "If-None-Match", "Conditional requests should be allowed")
+
+	// Simple request from a disallowed origin
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code, "Request should still be served")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"), "Disallowed origin should get no CORS headers")
+}
+
+// TestAPIVersioning tests that unversioned paths are deprecated aliases of /api/v1
+func TestAPIVersioning(t *testing.T) {
+	router := mux.NewRouter()
+	NewHTTPHandler(NewClaimStore()).RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "Versioned path should be served")
+	assert.Empty(t, rec.Header().Get("Deprecation"), "Versioned path should not be deprecated")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "Legacy path should still be served")
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"), "Legacy path should be deprecated")
+	assert.Contains(t, rec.Header().Get("Warning"), "/api/v1/stats", "Warning should name the successor path")
+	assert.Equal(t, `</api/v1/stats>; rel="successor-version"`, rec.Header().Get("Link"), "Link should point at the successor")
+}
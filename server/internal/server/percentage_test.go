@@ -64,7 +64,7 @@ func TestIPTree_PercentageCalculationAccuracy(t *testing.T) {
 			}
 
 			// Get stats
-			stats, ok := testTree.GetSubnetStats(tc.subnet)
+			stats, ok := testTree.GetSubnetStats(tc.subnet, 0)
 			require.True(t, ok, "Should be able to get stats for %s", tc.subnet)
 
 			// Check percentage bounds
@@ -96,7 +96,7 @@ func TestIPTree_PercentageWithDuplicates(t *testing.T) {
 	tree.processClaim(testIP, testUser, "")
 
 	// Get initial percentage
-	stats, ok := tree.GetSubnetStats("2001:db8::1/128")
+	stats, ok := tree.GetSubnetStats("2001:db8::1/128", 0)
 	require.True(t, ok, "Should get stats after initial claim")
 	initialPercentage := stats.Percentage
 
@@ -104,7 +104,7 @@ func TestIPTree_PercentageWithDuplicates(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		tree.processClaim(testIP, testUser, testUser) // Duplicate claim
 
-		stats, ok = tree.GetSubnetStats("2001:db8::1/128")
+		stats, ok = tree.GetSubnetStats("2001:db8::1/128", 0)
 		require.True(t, ok, "Should get stats after duplicate %d", i+1)
 
 		// Percentage should remain exactly the same
@@ -140,7 +140,7 @@ func TestIPTree_PercentageConsistency(t *testing.T) {
 	}
 
 	for _, subnet := range subnets {
-		stats, ok := tree.GetSubnetStats(subnet)
+		stats, ok := tree.GetSubnetStats(subnet, 0)
 		require.True(t, ok, "Should get stats for %s", subnet)
 
 		// All percentages should be reasonable
@@ -179,7 +179,7 @@ func TestIPTree_LargeScalePercentageAccuracy(t *testing.T) {
 	}
 
 	for _, subnet := range subnets {
-		stats, ok := tree.GetSubnetStats(subnet)
+		stats, ok := tree.GetSubnetStats(subnet, 0)
 		require.True(t, ok, "Should get stats for %s", subnet)
 
 		// Percentage should never exceed 100%
@@ -256,7 +256,7 @@ func TestIPTree_EdgeCasePercentages(t *testing.T) {
 			tc.claims(tree)
 
 			// Check resulting stats
-			stats, ok := tree.GetSubnetStats(tc.subnet)
+			stats, ok := tree.GetSubnetStats(tc.subnet, 0)
 			require.True(t, ok, "Should get stats for %s", tc.subnet)
 
 			// Critical checks
@@ -293,24 +293,55 @@ func TestClaimStore_PercentageAccuracyWithSQLite(t *testing.T) {
 	}
 
 	for _, ip := range claims {
-		err := store.ProcessClaim(ip, testUser)
+		err := store.ProcessClaim(t.Context(), ip, testUser)
 		require.NoError(t, err, "Claim should succeed")
 
 		// Make duplicate of each claim to test the fix
-		err = store.ProcessClaim(ip, testUser)
+		err = store.ProcessClaim(t.Context(), ip, testUser)
 		require.NoError(t, err, "Duplicate should not error")
 	}
 
 	// Check subnet stats
-	stats, ok := store.GetSubnetStats("2001:db8::/112")
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/112", 0)
 	require.True(t, ok, "Should get subnet stats")
 
 	assert.LessOrEqual(t, stats.Percentage, 100.0, "Percentage should not exceed 100% with SQLite")
 	assert.GreaterOrEqual(t, stats.Percentage, 0.0, "Percentage should be non-negative with SQLite")
 
 	// Verify total claims count is still correct (not inflated by duplicates)
-	allClaims := store.GetAllClaims()
+	allClaims := store.GetAllClaims(t.Context())
 	assert.Len(t, allClaims, len(claims), "Should have correct number of unique claims")
 
 	t.Logf("SQLite store - Subnet stats: Owner=%s, Percentage=%.6f%%", stats.Owner, stats.Percentage)
 }
+
+// TestIPTree_TopClaimants tests the per-claimant breakdown in subnet statistics
+func TestIPTree_TopClaimants(t *testing.T) {
+	tree := NewIPTree()
+
+	claims := []struct{ ip, user string }{
+		{"2001:db8::1", "user1"},
+		{"2001:db8::2", "user2"},
+		{"2001:db8::3", "user1"},
+		{"2001:db8::4", "user3"},
+		{"2001:db8::5", "user2"},
+		{"2001:db8::6", "user1"},
+	}
+	for _, claim := range claims {
+		tree.processClaim(claim.ip, claim.user, "")
+	}
+
+	// No breakdown unless requested
+	stats, ok := tree.GetSubnetStats("2001:db8::/120", 0)
+	require.True(t, ok, "Should get stats for subnet")
+	assert.Empty(t, stats.AllClaimants, "Breakdown should be omitted by default")
+
+	// Top claimants are ranked by share, limited to N
+	stats, ok = tree.GetSubnetStats("2001:db8::/112", 2)
+	require.True(t, ok, "Should get stats for subnet")
+	require.Len(t, stats.AllClaimants, 2, "Breakdown should be limited to top 2")
+	assert.Equal(t, "user1", stats.AllClaimants[0].Name, "Largest claimant should be first")
+	assert.Equal(t, "user2", stats.AllClaimants[1].Name, "Second largest claimant should be second")
+	assert.InDelta(t, 3.0/65536*100, stats.AllClaimants[0].Percentage, 1e-9, "Percentage should be share of the subnet")
+	assert.Empty(t, stats.Owner, "No claimant holds a majority of the /112")
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+)
+
+// registerPprofRoutes exposes the runtime profiling endpoints under /debug/pprof.
+// They reveal internals and can be expensive, so they are only enabled on request.
+func registerPprofRoutes(router *mux.Router) {
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// Index also serves the named profiles, such as heap and goroutine
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+}
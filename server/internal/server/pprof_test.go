@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHTTPHandler_Pprof tests that profiling endpoints are only served when enabled
+func TestHTTPHandler_Pprof(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		handler := NewHTTPHandler(NewClaimStore())
+		handler.pprof = enabled
+		router := mux.NewRouter()
+		handler.RegisterRoutes(router)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+
+		isProfile := rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "goroutine profile")
+		assert.Equal(t, enabled, isProfile, "Profiles should only be served when pprof is enabled (enabled=%v)", enabled)
+	}
+}
@@ -1,43 +1,165 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net"
+	"time"
 
 	"github.com/bjia56/spacenet/server/api"
 )
 
+// DifficultyParams controls how the required proof of work difficulty is calculated
+type DifficultyParams struct {
+	Base            int `yaml:"base"`            // Base difficulty (leading zero bits)
+	ClaimBonus      int `yaml:"claimBonus"`      // Additional difficulty if address is already claimed
+	MaxContiguity   int `yaml:"maxContiguity"`   // Maximum contiguous addresses to consider
+	ContiguityBonus int `yaml:"contiguityBonus"` // Additional difficulty per contiguous address
+	Max             int `yaml:"max"`             // Cap on the required difficulty
+}
+
+// DefaultDifficultyParams returns the standard difficulty parameters
+func DefaultDifficultyParams() DifficultyParams {
+	return DifficultyParams{
+		Base:            8,
+		ClaimBonus:      4,
+		MaxContiguity:   16,
+		ContiguityBonus: 2,
+		Max:             20,
+	}
+}
+
+// Validate checks that the difficulty parameters are usable
+func (p DifficultyParams) Validate() error {
+	if p.Base < 0 || p.ClaimBonus < 0 || p.MaxContiguity < 0 || p.ContiguityBonus < 0 {
+		return fmt.Errorf("difficulty parameters must not be negative")
+	}
+	if p.Max < p.Base || p.Max > 255 {
+		return fmt.Errorf("difficulty max must be between base (%d) and 255, got %d", p.Base, p.Max)
+	}
+	return nil
+}
+
+// Supported proof of work schemes
+const (
+	PoWSchemeSHA256   = api.SchemeSHA256
+	PoWSchemeArgon2id = api.SchemeArgon2id
+)
+
+// maxArgon2Difficulty caps the difficulty of argon2id proofs of work, whose
+// hashes take milliseconds rather than nanoseconds
+const maxArgon2Difficulty = 16
+
+// PoWOptions selects the hash function clients solve proofs of work with
+type PoWOptions struct {
+	Scheme string        `yaml:"scheme"` // "sha256" or "argon2id"
+	Argon2 Argon2Options `yaml:"argon2"`
+}
+
+// Argon2Options are the cost parameters of the argon2id scheme
+type Argon2Options struct {
+	Time      int `yaml:"time"`      // Passes over memory
+	MemoryKiB int `yaml:"memoryKiB"` // Memory per hash
+	Threads   int `yaml:"threads"`   // Parallel lanes per hash
+}
+
+// DefaultPoWOptions returns the default SHA-256 proof of work, with argon2id
+// parameters that take a few milliseconds per hash on a laptop
+func DefaultPoWOptions() PoWOptions {
+	return PoWOptions{
+		Scheme: PoWSchemeSHA256,
+		Argon2: Argon2Options{
+			Time:      1,
+			MemoryKiB: 16 * 1024,
+			Threads:   1,
+		},
+	}
+}
+
+// NewPoWScheme returns the proof of work scheme selected by the options
+func NewPoWScheme(opts PoWOptions) (api.PoWScheme, error) {
+	switch opts.Scheme {
+	case PoWSchemeSHA256, "":
+		return api.SHA256Scheme{}, nil
+	case PoWSchemeArgon2id:
+		a := opts.Argon2
+		if a.Time <= 0 || a.MemoryKiB <= 0 || a.Threads <= 0 || a.Threads > 255 || a.Time > math.MaxUint32 || a.MemoryKiB > math.MaxUint32 {
+			return nil, fmt.Errorf("argon2 time, memoryKiB and threads must be positive, with at most 255 threads")
+		}
+		scheme := api.Argon2idScheme{Time: uint32(a.Time), MemoryKiB: uint32(a.MemoryKiB), Threads: uint8(a.Threads)}
+		if err := scheme.Validate(); err != nil {
+			return nil, err
+		}
+		return scheme, nil
+	}
+	return nil, fmt.Errorf("unknown proof of work scheme %q", opts.Scheme)
+}
+
+// SetPoWScheme replaces the hash function proofs of work are validated with
+func (store *ClaimStore) SetPoWScheme(scheme api.PoWScheme) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.powScheme = scheme
+}
+
+// PoWScheme returns the hash function proofs of work are validated with
+func (store *ClaimStore) PoWScheme(ctx context.Context) api.PoWScheme {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.powScheme
+}
+
+// SetDifficultyParams replaces the parameters used to calculate difficulty
+func (store *ClaimStore) SetDifficultyParams(params DifficultyParams) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.difficulty = params
+}
+
 // CalculateDifficulty determines the required difficulty for claiming an address
-func (store *ClaimStore) CalculateDifficulty(targetIP string) uint8 {
-	const (
-		baseDifficulty  = 8  // Base difficulty (8 leading zero bits)
-		claimBonus      = 4  // Additional difficulty if address is already claimed
-		maxContiguity   = 16 // Maximum contiguous addresses to consider
-		contiguityBonus = 2  // Additional difficulty per contiguous address
-	)
+func (store *ClaimStore) CalculateDifficulty(ctx context.Context, targetIP string) uint8 {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.difficultyLocked(targetIP, time.Now().UTC())
+}
 
-	difficulty := baseDifficulty
+// difficultyLocked determines the required difficulty for claiming an
+// address at a time (assumes lock is held)
+func (store *ClaimStore) difficultyLocked(targetIP string, now time.Time) uint8 {
+	params := store.difficulty
+	difficulty := params.Base
 
 	// Check if address is already claimed
-	store.mutex.RLock()
 	currentClaimant, exists := store.claims[targetIP]
-	store.mutex.RUnlock()
+	fortified := store.fortificationBonusLocked(targetIP, now)
+	heated := store.heatBonusLocked(targetIP, now)
 
 	if exists {
-		difficulty += claimBonus
+		difficulty += params.ClaimBonus
 
 		// Calculate contiguous addresses owned by current claimant
-		contiguous := min(store.countContiguousAddresses(targetIP, currentClaimant), maxContiguity)
+		contiguous := min(store.countContiguousAddresses(targetIP, currentClaimant), params.MaxContiguity)
 
-		difficulty += contiguous * contiguityBonus
+		difficulty += contiguous * params.ContiguityBonus
 	}
 
 	// Cap difficulty at reasonable maximum
-	if difficulty > 20 {
-		difficulty = 20
+	if difficulty > params.Max {
+		difficulty = params.Max
 	}
 
-	return uint8(difficulty)
+	// Fortification and heat are added past the cap, so they still count on
+	// the most contested addresses. Unclaimed addresses keep their heat for
+	// when they are claimed again, but it is only paid to take them over.
+	if !exists {
+		heated = 0
+	}
+	return uint8(min(difficulty+fortified+heated, 255))
 }
 
 // countContiguousAddresses counts how many addresses contiguous to the target
@@ -87,10 +209,11 @@ func (store *ClaimStore) countContiguousAddresses(targetIP string, claimant stri
 }
 
 // ValidateProofOfWork validates a proof of work submission
-func (store *ClaimStore) ValidateProofOfWork(pow *api.ProofOfWork) error {
-	// Get current difficulty for the target address
-	requiredDifficulty := store.CalculateDifficulty(pow.Target.String())
-	if !pow.IsValid(requiredDifficulty) {
+func (store *ClaimStore) ValidateProofOfWork(ctx context.Context, pow *api.ProofOfWork) error {
+	// Get current difficulty for the target address, raised for claimants flagged by anomaly detection
+	requiredDifficulty := store.CalculateDifficulty(ctx, pow.Target.String())
+	requiredDifficulty = uint8(min(int(requiredDifficulty)+store.DifficultyPenalty(pow.Name), 255))
+	if !pow.IsValidFor(store.PoWScheme(ctx), requiredDifficulty) {
 		return fmt.Errorf("invalid proof of work: insufficient difficulty")
 	}
 
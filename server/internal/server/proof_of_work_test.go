@@ -1,10 +1,19 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
 )
 
 func TestProofOfWork_IsValid(t *testing.T) {
@@ -36,20 +45,20 @@ func TestCalculateDifficulty(t *testing.T) {
 	store := NewClaimStore()
 
 	// Test unclaimed address
-	difficulty := store.CalculateDifficulty("2001:db8::1")
+	difficulty := store.CalculateDifficulty(t.Context(), "2001:db8::1")
 	expected := uint8(8) // Base difficulty
 	if difficulty != expected {
 		t.Errorf("Expected difficulty %d for unclaimed address, got %d", expected, difficulty)
 	}
 
 	// Claim the address
-	err := store.ProcessClaim("2001:db8::1", "alice")
+	err := store.ProcessClaim(t.Context(), "2001:db8::1", "alice")
 	if err != nil {
 		t.Fatalf("Failed to process claim: %v", err)
 	}
 
 	// Test claimed address
-	difficulty = store.CalculateDifficulty("2001:db8::1")
+	difficulty = store.CalculateDifficulty(t.Context(), "2001:db8::1")
 	expected = uint8(12) // Base (8) + claim bonus (4)
 	if difficulty != expected {
 		t.Errorf("Expected difficulty %d for claimed address, got %d", expected, difficulty)
@@ -58,24 +67,24 @@ func TestCalculateDifficulty(t *testing.T) {
 	// Claim contiguous addresses in the same /124 block
 	// 2001:db8::1 is in the /124 block 2001:db8::/124
 	// Let's claim a few more addresses in this block
-	err = store.ProcessClaim("2001:db8::2", "alice")
+	err = store.ProcessClaim(t.Context(), "2001:db8::2", "alice")
 	if err != nil {
 		t.Fatalf("Failed to process claim: %v", err)
 	}
-	err = store.ProcessClaim("2001:db8::3", "alice")
+	err = store.ProcessClaim(t.Context(), "2001:db8::3", "alice")
 	if err != nil {
 		t.Fatalf("Failed to process claim: %v", err)
 	}
 
 	// Check difficulty for another address in the same block
-	difficulty = store.CalculateDifficulty("2001:db8::4")
+	difficulty = store.CalculateDifficulty(t.Context(), "2001:db8::4")
 	expected = uint8(8) // Base difficulty (not claimed yet)
 	if difficulty != expected {
 		t.Errorf("Expected difficulty %d for unclaimed address in block with contiguous claims, got %d", expected, difficulty)
 	}
 
 	// Check difficulty for claiming an address owned by someone with contiguous claims
-	difficulty = store.CalculateDifficulty("2001:db8::1")
+	difficulty = store.CalculateDifficulty(t.Context(), "2001:db8::1")
 	// Base (8) + claim bonus (4) + contiguous bonus (2 * 2 contiguous addresses)
 	expected = uint8(16)
 	if difficulty != expected {
@@ -88,14 +97,14 @@ func TestValidateProofOfWork(t *testing.T) {
 	target := net.ParseIP("2001:db8::1")
 
 	// Create a valid proof of work
-	requiredDifficulty := store.CalculateDifficulty(target.String())
+	requiredDifficulty := store.CalculateDifficulty(t.Context(), target.String())
 	validPow, err := api.SolveProofOfWork(target, "alice", requiredDifficulty, 1000000)
 	if err != nil {
 		t.Fatalf("Failed to solve proof of work: %v", err)
 	}
 
 	// Should be valid
-	if err := store.ValidateProofOfWork(validPow); err != nil {
+	if err := store.ValidateProofOfWork(t.Context(), validPow); err != nil {
 		t.Errorf("Valid proof of work should pass validation: %v", err)
 	}
 
@@ -105,7 +114,200 @@ func TestValidateProofOfWork(t *testing.T) {
 		t.Fatalf("Failed to solve proof of work: %v", err)
 	}
 
-	if err := store.ValidateProofOfWork(invalidPow); err == nil {
+	if err := store.ValidateProofOfWork(t.Context(), invalidPow); err == nil {
 		t.Error("Proof of work with insufficient difficulty should fail validation")
 	}
 }
+
+func TestCalculateSubnetDifficulty(t *testing.T) {
+	store := NewClaimStore()
+
+	for _, ip := range []string{"2001:db8::1", "2001:db8::2", "2001:db8::3", "2001:db8::12"} {
+		if err := store.ProcessClaim(t.Context(), ip, "alice"); err != nil {
+			t.Fatalf("Failed to process claim: %v", err)
+		}
+	}
+	if err := store.ProcessClaim(t.Context(), "2001:db8::13", "bob"); err != nil {
+		t.Fatalf("Failed to process claim: %v", err)
+	}
+
+	// Small subnets list every address, matching the per-address calculation
+	response, ok := store.CalculateSubnetDifficulty(t.Context(), "2001:db8::/120")
+	if !ok {
+		t.Fatal("Expected subnet difficulty for valid subnet")
+	}
+	if len(response.Addresses) != 256 {
+		t.Fatalf("Expected 256 addresses, got %d", len(response.Addresses))
+	}
+	for _, entry := range response.Addresses {
+		expected := store.CalculateDifficulty(t.Context(), entry.Address)
+		if entry.Difficulty != expected {
+			t.Errorf("Expected difficulty %d for %s, got %d", expected, entry.Address, entry.Difficulty)
+		}
+	}
+
+	// Large subnets are summarized as a histogram
+	response, ok = store.CalculateSubnetDifficulty(t.Context(), "2001:db8::/64")
+	if !ok {
+		t.Fatal("Expected subnet difficulty for valid subnet")
+	}
+	if len(response.Addresses) != 0 {
+		t.Errorf("Expected no per-address entries for /64, got %d", len(response.Addresses))
+	}
+	expected := []api.DifficultyBucket{
+		{Difficulty: 8, Count: "18446744073709551611"},
+		{Difficulty: 12, Count: "2"},
+		{Difficulty: 16, Count: "3"},
+	}
+	if len(response.Histogram) != len(expected) {
+		t.Fatalf("Expected histogram %v, got %v", expected, response.Histogram)
+	}
+	for i := range expected {
+		if response.Histogram[i] != expected[i] {
+			t.Errorf("Expected bucket %v, got %v", expected[i], response.Histogram[i])
+		}
+	}
+
+	// Invalid subnets are rejected
+	if _, ok := store.CalculateSubnetDifficulty(t.Context(), "invalid"); ok {
+		t.Error("Expected invalid subnet to be rejected")
+	}
+}
+
+func TestSolveProofOfWork_LongName(t *testing.T) {
+	// Names spanning full SHA-256 blocks are solved from a precomputed prefix state
+	for _, name := range []string{"alice", strings.Repeat("a", 48), strings.Repeat("ü", 60)} {
+		pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::1"), name, 10, 1000000)
+		if err != nil {
+			t.Fatalf("Failed to solve proof of work for a %d byte name: %v", len(name), err)
+		}
+		if !pow.IsValid(10) {
+			t.Errorf("Solution for a %d byte name should be valid", len(name))
+		}
+	}
+}
+
+func TestSolveProofOfWorkParallel(t *testing.T) {
+	target := net.ParseIP("2001:db8::1")
+	pow, stats, err := api.SolveProofOfWorkParallel(target, strings.Repeat("bob", 30), 12, 10000000, 4)
+	if err != nil {
+		t.Fatalf("Failed to solve proof of work: %v", err)
+	}
+	if !pow.IsValid(12) {
+		t.Error("Parallel solution should be valid")
+	}
+	if stats.Hashes == 0 || stats.HashRate() <= 0 {
+		t.Errorf("Expected hashes to be counted, got %+v", stats)
+	}
+
+	// Exhausting the attempts is an error
+	if _, stats, err := api.SolveProofOfWorkParallel(target, "alice", 255, 1000, 3); err == nil {
+		t.Error("Expected impossible proof of work to fail")
+	} else if stats.Hashes != 1000 {
+		t.Errorf("Expected every attempt to be tried once, got %d hashes", stats.Hashes)
+	}
+}
+
+func TestSolveProofOfWorkContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Difficulty 255 is never met, so only cancelling ends the search early
+	start := time.Now()
+	_, stats, err := api.SolveProofOfWorkContext(ctx, api.SHA256Scheme{}, net.ParseIP("2001:db8::1"), "alice", 255, math.MaxUint64, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancelled proof of work to fail with context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Cancelled proof of work should stop promptly, took %s", elapsed)
+	}
+	if stats.Hashes == 0 {
+		t.Error("Expected the hashes tried before cancelling to be counted")
+	}
+}
+
+func BenchmarkSolveProofOfWork(b *testing.B) {
+	target := net.ParseIP("2001:db8::1")
+	b.ReportAllocs()
+	for b.Loop() {
+		// Difficulty 255 is never met, so every attempt is hashed
+		_, _ = api.SolveProofOfWork(target, "alice", 255, 1000)
+	}
+}
+
+func TestValidateProofOfWork_Argon2id(t *testing.T) {
+	store := NewClaimStore()
+	store.SetDifficultyParams(DifficultyParams{Base: 4, Max: 4})
+	scheme := api.Argon2idScheme{Time: 1, MemoryKiB: 64, Threads: 1}
+	store.SetPoWScheme(scheme)
+	target := net.ParseIP("2001:db8::1")
+
+	pow, _, err := api.SolveProofOfWorkWith(scheme, target, "alice", 4, 100000, 2)
+	if err != nil {
+		t.Fatalf("Failed to solve argon2id proof of work: %v", err)
+	}
+	if err := store.ValidateProofOfWork(t.Context(), pow); err != nil {
+		t.Errorf("Argon2id proof of work should pass validation: %v", err)
+	}
+
+	// A SHA-256 solution is not an argon2id solution
+	shaPow, err := api.SolveProofOfWork(target, "alice", 4, 100000)
+	if err != nil {
+		t.Fatalf("Failed to solve proof of work: %v", err)
+	}
+	if err := store.ValidateProofOfWork(t.Context(), shaPow); err == nil {
+		t.Error("SHA-256 proof of work should fail argon2id validation")
+	}
+}
+
+func TestHTTPHandler_Challenge(t *testing.T) {
+	store := NewClaimStore()
+	scheme := api.Argon2idScheme{Time: 2, MemoryKiB: 1024, Threads: 1}
+	store.SetPoWScheme(scheme)
+
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/challenge/2001:db8::1", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var challenge api.PoWChallenge
+	if err := json.NewDecoder(rr.Body).Decode(&challenge); err != nil {
+		t.Fatalf("Failed to decode challenge: %v", err)
+	}
+	if challenge.Scheme != api.SchemeArgon2id || challenge.Difficulty != store.CalculateDifficulty(t.Context(), "2001:db8::1") {
+		t.Errorf("Unexpected challenge %+v", challenge)
+	}
+	if negotiated, err := challenge.PoWScheme(); err != nil || negotiated != scheme {
+		t.Errorf("Expected challenge to negotiate %+v, got %+v (%v)", scheme, negotiated, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/challenge/invalid", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid address, got %d", rr.Code)
+	}
+}
+
+func TestNewPoWScheme(t *testing.T) {
+	if scheme, err := NewPoWScheme(PoWOptions{}); err != nil || scheme.Name() != api.SchemeSHA256 {
+		t.Errorf("Expected SHA-256 by default, got %v (%v)", scheme, err)
+	}
+
+	opts := DefaultPoWOptions()
+	opts.Scheme = PoWSchemeArgon2id
+	if scheme, err := NewPoWScheme(opts); err != nil || scheme.Name() != api.SchemeArgon2id {
+		t.Errorf("Expected argon2id, got %v (%v)", scheme, err)
+	}
+
+	opts.Argon2.Threads = 300
+	if _, err := NewPoWScheme(opts); err == nil {
+		t.Error("Expected too many argon2id threads to be rejected")
+	}
+}
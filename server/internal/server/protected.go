@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"net"
+)
+
+// ParseProtectedPrefixes parses the prefixes nobody may claim addresses in,
+// such as the server operator's own networks
+func ParseProtectedPrefixes(prefixes []string) ([]*net.IPNet, error) {
+	protected := make([]*net.IPNet, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil || ipNet.IP.To4() != nil {
+			return nil, fmt.Errorf("protected prefixes must be IPv6 CIDRs, got %q", prefix)
+		}
+		protected = append(protected, ipNet)
+	}
+	return protected, nil
+}
+
+// SetProtectedPrefixes replaces the prefixes nobody may claim addresses in.
+// Claims already in them are kept until released.
+func (cs *ClaimStore) SetProtectedPrefixes(prefixes []*net.IPNet) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.protected = prefixes
+}
+
+// checkClaimableLocked refuses claims on unparsable addresses and on
+// addresses in protected prefixes. The caller must hold the lock.
+func (cs *ClaimStore) checkClaimableLocked(ipAddr string) error {
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return ErrInvalidAddress
+	}
+	for _, prefix := range cs.protected {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w by %s", ErrProtected, prefix)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_ProtectedPrefixes tests that addresses in protected prefixes cannot be claimed
+func TestClaimStore_ProtectedPrefixes(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:ffff::1", "alice"))
+
+	protected, err := ParseProtectedPrefixes([]string{"2001:db8:ffff::/48"})
+	require.NoError(t, err)
+	store.SetProtectedPrefixes(protected)
+
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8:ffff::2", "bob"), ErrProtected)
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "not-an-address", "bob"), ErrInvalidAddress)
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"), "Addresses outside protected prefixes should be claimable")
+
+	err = store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::2", Claimant: "bob"},
+		{IP: "2001:db8:ffff::3", Claimant: "bob"},
+	})
+	assert.ErrorIs(t, err, ErrProtected, "A protected address should reject the batch")
+	_, exists := store.GetClaim(t.Context(), "2001:db8::2")
+	assert.False(t, exists, "Rejected batches should not be applied")
+
+	claimant, _ := store.GetClaim(t.Context(), "2001:db8:ffff::1")
+	assert.Equal(t, "alice", claimant, "Claims made before protection should be kept")
+	assert.NoError(t, store.Unclaim(t.Context(), "2001:db8:ffff::1", "alice"), "Protected claims should still be releasable")
+}
+
+// TestParseProtectedPrefixes tests that only IPv6 CIDRs are accepted
+func TestParseProtectedPrefixes(t *testing.T) {
+	protected, err := ParseProtectedPrefixes([]string{"2001:db8::/32", "fd00::1/8"})
+	require.NoError(t, err)
+	require.Len(t, protected, 2)
+	assert.Equal(t, "fd00::/8", protected[1].String(), "Prefixes should be masked")
+
+	for _, bad := range []string{"2001:db8::", "192.0.2.0/24", "nonsense"} {
+		_, err := ParseProtectedPrefixes([]string{bad})
+		assert.Error(t, err, "Prefix %q should be rejected", bad)
+	}
+}
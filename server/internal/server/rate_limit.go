@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiterIdleTimeout is how long an idle client's bucket is kept before pruning
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// RateLimiter implements per-client token bucket rate limiting
+type RateLimiter struct {
+	mutex     sync.Mutex
+	rate      float64 // Tokens added per second
+	burst     float64 // Maximum tokens in a bucket
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// tokenBucket tracks the available tokens for a single client
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing perMinute requests per client
+// with bursts of up to burst requests. A burst of zero defaults to perMinute.
+func NewRateLimiter(perMinute int, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = perMinute
+	}
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether a request from the given client may proceed, consuming a token if so
+func (rl *RateLimiter) Allow(client string) bool {
+	return rl.AllowN(client, 1)
+}
+
+// AllowN reports whether n requests from the given client may proceed at
+// once, consuming n tokens if so. Batches larger than the burst never proceed.
+func (rl *RateLimiter) AllowN(client string, n int) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	rl.pruneLocked(now)
+
+	bucket, exists := rl.buckets[client]
+	if !exists {
+		bucket = &tokenBucket{tokens: rl.burst, lastSeen: now}
+		rl.buckets[client] = bucket
+	}
+
+	// Refill tokens for the time elapsed since the last request
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = min(bucket.tokens+elapsed*rl.rate, rl.burst)
+	bucket.lastSeen = now
+
+	if bucket.tokens < float64(n) {
+		return false
+	}
+	bucket.tokens -= float64(n)
+	return true
+}
+
+// pruneLocked removes buckets for clients that have been idle (assumes lock is held)
+func (rl *RateLimiter) pruneLocked(now time.Time) {
+	if now.Sub(rl.lastPrune) < time.Minute {
+		return
+	}
+	rl.lastPrune = now
+
+	for client, bucket := range rl.buckets {
+		if now.Sub(bucket.lastSeen) > rateLimiterIdleTimeout {
+			delete(rl.buckets, client)
+		}
+	}
+}
+
+// Middleware wraps a handler, rejecting requests over the limit with 429 Too Many Requests
+func (rl *RateLimiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rl.Allow(clientAddress(r)) {
+			writeError(w, r, ErrRateLimited)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientAddress returns the address of the client making a request
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
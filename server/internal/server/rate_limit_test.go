@@ -0,0 +1,37 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRateLimiter_Allow tests token bucket refill and per-client isolation
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(60, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow("client1"), "First request should be allowed")
+	assert.True(t, limiter.Allow("client1"), "Burst request should be allowed")
+	assert.False(t, limiter.Allow("client1"), "Request over burst should be rejected")
+	assert.True(t, limiter.Allow("client2"), "Other clients should have their own bucket")
+
+	// One token is refilled per second at 60 per minute
+	now = now.Add(time.Second)
+	assert.True(t, limiter.Allow("client1"), "Request should be allowed after refill")
+	assert.False(t, limiter.Allow("client1"), "Only one token should have been refilled")
+}
+
+// TestRateLimiter_AllowN tests that batches consume a token per request
+func TestRateLimiter_AllowN(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(60, 5)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.AllowN("client1", 3), "Batch within the burst should be allowed")
+	assert.False(t, limiter.AllowN("client1", 3), "Batch over the remaining tokens should be rejected")
+	assert.True(t, limiter.AllowN("client1", 2), "Rejected batches should not consume tokens")
+	assert.False(t, limiter.AllowN("client2", 6), "Batches over the burst should never be allowed")
+}
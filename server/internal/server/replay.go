@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// ReplayStats describes a replay of an event log
+type ReplayStats struct {
+	Events  int       // Events applied
+	Skipped int       // Events after the replay's end
+	Last    time.Time // Time of the last event applied, zero if none was
+}
+
+// SnapshotDifference is an address held by different claimants after a
+// replay and in a snapshot, empty for whichever side has it unclaimed
+type SnapshotDifference struct {
+	IP       string
+	Replayed string
+	Snapshot string
+}
+
+// ReplayEventLog rebuilds the claims of a SQLite database from its event log
+// as they stood at until, or after every event if until is zero. The database
+// is opened read-only, so a running server's database may be replayed. Subnet
+// grants are not logged, so the rebuilt store holds address claims only.
+func ReplayEventLog(dbPath string, until time.Time) (*ClaimStore, ReplayStats, error) {
+	var stats ReplayStats
+
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return nil, stats, err
+	}
+	defer func() { _ = db.Close() }()
+
+	store := NewClaimStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err = readEventLog(db, func(event api.ClaimEvent) {
+		if !until.IsZero() && event.Timestamp.After(until) {
+			stats.Skipped++
+			return
+		}
+		store.replayEventLocked(event)
+		stats.Events++
+		stats.Last = event.Timestamp
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+	return store, stats, nil
+}
+
+// replayEventLocked applies a logged event to the store (assumes lock is held)
+func (cs *ClaimStore) replayEventLocked(event api.ClaimEvent) {
+	if event.Type == api.EventTypeUnclaim {
+		cs.applyReplicaClaimLocked(replicaClaim{ip: event.IP, released: true})
+		return
+	}
+
+	owner, exists := cs.claims[event.IP]
+	cs.applyReplicaClaimLocked(replicaClaim{
+		ip:       event.IP,
+		claimant: event.Claimant,
+		metadata: nextClaimMetadata(owner, exists, cs.metadata[event.IP], event.Claimant, event.Timestamp),
+	})
+}
+
+// CompareSnapshot compares the claims of a store with the claims held in a
+// SQLite database, returning the statistics of the snapshot and every
+// address held differently, in address order
+func CompareSnapshot(store *ClaimStore, snapshotPath string) (api.StatsResponse, []SnapshotDifference, error) {
+	var stats api.StatsResponse
+
+	db, err := openReadOnly(snapshotPath)
+	if err != nil {
+		return stats, nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.Query("SELECT ip_address, claimant FROM claims WHERE released_at IS NULL")
+	if err != nil {
+		return stats, nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	snapshot := make(map[string]string)
+	claimants := make(map[string]struct{})
+	for rows.Next() {
+		var ipAddr, claimant string
+		if err := rows.Scan(&ipAddr, &claimant); err != nil {
+			return stats, nil, err
+		}
+		snapshot[ipAddr] = claimant
+		claimants[claimant] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return stats, nil, err
+	}
+	stats = api.StatsResponse{TotalClaims: len(snapshot), Claimants: len(claimants)}
+
+	replayed := store.GetAllClaims(context.Background())
+	var differences []SnapshotDifference
+	for ipAddr, claimant := range replayed {
+		if snapshot[ipAddr] != claimant {
+			differences = append(differences, SnapshotDifference{IP: ipAddr, Replayed: claimant, Snapshot: snapshot[ipAddr]})
+		}
+	}
+	for ipAddr, claimant := range snapshot {
+		if _, exists := replayed[ipAddr]; !exists {
+			differences = append(differences, SnapshotDifference{IP: ipAddr, Snapshot: claimant})
+		}
+	}
+	sort.Slice(differences, func(i, j int) bool {
+		a, b := net.ParseIP(differences[i].IP), net.ParseIP(differences[j].IP)
+		if a == nil || b == nil {
+			return differences[i].IP < differences[j].IP
+		}
+		return string(a.To16()) < string(b.To16())
+	})
+
+	return stats, differences, nil
+}
+
+// openReadOnly opens an existing SQLite database without writing to it
+func openReadOnly(dbPath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReplayEventLog tests that replaying the event log rebuilds claims at any time
+func TestReplayEventLog(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+	store.SetFortificationOptions(testFortificationOptions())
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::2", Claimant: "alice"}, {IP: "2001:db8::3", Claimant: "bob"}}))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"), "Claims by the owner should not be logged")
+	time.Sleep(10 * time.Millisecond)
+	middle := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::2", ""))
+	_, err = store.Fortify(t.Context(), "2001:db8::3", "bob")
+	require.NoError(t, err, "Fortifying should not be logged")
+
+	replayed, stats, err := ReplayEventLog(dbPath, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, ReplayStats{Events: 5, Last: stats.Last}, stats)
+	assert.Equal(t, map[string]string{"2001:db8::1": "bob", "2001:db8::3": "bob"}, replayed.GetAllClaims(t.Context()))
+	metadata, _ := replayed.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, 1, metadata.TakeoverCount)
+
+	snapshot, differences, err := CompareSnapshot(replayed, dbPath)
+	require.NoError(t, err)
+	assert.Empty(t, differences)
+	assert.Equal(t, store.GetStats(t.Context()), snapshot)
+
+	earlier, stats, err := ReplayEventLog(dbPath, middle)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Events)
+	assert.Equal(t, 2, stats.Skipped)
+	assert.Equal(t, map[string]string{"2001:db8::1": "alice", "2001:db8::2": "alice", "2001:db8::3": "bob"}, earlier.GetAllClaims(t.Context()))
+
+	_, differences, err = CompareSnapshot(earlier, dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, []SnapshotDifference{
+		{IP: "2001:db8::1", Replayed: "alice", Snapshot: "bob"},
+		{IP: "2001:db8::2", Replayed: "alice"},
+	}, differences)
+
+	// Season resets release every claim
+	require.NoError(t, store.Reset(t.Context()))
+	replayed, _, err = ReplayEventLog(dbPath, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, replayed.GetAllClaims(t.Context()))
+}
+
+// TestReplayEventLog_Migration tests that databases written before the event log start it with their claims
+func TestReplayEventLog_Migration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::2", ""))
+	_, err = store.db.Exec(`DROP TRIGGER log_claim_insert; DROP TRIGGER log_claim_update; DROP TRIGGER log_claim_release;
+		DROP TRIGGER log_claim_delete; DROP TABLE claim_events`)
+	require.NoError(t, err)
+
+	_, _, err = ReplayEventLog(dbPath, time.Time{})
+	assert.ErrorIs(t, err, ErrNoEventLog)
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	replayed, stats, err := ReplayEventLog(dbPath, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Events, "Only claims still held should start the log")
+	assert.Equal(t, map[string]string{"2001:db8::1": "alice"}, replayed.GetAllClaims(t.Context()))
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
+)
+
+// ReplicaOptions configures read-only replicas, which serve the API from the
+// database a primary writes to, so read traffic scales separately from claims
+type ReplicaOptions struct {
+	ReadOnly        bool          `yaml:"readOnly"`        // Serve reads from the shared database without accepting writes
+	Primary         string        `yaml:"primary"`         // URL of the primary writes are proxied to, rejected if empty
+	RefreshInterval time.Duration `yaml:"refreshInterval"` // Time between loading changes written by the primary
+}
+
+// DefaultReplicaOptions returns the standard replica options, as a primary
+func DefaultReplicaOptions() ReplicaOptions {
+	return ReplicaOptions{RefreshInterval: 5 * time.Second}
+}
+
+// Validate checks that the replica options are usable
+func (o ReplicaOptions) Validate() error {
+	if !o.ReadOnly {
+		return nil
+	}
+	if o.RefreshInterval <= 0 {
+		return errors.New("replica refreshInterval must be positive")
+	}
+	if o.Primary != "" {
+		if u, err := url.Parse(o.Primary); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("replica primary must be an http or https URL, got %q", o.Primary)
+		}
+	}
+	return nil
+}
+
+// NewClaimStoreReadOnly creates a claim store reading the SQLite database of
+// a primary, which must already exist. Call Refresh to load later changes.
+func NewClaimStoreReadOnly(dbPath string) (*ClaimStore, error) {
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &ClaimStore{
+		claims:     make(map[string]string),
+		metadata:   make(map[string]ClaimMetadata),
+		ipTree:     NewIPTree(),
+		blocks:     newBlockIndex(),
+		db:         db,
+		dbPath:     dbPath,
+		readOnly:   true,
+		difficulty: DefaultDifficultyParams(),
+		powScheme:  api.SHA256Scheme{},
+		events:     NewEventBroker(),
+		activity:   NewActivityLog(DefaultActivityOptions()),
+		grants:     make(map[string]subnetGrant),
+		names:      make(map[string]string),
+		subnets:    names.NewIndex(),
+		logger:     componentLogger("store"),
+	}
+
+	if _, err := store.Refresh(); err != nil {
+		return nil, fmt.Errorf("failed to load database of the primary: %w", err)
+	}
+	store.logger.Info("Loaded claims from SQLite replica", "path", dbPath, "claims", len(store.claims), "grants", len(store.grants))
+
+	return store, nil
+}
+
+// replicaOverlap is how far before the last refresh changed claims are read again
+const replicaOverlap = "-5 seconds"
+
+// replicaClaim is a row of the claims table as read by a replica
+type replicaClaim struct {
+	ip       string
+	claimant string
+	metadata ClaimMetadata
+	released bool
+}
+
+// Refresh loads the claims, grants and names the primary wrote since the
+// last refresh, publishing ownership changes as claim events, and reports
+// how many claims changed. Claims are read incrementally by update time;
+// a full reload happens if the primary deleted claims, such as at the end
+// of a season.
+func (cs *ClaimStore) Refresh() (int, error) {
+	if !cs.readOnly {
+		return 0, errors.New("only read-only stores are refreshed")
+	}
+
+	cs.refreshMutex.Lock()
+	defer cs.refreshMutex.Unlock()
+
+	var watermark string
+	if err := cs.db.QueryRow("SELECT COALESCE(CAST(MAX(updated_at) AS TEXT), '') FROM claims").Scan(&watermark); err != nil {
+		return 0, err
+	}
+	where, args := "", []any(nil)
+	if cs.watermark != "" {
+		// A slow transaction can commit rows stamped before the last watermark,
+		// so read back a little; applying a row twice is harmless
+		where, args = "WHERE updated_at >= datetime(?, ?)", []any{cs.watermark, replicaOverlap}
+	}
+	changed, err := cs.readClaims(where, args...)
+	if err != nil {
+		return 0, err
+	}
+	var live int
+	if err := cs.db.QueryRow("SELECT COUNT(*) FROM claims WHERE released_at IS NULL").Scan(&live); err != nil {
+		return 0, err
+	}
+	grants, err := cs.readGrants()
+	if err != nil {
+		return 0, err
+	}
+	displayNames, err := cs.readNames()
+	if err != nil {
+		return 0, err
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.names = displayNames
+	for subnet := range grants {
+		if _, exists := cs.grants[subnet]; !exists {
+			cs.indexSubnetNames(subnet)
+		}
+	}
+	cs.grants = grants
+
+	count := 0
+	for _, claim := range changed {
+		if cs.applyReplicaClaimLocked(claim) {
+			count++
+		}
+	}
+
+	// Deleted claims leave no rows to read, so reload everything if the counts disagree
+	if len(cs.claims) != live {
+		all, err := cs.readClaims("WHERE released_at IS NULL")
+		if err != nil {
+			return count, err
+		}
+		current := make(map[string]bool, len(all))
+		for _, claim := range all {
+			current[claim.ip] = true
+			if cs.applyReplicaClaimLocked(claim) {
+				count++
+			}
+		}
+		for ip, owner := range cs.claims {
+			if !current[ip] {
+				cs.applyReplicaClaimLocked(replicaClaim{ip: ip, claimant: owner, released: true})
+				count++
+			}
+		}
+	}
+
+	cs.watermark = watermark
+	return count, nil
+}
+
+// applyReplicaClaimLocked applies a claim row read from the primary, reporting
+// whether the owner of the address changed (assumes lock is held)
+func (cs *ClaimStore) applyReplicaClaimLocked(claim replicaClaim) bool {
+	owner, exists := cs.claims[claim.ip]
+	now := time.Now().UTC()
+
+	if claim.released {
+		if !exists {
+			return false
+		}
+		delete(cs.claims, claim.ip)
+		delete(cs.metadata, claim.ip)
+		cs.ipTree.processUnclaim(claim.ip, owner)
+		cs.blocks.update(cs.claims, claim.ip)
+		cs.supply.moved(owner, "")
+		cs.events.Publish(api.ClaimEvent{Type: api.EventTypeUnclaim, IP: claim.ip, PreviousClaimant: owner, Timestamp: now})
+		return true
+	}
+
+	cs.metadata[claim.ip] = claim.metadata
+	if exists && owner == claim.claimant {
+		return false
+	}
+
+	cs.claims[claim.ip] = claim.claimant
+	cs.ipTree.processClaim(claim.ip, claim.claimant, owner)
+	cs.blocks.update(cs.claims, claim.ip)
+	cs.supply.moved(owner, claim.claimant)
+	if !exists {
+		cs.indexSubnetNames(claim.ip)
+	}
+
+	event := api.ClaimEvent{
+		Type:             api.EventTypeClaim,
+		IP:               claim.ip,
+		Claimant:         claim.claimant,
+		PreviousClaimant: owner,
+		Timestamp:        now,
+	}
+	cs.activity.record(event)
+	cs.heat.record(event)
+	cs.events.Publish(event)
+	return true
+}
+
+// readClaims reads claim rows matching a WHERE clause
+func (cs *ClaimStore) readClaims(where string, args ...any) ([]replicaClaim, error) {
+	rows, err := cs.db.Query(
+		`SELECT ip_address, claimant, claimed_at, takeover_count, fortification, fortified_at, released_at,
+			source_channel, source_ip, source_user_agent
+		FROM claims `+where,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	var claims []replicaClaim
+	for rows.Next() {
+		var claim replicaClaim
+		var fortifiedAt, releasedAt sql.NullTime
+		source := &claim.metadata.Source
+		if err := rows.Scan(&claim.ip, &claim.claimant, &claim.metadata.ClaimedAt, &claim.metadata.TakeoverCount,
+			&claim.metadata.Fortification, &fortifiedAt, &releasedAt, &source.Channel, &source.RemoteIP, &source.UserAgent); err != nil {
+			return nil, err
+		}
+		claim.metadata.FortifiedAt = fortifiedAt.Time
+		claim.released = releasedAt.Valid
+		claims = append(claims, claim)
+	}
+	return claims, rows.Err()
+}
+
+// readGrants reads every subnet grant
+func (cs *ClaimStore) readGrants() (map[string]subnetGrant, error) {
+	grants := make(map[string]subnetGrant)
+	rows, err := cs.db.Query("SELECT subnet, claimant FROM subnet_grants")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var subnet, claimant string
+		if err := rows.Scan(&subnet, &claimant); err != nil {
+			return nil, err
+		}
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			continue
+		}
+		grants[subnet] = subnetGrant{subnet: ipNet, claimant: claimant}
+	}
+	return grants, rows.Err()
+}
+
+// readNames reads every registered claimant display name
+func (cs *ClaimStore) readNames() (map[string]string, error) {
+	displayNames := make(map[string]string)
+	rows, err := cs.db.Query("SELECT canonical, display FROM claimant_names")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var canonical, display string
+		if err := rows.Scan(&canonical, &display); err != nil {
+			return nil, err
+		}
+		displayNames[canonical] = display
+	}
+	return displayNames, rows.Err()
+}
+
+// ReplicaRefresher periodically loads changes the primary wrote to the shared database
+type ReplicaRefresher struct {
+	store    *ClaimStore
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+// NewReplicaRefresher creates a refresher for a read-only store
+func NewReplicaRefresher(store *ClaimStore, interval time.Duration) *ReplicaRefresher {
+	return &ReplicaRefresher{
+		store:    store,
+		interval: interval,
+		logger:   componentLogger("replica"),
+	}
+}
+
+// Start begins refreshing in the background
+func (r *ReplicaRefresher) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if changed, err := r.store.Refresh(); err != nil {
+					r.logger.Error("Failed to refresh from the primary's database", "error", err)
+				} else if changed > 0 {
+					r.logger.Debug("Refreshed from the primary's database", "changed", changed)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing
+func (r *ReplicaRefresher) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+// isReadRequest reports whether a request only reads, and can be served by a replica
+func isReadRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+}
+
+// readOnlyMiddleware serves reads on a replica and proxies writes to the
+// primary, or rejects them if no primary is configured
+func readOnlyMiddleware(opts ReplicaOptions, next http.Handler) http.Handler {
+	var proxy *httputil.ReverseProxy
+	if opts.Primary != "" {
+		primary, _ := url.Parse(opts.Primary)
+		proxy = httputil.NewSingleHostReverseProxy(primary)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			componentLogger("replica").Warn("Failed to proxy write to the primary", "path", r.URL.Path, "error", err)
+			writeError(w, r, badGateway("primary server is unreachable"))
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadRequest(r) || !strings.HasPrefix(r.URL.Path, legacyAPIPrefix+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if proxy == nil {
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+			writeError(w, r, methodNotAllowed("this server is a read-only replica"))
+			return
+		}
+		// Keep the request ID, so the write can be traced across both servers
+		r.Header.Set(requestIDHeader, requestID(r.Context()))
+		proxy.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_Refresh tests a replica following the claims its primary writes
+func TestClaimStore_Refresh(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "primary.db")
+	primary, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create primary store")
+	defer primary.Close()
+	require.NoError(t, primary.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, primary.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+
+	replica, err := NewClaimStoreReadOnly(dbPath)
+	require.NoError(t, err, "Should open the primary's database")
+	defer replica.Close()
+
+	claimant, ok := replica.GetClaim(t.Context(), "2001:db8::1")
+	assert.True(t, ok, "Replica should load existing claims")
+	assert.Equal(t, "alice", claimant)
+	assert.Equal(t, primary.GetStats(t.Context()), replica.GetStats(t.Context()))
+	assert.Error(t, replica.ProcessClaim(t.Context(), "2001:db8::3", "mallory"), "Replica should not accept writes")
+
+	events, unsubscribe := replica.SubscribeEvents(t.Context())
+	defer unsubscribe()
+
+	require.NoError(t, primary.ProcessClaim(t.Context(), "2001:db8::1", "carol"))
+	require.NoError(t, primary.Unclaim(t.Context(), "2001:db8::2", ""))
+	require.NoError(t, primary.GrantSubnet(t.Context(), "2001:db8:1::/48", "dave"))
+
+	changed, err := replica.Refresh()
+	require.NoError(t, err, "Should refresh")
+	assert.Equal(t, 2, changed, "Takeover and release should be loaded")
+
+	claimant, _ = replica.GetClaim(t.Context(), "2001:db8::1")
+	assert.Equal(t, "carol", claimant)
+	_, ok = replica.GetClaim(t.Context(), "2001:db8::2")
+	assert.False(t, ok, "Released claim should be gone")
+	metadata, _ := replica.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, 1, metadata.TakeoverCount)
+	grantName, err := names.GenerateName("2001:db8:1::", 48)
+	require.NoError(t, err)
+	assert.Contains(t, replica.ResolveName(t.Context(), grantName), "2001:db8:1::/48", "Granted subnet should resolve")
+
+	received := map[string]string{}
+	for range 2 {
+		select {
+		case event := <-events:
+			received[event.Type] = event.IP
+		case <-time.After(time.Second):
+			t.Fatal("Replica should publish the changes as events")
+		}
+	}
+	assert.Equal(t, map[string]string{api.EventTypeClaim: "2001:db8::1", api.EventTypeUnclaim: "2001:db8::2"}, received)
+
+	changed, err = replica.Refresh()
+	require.NoError(t, err, "Should refresh")
+	assert.Zero(t, changed, "Rows read again should not count as changes")
+
+	// Deleted claims are noticed by the claim count
+	require.NoError(t, primary.Reset(t.Context()))
+	_, err = replica.Refresh()
+	require.NoError(t, err, "Should refresh")
+	assert.Empty(t, replica.GetAllClaims(t.Context()), "Reset of the primary should be loaded")
+}
+
+// TestReadOnlyMiddleware tests that replicas serve reads and proxy or reject writes
+func TestReadOnlyMiddleware(t *testing.T) {
+	reads := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(handler http.Handler, method string, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		assignRequestIDs(handler).ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	rejecting := readOnlyMiddleware(ReplicaOptions{ReadOnly: true}, reads)
+	assert.Equal(t, http.StatusOK, serve(rejecting, http.MethodGet, "/api/v1/stats").Code, "Reads should be served")
+	rr := serve(rejecting, http.MethodPost, "/api/v1/claim/2001:db8::1")
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code, "Writes should be rejected without a primary")
+	var resp api.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Errors should have a JSON body")
+	assert.Equal(t, "method_not_allowed", resp.Code)
+
+	var forwarded *http.Request
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer primary.Close()
+
+	proxying := readOnlyMiddleware(ReplicaOptions{ReadOnly: true, Primary: primary.URL}, reads)
+	rr = serve(proxying, http.MethodPost, "/api/v1/claim/2001:db8::1")
+	assert.Equal(t, http.StatusCreated, rr.Code, "Writes should be proxied to the primary")
+	require.NotNil(t, forwarded)
+	assert.Equal(t, "/api/v1/claim/2001:db8::1", forwarded.URL.Path)
+	assert.Equal(t, rr.Header().Get(requestIDHeader), forwarded.Header.Get(requestIDHeader), "Request ID should be forwarded")
+
+	primary.Close()
+	assert.Equal(t, http.StatusBadGateway, serve(proxying, http.MethodDelete, "/api/v1/claim/2001:db8::1").Code,
+		"Unreachable primary should be reported")
+}
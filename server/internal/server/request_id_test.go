@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAssignRequestIDs tests request IDs and the error envelope
+func TestAssignRequestIDs(t *testing.T) {
+	handler := NewHTTPHandler(NewClaimStore())
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := assignRequestIDs(router)
+
+	get := func(path string, id string) (*httptest.ResponseRecorder, api.ErrorResponse) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		var resp api.ErrorResponse
+		if rr.Code >= 400 {
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Errors should have a JSON body")
+		}
+		return rr, resp
+	}
+
+	rr, resp := get("/api/v1/ip/not-an-address", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	id := rr.Header().Get(requestIDHeader)
+	assert.NotEmpty(t, id, "Every response should carry a request ID")
+	assert.Equal(t, "bad_request", resp.Code)
+	assert.Equal(t, "invalid address", resp.Message)
+	assert.Equal(t, resp.Message, resp.Error, "The old error field should still be set")
+	assert.Equal(t, id, resp.RequestID, "The envelope should carry the request ID")
+
+	rr, resp = get("/api/v1/ip/2001:db8::1", "trace-42")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "not_found", resp.Code)
+	assert.Equal(t, "trace-42", rr.Header().Get(requestIDHeader), "A client's request ID should be kept")
+	assert.Equal(t, "trace-42", resp.RequestID)
+
+	for _, bad := range []string{"has spaces", "quote\"", strings.Repeat("a", maxRequestIDLength+1)} {
+		rr, _ = get("/api/v1/stats", bad)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEqual(t, bad, rr.Header().Get(requestIDHeader), "Unsafe request ID %q should be replaced", bad)
+		assert.Len(t, rr.Header().Get(requestIDHeader), 16, "Generated request IDs should be 16 hex digits")
+	}
+
+	rr, resp = get("/api/v1/admin/claims", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Admin endpoints should be disabled without tokens")
+	assert.Equal(t, "forbidden", resp.Code)
+}
+
+// TestWriteError_Internal tests that unexpected errors are not leaked to clients
+func TestWriteError_Internal(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeError(rr, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("database is on fire"))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	var resp api.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Errors should have a JSON body")
+	assert.Equal(t, "internal", resp.Code)
+	assert.NotContains(t, resp.Message, "fire", "Internal error details should not be sent")
+}
+
+// TestWriteError_StoreErrors tests that typed store errors are answered with the status of their kind
+func TestWriteError_StoreErrors(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{backendError(errors.New("database is locked")), http.StatusServiceUnavailable, "unavailable"},
+		{backendError(context.DeadlineExceeded), http.StatusServiceUnavailable, "unavailable"},
+		{ErrInvalidAddress, http.StatusBadRequest, "bad_request"},
+		{fmt.Errorf("claims[1]: %w", ErrProtected), http.StatusForbidden, "forbidden"},
+		{ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			writeError(rr, httptest.NewRequest(http.MethodPost, "/", nil), tc.err)
+
+			assert.Equal(t, tc.status, rr.Code)
+			var resp api.ErrorResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Errors should have a JSON body")
+			assert.Equal(t, tc.code, resp.Code)
+			assert.NotContains(t, resp.Message, "locked", "Backend error details should not be sent")
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	writeError(rr, httptest.NewRequest(http.MethodPost, "/", nil), backendError(errors.New("disk I/O error")))
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"), "Backend failures should be retryable")
+}
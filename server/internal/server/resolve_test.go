@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_ResolveName tests that claimed and granted subnets resolve by name
+func TestClaimStore_ResolveName(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "resolve.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.GrantSubnet(t.Context(), "2001:db8:ff00::/40", "bob"))
+
+	addressName, err := names.GenerateName("2001:db8::1", 64)
+	require.NoError(t, err)
+	grantName, err := names.GenerateName("2001:db8:ff00::", 32)
+	require.NoError(t, err)
+	unclaimedName, err := names.GenerateName("2001:db8:1234::", 48)
+	require.NoError(t, err)
+
+	assert.Contains(t, store.ResolveName(t.Context(), addressName), "2001:db8::/64", "Subnets containing a claim should resolve")
+	assert.Contains(t, store.ResolveName(t.Context(), grantName), "2001:db8::/32", "Subnets containing a grant should resolve")
+	assert.NotContains(t, store.ResolveName(t.Context(), unclaimedName), "2001:db8:1234::/48", "Subnets without claims should not resolve")
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	assert.Contains(t, reopened.ResolveName(t.Context(), addressName), "2001:db8::/64", "Loaded claims should resolve")
+
+	require.NoError(t, reopened.Reset(t.Context()))
+	assert.Empty(t, reopened.ResolveName(t.Context(), addressName), "Reset should forget claimed subnets")
+}
+
+// TestHTTPHandler_ResolveName tests the name resolution endpoint
+func TestHTTPHandler_ResolveName(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	handler := NewHTTPHandler(store)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	name, err := names.GenerateName("2001:db8::1", 128)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/resolve?name="+url.QueryEscape(name), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Claimed address name should resolve")
+
+	var resp api.ResolveResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, name, resp.Name, "Response should echo the name")
+	assert.Equal(t, []string{"2001:db8::1/128"}, resp.Subnets, "Name should resolve to the claimed address")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/resolve?name=Nonexistent+Name+Nebula-1", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Unknown names should not resolve")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/resolve", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Name should be required")
+}
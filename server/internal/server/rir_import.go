@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// How imported allocations are grouped into NPC factions
+const (
+	FactionByRegistry = "registry" // One faction per RIR, such as ARIN
+	FactionByCountry  = "country"  // One faction per country, such as DE
+	FactionByBoth     = "both"     // One faction per RIR and country, such as RIPENCC-DE
+)
+
+// maxRIRImportBytes bounds the size of delegation files uploaded to the import endpoint
+const maxRIRImportBytes = 64 << 20
+
+// RIRAllocation is an IPv6 prefix allocated or assigned by a regional internet registry
+type RIRAllocation struct {
+	Registry string // Registry ID, such as arin or ripencc
+	Country  string // ISO 3166 country code
+	Prefix   *net.IPNet
+	Status   string // allocated or assigned
+}
+
+// Faction returns the name of the NPC faction owning an allocation
+func (a RIRAllocation) Faction(by string) string {
+	registry := strings.ToUpper(a.Registry)
+	country := strings.ToUpper(a.Country)
+	switch by {
+	case FactionByRegistry:
+		return registry
+	case FactionByCountry:
+		return country
+	}
+	return registry + "-" + country
+}
+
+// ParseRIRDelegations reads the IPv6 allocations from an RIR statistics
+// exchange file, in the delegated or delegated-extended format published by
+// every registry. Version, summary and comment lines are skipped, as are
+// other address families and unallocated prefixes.
+func ParseRIRDelegations(r io.Reader) ([]RIRAllocation, error) {
+	var allocations []RIRAllocation
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// registry|cc|type|start|value|date|status[|opaque-id[|extensions...]]
+		fields := strings.Split(text, "|")
+		if len(fields) < 7 || fields[2] != "ipv6" || fields[1] == "*" {
+			// Version and summary lines, or another address family
+			continue
+		}
+
+		status := fields[6]
+		if status != "allocated" && status != "assigned" {
+			continue
+		}
+
+		prefixLen, err := strconv.Atoi(fields[4])
+		if err != nil || prefixLen < 0 || prefixLen > 128 {
+			return nil, fmt.Errorf("line %d: invalid prefix length %q", line, fields[4])
+		}
+		start := net.ParseIP(fields[3])
+		if start == nil || start.To4() != nil {
+			return nil, fmt.Errorf("line %d: invalid IPv6 address %q", line, fields[3])
+		}
+		mask := net.CIDRMask(prefixLen, 128)
+
+		allocations = append(allocations, RIRAllocation{
+			Registry: fields[0],
+			Country:  fields[1],
+			Prefix:   &net.IPNet{IP: start.Mask(mask), Mask: mask},
+			Status:   status,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return allocations, nil
+}
+
+// validateFactionBy checks a faction grouping
+func validateFactionBy(by string) error {
+	switch by {
+	case FactionByRegistry, FactionByCountry, FactionByBoth:
+		return nil
+	}
+	return fmt.Errorf("unknown faction grouping %q, want registry, country or both", by)
+}
+
+// ImportRIRAllocations grants each allocation to its NPC faction, giving new
+// games a populated universe. Allocations whose faction name is rejected by
+// the name policy or looks like an existing claimant's are skipped.
+func ImportRIRAllocations(ctx context.Context, store Store, policy *NamePolicy, allocations []RIRAllocation, factionBy string) (api.RIRImportResponse, error) {
+	var result api.RIRImportResponse
+
+	if err := validateFactionBy(factionBy); err != nil {
+		return result, err
+	}
+
+	factions := make(map[string]struct{})
+	for _, allocation := range allocations {
+		name, err := policy.Normalize(allocation.Faction(factionBy))
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		if err := store.GrantSubnet(ctx, allocation.Prefix.String(), name); errors.Is(err, ErrNameConfusable) {
+			result.Skipped++
+			continue
+		} else if err != nil {
+			return result, err
+		}
+
+		factions[name] = struct{}{}
+		result.Imported++
+	}
+	result.Factions = len(factions)
+
+	return result, nil
+}
+
+// handleAdminImportRIR seeds NPC factions from an RIR delegation file in the request body
+func (h *HTTPHandler) handleAdminImportRIR(w http.ResponseWriter, r *http.Request) {
+	factionBy := r.URL.Query().Get("factions")
+	if factionBy == "" {
+		factionBy = FactionByBoth
+	}
+	if err := validateFactionBy(factionBy); err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	allocations, err := ParseRIRDelegations(http.MaxBytesReader(w, r.Body, maxRIRImportBytes))
+	if err != nil {
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	// Imports grant many subnets, so only the client bounds how long they take
+	result, err := ImportRIRAllocations(r.Context(), h.store, h.names, allocations, factionBy)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("importing RIR allocations: %w", err))
+		return
+	}
+
+	h.logger.Info("Imported RIR allocations", "imported", result.Imported, "skipped", result.Skipped, "factions", result.Factions)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRIRDelegations is an excerpt of a delegated-extended statistics file
+const testRIRDelegations = `# comment
+2|ripencc|1700000000|4|19830101|20231114|+0100
+ripencc|*|ipv4|*|1|summary
+ripencc|*|ipv6|*|3|summary
+ripencc|DE|ipv4|192.0.2.0|256|20100101|allocated|abc
+ripencc|DE|ipv6|2001:db8::|32|20100101|allocated|abc
+ripencc|FR|ipv6|2001:db9::|29|20110101|assigned|def
+ripencc|ZZ|ipv6|2001:dba::|32||available||
+`
+
+// TestParseRIRDelegations tests that only allocated IPv6 prefixes are read
+func TestParseRIRDelegations(t *testing.T) {
+	allocations, err := ParseRIRDelegations(strings.NewReader(testRIRDelegations))
+	require.NoError(t, err, "Should parse a delegation file")
+	require.Len(t, allocations, 2, "Should skip headers, summaries, IPv4 and available prefixes")
+
+	assert.Equal(t, "2001:db8::/32", allocations[0].Prefix.String())
+	assert.Equal(t, "2001:db8::/29", allocations[1].Prefix.String(), "Prefixes should be masked")
+	assert.Equal(t, "RIPENCC-DE", allocations[0].Faction(FactionByBoth))
+	assert.Equal(t, "RIPENCC", allocations[0].Faction(FactionByRegistry))
+	assert.Equal(t, "FR", allocations[1].Faction(FactionByCountry))
+
+	_, err = ParseRIRDelegations(strings.NewReader("arin|US|ipv6|nonsense|32|20100101|allocated\n"))
+	assert.Error(t, err, "Should reject invalid addresses")
+}
+
+// TestHTTPHandler_ImportRIR tests seeding NPC factions through the admin endpoint
+func TestHTTPHandler_ImportRIR(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "ripencc-fr"))
+
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	importRIR := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/import/rir"+query, strings.NewReader(testRIRDelegations))
+		req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, importRIR("?factions=planet").Code, "Unknown groupings should be rejected")
+
+	rr := importRIR("")
+	require.Equal(t, http.StatusOK, rr.Code, "Import should succeed")
+	var result api.RIRImportResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+	assert.Equal(t, api.RIRImportResponse{Imported: 1, Skipped: 1, Factions: 1}, result,
+		"Factions confusable with existing claimants should be skipped")
+
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/32", 0)
+	require.True(t, ok)
+	assert.Equal(t, "RIPENCC-DE", stats.Owner, "Allocations should be owned by their faction")
+	assert.True(t, stats.Granted, "Faction ownership should be a grant")
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Verify ClaimStore can persist score history
+var _ scorePersistence = (*ClaimStore)(nil)
+
+// loadScoreHistory returns the most recent score history entries per player from SQLite,
+// oldest first. It returns nil without SQLite, leaving history in memory only.
+func (cs *ClaimStore) loadScoreHistory(limit int) (map[string][]api.ScorePoint, error) {
+	if cs.db == nil {
+		return nil, nil
+	}
+
+	query := `
+		SELECT name, tick, points, score FROM (
+			SELECT name, tick, points, score,
+				ROW_NUMBER() OVER (PARTITION BY name ORDER BY tick DESC) AS age
+			FROM score_history
+		) WHERE ? <= 0 OR age <= ?
+		ORDER BY name, tick`
+	rows, err := cs.db.Query(query, limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	history := make(map[string][]api.ScorePoint)
+	for rows.Next() {
+		var name string
+		var point api.ScorePoint
+		if err := rows.Scan(&name, &point.Timestamp, &point.Points, &point.Score); err != nil {
+			return nil, err
+		}
+		history[name] = append(history[name], point)
+	}
+
+	return history, rows.Err()
+}
+
+// saveScoreTick records the points awarded to each player at a tick in SQLite
+func (cs *ClaimStore) saveScoreTick(tick time.Time, points map[string]api.ScorePoint) error {
+	if cs.db == nil {
+		return nil
+	}
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for name, point := range points {
+		if _, err := tx.Exec(
+			"INSERT INTO score_history (name, tick, points, score) VALUES (?, ?, ?, ?)",
+			name, tick, point.Points, point.Score,
+		); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				cs.logger.Error("Error rolling back score history", "error", rbErr)
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// clearScoreHistory removes all score history from SQLite
+func (cs *ClaimStore) clearScoreHistory() error {
+	if cs.db == nil {
+		return nil
+	}
+
+	_, err := cs.db.Exec("DELETE FROM score_history")
+	return err
+}
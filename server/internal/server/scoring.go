@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// ScoringOptions configures the periodic scoring engine
+type ScoringOptions struct {
+	Interval        time.Duration `yaml:"interval"`        // Time between scoring ticks, zero disables scoring
+	AddressPoints   int64         `yaml:"addressPoints"`   // Points per held /128 per tick
+	BlockMultiplier int64         `yaml:"blockMultiplier"` // Multiplies the address points of a complete /124, and again of a complete /120; 1 or less disables
+	SubnetPoints    int64         `yaml:"subnetPoints"`    // Points per dominated /112 per tick, doubling per level above
+	ArtifactPoints  int64         `yaml:"artifactPoints"`  // Bonus points per held artifact per tick
+	HistoryLength   int           `yaml:"historyLength"`   // Score history entries kept per player
+}
+
+// DefaultScoringOptions returns the standard scoring options
+func DefaultScoringOptions() ScoringOptions {
+	return ScoringOptions{
+		Interval:        time.Minute,
+		AddressPoints:   1,
+		BlockMultiplier: 2,
+		SubnetPoints:    10,
+		ArtifactPoints:  50,
+		HistoryLength:   1440,
+	}
+}
+
+// Enabled reports whether scoring ticks should run
+func (o ScoringOptions) Enabled() bool {
+	return o.Interval > 0
+}
+
+// scorePersistence stores score history across restarts
+type scorePersistence interface {
+	// loadScoreHistory returns the most recent history entries per player, oldest first
+	loadScoreHistory(limit int) (map[string][]api.ScorePoint, error)
+
+	// saveScoreTick records the points awarded to each player at a tick
+	saveScoreTick(tick time.Time, points map[string]api.ScorePoint) error
+
+	// clearScoreHistory removes all recorded score history
+	clearScoreHistory() error
+}
+
+// ScoringEngine periodically awards points for held addresses and dominated subnets
+type ScoringEngine struct {
+	store       Store
+	opts        ScoringOptions
+	persistence scorePersistence // Optional, nil keeps history in memory only
+	artifacts   *ArtifactSet     // Optional, nil awards no artifact bonus
+	events      *GameEvents      // Optional, nil applies no game event multipliers
+
+	mutex   sync.RWMutex
+	scores  map[string]int64
+	history map[string][]api.ScorePoint
+
+	stop   chan struct{}
+	done   chan struct{}
+	logger *slog.Logger
+}
+
+// NewScoringEngine creates a scoring engine for the given store, restoring
+// persisted history if the store supports it
+func NewScoringEngine(store Store, opts ScoringOptions) (*ScoringEngine, error) {
+	engine := &ScoringEngine{
+		store:   store,
+		opts:    opts,
+		scores:  make(map[string]int64),
+		history: make(map[string][]api.ScorePoint),
+		logger:  componentLogger("scoring"),
+	}
+
+	if persistence, ok := store.(scorePersistence); ok {
+		history, err := persistence.loadScoreHistory(opts.HistoryLength)
+		if err != nil {
+			return nil, err
+		}
+		if history != nil {
+			engine.persistence = persistence
+			engine.history = history
+			for name, points := range history {
+				engine.scores[name] = points[len(points)-1].Score
+			}
+		}
+	}
+
+	return engine, nil
+}
+
+// Start runs scoring ticks in the background until Stop is called
+func (e *ScoringEngine) Start() {
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+
+	go func() {
+		defer close(e.done)
+
+		ticker := time.NewTicker(e.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.stop:
+				return
+			case tick := <-ticker.C:
+				e.Tick(tick.UTC())
+			}
+		}
+	}()
+}
+
+// Stop stops the background scoring ticks
+func (e *ScoringEngine) Stop() {
+	if e.stop == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+	e.stop = nil
+}
+
+// Tick awards points for the current state of the store
+func (e *ScoringEngine) Tick(now time.Time) {
+	awarded := e.computePoints(now)
+
+	e.mutex.Lock()
+	points := make(map[string]api.ScorePoint, len(awarded))
+	for name, amount := range awarded {
+		e.scores[name] += amount
+		point := api.ScorePoint{
+			Timestamp: now,
+			Points:    amount,
+			Score:     e.scores[name],
+		}
+		points[name] = point
+
+		history := append(e.history[name], point)
+		if e.opts.HistoryLength > 0 && len(history) > e.opts.HistoryLength {
+			history = history[len(history)-e.opts.HistoryLength:]
+		}
+		e.history[name] = history
+	}
+	e.mutex.Unlock()
+
+	if e.persistence != nil && len(points) > 0 {
+		if err := e.persistence.saveScoreTick(now, points); err != nil {
+			e.logger.Error("Failed to persist scores", "error", err)
+		}
+	}
+}
+
+// computePoints calculates the points each player earns at a tick
+func (e *ScoringEngine) computePoints(now time.Time) map[string]int64 {
+	awarded := make(map[string]int64)
+	ctx := context.Background()
+
+	for _, entry := range e.store.GetLeaderboard(ctx, 0) {
+		awarded[entry.Name] += int64(entry.Addresses)*e.opts.AddressPoints + e.blockPoints(entry)
+	}
+
+	if e.artifacts != nil && e.opts.ArtifactPoints > 0 {
+		for ipAddr, claimant := range e.store.GetAllClaims(ctx) {
+			if ip := net.ParseIP(ipAddr); ip != nil && e.artifacts.IsArtifact(ip) {
+				awarded[claimant] += e.opts.ArtifactPoints
+			}
+		}
+	}
+
+	// Dominated subnets are worth more the larger they are, addresses were counted above
+	weight := e.opts.SubnetPoints
+	levels := e.store.Levels(ctx)
+	for i := len(levels) - 1; i >= 0; i-- {
+		if levels[i] == 128 {
+			continue
+		}
+		subnets, _ := e.store.GetAllSubnets(ctx, levels[i])
+		for _, subnet := range subnets {
+			if subnet.Owner != "" {
+				awarded[subnet.Owner] += weight
+			}
+		}
+		weight *= 2
+	}
+
+	for name, points := range e.gameEventPoints(now) {
+		awarded[name] += points
+	}
+
+	return awarded
+}
+
+// blockPoints returns the bonus a player earns for the blocks they hold
+// completely. Each address of a complete /124 earns its points multiplied
+// by the block multiplier, and each of a complete /120 multiplied by it twice.
+func (e *ScoringEngine) blockPoints(entry api.LeaderboardEntry) int64 {
+	m := e.opts.BlockMultiplier
+	if m <= 1 {
+		return 0
+	}
+	// The /124s of a complete /120 are counted among the complete /124s, so
+	// a /120 only adds the second multiplication
+	per124 := (m - 1) * 16 * e.opts.AddressPoints
+	per120 := (m*m - m) * 256 * e.opts.AddressPoints
+	return int64(entry.Blocks124)*per124 + int64(entry.Blocks120)*per120
+}
+
+// gameEventPoints returns the points each player earns at a tick on top of
+// the usual ones from the game events running, for addresses held and
+// subnets dominated inside their subnets
+func (e *ScoringEngine) gameEventPoints(now time.Time) map[string]int64 {
+	active := e.events.Active(now)
+	if len(active) == 0 {
+		return nil
+	}
+	subnets := make([]*net.IPNet, len(active))
+	for i, event := range active {
+		subnets[i] = mustParseSubnet(event.Subnet)
+	}
+	// Overlapping events multiply each other
+	multiplier := func(ip net.IP, prefixLen int) float64 {
+		m := 1.0
+		for i, subnet := range subnets {
+			if prefixLength(subnet) <= prefixLen && subnet.Contains(ip) {
+				m *= active[i].Multiplier
+			}
+		}
+		return m
+	}
+	ctx := context.Background()
+
+	extra := make(map[string]float64)
+	for ipAddr, claimant := range e.store.GetAllClaims(ctx) {
+		if ip := net.ParseIP(ipAddr); ip != nil {
+			extra[claimant] += (multiplier(ip, 128) - 1) * float64(e.opts.AddressPoints)
+		}
+	}
+
+	weight := e.opts.SubnetPoints
+	levels := e.store.Levels(ctx)
+	for i := len(levels) - 1; i >= 0; i-- {
+		if levels[i] == 128 {
+			continue
+		}
+		listed, _ := e.store.GetAllSubnets(ctx, levels[i])
+		for _, subnet := range listed {
+			if subnet.Owner == "" {
+				continue
+			}
+			if ipNet, err := api.ParseSubnet(subnet.Subnet); err == nil {
+				extra[subnet.Owner] += (multiplier(ipNet.IP, levels[i]) - 1) * float64(weight)
+			}
+		}
+		weight *= 2
+	}
+
+	points := make(map[string]int64, len(extra))
+	for name, amount := range extra {
+		if rounded := int64(math.Round(amount)); rounded != 0 {
+			points[name] = rounded
+		}
+	}
+	return points
+}
+
+// Reset clears all scores and history, as at the start of a new season
+func (e *ScoringEngine) Reset() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.persistence != nil {
+		if err := e.persistence.clearScoreHistory(); err != nil {
+			return err
+		}
+	}
+
+	e.scores = make(map[string]int64)
+	e.history = make(map[string][]api.ScorePoint)
+
+	return nil
+}
+
+// Scores returns all players ranked by score
+func (e *ScoringEngine) Scores() []api.ScoreEntry {
+	e.mutex.RLock()
+	scores := make([]api.ScoreEntry, 0, len(e.scores))
+	for name, score := range e.scores {
+		scores = append(scores, api.ScoreEntry{Name: name, Score: score})
+	}
+	e.mutex.RUnlock()
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Name < scores[j].Name
+	})
+
+	return scores
+}
+
+// History returns the score history of a player, oldest first
+func (e *ScoringEngine) History(name string) ([]api.ScorePoint, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	history, exists := e.history[name]
+	if !exists {
+		return nil, false
+	}
+	return append([]api.ScorePoint(nil), history...), true
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dominatedSubnetStore reports fixed subnet owners, since dominating a real /112 takes 32769 claims
+type dominatedSubnetStore struct {
+	*ClaimStore
+	owners map[int]string
+}
+
+func (s *dominatedSubnetStore) GetAllSubnets(ctx context.Context, prefixLen int) ([]api.SubnetListEntry, bool) {
+	owner, exists := s.owners[prefixLen]
+	if !exists {
+		return nil, true
+	}
+	return []api.SubnetListEntry{{Owner: owner, Percentage: 100}}, true
+}
+
+// TestScoringEngine_Tick tests that points are awarded for addresses and dominated subnets
+func TestScoringEngine_Tick(t *testing.T) {
+	store := &dominatedSubnetStore{ClaimStore: NewClaimStore(), owners: map[int]string{112: "alice", 96: "alice", 16: "bob"}}
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::3", "bob"))
+
+	opts := ScoringOptions{Interval: time.Minute, AddressPoints: 1, SubnetPoints: 10, HistoryLength: 2}
+	engine, err := NewScoringEngine(store, opts)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		engine.Tick(start.Add(time.Duration(i) * time.Minute))
+	}
+
+	// alice holds 2 addresses, a /112 (10) and a /96 (20); bob holds 1 address and a /16 (640)
+	perTick := int64(2 + 10 + 20)
+	assert.Equal(t, []api.ScoreEntry{
+		{Name: "bob", Score: 3 * 641},
+		{Name: "alice", Score: 3 * perTick},
+	}, engine.Scores(), "Scores should be ranked by total points")
+
+	history, ok := engine.History("alice")
+	require.True(t, ok, "alice should have a score history")
+	require.Len(t, history, 2, "History should be trimmed to the configured length")
+	assert.Equal(t, start.Add(2*time.Minute), history[1].Timestamp, "Latest entry should be last")
+	assert.Equal(t, perTick, history[1].Points, "Points should be awarded per tick")
+	assert.Equal(t, 3*perTick, history[1].Score, "History should record the running score")
+
+	_, ok = engine.History("carol")
+	assert.False(t, ok, "Unknown player should have no history")
+}
+
+// TestScoringEngine_BlockPoints tests that complete blocks multiply the points of their addresses
+func TestScoringEngine_BlockPoints(t *testing.T) {
+	store := NewClaimStore()
+	batch := make([]BatchClaim, 0, 256+16+1)
+	for i := range 256 {
+		batch = append(batch, BatchClaim{IP: fmt.Sprintf("2001:db8::1:%x", i), Claimant: "alice"})
+	}
+	for i := range 16 {
+		batch = append(batch, BatchClaim{IP: fmt.Sprintf("2001:db8::2:%x", i), Claimant: "bob"})
+	}
+	batch = append(batch, BatchClaim{IP: "2001:db8::3:0", Claimant: "carol"})
+	require.NoError(t, store.ProcessClaims(t.Context(), batch))
+
+	opts := ScoringOptions{Interval: time.Minute, AddressPoints: 1, BlockMultiplier: 2}
+	engine, err := NewScoringEngine(store, opts)
+	require.NoError(t, err)
+	engine.Tick(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []api.ScoreEntry{
+		{Name: "alice", Score: 4 * 256},
+		{Name: "bob", Score: 2 * 16},
+		{Name: "carol", Score: 1},
+	}, engine.Scores(), "A complete /124 should double its points, and a complete /120 double them again")
+
+	opts.BlockMultiplier = 1
+	engine, err = NewScoringEngine(store, opts)
+	require.NoError(t, err)
+	engine.Tick(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, int64(256), engine.Scores()[0].Score, "A multiplier of 1 should add nothing")
+}
+
+// TestScoringEngine_Persistence tests that score history survives a restart with SQLite
+func TestScoringEngine_Persistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "scores.db")
+	opts := ScoringOptions{Interval: time.Minute, AddressPoints: 1, HistoryLength: 10}
+
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	engine, err := NewScoringEngine(store, opts)
+	require.NoError(t, err)
+	engine.Tick(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine.Tick(time.Date(2025, 1, 1, 0, 1, 0, 0, time.UTC))
+	require.NoError(t, store.Close())
+
+	store, err = NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err)
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+
+	restored, err := NewScoringEngine(store, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []api.ScoreEntry{{Name: "alice", Score: 2}}, restored.Scores(), "Scores should be restored")
+
+	history, ok := restored.History("alice")
+	require.True(t, ok, "History should be restored")
+	assert.Len(t, history, 2, "All ticks should be restored")
+}
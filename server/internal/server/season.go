@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// SeasonOptions configures game seasons
+type SeasonOptions struct {
+	Length     time.Duration `yaml:"length"`     // Season length, zero only ends seasons manually
+	ArchiveDir string        `yaml:"archiveDir"` // Directory for season archives, empty disables archiving
+}
+
+// SeasonManager tracks the current season, archiving and resetting the game when it ends
+type SeasonManager struct {
+	store   Store
+	scoring *ScoringEngine // Optional, reset along with the claims
+	opts    SeasonOptions
+
+	mutex     sync.Mutex
+	number    int
+	startedAt time.Time
+
+	changed chan struct{} // Signals the scheduler that the season end moved
+	stop    chan struct{}
+	done    chan struct{}
+	now     func() time.Time
+	logger  *slog.Logger
+}
+
+// NewSeasonManager creates a season manager, resuming the season count from
+// existing archives. Without archives the first season starts now.
+func NewSeasonManager(store Store, scoring *ScoringEngine, opts SeasonOptions) (*SeasonManager, error) {
+	m := &SeasonManager{
+		store:     store,
+		scoring:   scoring,
+		opts:      opts,
+		number:    1,
+		startedAt: time.Now().UTC(),
+		changed:   make(chan struct{}, 1),
+		now:       time.Now,
+		logger:    componentLogger("season"),
+	}
+
+	last, err := m.lastArchive()
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		m.number = last.Number + 1
+		m.startedAt = last.EndedAt
+	}
+
+	return m, nil
+}
+
+// lastArchive reads the archive of the most recent finished season, nil if there is none
+func (m *SeasonManager) lastArchive() (*api.SeasonArchive, error) {
+	if m.opts.ArchiveDir == "" {
+		return nil, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(m.opts.ArchiveDir, "season-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	latest, latestPath := 0, ""
+	for _, path := range paths {
+		var number int
+		if _, err := fmt.Sscanf(filepath.Base(path), "season-%d.json", &number); err != nil {
+			continue
+		}
+		if number > latest {
+			latest, latestPath = number, path
+		}
+	}
+	if latestPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(latestPath)
+	if err != nil {
+		return nil, err
+	}
+	var archive api.SeasonArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse season archive %s: %w", latestPath, err)
+	}
+
+	return &archive, nil
+}
+
+// Current returns the current season
+func (m *SeasonManager) Current() api.SeasonResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	season := api.SeasonResponse{
+		Number:    m.number,
+		StartedAt: m.startedAt,
+	}
+	if m.opts.Length > 0 {
+		endsAt := m.startedAt.Add(m.opts.Length)
+		season.EndsAt = &endsAt
+	}
+	return season
+}
+
+// EndSeason archives the final state of the current season, resets the game
+// and starts the next season
+func (m *SeasonManager) EndSeason() (*api.SeasonArchive, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Once started, the season ends even if whoever ended it stops waiting
+	ctx := context.Background()
+
+	archive := &api.SeasonArchive{
+		Number:      m.number,
+		StartedAt:   m.startedAt,
+		EndedAt:     m.now().UTC(),
+		Leaderboard: m.store.GetLeaderboard(ctx, 0),
+		Claims:      m.store.GetAllClaims(ctx),
+	}
+	if m.scoring != nil {
+		archive.Scores = m.scoring.Scores()
+	}
+
+	if err := m.writeArchive(archive); err != nil {
+		return nil, fmt.Errorf("failed to archive season %d: %w", archive.Number, err)
+	}
+
+	if err := m.store.Reset(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reset claims: %w", err)
+	}
+	if m.scoring != nil {
+		if err := m.scoring.Reset(); err != nil {
+			return nil, fmt.Errorf("failed to reset scores: %w", err)
+		}
+	}
+
+	m.number++
+	m.startedAt = archive.EndedAt
+	m.logger.Info("Season ended", "season", archive.Number, "claims", len(archive.Claims))
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+
+	return archive, nil
+}
+
+// writeArchive saves a season archive to the archive directory, if configured
+func (m *SeasonManager) writeArchive(archive *api.SeasonArchive) error {
+	if m.opts.ArchiveDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.opts.ArchiveDir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial archive
+	path := filepath.Join(m.opts.ArchiveDir, fmt.Sprintf("season-%d.json", archive.Number))
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Start ends seasons in the background when their length elapses, until Stop is called
+func (m *SeasonManager) Start() {
+	if m.opts.Length <= 0 {
+		return
+	}
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+
+		for {
+			m.mutex.Lock()
+			wait := m.startedAt.Add(m.opts.Length).Sub(m.now())
+			m.mutex.Unlock()
+
+			timer := time.NewTimer(max(wait, 0))
+			select {
+			case <-m.stop:
+				timer.Stop()
+				return
+			case <-m.changed:
+				// Ended manually, reschedule for the new season
+				timer.Stop()
+			case <-timer.C:
+				if _, err := m.EndSeason(); err != nil {
+					m.logger.Error("Failed to end season", "error", err)
+					// Retry later rather than spinning on a persistent failure
+					select {
+					case <-m.stop:
+						return
+					case <-time.After(time.Minute):
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the background season scheduler
+func (m *SeasonManager) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+	m.stop = nil
+}
+
+// handleGetSeason returns the current season
+func (h *HTTPHandler) handleGetSeason(w http.ResponseWriter, r *http.Request) {
+	if h.seasons == nil {
+		writeError(w, r, notFound("seasons are disabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.seasons.Current()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminEndSeason ends the current season immediately and returns its archive
+func (h *HTTPHandler) handleAdminEndSeason(w http.ResponseWriter, r *http.Request) {
+	if h.seasons == nil {
+		writeError(w, r, notFound("seasons are disabled"))
+		return
+	}
+
+	archive, err := h.seasons.EndSeason()
+	if err != nil {
+		writeError(w, r, fmt.Errorf("ending season: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeasonManager_EndSeason tests that ending a season archives, resets and advances the season
+func TestSeasonManager_EndSeason(t *testing.T) {
+	archiveDir := t.TempDir()
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+
+	scoring, err := NewScoringEngine(store, ScoringOptions{Interval: time.Minute, AddressPoints: 1})
+	require.NoError(t, err)
+	scoring.Tick(time.Now())
+
+	opts := SeasonOptions{Length: time.Hour, ArchiveDir: archiveDir}
+	seasons, err := NewSeasonManager(store, scoring, opts)
+	require.NoError(t, err)
+	endedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	seasons.now = func() time.Time { return endedAt }
+
+	assert.Equal(t, 1, seasons.Current().Number, "First season should be 1")
+
+	archive, err := seasons.EndSeason()
+	require.NoError(t, err)
+	assert.Equal(t, 1, archive.Number, "Archive should record the ended season")
+	assert.Len(t, archive.Claims, 2, "Archive should contain the final claims")
+	assert.Len(t, archive.Leaderboard, 2, "Archive should contain the final leaderboard")
+	assert.Len(t, archive.Scores, 2, "Archive should contain the final scores")
+
+	assert.Empty(t, store.GetAllClaims(t.Context()), "Claims should be reset")
+	stats, _ := store.GetSubnetStats(t.Context(), "2001:db8::/32", 0)
+	assert.Empty(t, stats.Owner, "Subnet tree should be reset")
+	assert.Empty(t, scoring.Scores(), "Scores should be reset")
+
+	current := seasons.Current()
+	assert.Equal(t, 2, current.Number, "Next season should start")
+	assert.Equal(t, endedAt, current.StartedAt, "Next season should start when the last ended")
+	require.NotNil(t, current.EndsAt, "Season with a length should have an end")
+	assert.Equal(t, endedAt.Add(time.Hour), *current.EndsAt, "Season should end after its length")
+
+	// A restarted server resumes from the archives
+	restored, err := NewSeasonManager(NewClaimStore(), nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, current, restored.Current(), "Season should be restored from archives")
+}
+
+// TestHTTPHandler_EndSeason tests the season endpoints and admin authorization
+func TestHTTPHandler_EndSeason(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	seasons, err := NewSeasonManager(store, nil, SeasonOptions{})
+	require.NoError(t, err)
+
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	handler.seasons = seasons
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/season/end", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "Ending a season should require a token")
+	assert.Len(t, store.GetAllClaims(t.Context()), 1, "Unauthorized request should not reset claims")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/season/end", nil)
+	req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, "Admin should be able to end a season")
+	assert.Empty(t, store.GetAllClaims(t.Context()), "Ending a season should reset claims")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/season", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, "Season should be available")
+
+	var season api.SeasonResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&season))
+	assert.Equal(t, 2, season.Number, "Season counter should be incremented")
+	assert.Nil(t, season.EndsAt, "Season without a length should have no end")
+}
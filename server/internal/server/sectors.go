@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSectorNameLength is the most characters a sector name may have
+const maxSectorNameLength = 64
+
+// SectorOptions configures sector names, which operators give to chosen
+// subnets such as a hackerspace's /48 in place of their generated names
+type SectorOptions struct {
+	File string `yaml:"file"` // YAML file mapping subnets to names, saved on admin changes; memory only if empty
+}
+
+// SectorNames holds the names operators gave to subnets
+type SectorNames struct {
+	mutex sync.RWMutex
+	names map[string]string // Names by canonical subnet
+	file  string
+}
+
+// NewSectorNames creates the sector names, loading them from the options'
+// file if it exists
+func NewSectorNames(opts SectorOptions) (*SectorNames, error) {
+	s := &SectorNames{names: make(map[string]string), file: opts.File}
+	if opts.File == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(opts.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sector names: %w", err)
+	}
+
+	var names map[string]string
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&names); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse sector names %s: %w", opts.File, err)
+	}
+	for subnet, name := range names {
+		canonical, name, err := validateSector(subnet, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sector in %s: %w", opts.File, err)
+		}
+		s.names[canonical] = name
+	}
+	return s, nil
+}
+
+// validateSector checks a sector, returning its subnet in canonical form and
+// its name without surrounding space
+func validateSector(subnet, name string) (string, string, error) {
+	ipNet, err := api.ParseSubnet(subnet)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subnet %q", subnet)
+	}
+	prefixLen, _ := ipNet.Mask.Size()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", fmt.Errorf("sector %s has no name", subnet)
+	}
+	if len([]rune(name)) > maxSectorNameLength {
+		return "", "", fmt.Errorf("sector names must be at most %d characters", maxSectorNameLength)
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", "", errors.New("sector names must not contain control characters")
+	}
+	return api.CanonicalSubnet(ipNet.IP, prefixLen), name, nil
+}
+
+// Name returns the name of a subnet in canonical form, empty if it has none
+func (s *SectorNames) Name(subnet string) string {
+	if s == nil {
+		return ""
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.names[subnet]
+}
+
+// Set names a subnet, replacing any name it had, and returns the sector as stored
+func (s *SectorNames) Set(subnet, name string) (api.Sector, error) {
+	canonical, name, err := validateSector(subnet, name)
+	if err != nil {
+		return api.Sector{}, badRequest(err.Error())
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, existed := s.names[canonical]
+	s.names[canonical] = name
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			s.names[canonical] = previous
+		} else {
+			delete(s.names, canonical)
+		}
+		return api.Sector{}, err
+	}
+	return api.Sector{Subnet: canonical, Name: name}, nil
+}
+
+// Delete removes the name of a subnet, reporting whether it had one
+func (s *SectorNames) Delete(subnet string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	name, exists := s.names[subnet]
+	if !exists {
+		return false, nil
+	}
+	delete(s.names, subnet)
+	if err := s.saveLocked(); err != nil {
+		s.names[subnet] = name
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns every sector in address order, widest first for equal addresses
+func (s *SectorNames) List() []api.Sector {
+	sectors := make([]api.Sector, 0)
+	if s == nil {
+		return sectors
+	}
+
+	s.mutex.RLock()
+	for subnet, name := range s.names {
+		sectors = append(sectors, api.Sector{Subnet: subnet, Name: name})
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(sectors, func(i, j int) bool {
+		a, b := mustParseSubnet(sectors[i].Subnet), mustParseSubnet(sectors[j].Subnet)
+		if cmp := bytes.Compare(a.IP.To16(), b.IP.To16()); cmp != 0 {
+			return cmp < 0
+		}
+		return prefixLength(a) < prefixLength(b)
+	})
+	return sectors
+}
+
+// Resolve returns the subnets with a name, ignoring case, in CIDR notation
+func (s *SectorNames) Resolve(name string) []string {
+	var subnets []string
+	for _, sector := range s.List() {
+		if strings.EqualFold(sector.Name, name) {
+			subnets = append(subnets, sector.Subnet)
+		}
+	}
+	return subnets
+}
+
+// saveLocked writes the names to the file, if configured (assumes lock is held)
+func (s *SectorNames) saveLocked() error {
+	if s.file == "" {
+		return nil
+	}
+
+	data, err := yaml.Marshal(s.names)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves partial names
+	if err := os.WriteFile(s.file+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("saving sector names: %w", err)
+	}
+	if err := os.Rename(s.file+".tmp", s.file); err != nil {
+		return fmt.Errorf("saving sector names: %w", err)
+	}
+	return nil
+}
+
+// mustParseSubnet parses a subnet already known to be valid
+func mustParseSubnet(subnet string) *net.IPNet {
+	_, ipNet, _ := net.ParseCIDR(subnet)
+	return ipNet
+}
+
+// handleGetSectors lists the subnets operators named
+func (h *HTTPHandler) handleGetSectors(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.sectors.List()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminSetSector names a subnet, overriding its generated name
+func (h *HTTPHandler) handleAdminSetSector(w http.ResponseWriter, r *http.Request) {
+	if h.sectors == nil {
+		writeError(w, r, notFound("sector names are disabled"))
+		return
+	}
+
+	var req api.Sector
+	if err := h.decodeBody(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sector, err := h.sectors.Set(req.Subnet, req.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.logger.Info("Named sector", "subnet", sector.Subnet, "name", sector.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sector); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAdminDeleteSector removes the name of a subnet, restoring its generated name
+func (h *HTTPHandler) handleAdminDeleteSector(w http.ResponseWriter, r *http.Request) {
+	if h.sectors == nil {
+		writeError(w, r, notFound("sector names are disabled"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	subnet, err := api.ParseSubnet(vars["address"] + "/" + vars["prefix"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid subnet"))
+		return
+	}
+
+	deleted, err := h.sectors.Delete(api.CanonicalSubnet(subnet.IP, prefixLength(subnet)))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !deleted {
+		writeError(w, r, notFound("subnet has no sector name"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSectorNames_File tests that sector names are loaded from and saved to their file
+func TestSectorNames_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sectors.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`"2001:0db8:0001::/48": "  Our Hackerspace "`), 0o600))
+
+	sectors, err := NewSectorNames(SectorOptions{File: path})
+	require.NoError(t, err)
+	assert.Equal(t, "Our Hackerspace", sectors.Name("2001:db8:1::/48"), "Subnets and names should be normalized")
+
+	sector, err := sectors.Set("2001:db8:2::1/48", "University Lab")
+	require.NoError(t, err)
+	assert.Equal(t, api.Sector{Subnet: "2001:db8:2::/48", Name: "University Lab"}, sector)
+	deleted, err := sectors.Delete("2001:db8:1::/48")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	reloaded, err := NewSectorNames(SectorOptions{File: path})
+	require.NoError(t, err)
+	assert.Equal(t, []api.Sector{{Subnet: "2001:db8:2::/48", Name: "University Lab"}}, reloaded.List(), "Changes should be saved")
+
+	_, err = sectors.Set("2001:db8::/32", strings.Repeat("x", maxSectorNameLength+1))
+	assert.Error(t, err, "Long names should be rejected")
+	_, err = sectors.Set("2001:db8::/32", "Lab\n")
+	assert.NoError(t, err, "Surrounding space should be trimmed")
+	_, err = sectors.Set("10.0.0.0/8", "Lab")
+	assert.Error(t, err, "IPv4 subnets should be rejected")
+
+	require.NoError(t, os.WriteFile(path, []byte(`"2001:db8::/32": ""`), 0o600))
+	_, err = NewSectorNames(SectorOptions{File: path})
+	assert.Error(t, err, "Files with unnamed sectors should be rejected")
+}
+
+// TestHTTPHandler_Sectors tests that sector names override generated names in responses
+func TestHTTPHandler_Sectors(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	handler.sectors, _ = NewSectorNames(SectorOptions{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPut, "/api/v1/admin/sectors", `{"subnet": "2001:0db8::/32", "name": "Our Hackerspace"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodGet, "/api/v1/subnet/2001:db8::/32", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var subnet api.SubnetResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&subnet))
+	assert.Equal(t, "Our Hackerspace", subnet.Name)
+
+	rec = serve(http.MethodGet, "/api/v1/subnets/32", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var subnets []api.SubnetListEntry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&subnets))
+	require.Len(t, subnets, 1)
+	assert.Equal(t, "Our Hackerspace", subnets[0].Name)
+
+	rec = serve(http.MethodGet, "/api/v1/resolve?name=our+hackerspace", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resolved api.ResolveResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resolved))
+	assert.Equal(t, []string{"2001:db8::/32"}, resolved.Subnets, "Sector names should resolve")
+
+	rec = serve(http.MethodDelete, "/api/v1/admin/sectors/2001:db8::/32", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = serve(http.MethodDelete, "/api/v1/admin/sectors/2001:db8::/32", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "Subnets without a name cannot be unnamed")
+
+	rec = serve(http.MethodGet, "/api/v1/sectors", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+}
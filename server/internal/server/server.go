@@ -3,12 +3,15 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/bjia56/spacenet/server/names"
 	"github.com/gorilla/mux"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // Server represents the server for spacenet
@@ -18,37 +21,416 @@ type Server struct {
 	httpPort      int
 	httpHandler   *HTTPHandler
 	httpPortReady chan int
+	tls           TLSConfig
+	cors          CORSConfig
+	compress      bool
+	httpLimits    HTTPOptions
+	scoring       *ScoringEngine
+	bots          *BotSimulator
+	seasons       *SeasonManager
+	gameEvents    *GameEvents
+	claimPool     *ClaimPool
+	udp           *UDPListener
+	tracing       *sdktrace.TracerProvider
+	federation    *Federation
+	replica       ReplicaOptions
+	refresher     *ReplicaRefresher
+	compactor     *TreeCompactor
+	journal       *JournalCompactor
+	snapshots     *Snapshotter
+	webhooks      *Webhooks
+	advertiser    *Advertiser
+	lifecycle     *Lifecycle
+	load          func() error // Loads the claims once the server is listening
+	logger        *slog.Logger
 }
 
 // ServerOptions holds configuration options for the server
 type ServerOptions struct {
-	HTTPPort int
-	DBPath   string // Path to SQLite database file
+	HTTPPort           int
+	DBPath             string               // Path to SQLite database file
+	Levels             []int                // Prefix lengths of the subnet hierarchy, the standard eight if nil
+	Protected          []string             // IPv6 prefixes nobody may claim addresses in
+	Difficulty         *DifficultyParams    // Proof of work difficulty, defaults if nil
+	Fortification      FortificationOptions // Defense levels bought with extra proof of work
+	Heat               HeatOptions          // Difficulty added to recently taken over addresses, decaying over hours
+	ClaimSources       ClaimSourceOptions   // Record the address and client of each claim for operators
+	Anomalies          AnomalyOptions       // Watch claims for abuse, penalizing or quarantining claimants
+	SupplyLines        SupplyLineOptions    // Keep new claims near a player's holdings once they hold a few
+	PoW                PoWOptions           // Proof of work hash function, SHA-256 if unset
+	RateLimit          RateLimitConfig      // Per-client claim rate limit, disabled if zero
+	TLS                TLSConfig            // Serve the API over HTTPS if set
+	AdminTokens        []string             // Bearer tokens accepted by admin endpoints
+	CORS               CORSConfig           // Cross-origin policy for browser clients
+	Scoring            ScoringOptions       // Periodic scoring, disabled if the interval is zero
+	Season             SeasonOptions        // Season length and archive location
+	Artifacts          ArtifactOptions      // Artifact placement, disabled if none per subnet
+	Pprof              bool                 // Serve profiling endpoints under /debug/pprof
+	DisableCompression bool                 // Never gzip responses, e.g. behind a compressing proxy
+	ClaimPool          ClaimPoolOptions     // Claim worker pool, claims are processed inline if no workers
+	UDP                UDPOptions           // Optional UDP claim listener
+	ICMP               ICMPOptions          // Verify claims by ping instead of proof of work
+	DNSClaims          DNSClaimOptions      // Claim subnets by publishing TXT records in reverse DNS
+	Names              *NamePolicyOptions   // Claimant name policy, defaults if nil
+	Sectors            SectorOptions        // Names operators give to subnets, overriding generated names
+	GameEvents         GameEventOptions     // Events admins schedule, such as double score in a subnet for an hour
+	NamePack           string               // File of words replacing the built-in subnet names, if set
+	Delegation         DelegationOptions    // Tokens letting bots claim on a player's behalf
+	WebSessions        WebSessionOptions    // Lighter proofs of work with a claim quota for web clients
+	Bots               BotOptions           // Simulated claimants, disabled if the count is zero
+	Activity           ActivityOptions      // Recent claim history for subnet activity, disabled if no capacity
+	Health             HealthOptions        // Readiness checks, defaults if the timeout is zero
+	Tracing            TracingOptions       // Export traces of claim processing over OTLP, disabled if no endpoint
+	Federation         FederationOptions    // Share dominance summaries with peer servers, disabled if no prefixes
+	Replica            ReplicaOptions       // Serve reads from a primary's database without accepting writes
+	Limits             LimitOptions         // Caps on the claims and tree nodes held, unlimited if zero
+	Tree               TreeOptions          // Upkeep of the subnet tree, no compaction if zero
+	Journal            JournalOptions       // Append-only journal of the in-memory store, disabled if its path is empty
+	Snapshots          SnapshotOptions      // Snapshots of the in-memory store in object storage, disabled if no endpoint is set
+	HTTP               HTTPOptions          // Timeouts and request body limit of the API, none if zero
+	Webhooks           WebhookOptions       // Post claim events to external integrations, disabled if no URLs
+	Discovery          DiscoveryOptions     // Advertise the API on the local network over mDNS
 }
 
 // NewServerWithOptions creates a new spacenet server instance with custom options
 func NewServerWithOptions(opts ServerOptions) *Server {
-	var store Store
+	var store *ClaimStore
 	var err error
 
-	if opts.DBPath == "" {
+	if err := opts.Replica.Validate(); err != nil {
+		componentLogger("server").Error("Invalid replica options", "error", err)
+		os.Exit(1)
+	}
+
+	if opts.Replica.ReadOnly {
+		if opts.DBPath == "" {
+			componentLogger("server").Error("Read-only replicas need the database of a primary")
+			os.Exit(1)
+		}
+		store, err = NewClaimStoreReadOnly(opts.DBPath)
+		if err != nil {
+			componentLogger("server").Error("Failed to open the database of the primary", "path", opts.DBPath, "error", err)
+			os.Exit(1)
+		}
+	} else if opts.DBPath == "" {
 		store = NewClaimStore()
+		if err := opts.Journal.Validate(); err != nil {
+			componentLogger("server").Error("Invalid journal options", "error", err)
+			os.Exit(1)
+		}
 	} else {
-		// Use ClaimStore with SQLite backend
-		store, err = NewClaimStoreWithSQLite(opts.DBPath)
+		// Use ClaimStore with SQLite backend, loaded by Start
+		store, err = openClaimStoreWithSQLite(opts.DBPath)
 		if err != nil {
-			log.Fatalf("Failed to open SQLite database at %s: %v", opts.DBPath, err)
+			componentLogger("server").Error("Failed to open SQLite database", "path", opts.DBPath, "error", err)
+			os.Exit(1)
 		}
 	}
 
+	if opts.Levels != nil {
+		if err := ValidateLevels(opts.Levels); err != nil {
+			componentLogger("server").Error("Invalid levels", "error", err)
+			os.Exit(1)
+		}
+		store.SetLevels(opts.Levels)
+	}
+
+	if len(opts.Protected) > 0 {
+		protected, err := ParseProtectedPrefixes(opts.Protected)
+		if err != nil {
+			componentLogger("server").Error("Invalid protected prefixes", "error", err)
+			os.Exit(1)
+		}
+		store.SetProtectedPrefixes(protected)
+	}
+
+	if opts.Difficulty != nil {
+		store.SetDifficultyParams(*opts.Difficulty)
+	}
+
+	if err := opts.Fortification.Validate(); err != nil {
+		componentLogger("server").Error("Invalid fortification options", "error", err)
+		os.Exit(1)
+	}
+	store.SetFortificationOptions(opts.Fortification)
+
+	if err := opts.Heat.Validate(); err != nil {
+		componentLogger("server").Error("Invalid heat options", "error", err)
+		os.Exit(1)
+	}
+	store.SetHeatOptions(opts.Heat)
+	store.SetActivityOptions(opts.Activity)
+	store.SetClaimSourceOptions(opts.ClaimSources)
+
+	if err := opts.Anomalies.Validate(); err != nil {
+		componentLogger("server").Error("Invalid anomaly options", "error", err)
+		os.Exit(1)
+	}
+	store.SetAnomalyOptions(opts.Anomalies)
+
+	if err := opts.SupplyLines.Validate(); err != nil {
+		componentLogger("server").Error("Invalid supply line options", "error", err)
+		os.Exit(1)
+	}
+	store.SetSupplyLineOptions(opts.SupplyLines)
+
+	if err := opts.Limits.Validate(); err != nil {
+		componentLogger("server").Error("Invalid store limits", "error", err)
+		os.Exit(1)
+	}
+	store.SetLimitOptions(opts.Limits)
+
+	if err := opts.HTTP.Validate(); err != nil {
+		componentLogger("server").Error("Invalid HTTP options", "error", err)
+		os.Exit(1)
+	}
+
+	powScheme, err := NewPoWScheme(opts.PoW)
+	if err != nil {
+		componentLogger("server").Error("Invalid proof of work scheme", "error", err)
+		os.Exit(1)
+	}
+	store.SetPoWScheme(powScheme)
+
 	// Create HTTP handler for API endpoints
 	httpHandler := NewHTTPHandler(store)
+	httpHandler.adminTokens = opts.AdminTokens
+	httpHandler.pprof = opts.Pprof
+	httpHandler.maxBodyBytes = opts.HTTP.MaxBodyBytes
+	httpHandler.storeTimeout = opts.HTTP.StoreTimeout
+	httpHandler.supplyLines = opts.SupplyLines
+	if opts.Health.Timeout > 0 {
+		httpHandler.health = opts.Health
+	}
+
+	if opts.Names != nil {
+		names, err := NewNamePolicy(*opts.Names)
+		if err != nil {
+			componentLogger("server").Error("Invalid name policy", "error", err)
+			os.Exit(1)
+		}
+		httpHandler.names = names
+	}
+
+	if opts.NamePack != "" {
+		pack, err := names.LoadPackFile(opts.NamePack)
+		if err != nil {
+			componentLogger("server").Error("Invalid name pack", "path", opts.NamePack, "error", err)
+			os.Exit(1)
+		}
+		store.SetNamePack(pack)
+		httpHandler.namePack = pack
+		componentLogger("server").Info("Loaded name pack", "id", pack.PackID())
+	}
+
+	httpHandler.sectors, err = NewSectorNames(opts.Sectors)
+	if err != nil {
+		componentLogger("server").Error("Invalid sector names", "error", err)
+		os.Exit(1)
+	}
+
+	var federation *Federation
+	if opts.Federation.Enabled() {
+		federation, err = NewFederation(store, opts.Federation)
+		if err != nil {
+			componentLogger("server").Error("Invalid federation options", "error", err)
+			os.Exit(1)
+		}
+		httpHandler.federation = federation
+	}
+
+	var udp *UDPListener
+	if opts.UDP.Enabled && !opts.Replica.ReadOnly {
+		udp = NewUDPListener(store, opts.UDP)
+		udp.names = httpHandler.names
+		udp.federation = federation
+	}
+
+	if opts.ICMP.Enabled {
+		httpHandler.icmp = NewICMPVerifier(opts.ICMP)
+	}
+
+	if opts.DNSClaims.Enabled {
+		httpHandler.dnsClaims = NewDNSClaimVerifier(opts.DNSClaims)
+	}
+
+	if opts.Delegation.Enabled {
+		httpHandler.delegation, err = NewDelegationTokens(store, opts.Delegation)
+		if err != nil {
+			componentLogger("server").Error("Failed to load delegation tokens", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := opts.WebSessions.Validate(); err != nil {
+		componentLogger("server").Error("Invalid session challenge options", "error", err)
+		os.Exit(1)
+	}
+	if opts.WebSessions.Enabled {
+		httpHandler.sessions = NewWebSessions(opts.WebSessions)
+	}
+
+	var claimPool *ClaimPool
+	if opts.ClaimPool.Enabled() {
+		claimPool = NewClaimPool(opts.ClaimPool)
+		httpHandler.claimPool = claimPool
+	}
+
+	artifacts := NewArtifactSet(opts.Artifacts)
+	httpHandler.artifacts = artifacts
+
+	if err := opts.GameEvents.Validate(); err != nil {
+		componentLogger("server").Error("Invalid game event options", "error", err)
+		os.Exit(1)
+	}
+	gameEvents, err := NewGameEvents(store, opts.GameEvents)
+	if err != nil {
+		componentLogger("server").Error("Failed to load game events", "error", err)
+		os.Exit(1)
+	}
+	httpHandler.gameEvents = gameEvents
+
+	// Scores, seasons and bots are run by the primary, which writes their results
+	var scoring *ScoringEngine
+	if opts.Scoring.Enabled() && !opts.Replica.ReadOnly {
+		scoring, err = NewScoringEngine(store, opts.Scoring)
+		if err != nil {
+			componentLogger("server").Error("Failed to load score history", "error", err)
+			os.Exit(1)
+		}
+		scoring.artifacts = artifacts
+		scoring.events = gameEvents
+		httpHandler.scoring = scoring
+	}
+
+	var bots *BotSimulator
+	if opts.Bots.Count > 0 && !opts.Replica.ReadOnly {
+		bots, err = NewBotSimulator(store, opts.Bots)
+		if err != nil {
+			componentLogger("server").Error("Invalid bot options", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	seasons, err := NewSeasonManager(store, scoring, opts.Season)
+	if err != nil {
+		componentLogger("server").Error("Failed to load season archives", "error", err)
+		os.Exit(1)
+	}
+	httpHandler.seasons = seasons
+
+	if opts.RateLimit.ClaimsPerMinute > 0 {
+		httpHandler.rateLimiter = NewRateLimiter(opts.RateLimit.ClaimsPerMinute, opts.RateLimit.Burst)
+	}
+
+	var tracing *sdktrace.TracerProvider
+	if opts.Tracing.Enabled() {
+		tracing, err = NewTracerProvider(opts.Tracing)
+		if err != nil {
+			componentLogger("server").Error("Invalid tracing options", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var refresher *ReplicaRefresher
+	if opts.Replica.ReadOnly {
+		refresher = NewReplicaRefresher(store, opts.Replica.RefreshInterval)
+	}
+
+	// Replicas leave posting events to the primary, which sees every claim
+	var webhooks *Webhooks
+	if opts.Webhooks.Enabled() && !opts.Replica.ReadOnly {
+		webhooks, err = NewWebhooks(store, opts.Webhooks)
+		if err != nil {
+			componentLogger("server").Error("Invalid webhook options", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var advertiser *Advertiser
+	if opts.Discovery.Enabled {
+		advertiser, err = NewAdvertiser(opts.Discovery)
+		if err != nil {
+			componentLogger("server").Error("Invalid discovery options", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var compactor *TreeCompactor
+	if opts.Tree.CompactInterval > 0 {
+		compactor = NewTreeCompactor(store, opts.Tree.CompactInterval)
+	}
+
+	var journalCompactor *JournalCompactor
+	if opts.Journal.Enabled() && opts.DBPath == "" {
+		journalCompactor = NewJournalCompactor(store, opts.Journal.CompactInterval)
+	}
+
+	var snapshots *Snapshotter
+	if opts.Snapshots.Enabled() {
+		if err := opts.Snapshots.Validate(); err != nil {
+			componentLogger("server").Error("Invalid snapshot options", "error", err)
+			os.Exit(1)
+		}
+		if opts.DBPath != "" {
+			componentLogger("server").Error("Snapshots are only taken of the in-memory store")
+			os.Exit(1)
+		}
+		snapshots = NewSnapshotter(store, opts.Snapshots)
+	}
+
+	// Claims are loaded by Start once the server is listening, so probes can
+	// follow the progress of a long load instead of timing out
+	lifecycle := NewLifecycle()
+	store.loading = lifecycle
+	httpHandler.lifecycle = lifecycle
+	load := func() error {
+		if opts.DBPath != "" && !opts.Replica.ReadOnly {
+			if err := store.loadSQLite(); err != nil {
+				return fmt.Errorf("failed to load SQLite database %s: %w", opts.DBPath, err)
+			}
+		}
+		if opts.Journal.Enabled() && opts.DBPath == "" {
+			if err := store.OpenJournal(opts.Journal); err != nil {
+				return fmt.Errorf("failed to open journal %s: %w", opts.Journal.Path, err)
+			}
+		}
+		// Starting empty would overwrite the universe with the next upload
+		if snapshots != nil {
+			if err := snapshots.Restore(context.Background()); err != nil {
+				return fmt.Errorf("failed to restore snapshot: %w", err)
+			}
+		}
+		return nil
+	}
 
 	return &Server{
 		store:         store,
 		httpPort:      opts.HTTPPort,
+		tls:           opts.TLS,
+		cors:          opts.CORS,
+		compress:      !opts.DisableCompression,
+		httpLimits:    opts.HTTP,
+		scoring:       scoring,
+		bots:          bots,
+		seasons:       seasons,
+		gameEvents:    gameEvents,
+		claimPool:     claimPool,
+		udp:           udp,
+		tracing:       tracing,
+		federation:    federation,
+		replica:       opts.Replica,
+		refresher:     refresher,
+		compactor:     compactor,
+		journal:       journalCompactor,
+		snapshots:     snapshots,
+		webhooks:      webhooks,
+		advertiser:    advertiser,
+		lifecycle:     lifecycle,
+		load:          load,
 		httpHandler:   httpHandler,
 		httpPortReady: make(chan int, 1),
+		logger:        componentLogger("server"),
 	}
 }
 
@@ -59,42 +441,122 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
 
+	// Health checks are answered while the claims load, the API once they have
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.lifecycle.finish()
+
+	// Advertised once ready, so players are not sent to a loading server
+	if s.advertiser != nil {
+		if err := s.advertiser.Advertise(s.httpPort); err != nil {
+			s.logger.Error("Failed to advertise server", "error", err)
+		}
+	}
+
+	if s.udp != nil {
+		if err := s.udp.Listen(); err != nil {
+			return fmt.Errorf("failed to start UDP listener: %w", err)
+		}
+	}
+
+	if s.scoring != nil {
+		s.scoring.Start()
+	}
+	s.gameEvents.Start()
+	if s.refresher != nil {
+		s.refresher.Start()
+	} else {
+		s.seasons.Start()
+	}
+	if s.compactor != nil {
+		s.compactor.Start()
+	}
+	if s.journal != nil {
+		s.journal.Start()
+	}
+	if s.snapshots != nil {
+		s.snapshots.Start()
+	}
+	if s.bots != nil {
+		s.bots.Start()
+	}
+	if s.federation != nil {
+		s.federation.Start()
+	}
+	if s.webhooks != nil {
+		s.webhooks.Start()
+	}
+
 	return nil
 }
 
 // startHTTPServer starts the HTTP server for the API
 func (s *Server) startHTTPServer() error {
 	router := mux.NewRouter()
+	if s.tracing != nil {
+		router.Use(traceRequests)
+	}
 	s.httpHandler.RegisterRoutes(router)
 
+	var handler http.Handler = router
+	if s.replica.ReadOnly {
+		handler = readOnlyMiddleware(s.replica, handler)
+	}
+	handler = s.lifecycle.gate(handler)
+	if s.compress {
+		handler = gzipMiddleware(handler)
+	}
+	if s.cors.Enabled() {
+		handler = corsMiddleware(s.cors, handler)
+	}
+	handler = assignRequestIDs(handler)
+
+	// Cancel request contexts on shutdown so long-lived event streams end
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.httpPort),
-		Handler: router,
+		Addr:              fmt.Sprintf(":%d", s.httpPort),
+		Handler:           handler,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+		ReadHeaderTimeout: s.httpLimits.ReadHeaderTimeout,
+		ReadTimeout:       s.httpLimits.ReadTimeout,
+		WriteTimeout:      s.httpLimits.WriteTimeout,
+		IdleTimeout:       s.httpLimits.IdleTimeout,
 	}
+	s.httpServer.RegisterOnShutdown(cancelRequests)
 
-	// Start the HTTP server in a goroutine
-	go func() {
-		listener, err := net.Listen("tcp", s.httpServer.Addr)
-		if err != nil {
-			log.Printf("Failed to create HTTP listener: %v", err)
-			return
-		}
+	// Listen before returning, so health checks are answered while the claims load
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
 
-		// Update httpPort with the actual assigned port if using ephemeral port (0)
-		if s.httpPort == 0 {
-			s.httpPort = listener.Addr().(*net.TCPAddr).Port
-		}
+	// Update httpPort with the actual assigned port if using ephemeral port (0)
+	if s.httpPort == 0 {
+		s.httpPort = listener.Addr().(*net.TCPAddr).Port
+	}
 
-		// Notify that HTTP port is ready
-		select {
-		case s.httpPortReady <- s.httpPort:
-		default:
-			// Channel already has a value, which is fine
-		}
+	// Notify that HTTP port is ready
+	select {
+	case s.httpPortReady <- s.httpPort:
+	default:
+		// Channel already has a value, which is fine
+	}
 
-		log.Printf("SpaceNet HTTP server listening on :%d", s.httpPort)
-		if err := s.httpServer.Serve(listener); err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+	s.logger.Info("SpaceNet HTTP server listening", "port", s.httpPort, "tls", s.tls.Enabled())
+
+	// Serve in a goroutine
+	httpServer := s.httpServer
+	go func() {
+		var err error
+		if s.tls.Enabled() {
+			err = httpServer.ServeTLS(listener, s.tls.CertFile, s.tls.KeyFile)
+		} else {
+			err = httpServer.Serve(listener)
+		}
+		if err != http.ErrServerClosed {
+			s.logger.Error("HTTP server error", "error", err)
 		}
 	}()
 
@@ -113,11 +575,74 @@ func (s *Server) WaitForHTTPPort(timeout time.Duration) (int, error) {
 
 // Stop stops all server components
 func (s *Server) Stop() {
+	// Fail readiness first, giving load balancers time to stop sending
+	// requests before the listener closes
+	s.lifecycle.drain()
+	if s.httpLimits.DrainDelay > 0 && s.httpServer != nil {
+		s.logger.Info("Draining before shutdown", "delay", s.httpLimits.DrainDelay)
+		time.Sleep(s.httpLimits.DrainDelay)
+	}
+
+	if s.advertiser != nil {
+		s.advertiser.Stop()
+	}
 	s.stopHTTPServer()
 
+	// Finish queued claims while the journal, webhooks and store still take
+	// their events
+	if s.claimPool != nil {
+		s.claimPool.Stop()
+	}
+
+	if s.federation != nil {
+		s.federation.Stop()
+	}
+	if s.refresher != nil {
+		s.refresher.Stop()
+	}
+	if s.compactor != nil {
+		s.compactor.Stop()
+	}
+	if s.journal != nil {
+		s.journal.Stop()
+	}
+	if s.webhooks != nil {
+		s.webhooks.Stop()
+	}
+
+	if s.bots != nil {
+		s.bots.Stop()
+	}
+
+	if s.udp != nil {
+		if err := s.udp.Close(); err != nil {
+			s.logger.Error("Error closing UDP listener", "error", err)
+		}
+	}
+
+	s.seasons.Stop()
+	if s.scoring != nil {
+		s.scoring.Stop()
+	}
+	s.gameEvents.Stop()
+
+	// Upload the final state before the store closes
+	if s.snapshots != nil {
+		s.snapshots.Stop()
+	}
+
 	if s.store != nil {
 		if err := s.store.Close(); err != nil {
-			log.Printf("Error closing store during shutdown: %v", err)
+			s.logger.Error("Error closing store during shutdown", "error", err)
+		}
+	}
+
+	// Flush the spans of the last claims
+	if s.tracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.tracing.Shutdown(ctx); err != nil {
+			s.logger.Error("Error flushing traces", "error", err)
 		}
 	}
 }
@@ -125,15 +650,18 @@ func (s *Server) Stop() {
 // stopHTTPServer stops the HTTP server
 func (s *Server) stopHTTPServer() {
 	if s.httpServer != nil {
-		// Create a context with timeout for graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		// Requests in flight finish within the shutdown timeout, if there is one
+		ctx := context.Background()
+		if s.httpLimits.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.httpLimits.ShutdownTimeout)
+			defer cancel()
+		}
 
 		if err := s.httpServer.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down HTTP server: %v", err)
+			s.logger.Error("Error shutting down HTTP server", "error", err)
 		}
 
 		s.httpServer = nil
 	}
 }
-
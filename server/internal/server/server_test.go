@@ -63,27 +63,27 @@ func TestClaimStoreBasicOperations(t *testing.T) {
 	store := NewClaimStore()
 
 	// Test processing a claim
-	err := store.ProcessClaim("2001:db8::1", "testuser")
+	err := store.ProcessClaim(t.Context(), "2001:db8::1", "testuser")
 	require.NoError(t, err, "ProcessClaim should not fail")
 
 	// Test retrieving a claim
-	claimant, exists := store.GetClaim("2001:db8::1")
+	claimant, exists := store.GetClaim(t.Context(), "2001:db8::1")
 	assert.True(t, exists, "Claim should exist")
 	assert.Equal(t, "testuser", claimant, "Claimant should match")
 
 	// Test non-existent claim
-	_, exists = store.GetClaim("2001:db8::2")
+	_, exists = store.GetClaim(t.Context(), "2001:db8::2")
 	assert.False(t, exists, "Non-existent claim should not exist")
 
 	// Test getting all claims
-	allClaims := store.GetAllClaims()
+	allClaims := store.GetAllClaims(t.Context())
 	assert.Len(t, allClaims, 1, "Should have exactly 1 claim")
 
 	// Test overwriting claim
-	err = store.ProcessClaim("2001:db8::1", "newuser")
+	err = store.ProcessClaim(t.Context(), "2001:db8::1", "newuser")
 	require.NoError(t, err, "ProcessClaim should not fail on overwrite")
 
-	claimant, exists = store.GetClaim("2001:db8::1")
+	claimant, exists = store.GetClaim(t.Context(), "2001:db8::1")
 	assert.True(t, exists, "Overwritten claim should exist")
 	assert.Equal(t, "newuser", claimant, "Claimant should be updated")
 
@@ -122,7 +122,7 @@ func TestHTTPHandlerBasicOperations(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode, "Health check should return 200")
 
 	// Add a claim directly to the store
-	err = server.store.ProcessClaim("2001:db8::1", "testuser")
+	err = server.store.ProcessClaim(t.Context(), "2001:db8::1", "testuser")
 	require.NoError(t, err, "Adding claim should succeed")
 
 	// Test get claim endpoint
@@ -141,6 +141,7 @@ func TestHTTPHandlerBasicOperations(t *testing.T) {
 	require.NoError(t, err, "Response should decode successfully")
 
 	assert.Equal(t, "testuser", claimResp.Name, "Response claimant should match")
+	assert.NotNil(t, claimResp.ClaimedAt, "Response should include when the address was claimed")
 
 	// Test non-existent claim
 	resp, err = http.Get(fmt.Sprintf("%s/api/ip/2001:db8::999", baseURL))
@@ -185,7 +186,7 @@ func TestHTTPClaimProcessing(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode, "HTTP claim should return 201 Created")
 
 	// Verify the claim was processed by checking the store
-	claimant, exists := server.store.GetClaim(targetIP)
+	claimant, exists := server.store.GetClaim(t.Context(), targetIP)
 	assert.True(t, exists, "Claim should exist in store")
 	assert.Equal(t, "testuser", claimant, "Claimant should match")
 }
@@ -195,17 +196,17 @@ func TestSubnetStats(t *testing.T) {
 	store := NewClaimStore()
 
 	// Add some claims in a subnet
-	err := store.ProcessClaim("2001:db8::1", "user1")
+	err := store.ProcessClaim(t.Context(), "2001:db8::1", "user1")
 	require.NoError(t, err, "ProcessClaim should succeed")
 
-	err = store.ProcessClaim("2001:db8::2", "user1")
+	err = store.ProcessClaim(t.Context(), "2001:db8::2", "user1")
 	require.NoError(t, err, "ProcessClaim should succeed")
 
-	err = store.ProcessClaim("2001:db8::3", "user2")
+	err = store.ProcessClaim(t.Context(), "2001:db8::3", "user2")
 	require.NoError(t, err, "ProcessClaim should succeed")
 
 	// Test getting subnet stats
-	stats, ok := store.GetSubnetStats("2001:db8::/64")
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/64", 0)
 	assert.True(t, ok, "Subnet stats should be available")
 
 	// Should have stats (though specific values depend on the tree implementation)
@@ -234,7 +235,7 @@ func TestServerIntegration(t *testing.T) {
 	// Add claim via store (simulating UDP processing)
 	testIP := "2001:db8::42"
 	testUser := "integrationtest"
-	err = server.store.ProcessClaim(testIP, testUser)
+	err = server.store.ProcessClaim(t.Context(), testIP, testUser)
 	require.NoError(t, err, "Claim processing should succeed")
 
 	// Verify via HTTP API
@@ -297,9 +298,9 @@ func TestHTTPHandler_SubnetStats(t *testing.T) {
 	require.NoError(t, err, "HTTP port should be assigned within timeout")
 
 	// Add some claims to create subnet statistics
-	err = server.store.ProcessClaim("2001:db8::1", "user1")
+	err = server.store.ProcessClaim(t.Context(), "2001:db8::1", "user1")
 	require.NoError(t, err, "Adding claim should succeed")
-	err = server.store.ProcessClaim("2001:db8::2", "user1")
+	err = server.store.ProcessClaim(t.Context(), "2001:db8::2", "user1")
 	require.NoError(t, err, "Adding claim should succeed")
 
 	baseURL := fmt.Sprintf("http://localhost:%d", httpPort)
@@ -388,7 +389,7 @@ func TestHTTPServer_InvalidPayload(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Invalid JSON should return 400")
 
 	// Verify no claims were added (invalid payloads should be rejected)
-	allClaims := server.store.GetAllClaims()
+	allClaims := server.store.GetAllClaims(t.Context())
 	assert.Empty(t, allClaims, "Invalid payloads should not create claims")
 }
 
@@ -451,7 +452,7 @@ func TestHTTPServer_PayloadValidation(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Invalid IP should return 400")
 
 	// Verify no claims were added
-	allClaims := server.store.GetAllClaims()
+	allClaims := server.store.GetAllClaims(t.Context())
 	assert.Empty(t, allClaims, "Invalid payloads should not create claims")
 }
 
@@ -522,12 +523,12 @@ func TestHTTPServer_DuplicateClaimHandling(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Initial claim should be accepted")
 
 	// Verify claim exists
-	claimant, exists := server.store.GetClaim(targetIP)
+	claimant, exists := server.store.GetClaim(t.Context(), targetIP)
 	assert.True(t, exists, "Initial claim should exist")
 	assert.Equal(t, testUser, claimant, "Initial claimant should match")
 
 	// Get initial subnet stats
-	stats, ok := server.store.GetSubnetStats("2001:db8::1/128")
+	stats, ok := server.store.GetSubnetStats(t.Context(), "2001:db8::1/128", 0)
 	require.True(t, ok, "Should get initial subnet stats")
 	initialPercentage := stats.Percentage
 
@@ -541,18 +542,18 @@ func TestHTTPServer_DuplicateClaimHandling(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Duplicate claim should still be accepted by HTTP")
 
 	// Verify claim still exists and hasn't changed
-	claimant, exists = server.store.GetClaim(targetIP)
+	claimant, exists = server.store.GetClaim(t.Context(), targetIP)
 	assert.True(t, exists, "Claim should still exist after duplicate")
 	assert.Equal(t, testUser, claimant, "Claimant should still be the same")
 
 	// Most importantly: verify stats haven't inflated
-	stats, ok = server.store.GetSubnetStats("2001:db8::1/128")
+	stats, ok = server.store.GetSubnetStats(t.Context(), "2001:db8::1/128", 0)
 	require.True(t, ok, "Should still get subnet stats")
 	assert.Equal(t, initialPercentage, stats.Percentage, "Percentage should not change after duplicate claim")
 	assert.LessOrEqual(t, stats.Percentage, 100.0, "Percentage should never exceed 100%")
 
 	// Verify we still have only one claim total
-	allClaims := server.store.GetAllClaims()
+	allClaims := server.store.GetAllClaims(t.Context())
 	assert.Len(t, allClaims, 1, "Should still have exactly one claim")
 }
 
@@ -584,7 +585,7 @@ func TestHTTPServer_MultipleDuplicateClaimsPercentage(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Initial claim should be accepted")
 
 	// Get initial stats for comparison
-	stats, ok := server.store.GetSubnetStats("2001:db8::1/128")
+	stats, ok := server.store.GetSubnetStats(t.Context(), "2001:db8::1/128", 0)
 	require.True(t, ok, "Should get initial subnet stats")
 	initialPercentage := stats.Percentage
 
@@ -600,13 +601,13 @@ func TestHTTPServer_MultipleDuplicateClaimsPercentage(t *testing.T) {
 	}
 
 	// Verify stats remain unchanged
-	stats, ok = server.store.GetSubnetStats("2001:db8::1/128")
+	stats, ok = server.store.GetSubnetStats(t.Context(), "2001:db8::1/128", 0)
 	require.True(t, ok, "Should still get subnet stats after multiple duplicates")
 	assert.Equal(t, initialPercentage, stats.Percentage, "Percentage should remain unchanged after multiple duplicates")
 	assert.LessOrEqual(t, stats.Percentage, 100.0, "Percentage should never exceed 100%")
 
 	// Verify we still have only one claim
-	allClaims := server.store.GetAllClaims()
+	allClaims := server.store.GetAllClaims(t.Context())
 	assert.Len(t, allClaims, 1, "Should still have exactly one claim after multiple duplicates")
 }
 
@@ -673,7 +674,7 @@ func TestHTTPServer_SubnetStatsWithDuplicates(t *testing.T) {
 	t.Logf("HTTP API Subnet stats: Owner=%s, Percentage=%.6f%%", statsResp.Owner, statsResp.Percentage)
 
 	// Verify we have exactly the expected number of claims (no duplicates counted)
-	allClaims := server.store.GetAllClaims()
+	allClaims := server.store.GetAllClaims(t.Context())
 	assert.Len(t, allClaims, len(ips), "Should have exactly %d unique claims", len(ips))
 }
 
@@ -697,7 +698,7 @@ func TestClaimStore_ConcurrentAccess(t *testing.T) {
 			for j := 0; j < claimsPerGoroutine; j++ {
 				ip := fmt.Sprintf("2001:db8::%d:%d", goroutineID, j)
 				user := fmt.Sprintf("user%d", goroutineID)
-				err := store.ProcessClaim(ip, user)
+				err := store.ProcessClaim(t.Context(), ip, user)
 				assert.NoError(t, err, "Concurrent claim should succeed")
 			}
 		}(i)
@@ -709,7 +710,7 @@ func TestClaimStore_ConcurrentAccess(t *testing.T) {
 	}
 
 	// Verify all claims were processed
-	allClaims := store.GetAllClaims()
+	allClaims := store.GetAllClaims(t.Context())
 	expectedCount := numGoroutines * claimsPerGoroutine
 	assert.Len(t, allClaims, expectedCount, "All claims should be processed")
 }
@@ -752,7 +753,7 @@ func TestHTTPServer_ClaimOverwrite(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode, "First claim should be accepted")
 
 	// Verify first claim was processed
-	claimant, exists := server.store.GetClaim(targetIP)
+	claimant, exists := server.store.GetClaim(t.Context(), targetIP)
 	assert.True(t, exists, "First claim should exist")
 	assert.Equal(t, "firstuser", claimant, "First claimant should match")
 
@@ -767,11 +768,157 @@ func TestHTTPServer_ClaimOverwrite(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Second claim should be accepted")
 
 	// Verify claim was overwritten
-	claimant, exists = server.store.GetClaim(targetIP)
+	claimant, exists = server.store.GetClaim(t.Context(), targetIP)
 	assert.True(t, exists, "Overwritten claim should exist")
 	assert.Equal(t, "seconduser", claimant, "Claimant should be updated to second user")
 
 	// Verify only one claim exists for this IP
-	allClaims := server.store.GetAllClaims()
+	allClaims := server.store.GetAllClaims(t.Context())
 	assert.Len(t, allClaims, 1, "Should have exactly one claim")
 }
+
+// TestHTTPHandler_GetAllSubnets tests enumerating claimed subnets at a level
+func TestHTTPHandler_GetAllSubnets(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		HTTPPort: 0,
+	})
+
+	err := server.Start()
+	require.NoError(t, err, "Server should start successfully")
+	defer server.Stop()
+
+	httpPort, err := server.WaitForHTTPPort(5 * time.Second)
+	require.NoError(t, err, "HTTP port should be assigned within timeout")
+
+	err = server.store.ProcessClaim(t.Context(), "2001:db8::1", "user1")
+	require.NoError(t, err, "Adding claim should succeed")
+	err = server.store.ProcessClaim(t.Context(), "2001:db9::1", "user2")
+	require.NoError(t, err, "Adding claim should succeed")
+
+	baseURL := fmt.Sprintf("http://localhost:%d", httpPort)
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/subnets/128", baseURL))
+	require.NoError(t, err, "Subnet listing request should succeed")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Subnet listing should return 200")
+
+	var subnets []api.SubnetListEntry
+	err = json.NewDecoder(resp.Body).Decode(&subnets)
+	require.NoError(t, err, "Subnet listing should decode successfully")
+
+	owners := make(map[string]string)
+	for _, entry := range subnets {
+		owners[entry.Subnet] = entry.Owner
+	}
+	assert.Equal(t, map[string]string{
+		"2001:db8::1/128": "user1",
+		"2001:db9::1/128": "user2",
+	}, owners, "Listing should contain both claimed /128s")
+
+	// Non-standard prefixes are rejected
+	resp, err = http.Get(fmt.Sprintf("%s/api/subnets/20", baseURL))
+	require.NoError(t, err, "Subnet listing request should succeed")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Non-standard prefix should return 400")
+}
+
+// TestHTTPHandler_GetAllSubnetsQuery tests filtering, sorting and paging the subnet listing
+func TestHTTPHandler_GetAllSubnetsQuery(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		HTTPPort: 0,
+	})
+
+	err := server.Start()
+	require.NoError(t, err, "Server should start successfully")
+	defer server.Stop()
+
+	httpPort, err := server.WaitForHTTPPort(5 * time.Second)
+	require.NoError(t, err, "HTTP port should be assigned within timeout")
+
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::1", "carol"))
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::3", "bob"))
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db9::1", "dave"))
+
+	baseURL := fmt.Sprintf("http://localhost:%d", httpPort)
+
+	resp, err := http.Get(baseURL + "/api/v1/subnets/128?within=2001:db8::/32&sort=owner&order=desc&limit=2")
+	require.NoError(t, err, "Subnet listing request should succeed")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Subnet listing should return 200")
+	assert.Equal(t, "3", resp.Header.Get("X-Total-Count"), "Total should count the subnets within before paging")
+
+	var subnets []api.SubnetListEntry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&subnets), "Subnet listing should decode successfully")
+	require.Len(t, subnets, 2, "Listing should be limited")
+	assert.Equal(t, "carol", subnets[0].Owner, "Owners should be sorted descending")
+	assert.Equal(t, "bob", subnets[1].Owner, "Owners should be sorted descending")
+
+	for _, query := range []string{"sort=size", "order=up", "offset=-1", "limit=0", "claimed=maybe", "within=2001:db8::"} {
+		resp, err := http.Get(baseURL + "/api/v1/subnets/128?" + query)
+		require.NoError(t, err, "Subnet listing request should succeed")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Query %q should return 400", query)
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}
+}
+
+// TestHTTPHandler_GetOwners tests summarizing the owners of the subnets within a parent
+func TestHTTPHandler_GetOwners(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		HTTPPort: 0,
+	})
+
+	err := server.Start()
+	require.NoError(t, err, "Server should start successfully")
+	defer server.Stop()
+
+	httpPort, err := server.WaitForHTTPPort(5 * time.Second)
+	require.NoError(t, err, "HTTP port should be assigned within timeout")
+
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::3", "bob"))
+
+	baseURL := fmt.Sprintf("http://localhost:%d", httpPort)
+
+	resp, err := http.Get(baseURL + "/api/v1/owners/2001:db8::/112/128")
+	require.NoError(t, err, "Owners request should succeed")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Owners should return 200")
+
+	var summary api.OwnersResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary), "Owners should decode successfully")
+	assert.Equal(t, 3, summary.Subnets)
+	require.Len(t, summary.Owners, 2)
+	assert.Equal(t, "alice", summary.Owners[0].Name, "Owner of the most subnets should be first")
+	assert.Equal(t, 2, summary.Owners[0].Subnets)
+
+	for _, path := range []string{"/api/v1/owners/2001:db8::/112/64", "/api/v1/owners/2001:db8::/112/120", "/api/v1/owners/nowhere/112/128"} {
+		resp, err := http.Get(baseURL + path)
+		require.NoError(t, err, "Owners request should succeed")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "%s should return 400", path)
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Error closing response body: %v", err)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotOptions configures periodic snapshots of the claims to S3-compatible
+// object storage, restored on startup, so deployments without a persistent
+// disk keep their universe between restarts
+type SnapshotOptions struct {
+	Endpoint  string        `yaml:"endpoint"`  // Object storage URL, e.g. https://s3.us-east-1.amazonaws.com, disabled if empty
+	Bucket    string        `yaml:"bucket"`    // Bucket the snapshot is written to
+	Key       string        `yaml:"key"`       // Object key of the snapshot
+	Region    string        `yaml:"region"`    // Region requests are signed for
+	AccessKey string        `yaml:"accessKey"` // Credentials, requests are unsigned if empty
+	SecretKey string        `yaml:"secretKey"`
+	Interval  time.Duration `yaml:"interval"` // Time between uploads, the last one is made on shutdown
+	Timeout   time.Duration `yaml:"timeout"`  // Longest an upload or download may take
+}
+
+// DefaultSnapshotOptions returns the standard snapshot options, disabled until an endpoint is set
+func DefaultSnapshotOptions() SnapshotOptions {
+	return SnapshotOptions{
+		Key:      "spacenet/snapshot.jsonl.gz",
+		Region:   "us-east-1",
+		Interval: 5 * time.Minute,
+		Timeout:  time.Minute,
+	}
+}
+
+// Enabled reports whether snapshots are taken
+func (o SnapshotOptions) Enabled() bool {
+	return o.Endpoint != ""
+}
+
+// Validate checks the options are usable
+func (o SnapshotOptions) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if endpoint, err := url.Parse(o.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("snapshots endpoint must be an http or https URL, got %q", o.Endpoint)
+	}
+	if o.Bucket == "" || strings.Trim(o.Key, "/") == "" {
+		return errors.New("snapshots need a bucket and key")
+	}
+	if o.Region == "" {
+		return errors.New("snapshots need a region")
+	}
+	if (o.AccessKey == "") != (o.SecretKey == "") {
+		return errors.New("snapshots need both accessKey and secretKey, or neither")
+	}
+	if o.Interval <= 0 || o.Timeout <= 0 {
+		return errors.New("snapshots interval and timeout must be positive")
+	}
+	return nil
+}
+
+// objectURL returns the path-style URL of the snapshot object
+func (o SnapshotOptions) objectURL() string {
+	return strings.TrimRight(o.Endpoint, "/") + "/" + s3Escape(o.Bucket) + "/" + s3Escape(strings.Trim(o.Key, "/"))
+}
+
+// WriteSnapshot writes the claims, grants and claimant names of the store as
+// gzipped lines of journal entries
+func (cs *ClaimStore) WriteSnapshot(w io.Writer) error {
+	cs.mutex.RLock()
+	entries := cs.journalSnapshotLocked()
+	cs.mutex.RUnlock()
+
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return gz.Close()
+}
+
+// RestoreSnapshot loads a snapshot written by WriteSnapshot into the store,
+// returning the entries loaded. Journaled stores journal the restored state.
+func (cs *ClaimStore) RestoreSnapshot(r io.Reader) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if cs.db != nil {
+		return 0, errors.New("only in-memory stores are restored from snapshots")
+	}
+	if len(cs.claims) > 0 || len(cs.grants) > 0 {
+		return 0, errors.New("snapshots are only restored into an empty store")
+	}
+
+	cs.loading.begin(startupSnapshot, 0)
+	entries, err := cs.loadJournalEntriesLocked(gz, "snapshot")
+	if err != nil {
+		return entries, err
+	}
+	if cs.journal != nil {
+		return entries, cs.journal.rewrite(cs.journalSnapshotLocked())
+	}
+	return entries, nil
+}
+
+// snapshotClient reads and writes the snapshot object over the S3 API
+type snapshotClient struct {
+	opts   SnapshotOptions
+	client *http.Client
+	now    func() time.Time
+}
+
+// newSnapshotClient creates a client for the snapshot object of opts
+func newSnapshotClient(opts SnapshotOptions) *snapshotClient {
+	return &snapshotClient{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		now:    time.Now,
+	}
+}
+
+// get downloads the snapshot, reporting false if there is none yet
+func (c *snapshotClient) get(ctx context.Context) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.opts.objectURL(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	c.sign(req, nil)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("downloading snapshot: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, err == nil, err
+}
+
+// put uploads the snapshot, replacing the previous one
+func (c *snapshotClient) put(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.opts.objectURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	c.sign(req, body)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("uploading snapshot: %s", resp.Status)
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 authorization to a request with the
+// given body, leaving it unsigned without credentials
+func (c *snapshotClient) sign(req *http.Request, body []byte) {
+	if c.opts.AccessKey == "" {
+		return
+	}
+	signV4(req, body, c.opts.Region, c.opts.AccessKey, c.opts.SecretKey, c.now().UTC())
+}
+
+// signV4 signs a request for S3 with AWS Signature Version 4, covering the host
+// and every header already set
+func signV4(req *http.Request, body []byte, region, accessKey, secretKey string, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	// Canonical headers are the lower-cased names in order with trimmed values
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	date := amzDate[:8]
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes an object path as S3 signs it, keeping slashes and
+// the unreserved characters
+func s3Escape(path string) string {
+	var b strings.Builder
+	for _, c := range []byte(path) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// Snapshotter restores a store from its snapshot on startup, then uploads a
+// new snapshot periodically and on shutdown
+type Snapshotter struct {
+	store    *ClaimStore
+	client   *snapshotClient
+	interval time.Duration
+	timeout  time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+// NewSnapshotter creates a snapshotter for a store
+func NewSnapshotter(store *ClaimStore, opts SnapshotOptions) *Snapshotter {
+	return &Snapshotter{
+		store:    store,
+		client:   newSnapshotClient(opts),
+		interval: opts.Interval,
+		timeout:  opts.Timeout,
+		logger:   componentLogger("snapshots"),
+	}
+}
+
+// Restore loads the latest snapshot into the store, unless there is none yet
+// or the store already holds claims, such as from a journal that survived
+func (s *Snapshotter) Restore(ctx context.Context) error {
+	if claims := s.store.GetStats(ctx).TotalClaims; claims > 0 {
+		s.logger.Info("Store is not empty, skipping snapshot restore", "claims", claims)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	body, found, err := s.client.get(ctx)
+	if err != nil {
+		return err
+	}
+	if !found {
+		s.logger.Info("No snapshot to restore, starting a new universe")
+		return nil
+	}
+
+	entries, err := s.store.RestoreSnapshot(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	stats := s.store.GetStats(ctx)
+	s.logger.Info("Restored snapshot", "entries", entries, "claims", stats.TotalClaims, "claimants", stats.Claimants)
+	return nil
+}
+
+// Upload writes a snapshot of the store to object storage
+func (s *Snapshotter) Upload(ctx context.Context) error {
+	var body bytes.Buffer
+	if err := s.store.WriteSnapshot(&body); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.client.put(ctx, body.Bytes()); err != nil {
+		return err
+	}
+	s.logger.Debug("Uploaded snapshot", "bytes", body.Len())
+	return nil
+}
+
+// Start begins uploading snapshots in the background
+func (s *Snapshotter) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.Upload(context.Background()); err != nil {
+					s.logger.Error("Error uploading snapshot", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops uploading, then uploads a last snapshot of the final state
+func (s *Snapshotter) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+
+	if err := s.Upload(context.Background()); err != nil {
+		s.logger.Error("Error uploading final snapshot", "error", err)
+	}
+}
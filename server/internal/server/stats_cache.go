@@ -0,0 +1,89 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// statsCacheSize is the number of subnets whose statistics are kept, enough for
+// every row of a fully scrolled TUI table
+const statsCacheSize = 1 << 16
+
+// statsCache is an LRU cache of computed subnet statistics. A node without a
+// cache entry is dirty, so writes only need to drop the entry and dominance is
+// recalculated on the next read instead of on every claim.
+type statsCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[*IPNode]*list.Element
+	order    *list.List // Most recently used first
+}
+
+// statsCacheEntry is a cached node's statistics
+type statsCacheEntry struct {
+	node  *IPNode
+	stats SubnetStats
+}
+
+// newStatsCache creates an LRU cache holding up to capacity subnets
+func newStatsCache(capacity int) *statsCache {
+	return &statsCache{
+		capacity: capacity,
+		entries:  make(map[*IPNode]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns a copy of the cached statistics of a node
+func (c *statsCache) get(node *IPNode) (*SubnetStats, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.entries[node]
+	if !exists {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	stats := element.Value.(*statsCacheEntry).stats
+	return &stats, true
+}
+
+// add caches the statistics of a node, evicting the least recently used entry if full
+func (c *statsCache) add(node *IPNode, stats SubnetStats) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.entries[node]; exists {
+		element.Value.(*statsCacheEntry).stats = stats
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[node] = c.order.PushFront(&statsCacheEntry{node: node, stats: stats})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*statsCacheEntry).node)
+	}
+}
+
+// invalidate marks a node dirty by dropping its cached statistics
+func (c *statsCache) invalidate(node *IPNode) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.entries[node]; exists {
+		c.order.Remove(element)
+		delete(c.entries, node)
+	}
+}
+
+// clear drops every cached entry
+func (c *statsCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[*IPNode]*list.Element)
+	c.order.Init()
+}
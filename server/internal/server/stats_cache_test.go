@@ -0,0 +1,53 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsCache_Eviction tests least recently used eviction and invalidation
+func TestStatsCache_Eviction(t *testing.T) {
+	cache := newStatsCache(2)
+	a, b, c := &IPNode{}, &IPNode{}, &IPNode{}
+
+	cache.add(a, SubnetStats{Owner: "alice"})
+	cache.add(b, SubnetStats{Owner: "bob"})
+	_, ok := cache.get(a) // a is now more recently used than b
+	require.True(t, ok, "Cached node should be found")
+
+	cache.add(c, SubnetStats{Owner: "carol"})
+	_, ok = cache.get(b)
+	assert.False(t, ok, "Least recently used node should be evicted")
+
+	stats, ok := cache.get(a)
+	require.True(t, ok, "Recently used node should be kept")
+	assert.Equal(t, "alice", stats.Owner, "Cached stats should be returned")
+
+	stats.Owner = "mallory"
+	stats, _ = cache.get(a)
+	assert.Equal(t, "alice", stats.Owner, "Callers should get a copy of cached stats")
+
+	cache.invalidate(a)
+	_, ok = cache.get(a)
+	assert.False(t, ok, "Invalidated node should be dropped")
+}
+
+// TestIPTree_LazyDominance tests that cached subnet stats are recalculated after writes
+func TestIPTree_LazyDominance(t *testing.T) {
+	tree := NewIPTree()
+	tree.processClaim("2001:db8::1", "alice", "")
+
+	stats, ok := tree.GetSubnetStats("2001:db8::1/128", 0)
+	require.True(t, ok, "Subnet query should succeed")
+	assert.Equal(t, "alice", stats.Owner, "alice should own her address")
+
+	tree.processClaim("2001:db8::1", "bob", "alice")
+	stats, _ = tree.GetSubnetStats("2001:db8::1/128", 0)
+	assert.Equal(t, "bob", stats.Owner, "Takeover should invalidate the cached owner")
+
+	tree.reset()
+	stats, _ = tree.GetSubnetStats("2001:db8::1/128", 0)
+	assert.Empty(t, stats.Owner, "Reset should clear cached owners")
+}
@@ -1,29 +1,146 @@
 package server
 
-import "github.com/bjia56/spacenet/server/api"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
 
 // SubnetStats represents statistics about a subnet
 type SubnetStats = api.SubnetResponse
 
-// Store defines the interface for claim storage backends
+// ClaimMetadata records the history of the claim on an address
+type ClaimMetadata struct {
+	ClaimedAt     time.Time   // When the current claimant took the address
+	TakeoverCount int         // Times the address has changed hands
+	Fortification int         // Defense level bought by the owner, before decay
+	FortifiedAt   time.Time   // When the owner last fortified the address
+	Source        ClaimSource // Where the latest claim came from
+}
+
+// ClaimSource records where the latest claim on an address came from, for
+// operators investigating abuse. It is empty if sources are not collected.
+type ClaimSource struct {
+	Channel   string `json:"channel,omitempty"`   // How the claim arrived, such as "http" or "udp"
+	RemoteIP  string `json:"remoteIP,omitempty"`  // Address of the client that sent the claim
+	UserAgent string `json:"userAgent,omitempty"` // User-Agent of an HTTP client, truncated
+}
+
+// BatchClaim is one claim in a batch applied by ProcessClaims
+type BatchClaim struct {
+	IP       string
+	Claimant string
+}
+
+// Typed store failures, which handlers answer with the status of their kind
+var (
+	ErrBackendUnavailable = errors.New("store backend is unavailable")
+	ErrInvalidAddress     = errors.New("invalid address")
+	ErrProtected          = errors.New("address is protected from claims")
+	ErrRateLimited        = errors.New("rate limit exceeded")
+)
+
+// backendError marks a failure of the database behind a store, keeping the
+// cause so timeouts are still reported as such
+func backendError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+}
+
+// Store defines the interface for claim storage backends. Methods that fail
+// return the errors above, or wrap them, where one applies.
 type Store interface {
-	// ProcessClaim processes a claim request and updates the store
-	ProcessClaim(ipAddr string, claimant string) error
+	// ProcessClaim processes a claim request and updates the store, refusing
+	// unparsable addresses with ErrInvalidAddress and protected ones with ErrProtected
+	ProcessClaim(ctx context.Context, ipAddr string, claimant string) error
+
+	// ProcessClaims applies a batch of claims atomically, either all of them or none
+	ProcessClaims(ctx context.Context, batch []BatchClaim) error
+
+	// Unclaim releases the claim on an address, which must be held by
+	// claimant unless claimant is empty
+	Unclaim(ctx context.Context, ipAddr string, claimant string) error
 
 	// GetClaim retrieves the claimant for an IP address
-	GetClaim(ipAddr string) (string, bool)
+	GetClaim(ctx context.Context, ipAddr string) (string, bool)
+
+	// GetClaimMetadata retrieves the history of the claim on an IP address,
+	// with its current defense level after decay
+	GetClaimMetadata(ctx context.Context, ipAddr string) (ClaimMetadata, bool)
+
+	// Fortify raises the defense level of an address held by claimant, returning the new level
+	Fortify(ctx context.Context, ipAddr string, claimant string) (int, error)
 
 	// GetAllClaims returns all claims in the store
-	GetAllClaims() map[string]string
+	GetAllClaims(ctx context.Context) map[string]string
+
+	// GetSubnetStats retrieves statistics for a specific subnet,
+	// including the top N claimants when topN is positive
+	GetSubnetStats(ctx context.Context, subnet string, topN int) (*SubnetStats, bool)
 
-	// GetSubnetStats retrieves statistics for a specific subnet
-	GetSubnetStats(subnet string) (*SubnetStats, bool)
+	// GetAllSubnets retrieves statistics for all claimed subnets at a tracked prefix length
+	GetAllSubnets(ctx context.Context, prefixLen int) ([]api.SubnetListEntry, bool)
+
+	// ListSubnets retrieves a page of the claimed subnets at a tracked prefix
+	// length selected and sorted by the options, with the number selected
+	// before paging
+	ListSubnets(ctx context.Context, prefixLen int, opts SubnetListOptions) ([]api.SubnetListEntry, int, bool)
+
+	// SummarizeOwners counts the subnets at a tracked prefix length within a
+	// parent subnet that each claimant owns
+	SummarizeOwners(ctx context.Context, parent *net.IPNet, prefixLen int) (*api.OwnersResponse, bool)
+
+	// Levels returns the prefix lengths of the subnet hierarchy, widest first
+	Levels(ctx context.Context) []int
+
+	// GetSubnetActivity summarizes recent claims in a subnet over a rolling window
+	GetSubnetActivity(ctx context.Context, subnet string) (*api.SubnetActivityResponse, error)
+
+	// GetClaimHistory lists the recent claims on an IP address, newest first
+	GetClaimHistory(ctx context.Context, ipAddr string) (*api.ClaimHistoryResponse, error)
 
 	// CalculateDifficulty calculates the difficulty for a given target
-	CalculateDifficulty(targetIP string) uint8
+	CalculateDifficulty(ctx context.Context, targetIP string) uint8
+
+	// CalculateSubnetDifficulty calculates the difficulty for every address in a subnet
+	CalculateSubnetDifficulty(ctx context.Context, subnet string) (*api.SubnetDifficultyResponse, bool)
+
+	// PoWScheme returns the hash function proofs of work are solved with
+	PoWScheme(ctx context.Context) api.PoWScheme
 
 	// ValidateProofOfWork checks if the provided proof of work is valid
-	ValidateProofOfWork(pow *api.ProofOfWork) error
+	ValidateProofOfWork(ctx context.Context, pow *api.ProofOfWork) error
+
+	// GetLeaderboard returns claimants ranked by addresses held, limited to the top limit if positive
+	GetLeaderboard(ctx context.Context, limit int) []api.LeaderboardEntry
+
+	// GetStats returns global statistics about the game
+	GetStats(ctx context.Context) api.StatsResponse
+
+	// GetLevelStats returns the distribution of dominance over the claimed
+	// subnets at each tracked prefix length, widest first
+	GetLevelStats(ctx context.Context) []api.LevelStats
+
+	// ResolveName returns the claimed or granted subnets whose generated name
+	// matches, ignoring case, in CIDR notation
+	ResolveName(ctx context.Context, name string) []string
+
+	// SubscribeEvents subscribes to the live feed of claim events, returning
+	// the event channel and a function to unsubscribe
+	SubscribeEvents(ctx context.Context) (<-chan api.ClaimEvent, func())
+
+	// GrantSubnet gives a claimant dominance of a whole subnet, such as one
+	// whose ownership they proved outside the game
+	GrantSubnet(ctx context.Context, subnet string, claimant string) error
+
+	// Reset removes every claim, as at the start of a new season
+	Reset(ctx context.Context) error
 
 	// Close releases any resources held by the store
 	Close() error
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// ErrStoreFull marks claims refused because the store is at a size limit
+var ErrStoreFull = errors.New("store is full")
+
+// Policies for claims on new addresses once the store is at a limit
+const (
+	LimitPolicyReject = "reject" // Refuse the claim
+	LimitPolicyEvict  = "evict"  // Release the least recently claimed addresses to make room
+)
+
+// LimitOptions caps the memory the store uses, so a public in-memory
+// instance survives someone claiming millions of scattered addresses
+type LimitOptions struct {
+	MaxClaims    int    `yaml:"maxClaims"`    // Addresses claimed at once, unlimited if zero
+	MaxTreeNodes int    `yaml:"maxTreeNodes"` // Subnet nodes in the tree, unlimited if zero
+	Policy       string `yaml:"policy"`       // "reject" (default) or "evict" claims on new addresses at a limit
+}
+
+// DefaultLimitOptions returns the standard limit options, without limits
+func DefaultLimitOptions() LimitOptions {
+	return LimitOptions{Policy: LimitPolicyReject}
+}
+
+// Enabled reports whether any limit is set
+func (o LimitOptions) Enabled() bool {
+	return o.MaxClaims > 0 || o.MaxTreeNodes > 0
+}
+
+// Validate checks that the limit options are usable
+func (o LimitOptions) Validate() error {
+	if o.MaxClaims < 0 || o.MaxTreeNodes < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if o.Policy != "" && o.Policy != LimitPolicyReject && o.Policy != LimitPolicyEvict {
+		return fmt.Errorf("limits policy must be %q or %q, got %q", LimitPolicyReject, LimitPolicyEvict, o.Policy)
+	}
+	return nil
+}
+
+// claimLimits enforces the limits of a store, guarded by the store's mutex
+type claimLimits struct {
+	opts     LimitOptions
+	order    *list.List               // Addresses from least to most recently claimed, kept only to evict
+	elements map[string]*list.Element // Elements of order by address
+	rejected atomic.Uint64
+	evicted  atomic.Uint64
+}
+
+// evicting reports whether the oldest claims are released at a limit
+func (l *claimLimits) evicting() bool {
+	return l.opts.Policy == LimitPolicyEvict
+}
+
+// touch marks an address as the most recently claimed
+func (l *claimLimits) touch(ipAddr string) {
+	if l == nil || !l.evicting() {
+		return
+	}
+	if element, exists := l.elements[ipAddr]; exists {
+		l.order.MoveToBack(element)
+		return
+	}
+	l.elements[ipAddr] = l.order.PushBack(ipAddr)
+}
+
+// forget drops a released address
+func (l *claimLimits) forget(ipAddr string) {
+	if l == nil || !l.evicting() {
+		return
+	}
+	if element, exists := l.elements[ipAddr]; exists {
+		l.order.Remove(element)
+		delete(l.elements, ipAddr)
+	}
+}
+
+// clear forgets every address
+func (l *claimLimits) clear() {
+	if l == nil {
+		return
+	}
+	l.order.Init()
+	l.elements = make(map[string]*list.Element)
+}
+
+// SetLimitOptions sets the size limits of the store, which apply to claims
+// on new addresses. Claims already held beyond a lower limit are kept.
+func (cs *ClaimStore) SetLimitOptions(opts LimitOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if !opts.Enabled() {
+		cs.limits = nil
+		return
+	}
+
+	cs.limits = &claimLimits{opts: opts, order: list.New(), elements: make(map[string]*list.Element)}
+	cs.indexLimitsLocked()
+}
+
+// indexLimitsLocked orders the claims held for eviction, which happens in
+// the order they were claimed (assumes lock is held). Stores loading claims
+// after their limits are set index them again once loaded.
+func (cs *ClaimStore) indexLimitsLocked() {
+	if cs.limits == nil || !cs.limits.evicting() {
+		return
+	}
+	addresses := make([]string, 0, len(cs.claims))
+	for ipAddr := range cs.claims {
+		addresses = append(addresses, ipAddr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return cs.metadata[addresses[i]].ClaimedAt.Before(cs.metadata[addresses[j]].ClaimedAt)
+	})
+	cs.limits.clear()
+	for _, ipAddr := range addresses {
+		cs.limits.touch(ipAddr)
+	}
+}
+
+// admitLocked makes room for claims on addresses not yet claimed, evicting
+// the least recently claimed addresses if the policy allows, or refuses them
+// with ErrStoreFull (assumes lock is held). Addresses in keep are not evicted.
+func (cs *ClaimStore) admitLocked(ctx context.Context, newAddresses []string, keep map[string]bool) error {
+	limits := cs.limits
+	if limits == nil || len(newAddresses) == 0 {
+		return nil
+	}
+
+	err := cs.checkLimitsLocked(newAddresses)
+	if err == nil {
+		return nil
+	}
+	// Claims that would not fit in an empty store are refused without evicting everything
+	if !limits.evicting() || (limits.opts.MaxClaims > 0 && len(newAddresses) > limits.opts.MaxClaims) ||
+		(limits.opts.MaxTreeNodes > 0 && len(subnetsOf(newAddresses, cs.ipTree.levels())) > limits.opts.MaxTreeNodes) {
+		limits.rejected.Add(1)
+		return err
+	}
+
+	// Released addresses prune the nodes of subnets left without claims
+	now := time.Now().UTC()
+	element := limits.order.Front()
+	for err != nil {
+		for element != nil && keep[element.Value.(string)] {
+			element = element.Next()
+		}
+		if element == nil {
+			limits.rejected.Add(1)
+			return err
+		}
+		next := element.Next()
+		ipAddr := element.Value.(string)
+		if releaseErr := cs.releaseLocked(ctx, ipAddr, cs.claims[ipAddr], now); releaseErr != nil {
+			return releaseErr
+		}
+		limits.evicted.Add(1)
+		element = next
+		err = cs.checkLimitsLocked(newAddresses)
+	}
+	return nil
+}
+
+// checkLimitsLocked reports whether claims on addresses not yet claimed would
+// exceed a limit (assumes lock is held)
+func (cs *ClaimStore) checkLimitsLocked(newAddresses []string) error {
+	opts := cs.limits.opts
+	if opts.MaxClaims > 0 && len(cs.claims)+len(newAddresses) > opts.MaxClaims {
+		return fmt.Errorf("%w: claim limit of %d reached", ErrStoreFull, opts.MaxClaims)
+	}
+	if opts.MaxTreeNodes > 0 && cs.ipTree.size()+cs.ipTree.missingNodes(newAddresses) > opts.MaxTreeNodes {
+		return fmt.Errorf("%w: tree node limit of %d reached", ErrStoreFull, opts.MaxTreeNodes)
+	}
+	return nil
+}
+
+// StoreUsage reports the size of the store against its limits
+func (cs *ClaimStore) StoreUsage() api.StoreUsage {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	usage := api.StoreUsage{
+		Claims:      len(cs.claims),
+		TreeNodes:   cs.ipTree.size(),
+		PrunedNodes: cs.ipTree.prunedNodes(),
+	}
+	if limits := cs.limits; limits != nil {
+		usage.MaxClaims = limits.opts.MaxClaims
+		usage.MaxTreeNodes = limits.opts.MaxTreeNodes
+		usage.Policy = LimitPolicyReject
+		if limits.evicting() {
+			usage.Policy = LimitPolicyEvict
+		}
+		usage.Rejected = limits.rejected.Load()
+		usage.Evicted = limits.evicted.Load()
+	}
+	return usage
+}
+
+// size returns the number of subnet nodes in the tree
+func (t *IPTree) size() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.root.children)
+}
+
+// prunedNodes returns the number of nodes pruned since the tree was created
+func (t *IPTree) prunedNodes() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.removed
+}
+
+// missingNodes counts the nodes claims on the addresses would add to the tree
+func (t *IPTree) missingNodes(addresses []string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	missing := 0
+	for subnet := range subnetsOf(addresses, t.prefixes) {
+		if _, exists := t.root.children[subnet]; !exists {
+			missing++
+		}
+	}
+	return missing
+}
+
+// subnetsOf returns the subnets of the given prefix lengths containing the addresses
+func subnetsOf(addresses []string, prefixes []int) map[string]struct{} {
+	subnets := make(map[string]struct{})
+	for _, ipAddr := range addresses {
+		ip := net.ParseIP(ipAddr)
+		if ip == nil {
+			continue
+		}
+		for _, prefixLen := range prefixes {
+			subnets[api.CanonicalSubnet(ip, prefixLen)] = struct{}{}
+		}
+	}
+	return subnets
+}
+
+// storeUsageReporter is implemented by stores that report their size against their limits
+type storeUsageReporter interface {
+	StoreUsage() api.StoreUsage
+}
+
+// handleAdminGetStoreUsage returns the size of the store against its limits
+func (h *HTTPHandler) handleAdminGetStoreUsage(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := h.store.(storeUsageReporter)
+	if !ok {
+		writeError(w, r, notFound("store does not report its usage"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reporter.StoreUsage()); err != nil {
+		h.logger.Error("Error encoding JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
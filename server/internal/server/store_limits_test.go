@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_LimitReject tests that claims on new addresses are refused at the claim limit
+func TestClaimStore_LimitReject(t *testing.T) {
+	store := NewClaimStore()
+	store.SetLimitOptions(LimitOptions{MaxClaims: 2, Policy: LimitPolicyReject})
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::3", "bob"), ErrStoreFull, "New address should be refused")
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"), "Takeovers should not need room")
+
+	assert.ErrorIs(t, store.ProcessClaims(t.Context(), []BatchClaim{{IP: "2001:db8::3", Claimant: "bob"}}), ErrStoreFull,
+		"Batches should be refused as a whole")
+	_, ok := store.GetClaim(t.Context(), "2001:db8::3")
+	assert.False(t, ok, "Refused claim should not be stored")
+
+	usage := store.StoreUsage()
+	assert.Equal(t, 2, usage.Claims)
+	assert.Equal(t, 2, usage.MaxClaims)
+	assert.Equal(t, LimitPolicyReject, usage.Policy)
+	assert.Equal(t, uint64(2), usage.Rejected)
+	assert.Zero(t, usage.Evicted)
+}
+
+// TestClaimStore_LimitEvict tests that the least recently claimed addresses make room for new ones
+func TestClaimStore_LimitEvict(t *testing.T) {
+	store, err := NewClaimStoreWithSQLite(filepath.Join(t.TempDir(), "claims.db"))
+	require.NoError(t, err, "Should create store")
+	defer store.Close()
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::3", "alice"))
+	// Loaded claims are ordered by when they were claimed
+	store.SetLimitOptions(LimitOptions{MaxClaims: 3, Policy: LimitPolicyEvict})
+
+	events, unsubscribe := store.SubscribeEvents(t.Context())
+	defer unsubscribe()
+
+	// A takeover makes the address the most recently claimed
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"))
+	<-events
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::4", "bob"))
+
+	event := <-events
+	assert.Equal(t, api.EventTypeUnclaim, event.Type, "Eviction should be published as a release")
+	assert.Equal(t, "2001:db8::2", event.IP, "Least recently claimed address should be evicted")
+	_, ok := store.GetClaim(t.Context(), "2001:db8::2")
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::5", "BOB"), ErrNameConfusable)
+	_, ok = store.GetClaim(t.Context(), "2001:db8::3")
+	assert.True(t, ok, "Claims refused for their name should not evict")
+
+	// Addresses in the batch are not evicted to make room for each other
+	require.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::3", Claimant: "carol"},
+		{IP: "2001:db8::5", Claimant: "carol"},
+		{IP: "2001:db8::6", Claimant: "carol"},
+	}))
+	claims := store.GetAllClaims(t.Context())
+	assert.Len(t, claims, 3)
+	for _, ip := range []string{"2001:db8::3", "2001:db8::5", "2001:db8::6"} {
+		assert.Equal(t, "carol", claims[ip], "%s should be claimed by the batch", ip)
+	}
+	assert.ErrorIs(t, store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::7", Claimant: "carol"},
+		{IP: "2001:db8::8", Claimant: "carol"},
+		{IP: "2001:db8::9", Claimant: "carol"},
+		{IP: "2001:db8::a", Claimant: "carol"},
+	}), ErrStoreFull, "Batches larger than the limit should be refused")
+
+	usage := store.StoreUsage()
+	assert.Equal(t, uint64(3), usage.Evicted)
+	assert.Equal(t, uint64(1), usage.Rejected)
+
+	// Evictions are persisted
+	reopened, err := NewClaimStoreWithSQLite(store.dbPath)
+	require.NoError(t, err, "Should reopen store")
+	defer reopened.Close()
+	assert.Equal(t, claims, reopened.GetAllClaims(t.Context()))
+}
+
+// TestServer_LimitEvictAfterRestart tests that claims loaded once the server
+// starts are evicted to make room, not only those claimed since
+func TestServer_LimitEvictAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.db")
+	seed, err := NewClaimStoreWithSQLite(path)
+	require.NoError(t, err)
+	require.NoError(t, seed.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, seed.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, seed.Close())
+
+	server := NewServerWithOptions(ServerOptions{
+		HTTPPort: 0,
+		DBPath:   path,
+		Limits:   LimitOptions{MaxClaims: 2, Policy: LimitPolicyEvict},
+	})
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::3", "bob"), "Loaded claims should make room")
+	claims := server.store.GetAllClaims(t.Context())
+	assert.Len(t, claims, 2)
+	assert.Equal(t, "bob", claims["2001:db8::3"])
+	assert.Equal(t, uint64(1), server.store.(*ClaimStore).StoreUsage().Evicted)
+}
+
+// TestClaimStore_LimitTreeNodes tests that claims needing new subnet nodes are refused at the node limit
+func TestClaimStore_LimitTreeNodes(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	nodes := store.StoreUsage().TreeNodes
+	store.SetLimitOptions(LimitOptions{MaxTreeNodes: nodes + 1, Policy: LimitPolicyReject})
+
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "bob"), "Address needing only its own node should fit")
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::3", "bob"), ErrStoreFull, "Addresses beyond the node limit should be refused")
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "bob"), "Takeovers should not need nodes")
+	assert.Equal(t, nodes+1, store.StoreUsage().TreeNodes)
+
+	// Evicting prunes the subnets of the evicted addresses
+	store.SetLimitOptions(LimitOptions{MaxTreeNodes: nodes + 1, Policy: LimitPolicyEvict})
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "carol"), "Oldest claims should be evicted to make room")
+	assert.Equal(t, map[string]string{"2001:db9::1": "carol"}, store.GetAllClaims(t.Context()), "Both older claims should be evicted")
+	usage := store.StoreUsage()
+	assert.Equal(t, nodes, usage.TreeNodes)
+	assert.Equal(t, uint64(2), usage.Evicted)
+	assert.Equal(t, uint64(nodes+1), usage.PrunedNodes)
+}
+
+// TestHandleSubmitClaim_StoreFull tests that claims refused by the store limits are reported as unavailable
+func TestHandleSubmitClaim_StoreFull(t *testing.T) {
+	store := NewClaimStore()
+	store.SetLimitOptions(LimitOptions{MaxClaims: 1})
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	ip := "2001:db8::2"
+	pow, err := api.SolveProofOfWork(net.ParseIP(ip), "bob", store.CalculateDifficulty(t.Context(), ip), 1000000)
+	require.NoError(t, err, "Should solve proof of work")
+	body, _ := json.Marshal(api.ClaimRequest{Name: "bob", Nonce: pow.Nonce})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/claim/"+ip, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Full store should be reported as unavailable")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/store", nil)
+	req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var usage api.StoreUsage
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&usage), "Should decode store usage")
+	assert.Equal(t, 1, usage.Claims)
+	assert.Equal(t, uint64(1), usage.Rejected)
+}
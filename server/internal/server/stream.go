@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// streamFlushInterval is the number of elements written between flushes of a streamed listing
+const streamFlushInterval = 256
+
+// streamJSON writes a JSON array or object one element at a time, flushing
+// periodically so large listings are never encoded into a single buffer and
+// clients can start rendering before the listing ends
+type streamJSON struct {
+	w       io.Writer
+	flusher http.Flusher
+	open    byte // The opening bracket, or zero before the first element
+	count   int
+	err     error
+}
+
+// newStreamJSON starts a streamed JSON response
+func newStreamJSON(w http.ResponseWriter) *streamJSON {
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	return &streamJSON{w: w, flusher: flusher}
+}
+
+// write writes raw bytes unless an earlier write failed
+func (s *streamJSON) write(b string) {
+	if s.err == nil {
+		_, s.err = io.WriteString(s.w, b)
+	}
+}
+
+// encode writes a value as JSON unless an earlier write failed
+func (s *streamJSON) encode(v any) {
+	if s.err != nil {
+		return
+	}
+	var data []byte
+	if data, s.err = json.Marshal(v); s.err == nil {
+		_, s.err = s.w.Write(data)
+	}
+}
+
+// separate writes the bracket or comma preceding an element, then flushes if due
+func (s *streamJSON) separate(open byte) {
+	if s.open == 0 {
+		s.open = open
+		s.write(string(open))
+	} else {
+		s.write(",")
+	}
+
+	s.count++
+	if s.count%streamFlushInterval == 0 && s.flusher != nil && s.err == nil {
+		s.flusher.Flush()
+	}
+}
+
+// element writes the next element of an array
+func (s *streamJSON) element(v any) {
+	s.separate('[')
+	s.encode(v)
+}
+
+// field writes the next field of an object
+func (s *streamJSON) field(key string, v any) {
+	s.separate('{')
+	s.encode(key)
+	s.write(":")
+	s.encode(v)
+}
+
+// closeArray ends an array, writing an empty one if there were no elements
+func (s *streamJSON) closeArray() error {
+	if s.open == 0 {
+		s.write("[")
+	}
+	s.write("]\n")
+	return s.err
+}
+
+// closeObject ends an object, writing an empty one if there were no fields
+func (s *streamJSON) closeObject() error {
+	if s.open == 0 {
+		s.write("{")
+	}
+	s.write("}\n")
+	return s.err
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// minEnumeratedPrefix is the shortest prefix for which every address is listed
+// individually; larger subnets are summarized as a histogram
+const minEnumeratedPrefix = 120
+
+// CalculateSubnetDifficulty computes the required difficulty for every address in a subnet
+func (store *ClaimStore) CalculateSubnetDifficulty(ctx context.Context, subnet string) (*api.SubnetDifficultyResponse, bool) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() != nil {
+		return nil, false
+	}
+	prefixLen, _ := ipNet.Mask.Size()
+
+	store.mutex.RLock()
+	claimed := store.claimedDifficultiesLocked(ipNet)
+	base := uint8(store.difficulty.Base)
+	store.mutex.RUnlock()
+
+	response := &api.SubnetDifficultyResponse{
+		Subnet: ipNet.String(),
+	}
+
+	if prefixLen >= minEnumeratedPrefix {
+		count := 1 << (128 - prefixLen)
+		response.Addresses = make([]api.AddressDifficulty, 0, count)
+		for i := range count {
+			var key [16]byte
+			copy(key[:], ipNet.IP.To16())
+			key[15] |= byte(i)
+
+			difficulty, exists := claimed[key]
+			if !exists {
+				difficulty = base
+			}
+			response.Addresses = append(response.Addresses, api.AddressDifficulty{
+				Address:    net.IP(key[:]).String(),
+				Difficulty: difficulty,
+			})
+		}
+		return response, true
+	}
+
+	// Summarize claimed addresses by difficulty, everything else is unclaimed
+	counts := make(map[uint8]int64)
+	for _, difficulty := range claimed {
+		counts[difficulty]++
+	}
+
+	total := new(big.Int).Lsh(big.NewInt(1), uint(128-prefixLen))
+	unclaimed := new(big.Int).Sub(total, big.NewInt(int64(len(claimed))))
+
+	for difficulty, count := range counts {
+		bucket := big.NewInt(count)
+		if difficulty == base {
+			bucket.Add(bucket, unclaimed)
+			unclaimed = nil
+		}
+		response.Histogram = append(response.Histogram, api.DifficultyBucket{
+			Difficulty: difficulty,
+			Count:      bucket.String(),
+		})
+	}
+	if unclaimed != nil && unclaimed.Sign() > 0 {
+		response.Histogram = append(response.Histogram, api.DifficultyBucket{
+			Difficulty: base,
+			Count:      unclaimed.String(),
+		})
+	}
+
+	sort.Slice(response.Histogram, func(i, j int) bool {
+		return response.Histogram[i].Difficulty < response.Histogram[j].Difficulty
+	})
+
+	return response, true
+}
+
+// claimedDifficultiesLocked computes the difficulty of every claimed address in a subnet
+// in a single pass over the claims index (assumes read lock is held).
+// Claims are grouped by /124 block so contiguity is counted once per block
+// instead of once per address.
+func (store *ClaimStore) claimedDifficultiesLocked(subnet *net.IPNet) map[[16]byte]uint8 {
+	owners := make(map[[16]byte]string)
+	fortified := make(map[[16]byte]int)
+	blocks := make(map[[16]byte]map[string]int)
+	blockMask := net.CIDRMask(124, 128)
+	now := time.Now().UTC()
+
+	for ipAddr, claimant := range store.claims {
+		ip := net.ParseIP(ipAddr)
+		if ip == nil || !subnet.Contains(ip) {
+			continue
+		}
+
+		var key, block [16]byte
+		copy(key[:], ip.To16())
+		copy(block[:], ip.Mask(blockMask))
+
+		owners[key] = claimant
+		fortified[key] = store.fortificationBonusLocked(ipAddr, now) + store.heatBonusLocked(ipAddr, now)
+		if blocks[block] == nil {
+			blocks[block] = make(map[string]int)
+		}
+		blocks[block][claimant]++
+	}
+
+	params := store.difficulty
+	difficulties := make(map[[16]byte]uint8, len(owners))
+	for key, claimant := range owners {
+		var block [16]byte
+		copy(block[:], net.IP(key[:]).Mask(blockMask))
+
+		// The address itself is not counted towards contiguity
+		contiguous := min(blocks[block][claimant]-1, params.MaxContiguity)
+		difficulty := min(params.Base+params.ClaimBonus+contiguous*params.ContiguityBonus, params.Max)
+		difficulties[key] = uint8(min(difficulty+fortified[key], 255))
+	}
+
+	return difficulties
+}
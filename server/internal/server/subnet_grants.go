@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net"
+)
+
+// subnetGrant is a subnet whose dominance was granted to a claimant
+type subnetGrant struct {
+	subnet   *net.IPNet
+	claimant string
+}
+
+// GrantSubnet gives a claimant dominance of a whole subnet
+func (cs *ClaimStore) GrantSubnet(ctx context.Context, subnet string, claimant string) error {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return err
+	}
+	key := ipNet.String()
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if err := cs.registerNameLocked(claimant); err != nil {
+		return err
+	}
+
+	if cs.db != nil {
+		if _, err := cs.db.ExecContext(ctx,
+			`INSERT INTO subnet_grants (subnet, claimant) VALUES (?, ?)
+			ON CONFLICT(subnet) DO UPDATE SET claimant = excluded.claimant, created_at = CURRENT_TIMESTAMP`,
+			key, claimant,
+		); err != nil {
+			return backendError(err)
+		}
+	}
+	if err := cs.journal.append(journalEntry{Op: journalGrant, Subnet: key, Claimant: claimant}); err != nil {
+		return backendError(err)
+	}
+
+	cs.grants[key] = subnetGrant{subnet: ipNet, claimant: claimant}
+	cs.indexSubnetNames(key)
+	return nil
+}
+
+// subnetGrantee returns the claimant granted the most specific subnet containing subnet
+func (cs *ClaimStore) subnetGrantee(subnet string) (string, bool) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", false
+	}
+	queryLen := prefixLength(ipNet)
+
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	grantee, bestLen := "", -1
+	for _, grant := range cs.grants {
+		grantLen := prefixLength(grant.subnet)
+		if grantLen <= queryLen && grantLen > bestLen && grant.subnet.Contains(ipNet.IP) {
+			grantee, bestLen = grant.claimant, grantLen
+		}
+	}
+	return grantee, bestLen >= 0
+}
+
+// loadGrantsFromSQLite loads all subnet grants from SQLite into memory,
+// leaving their names to be indexed with the claims
+func (cs *ClaimStore) loadGrantsFromSQLite() error {
+	rows, err := cs.db.Query("SELECT subnet, claimant FROM subnet_grants")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			cs.logger.Error("Error closing rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var subnet, claimant string
+		if err := rows.Scan(&subnet, &claimant); err != nil {
+			return err
+		}
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			cs.logger.Warn("Ignoring invalid subnet grant", "subnet", subnet)
+			continue
+		}
+		cs.grants[subnet] = subnetGrant{subnet: ipNet, claimant: claimant}
+	}
+
+	return rows.Err()
+}
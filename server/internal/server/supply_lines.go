@@ -0,0 +1,196 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrOutOfSupply marks claims too far from every address their claimant holds
+var ErrOutOfSupply = errors.New("address is beyond the claimant's supply lines")
+
+// maxSupplyDistance bounds the distance of supply lines, as every address
+// within it is looked up when checking a claim
+const maxSupplyDistance = 4096
+
+// SupplyLineOptions configures the supply lines rule, under which players
+// expand from a frontier: once they hold a few addresses, new claims must be
+// near one of them
+type SupplyLineOptions struct {
+	Enabled    bool `yaml:"enabled"`
+	FreeClaims int  `yaml:"freeClaims"` // Addresses a player may hold before new claims must be near their holdings
+	Distance   int  `yaml:"distance"`   // Most addresses between a new claim and one the player holds
+}
+
+// DefaultSupplyLineOptions returns the standard supply line options
+func DefaultSupplyLineOptions() SupplyLineOptions {
+	return SupplyLineOptions{
+		FreeClaims: 4,
+		Distance:   16,
+	}
+}
+
+// Validate checks that the supply line options are usable
+func (o SupplyLineOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.FreeClaims <= 0 {
+		return errors.New("supply lines freeClaims must be positive, or new players could never claim")
+	}
+	if o.Distance <= 0 || o.Distance > maxSupplyDistance {
+		return fmt.Errorf("supply lines distance must be between 1 and %d, got %d", maxSupplyDistance, o.Distance)
+	}
+	return nil
+}
+
+// supplyLines enforces the supply lines rule, guarded by the store's mutex
+type supplyLines struct {
+	opts SupplyLineOptions
+	held map[string]int // Addresses held by claimant
+}
+
+// SetSupplyLineOptions sets the supply lines rule, which applies to claims
+// made from then on. Addresses already held beyond it are kept.
+func (cs *ClaimStore) SetSupplyLineOptions(opts SupplyLineOptions) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if !opts.Enabled {
+		cs.supply = nil
+		return
+	}
+
+	cs.supply = &supplyLines{opts: opts}
+	cs.countSupplyLocked()
+}
+
+// countSupplyLocked counts the addresses each claimant holds (assumes lock
+// is held). Stores loading claims after the rule is set count them again
+// once loaded.
+func (cs *ClaimStore) countSupplyLocked() {
+	if cs.supply == nil {
+		return
+	}
+	cs.supply.held = make(map[string]int)
+	for _, claimant := range cs.claims {
+		cs.supply.held[claimant]++
+	}
+}
+
+// moved counts an address passing from one claimant to another, either
+// empty if the address was or is left unclaimed
+func (s *supplyLines) moved(from, to string) {
+	if s == nil || from == to {
+		return
+	}
+	if from != "" {
+		if s.held[from]--; s.held[from] <= 0 {
+			delete(s.held, from)
+		}
+	}
+	if to != "" {
+		s.held[to]++
+	}
+}
+
+// clear forgets every address
+func (s *supplyLines) clear() {
+	if s == nil {
+		return
+	}
+	s.held = make(map[string]int)
+}
+
+// check refuses a claim on an address beyond the supply lines of a claimant
+// holding held addresses, looking up the owners of addresses with owner
+func (s *supplyLines) check(ipAddr string, claimant string, held int, owner func(string) string) error {
+	if s == nil || held < s.opts.FreeClaims || owner(ipAddr) == claimant {
+		return nil
+	}
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return ErrInvalidAddress
+	}
+
+	// Walk outwards both ways, stopping at either end of the address space
+	up, down := copyAddress(ip), copyAddress(ip)
+	upOK, downOK := true, true
+	for range s.opts.Distance {
+		upOK = upOK && nextAddress(up)
+		downOK = downOK && prevAddress(down)
+		if (upOK && owner(up.String()) == claimant) || (downOK && owner(down.String()) == claimant) {
+			return nil
+		}
+		if !upOK && !downOK {
+			break
+		}
+	}
+	return fmt.Errorf("%w: once %d addresses are held, claims must be within %d addresses of one of them",
+		ErrOutOfSupply, s.opts.FreeClaims, s.opts.Distance)
+}
+
+// checkBatch checks claims in order, each against the holdings of its
+// claimant as they stand after the claims before it
+func (s *supplyLines) checkBatch(claims map[string]string, batch []BatchClaim) error {
+	if s == nil {
+		return nil
+	}
+	pending := make(map[string]string, len(batch))
+	gained := make(map[string]int)
+	owner := func(ipAddr string) string {
+		if claimant, exists := pending[ipAddr]; exists {
+			return claimant
+		}
+		return claims[ipAddr]
+	}
+	for i, claim := range batch {
+		if err := s.check(claim.IP, claim.Claimant, s.held[claim.Claimant]+gained[claim.Claimant], owner); err != nil {
+			return fmt.Errorf("claims[%d]: %w", i, err)
+		}
+		if previous := owner(claim.IP); previous != claim.Claimant {
+			if previous != "" {
+				gained[previous]--
+			}
+			gained[claim.Claimant]++
+		}
+		pending[claim.IP] = claim.Claimant
+	}
+	return nil
+}
+
+// checkSupplyLocked refuses a claim beyond the supply lines of its claimant
+// (assumes lock is held)
+func (cs *ClaimStore) checkSupplyLocked(ipAddr string, claimant string) error {
+	if cs.supply == nil {
+		return nil
+	}
+	return cs.supply.check(ipAddr, claimant, cs.supply.held[claimant], func(ipAddr string) string {
+		return cs.claims[ipAddr]
+	})
+}
+
+// copyAddress returns a copy of an address in its 16 byte form
+func copyAddress(ip net.IP) net.IP {
+	return append(net.IP(nil), ip.To16()...)
+}
+
+// nextAddress advances an address by one, reporting false if it wrapped around
+func nextAddress(ip net.IP) bool {
+	for i := len(ip) - 1; i >= 0; i-- {
+		if ip[i]++; ip[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// prevAddress moves an address back by one, reporting false if it wrapped around
+func prevAddress(ip net.IP) bool {
+	for i := len(ip) - 1; i >= 0; i-- {
+		if ip[i]--; ip[i] != 0xff {
+			return true
+		}
+	}
+	return false
+}
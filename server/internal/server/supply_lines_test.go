@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// supplyLineOptions returns enabled supply line options with two free claims
+func supplyLineOptions() SupplyLineOptions {
+	return SupplyLineOptions{Enabled: true, FreeClaims: 2, Distance: 4}
+}
+
+// TestClaimStore_SupplyLines tests that claims beyond a claimant's supply
+// lines are refused once they hold their free claims
+func TestClaimStore_SupplyLines(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::100", "bob"))
+	store.SetSupplyLineOptions(supplyLineOptions())
+
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:ffff::1", "alice"), "Free claims may be made anywhere")
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8:aaaa::1", "alice"), ErrOutOfSupply)
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::5", "alice"), "Claims within the distance should be allowed")
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:fffe:ffff:ffff:ffff:ffff:fffe", "alice"),
+		"Supply lines should reach across subnet boundaries")
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"), "Claiming a held address again should be allowed")
+
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::104", "alice"), ErrOutOfSupply)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::101", "bob"), "Claims held before the rule should count")
+	assert.ErrorIs(t, store.ProcessClaim(t.Context(), "2001:db8::106", "bob"), ErrOutOfSupply)
+
+	err := store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::9", Claimant: "alice"},
+		{IP: "2001:db8::d", Claimant: "alice"},
+		{IP: "2001:db8::20", Claimant: "alice"},
+	})
+	assert.ErrorIs(t, err, ErrOutOfSupply, "Batches should be refused past the supply lines")
+	_, exists := store.GetClaim(t.Context(), "2001:db8::9")
+	assert.False(t, exists, "Refused batches should not be applied")
+	assert.NoError(t, store.ProcessClaims(t.Context(), []BatchClaim{
+		{IP: "2001:db8::9", Claimant: "alice"},
+		{IP: "2001:db8::d", Claimant: "alice"},
+	}), "Claims in a batch should extend the supply lines of later ones")
+
+	for _, ipAddr := range []string{"2001:db8::100", "2001:db8::101"} {
+		require.NoError(t, store.Unclaim(t.Context(), ipAddr, "bob"))
+	}
+	assert.NoError(t, store.ProcessClaim(t.Context(), "2001:db8:bbbb::1", "bob"), "Players wiped out should start again anywhere")
+}
+
+// TestServer_SupplyLinesAfterRestart tests that claims loaded once the
+// server starts count towards the free claims of their claimants
+func TestServer_SupplyLinesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spacenet.db")
+	seed, err := NewClaimStoreWithSQLite(path)
+	require.NoError(t, err)
+	require.NoError(t, seed.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, seed.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	require.NoError(t, seed.Close())
+
+	server := NewServerWithOptions(ServerOptions{HTTPPort: 0, DBPath: path, SupplyLines: supplyLineOptions()})
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	assert.ErrorIs(t, server.store.ProcessClaim(t.Context(), "2001:db8:aaaa::1", "alice"), ErrOutOfSupply,
+		"Loaded claims should use up the free claims")
+	assert.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::5", "alice"))
+}
+
+// TestSupplyLineOptions_Validate tests that unusable supply line options are rejected
+func TestSupplyLineOptions_Validate(t *testing.T) {
+	require.NoError(t, supplyLineOptions().Validate())
+	require.NoError(t, DefaultSupplyLineOptions().Validate(), "Disabled supply lines should be valid")
+
+	testCases := map[string]func(*SupplyLineOptions){
+		"no free claims":        func(o *SupplyLineOptions) { o.FreeClaims = 0 },
+		"no distance":           func(o *SupplyLineOptions) { o.Distance = 0 },
+		"distance over the cap": func(o *SupplyLineOptions) { o.Distance = maxSupplyDistance + 1 },
+	}
+	for name, modify := range testCases {
+		t.Run(name, func(t *testing.T) {
+			opts := supplyLineOptions()
+			modify(&opts)
+			assert.Error(t, opts.Validate())
+		})
+	}
+}
+
+// TestHTTPHandler_SupplyLines tests that the rule is announced by /config
+// and claims beyond it are answered with a conflict
+func TestHTTPHandler_SupplyLines(t *testing.T) {
+	store := NewClaimStore()
+	store.SetSupplyLineOptions(supplyLineOptions())
+	for _, ipAddr := range []string{"2001:db8::1", "2001:db8::2"} {
+		require.NoError(t, store.ProcessClaim(t.Context(), ipAddr, "alice"))
+	}
+	handler := NewHTTPHandler(store)
+	handler.supplyLines = supplyLineOptions()
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var config api.GameConfig
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&config))
+	assert.Equal(t, &api.SupplyLineRule{FreeClaims: 2, Distance: 4}, config.SupplyLines)
+
+	pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8:aaaa::1"), "alice", store.CalculateDifficulty(t.Context(), "2001:db8:aaaa::1"), 1000000)
+	require.NoError(t, err, "Should solve proof of work")
+	data, err := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: pow.Nonce})
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/claim/2001:db8:aaaa::1", bytes.NewReader(data)))
+	assert.Equal(t, http.StatusConflict, rr.Code, "Claims beyond the supply lines should conflict: %s", rr.Body)
+	var body api.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Contains(t, body.Message, "within 4 addresses")
+}
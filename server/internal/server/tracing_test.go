@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestTraceRequests tests that the stages of a claim are traced as children
+// of the caller's trace
+func TestTraceRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	store, err := NewClaimStoreWithSQLite(filepath.Join(t.TempDir(), "tracing.db"))
+	require.NoError(t, err, "Should create SQLite store")
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	handler := NewHTTPHandler(store)
+	handler.claimPool = NewClaimPool(ClaimPoolOptions{Workers: 1, QueueSize: 1})
+	defer handler.claimPool.Stop()
+	router := mux.NewRouter()
+	router.Use(traceRequests)
+	handler.RegisterRoutes(router)
+
+	pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::1"), "alice", store.CalculateDifficulty(t.Context(), "2001:db8::1"), 1000000)
+	require.NoError(t, err, "Should solve proof of work")
+	data, err := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: pow.Nonce})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/claim/2001:db8::1", bytes.NewReader(data))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code, "Claim should be accepted")
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(),
+			"Span %s should continue the caller's trace", span.Name())
+	}
+
+	request := spans["POST /api/v1/claim/{ip}"]
+	require.NotNil(t, request, "Request should be traced by route")
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String(), "Request span should be a child of the caller")
+
+	for _, name := range []string{"claim.queue", "claim.verify", "store.processClaim"} {
+		require.Contains(t, spans, name, "Claim stage %s should be traced", name)
+		assert.Equal(t, request.SpanContext().SpanID(), spans[name].Parent().SpanID(), "%s should be a child of the request", name)
+	}
+	for _, name := range []string{"store.persist", "tree.update"} {
+		require.Contains(t, spans, name, "Store stage %s should be traced", name)
+		assert.Equal(t, spans["store.processClaim"].SpanContext().SpanID(), spans[name].Parent().SpanID(),
+			"%s should be a child of the store span", name)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/binary"
+	"math/big"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// treeBuildProgressInterval is how many claims are placed between progress reports
+const treeBuildProgressInterval = 10000
+
+// builtClaim is a claim parsed for building a tree
+type builtClaim struct {
+	ip       net.IP
+	claimant string
+}
+
+// subnetKey identifies the subnet of a prefix length without allocating its string
+type subnetKey [net.IPv6len + 1]byte
+
+// build replaces the contents of the tree with the given claims by address,
+// much faster than processing them one at a time. The nodes are built on
+// every CPU in shards by subnet, which share nothing so need no locks, then
+// linked into the tree. progress, if set, is called with the number of claims
+// placed as the build goes.
+func (t *IPTree) build(claims map[string]string, progress func(int)) {
+	prefixes := t.levels()
+	parsed := parseBuiltClaims(claims)
+
+	// Every shard walks every claim, keeping the subnets that hash to it, so
+	// the progress of the first stands for all of them
+	shards := runtime.GOMAXPROCS(0)
+	built := make([]map[subnetKey]*IPNode, shards)
+	var wg sync.WaitGroup
+	for shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report := progress
+			if shard != 0 {
+				report = nil
+			}
+			built[shard] = buildShard(parsed, prefixes, shard, shards, report)
+		}()
+	}
+	wg.Wait()
+
+	counts := make(map[int]int, len(prefixes))
+	total := 0
+	for _, nodes := range built {
+		for _, node := range nodes {
+			counts[node.prefixLen]++
+		}
+		total += len(nodes)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.root = newRootNode()
+	t.root.children = make(map[string]*IPNode, total)
+	t.dominance = newLevelDominance(t.prefixes)
+	t.claimed = make(map[int]*bloomFilter, len(t.prefixes))
+	for _, prefixLen := range t.prefixes {
+		t.claimed[prefixLen] = newBloomFilter(max(counts[prefixLen], bloomInitialCapacity))
+	}
+	t.stats.clear()
+	t.pruned = 0
+
+	for _, nodes := range built {
+		for _, node := range nodes {
+			t.root.children[api.CanonicalSubnet(node.subnet.IP, node.prefixLen)] = node
+			t.claimed[node.prefixLen].add(node.subnet.IP.To16())
+			t.setDominantCount(node, node.maxClaimantCount())
+		}
+	}
+}
+
+// parseBuiltClaims parses the addresses of claims on every CPU, skipping
+// invalid ones as processClaim does
+func parseBuiltClaims(claims map[string]string) []builtClaim {
+	addrs := make([]string, 0, len(claims))
+	for ipAddr := range claims {
+		addrs = append(addrs, ipAddr)
+	}
+
+	parsed := make([]builtClaim, len(addrs))
+	workers := runtime.GOMAXPROCS(0)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(addrs); i += workers {
+				if ip := net.ParseIP(addrs[i]); ip != nil && ip.To4() == nil {
+					parsed[i] = builtClaim{ip: ip.To16(), claimant: claims[addrs[i]]}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	valid := parsed[:0]
+	for _, claim := range parsed {
+		if claim.ip != nil {
+			valid = append(valid, claim)
+		}
+	}
+	return valid
+}
+
+// buildShard builds the nodes of the subnets that hash to one of shards
+func buildShard(claims []builtClaim, prefixes []int, shard, shards int, progress func(int)) map[subnetKey]*IPNode {
+	masks := make([]net.IPMask, len(prefixes))
+	for i, prefixLen := range prefixes {
+		masks[i] = net.CIDRMask(prefixLen, 128)
+	}
+
+	nodes := make(map[subnetKey]*IPNode)
+	one := big.NewInt(1)
+	for i, claim := range claims {
+		for level, prefixLen := range prefixes {
+			var key subnetKey
+			for b := range net.IPv6len {
+				key[b] = claim.ip[b] & masks[level][b]
+			}
+			key[net.IPv6len] = byte(prefixLen)
+			if int(shardHash(key)%uint64(shards)) != shard {
+				continue
+			}
+
+			node, exists := nodes[key]
+			if !exists {
+				ip := make(net.IP, net.IPv6len)
+				copy(ip, key[:net.IPv6len])
+				node = &IPNode{
+					subnet:         &net.IPNet{IP: ip, Mask: masks[level]},
+					prefixLen:      prefixLen,
+					claimedCount:   big.NewInt(0),
+					totalAddresses: new(big.Int).Lsh(big.NewInt(1), uint(128-prefixLen)),
+					claimants:      make(map[string]*big.Int),
+					children:       make(map[string]*IPNode),
+				}
+				nodes[key] = node
+			}
+
+			count, exists := node.claimants[claim.claimant]
+			if !exists {
+				count = big.NewInt(0)
+				node.claimants[claim.claimant] = count
+			}
+			count.Add(count, one)
+			node.claimedCount.Add(node.claimedCount, one)
+		}
+
+		if progress != nil && (i+1)%treeBuildProgressInterval == 0 {
+			progress(treeBuildProgressInterval)
+		}
+	}
+	if progress != nil {
+		progress(len(claims) % treeBuildProgressInterval)
+	}
+	return nodes
+}
+
+// shardHash mixes the bytes of a subnet key, so subnets spread evenly over shards
+func shardHash(key subnetKey) uint64 {
+	h := binary.BigEndian.Uint64(key[0:8]) ^ binary.BigEndian.Uint64(key[8:16])*0x9e3779b97f4a7c15 ^ uint64(key[16])
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIPTree_Build tests that a tree built in bulk matches one built claim by claim
+func TestIPTree_Build(t *testing.T) {
+	// Shards are built on every CPU, so have several even on one
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	claims := make(map[string]string)
+	for i := range bloomInitialCapacity + 500 {
+		claims[fmt.Sprintf("2001:db8:%x:%x::%x", i%7, i%300, i)] = fmt.Sprintf("player%d", i%5)
+	}
+	claims["not an address"] = "mallory"
+
+	for _, levels := range [][]int{standardPrefixes, {32, 64}} {
+		t.Run(fmt.Sprint(levels), func(t *testing.T) {
+			sequential := NewIPTree()
+			sequential.setLevels(levels)
+			for ipAddr, claimant := range claims {
+				sequential.processClaim(ipAddr, claimant, "")
+			}
+
+			built := NewIPTree()
+			built.setLevels(levels)
+			built.processClaim("2001:db9::1", "stale", "")
+			placed := 0
+			built.build(claims, func(n int) { placed += n })
+			assert.Equal(t, len(claims)-1, placed, "Progress should count every valid claim")
+
+			assert.Equal(t, sequential.size(), built.size(), "Trees should hold the same subnets")
+			assert.Equal(t, sequential.LevelStats(), built.LevelStats(), "Dominance should be tallied the same")
+			for _, prefixLen := range levels {
+				want, _ := sequential.GetAllSubnets(prefixLen)
+				got, _ := built.GetAllSubnets(prefixLen)
+				bySubnet := func(a, b api.SubnetListEntry) int { return strings.Compare(a.Subnet, b.Subnet) }
+				slices.SortFunc(want, bySubnet)
+				slices.SortFunc(got, bySubnet)
+				assert.Equal(t, want, got, "/%d subnets should match", prefixLen)
+			}
+			want, _ := sequential.GetSubnetStats("2001:db8:3::/48", 3)
+			got, ok := built.GetSubnetStats("2001:db8:3::/48", 3)
+			require.True(t, ok)
+			assert.Equal(t, want, got)
+
+			stats, _ := built.GetSubnetStats("2001:db9::/32", 0)
+			assert.Empty(t, stats.Owner, "Previous contents should be replaced")
+			assert.False(t, built.claimed[levels[len(levels)-1]].full(), "Filters should be sized for the built subnets")
+
+			// A built tree takes claims and releases like any other
+			built.processClaim("2001:db8:1:1::1", "player9", "")
+			built.processUnclaim("2001:db8:1:1::1", "player9")
+			assert.Equal(t, sequential.size(), built.size())
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"log/slog"
+	"maps"
+	"time"
+)
+
+// TreeOptions configures upkeep of the subnet tree
+type TreeOptions struct {
+	CompactInterval time.Duration `yaml:"compactInterval"` // Time between compactions after pruning, disabled if zero
+}
+
+// DefaultTreeOptions returns the standard tree options
+func DefaultTreeOptions() TreeOptions {
+	return TreeOptions{CompactInterval: 10 * time.Minute}
+}
+
+// compact rebuilds the subnet map and filters if nodes were pruned since the
+// last compaction, as Go maps keep their memory after deletion and filters
+// keep the subnets of pruned nodes. It returns the nodes pruned since the last
+// compaction.
+func (t *IPTree) compact() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pruned := t.pruned
+	if pruned == 0 {
+		return 0
+	}
+
+	t.root.children = maps.Clone(t.root.children)
+
+	counts := make(map[int]int, len(t.prefixes))
+	for _, node := range t.root.children {
+		counts[node.prefixLen]++
+	}
+	for _, prefixLen := range t.prefixes {
+		t.claimed[prefixLen] = newBloomFilter(max(bloomInitialCapacity, 2*counts[prefixLen]))
+	}
+	for _, node := range t.root.children {
+		t.claimed[node.prefixLen].add(node.subnet.IP.To16())
+	}
+
+	t.pruned = 0
+	return pruned
+}
+
+// TreeCompactor periodically compacts the subnet tree of a store
+type TreeCompactor struct {
+	tree     *IPTree
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+// NewTreeCompactor creates a compactor for the tree of a store
+func NewTreeCompactor(store *ClaimStore, interval time.Duration) *TreeCompactor {
+	return &TreeCompactor{
+		tree:     store.ipTree,
+		interval: interval,
+		logger:   componentLogger("tree"),
+	}
+}
+
+// Start begins compacting in the background
+func (c *TreeCompactor) Start() {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				if pruned := c.tree.compact(); pruned > 0 {
+					c.logger.Debug("Compacted subnet tree", "pruned", pruned, "nodes", c.tree.size())
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops compacting
+func (c *TreeCompactor) Stop() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+	c.stop = nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIPTree_Pruning tests that subnets left without claims are removed from the tree
+func TestIPTree_Pruning(t *testing.T) {
+	store := NewClaimStore()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	nodes := store.ipTree.size()
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "bob"))
+
+	// Takeovers keep the nodes of the address
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+	assert.Zero(t, store.ipTree.prunedNodes(), "Takeovers should not prune")
+
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db9::1", ""))
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::2", ""))
+	assert.Equal(t, nodes, store.ipTree.size(), "Subnets without claims should be pruned")
+	assert.Equal(t, uint64(8), store.ipTree.prunedNodes(), "Only the subnets of 2001:db9::1 and the /128 of 2001:db8::2 should be pruned")
+
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db9::/32", 0)
+	require.True(t, ok)
+	assert.Empty(t, stats.Owner, "Pruned subnet should be empty")
+	subnets, _ := store.ipTree.GetAllSubnets(128)
+	assert.Len(t, subnets, 1, "Pruned subnets should not be listed")
+
+	// Pruned subnets can be claimed again
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db9::1", "carol"))
+	stats, _ = store.GetSubnetStats(t.Context(), "2001:db9::1/128", 0)
+	assert.Equal(t, "carol", stats.Owner)
+}
+
+// TestIPTree_Compact tests that compaction keeps every subnet and shrinks grown filters
+func TestIPTree_Compact(t *testing.T) {
+	tree := NewIPTree()
+	assert.Zero(t, tree.compact(), "Tree without pruned nodes should not be compacted")
+
+	claims := bloomInitialCapacity + 100
+	address := func(i int) string { return fmt.Sprintf("2001:db8::%x:%x", i>>16, i&0xffff) }
+	for i := range claims {
+		tree.processClaim(address(i), "alice", "")
+	}
+	require.Greater(t, tree.claimed[128].capacity, bloomInitialCapacity, "Filter should have grown")
+	for i := 1; i < claims; i++ {
+		tree.processUnclaim(address(i), "alice")
+	}
+
+	assert.Equal(t, claims-1, tree.compact(), "Compaction should report the pruned nodes")
+	assert.Equal(t, bloomInitialCapacity, tree.claimed[128].capacity, "Filter should be sized for the remaining subnets")
+	assert.Zero(t, tree.compact(), "Pruned nodes should only be reported once")
+
+	stats, ok := tree.GetSubnetStats(address(0)+"/128", 0)
+	require.True(t, ok)
+	assert.Equal(t, "alice", stats.Owner, "Remaining claims should be found after compaction")
+	stats, _ = tree.GetSubnetStats("2001:db8::/32", 0)
+	assert.Equal(t, "", stats.Owner, "A single address should not dominate a /32")
+	subnets, _ := tree.GetAllSubnets(32)
+	assert.Len(t, subnets, 1)
+}
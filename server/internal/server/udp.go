@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// maxUDPPayload is the largest claim payload read; longer names are rejected anyway
+const maxUDPPayload = 512
+
+// UDPOptions configures the UDP claim listener, where sending a packet claims
+// the source address for the name in the payload. Claims need no proof of work,
+// since the sender must be able to receive at the address, so it is meant for
+// trusted networks such as LAN parties.
+type UDPOptions struct {
+	Enabled         bool `yaml:"enabled"`
+	Port            int  `yaml:"port"`
+	ClaimsPerMinute int  `yaml:"claimsPerMinute"` // Per source address
+	Burst           int  `yaml:"burst"`
+}
+
+// DefaultUDPOptions returns the standard UDP listener options
+func DefaultUDPOptions() UDPOptions {
+	return UDPOptions{
+		Port:            6464,
+		ClaimsPerMinute: 10,
+	}
+}
+
+// UDPListener accepts claims for the source address of UDP packets
+type UDPListener struct {
+	store       Store
+	port        int
+	rateLimiter *RateLimiter
+	names       *NamePolicy
+	federation  *Federation // Ignores claims outside this server's prefixes, nil if not federated
+	conn        *net.UDPConn
+	done        chan struct{}
+	closeOnce   sync.Once
+	logger      *slog.Logger
+}
+
+// NewUDPListener creates a UDP claim listener for the given store
+func NewUDPListener(store Store, opts UDPOptions) *UDPListener {
+	listener := &UDPListener{
+		store:  store,
+		port:   opts.Port,
+		names:  defaultNamePolicy(),
+		done:   make(chan struct{}),
+		logger: componentLogger("udp"),
+	}
+	if opts.ClaimsPerMinute > 0 {
+		listener.rateLimiter = NewRateLimiter(opts.ClaimsPerMinute, opts.Burst)
+	}
+	return listener
+}
+
+// Listen binds the UDP port and processes claims in the background until Close is called
+func (l *UDPListener) Listen() error {
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{Port: l.port})
+	if err != nil {
+		return err
+	}
+	l.conn = conn
+	l.logger.Info("SpaceNet UDP claim listener listening", "addr", conn.LocalAddr().String())
+
+	go l.serve()
+	return nil
+}
+
+// Addr returns the address the listener is bound to
+func (l *UDPListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// serve reads claim packets until the connection is closed
+func (l *UDPListener) serve() {
+	defer close(l.done)
+
+	buf := make([]byte, maxUDPPayload)
+	for {
+		n, addr, err := l.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.logger.Error("Error reading UDP packet", "error", err)
+			}
+			return
+		}
+
+		l.handlePacket(net.IP(addr.Addr().AsSlice()), buf[:n])
+	}
+}
+
+// handlePacket claims the source address of a packet for the name in its payload
+func (l *UDPListener) handlePacket(source net.IP, payload []byte) {
+	if source.To4() != nil {
+		l.logger.Debug("Ignoring claim from IPv4 source", "source", source.String())
+		return
+	}
+
+	name, err := l.names.Normalize(string(bytes.TrimSpace(payload)))
+	if err != nil {
+		l.logger.Debug("Ignoring claim with invalid name", "source", source.String(), "error", err)
+		return
+	}
+
+	if err := l.federation.checkAuthoritativeIP(source); err != nil {
+		l.logger.Debug("Ignoring claim outside this server's prefixes", "source", source.String())
+		return
+	}
+
+	ipAddr := source.String()
+	if l.rateLimiter != nil && !l.rateLimiter.Allow(ipAddr) {
+		l.logger.Debug("Rate limited UDP claim", "source", ipAddr)
+		return
+	}
+
+	ctx := withClaimSource(context.Background(), ClaimSource{Channel: claimChannelUDP, RemoteIP: ipAddr})
+	if err := l.store.ProcessClaim(ctx, ipAddr, name); err != nil {
+		l.logger.Error("Failed to process UDP claim", "ip", ipAddr, "claimant", name, "error", err)
+	}
+}
+
+// Close stops the listener
+func (l *UDPListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		err = l.conn.Close()
+		<-l.done
+	})
+	return err
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUDPListener_Claims tests that packets claim their source address, subject to rate limits
+func TestUDPListener_Claims(t *testing.T) {
+	store := NewClaimStore()
+	listener := NewUDPListener(store, UDPOptions{Enabled: true, ClaimsPerMinute: 1})
+	if err := listener.Listen(); err != nil {
+		t.Skipf("IPv6 UDP is unavailable: %v", err)
+	}
+	defer func() {
+		if err := listener.Close(); err != nil {
+			t.Logf("Error closing UDP listener: %v", err)
+		}
+	}()
+
+	port := listener.Addr().(*net.UDPAddr).Port
+	conn, err := net.DialUDP("udp6", nil, &net.UDPAddr{IP: net.IPv6loopback, Port: port})
+	if err != nil {
+		t.Skipf("IPv6 loopback is unavailable: %v", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			t.Logf("Error closing UDP connection: %v", err)
+		}
+	}()
+
+	_, err = conn.Write([]byte("alice\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		claimant, exists := store.GetClaim(t.Context(), "::1")
+		return exists && claimant == "alice"
+	}, time.Second, 10*time.Millisecond, "Packet should claim its source address")
+
+	// The second claim within a minute is over the limit
+	_, err = conn.Write([]byte("bob"))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	claimant, _ := store.GetClaim(t.Context(), "::1")
+	assert.Equal(t, "alice", claimant, "Rate limited claim should be ignored")
+}
+
+// TestUDPListener_HandlePacket tests payload and source validation
+func TestUDPListener_HandlePacket(t *testing.T) {
+	store := NewClaimStore()
+	listener := NewUDPListener(store, UDPOptions{})
+
+	listener.handlePacket(net.ParseIP("192.0.2.1"), []byte("alice"))
+	listener.handlePacket(net.ParseIP("2001:db8::1"), []byte("  "))
+	listener.handlePacket(net.ParseIP("2001:db8::2"), []byte("a-name-that-is-far-too-long-to-claim"))
+	listener.handlePacket(net.ParseIP("2001:db8::4"), []byte("Admin"))
+	assert.Empty(t, store.GetAllClaims(t.Context()), "Invalid packets should not claim anything")
+
+	listener.handlePacket(net.ParseIP("2001:db8::3"), []byte("carol\r\n"))
+	claimant, exists := store.GetClaim(t.Context(), "2001:db8::3")
+	assert.True(t, exists, "Valid packet should claim the source")
+	assert.Equal(t, "carol", claimant, "Name should be trimmed")
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+)
+
+// Unclaim failures
+var (
+	ErrNotClaimed = errors.New("address is not claimed")
+	ErrNotOwner   = errors.New("address is claimed by someone else")
+)
+
+// Unclaim releases the claim on an address, which must be held by claimant
+// unless claimant is empty. With SQLite the claim is kept, marked released,
+// so its history survives.
+func (cs *ClaimStore) Unclaim(ctx context.Context, ipAddr string, claimant string) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	owner, exists := cs.claims[ipAddr]
+	if !exists {
+		return ErrNotClaimed
+	}
+	if claimant != "" && owner != claimant {
+		return ErrNotOwner
+	}
+
+	return cs.releaseLocked(ctx, ipAddr, owner, time.Now().UTC())
+}
+
+// releaseLocked releases a claim held by owner (assumes lock is held)
+func (cs *ClaimStore) releaseLocked(ctx context.Context, ipAddr string, owner string, now time.Time) error {
+	if cs.db != nil {
+		if _, err := cs.db.ExecContext(ctx,
+			"UPDATE claims SET released_at = ?, updated_at = CURRENT_TIMESTAMP WHERE ip_address = ?",
+			now, ipAddr,
+		); err != nil {
+			cs.logger.Error("Failed to persist unclaim", "ip", ipAddr, "claimant", owner, "error", err)
+			return backendError(err)
+		}
+	}
+	if err := cs.journal.append(journalEntry{Op: journalRelease, IP: ipAddr}); err != nil {
+		cs.logger.Error("Failed to journal unclaim", "ip", ipAddr, "claimant", owner, "error", err)
+		return backendError(err)
+	}
+
+	delete(cs.claims, ipAddr)
+	delete(cs.metadata, ipAddr)
+	cs.ipTree.processUnclaim(ipAddr, owner)
+	cs.blocks.update(cs.claims, ipAddr)
+	cs.supply.moved(owner, "")
+	cs.limits.forget(ipAddr)
+
+	cs.events.Publish(api.ClaimEvent{
+		Type:             api.EventTypeUnclaim,
+		IP:               ipAddr,
+		PreviousClaimant: owner,
+		Timestamp:        now,
+	})
+
+	return nil
+}
+
+// processUnclaim removes a released claim from the tree
+func (t *IPTree) processUnclaim(ipAddr string, claimant string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeClaimLocked(ipAddr, claimant, true)
+}
+
+// handleUnclaim releases a claim. Admins may release any claim with their
+// bearer token; owners prove themselves the same way they claim, with a proof
+// of work over their name, or an echo reply when claims are verified by ping.
+func (h *HTTPHandler) handleUnclaim(w http.ResponseWriter, r *http.Request) {
+	ipAddr := mux.Vars(r)["ip"]
+	targetIP := net.ParseIP(ipAddr)
+	if targetIP == nil {
+		writeError(w, r, badRequest("invalid address"))
+		return
+	}
+
+	// Admins release claims whoever holds them
+	var claimant string
+	if token, ok := bearerToken(r); !ok || !h.isAdminToken(token) {
+		var unclaimReq api.ClaimRequest
+		if err := h.decodeBody(w, r, &unclaimReq); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		name, err := h.names.Normalize(unclaimReq.Name)
+		if err != nil {
+			writeError(w, r, badRequest(err.Error()))
+			return
+		}
+
+		if h.icmp != nil {
+			err = h.icmp.Verify(r.Context(), targetIP, name)
+		} else if err = h.store.ValidateProofOfWork(r.Context(), &api.ProofOfWork{Target: targetIP, Name: unclaimReq.Name, Nonce: unclaimReq.Nonce}); err != nil {
+			err = fmt.Errorf("%w: %v", errInvalidProofOfWork, err)
+		}
+		if err != nil {
+			writeError(w, r, unprocessable(err.Error()))
+			return
+		}
+		claimant = name
+	}
+
+	ctx, cancel := h.storeContext(r)
+	defer cancel()
+
+	switch err := h.store.Unclaim(ctx, ipAddr, claimant); {
+	case err == nil:
+	case errors.Is(err, ErrNotClaimed):
+		writeError(w, r, notFound(err.Error()))
+		return
+	case errors.Is(err, ErrNotOwner):
+		writeError(w, r, forbidden(err.Error()))
+		return
+	default:
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStore_Unclaim tests releasing claims and that released claims stay released
+func TestClaimStore_Unclaim(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "unclaim.db")
+	store, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should create SQLite store")
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+
+	events, unsubscribe := store.SubscribeEvents(t.Context())
+	defer unsubscribe()
+
+	assert.ErrorIs(t, store.Unclaim(t.Context(), "2001:db8::1", "bob"), ErrNotOwner, "Only the owner should release a claim")
+	assert.ErrorIs(t, store.Unclaim(t.Context(), "2001:db8::3", "alice"), ErrNotClaimed, "Unclaimed addresses cannot be released")
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::1", "alice"), "Owner should release a claim")
+
+	_, exists := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.False(t, exists, "Released address should be unclaimed")
+	assert.Equal(t, 1, store.GetStats(t.Context()).TotalClaims, "Release should remove the claim")
+	stats, ok := store.GetSubnetStats(t.Context(), "2001:db8::/64", 10)
+	require.True(t, ok)
+	require.Len(t, stats.AllClaimants, 1, "Tree should still hold the other claim")
+	assert.Equal(t, "bob", stats.AllClaimants[0].Name, "Tree should drop the released claim")
+
+	event := <-events
+	assert.Equal(t, api.EventTypeUnclaim, event.Type, "Release should be published")
+	assert.Equal(t, "alice", event.PreviousClaimant)
+	require.NoError(t, store.Close())
+
+	reopened, err := NewClaimStoreWithSQLite(dbPath)
+	require.NoError(t, err, "Should reopen SQLite store")
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Logf("Error closing store: %v", err)
+		}
+	}()
+	_, exists = reopened.GetClaim(t.Context(), "2001:db8::1")
+	assert.False(t, exists, "Released claims should not be loaded")
+	assert.NoError(t, reopened.ProcessClaim(t.Context(), "2001:db8::1", "carol"), "Released addresses should be claimable")
+	metadata, _ := reopened.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Zero(t, metadata.TakeoverCount, "Claiming a released address should not be a takeover")
+}
+
+// TestHTTPHandler_Unclaim tests releasing claims through the API
+func TestHTTPHandler_Unclaim(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	handler.adminTokens = []string{"0123456789abcdef"}
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::2", "alice"))
+
+	unclaim := func(ip, name, nonce string) *httptest.ResponseRecorder {
+		if nonce == "" {
+			pow, err := api.SolveProofOfWork(net.ParseIP(ip), name, store.CalculateDifficulty(t.Context(), ip), 1000000)
+			require.NoError(t, err, "Should solve proof of work")
+			nonce = pow.Nonce
+		}
+		data, err := json.Marshal(api.ClaimRequest{Name: name, Nonce: nonce})
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/claim/"+ip, bytes.NewReader(data)))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, unclaim("2001:db8::1", "alice", "invalid").Code,
+		"Invalid proofs of work should be rejected")
+	assert.Equal(t, http.StatusForbidden, unclaim("2001:db8::1", "bob", "").Code, "Only the owner should release a claim")
+	assert.Equal(t, http.StatusNotFound, unclaim("2001:db8::3", "alice", "").Code, "Unclaimed addresses cannot be released")
+
+	assert.Equal(t, http.StatusNoContent, unclaim("2001:db8::1", "alice", "").Code, "Owner should release a claim")
+	_, exists := store.GetClaim(t.Context(), "2001:db8::1")
+	assert.False(t, exists, "Released address should be unclaimed")
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/claim/2001:db8::2", nil)
+	req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code, "Admins should release any claim")
+	assert.Zero(t, store.GetStats(t.Context()).TotalClaims, "Admin release should remove the claim")
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"math/big"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"golang.org/x/net/websocket"
+)
+
+// maxViewportRows is the most rows a viewport subscription may watch
+const maxViewportRows = 1024
+
+// viewportFlushInterval is how long the rows changed by claims are gathered
+// before they are pushed, so a burst of claims becomes one update
+const viewportFlushInterval = 250 * time.Millisecond
+
+// viewportResyncInterval is how often every watched row is checked for
+// changes, catching claims whose events a slow subscriber missed
+const viewportResyncInterval = 30 * time.Second
+
+// viewport is the rows a client is watching, with the claims last pushed
+type viewport struct {
+	api.ViewportSubscription
+	parent *net.IPNet
+	sent   map[int]string // ETag of each row's claim when last pushed
+	dirty  map[int]bool   // Rows claimed in since the last push
+}
+
+// newViewport validates a subscription
+func newViewport(sub api.ViewportSubscription) (*viewport, error) {
+	parent, err := api.ParseSubnet(sub.Parent)
+	if err != nil {
+		return nil, err
+	}
+	rowBits := sub.Prefix - prefixLength(parent)
+	if rowBits < 0 || sub.Prefix > 128 {
+		return nil, fmt.Errorf("prefix length %d is outside %s", sub.Prefix, parent)
+	}
+	if sub.Start < 0 || sub.End <= sub.Start || (rowBits < 62 && sub.End > 1<<rowBits) {
+		return nil, fmt.Errorf("rows %d to %d are outside %s", sub.Start, sub.End, parent)
+	}
+	if sub.End-sub.Start > maxViewportRows {
+		return nil, fmt.Errorf("at most %d rows may be watched", maxViewportRows)
+	}
+	return &viewport{
+		ViewportSubscription: sub,
+		parent:               parent,
+		sent:                 make(map[int]string),
+		dirty:                make(map[int]bool),
+	}, nil
+}
+
+// subnet returns the subnet of a row
+func (v *viewport) subnet(row int) *net.IPNet {
+	n := new(big.Int).SetBytes(v.parent.IP.To16())
+	n.Or(n, new(big.Int).Lsh(big.NewInt(int64(row)), uint(128-v.Prefix)))
+	return &net.IPNet{IP: n.FillBytes(make(net.IP, net.IPv6len)), Mask: net.CIDRMask(v.Prefix, 128)}
+}
+
+// row returns the watched row holding an address, if any
+func (v *viewport) row(ip net.IP) (int, bool) {
+	if ip.To16() == nil || !v.parent.Contains(ip) {
+		return 0, false
+	}
+	n := new(big.Int).SetBytes(ip.To16())
+	n.Rsh(n, uint(128-v.Prefix))
+	n.And(n, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(v.Prefix-prefixLength(v.parent))), big.NewInt(1)))
+	if n.Cmp(big.NewInt(int64(v.Start))) < 0 || n.Cmp(big.NewInt(int64(v.End))) >= 0 {
+		return 0, false
+	}
+	return int(n.Int64()), true
+}
+
+// viewportUpdate returns the claims on rows of a viewport, only those that
+// changed since they were last pushed unless initial
+func (h *HTTPHandler) viewportUpdate(ctx context.Context, v *viewport, rows []int, initial bool) (api.ViewportUpdate, error) {
+	update := api.ViewportUpdate{Seq: v.Seq, Initial: initial}
+	for _, row := range rows {
+		subnet := v.subnet(row)
+		stats, ok := h.subnetResponse(ctx, subnet, 0)
+		if !ok {
+			return api.ViewportUpdate{}, fmt.Errorf("prefix length %d is not tracked", v.Prefix)
+		}
+		etag := subnetETag(stats)
+		if !initial && v.sent[row] == etag {
+			continue
+		}
+		v.sent[row] = etag
+		update.Rows = append(update.Rows, api.ViewportRow{Row: row, Subnet: subnet.String(), Claim: *stats})
+	}
+	return update, nil
+}
+
+// handleSubscribe pushes the claims on the rows a client watches over a
+// WebSocket, as described in serveViewports
+func (h *HTTPHandler) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	server := websocket.Server{
+		// Claims are as public over a WebSocket as anywhere else in the API
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   h.serveViewports,
+	}
+	server.ServeHTTP(hijackableWriter{w}, r)
+}
+
+// hijackableWriter lets the WebSocket server take over connections through
+// middleware whose response writers only unwrap to one that can be hijacked
+type hijackableWriter struct {
+	http.ResponseWriter
+}
+
+// Hijack takes over the connection of the underlying writer
+func (w hijackableWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// serveViewports reads api.ViewportSubscription messages, each replacing the
+// previous one, and answers each with every watched row, then pushes the
+// rows whose claims change as api.ViewportUpdate messages. Invalid
+// subscriptions are answered with an error and leave nothing watched, as
+// does a subscription without rows.
+func (h *HTTPHandler) serveViewports(ws *websocket.Conn) {
+	defer func() { _ = ws.Close() }()
+
+	// The server's read and write timeouts would otherwise end the connection
+	_ = ws.SetDeadline(time.Time{})
+
+	ctx := ws.Request().Context()
+	events, unsubscribe := h.store.SubscribeEvents(ctx)
+	defer unsubscribe()
+
+	// Only the latest subscription matters when the client scrolls faster
+	// than snapshots are sent
+	subs := make(chan api.ViewportSubscription, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var sub api.ViewportSubscription
+			if err := websocket.JSON.Receive(ws, &sub); err != nil {
+				return
+			}
+			select {
+			case <-subs:
+			default:
+			}
+			subs <- sub
+		}
+	}()
+
+	flush := time.NewTicker(viewportFlushInterval)
+	defer flush.Stop()
+	resync := time.NewTicker(viewportResyncInterval)
+	defer resync.Stop()
+
+	var view *viewport
+	push := func(rows []int, initial bool) error {
+		update, err := h.viewportUpdate(ctx, view, rows, initial)
+		if err != nil {
+			update = api.ViewportUpdate{Seq: view.Seq, Error: err.Error()}
+			view = nil
+		}
+		if !initial && err == nil && len(update.Rows) == 0 {
+			return nil
+		}
+		return websocket.JSON.Send(ws, update)
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case sub := <-subs:
+			if sub.End == sub.Start {
+				view = nil
+				continue
+			}
+			if view, err = newViewport(sub); err != nil {
+				err = websocket.JSON.Send(ws, api.ViewportUpdate{Seq: sub.Seq, Error: err.Error()})
+				break
+			}
+			rows := make([]int, 0, view.End-view.Start)
+			for row := view.Start; row < view.End; row++ {
+				rows = append(rows, row)
+			}
+			err = push(rows, true)
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if view != nil {
+				if row, ok := view.row(net.ParseIP(event.IP)); ok {
+					view.dirty[row] = true
+				}
+			}
+		case <-flush.C:
+			if view != nil && len(view.dirty) > 0 {
+				rows := slices.Sorted(maps.Keys(view.dirty))
+				clear(view.dirty)
+				err = push(rows, false)
+			}
+		case <-resync.C:
+			if view != nil {
+				err = push(slices.Sorted(maps.Keys(view.sent)), false)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// TestViewport_Rows tests that rows map to the subnets of a viewport and back
+func TestViewport_Rows(t *testing.T) {
+	view, err := newViewport(api.ViewportSubscription{Parent: "2001:db8::/32", Prefix: 48, Start: 0, End: 16})
+	require.NoError(t, err, "Viewport should be valid")
+
+	assert.Equal(t, "2001:db8:5::/48", view.subnet(5).String(), "Row should be a /48 of the parent")
+
+	row, ok := view.row(net.ParseIP("2001:db8:5::1"))
+	assert.True(t, ok, "Address in a watched row should be found")
+	assert.Equal(t, 5, row, "Address should be in its /48's row")
+
+	_, ok = view.row(net.ParseIP("2001:db8:20::1"))
+	assert.False(t, ok, "Address in an unwatched row should not be found")
+	_, ok = view.row(net.ParseIP("2001:db9::1"))
+	assert.False(t, ok, "Address outside the parent should not be found")
+}
+
+// TestNewViewport_Invalid tests that subscriptions outside their parent or too large are refused
+func TestNewViewport_Invalid(t *testing.T) {
+	for _, sub := range []api.ViewportSubscription{
+		{Parent: "2001:db8::", Prefix: 48, Start: 0, End: 16},
+		{Parent: "2001:db8::/32", Prefix: 16, Start: 0, End: 16},
+		{Parent: "2001:db8::/32", Prefix: 48, Start: 16, End: 0},
+		{Parent: "2001:db8::/32", Prefix: 48, Start: 65530, End: 65540},
+		{Parent: "2001:db8::/32", Prefix: 48, Start: 0, End: maxViewportRows + 1},
+	} {
+		_, err := newViewport(sub)
+		assert.Error(t, err, "Subscription %+v should be refused", sub)
+	}
+}
+
+// TestHTTPHandler_Subscribe tests that a subscription receives its rows, then the rows claims change
+func TestHTTPHandler_Subscribe(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		HTTPPort: 0,
+	})
+
+	err := server.Start()
+	require.NoError(t, err, "Server should start successfully")
+	defer server.Stop()
+
+	httpPort, err := server.WaitForHTTPPort(5 * time.Second)
+	require.NoError(t, err, "HTTP port should be assigned within timeout")
+
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::2", "bob"))
+
+	ws, err := websocket.Dial(fmt.Sprintf("ws://localhost:%d/api/v1/subscribe", httpPort), "", "http://localhost/")
+	require.NoError(t, err, "WebSocket should connect")
+	defer func() {
+		if err := ws.Close(); err != nil {
+			t.Logf("Error closing WebSocket: %v", err)
+		}
+	}()
+	require.NoError(t, ws.SetDeadline(time.Now().Add(5*time.Second)))
+
+	sub := api.ViewportSubscription{Seq: 1, Parent: "2001:db8::/112", Prefix: 128, Start: 0, End: 16}
+	require.NoError(t, websocket.JSON.Send(ws, sub), "Subscription should be sent")
+
+	var update api.ViewportUpdate
+	require.NoError(t, websocket.JSON.Receive(ws, &update), "Initial rows should be received")
+	assert.True(t, update.Initial, "First update should hold every row")
+	assert.Equal(t, 1, update.Seq, "Update should echo the subscription")
+	require.Len(t, update.Rows, 16, "Every watched row should be sent")
+	assert.Equal(t, "2001:db8::2/128", update.Rows[2].Subnet, "Rows should be in order")
+	assert.Equal(t, "bob", update.Rows[2].Claim.Owner, "Existing claim should be sent")
+
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::5", "alice"))
+	require.NoError(t, server.store.ProcessClaim(t.Context(), "2001:db8::ff", "alice"))
+
+	update = api.ViewportUpdate{}
+	require.NoError(t, websocket.JSON.Receive(ws, &update), "Changed rows should be pushed")
+	assert.False(t, update.Initial, "Later updates should hold only changed rows")
+	require.Len(t, update.Rows, 1, "Only the watched row claimed in should be pushed")
+	assert.Equal(t, 5, update.Rows[0].Row, "Pushed row should be the one claimed in")
+	assert.Equal(t, "alice", update.Rows[0].Claim.Owner, "Pushed row should hold the new claim")
+
+	sub = api.ViewportSubscription{Seq: 2, Parent: "2001:db8::/112", Prefix: 128, Start: 0, End: maxViewportRows + 1}
+	require.NoError(t, websocket.JSON.Send(ws, sub), "Subscription should be sent")
+	update = api.ViewportUpdate{}
+	require.NoError(t, websocket.JSON.Receive(ws, &update), "Refusal should be received")
+	assert.Equal(t, 2, update.Seq, "Refusal should echo the subscription")
+	assert.NotEmpty(t, update.Error, "Oversized subscription should be refused")
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// sessionPrefix marks session secrets, so they are easy to tell from delegation tokens
+const sessionPrefix = "sns_"
+
+// sessionSweepInterval is the number of sessions issued between sweeps of expired ones
+const sessionSweepInterval = 1024
+
+// Session challenge failures, reported to the client claiming with the session
+var (
+	errSessionInvalid   = errors.New("session is invalid or expired")
+	errSessionExhausted = errors.New("session has no claims left in this window")
+	errSessionLimit     = errors.New("too many sessions for this client address")
+)
+
+// WebSessionOptions configures the lighter proof of work offered to
+// clients that cannot afford the full one, such as browsers solving it in
+// WebAssembly. A session challenge takes difficulty off the proof of work,
+// and each session may only make so many claims per window in exchange.
+type WebSessionOptions struct {
+	Enabled       bool          `yaml:"enabled"`
+	Discount      int           `yaml:"discount"`      // Difficulty taken off the proof of work of an address
+	MinDifficulty int           `yaml:"minDifficulty"` // Least difficulty the discount may leave
+	Claims        int           `yaml:"claims"`        // Claims a session may make per window
+	Window        time.Duration `yaml:"window"`        // Time after which a session's claims are replenished
+	Lifetime      time.Duration `yaml:"lifetime"`      // How long a session lasts once issued
+	MaxPerClient  int           `yaml:"maxPerClient"`  // Unexpired sessions one client address may hold
+}
+
+// DefaultWebSessionOptions returns the standard session challenge
+// options, disabled until enabled
+func DefaultWebSessionOptions() WebSessionOptions {
+	return WebSessionOptions{
+		Discount:      6,
+		MinDifficulty: 4,
+		Claims:        120,
+		Window:        time.Hour,
+		Lifetime:      24 * time.Hour,
+		MaxPerClient:  4,
+	}
+}
+
+// Validate checks that the session challenge options are usable
+func (o WebSessionOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.Discount <= 0 || o.MinDifficulty < 0 {
+		return errors.New("webSessions discount must be positive and minDifficulty must not be negative")
+	}
+	if o.Claims <= 0 || o.MaxPerClient <= 0 {
+		return errors.New("webSessions claims and maxPerClient must be positive")
+	}
+	if o.Window <= 0 || o.Lifetime < o.Window {
+		return fmt.Errorf("webSessions window must be positive and lifetime at least the window, got %s and %s", o.Window, o.Lifetime)
+	}
+	return nil
+}
+
+// challengeSession is an issued session, identified by its secret
+type challengeSession struct {
+	secret      string
+	client      string // Address of the client the session was issued to
+	claims      int    // Claims made or in progress in the current window
+	windowStart time.Time
+	expiresAt   time.Time
+}
+
+// WebSessions issues the sessions web clients claim with, and admits
+// their claims with a lighter proof of work while the session has claims
+// left. A session is not bound to the client's address, which browsers
+// change as they roam, so the number issued to each address is bounded instead.
+type WebSessions struct {
+	opts WebSessionOptions
+
+	mutex    sync.Mutex
+	sessions map[string]*challengeSession // By secret
+	clients  map[string]int               // Unexpired sessions by client address
+	issued   int                          // Sessions issued since the last sweep
+}
+
+// NewWebSessions creates a session manager with no sessions
+func NewWebSessions(opts WebSessionOptions) *WebSessions {
+	return &WebSessions{
+		opts:     opts,
+		sessions: make(map[string]*challengeSession),
+		clients:  make(map[string]int),
+	}
+}
+
+// lookup returns the unexpired session with a secret (assumes lock is held)
+func (s *WebSessions) lookup(secret string, now time.Time) (*challengeSession, error) {
+	session, exists := s.sessions[secret]
+	if !exists {
+		return nil, errSessionInvalid
+	}
+	if !now.Before(session.expiresAt) {
+		s.forget(session)
+		return nil, errSessionInvalid
+	}
+	if !now.Before(session.windowStart.Add(s.opts.Window)) {
+		session.claims, session.windowStart = 0, now
+	}
+	return session, nil
+}
+
+// forget drops an expired session (assumes lock is held)
+func (s *WebSessions) forget(session *challengeSession) {
+	delete(s.sessions, session.secret)
+	if s.clients[session.client]--; s.clients[session.client] <= 0 {
+		delete(s.clients, session.client)
+	}
+}
+
+// issue creates a session for a client address (assumes lock is held)
+func (s *WebSessions) issue(client string, now time.Time) (*challengeSession, error) {
+	if s.issued++; s.issued >= sessionSweepInterval {
+		s.issued = 0
+		for _, session := range s.sessions {
+			if !now.Before(session.expiresAt) {
+				s.forget(session)
+			}
+		}
+	}
+	if s.clients[client] >= s.opts.MaxPerClient {
+		return nil, errSessionLimit
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	secret := sessionPrefix + hex.EncodeToString(random)
+	session := &challengeSession{
+		secret:      secret,
+		client:      client,
+		windowStart: now,
+		expiresAt:   now.Add(s.opts.Lifetime),
+	}
+	s.sessions[secret] = session
+	s.clients[client]++
+	return session, nil
+}
+
+// Challenge describes the session challenge of an address whose proof of
+// work has a difficulty, continuing the session with a secret or issuing a
+// new one if the secret is empty, invalid or expired
+func (s *WebSessions) Challenge(challenge api.PoWChallenge, secret string, client string) (api.PoWChallenge, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().UTC()
+	session, err := s.lookup(secret, now)
+	if err != nil {
+		if session, err = s.issue(client, now); err != nil {
+			return api.PoWChallenge{}, err
+		}
+	}
+
+	resetsAt, expiresAt := session.windowStart.Add(s.opts.Window), session.expiresAt
+	challenge.Type = api.ChallengeSession
+	challenge.Difficulty = s.difficulty(challenge.Difficulty)
+	challenge.Session = session.secret
+	challenge.SessionClaims = s.opts.Claims - session.claims
+	challenge.SessionResetsAt = &resetsAt
+	challenge.SessionExpiresAt = &expiresAt
+	return challenge, nil
+}
+
+// difficulty returns the discounted difficulty of a proof of work, leaving
+// difficulties already below the minimum alone
+func (s *WebSessions) difficulty(full uint8) uint8 {
+	return uint8(max(int(full)-s.opts.Discount, min(int(full), s.opts.MinDifficulty)))
+}
+
+// reserve takes one of a session's claims, which must be returned with
+// release once the claim succeeds or fails
+func (s *WebSessions) reserve(secret string) (*challengeSession, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, err := s.lookup(secret, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if session.claims >= s.opts.Claims {
+		return nil, errSessionExhausted
+	}
+
+	session.claims++
+	return session, nil
+}
+
+// release settles a reserved claim, giving it back to the session if the claim failed
+func (s *WebSessions) release(session *challengeSession, claimed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !claimed && session.claims > 0 {
+		session.claims--
+	}
+}
+
+// validate checks the proof of work of a claim made with a session, which
+// must meet the discounted difficulty of its address plus any penalty
+// anomaly detection added for its claimant
+func (s *WebSessions) validate(ctx context.Context, store Store, pow *api.ProofOfWork) error {
+	required := int(s.difficulty(store.CalculateDifficulty(ctx, pow.Target.String())))
+	if reviewer, ok := store.(anomalyReviewer); ok {
+		required += reviewer.DifficultyPenalty(pow.Name)
+	}
+	if !pow.IsValidFor(store.PoWScheme(ctx), uint8(min(required, 255))) {
+		return fmt.Errorf("invalid proof of work: insufficient difficulty")
+	}
+	return nil
+}
+
+// writeSessionError responds to a claim or challenge whose session was rejected
+func writeSessionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errSessionInvalid):
+		writeError(w, r, unauthorized(err.Error()))
+	case errors.Is(err, errSessionExhausted), errors.Is(err, errSessionLimit):
+		writeError(w, r, tooManyRequests(err.Error()))
+	default:
+		writeError(w, r, err)
+	}
+}
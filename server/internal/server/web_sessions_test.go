@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webSessionOptions returns enabled web session options with a small quota
+func webSessionOptions() WebSessionOptions {
+	opts := DefaultWebSessionOptions()
+	opts.Enabled = true
+	opts.Claims = 2
+	opts.MaxPerClient = 2
+	return opts
+}
+
+// TestWebSessions_Quota tests that sessions discount the proof of work and
+// limit the claims made with them
+func TestWebSessions_Quota(t *testing.T) {
+	sessions := NewWebSessions(webSessionOptions())
+	full := api.NewPoWChallenge("2001:db8::1", api.SHA256Scheme{}, 16)
+
+	challenge, err := sessions.Challenge(full, "", "2001:db8:ffff::1")
+	require.NoError(t, err)
+	assert.Equal(t, api.ChallengeSession, challenge.Type)
+	assert.Equal(t, uint8(10), challenge.Difficulty, "Session challenges should take the discount off")
+	assert.Equal(t, 2, challenge.SessionClaims)
+	require.NotNil(t, challenge.SessionExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *challenge.SessionExpiresAt, time.Minute)
+
+	easy := api.NewPoWChallenge("2001:db8::1", api.SHA256Scheme{}, 5)
+	continued, err := sessions.Challenge(easy, challenge.Session, "2001:db8:ffff::2")
+	require.NoError(t, err)
+	assert.Equal(t, challenge.Session, continued.Session, "Sessions should continue from other addresses")
+	assert.Equal(t, uint8(4), continued.Difficulty, "The discount should stop at the minimum difficulty")
+	continued, err = sessions.Challenge(api.NewPoWChallenge("2001:db8::1", api.SHA256Scheme{}, 3), challenge.Session, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint8(3), continued.Difficulty, "Difficulties below the minimum should be left alone")
+
+	first, err := sessions.reserve(challenge.Session)
+	require.NoError(t, err)
+	second, err := sessions.reserve(challenge.Session)
+	require.NoError(t, err)
+	_, err = sessions.reserve(challenge.Session)
+	assert.ErrorIs(t, err, errSessionExhausted)
+	sessions.release(first, true)
+	sessions.release(second, false)
+	_, err = sessions.reserve(challenge.Session)
+	assert.NoError(t, err, "Failed claims should be given back to the session")
+
+	_, err = sessions.reserve("sns_unknown")
+	assert.ErrorIs(t, err, errSessionInvalid)
+	other, err := sessions.Challenge(full, "sns_unknown", "2001:db8:ffff::1")
+	require.NoError(t, err)
+	assert.NotEqual(t, challenge.Session, other.Session, "Unknown sessions should be replaced")
+	_, err = sessions.Challenge(full, "", "2001:db8:ffff::1")
+	assert.ErrorIs(t, err, errSessionLimit, "Client addresses should hold a bounded number of sessions")
+}
+
+// TestWebSessionOptions_Validate tests that unusable web session options are rejected
+func TestWebSessionOptions_Validate(t *testing.T) {
+	require.NoError(t, webSessionOptions().Validate())
+	require.NoError(t, WebSessionOptions{}.Validate(), "Disabled web sessions should be valid")
+
+	testCases := map[string]func(*WebSessionOptions){
+		"no discount":               func(o *WebSessionOptions) { o.Discount = 0 },
+		"negative min difficulty":   func(o *WebSessionOptions) { o.MinDifficulty = -1 },
+		"no claims":                 func(o *WebSessionOptions) { o.Claims = 0 },
+		"no sessions per client":    func(o *WebSessionOptions) { o.MaxPerClient = 0 },
+		"no window":                 func(o *WebSessionOptions) { o.Window = 0 },
+		"lifetime below the window": func(o *WebSessionOptions) { o.Lifetime = o.Window / 2 },
+	}
+	for name, modify := range testCases {
+		t.Run(name, func(t *testing.T) {
+			opts := webSessionOptions()
+			modify(&opts)
+			assert.Error(t, opts.Validate())
+		})
+	}
+}
+
+// TestHTTPHandler_WebSessions tests negotiating a session challenge and
+// claiming through the same pipeline with its lighter proof of work
+func TestHTTPHandler_WebSessions(t *testing.T) {
+	store := NewClaimStore()
+	handler := NewHTTPHandler(store)
+	opts := webSessionOptions()
+	opts.MinDifficulty = 2
+	handler.sessions = NewWebSessions(opts)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	challenge := func(router *mux.Router, query string) api.PoWChallenge {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/challenge/2001:db8::1"+query, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		var challenge api.PoWChallenge
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&challenge))
+		return challenge
+	}
+	claim := func(ip string, difficulty uint8, session string) *httptest.ResponseRecorder {
+		pow, err := api.SolveProofOfWork(net.ParseIP(ip), "alice", difficulty, 1000000)
+		require.NoError(t, err, "Should solve proof of work")
+		data, err := json.Marshal(api.ClaimRequest{Name: "alice", Nonce: pow.Nonce, Session: session})
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/claim/"+ip, bytes.NewReader(data)))
+		return rr
+	}
+
+	full := challenge(router, "")
+	assert.Equal(t, api.ChallengePoW, full.Type)
+	assert.Empty(t, full.Session)
+
+	session := challenge(router, "?type=session")
+	assert.Equal(t, api.ChallengeSession, session.Type)
+	assert.Equal(t, uint8(2), session.Difficulty)
+	require.NotEmpty(t, session.Session)
+
+	for i := range 2 {
+		rr := claim(fmt.Sprintf("2001:db8::%x", i+1), session.Difficulty, session.Session)
+		require.Equal(t, http.StatusCreated, rr.Code, "Session claims should be admitted with the lighter proof of work: %s", rr.Body)
+	}
+	metadata, _ := store.GetClaimMetadata(t.Context(), "2001:db8::1")
+	assert.Equal(t, claimChannelSession, metadata.Source.Channel)
+	assert.Equal(t, http.StatusTooManyRequests, claim("2001:db8::3", session.Difficulty, session.Session).Code,
+		"Sessions should be limited to their claims")
+	assert.Equal(t, 0, challenge(router, "?type=session&session="+session.Session).SessionClaims)
+	assert.Equal(t, http.StatusUnauthorized, claim("2001:db8::3", session.Difficulty, "sns_unknown").Code)
+
+	pow, err := api.SolveProofOfWork(net.ParseIP("2001:db8::3"), "alice", session.Difficulty, 1000000)
+	require.NoError(t, err)
+	if !pow.IsValid(full.Difficulty) {
+		assert.Equal(t, http.StatusUnprocessableEntity, claim("2001:db8::3", session.Difficulty, "").Code,
+			"Claims without a session should need the full proof of work")
+	}
+
+	disabled := mux.NewRouter()
+	NewHTTPHandler(NewClaimStore()).RegisterRoutes(disabled)
+	assert.Equal(t, api.ChallengePoW, challenge(disabled, "?type=session").Type,
+		"The full proof of work should be asked for when web sessions are disabled")
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// Headers of webhook deliveries
+const (
+	webhookEventHeader     = "X-SpaceNet-Event"     // Type of the claim event
+	webhookDeliveryHeader  = "X-SpaceNet-Delivery"  // Identifies a delivery across retries
+	webhookSignatureHeader = "X-SpaceNet-Signature" // "sha256=" and the hex HMAC-SHA256 of the body keyed by the secret
+)
+
+// WebhookOptions configures webhooks, which POST claim events as JSON to
+// external integrations such as chat bots and scoreboards
+type WebhookOptions struct {
+	URLs        []string      `yaml:"urls"`        // Endpoints every event is posted to, disabled if empty
+	Secret      string        `yaml:"secret"`      // Key of the HMAC signature of each delivery, unsigned if empty
+	Events      []string      `yaml:"events"`      // Event types delivered, all if empty
+	Timeout     time.Duration `yaml:"timeout"`     // Longest a delivery attempt may take
+	MaxAttempts int           `yaml:"maxAttempts"` // Attempts before a delivery is dropped
+	Backoff     time.Duration `yaml:"backoff"`     // Wait before the first retry, doubling with each retry
+	QueueSize   int           `yaml:"queueSize"`   // Events waiting per endpoint before new ones are dropped
+}
+
+// DefaultWebhookOptions returns the standard webhook options, without endpoints
+func DefaultWebhookOptions() WebhookOptions {
+	return WebhookOptions{
+		Timeout:     5 * time.Second,
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		QueueSize:   1024,
+	}
+}
+
+// Enabled reports whether any endpoint is configured
+func (o WebhookOptions) Enabled() bool {
+	return len(o.URLs) > 0
+}
+
+// Validate checks that the webhook options are usable
+func (o WebhookOptions) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	for _, endpoint := range o.URLs {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook URL must be an http or https URL, got %q", endpoint)
+		}
+	}
+	for _, eventType := range o.Events {
+		if eventType != api.EventTypeClaim && eventType != api.EventTypeUnclaim {
+			return fmt.Errorf("unknown webhook event type %q", eventType)
+		}
+	}
+	if o.Timeout <= 0 || o.MaxAttempts <= 0 || o.Backoff <= 0 || o.QueueSize <= 0 {
+		return errors.New("webhook timeout, maxAttempts, backoff and queueSize must be positive")
+	}
+	return nil
+}
+
+// webhookDelivery is a claim event waiting to be posted
+type webhookDelivery struct {
+	id    string
+	event api.ClaimEvent
+}
+
+// Webhooks posts claim events from a store to the configured endpoints,
+// each with its own queue so one slow endpoint does not hold up the others
+type Webhooks struct {
+	store  Store
+	opts   WebhookOptions
+	client *http.Client
+	stop   chan struct{}
+	done   sync.WaitGroup
+	logger *slog.Logger
+}
+
+// NewWebhooks creates webhooks posting the events of a store
+func NewWebhooks(store Store, opts WebhookOptions) (*Webhooks, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Webhooks{
+		store:  store,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		logger: componentLogger("webhooks"),
+	}, nil
+}
+
+// Start begins posting events in the background
+func (wh *Webhooks) Start() {
+	wh.stop = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	queues := make([]chan webhookDelivery, len(wh.opts.URLs))
+	for i, endpoint := range wh.opts.URLs {
+		queues[i] = make(chan webhookDelivery, wh.opts.QueueSize)
+		wh.done.Add(1)
+		go func() {
+			defer wh.done.Done()
+			for delivery := range queues[i] {
+				// Drain the queue without delivering once stopped
+				if ctx.Err() == nil {
+					wh.deliver(ctx, endpoint, delivery)
+				}
+			}
+		}()
+	}
+
+	events, unsubscribe := wh.store.SubscribeEvents(ctx)
+	wh.done.Add(1)
+	go func() {
+		defer wh.done.Done()
+		defer func() {
+			unsubscribe()
+			cancel()
+			for _, queue := range queues {
+				close(queue)
+			}
+		}()
+
+		for {
+			select {
+			case <-wh.stop:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if len(wh.opts.Events) > 0 && !slices.Contains(wh.opts.Events, event.Type) {
+					continue
+				}
+				delivery := webhookDelivery{id: newRequestID(), event: event}
+				for i, queue := range queues {
+					select {
+					case queue <- delivery:
+					default:
+						wh.logger.Warn("Webhook queue is full, dropping event", "url", wh.opts.URLs[i], "ip", event.IP)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops posting events, abandoning deliveries in progress
+func (wh *Webhooks) Stop() {
+	if wh.stop == nil {
+		return
+	}
+	close(wh.stop)
+	wh.done.Wait()
+	wh.stop = nil
+}
+
+// deliver posts an event to an endpoint, retrying with exponential backoff
+// until it is accepted, refused for good, or out of attempts
+func (wh *Webhooks) deliver(ctx context.Context, endpoint string, delivery webhookDelivery) {
+	body, err := json.Marshal(delivery.event)
+	if err != nil {
+		wh.logger.Error("Failed to encode webhook event", "error", err)
+		return
+	}
+
+	backoff := wh.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := wh.post(ctx, endpoint, delivery, body)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if !retry || attempt == wh.opts.MaxAttempts {
+			wh.logger.Warn("Failed to deliver webhook", "url", endpoint, "delivery", delivery.id, "attempts", attempt, "error", err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth retrying
+func (wh *Webhooks) post(ctx context.Context, endpoint string, delivery webhookDelivery, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, delivery.event.Type)
+	req.Header.Set(webhookDeliveryHeader, delivery.id)
+	if wh.opts.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhook(wh.opts.Secret, body))
+	}
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	if err := resp.Body.Close(); err != nil {
+		wh.logger.Debug("Error closing webhook response body", "error", err)
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned status: %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("endpoint refused the delivery with status: %d", resp.StatusCode)
+	}
+}
+
+// signWebhook returns the signature header value of a delivery body
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
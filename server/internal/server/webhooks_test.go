@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWebhookOptions returns webhook options posting to an endpoint with short waits
+func testWebhookOptions(endpoint string) WebhookOptions {
+	opts := DefaultWebhookOptions()
+	opts.URLs = []string{endpoint}
+	opts.Backoff = 10 * time.Millisecond
+	return opts
+}
+
+// startWebhooks starts webhooks posting the events of a new store
+func startWebhooks(t *testing.T, opts WebhookOptions) *ClaimStore {
+	t.Helper()
+	store := NewClaimStore()
+	webhooks, err := NewWebhooks(store, opts)
+	require.NoError(t, err)
+	webhooks.Start()
+	t.Cleanup(webhooks.Stop)
+	return store
+}
+
+// TestWebhooks_Delivery tests that claim events are posted with signed bodies
+func TestWebhooks_Delivery(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan delivery, 1)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{header: r.Header, body: body}
+	}))
+	defer endpoint.Close()
+
+	opts := testWebhookOptions(endpoint.URL)
+	opts.Secret = "hunter2"
+	store := startWebhooks(t, opts)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+	select {
+	case got := <-deliveries:
+		assert.Equal(t, "application/json", got.header.Get("Content-Type"))
+		assert.Equal(t, api.EventTypeClaim, got.header.Get(webhookEventHeader))
+		assert.Len(t, got.header.Get(webhookDeliveryHeader), 16)
+		assert.Equal(t, signWebhook("hunter2", got.body), got.header.Get(webhookSignatureHeader))
+
+		var event api.ClaimEvent
+		require.NoError(t, json.Unmarshal(got.body, &event))
+		assert.Equal(t, "2001:db8::1", event.IP)
+		assert.Equal(t, "alice", event.Claimant)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhook was not delivered")
+	}
+}
+
+// TestWebhooks_Retry tests that failed deliveries are retried only when worth it
+func TestWebhooks_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int32
+	}{
+		{"retried after server error", []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK}, 3},
+		{"not retried when refused", []int{http.StatusBadRequest}, 1},
+		{"dropped after max attempts", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			var mu sync.Mutex
+			var deliveryIDs []string
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				mu.Lock()
+				deliveryIDs = append(deliveryIDs, r.Header.Get(webhookDeliveryHeader))
+				mu.Unlock()
+				w.WriteHeader(tt.statuses[min(int(attempt), len(tt.statuses))-1])
+			}))
+			defer endpoint.Close()
+
+			opts := testWebhookOptions(endpoint.URL)
+			opts.MaxAttempts = 3
+			store := startWebhooks(t, opts)
+			require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+
+			require.Eventually(t, func() bool { return attempts.Load() >= tt.attempts }, 5*time.Second, 5*time.Millisecond)
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, tt.attempts, attempts.Load())
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range deliveryIDs {
+				assert.Equal(t, deliveryIDs[0], id, "Retries should keep the delivery ID")
+			}
+		})
+	}
+}
+
+// TestWebhooks_Events tests that only the configured event types are posted
+func TestWebhooks_Events(t *testing.T) {
+	types := make(chan string, 4)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		types <- r.Header.Get(webhookEventHeader)
+	}))
+	defer endpoint.Close()
+
+	opts := testWebhookOptions(endpoint.URL)
+	opts.Events = []string{api.EventTypeUnclaim}
+	store := startWebhooks(t, opts)
+	require.NoError(t, store.ProcessClaim(t.Context(), "2001:db8::1", "alice"))
+	require.NoError(t, store.Unclaim(t.Context(), "2001:db8::1", ""))
+
+	select {
+	case eventType := <-types:
+		assert.Equal(t, api.EventTypeUnclaim, eventType)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhook was not delivered")
+	}
+	assert.Empty(t, types, "Claim events should not be posted")
+}
+
+// TestWebhookOptions_Validate tests validation of the webhook options
+func TestWebhookOptions_Validate(t *testing.T) {
+	assert.NoError(t, DefaultWebhookOptions().Validate(), "Disabled webhooks should be valid")
+
+	opts := testWebhookOptions("https://hooks.example.org/spacenet")
+	assert.NoError(t, opts.Validate())
+
+	opts.Events = []string{"takeover"}
+	assert.Error(t, opts.Validate(), "Unknown event types should be refused")
+
+	opts = testWebhookOptions("ftp://hooks.example.org")
+	assert.Error(t, opts.Validate(), "Only http and https should be allowed")
+
+	opts = testWebhookOptions("https://hooks.example.org")
+	opts.MaxAttempts = 0
+	assert.Error(t, opts.Validate())
+}
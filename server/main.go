@@ -1,7 +1,9 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,8 +13,22 @@ import (
 )
 
 var (
-	httpPort int
-	dbPath   string
+	configPath string
+	httpPort   int
+	dbPath     string
+	logLevel   string
+	logFormat  string
+	pprof      bool
+	udp        bool
+	udpPort    int
+	factionBy  string
+	bots       int
+	readOnly   bool
+	primary    string
+	advertise  bool
+
+	snapshotEndpoint string
+	snapshotBucket   string
 )
 
 func main() {
@@ -20,38 +36,140 @@ func main() {
 		Use:   "spacenet",
 		Short: "An IPv6 territory control game",
 		Long:  "A space-themed network control game where players claim IPv6 addresses via HTTP API.",
-		Run: func(cmd *cobra.Command, args []string) {
-			runServer()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			logger, err := server.NewLogger(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+			if err != nil {
+				return err
+			}
+			slog.SetDefault(logger)
+
+			runServer(cfg)
+			return nil
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import-rir FILE...",
+		Short: "Seed NPC factions from RIR delegation files",
+		Long: "Grant the IPv6 prefixes allocated in RIR statistics exchange files (delegated or delegated-extended, " +
+			"\"-\" for stdin) to NPC factions in the SQLite database, giving a new game a populated universe.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return importRIR(cfg, args)
 		},
 	}
+	importCmd.Flags().StringVar(&factionBy, "factions", server.FactionByBoth, "Group allocations into factions by registry, country or both")
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(newBenchCommand())
+	rootCmd.AddCommand(newDiscordCommand())
+	rootCmd.AddCommand(newReplayCommand())
 
 	// Define flags
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to a YAML config file")
+	rootCmd.PersistentFlags().StringVarP(&dbPath, "database", "d", "", "SQLite database file path, if not specified in-memory store is used")
 	rootCmd.Flags().IntVar(&httpPort, "http-port", 8080, "HTTP port for the REST API")
-	rootCmd.Flags().StringVarP(&dbPath, "database", "d", "", "SQLite database file path, if not specified in-memory store is used")
+	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.Flags().StringVar(&logFormat, "log-format", "text", "Log format (text, json)")
+	rootCmd.Flags().BoolVar(&pprof, "pprof", false, "Serve profiling endpoints under /debug/pprof")
+	rootCmd.Flags().BoolVar(&udp, "udp", false, "Accept claims for the source address of UDP packets, without proof of work")
+	rootCmd.Flags().IntVar(&udpPort, "udp-port", 6464, "UDP port for packet claims")
+	rootCmd.Flags().IntVar(&bots, "bots", 0, "Number of simulated claimants to run, for demos, load testing and practice")
+	rootCmd.Flags().BoolVar(&readOnly, "read-only", false, "Serve reads from the database of a primary server without accepting writes")
+	rootCmd.Flags().BoolVar(&advertise, "advertise", false, "Advertise the server on the local network over mDNS for clients to discover")
+	rootCmd.Flags().StringVar(&primary, "primary", "", "URL of the primary server writes are proxied to in read-only mode, rejected if empty")
+	rootCmd.Flags().StringVar(&snapshotEndpoint, "snapshot-endpoint", "", "S3-compatible object storage URL to keep snapshots of the in-memory store in")
+	rootCmd.Flags().StringVar(&snapshotBucket, "snapshot-bucket", "", "Bucket of the snapshots, credentials are read from the config or environment")
 
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatalf("Failed to execute command: %v", err)
+		fmt.Fprintf(os.Stderr, "Failed to execute command: %v\n", err)
+		os.Exit(1)
 	}
 }
 
+// loadConfig reads the config file and environment, then applies any flags set
+// on the command line, which take precedence over both
+func loadConfig(cmd *cobra.Command) (server.Config, error) {
+	cfg, err := server.LoadConfig(configPath)
+	if err != nil {
+		return cfg, err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("http-port") {
+		cfg.HTTPPort = httpPort
+	}
+	if flags.Changed("database") {
+		cfg.Database = dbPath
+		cfg.Backend = server.BackendSQLite
+	}
+	if flags.Changed("log-level") {
+		cfg.Log.Level = logLevel
+	}
+	if flags.Changed("log-format") {
+		cfg.Log.Format = logFormat
+	}
+	if flags.Changed("pprof") {
+		cfg.Pprof = pprof
+	}
+	if flags.Changed("udp") {
+		cfg.UDP.Enabled = udp
+	}
+	if flags.Changed("udp-port") {
+		cfg.UDP.Port = udpPort
+	}
+	if flags.Changed("bots") {
+		cfg.Bots.Count = bots
+	}
+	if flags.Changed("read-only") {
+		cfg.Replica.ReadOnly = readOnly
+	}
+	if flags.Changed("primary") {
+		cfg.Replica.Primary = primary
+	}
+	if flags.Changed("advertise") {
+		cfg.Discovery.Enabled = advertise
+	}
+	if flags.Changed("snapshot-endpoint") {
+		cfg.Snapshots.Endpoint = snapshotEndpoint
+	}
+	if flags.Changed("snapshot-bucket") {
+		cfg.Snapshots.Bucket = snapshotBucket
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
 // runServer starts the SpaceNet server with the configured options
-func runServer() {
-	log.Printf("Starting SpaceNet server on HTTP port %d", httpPort)
-	if dbPath == "" {
-		log.Println("Using in-memory store")
+func runServer(cfg server.Config) {
+	opts := cfg.ServerOptions()
+
+	slog.Info("Starting SpaceNet server", "httpPort", opts.HTTPPort)
+	if opts.DBPath == "" {
+		slog.Info("Using in-memory store")
 	} else {
-		log.Printf("Using SQLite database at %s", dbPath)
+		slog.Info("Using SQLite database", "path", opts.DBPath)
 	}
 
 	// Create a new server with options
-	srv := server.NewServerWithOptions(server.ServerOptions{
-		HTTPPort: httpPort,
-		DBPath:   dbPath,
-	})
+	srv := server.NewServerWithOptions(opts)
 
 	// Start the server
 	if err := srv.Start(); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
 	}
 
 	// Set up signal handling for graceful shutdown
@@ -59,9 +177,62 @@ func runServer() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 	// Wait for termination signal
-	<-sigCh
+	sig := <-sigCh
+
+	// A second signal skips draining, for operators who cannot wait
+	go func() {
+		<-sigCh
+		slog.Warn("Forcing exit without draining")
+		os.Exit(1)
+	}()
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server...", "signal", sig)
 	srv.Stop()
-	log.Println("Server stopped")
+	slog.Info("Server stopped")
+}
+
+// importRIR grants the allocations in RIR delegation files to NPC factions in the configured database
+func importRIR(cfg server.Config, paths []string) error {
+	if cfg.Database == "" || cfg.Backend == server.BackendMemory {
+		return fmt.Errorf("importing requires a SQLite database")
+	}
+
+	policy, err := server.NewNamePolicy(cfg.Names)
+	if err != nil {
+		return err
+	}
+
+	store, err := server.NewClaimStoreWithSQLite(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			slog.Error("Error closing database", "error", err)
+		}
+	}()
+
+	for _, path := range paths {
+		file := os.Stdin
+		if path != "-" {
+			if file, err = os.Open(path); err != nil {
+				return err
+			}
+		}
+		allocations, err := server.ParseRIRDelegations(file)
+		if path != "-" {
+			_ = file.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		result, err := server.ImportRIRAllocations(context.Background(), store, policy, allocations, factionBy)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		slog.Info("Imported RIR allocations", "file", path, "imported", result.Imported, "skipped", result.Skipped, "factions", result.Factions)
+	}
+
+	return nil
 }
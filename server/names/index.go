@@ -0,0 +1,248 @@
+package names
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index maps generated names in every locale back to the subnets that carry
+// them. Names are one-way hashes of the subnet, so only subnets added to the
+// index resolve, and a name can belong to more than one subnet.
+type Index struct {
+	mutex   sync.RWMutex
+	locales []*Locale               // Locales whose names resolve
+	subnets map[string][]*net.IPNet // Named subnets by name key
+	indexed map[string]struct{}     // Subnets already indexed, in CIDR notation
+}
+
+// NewIndex creates an empty name index for the names of every embedded locale
+func NewIndex() *Index {
+	all := make([]*Locale, 0, len(locales))
+	for _, lang := range Locales() {
+		all = append(all, locales[lang])
+	}
+	return NewIndexFor(all...)
+}
+
+// NewIndexFor creates an empty name index for the names of the given locales,
+// such as a name pack
+func NewIndexFor(locales ...*Locale) *Index {
+	return &Index{
+		locales: locales,
+		subnets: make(map[string][]*net.IPNet),
+		indexed: make(map[string]struct{}),
+	}
+}
+
+// nameKey folds the case and spacing of a name, so searches needn't match it
+// exactly. It lowercases the words of the name and joins them with single
+// spaces in one pass, since every generated name is folded when indexed.
+func nameKey(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	space := false
+	for _, r := range name {
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Add indexes the names of an address or CIDR subnet and of every named
+// subnet containing it
+func (x *Index) Add(subnet string) error {
+	var addr net.IP
+	prefixLen := 128
+	if ip, ipNet, err := net.ParseCIDR(subnet); err == nil {
+		addr = ip
+		prefixLen, _ = ipNet.Mask.Size()
+	} else {
+		addr = net.ParseIP(subnet)
+	}
+	if addr == nil || addr.To4() != nil {
+		return fmt.Errorf("invalid IPv6 address or subnet: %s", subnet)
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	for _, size := range Levels {
+		if size > prefixLen {
+			break
+		}
+
+		named := &net.IPNet{IP: addr.Mask(net.CIDRMask(size, 128)), Mask: net.CIDRMask(size, 128)}
+		cidr := named.String()
+		if _, exists := x.indexed[cidr]; exists {
+			continue
+		}
+
+		hash := subnetHash(named.IP, size)
+		keys := make(map[string]struct{}, len(x.locales))
+		for _, locale := range x.locales {
+			key := nameKey(locale.nameFromHash(hash, size))
+			if _, exists := keys[key]; !exists {
+				keys[key] = struct{}{}
+				x.subnets[key] = append(x.subnets[key], named)
+			}
+		}
+		x.indexed[cidr] = struct{}{}
+	}
+
+	return nil
+}
+
+// progressInterval is how many named subnets AddAll generates between progress reports
+const progressInterval = 10000
+
+// AddAll indexes the names of many addresses or CIDR subnets, as Add does,
+// generating the names on every CPU, for loading a whole store at once. Every
+// valid subnet is indexed; the first invalid one is reported. progress, if
+// set, is called from every CPU with the number of named subnets generated,
+// counting the subnets containing those given, as indexing goes.
+func (x *Index) AddAll(subnets []string, progress func(int)) error {
+	// Each named subnet is generated once, however many of the subnets it contains
+	type namedSubnet struct {
+		subnet *net.IPNet
+		size   int
+	}
+	var invalid error
+	seen := make(map[[net.IPv6len + 1]byte]struct{})
+	var named []namedSubnet
+	for _, subnet := range subnets {
+		var addr net.IP
+		prefixLen := 128
+		if !strings.Contains(subnet, "/") {
+			addr = net.ParseIP(subnet)
+		} else if ip, ipNet, err := net.ParseCIDR(subnet); err == nil {
+			addr = ip
+			prefixLen, _ = ipNet.Mask.Size()
+		}
+		if addr == nil || addr.To4() != nil {
+			if invalid == nil {
+				invalid = fmt.Errorf("invalid IPv6 address or subnet: %s", subnet)
+			}
+			continue
+		}
+
+		for _, size := range Levels {
+			if size > prefixLen {
+				break
+			}
+			mask := net.CIDRMask(size, 128)
+			ip := addr.Mask(mask)
+			var key [net.IPv6len + 1]byte
+			copy(key[:], ip)
+			key[net.IPv6len] = byte(size)
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			named = append(named, namedSubnet{&net.IPNet{IP: ip, Mask: mask}, size})
+		}
+	}
+
+	// Names are generated in parallel, then added under the lock
+	cidrs := make([]string, len(named))
+	keys := make([][]string, len(named))
+	workers := min(runtime.GOMAXPROCS(0), max(len(named)/1024, 1))
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			generated := 0
+			for i := w; i < len(named); i += workers {
+				if generated++; progress != nil && generated%progressInterval == 0 {
+					progress(progressInterval)
+				}
+				cidrs[i] = named[i].subnet.String()
+				x.mutex.RLock()
+				_, exists := x.indexed[cidrs[i]]
+				x.mutex.RUnlock()
+				if exists {
+					continue
+				}
+				hash := subnetHash(named[i].subnet.IP, named[i].size)
+				for _, locale := range x.locales {
+					key := nameKey(locale.nameFromHash(hash, named[i].size))
+					if !slices.Contains(keys[i], key) {
+						keys[i] = append(keys[i], key)
+					}
+				}
+			}
+			if progress != nil {
+				progress(generated % progressInterval)
+			}
+		}()
+	}
+	wg.Wait()
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	for i, cidr := range cidrs {
+		if _, exists := x.indexed[cidr]; exists {
+			continue
+		}
+		for _, key := range keys[i] {
+			x.subnets[key] = append(x.subnets[key], named[i].subnet)
+		}
+		x.indexed[cidr] = struct{}{}
+	}
+
+	return invalid
+}
+
+// Resolve returns the indexed subnets with a name in CIDR notation, widest first
+func (x *Index) Resolve(name string) []string {
+	x.mutex.RLock()
+	matches := append([]*net.IPNet(nil), x.subnets[nameKey(name)]...)
+	x.mutex.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		iLen, _ := matches[i].Mask.Size()
+		jLen, _ := matches[j].Mask.Size()
+		if iLen != jLen {
+			return iLen < jLen
+		}
+		return bytes.Compare(matches[i].IP, matches[j].IP) < 0
+	})
+
+	subnets := make([]string, len(matches))
+	for i, match := range matches {
+		subnets[i] = match.String()
+	}
+	return subnets
+}
+
+// Len returns the number of indexed subnets
+func (x *Index) Len() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	return len(x.indexed)
+}
+
+// Reset removes every subnet from the index
+func (x *Index) Reset() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.subnets = make(map[string][]*net.IPNet)
+	x.indexed = make(map[string]struct{})
+}
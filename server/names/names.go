@@ -0,0 +1,253 @@
+// Package names generates the deterministic celestial names of IPv6 subnets
+// shown by SpaceNet clients, and resolves generated names back to subnets.
+package names
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Levels are the prefix lengths of the subnets that have names, from the
+// widest to a single address
+var Levels = []int{16, 32, 48, 64, 80, 96, 112, 128}
+
+//go:embed ipv6names.json
+var ipv6NamesData []byte
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// DefaultLocale is the language of names generated without a locale, and of
+// the word lists in ipv6names.json
+const DefaultLocale = "en"
+
+// defaultFormat orders the words of a name when a locale doesn't set a format
+const defaultFormat = "{adjective} {noun} {type}"
+
+type IPv6Names struct {
+	Name           string              `json:"name,omitempty" yaml:"name"` // Name of a name pack
+	Adjectives     map[string][]string `json:"adjectives" yaml:"adjectives"`
+	Nouns          map[string][]string `json:"nouns" yaml:"nouns"`
+	CelestialTypes map[string][]string `json:"celestialTypes" yaml:"celestialTypes"`
+	SubnetMappings map[string]int      `json:"subnetMappings,omitempty" yaml:"subnetMappings"`
+	LevelNames     []string            `json:"levelNames,omitempty" yaml:"levelNames"`
+	Format         string              `json:"format,omitempty" yaml:"format"` // Word order, using {adjective}, {noun} and {type}
+}
+
+// Locale generates names from the word lists of one language. Every locale
+// picks words with the same hash of the subnet, so names are deterministic
+// per locale and share their numeric suffix across locales.
+type Locale struct {
+	lang           string
+	adjectives     map[int][]string
+	nouns          map[int][]string
+	celestialTypes map[int][]string
+	format         string
+	packID         string // Version of a name pack, empty for embedded locales
+	pack           []byte // Canonical JSON of a name pack
+}
+
+// locales holds the embedded locales by language tag
+var locales = make(map[string]*Locale)
+
+func init() {
+	addLocale(DefaultLocale, ipv6NamesData)
+
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to read embedded locales: %v", err))
+	}
+	for _, file := range files {
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("Failed to read embedded locale %s: %v", file.Name(), err))
+		}
+		addLocale(strings.TrimSuffix(file.Name(), ".json"), data)
+	}
+}
+
+// addLocale parses the word lists of an embedded locale, which must name every level
+func addLocale(lang string, data []byte) {
+	var namesData IPv6Names
+	if err := json.Unmarshal(data, &namesData); err != nil {
+		panic(fmt.Sprintf("Failed to parse embedded IPv6 names data for %s: %v", lang, err))
+	}
+
+	locale := &Locale{
+		lang:           lang,
+		adjectives:     levelWords(namesData.Adjectives),
+		nouns:          levelWords(namesData.Nouns),
+		celestialTypes: levelWords(namesData.CelestialTypes),
+		format:         namesData.Format,
+	}
+	if locale.format == "" {
+		locale.format = defaultFormat
+	}
+
+	for _, size := range Levels {
+		if len(locale.adjectives[size]) == 0 || len(locale.nouns[size]) == 0 || len(locale.celestialTypes[size]) == 0 {
+			panic(fmt.Sprintf("Embedded IPv6 names data for %s has no words for /%d", lang, size))
+		}
+	}
+
+	locales[lang] = locale
+}
+
+// levelWords converts word lists keyed by prefix length strings to integer keys
+func levelWords(words map[string][]string) map[int][]string {
+	levels := make(map[int][]string)
+	for strKey, value := range words {
+		var key int
+		if _, err := fmt.Sscanf(strKey, "%d", &key); err != nil {
+			// Skip invalid keys
+			continue
+		}
+		levels[key] = value
+	}
+	return levels
+}
+
+// LookupLocale returns the locale for a language tag such as "es"
+func LookupLocale(lang string) (*Locale, bool) {
+	locale, ok := locales[strings.ToLower(lang)]
+	return locale, ok
+}
+
+// Locales returns the language tags of the available locales
+func Locales() []string {
+	langs := make([]string, 0, len(locales))
+	for lang := range locales {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Lang returns the language tag of the locale
+func (l *Locale) Lang() string {
+	return l.lang
+}
+
+// GenerateName creates a unique name for an IPv6 address at a given subnet size
+// in the default locale
+func GenerateName(saddr string, subnetSize int) (string, error) {
+	return locales[DefaultLocale].GenerateName(saddr, subnetSize)
+}
+
+// GenerateName creates a unique name for an IPv6 address at a given subnet size
+func (l *Locale) GenerateName(saddr string, subnetSize int) (string, error) {
+	addr := net.ParseIP(saddr)
+	if addr == nil {
+		return "", fmt.Errorf("invalid IPv6 address")
+	}
+	if addr.To16() == nil {
+		return "", fmt.Errorf("invalid IPv6 address")
+	}
+	if _, ok := l.celestialTypes[subnetSize]; !ok {
+		return "", fmt.Errorf("invalid subnet size: %d", subnetSize)
+	}
+
+	return l.nameFromHash(subnetHash(addr, subnetSize), subnetSize), nil
+}
+
+// subnetHash hashes the subnet of the given size containing an address
+func subnetHash(addr net.IP, subnetSize int) [sha256.Size]byte {
+	truncatedAddr := truncateIPv6(addr, subnetSize)
+	subnetSizeBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(subnetSizeBytes, uint32(subnetSize))
+	return sha256.Sum256(append(truncatedAddr, subnetSizeBytes...))
+}
+
+// nameFromHash picks the words of a name from the hash of its subnet
+func (l *Locale) nameFromHash(hash [sha256.Size]byte, subnetSize int) string {
+	// Use different parts of the hash for different components of the name
+	adjIndex := int(binary.BigEndian.Uint32(hash[0:4])) % len(l.adjectives[subnetSize])
+	nounIndex := int(binary.BigEndian.Uint32(hash[4:8])) % len(l.nouns[subnetSize])
+	celestialIndex := int(binary.BigEndian.Uint32(hash[8:12])) % len(l.celestialTypes[subnetSize])
+
+	// Generate a numeric suffix using another part of the hash
+	suffix := binary.BigEndian.Uint16(hash[12:14]) % 1000
+
+	words := fillFormat(l.format,
+		l.adjectives[subnetSize][adjIndex],
+		l.nouns[subnetSize][nounIndex],
+		l.celestialTypes[subnetSize][celestialIndex],
+	)
+	return words + "-" + strconv.Itoa(int(suffix))
+}
+
+// fillFormat replaces the placeholders of a name format with its words in
+// one pass, as a strings.Replacer would without building one for every name
+func fillFormat(format, adjective, noun, celestialType string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(format, '{')
+		if start < 0 {
+			b.WriteString(format)
+			return b.String()
+		}
+		b.WriteString(format[:start])
+		format = format[start:]
+
+		switch {
+		case strings.HasPrefix(format, "{adjective}"):
+			b.WriteString(adjective)
+			format = format[len("{adjective}"):]
+		case strings.HasPrefix(format, "{noun}"):
+			b.WriteString(noun)
+			format = format[len("{noun}"):]
+		case strings.HasPrefix(format, "{type}"):
+			b.WriteString(celestialType)
+			format = format[len("{type}"):]
+		default:
+			b.WriteByte('{')
+			format = format[1:]
+		}
+	}
+}
+
+// truncateIPv6 masks an IPv6 address to the specified subnet size
+func truncateIPv6(addr net.IP, bits int) []byte {
+	mask := net.CIDRMask(bits, 128)
+	return addr.Mask(mask)
+}
+
+// GetHierarchy generates names for all parent categories of an IPv6 address
+func GetHierarchy(addr net.IP) ([]string, error) {
+	var names []string
+
+	for _, size := range Levels {
+		name, err := GenerateName(addr.String(), size)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// FormatHierarchy returns a formatted string of the complete hierarchy
+func FormatHierarchy(addr net.IP) (string, error) {
+	names, err := GetHierarchy(addr)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for i, name := range names {
+		builder.WriteString(strings.Repeat("  ", i))
+		builder.WriteString(name)
+		builder.WriteString("\n")
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,168 @@
+package names
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateName tests that names are deterministic per subnet
+func TestGenerateName(t *testing.T) {
+	name, err := GenerateName("2001:db8::1", 48)
+	require.NoError(t, err, "Should name a valid address")
+	assert.Regexp(t, regexp.MustCompile(`^\S+ \S+ .+-\d+$`), name, "Name should be adjective, noun, type and number")
+
+	again, err := GenerateName("2001:db8:0:ffff::", 48)
+	require.NoError(t, err, "Should name a valid address")
+	assert.Equal(t, name, again, "Addresses in the same subnet should share its name")
+
+	_, err = GenerateName("2001:db8::1", 50)
+	assert.Error(t, err, "Should reject a prefix length without names")
+	_, err = GenerateName("not an address", 48)
+	assert.Error(t, err, "Should reject an invalid address")
+}
+
+// TestGetHierarchy tests that every level of an address is named
+func TestGetHierarchy(t *testing.T) {
+	hierarchy, err := GetHierarchy(net.ParseIP("2001:db8::1"))
+	require.NoError(t, err, "Should name every level")
+	require.Len(t, hierarchy, len(Levels), "Should have a name per level")
+
+	for i, size := range Levels {
+		name, err := GenerateName("2001:db8::1", size)
+		require.NoError(t, err, "Should name the level")
+		assert.Equal(t, name, hierarchy[i], "Hierarchy should be ordered from the widest level")
+	}
+}
+
+// TestIndex_Resolve tests resolving generated names back to indexed subnets
+func TestIndex_Resolve(t *testing.T) {
+	index := NewIndex()
+	require.NoError(t, index.Add("2001:db8::1"), "Should index an address")
+	assert.Equal(t, len(Levels), index.Len(), "An address should index every level containing it")
+
+	for _, size := range Levels {
+		name, err := GenerateName("2001:db8::1", size)
+		require.NoError(t, err, "Should name the level")
+
+		subnet := &net.IPNet{IP: net.ParseIP("2001:db8::1").Mask(net.CIDRMask(size, 128)), Mask: net.CIDRMask(size, 128)}
+		assert.Contains(t, index.Resolve(name), subnet.String(), "Name should resolve to its subnet")
+	}
+
+	name, err := GenerateName("2001:db8::1", 128)
+	require.NoError(t, err, "Should name the address")
+	assert.Equal(t, []string{"2001:db8::1/128"}, index.Resolve("  "+name+" "), "Surrounding space should be ignored")
+
+	name, err = GenerateName("2001:db8::", 32)
+	require.NoError(t, err, "Should name the subnet")
+	assert.Equal(t, []string{"2001:db8::/32"}, index.Resolve(name), "Case should be ignored")
+
+	assert.Empty(t, index.Resolve("Nonexistent Name Nebula-1"), "Unknown names should not resolve")
+
+	index.Reset()
+	assert.Empty(t, index.Resolve(name), "Reset should empty the index")
+	assert.Zero(t, index.Len(), "Reset should empty the index")
+}
+
+// TestIndex_AddSubnet tests indexing a subnet without descending below it
+func TestIndex_AddSubnet(t *testing.T) {
+	index := NewIndex()
+	require.NoError(t, index.Add("2001:db8:1::/48"), "Should index a subnet")
+	assert.Equal(t, 3, index.Len(), "Should index the /16, /32 and /48 containing the subnet")
+
+	require.NoError(t, index.Add("2001:db8:1:2::/64"), "Should index a nested subnet")
+	assert.Equal(t, 4, index.Len(), "Containing subnets should only be indexed once")
+
+	assert.Error(t, index.Add("192.0.2.1"), "Should reject IPv4 addresses")
+	assert.Error(t, index.Add("nonsense"), "Should reject invalid input")
+}
+
+// TestIndex_AddAll tests that indexing in bulk resolves the same as indexing one at a time
+func TestIndex_AddAll(t *testing.T) {
+	subnets := []string{"2001:db8::1", "2001:db8::2", "2001:db8:1::/48", "2001:db8:1:2::/64", "2001:db8::1"}
+	one := NewIndex()
+	for _, subnet := range subnets {
+		require.NoError(t, one.Add(subnet))
+	}
+	bulk := NewIndex()
+	require.NoError(t, bulk.Add("2001:db8::1"), "Already indexed subnets should be kept")
+	generated := 0
+	require.NoError(t, bulk.AddAll(subnets, func(n int) { generated += n }), "Should index every subnet")
+	assert.Equal(t, one.Len(), generated, "Progress should count every named subnet")
+	assert.Equal(t, one.Len(), bulk.Len())
+
+	spanish, _ := LookupLocale("es")
+	for _, subnet := range []string{"2001:db8::1", "2001:db8::2", "2001:db8:1:2::"} {
+		for _, size := range Levels {
+			name, err := GenerateName(subnet, size)
+			require.NoError(t, err)
+			assert.Equal(t, one.Resolve(name), bulk.Resolve(name), "/%d of %s should resolve the same", size, subnet)
+			localized, err := spanish.GenerateName(subnet, size)
+			require.NoError(t, err)
+			assert.Equal(t, one.Resolve(localized), bulk.Resolve(localized))
+		}
+	}
+
+	invalid := NewIndex()
+	assert.Error(t, invalid.AddAll([]string{"192.0.2.1", "2001:db8::1"}, nil), "Should report invalid input")
+	assert.Equal(t, len(Levels), invalid.Len(), "Valid subnets should still be indexed")
+}
+
+// TestFillFormat tests that name formats are filled as a strings.Replacer would
+func TestFillFormat(t *testing.T) {
+	for _, format := range []string{defaultFormat, "{noun} {type} of {adjective}", "{type}{{noun}}", "no words", "{unknown} {noun"} {
+		expected := strings.NewReplacer("{adjective}", "Red", "{noun}", "Dwarf", "{type}", "Star").Replace(format)
+		assert.Equal(t, expected, fillFormat(format, "Red", "Dwarf", "Star"), "Format %q", format)
+	}
+}
+
+// TestLocales tests that every locale names every level deterministically
+func TestLocales(t *testing.T) {
+	require.Contains(t, Locales(), DefaultLocale, "Default locale should be available")
+	require.Contains(t, Locales(), "es", "Spanish should be available")
+
+	english, err := GenerateName("2001:db8::1", 64)
+	require.NoError(t, err)
+	suffix := english[strings.LastIndex(english, "-"):]
+
+	for _, lang := range Locales() {
+		locale, ok := LookupLocale(strings.ToUpper(lang))
+		require.True(t, ok, "Lookup should ignore case")
+		assert.Equal(t, lang, locale.Lang(), "Locale should report its language")
+
+		for _, size := range Levels {
+			name, err := locale.GenerateName("2001:db8::1", size)
+			require.NoError(t, err, "%s should name /%d", lang, size)
+			again, err := locale.GenerateName("2001:db8::1", size)
+			require.NoError(t, err)
+			assert.Equal(t, name, again, "%s names should be deterministic", lang)
+		}
+
+		name, err := locale.GenerateName("2001:db8::1", 64)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(name, suffix), "%s should share the numeric suffix of %q, got %q", lang, english, name)
+	}
+
+	defaultLocale, _ := LookupLocale(DefaultLocale)
+	name, err := defaultLocale.GenerateName("2001:db8::1", 64)
+	require.NoError(t, err)
+	assert.Equal(t, english, name, "GenerateName should use the default locale")
+
+	_, ok := LookupLocale("xx")
+	assert.False(t, ok, "Unknown locales should not be found")
+}
+
+// TestIndex_ResolveLocalized tests resolving names generated in any locale
+func TestIndex_ResolveLocalized(t *testing.T) {
+	index := NewIndex()
+	require.NoError(t, index.Add("2001:db8::1"), "Should index an address")
+
+	spanish, _ := LookupLocale("es")
+	name, err := spanish.GenerateName("2001:db8::", 32)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8::/32"}, index.Resolve(strings.ToUpper(name)), "Localized names should resolve")
+}
@@ -0,0 +1,88 @@
+package names
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// packNamePattern limits pack names to what fits in a pack ID
+var packNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// LoadPack parses a name pack, a JSON or YAML file with the word lists of
+// ipv6names.json, to theme a game's names (fantasy, ocean, cyberpunk). The
+// pack must name every level. Its ID combines its name with a hash of its
+// words, so clients can tell whether they hold the same version of a pack.
+func LoadPack(data []byte) (*Locale, error) {
+	var pack IPv6Names
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&pack); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("name pack is empty")
+		}
+		return nil, fmt.Errorf("failed to parse name pack: %w", err)
+	}
+	if pack.Name == "" {
+		pack.Name = "pack"
+	}
+	if !packNamePattern.MatchString(pack.Name) {
+		return nil, fmt.Errorf("name pack name %q must be lowercase letters, digits, - and _", pack.Name)
+	}
+
+	locale := &Locale{
+		lang:           pack.Name,
+		adjectives:     levelWords(pack.Adjectives),
+		nouns:          levelWords(pack.Nouns),
+		celestialTypes: levelWords(pack.CelestialTypes),
+		format:         pack.Format,
+	}
+	if locale.format == "" {
+		locale.format = defaultFormat
+	}
+	for _, size := range Levels {
+		if len(locale.adjectives[size]) == 0 || len(locale.nouns[size]) == 0 || len(locale.celestialTypes[size]) == 0 {
+			return nil, fmt.Errorf("name pack %s has no words for /%d", pack.Name, size)
+		}
+	}
+
+	// Maps marshal with sorted keys, so a pack hashes the same in either format
+	pack.SubnetMappings, pack.LevelNames = nil, nil
+	canonical, err := json.Marshal(pack)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	locale.packID = pack.Name + "-" + hex.EncodeToString(sum[:6])
+	locale.pack = canonical
+	return locale, nil
+}
+
+// LoadPackFile reads a name pack from a JSON or YAML file
+func LoadPackFile(path string) (*Locale, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read name pack: %w", err)
+	}
+	return LoadPack(data)
+}
+
+// PackID returns the version of a name pack, such as "ocean-1a2b3c4d5e6f",
+// or an empty string for the embedded locales
+func (l *Locale) PackID() string {
+	return l.packID
+}
+
+// Pack returns a name pack as canonical JSON, which loads as the same pack,
+// or nil for the embedded locales
+func (l *Locale) Pack() []byte {
+	return l.pack
+}
@@ -0,0 +1,65 @@
+package names
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oceanPack returns a YAML name pack with one word per list at every level
+func oceanPack() string {
+	var b strings.Builder
+	b.WriteString("name: ocean\nformat: \"{noun} {type} of {adjective}\"\n")
+	for _, list := range []string{"adjectives", "nouns", "celestialTypes"} {
+		b.WriteString(list + ":\n")
+		for _, size := range Levels {
+			fmt.Fprintf(&b, "  %d: [%s%d]\n", size, strings.TrimSuffix(list, "s"), size)
+		}
+	}
+	return b.String()
+}
+
+// TestLoadPack tests that name packs theme names and are versioned by their words
+func TestLoadPack(t *testing.T) {
+	pack, err := LoadPack([]byte(oceanPack()))
+	require.NoError(t, err, "YAML packs should load")
+	assert.Regexp(t, `^ocean-[0-9a-f]{12}$`, pack.PackID())
+
+	name, err := pack.GenerateName("2001:db8::1", 48)
+	require.NoError(t, err)
+	assert.Regexp(t, `^noun48 celestialType48 of adjective48-\d+$`, name, "Names should use the pack's words and format")
+
+	again, err := LoadPack(pack.Pack())
+	require.NoError(t, err, "Canonical JSON should load")
+	assert.Equal(t, pack.PackID(), again.PackID(), "The same words should have the same ID in either format")
+
+	changed, err := LoadPack([]byte(strings.Replace(oceanPack(), "[noun48]", "[noun48, reef]", 1)))
+	require.NoError(t, err)
+	assert.NotEqual(t, pack.PackID(), changed.PackID(), "Changed words should change the ID")
+
+	_, err = LoadPack([]byte(strings.Replace(oceanPack(), "  128: [noun128]\n", "", 1)))
+	assert.Error(t, err, "Packs missing a level should be rejected")
+	_, err = LoadPack([]byte(strings.Replace(oceanPack(), "name: ocean", "name: Ocean Deep", 1)))
+	assert.Error(t, err, "Pack names should fit in an ID")
+	_, err = LoadPack(nil)
+	assert.Error(t, err, "Empty packs should be rejected")
+}
+
+// TestIndex_ResolvePack tests that an index for a pack resolves the pack's names only
+func TestIndex_ResolvePack(t *testing.T) {
+	pack, err := LoadPack([]byte(oceanPack()))
+	require.NoError(t, err)
+	index := NewIndexFor(pack)
+	require.NoError(t, index.Add("2001:db8::/32"))
+
+	name, err := pack.GenerateName("2001:db8::", 32)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8::/32"}, index.Resolve(name))
+
+	builtin, err := GenerateName("2001:db8::", 32)
+	require.NoError(t, err)
+	assert.Empty(t, index.Resolve(builtin), "Embedded names should not resolve")
+}
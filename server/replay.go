@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bjia56/spacenet/server/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// maxReportedDifferences bounds the differing addresses listed by a replay
+const maxReportedDifferences = 20
+
+// replayOptions configures a replay of an event log
+type replayOptions struct {
+	from     string
+	until    string
+	snapshot string
+	top      int
+}
+
+// newReplayCommand creates the event log replay command
+func newReplayCommand() *cobra.Command {
+	opts := replayOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Rebuild the claims of a database from its event log",
+		Long: "Replay the claim event log of a SQLite database up to a point in time and report the resulting " +
+			"statistics and leaderboard. Replaying the whole log verifies the result against the claims the " +
+			"database holds, or against another database given with --snapshot, and fails if any address differs.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReplay(cmd.OutOrStdout(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.from, "from", "", "SQLite database whose event log is replayed")
+	cmd.Flags().StringVar(&opts.until, "until", "", "Replay events up to this RFC 3339 time, all events if empty")
+	cmd.Flags().StringVar(&opts.snapshot, "snapshot", "", "SQLite database to verify the result against, --from if empty and replaying all events")
+	cmd.Flags().IntVar(&opts.top, "top", 10, "Claimants shown from the leaderboard")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+// runReplay replays an event log and prints its report
+func runReplay(w io.Writer, opts replayOptions) error {
+	var until time.Time
+	if opts.until != "" {
+		var err error
+		if until, err = time.Parse(time.RFC3339, opts.until); err != nil {
+			return fmt.Errorf("until must be an RFC 3339 time: %w", err)
+		}
+	}
+	if _, err := os.Stat(opts.from); err != nil {
+		return err
+	}
+
+	store, stats, err := server.ReplayEventLog(opts.from, until)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Replayed %d events", stats.Events)
+	if !stats.Last.IsZero() {
+		fmt.Fprintf(w, " up to %s", stats.Last.Format(time.RFC3339))
+	}
+	if stats.Skipped > 0 {
+		fmt.Fprintf(w, ", skipped %d later events", stats.Skipped)
+	}
+	fmt.Fprintln(w)
+
+	ctx := context.Background()
+	totals := store.GetStats(ctx)
+	fmt.Fprintf(w, "%d addresses claimed by %d claimants\n", totals.TotalClaims, totals.Claimants)
+	for i, entry := range store.GetLeaderboard(ctx, opts.top) {
+		fmt.Fprintf(w, "%3d. %s: %d\n", i+1, entry.Name, entry.Addresses)
+	}
+
+	snapshot := opts.snapshot
+	if snapshot == "" && until.IsZero() {
+		snapshot = opts.from
+	}
+	if snapshot == "" {
+		return nil
+	}
+
+	expected, differences, err := server.CompareSnapshot(store, snapshot)
+	if err != nil {
+		return err
+	}
+	if len(differences) == 0 {
+		fmt.Fprintf(w, "Matches snapshot %s\n", snapshot)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Snapshot %s has %d addresses claimed by %d claimants, %d addresses differ:\n",
+		snapshot, expected.TotalClaims, expected.Claimants, len(differences))
+	for i, diff := range differences {
+		if i == maxReportedDifferences {
+			fmt.Fprintf(w, "  ...and %d more\n", len(differences)-i)
+			break
+		}
+		fmt.Fprintf(w, "  %s: replayed %s, snapshot %s\n", diff.IP, orUnclaimed(diff.Replayed), orUnclaimed(diff.Snapshot))
+	}
+	return fmt.Errorf("replay does not match snapshot %s", snapshot)
+}
+
+// orUnclaimed describes the claimant of an address, which may be empty
+func orUnclaimed(claimant string) string {
+	if claimant == "" {
+		return "unclaimed"
+	}
+	return claimant
+}
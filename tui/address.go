@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Labels of the address prompt, for its two actions
+const (
+	jumpLabel  = "Jump to: "
+	claimLabel = "Claim: "
+)
+
+// openAddressInput starts typing an address or subnet to jump to, or with
+// tab an address to claim
+func (m *Model) openAddressInput() {
+	m.prompt = &prompt{label: jumpLabel, submit: (*Model).jumpToSubmit, hint: (*Model).jumpHint, tab: toggleAddressAction}
+}
+
+// toggleAddressAction switches the address prompt between jumping and claiming
+func toggleAddressAction(p *prompt) {
+	if p.label == jumpLabel {
+		p.label, p.submit, p.hint = claimLabel, (*Model).claimSubmit, (*Model).claimHint
+	} else {
+		p.label, p.submit, p.hint = jumpLabel, (*Model).jumpToSubmit, (*Model).jumpHint
+	}
+}
+
+// jumpToInput jumps to an address, or to a subnet in CIDR notation
+func (m *Model) jumpToInput(input string) error {
+	if strings.Contains(input, "/") {
+		return m.JumpToSubnet(input)
+	}
+	return m.JumpTo(input)
+}
+
+// jumpToSubmit jumps to the address or subnet typed
+func (m *Model) jumpToSubmit(input string) tea.Cmd {
+	if input == "" {
+		return nil
+	}
+	if err := m.jumpToInput(input); err != nil {
+		m.errorMessage = errorMessageStyle.Render(err.Error())
+	}
+	return nil
+}
+
+// claimSubmit claims the address typed
+func (m *Model) claimSubmit(input string) tea.Cmd {
+	if input == "" {
+		return nil
+	}
+	ip, err := api.ParseAddress(input)
+	if err != nil {
+		m.errorMessage = errorMessageStyle.Render(err.Error())
+		return nil
+	}
+	return m.claim(ip.String())
+}
+
+// jumpHint validates an address or subnet as it is typed, showing it
+// written out in full
+func (m *Model) jumpHint(input string) string {
+	input = strings.TrimSpace(input)
+	switch {
+	case input == "":
+		return helpStyle("address or subnet, tab: claim instead")
+	case strings.Contains(input, "/"):
+		ipNet, _, err := m.parseSubnetLevel(input)
+		if err != nil {
+			return errorMessageStyle.Render(err.Error())
+		}
+		ones, _ := ipNet.Mask.Size()
+		return helpStyle(fmt.Sprintf("= %s/%d", api.ExpandAddress(ipNet.IP), ones))
+	}
+	return addressHint(input)
+}
+
+// claimHint validates an address to claim as it is typed, showing it
+// written out in full
+func (m *Model) claimHint(input string) string {
+	input = strings.TrimSpace(input)
+	switch {
+	case input == "":
+		return helpStyle("address, tab: jump instead")
+	case strings.Contains(input, "/"):
+		return errorMessageStyle.Render("claims are for single addresses")
+	}
+	return addressHint(input)
+}
+
+// addressHint shows an address written out in full, or why it is invalid
+func addressHint(input string) string {
+	ip, err := api.ParseAddress(input)
+	if err != nil {
+		return errorMessageStyle.Render("not an IPv6 address")
+	}
+	return helpStyle("= " + api.ExpandAddress(ip))
+}
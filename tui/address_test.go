@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAddressHints tests the hints shown as addresses and subnets are typed
+func TestAddressHints(t *testing.T) {
+	m := &Model{depth: t64}
+	testCases := []struct {
+		name     string
+		hint     func(string) string
+		input    string
+		expected string
+	}{
+		{"jump empty", m.jumpHint, "", "tab: claim instead"},
+		{"jump address", m.jumpHint, " 2001:db8::1 ", "= 2001:0db8:0000:0000:0000:0000:0000:0001"},
+		{"jump subnet", m.jumpHint, "2001:db8::/32", "= 2001:0db8:0000:0000:0000:0000:0000:0000/32"},
+		{"jump unbrowsable subnet", m.jumpHint, "2001:db8::/33", "not a browsable level"},
+		{"jump below the last level", m.jumpHint, "2001:db8::/80", "not a browsable level"},
+		{"jump invalid subnet", m.jumpHint, "2001:db8::/", "invalid IPv6 subnet"},
+		{"jump ipv4", m.jumpHint, "192.0.2.1", "not an IPv6 address"},
+		{"claim empty", m.claimHint, "", "tab: jump instead"},
+		{"claim address", m.claimHint, "::1", "= 0000:0000:0000:0000:0000:0000:0000:0001"},
+		{"claim subnet", m.claimHint, "2001:db8::/32", "claims are for single addresses"},
+		{"claim garbage", m.claimHint, "not an address", "not an IPv6 address"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Contains(t, tc.hint(tc.input), tc.expected)
+		})
+	}
+}
+
+// TestToggleAddressAction tests that tab switches the prompt between jumping and claiming
+func TestToggleAddressAction(t *testing.T) {
+	m := &Model{}
+	m.openAddressInput()
+	assert.Equal(t, jumpLabel, m.prompt.label)
+
+	toggleAddressAction(m.prompt)
+	assert.Equal(t, claimLabel, m.prompt.label)
+	assert.Contains(t, m.prompt.hint(m, ""), "tab: jump instead")
+
+	toggleAddressAction(m.prompt)
+	assert.Equal(t, jumpLabel, m.prompt.label)
+	assert.Contains(t, m.prompt.hint(m, ""), "tab: claim instead")
+}
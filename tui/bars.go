@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/colors"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxBarWidth is the most cells a percentage bar fills
+const maxBarWidth = 12
+
+// percentageWidth is the width of the widest percentage, "100.00%"
+const percentageWidth = 7
+
+// Characters percentage bars are drawn with, replaced in the ascii theme.
+// Partial blocks fill an eighth of a cell each.
+var (
+	barBlock         = "█"
+	barPartialBlocks = []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+)
+
+// ownerColors is the palette owners and their percentage bars are colored
+// from, none without color
+var ownerColors = colors.Standard
+
+// ownerColor returns the color of an owner, the same in every client
+func ownerColor(owner string) (lipgloss.Color, bool) {
+	color := ownerColors.Color(owner)
+	return lipgloss.Color(color), color != ""
+}
+
+// formatPercentage formats a percentage of a subnet's addresses, with a bar
+// in the owner's color if bars are shown and fit the column
+func (m *Model) formatPercentage(percentage float64, owner string) string {
+	number := strconv.FormatFloat(percentage, 'f', 2, 64) + "%"
+	if !m.showBars {
+		return number
+	}
+
+	width := m.unitTables[t16].Columns()[2].Width - percentageWidth - 1
+	style := lipgloss.NewStyle()
+	if color, ok := ownerColor(owner); ok {
+		// Tables cut cells to their width counting color codes, so the codes
+		// are left out of the bar's width
+		style = style.Foreground(color)
+		width -= styleOverhead(style)
+	}
+	width = min(width, maxBarWidth)
+	if width <= 0 {
+		return number
+	}
+	return style.Render(percentageBar(percentage, width)) + " " + fmt.Sprintf("%*s", percentageWidth, number)
+}
+
+// percentageBar draws a percentage as a bar a number of cells wide, padded
+// with spaces so the numbers after bars line up
+func percentageBar(percentage float64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	eighths := int(percentage / 100 * float64(width*8))
+	eighths = min(max(eighths, 0), width*8)
+	full, partial := eighths/8, eighths%8
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat(barBlock, full))
+	cells := full
+	if partial > 0 && len(barPartialBlocks) > 0 {
+		b.WriteString(barPartialBlocks[partial-1])
+		cells++
+	}
+	b.WriteString(strings.Repeat(" ", width-cells))
+	return b.String()
+}
+
+// toggleBars switches the percentage column between bars and plain numbers
+func (m *Model) toggleBars() {
+	m.showBars = !m.showBars
+	m.refresh()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// claimWorkers is the number of claims solved at once. Solving already uses
+// every CPU, so further claims wait in the queue.
+const claimWorkers = 1
+
+// claimSender sends claims to a server as a player, captured when a claim is
+// queued so switching servers or names leaves queued claims alone
+type claimSender struct {
+	client *conditionalClient
+	host   string
+	name   string
+}
+
+// sender returns what claims are currently sent with
+func (m *Model) sender() claimSender {
+	return claimSender{client: m.client, host: m.serverHost(), name: m.name}
+}
+
+// send solves the proof of work of a claim on an address and sends it,
+// returning the success message. Cancelling the context abandons the claim.
+func (s claimSender) send(ctx context.Context, ip string) (string, error) {
+	// Parse the IP to ensure it's valid
+	targetIP := net.ParseIP(ip)
+	if targetIP == nil {
+		return "", fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	challenge, err := fetchChallenge(s.client, s.host, ip)
+	if err != nil {
+		return "", err
+	}
+	scheme, err := challenge.PoWScheme()
+	if err != nil {
+		return "", err
+	}
+
+	// Solve proof of work on every CPU
+	pow, stats, err := api.SolveProofOfWorkContext(ctx, scheme, targetIP, s.name, challenge.Difficulty, maxClaimAttempts, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to solve proof of work: %w", err)
+	}
+	solved := fmt.Sprintf("%d hashes in %s, %s", stats.Hashes, stats.Elapsed.Truncate(time.Millisecond), formatHashRate(stats.HashRate()))
+
+	// Create claim request
+	data, err := json.Marshal(api.ClaimRequest{Nonce: pow.Nonce, Name: pow.Name})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	// Send HTTP POST request to server
+	serverURL := fmt.Sprintf("http://%s/api/v1/claim/%s", s.host, ip)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL, strings.NewReader(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error closing response body: %v", err)
+		}
+	}()
+
+	// Check response status
+	if resp.StatusCode == http.StatusCreated {
+		return fmt.Sprintf("Claim sent! (%s)", solved), nil
+	}
+
+	var errResp api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && errResp.Message != "" {
+		return "", fmt.Errorf("server rejected claim: %s (request %s)", errResp.Message, errResp.RequestID)
+	}
+	return "", fmt.Errorf("server returned status: %d", resp.StatusCode)
+}
+
+// claimJob is a claim being solved or waiting for a worker
+type claimJob struct {
+	id     int
+	ip     string
+	cancel context.CancelFunc
+}
+
+// claimDoneMsg reports the outcome of a claim sent in the background
+type claimDoneMsg struct {
+	id  int
+	ip  string
+	msg string // Success message
+	err error
+}
+
+// claimPool solves claims in the background on a fixed number of workers,
+// queueing the rest in the order they were made
+type claimPool struct {
+	slots  chan struct{} // Held by the claims being solved
+	jobs   []*claimJob   // Claims being solved or queued, oldest first
+	nextID int
+}
+
+// newClaimPool creates a pool solving up to workers claims at once
+func newClaimPool(workers int) *claimPool {
+	return &claimPool{slots: make(chan struct{}, workers)}
+}
+
+// busy reports whether any claim is being solved or queued
+func (p *claimPool) busy() bool {
+	return len(p.jobs) > 0
+}
+
+// cancelAll abandons every claim being solved or queued
+func (p *claimPool) cancelAll() {
+	for _, job := range p.jobs {
+		job.cancel()
+	}
+	p.jobs = nil
+}
+
+// finish forgets a claim once it is done, reporting whether it was still
+// pending rather than cancelled
+func (p *claimPool) finish(id int) bool {
+	for i, job := range p.jobs {
+		if job.id == id {
+			job.cancel()
+			p.jobs = append(p.jobs[:i], p.jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// status describes the claims in progress for the status line
+func (p *claimPool) status(cancelKey string) string {
+	status := fmt.Sprintf("Solving the proof of work for %s", p.jobs[0].ip)
+	if queued := len(p.jobs) - claimWorkers; queued > 0 {
+		status += fmt.Sprintf(" (%d queued)", queued)
+	}
+	return status + fmt.Sprintf(", %s: cancel", cancelKey)
+}
+
+// claim claims an address, first showing its cost for confirmation if enabled
+func (m *Model) claim(ip string) tea.Cmd {
+	if m.spectate {
+		m.statusMessage = statusMessageStyle.Render("Claiming is disabled in spectator mode")
+		return nil
+	}
+	if m.confirmClaims {
+		m.confirmClaim(ip)
+		return nil
+	}
+	return m.sendClaim(ip)
+}
+
+// sendClaim queues a claim for an address, solving it in the background and
+// reporting the outcome in the status line once done
+func (m *Model) sendClaim(ip string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.claims.nextID++
+	job := &claimJob{id: m.claims.nextID, ip: ip, cancel: cancel}
+	m.claims.jobs = append(m.claims.jobs, job)
+
+	sender, slots := m.sender(), m.claims.slots
+	return func() tea.Msg {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		case <-ctx.Done():
+			return claimDoneMsg{id: job.id, ip: ip, err: ctx.Err()}
+		}
+		msg, err := sender.send(ctx, ip)
+		return claimDoneMsg{id: job.id, ip: ip, msg: msg, err: err}
+	}
+}
+
+// handleClaimDone reports the outcome of a claim, unless it was cancelled
+func (m *Model) handleClaimDone(msg claimDoneMsg) {
+	if !m.claims.finish(msg.id) || errors.Is(msg.err, context.Canceled) {
+		return
+	}
+	if msg.err == nil {
+		m.statusMessage = statusMessageStyle.Render(msg.msg)
+		m.errorMessage = ""
+	} else {
+		m.errorMessage = errorMessageStyle.Render("Failed to send claim: " + msg.err.Error())
+		m.statusMessage = ""
+	}
+	m.refreshClaims = true
+}
+
+// cancelClaims abandons the claims in progress
+func (m *Model) cancelClaims() {
+	m.claims.cancelAll()
+	m.statusMessage = statusMessageStyle.Render("Claims cancelled")
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Config is the configuration of the TUI, read from a TOML file. Command
+// line flags override the file.
+type Config struct {
+	Server         string        `toml:"server"`          // IPv6 address of the server
+	HTTPPort       int           `toml:"http_port"`       // HTTP port for the server's API
+	Name           string        `toml:"name"`            // Name to use for claims
+	Lang           string        `toml:"lang"`            // Language of subnet names
+	NamePack       string        `toml:"name_pack"`       // JSON or YAML word lists to generate subnet names with
+	Refresh        time.Duration `toml:"refresh"`         // Shortest interval between refreshes of the visible claims
+	MaxRefresh     time.Duration `toml:"max_refresh"`     // Longest interval refreshes back off to while nothing changes, at least Refresh
+	AutoRefresh    bool          `toml:"auto_refresh"`    // Refresh the visible claims periodically when not spectating
+	Bell           bool          `toml:"bell"`            // Ring the terminal bell on takeover alerts
+	ConfirmClaims  bool          `toml:"confirm_claims"`  // Confirm the estimated cost of claims before solving them
+	PercentageBars bool          `toml:"percentage_bars"` // Draw percentages as bars in the owners' colors
+	RestoreSession bool          `toml:"restore_session"` // Resume at the subnet the last run on the server ended at
+	Profile        string        `toml:"profile"`         // Profile to start with, the settings above if empty
+	Profiles       []Profile     `toml:"profiles"`        // Servers to switch between
+	Theme          ThemeConfig   `toml:"theme"`
+	Keys           KeyConfig     `toml:"keys"`
+}
+
+// KeyConfig lists the keys bound to each action, such as ["q", "ctrl+c"].
+// Keys are named as bubbletea names them.
+type KeyConfig struct {
+	Quit     []string `toml:"quit"`
+	Up       []string `toml:"up"`
+	Down     []string `toml:"down"`
+	Select   []string `toml:"select"`   // Open the selected subnet, or claim it at the last level
+	Back     []string `toml:"back"`     // Return to the parent table
+	Takeover []string `toml:"takeover"` // Jump to the address of the latest takeover alert
+	Sort     []string `toml:"sort"`     // Cycle the table's sort order
+	Claimed  []string `toml:"claimed"`  // Toggle showing only claimed subnets
+	Mine     []string `toml:"mine"`     // Toggle showing only your subnets
+	Filter   []string `toml:"filter"`   // Type an owner to filter by
+	Clear    []string `toml:"clear"`    // Clear the table's sort order and filter
+	Command  []string `toml:"command"`  // Open the command palette
+	Help     []string `toml:"help"`     // Show every key and command
+	Servers  []string `toml:"servers"`  // Switch to another server profile
+	Discover []string `toml:"discover"` // Find servers on the local network
+	Address  []string `toml:"address"`  // Type an address or subnet to jump to or claim
+	History  []string `toml:"history"`  // Toggle the claim history of the selected address
+	Pause    []string `toml:"pause"`    // Pause scrolling the takeover ticker
+	Nearby   []string `toml:"nearby"`   // Toggle showing only takeovers under the current subnet in the ticker
+	Bars     []string `toml:"bars"`     // Toggle percentage bars
+	Refresh  []string `toml:"refresh"`  // Refresh the visible claims now
+}
+
+// defaultProfile names the profile of the top-level server settings
+const defaultProfile = "default"
+
+// Profile is a server to play on and the identity to play there as, for
+// players in several games
+type Profile struct {
+	Name     string `toml:"name"`      // Name shown in the server switcher
+	Server   string `toml:"server"`    // IPv6 address of the server
+	HTTPPort int    `toml:"http_port"` // HTTP port for the server's API, the top-level port if zero
+	Player   string `toml:"player"`    // Name to use for claims, the top-level name if empty
+}
+
+// DefaultConfig returns the configuration used for settings missing from the file
+func DefaultConfig() Config {
+	return Config{
+		Server:         "::1",
+		HTTPPort:       8080,
+		Name:           "Anonymous",
+		Lang:           names.DefaultLocale,
+		Refresh:        5 * time.Second,
+		MaxRefresh:     time.Minute,
+		AutoRefresh:    true,
+		ConfirmClaims:  true,
+		PercentageBars: true,
+		RestoreSession: true,
+		Theme:          DefaultThemeConfig(),
+		Keys: KeyConfig{
+			Quit:     []string{"q", "ctrl+c"},
+			Up:       []string{"up", "k"},
+			Down:     []string{"down", "j"},
+			Select:   []string{"enter"},
+			Back:     []string{"esc"},
+			Takeover: []string{"t"},
+			Sort:     []string{"s"},
+			Claimed:  []string{"c"},
+			Mine:     []string{"m"},
+			Filter:   []string{"/"},
+			Clear:    []string{"x"},
+			Command:  []string{":"},
+			Help:     []string{"?"},
+			Servers:  []string{"S"},
+			Discover: []string{"D"},
+			Address:  []string{"a"},
+			History:  []string{"h"},
+			Pause:    []string{"p"},
+			Nearby:   []string{"n"},
+			Bars:     []string{"%"},
+			Refresh:  []string{"r"},
+		},
+	}
+}
+
+// DefaultConfigPath returns ~/.config/spacenet/config.toml, or its
+// equivalent under $XDG_CONFIG_HOME
+func DefaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "spacenet", "config.toml")
+}
+
+// LoadConfig reads the configuration from a file over the defaults. A
+// missing file leaves the defaults unless it was asked for explicitly.
+func LoadConfig(path string, required bool) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	meta, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("unknown setting %q in config %s", undecoded[0].String(), path)
+	}
+	return cfg, nil
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	if c.Refresh <= 0 {
+		return errors.New("refresh interval must be positive")
+	}
+	if c.MaxRefresh < 0 {
+		return errors.New("max refresh interval must not be negative")
+	}
+	if c.HTTPPort <= 0 || c.HTTPPort > 65535 {
+		return fmt.Errorf("invalid http port %d", c.HTTPPort)
+	}
+	if _, ok := names.LookupLocale(c.Lang); !ok {
+		return fmt.Errorf("unknown language %q, choose one of %s", c.Lang, strings.Join(names.Locales(), ", "))
+	}
+	if err := c.Theme.Validate(); err != nil {
+		return err
+	}
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
+	return c.Keys.Validate()
+}
+
+// validateProfiles checks that profiles have unique names and a server
+func (c Config) validateProfiles() error {
+	seen := map[string]bool{defaultProfile: true}
+	for _, p := range c.Profiles {
+		if p.Name == "" || p.Server == "" {
+			return errors.New("profiles must have a name and a server")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("profile name %q is used twice or reserved", p.Name)
+		}
+		seen[p.Name] = true
+		if p.HTTPPort < 0 || p.HTTPPort > 65535 {
+			return fmt.Errorf("invalid http port %d of profile %s", p.HTTPPort, p.Name)
+		}
+	}
+	return nil
+}
+
+// applyProfile replaces the top-level server settings with those of the
+// profile to start with, if any
+func (c *Config) applyProfile() error {
+	if c.Profile == "" {
+		return nil
+	}
+	for _, p := range c.profiles() {
+		if p.Name == c.Profile {
+			c.Server, c.HTTPPort, c.Name = p.Server, p.HTTPPort, p.Player
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown profile %q", c.Profile)
+}
+
+// profiles returns the top-level server settings as the default profile,
+// followed by the configured profiles with the top-level port and name
+// filled in where missing
+func (c Config) profiles() []Profile {
+	profiles := []Profile{{Name: defaultProfile, Server: c.Server, HTTPPort: c.HTTPPort, Player: c.Name}}
+	for _, p := range c.Profiles {
+		p.HTTPPort = cmp.Or(p.HTTPPort, c.HTTPPort)
+		p.Player = cmp.Or(p.Player, c.Name)
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+// actions returns the key lists by action name, in the order of the help
+func (k KeyConfig) actions() []struct {
+	name string
+	keys []string
+} {
+	return []struct {
+		name string
+		keys []string
+	}{
+		{"quit", k.Quit}, {"up", k.Up}, {"down", k.Down}, {"select", k.Select},
+		{"back", k.Back}, {"takeover", k.Takeover}, {"sort", k.Sort}, {"claimed", k.Claimed},
+		{"mine", k.Mine}, {"filter", k.Filter}, {"clear", k.Clear}, {"command", k.Command},
+		{"help", k.Help}, {"servers", k.Servers}, {"discover", k.Discover}, {"address", k.Address},
+		{"history", k.History}, {"pause", k.Pause}, {"nearby", k.Nearby},
+		{"bars", k.Bars}, {"refresh", k.Refresh},
+	}
+}
+
+// Validate checks that every action has a key and that no key has two actions
+func (k KeyConfig) Validate() error {
+	bound := make(map[string]string)
+	for _, action := range k.actions() {
+		if len(action.keys) == 0 {
+			return fmt.Errorf("no key is bound to %s", action.name)
+		}
+		for _, key := range action.keys {
+			// Digits jump to the levels of the breadcrumbs
+			if len(key) == 1 && key >= "1" && key <= "8" {
+				return fmt.Errorf("key %q of %s is reserved for jumping to levels", key, action.name)
+			}
+			if other, exists := bound[key]; exists && other != action.name {
+				return fmt.Errorf("key %q is bound to both %s and %s", key, other, action.name)
+			}
+			bound[key] = action.name
+		}
+	}
+	return nil
+}
+
+// keyMap holds the key bindings of the actions
+type keyMap struct {
+	Quit, Up, Down, Select, Back, Takeover key.Binding
+	Sort, Claimed, Mine, Filter, Clear     key.Binding
+	Command, Help, Servers, Discover       key.Binding
+	Address, History, Pause, Nearby, Bars  key.Binding
+	Refresh                                key.Binding
+}
+
+// newKeyMap creates the key bindings from their configuration
+func newKeyMap(k KeyConfig) keyMap {
+	bind := func(keys []string, desc string) key.Binding {
+		return key.NewBinding(key.WithKeys(slices.Clone(keys)...), key.WithHelp(strings.Join(keys, "/"), desc))
+	}
+	return keyMap{
+		Quit:     bind(k.Quit, "quit"),
+		Up:       bind(k.Up, "move up"),
+		Down:     bind(k.Down, "move down"),
+		Select:   bind(k.Select, "open the selected subnet, or claim it at the last level"),
+		Back:     bind(k.Back, "return to the parent subnet"),
+		Takeover: bind(k.Takeover, "jump to the address of the latest takeover alert"),
+		Sort:     bind(k.Sort, "sort by owner, percentage or address"),
+		Claimed:  bind(k.Claimed, "show only claimed subnets"),
+		Mine:     bind(k.Mine, "show only your subnets"),
+		Filter:   bind(k.Filter, "filter by owner"),
+		Clear:    bind(k.Clear, "clear the sort order and filter"),
+		Command:  bind(k.Command, "open the command palette"),
+		Help:     bind(k.Help, "show this help"),
+		Servers:  bind(k.Servers, "switch to another server profile"),
+		Discover: bind(k.Discover, "find servers on the local network"),
+		Address:  bind(k.Address, "type an address or subnet to jump to, or tab to claim it"),
+		History:  bind(k.History, "show the claim history of the selected address"),
+		Pause:    bind(k.Pause, "pause the takeover ticker"),
+		Nearby:   bind(k.Nearby, "show only takeovers under the current subnet in the ticker"),
+		Bars:     bind(k.Bars, "switch percentages between bars and plain numbers"),
+		Refresh:  bind(k.Refresh, "refresh the visible claims now"),
+	}
+}
+
+// keyName returns the first key of a binding, for the help
+func keyName(binding key.Binding) string {
+	return binding.Keys()[0]
+}
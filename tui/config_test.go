@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes a config file to a temporary directory, returning its path
+func writeConfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+// TestLoadConfig tests that settings in the file override the defaults
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+server = "2001:db8::1"
+refresh = "10s"
+bell = true
+
+[keys]
+quit = ["Q"]
+
+[[profiles]]
+name = "work"
+server = "2001:db8::2"
+`)
+	cfg, err := LoadConfig(path, true)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	defaults := DefaultConfig()
+	assert.Equal(t, "2001:db8::1", cfg.Server)
+	assert.Equal(t, 10*time.Second, cfg.Refresh)
+	assert.True(t, cfg.Bell)
+	assert.Equal(t, []string{"Q"}, cfg.Keys.Quit)
+	assert.Equal(t, defaults.HTTPPort, cfg.HTTPPort, "Settings missing from the file should keep their defaults")
+	assert.Equal(t, defaults.Keys.Up, cfg.Keys.Up, "Keys missing from the file should keep their defaults")
+	assert.Equal(t, []Profile{
+		{Name: defaultProfile, Server: "2001:db8::1", HTTPPort: defaults.HTTPPort, Player: defaults.Name},
+		{Name: "work", Server: "2001:db8::2", HTTPPort: defaults.HTTPPort, Player: defaults.Name},
+	}, cfg.profiles(), "Profiles should fall back to the top-level port and name")
+}
+
+// TestLoadConfig_Errors tests missing, malformed and unknown settings
+func TestLoadConfig_Errors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.toml")
+	cfg, err := LoadConfig(missing, false)
+	require.NoError(t, err, "A missing default config should be ignored")
+	assert.Equal(t, DefaultConfig(), cfg)
+	_, err = LoadConfig(missing, true)
+	assert.Error(t, err, "A missing config asked for should be an error")
+
+	cfg, err = LoadConfig("", true)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+
+	_, err = LoadConfig(writeConfig(t, `server = `), true)
+	assert.ErrorContains(t, err, "failed to parse config")
+	_, err = LoadConfig(writeConfig(t, `serer = "::1"`), true)
+	assert.ErrorContains(t, err, `unknown setting "serer"`)
+}
+
+// TestConfig_Validate tests that unusable configurations are rejected
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, DefaultConfig().Validate())
+
+	testCases := map[string]func(*Config){
+		"no refresh interval":      func(c *Config) { c.Refresh = 0 },
+		"negative max refresh":     func(c *Config) { c.MaxRefresh = -time.Second },
+		"port out of range":        func(c *Config) { c.HTTPPort = 70000 },
+		"unknown language":         func(c *Config) { c.Lang = "xx" },
+		"profile without a server": func(c *Config) { c.Profiles = []Profile{{Name: "work"}} },
+		"reserved profile name":    func(c *Config) { c.Profiles = []Profile{{Name: defaultProfile, Server: "::1"}} },
+		"duplicate profile": func(c *Config) {
+			c.Profiles = []Profile{{Name: "work", Server: "::1"}, {Name: "work", Server: "::2"}}
+		},
+		"unbound action":     func(c *Config) { c.Keys.Quit = nil },
+		"key bound twice":    func(c *Config) { c.Keys.Sort = []string{"q"} },
+		"level key reserved": func(c *Config) { c.Keys.Clear = []string{"3"} },
+	}
+	for name, modify := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			modify(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+// TestConfig_ApplyProfile tests that starting with a profile replaces the server settings
+func TestConfig_ApplyProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Profiles = []Profile{{Name: "work", Server: "2001:db8::2", HTTPPort: 9090, Player: "bob"}}
+
+	require.NoError(t, cfg.applyProfile(), "No profile should leave the settings")
+	assert.Equal(t, DefaultConfig().Server, cfg.Server)
+
+	cfg.Profile = "work"
+	require.NoError(t, cfg.applyProfile())
+	assert.Equal(t, "2001:db8::2", cfg.Server)
+	assert.Equal(t, 9090, cfg.HTTPPort)
+	assert.Equal(t, "bob", cfg.Name)
+
+	cfg.Profile = "home"
+	assert.ErrorContains(t, cfg.applyProfile(), `unknown profile "home"`)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxClaimAttempts is the most nonces tried to solve the proof of work of a claim
+const maxClaimAttempts = 10000000
+
+// claimEstimate is what taking an address would cost, shown for confirmation
+// before solving its proof of work
+type claimEstimate struct {
+	ip        string
+	claim     *api.ClaimResponse // Current claim, nil if unclaimed
+	challenge *api.PoWChallenge
+	hashRate  float64 // Hashes per second measured on this machine
+}
+
+// expectedHashes returns the nonces tried on average to meet the difficulty
+func (e *claimEstimate) expectedHashes() float64 {
+	return math.Exp2(float64(e.challenge.Difficulty))
+}
+
+// expectedTime returns the average time to solve the proof of work
+func (e *claimEstimate) expectedTime() string {
+	if e.hashRate <= 0 {
+		return "unknown"
+	}
+	seconds := e.expectedHashes() / e.hashRate
+	if seconds > (100 * 365 * 24 * time.Hour).Seconds() {
+		return "more than a century"
+	}
+	return formatEstimate(time.Duration(seconds * float64(time.Second)))
+}
+
+// formatEstimate rounds an estimated duration to a useful precision
+func formatEstimate(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return "under a second"
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < 24*time.Hour:
+		return d.Round(time.Minute).String()
+	default:
+		return fmt.Sprintf("%.0f days", d.Hours()/24)
+	}
+}
+
+// estimateClaim fetches the challenge and current claim of an address and
+// measures how fast this machine solves the challenge's scheme
+func (m *Model) estimateClaim(ip string) (*claimEstimate, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+	challenge, err := m.FetchChallenge(ip)
+	if err != nil {
+		return nil, err
+	}
+	claim, err := m.FetchClaim(ip)
+	if err != nil {
+		return nil, err
+	}
+	hashRate, err := m.benchmark(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &claimEstimate{ip: ip, claim: claim, challenge: challenge, hashRate: hashRate}, nil
+}
+
+// benchmark measures the hash rate of this machine for a challenge's scheme,
+// once per scheme and parameters
+func (m *Model) benchmark(challenge *api.PoWChallenge) (float64, error) {
+	scheme, err := challenge.PoWScheme()
+	if err != nil {
+		return 0, err
+	}
+	id := fmt.Sprintf("%s %+v", scheme.Name(), challenge.Argon2id)
+	if rate, ok := m.hashRates[id]; ok {
+		return rate, nil
+	}
+
+	// Memory-hard schemes take far longer per hash, so they get a few hashes per CPU
+	attempts := uint64(1 << 18)
+	if scheme.Name() != api.SchemeSHA256 {
+		attempts = uint64(2 * runtime.NumCPU())
+	}
+
+	// No nonce meets the largest difficulty, so every attempt is tried
+	_, stats, _ := api.SolveProofOfWorkWith(scheme, net.ParseIP("::"), m.name, math.MaxUint8, attempts, 0)
+	m.hashRates[id] = stats.HashRate()
+	return m.hashRates[id], nil
+}
+
+// confirmClaim shows the cost of claiming an address, waiting for confirmation
+func (m *Model) confirmClaim(ip string) {
+	estimate, err := m.estimateClaim(ip)
+	if err != nil {
+		m.errorMessage = errorMessageStyle.Render("Failed to estimate claim: " + err.Error())
+		return
+	}
+	m.confirming = estimate
+}
+
+// handleConfirmKey sends the claim being confirmed on y or enter, and
+// cancels it on n or esc
+func (m *Model) handleConfirmKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "enter":
+		ip := m.confirming.ip
+		m.confirming = nil
+		return m.sendClaim(ip)
+	case "n", "esc":
+		m.confirming = nil
+		m.statusMessage = statusMessageStyle.Render("Claim cancelled")
+	}
+	return nil
+}
+
+// confirmDialog renders the cost of the claim being confirmed
+func (m *Model) confirmDialog() string {
+	e := m.confirming
+	var b strings.Builder
+	row := func(label, value string) {
+		fmt.Fprintf(&b, "%-14s %s\n", label, value)
+	}
+
+	fmt.Fprintf(&b, "Claim %s?\n\n", e.ip)
+	switch {
+	case e.claim == nil:
+		row("Owner", "unclaimed")
+	case e.claim.Name == m.name:
+		row("Owner", e.claim.Name+" (you)")
+	default:
+		row("Owner", e.claim.Name)
+	}
+
+	protection := "none"
+	if e.claim != nil && e.claim.Fortification > 0 {
+		protection = fmt.Sprintf("fortified to level %d", e.claim.Fortification)
+	}
+	row("Protection", protection)
+	row("Difficulty", fmt.Sprintf("%d bits (%s)", e.challenge.Difficulty, cmp.Or(e.challenge.Scheme, api.SchemeSHA256)))
+	row("Hash rate", formatHashRate(e.hashRate))
+	row("Estimated", e.expectedTime())
+	if e.expectedHashes() > maxClaimAttempts {
+		b.WriteString(alertMessageStyle.Render(fmt.Sprintf("Likely to give up after %d attempts", maxClaimAttempts)) + "\n")
+	}
+
+	b.WriteString("\n" + helpStyle("y/enter: claim, n/esc: cancel"))
+	return tableStyle.Padding(1, 2).Render(b.String())
+}
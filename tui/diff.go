@@ -0,0 +1,123 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Timing of the highlight of changed rows
+const (
+	fadeDuration = 3 * time.Second        // How long changed rows stay highlighted
+	fadeInterval = 500 * time.Millisecond // Time between fading highlights a step
+)
+
+// fadeColors are the colors changed rows fade through, brightest first. Without
+// color, changed rows are bold until the highlight ends.
+var fadeColors = []lipgloss.Color{"226", "220", "214", "178", "172", "136"}
+
+// rowChange is when the claim on a subnet last changed between fetches
+type rowChange struct {
+	at    time.Time
+	owner bool // Whether the owner changed at the latest fetch, rather than only the percentage
+}
+
+// fadeTickMsg fades the highlights of changed rows a step
+type fadeTickMsg struct{}
+
+// noteChange records whether the owner or percentage of a subnet changed
+// since it was last fetched. Subnets fetched for the first time are not
+// highlighted.
+func (m *Model) noteChange(cidr string, subnetResp api.SubnetResponse) {
+	last, seen := m.lastClaims[cidr]
+	m.lastClaims[cidr] = subnetResp
+	change, changing := m.changes[cidr]
+	switch {
+	case seen && last.Owner != subnetResp.Owner:
+		m.changes[cidr] = rowChange{at: time.Now(), owner: true}
+		m.lastChange = time.Now()
+	case seen && last.Percentage != subnetResp.Percentage:
+		m.changes[cidr] = rowChange{at: time.Now()}
+		m.lastChange = time.Now()
+	case changing:
+		change.owner = false // Marked until the next fetch only
+		m.changes[cidr] = change
+	}
+}
+
+// highlight returns the style of a changed subnet's row, false once the
+// change has faded or in plain mode
+func (m *Model) highlight(cidr string) (lipgloss.Style, bool) {
+	change, ok := m.changes[cidr]
+	age := time.Since(change.at)
+	if !ok || age >= fadeDuration || m.plain {
+		return lipgloss.Style{}, false
+	}
+	if len(fadeColors) == 0 {
+		return lipgloss.NewStyle().Bold(true), true
+	}
+	step := int(age * time.Duration(len(fadeColors)) / fadeDuration)
+	return lipgloss.NewStyle().Foreground(fadeColors[step]), true
+}
+
+// fade schedules the next step of fading highlights while rows are
+// highlighted or claims are about to be fetched
+func (m *Model) fade() tea.Cmd {
+	if m.fading || (!m.refreshClaims && !m.highlighting()) {
+		return nil
+	}
+	m.fading = true
+	return tea.Tick(fadeInterval, func(time.Time) tea.Msg {
+		return fadeTickMsg{}
+	})
+}
+
+// highlighting reports whether any row is still highlighted
+func (m *Model) highlighting() bool {
+	for _, change := range m.changes {
+		if time.Since(change.at) < fadeDuration {
+			return true
+		}
+	}
+	return false
+}
+
+// handleFadeTick restyles the visible rows of the current table with their
+// faded highlights, forgetting changes that have faded
+func (m *Model) handleFadeTick() {
+	m.fading = false
+	for cidr, change := range m.changes {
+		if time.Since(change.at) >= fadeDuration && !change.owner {
+			delete(m.changes, cidr)
+		}
+	}
+
+	table := &m.unitTables[m.viewing]
+	rows, shadowRows := table.Rows(), m.shadowTables[m.viewing].Rows()
+	for i := max(table.Cursor()-table.Height(), 0); i < min(table.Cursor()+table.Height(), len(shadowRows)); i++ {
+		cidr := shadowRows[i][0]
+		if claim, ok := m.lastClaims[cidr]; ok {
+			m.renderRowClaim(rows[i], cidr, claim)
+		}
+	}
+	table.SetRows(rows)
+}
+
+// styleCell styles the text of a table cell, first cutting it to fit the
+// column, since tables cut cells counting color codes as characters
+func styleCell(style lipgloss.Style, text string, width int) string {
+	width -= styleOverhead(style)
+	if runewidth.StringWidth(text) > width {
+		text = runewidth.Truncate(text, width, ellipsis)
+	}
+	return style.Render(text)
+}
+
+// styleOverhead returns the width tables count for the codes of a style
+func styleOverhead(style lipgloss.Style) int {
+	probe := style.Render("x")
+	return runewidth.StringWidth(probe) - lipgloss.Width(probe)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNoteChange tests which fetched claims are highlighted as changed
+func TestNoteChange(t *testing.T) {
+	const cidr = "2001:db8::/32"
+	testCases := []struct {
+		name    string
+		fetches []api.SubnetResponse
+		changed bool
+		owner   bool
+	}{
+		{"first fetch", []api.SubnetResponse{{Owner: "alice", Percentage: 60}}, false, false},
+		{"unchanged", []api.SubnetResponse{{Owner: "alice", Percentage: 60}, {Owner: "alice", Percentage: 60}}, false, false},
+		{"percentage", []api.SubnetResponse{{Owner: "alice", Percentage: 60}, {Owner: "alice", Percentage: 70}}, true, false},
+		{"owner", []api.SubnetResponse{{Owner: "alice", Percentage: 60}, {Owner: "bob", Percentage: 60}}, true, true},
+		{"owner marked until the next fetch", []api.SubnetResponse{
+			{Owner: "alice", Percentage: 60}, {Owner: "bob", Percentage: 60}, {Owner: "bob", Percentage: 60},
+		}, true, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Model{lastClaims: make(map[string]api.SubnetResponse), changes: make(map[string]rowChange)}
+			for _, fetched := range tc.fetches {
+				m.noteChange(cidr, fetched)
+			}
+			change, changed := m.changes[cidr]
+			assert.Equal(t, tc.changed, changed)
+			assert.Equal(t, tc.owner, change.owner)
+			assert.Equal(t, tc.changed, !m.lastChange.IsZero(), "Changes should be noted for the refresh back-off")
+			assert.Equal(t, tc.fetches[len(tc.fetches)-1], m.lastClaims[cidr])
+		})
+	}
+}
+
+// TestHighlight tests that changed rows are highlighted until they fade
+func TestHighlight(t *testing.T) {
+	m := &Model{changes: map[string]rowChange{
+		"recent": {at: time.Now()},
+		"faded":  {at: time.Now().Add(-fadeDuration)},
+	}}
+	_, ok := m.highlight("recent")
+	assert.True(t, ok)
+	_, ok = m.highlight("faded")
+	assert.False(t, ok, "Faded changes should not be highlighted")
+	_, ok = m.highlight("unchanged")
+	assert.False(t, ok)
+	assert.True(t, m.highlighting())
+
+	m.plain = true
+	_, ok = m.highlight("recent")
+	assert.False(t, ok, "Plain mode should not highlight")
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/grandcat/zeroconf"
+)
+
+// discoverTimeout is how long the local network is browsed for servers
+const discoverTimeout = 3 * time.Second
+
+// discoveredServer is a server advertising itself on the local network
+type discoveredServer struct {
+	name     string // Game name
+	addr     string
+	httpPort int
+}
+
+// profile returns a profile playing on the server as the current player
+func (s discoveredServer) profile(player string) Profile {
+	return Profile{Name: s.name, Server: s.addr, HTTPPort: s.httpPort, Player: player}
+}
+
+// discoverScreen is the screen listing the servers found on the local network
+type discoverScreen struct {
+	cursor   int                // Index of the selected server
+	servers  []discoveredServer // Servers found by the last browse, by name
+	browsing bool               // Whether the network is being browsed
+	err      error              // Why the last browse failed, if it did
+}
+
+// discoveredMsg carries the servers found by browsing the local network
+type discoveredMsg struct {
+	servers []discoveredServer
+	err     error
+}
+
+// findServers browses the local network for advertised servers, sorted by
+// game name
+func findServers(timeout time.Duration) ([]discoveredServer, error) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to browse the local network: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry)
+	if err := resolver.Browse(ctx, api.DiscoveryService, api.DiscoveryDomain, entries); err != nil {
+		return nil, fmt.Errorf("failed to browse the local network: %w", err)
+	}
+
+	// Entries are closed once the timeout ends the browse
+	var servers []discoveredServer
+	for entry := range entries {
+		addr, ok := serverAddress(entry)
+		if !ok {
+			continue
+		}
+		server := discoveredServer{
+			name:     cmp.Or(api.DiscoveredName(entry.Text), entry.Instance),
+			addr:     addr,
+			httpPort: entry.Port,
+		}
+		if !slices.Contains(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+	slices.SortStableFunc(servers, func(a, b discoveredServer) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return servers, nil
+}
+
+// serverAddress picks the address to reach an advertised server at,
+// preferring IPv6. Link-local addresses are skipped, since the interface
+// they are reached through is not advertised.
+func serverAddress(entry *zeroconf.ServiceEntry) (string, bool) {
+	for _, addrs := range [][]net.IP{entry.AddrIPv6, entry.AddrIPv4} {
+		for _, ip := range addrs {
+			if !ip.IsLinkLocalUnicast() {
+				return ip.String(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// discoverServers browses the local network in the background
+func discoverServers() tea.Cmd {
+	return func() tea.Msg {
+		servers, err := findServers(discoverTimeout)
+		return discoveredMsg{servers: servers, err: err}
+	}
+}
+
+// openDiscover shows the discover screen and browses the local network
+func (m *Model) openDiscover() tea.Cmd {
+	m.discover = &discoverScreen{browsing: true}
+	return discoverServers()
+}
+
+// handleDiscovered lists the servers found, keeping the cursor on the server
+// it was on if it is still advertised
+func (m *Model) handleDiscovered(msg discoveredMsg) {
+	if m.discover == nil {
+		return // Closed while browsing
+	}
+	d := m.discover
+	var selected discoveredServer
+	if d.cursor < len(d.servers) {
+		selected = d.servers[d.cursor]
+	}
+	d.servers, d.err, d.browsing = msg.servers, msg.err, false
+	d.cursor = max(slices.Index(d.servers, selected), 0)
+}
+
+// handleDiscoverKey moves through the servers found, switching to the
+// selected one on select, browsing again on discover and closing the screen
+// on back
+func (m *Model) handleDiscoverKey(msg tea.KeyMsg) tea.Cmd {
+	d := m.discover
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		d.cursor = max(d.cursor-1, 0)
+	case key.Matches(msg, m.keys.Down):
+		d.cursor = max(min(d.cursor+1, len(d.servers)-1), 0)
+	case key.Matches(msg, m.keys.Back):
+		m.discover = nil
+	case key.Matches(msg, m.keys.Discover):
+		if !d.browsing {
+			d.browsing = true
+			return discoverServers()
+		}
+	case key.Matches(msg, m.keys.Select):
+		if d.cursor >= len(d.servers) {
+			break
+		}
+		s := d.servers[d.cursor]
+		m.discover = nil
+		if err := m.SwitchServer(s.profile(m.name)); err != nil {
+			m.errorMessage = errorMessageStyle.Render(fmt.Sprintf("Failed to switch to %s: %v", s.name, err))
+			return nil
+		}
+		m.statusMessage = statusMessageStyle.Render(fmt.Sprintf("Playing on %s as %s", s.name, m.name))
+	}
+	return nil
+}
+
+// discoverView renders the servers found on the local network, marking the
+// current one
+func (m *Model) discoverView() string {
+	d := m.discover
+	var b strings.Builder
+	b.WriteString("Servers on the local network\n\n")
+	switch {
+	case d.browsing:
+		b.WriteString("Searching" + ellipsis + "\n")
+	case d.err != nil:
+		b.WriteString(errorMessageStyle.Render(d.err.Error()) + "\n")
+	case len(d.servers) == 0:
+		b.WriteString("No servers found\n")
+	}
+	for i, s := range d.servers {
+		marker := "  "
+		if m.isCurrent(s.profile(m.name)) {
+			marker = "* "
+		}
+		line := fmt.Sprintf("%s%-24s %s", marker, s.name, net.JoinHostPort(s.addr, strconv.Itoa(s.httpPort)))
+		if i == d.cursor {
+			line = tableStyles.Selected.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n" + helpStyle(fmt.Sprintf("%s: connect, %s: search again, %s: cancel",
+		keyName(m.keys.Select), keyName(m.keys.Discover), keyName(m.keys.Back))))
+	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+}
+
+// discoverCommand shows the servers on the local network
+func (m *Model) discoverCommand(args []string) (tea.Cmd, error) {
+	return m.openDiscover(), nil
+}
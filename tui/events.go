@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Backoff bounds for reconnecting to the event feed
+const (
+	minEventReconnectDelay = time.Second
+	maxEventReconnectDelay = 30 * time.Second
+)
+
+// claimEventMsg delivers a claim event from the server's event feed
+type claimEventMsg api.ClaimEvent
+
+// waitForEvent waits for the next event from the event feed
+func waitForEvent(events <-chan api.ClaimEvent) tea.Cmd {
+	return func() tea.Msg {
+		return claimEventMsg(<-events)
+	}
+}
+
+// startEvents follows the event feed and viewport subscriptions of the
+// current server, stopping those of any previous server
+func (m *Model) startEvents() {
+	if m.stopEvents != nil {
+		m.stopEvents()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopEvents = cancel
+	m.viewportLive = false
+	go streamEvents(ctx, m.serverHost(), m.events)
+	go followViewports(ctx, m.serverHost(), m.viewports)
+}
+
+// streamEvents follows a server's event feed, forwarding events to the channel
+// and reconnecting with exponential backoff whenever the stream drops, until
+// the context is done
+func streamEvents(ctx context.Context, host string, events chan<- api.ClaimEvent) {
+	delay := minEventReconnectDelay
+	for {
+		connected, err := readEventStream(ctx, host, events)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minEventReconnectDelay
+		}
+		log.Printf("Event stream disconnected: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxEventReconnectDelay)
+	}
+}
+
+// readEventStream reads server-sent events until the connection ends, reporting
+// whether the connection was established
+func readEventStream(ctx context.Context, host string, events chan<- api.ClaimEvent) (bool, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/events", host)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("server returned status: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event api.ClaimEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			log.Printf("Error decoding event: %v", err)
+			continue
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, fmt.Errorf("stream closed by server")
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxConcurrentFetches is the most claim requests sent to the server at once
+const maxConcurrentFetches = 4
+
+// rowSpan is a range of rows of a table, from start up to end
+type rowSpan struct {
+	start, end int
+}
+
+// minus returns the parts of a span outside another
+func (s rowSpan) minus(o rowSpan) []rowSpan {
+	if o.end <= s.start || o.start >= s.end {
+		return []rowSpan{s}
+	}
+	var parts []rowSpan
+	if s.start < o.start {
+		parts = append(parts, rowSpan{s.start, o.start})
+	}
+	if o.end < s.end {
+		parts = append(parts, rowSpan{o.end, s.end})
+	}
+	return parts
+}
+
+// claimsFetch is a range of rows whose claims are being fetched
+type claimsFetch struct {
+	id     int
+	lvl    level
+	span   rowSpan
+	cancel context.CancelFunc
+}
+
+// claimsFetchedMsg delivers the claims fetched for a range of rows, only
+// those fetched before it was cancelled if it was
+type claimsFetchedMsg struct {
+	id     int
+	lvl    level
+	span   rowSpan
+	claims map[string]api.SubnetResponse // By subnet
+	err    error                         // First failure, if any
+}
+
+// fetchVisible fetches the claims of the rows around the cursor in the
+// background. Rows already being fetched are left to their fetch, and
+// fetches of rows scrolled away from are cancelled. While the server pushes
+// claims, the rows of unfiltered tables are watched instead.
+func (m *Model) fetchVisible() tea.Cmd {
+	table := m.unitTables[m.viewing]
+	want := rowSpan{max(table.Cursor()-table.Height(), 0), min(table.Cursor()+table.Height(), len(table.Rows()))}
+	m.materializeRows(m.viewing, want.start, want.end)
+	tracked := m.tracked[subnetMappings[m.viewing]]
+	if m.viewportLive {
+		if tracked && !m.views[m.viewing].active() {
+			m.watchRows(m.viewing, want)
+			return nil
+		}
+		m.unwatchRows() // Filtered rows are not consecutive subnets
+	}
+	if !tracked {
+		return nil
+	}
+
+	pending := []rowSpan{want}
+	kept := m.fetches[:0]
+	for _, f := range m.fetches {
+		if f.lvl != m.viewing || f.span.end <= want.start || f.span.start >= want.end {
+			f.cancel() // Superseded
+			continue
+		}
+		kept = append(kept, f)
+		var rest []rowSpan
+		for _, s := range pending {
+			rest = append(rest, s.minus(f.span)...)
+		}
+		pending = rest
+	}
+	m.fetches = kept
+
+	var cmds []tea.Cmd
+	for _, s := range pending {
+		if s.end > s.start {
+			cmds = append(cmds, m.startFetch(m.viewing, s))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// startFetch fetches the claims of a range of rows of a level's table, a few
+// requests at a time across every fetch
+func (m *Model) startFetch(lvl level, span rowSpan) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.fetchID++
+	f := &claimsFetch{id: m.fetchID, lvl: lvl, span: span, cancel: cancel}
+	m.fetches = append(m.fetches, f)
+
+	shadowRows := m.shadowTables[lvl].Rows()
+	cidrs := make([]string, 0, span.end-span.start)
+	for i := span.start; i < span.end; i++ {
+		cidrs = append(cidrs, shadowRows[i][0])
+	}
+	client, host, slots := m.client, m.serverHost(), m.fetchSlots
+
+	return func() tea.Msg {
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+		msg := claimsFetchedMsg{id: f.id, lvl: lvl, span: span, claims: make(map[string]api.SubnetResponse)}
+	requests:
+		for _, cidr := range cidrs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				break requests
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				subnetResp, err := fetchSubnetClaim(ctx, client, host, cidr)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				if err != nil {
+					if msg.err == nil {
+						msg.err = err
+					}
+					return
+				}
+				msg.claims[cidr] = *subnetResp
+			}()
+		}
+		wg.Wait()
+		return msg
+	}
+}
+
+// handleClaimsFetched fills in the claims fetched for a range of rows, if
+// the table still lists the same subnets in them
+func (m *Model) handleClaimsFetched(msg claimsFetchedMsg) {
+	for i, f := range m.fetches {
+		if f.id == msg.id {
+			f.cancel()
+			m.fetches = append(m.fetches[:i], m.fetches[i+1:]...)
+			break
+		}
+	}
+	if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+		log.Printf("Error fetching claims: %v", msg.err)
+	}
+
+	rows, shadowRows := m.unitTables[msg.lvl].Rows(), m.shadowTables[msg.lvl].Rows()
+	for i := msg.span.start; i < min(msg.span.end, len(shadowRows)); i++ {
+		cidr := shadowRows[i][0]
+		if subnetResp, ok := msg.claims[cidr]; ok {
+			m.setRowClaim(rows[i], cidr, subnetResp)
+		}
+	}
+	m.unitTables[msg.lvl].SetRows(rows)
+}
+
+// forgetRows cancels the fetches of a level's table, whose rows are about to
+// change, and has the new rows pushed again if the table is watched
+func (m *Model) forgetRows(lvl level) {
+	m.cancelFetches(lvl)
+	if m.watchLevel == lvl {
+		m.watching.Parent = ""
+	}
+}
+
+// cancelFetches cancels the fetches of a level's table
+func (m *Model) cancelFetches(lvl level) {
+	kept := m.fetches[:0]
+	for _, f := range m.fetches {
+		if f.lvl == lvl {
+			f.cancel()
+			continue
+		}
+		kept = append(kept, f)
+	}
+	m.fetches = kept
+}
+
+// selectionFetch is a request about the selected address being made in the
+// background. It is replaced when the selection moves, dropping its result.
+type selectionFetch struct {
+	id     int
+	ip     string // Address of the latest request, kept once it finishes
+	cancel context.CancelFunc
+}
+
+// fetchSelected starts a request about the selected address in the
+// background, cancelling the one of the same kind being made about another
+// address, or leaving it be if it is about the same one. Requests take one
+// of the slots the claims fetches share, and give up waiting for it if they
+// are cancelled.
+func (m *Model) fetchSelected(f *selectionFetch, ip string, request func(ctx context.Context, id int) tea.Msg) tea.Cmd {
+	if f.cancel != nil && f.ip == ip {
+		return nil
+	}
+	f.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.fetchID++
+	*f = selectionFetch{id: m.fetchID, ip: ip, cancel: cancel}
+
+	id, slots := f.id, m.fetchSlots
+	return func() tea.Msg {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil // Replaced, so nothing is waiting for the result
+		}
+		defer func() { <-slots }()
+		return request(ctx, id)
+	}
+}
+
+// finish reports whether a result is of the request being made, which is
+// then done, rather than of one since replaced
+func (f *selectionFetch) finish(id int) bool {
+	if f.cancel == nil || f.id != id {
+		return false
+	}
+	f.cancel()
+	f.cancel = nil
+	return true
+}
+
+// stop cancels the request being made, if any
+func (f *selectionFetch) stop() {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	*f = selectionFetch{}
+}
+
+// fetchSubnetClaim fetches the claim on a subnet in CIDR notation
+func fetchSubnetClaim(ctx context.Context, client *conditionalClient, host, cidr string) (*api.SubnetResponse, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/subnet/%s", host, cidr)
+	status, body, err := client.GetContext(ctx, serverURL)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s: server returned status: %d", serverURL, status)
+	}
+
+	subnetResp := &api.SubnetResponse{}
+	if err := json.Unmarshal(body, subnetResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return subnetResp, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRowSpan_Minus tests the rows left to fetch around rows already being fetched
+func TestRowSpan_Minus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		span     rowSpan
+		other    rowSpan
+		expected []rowSpan
+	}{
+		{"before", rowSpan{10, 20}, rowSpan{0, 10}, []rowSpan{{10, 20}}},
+		{"after", rowSpan{10, 20}, rowSpan{20, 30}, []rowSpan{{10, 20}}},
+		{"overlapping start", rowSpan{10, 20}, rowSpan{5, 15}, []rowSpan{{15, 20}}},
+		{"overlapping end", rowSpan{10, 20}, rowSpan{15, 25}, []rowSpan{{10, 15}}},
+		{"inside", rowSpan{10, 20}, rowSpan{12, 18}, []rowSpan{{10, 12}, {18, 20}}},
+		{"covering", rowSpan{10, 20}, rowSpan{0, 30}, nil},
+		{"equal", rowSpan{10, 20}, rowSpan{10, 20}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.span.minus(tc.other))
+		})
+	}
+}
+
+// TestCancelFetches tests that only the fetches of a level's table are cancelled
+func TestCancelFetches(t *testing.T) {
+	fetch := func(lvl level) (*claimsFetch, context.Context) {
+		ctx, cancel := context.WithCancel(t.Context())
+		return &claimsFetch{lvl: lvl, cancel: cancel}, ctx
+	}
+	kept, keptCtx := fetch(t32)
+	dropped, droppedCtx := fetch(t48)
+	m := &Model{fetches: []*claimsFetch{kept, dropped}}
+
+	m.cancelFetches(t48)
+	assert.Equal(t, []*claimsFetch{kept}, m.fetches)
+	assert.NoError(t, keptCtx.Err())
+	assert.ErrorIs(t, droppedCtx.Err(), context.Canceled)
+}
+
+// TestFetchSelected tests that requests about the selected address are
+// coalesced, replace each other and wait for a free slot, and that only the
+// latest one's result is taken
+func TestFetchSelected(t *testing.T) {
+	m := &Model{fetchSlots: make(chan struct{}, 1)}
+	var f selectionFetch
+	request := func(ctx context.Context, id int) tea.Msg { return ctx }
+
+	first := m.fetchSelected(&f, "2001:db8::1", request)
+	require.NotNil(t, first)
+	assert.Nil(t, m.fetchSelected(&f, "2001:db8::1", request), "Requests about the address being fetched should be coalesced")
+	second := m.fetchSelected(&f, "2001:db8::2", request)
+	require.NotNil(t, second)
+
+	m.fetchSlots <- struct{}{} // Every slot is taken
+	assert.Nil(t, first(), "Replaced requests should give up waiting for a slot")
+	<-m.fetchSlots
+	require.NoError(t, second().(context.Context).Err())
+	assert.Empty(t, m.fetchSlots, "Requests should free their slot")
+
+	assert.False(t, f.finish(f.id-1), "Results of replaced requests should be dropped")
+	id := f.id
+	assert.True(t, f.finish(id))
+	assert.False(t, f.finish(id), "Results should only be taken once")
+	assert.Equal(t, "2001:db8::2", f.ip, "The address should be kept once the request finishes")
+	assert.NotNil(t, m.fetchSelected(&f, "2001:db8::2", request), "Finished requests should be made again")
+
+	f.stop()
+	assert.Empty(t, f.ip)
+}
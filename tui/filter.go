@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sortKey orders the rows of a table
+type sortKey int
+
+const (
+	sortByAddress sortKey = iota
+	sortByOwner
+	sortByPercentage
+)
+
+// rowFilter selects the rows of a table
+type rowFilter int
+
+const (
+	showAll rowFilter = iota
+	showClaimed
+	showMine
+	showOwner // Owners containing the filter's text
+)
+
+// tableView is how the rows of a table are sorted and filtered. Sorted or
+// filtered tables list only the claimed subnets of their parent, as fetched
+// from the server's listing of the level, since only those have owners.
+type tableView struct {
+	sort   sortKey
+	filter rowFilter
+	owner  string // Text owners must contain for showOwner, ignoring case
+}
+
+// active reports whether the view changes the table from every subnet in address order
+func (v tableView) active() bool {
+	return v.sort != sortByAddress || v.filter != showAll
+}
+
+// String describes the view for the title
+func (v tableView) String() string {
+	var parts []string
+	switch v.sort {
+	case sortByOwner:
+		parts = append(parts, "by owner")
+	case sortByPercentage:
+		parts = append(parts, "by percentage")
+	}
+	switch v.filter {
+	case showAll, showClaimed:
+		parts = append(parts, "claimed")
+	case showMine:
+		parts = append(parts, "mine")
+	case showOwner:
+		parts = append(parts, fmt.Sprintf("owner ~ %q", v.owner))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matches reports whether a claimed subnet passes the view's filter
+func (v tableView) matches(entry api.SubnetListEntry, name string) bool {
+	switch v.filter {
+	case showMine:
+		return entry.Owner == name
+	case showOwner:
+		return strings.Contains(strings.ToLower(entry.Owner), strings.ToLower(v.owner))
+	}
+	return true
+}
+
+// FetchSubnetList fetches the claimed subnets of a level within the parent of
+// its table, sorted by the server according to a view
+func (m *Model) FetchSubnetList(lvl level, view tableView) ([]api.SubnetListEntry, error) {
+	prefixLen := subnetMappings[lvl]
+	if !m.tracked[prefixLen] {
+		return nil, fmt.Errorf("the server does not track owners of /%d subnets", prefixLen)
+	}
+
+	query := url.Values{}
+	if parent := m.parentSubnet(lvl); parent != nil {
+		query.Set("within", parent.String())
+	}
+	switch view.sort {
+	case sortByOwner:
+		query.Set("sort", "owner")
+	case sortByPercentage:
+		query.Set("sort", "percentage")
+		query.Set("order", "desc")
+	}
+
+	serverURL := fmt.Sprintf("http://%s/api/v1/subnets/%d?%s", m.serverHost(), prefixLen, query.Encode())
+	status, body, err := m.client.Get(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subnets: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %d", status)
+	}
+
+	var entries []api.SubnetListEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode subnets: %v", err)
+	}
+	return entries, nil
+}
+
+// parentSubnet returns the subnet selected in the table above a level, nil at the top
+func (m *Model) parentSubnet(lvl level) *net.IPNet {
+	if lvl == t16 {
+		return nil
+	}
+	m.materializeCursor(lvl - 1)
+	rows := m.shadowTables[lvl-1].Rows()
+	cursor := m.unitTables[lvl-1].Cursor()
+	if cursor < 0 || cursor >= len(rows) {
+		return nil
+	}
+	parent, err := api.ParseSubnet(rows[cursor][0])
+	if err != nil {
+		return nil
+	}
+	return parent
+}
+
+// setView sorts and filters the current table, keeping its previous view if
+// the claimed subnets cannot be fetched
+func (m *Model) setView(view tableView) {
+	previous := m.views[m.viewing]
+	m.views[m.viewing] = view
+	if err := m.applyView(m.viewing); err != nil {
+		m.views[m.viewing] = previous
+		m.errorMessage = errorMessageStyle.Render(err.Error())
+	}
+	m.refreshClaims = true
+}
+
+// applyView fills the table of a level according to its view, keeping the cursor in place
+func (m *Model) applyView(lvl level) error {
+	view := m.views[lvl]
+	if !view.active() {
+		m.PopulateTable(m.GetParentSelection(lvl), lvl)
+		return nil
+	}
+
+	entries, err := m.FetchSubnetList(lvl, view)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]table.Row, 0, len(entries))
+	shadowRows := make([]table.Row, 0, len(entries))
+	for _, entry := range entries {
+		if !view.matches(entry, m.name) {
+			continue
+		}
+		row := table.Row{"", "", ""}
+		m.setRowClaim(row, entry.Subnet, api.SubnetResponse{
+			Owner:      entry.Owner,
+			Percentage: entry.Percentage,
+			Artifact:   entry.Artifact,
+			Name:       entry.Name,
+		})
+		rows = append(rows, row)
+		shadowRows = append(shadowRows, table.Row{entry.Subnet})
+	}
+
+	m.forgetRows(lvl)
+	cursor := m.unitTables[lvl].Cursor()
+	m.unitTables[lvl].SetRows(rows)
+	m.shadowTables[lvl].SetRows(shadowRows)
+	m.unitTables[lvl].SetCursor(min(max(cursor, 0), max(len(rows)-1, 0)))
+	return nil
+}
+
+// handleViewKey changes the view of the current table, reporting whether the key was a view key
+func (m *Model) handleViewKey(msg tea.KeyMsg) bool {
+	view := m.views[m.viewing]
+	switch {
+	case key.Matches(msg, m.keys.Sort):
+		view.sort = (view.sort + 1) % (sortByPercentage + 1)
+	case key.Matches(msg, m.keys.Claimed):
+		view.filter = toggleFilter(view.filter, showClaimed)
+	case key.Matches(msg, m.keys.Mine):
+		view.filter = toggleFilter(view.filter, showMine)
+	case key.Matches(msg, m.keys.Filter):
+		m.prompt = &prompt{label: "Owner filter: ", input: view.owner, submit: (*Model).setOwnerFilter}
+		return true
+	case key.Matches(msg, m.keys.Clear):
+		view = tableView{}
+	default:
+		return false
+	}
+	m.setView(view)
+	return true
+}
+
+// toggleFilter switches a filter on, or off if it is already on
+func toggleFilter(current, filter rowFilter) rowFilter {
+	if current == filter {
+		return showAll
+	}
+	return filter
+}
+
+// setOwnerFilter shows only subnets whose owners contain the typed text,
+// or every claimed subnet if it is empty
+func (m *Model) setOwnerFilter(owner string) tea.Cmd {
+	view := m.views[m.viewing]
+	view.owner = owner
+	view.filter = showOwner
+	if owner == "" {
+		view.filter = showAll
+	}
+	m.setView(view)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTableView_String tests the titles of sorted and filtered tables
+func TestTableView_String(t *testing.T) {
+	testCases := []struct {
+		name     string
+		view     tableView
+		active   bool
+		expected string
+	}{
+		{"unsorted", tableView{}, false, "claimed"},
+		{"by owner", tableView{sort: sortByOwner}, true, "by owner, claimed"},
+		{"by percentage", tableView{sort: sortByPercentage, filter: showClaimed}, true, "by percentage, claimed"},
+		{"mine", tableView{filter: showMine}, true, "mine"},
+		{"owner", tableView{sort: sortByOwner, filter: showOwner, owner: "al"}, true, `by owner, owner ~ "al"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.active, tc.view.active())
+			assert.Equal(t, tc.expected, tc.view.String())
+		})
+	}
+}
+
+// TestTableView_Matches tests which claimed subnets pass each filter
+func TestTableView_Matches(t *testing.T) {
+	entry := api.SubnetListEntry{Subnet: "2001:db8::/32", Owner: "Alice"}
+	testCases := []struct {
+		name     string
+		view     tableView
+		player   string
+		expected bool
+	}{
+		{"all", tableView{}, "bob", true},
+		{"claimed", tableView{filter: showClaimed}, "bob", true},
+		{"mine", tableView{filter: showMine}, "Alice", true},
+		{"not mine", tableView{filter: showMine}, "bob", false},
+		{"mine is exact", tableView{filter: showMine}, "alice", false},
+		{"owner ignores case", tableView{filter: showOwner, owner: "LIC"}, "bob", true},
+		{"other owner", tableView{filter: showOwner, owner: "bob"}, "bob", false},
+		{"empty owner", tableView{filter: showOwner}, "bob", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.view.matches(entry, tc.player))
+		})
+	}
+}
+
+// TestToggleFilter tests that filters toggle on and off
+func TestToggleFilter(t *testing.T) {
+	assert.Equal(t, showClaimed, toggleFilter(showAll, showClaimed))
+	assert.Equal(t, showAll, toggleFilter(showClaimed, showClaimed))
+	assert.Equal(t, showMine, toggleFilter(showClaimed, showMine), "Filters should replace each other")
+	assert.Equal(t, showMine, toggleFilter(showOwner, showMine))
+}
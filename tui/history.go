@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// historyPanelWidth is the width of the claim history panel beside the
+// table, including its border
+const historyPanelWidth = 40
+
+// maxHistoryName is the most characters of a claimant shown in the panel
+const maxHistoryName = 16
+
+// errNoHistory is returned by servers that keep no claim history
+var errNoHistory = errors.New("the server keeps no claim history")
+
+// historyMsg delivers the claim history fetched for the selected address
+type historyMsg struct {
+	id      int
+	history *api.ClaimHistoryResponse
+	err     error
+}
+
+// fetchClaimHistory fetches the recent claims on an address, newest first
+func fetchClaimHistory(ctx context.Context, client *conditionalClient, host, ip string) (*api.ClaimHistoryResponse, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/ip/%s/history", host, ip)
+	status, body, err := client.GetContext(ctx, serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch claim history: %v", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, errNoHistory
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %d", status)
+	}
+
+	var historyResp api.ClaimHistoryResponse
+	if err := json.Unmarshal(body, &historyResp); err != nil {
+		return nil, fmt.Errorf("failed to decode claim history: %v", err)
+	}
+	return &historyResp, nil
+}
+
+// toggleHistory shows or hides the claim history panel
+func (m *Model) toggleHistory() {
+	m.showHistory = !m.showHistory
+	m.history, m.historyErr = nil, nil
+	m.historyFetch.stop()
+	m.resizeTables()
+	m.refreshClaims = true
+}
+
+// refreshHistory fetches the history of the selected address in the
+// background while the panel is shown, clearing it above the last level.
+// The history shown stays while the same address is fetched again.
+func (m *Model) refreshHistory(ip string) tea.Cmd {
+	if !m.showHistory {
+		ip = ""
+	}
+	if ip != m.historyFetch.ip {
+		m.history, m.historyErr = nil, nil
+	}
+	if ip == "" {
+		m.historyFetch.stop()
+		return nil
+	}
+
+	client, host := m.client, m.serverHost()
+	return m.fetchSelected(&m.historyFetch, ip, func(ctx context.Context, id int) tea.Msg {
+		history, err := fetchClaimHistory(ctx, client, host, ip)
+		return historyMsg{id: id, history: history, err: err}
+	})
+}
+
+// handleHistory shows the history fetched for the selected address, unless
+// the selection has moved on since
+func (m *Model) handleHistory(msg historyMsg) {
+	if m.historyFetch.finish(msg.id) {
+		m.history, m.historyErr = msg.history, msg.err
+	}
+}
+
+// historyView renders the claims on the selected address beside the table,
+// as tall as the table
+func (m *Model) historyView(height int) string {
+	var b strings.Builder
+	switch {
+	case m.historyErr != nil:
+		b.WriteString(errorMessageStyle.Render(m.historyErr.Error()))
+	case m.history == nil:
+		b.WriteString(helpStyle("Open a subnet of the last level to see the claims on its address"))
+	default:
+		m.writeHistory(&b)
+	}
+	return tableStyle.Width(historyPanelWidth-2).Height(height-2).MaxHeight(height).Padding(0, 1).Render(b.String())
+}
+
+// writeHistory lists the claims on an address with how contested it is
+func (m *Model) writeHistory(b *strings.Builder) {
+	h := m.history
+	takeovers := 0
+	for _, entry := range h.Claims {
+		if entry.PreviousClaimant != "" {
+			takeovers++
+		}
+	}
+	more := ""
+	if h.Truncated {
+		more = "+"
+	}
+	fmt.Fprintf(b, "History of %s\n", h.IP)
+	b.WriteString(helpStyle(fmt.Sprintf("Claims: %d%s, takeovers: %d%s", len(h.Claims), more, takeovers, more)) + "\n\n")
+	if len(h.Claims) == 0 {
+		b.WriteString("Never claimed recently\n")
+		return
+	}
+
+	for _, entry := range h.Claims {
+		name := entry.Claimant
+		if runes := []rune(name); len(runes) > maxHistoryName {
+			name = string(runes[:maxHistoryName-1]) + ellipsis
+		}
+		age := time.Since(entry.ClaimedAt).Truncate(time.Second).String() + " ago"
+		fmt.Fprintf(b, "%-*s %10s ", maxHistoryName, name, age)
+		if entry.Difficulty > 0 {
+			fmt.Fprintf(b, "d%d", entry.Difficulty)
+		}
+		b.WriteString("\n")
+		if entry.PreviousClaimant != "" {
+			b.WriteString(helpStyle("  from "+entry.PreviousClaimant) + "\n")
+		}
+	}
+}
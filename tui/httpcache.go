@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxCachedResponses bounds the conditional request cache, about two screens of every table level
+const maxCachedResponses = 4096
+
+// cachedResponse is a response body and the ETag it was served with
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// conditionalClient sends GET requests with If-None-Match and reuses the cached
+// body when the server answers 304 Not Modified. Requests failing for
+// transient reasons are retried while the server was last reachable.
+type conditionalClient struct {
+	client *http.Client
+	mutex  sync.Mutex
+	cache  map[string]cachedResponse
+	state  connState // Guarded by mutex
+}
+
+// newConditionalClient creates a client with an empty cache
+func newConditionalClient() *conditionalClient {
+	return &conditionalClient{
+		client: httpClient,
+		cache:  make(map[string]cachedResponse),
+	}
+}
+
+// connState returns the state of the connection to the server
+func (c *conditionalClient) connState() connState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.state
+}
+
+// setConnState records the state of the connection to the server
+func (c *conditionalClient) setConnState(state connState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.state = state
+}
+
+// Get fetches a URL, returning the status code and body. Not modified responses
+// are reported as 200 with the cached body.
+func (c *conditionalClient) Get(url string) (int, []byte, error) {
+	return c.GetContext(context.Background(), url)
+}
+
+// GetContext is Get, giving up once the context is cancelled
+func (c *conditionalClient) GetContext(ctx context.Context, url string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.mutex.Lock()
+	cached, exists := c.cache[url]
+	c.mutex.Unlock()
+	if exists {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified && exists {
+		return http.StatusOK, cached.body, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if etag := resp.Header.Get("ETag"); resp.StatusCode == http.StatusOK && etag != "" {
+		c.mutex.Lock()
+		if len(c.cache) >= maxCachedResponses {
+			c.cache = make(map[string]cachedResponse) // Start over rather than track recency
+		}
+		c.cache[url] = cachedResponse{etag: etag, body: body}
+		c.mutex.Unlock()
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// do sends a request, retrying with exponential backoff while it fails for
+// transient reasons. Once the server is down, requests fail without retrying
+// until one gets through, so the TUI does not stall on every fetch.
+func (c *conditionalClient) do(req *http.Request) (*http.Response, error) {
+	retries := maxRetries
+	if c.connState() == connDown {
+		retries = 0
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		if !transient(status, err) {
+			if err == nil {
+				c.setConnState(connOK)
+			}
+			return resp, err
+		}
+		if attempt == retries {
+			c.setConnState(connDown)
+			return resp, err
+		}
+
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		c.setConnState(connRetrying)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		delay *= 2
+	}
+}
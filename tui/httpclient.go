@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Timeouts of requests to the server
+const (
+	requestTimeout = 15 * time.Second // Whole requests, including bodies such as subnet listings
+	dialTimeout    = 5 * time.Second
+)
+
+// Retries of requests failing for transient reasons, such as a server restarting
+const (
+	maxRetries = 3
+	retryDelay = 100 * time.Millisecond // Doubled after every retry
+)
+
+// httpTransport keeps connections to the server alive between requests,
+// shared by every client
+var httpTransport = &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+	MaxIdleConnsPerHost:   16,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   dialTimeout,
+	ExpectContinueTimeout: time.Second,
+}
+
+// httpClient sends requests to the server. Streams such as the event feed,
+// which stay open indefinitely, use streamClient instead.
+var (
+	httpClient   = &http.Client{Transport: userAgentTransport{httpTransport}, Timeout: requestTimeout}
+	streamClient = &http.Client{Transport: userAgentTransport{httpTransport}}
+)
+
+// userAgent identifies the client and its version to the server, so
+// operators can tell the clients claiming apart
+var userAgent = "spacenet-tui/" + clientVersion()
+
+// clientVersion returns the version of the module the client was built
+// from, "dev" if it was built from a checkout
+func clientVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// userAgentTransport sends every request with the client's User-Agent
+type userAgentTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip sends a copy of the request, as transports must not change it
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// connState is the state of the connection to the server, from the outcome
+// of the latest requests
+type connState int
+
+const (
+	connUnknown  connState = iota // No request has finished yet
+	connOK                        // The latest request reached the server
+	connRetrying                  // A request is being retried after a transient failure
+	connDown                      // The latest request failed after its retries
+)
+
+// transient reports whether a request failing with an error or status is
+// worth retrying
+func transient(status int, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) // Failed to reach the server, rather than abandoned
+	}
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// connectionView renders the state of the connection to the server for the
+// header, nothing before the first request finishes
+func (m *Model) connectionView() string {
+	switch m.client.connState() {
+	case connOK:
+		return statusMessageStyle.Render(connMarker + " online")
+	case connRetrying:
+		return alertMessageStyle.Render(connMarker + " retrying")
+	case connDown:
+		return errorMessageStyle.Render(connMarker + " offline")
+	}
+	return ""
+}
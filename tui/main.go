@@ -1,17 +1,25 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bjia56/spacenet/server/api"
+	"github.com/bjia56/spacenet/server/demo"
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -21,9 +29,11 @@ import (
 var (
 	titleStyle         = lipgloss.NewStyle().MarginLeft(2).Bold(true)
 	statusMessageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#04B575"))
+	alertMessageStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Bold(true)
 	errorMessageStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
 	tableStyle         = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
 	helpStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render
+	tableStyles        = table.DefaultStyles()
 )
 
 // Tables
@@ -55,6 +65,7 @@ func (ut *UnitTables) Initialize() {
 			table.WithRows([]table.Row{}),
 			table.WithFocused(true),
 			table.WithHeight(10),
+			table.WithStyles(tableStyles),
 		)
 	}
 }
@@ -69,8 +80,8 @@ func (ut *UnitTables) SetWidth(width int) {
 	for i := range ut {
 		ut[i].SetWidth(width)
 		columns := ut[i].Columns()
-		columns[0].Width = (width * 5) / 10
-		columns[1].Width = (width * 3) / 10
+		columns[0].Width = (width * 45) / 100
+		columns[1].Width = (width * 25) / 100
 		columns[2].Width = width - (columns[0].Width + columns[1].Width) - 6
 		ut[i].SetColumns(columns)
 	}
@@ -88,20 +99,89 @@ var subnetMappings = [8]int{
 	t128: 128,
 }
 
+// Markers, replaced by ASCII ones in the ascii theme
+var (
+	changedOwnerMarker = "» " // Prefixes owners that changed since the previous refresh
+	artifactMarker     = "◆ " // Prefixes owners of subnets containing an artifact address
+	crumbSeparator     = " › "
+	ellipsis           = "…"
+	inputCursor        = "█"
+	connMarker         = "●" // Precedes the state of the connection to the server
+)
+
+// maxCrumbLength is the most characters of a name shown in the breadcrumbs
+const maxCrumbLength = 24
+
 // Model represents the state of our application
 type Model struct {
 	serverAddr string
 	httpPort   int
 	name       string
+	keys       keyMap
+	client     *conditionalClient // Reuses unchanged responses between refreshes
+	locale     *names.Locale      // Language of subnet names
+	names      *nameCache         // Generated names of subnets
+	baseLocale *names.Locale      // Language chosen by the player, for servers without a name pack
+	profiles   []Profile          // Servers to switch between
+	statePath  string             // File sessions are saved to, none if empty
+
+	spectate        bool                          // Read-only mode with periodic refresh
+	autoRefresh     bool                          // Refresh the visible claims periodically when not spectating
+	refreshInterval time.Duration                 // Shortest interval between periodic refreshes
+	maxRefresh      time.Duration                 // Longest interval refreshes back off to while nothing changes
+	refreshDelay    time.Duration                 // Interval until the next periodic refresh
+	refreshGen      int                           // Generation of the pending refresh tick
+	lastRefresh     time.Time                     // When the visible claims were last refreshed periodically
+	lastChange      time.Time                     // When a fetched claim last changed
+	lastClaims      map[string]api.SubnetResponse // Claims seen at the previous refresh, by subnet
+	changes         map[string]rowChange          // When claims last changed between refreshes, by subnet
+	fading          bool                          // Whether a fade tick is pending
+
+	events        chan api.ClaimEvent // Claim events from the server's event feed
+	stopEvents    func()              // Stops following the event feed
+	bell          bool                // Ring the terminal bell on takeover alerts
+	alertMessage  string              // Takeover alert shown until dismissed
+	contestedAddr string              // Address from the latest takeover alert
+	ticker        activityTicker      // Latest takeovers scrolling across the footer
 
 	unitTables    UnitTables // Tables for displaying subnets with fun names
 	shadowTables  UnitTables // For shadowing the current table with actual IPv6 addresses
+	rowPrefixes   [8]string  // Parent selections the unfiltered tables were populated under
 	selections    [8]string  // Selected subnets for each table level
 	viewing       level
-	refreshClaims bool // Whether to refresh claims on the next update
+	depth         level              // Table of the server's last level, where enter claims
+	tracked       map[int]bool       // Prefix lengths the server tracks owners of
+	refreshClaims bool               // Whether to refresh claims on the next update
+	views         [8]tableView       // How each table's rows are sorted and filtered
+	prompt        *prompt            // Text being typed, such as a command, if any
+	showHelp      bool               // Whether the help overlay replaces the table
+	switcher      *serverSwitcher    // Server switcher replacing the table, if open
+	discover      *discoverScreen    // Servers on the local network replacing the table, if open
+	confirmClaims bool               // Whether claims wait for confirmation of their cost
+	showBars      bool               // Whether percentages are drawn as bars in the owners' colors
+	plain         bool               // Whether the browser prints lines of text instead of drawing the screen
+	confirming    *claimEstimate     // Claim waiting for confirmation, if any
+	claims        *claimPool         // Claims being solved in the background
+	fetches       []*claimsFetch     // Claims being fetched in the background
+	fetchID       int                // ID of the latest claims fetch or subscription
+	fetchSlots    chan struct{}      // Limits the claims requests sent at once
+	hashRates     map[string]float64 // Hash rates measured on this machine, by scheme
+
+	viewports    *viewportFeed            // Subscriptions to the rows whose claims the server pushes
+	viewportLive bool                     // Whether the server is pushing claims
+	watching     api.ViewportSubscription // Rows the server was last asked to push
+	watchLevel   level                    // Table of the watched rows
 
-	statusMessage string
-	errorMessage  string
+	statusMessage  string
+	errorMessage   string
+	claimInfo      string         // How entrenched the selected address is, at the last level
+	claimInfoFetch selectionFetch // Request for claimInfo being made, if any
+
+	width        int                       // Width of the terminal
+	showHistory  bool                      // Whether the claim history panel is shown beside the table
+	history      *api.ClaimHistoryResponse // Claims on the selected address, at the last level
+	historyErr   error                     // Why the claim history could not be fetched, if it could not
+	historyFetch selectionFetch            // Request for the claim history being made, if any
 }
 
 func makeIPv6Full(i int, prefix string, level level) (string, int) {
@@ -118,140 +198,352 @@ func makeIPv6Full(i int, prefix string, level level) (string, int) {
 	return full, subnetMappings[level]
 }
 
+// blockPrefix returns the hex blocks of an address down to a level, such as
+// "2001:0db8:" at the /32 level, as the parent selection of the next level
+func blockPrefix(addr net.IP, lvl level) string {
+	addr = addr.To16()
+	prefix := ""
+	for i := t16; i <= lvl; i++ {
+		prefix += fmt.Sprintf("%02x%02x:", addr[2*i], addr[2*i+1])
+	}
+	return prefix
+}
+
 // Initialize returns an initial model
-func Initialize(serverAddr string, httpPort int, name string) *Model {
+func Initialize(serverAddr string, httpPort int, name string, locale *names.Locale, spectate bool, refreshInterval time.Duration, bell bool, keys keyMap) *Model {
 	m := &Model{
-		serverAddr:    serverAddr,
-		httpPort:      httpPort,
-		name:          name,
-		refreshClaims: true,
+		serverAddr:      serverAddr,
+		httpPort:        httpPort,
+		name:            name,
+		keys:            keys,
+		client:          newConditionalClient(),
+		locale:          locale,
+		baseLocale:      locale,
+		names:           newNameCache(nameCacheSize),
+		claims:          newClaimPool(claimWorkers),
+		fetchSlots:      make(chan struct{}, maxConcurrentFetches),
+		viewports:       newViewportFeed(),
+		spectate:        spectate,
+		refreshInterval: refreshInterval,
+		events:          make(chan api.ClaimEvent),
+		bell:            bell,
+		refreshDelay:    refreshInterval,
+		confirmClaims:   true,
+		showBars:        true,
+		hashRates:       make(map[string]float64),
+		refreshClaims:   true,
 	}
+	m.setLevels(subnetMappings[:])
 	m.unitTables.Initialize()
 	m.shadowTables.Initialize()
+	for i := range m.unitTables {
+		m.unitTables[i].KeyMap.LineUp = keys.Up
+		m.unitTables[i].KeyMap.LineDown = keys.Down
+	}
 	m.PopulateTable("", t16)
 	return m
 }
 
-// SendClaim sends a proof of work claim for an IP via HTTP API
-func (m *Model) SendClaim(ip string) (string, error) {
-	// Parse the IP to ensure it's valid
-	targetIP := net.ParseIP(ip)
-	if targetIP == nil {
-		return "", fmt.Errorf("invalid IP address: %s", ip)
+// serverHost returns the host:port of the server's HTTP API
+func (m *Model) serverHost() string {
+	return net.JoinHostPort(m.serverAddr, strconv.Itoa(m.httpPort))
+}
+
+// setLevels builds the table hierarchy for the server's levels. Every table
+// lists the 2^16 subnets of its parent, so the tables step down from /16 to
+// the last level, and tables of levels the server does not track only
+// navigate without showing owners.
+func (m *Model) setLevels(levels []int) {
+	m.tracked = make(map[int]bool, len(levels))
+	for _, prefixLen := range levels {
+		m.tracked[prefixLen] = true
 	}
+	m.depth = level(levels[len(levels)-1]/16 - 1)
+}
 
-	// Solve proof of work (limit to 10 million attempts)
-	pow, err := api.SolveProofOfWork(targetIP, m.name, 20, 10000000)
+// FetchConfig fetches the server's levels and adapts the tables to them.
+// Servers without a configuration use the standard levels.
+func (m *Model) FetchConfig() error {
+	levels, locale, err := m.fetchGameConfig(m.locale)
 	if err != nil {
-		return "", fmt.Errorf("failed to solve proof of work: %v", err)
+		return err
 	}
-
-	// Create claim request
-	claimReq := api.ClaimRequest{
-		Nonce: pow.Nonce,
-		Name:  pow.Name,
+	m.setLevels(levels)
+	if locale != m.locale {
+		m.locale = locale
+		m.PopulateTable("", t16)
 	}
+	return nil
+}
 
-	// Marshal to JSON
-	data, err := json.Marshal(claimReq)
+// fetchGameConfig fetches the server's levels, and its name pack unless the
+// pack is the locale already in use
+func (m *Model) fetchGameConfig(locale *names.Locale) ([]int, *names.Locale, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/config", m.serverHost())
+	status, body, err := m.client.Get(serverURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return nil, nil, fmt.Errorf("failed to fetch config: %v", err)
+	}
+	if status == http.StatusNotFound {
+		return subnetMappings[:], locale, nil
+	}
+	if status != http.StatusOK {
+		return nil, nil, fmt.Errorf("server returned status: %d", status)
 	}
 
-	// Send HTTP POST request to server
-	serverURL := fmt.Sprintf("http://%s:%d/api/claim/%s", m.serverAddr, m.httpPort, ip)
+	var config api.GameConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode config: %v", err)
+	}
+	if len(config.Levels) == 0 {
+		return nil, nil, fmt.Errorf("server has no levels")
+	}
+	for _, prefixLen := range config.Levels {
+		if prefixLen%16 != 0 || prefixLen < 16 || prefixLen > 128 {
+			return nil, nil, fmt.Errorf("server level /%d is not browsable", prefixLen)
+		}
+	}
 
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", serverURL, strings.NewReader(string(data)))
+	// Names must match the server's for names to resolve and other players to recognize them
+	if config.NamePack != "" && config.NamePack != locale.PackID() {
+		pack, err := m.fetchNamePack(config.NamePack)
+		if err != nil {
+			return nil, nil, err
+		}
+		locale = pack
+	}
+	return config.Levels, locale, nil
+}
+
+// fetchNamePack downloads the server's name pack, checking it is the version
+// the server's config named
+func (m *Model) fetchNamePack(id string) (*names.Locale, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/name-pack", m.serverHost())
+	status, body, err := m.client.Get(serverURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to fetch name pack: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %d", status)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	pack, err := names.LoadPack(body)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("Error closing response body: %v", err)
-		}
-	}()
+	if pack.PackID() != id {
+		return nil, fmt.Errorf("server sent name pack %s instead of %s", pack.PackID(), id)
+	}
+	return pack, nil
+}
+
+// FetchChallenge fetches the proof of work scheme and difficulty required to claim an IP
+func (m *Model) FetchChallenge(ip string) (*api.PoWChallenge, error) {
+	return fetchChallenge(m.client, m.serverHost(), ip)
+}
+
+// fetchChallenge fetches the proof of work challenge of an address from a server
+func fetchChallenge(client *conditionalClient, host, ip string) (*api.PoWChallenge, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/challenge/%s", host, ip)
+	status, body, err := client.Get(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch challenge: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %d", status)
+	}
+
+	var challenge api.PoWChallenge
+	if err := json.Unmarshal(body, &challenge); err != nil {
+		return nil, fmt.Errorf("failed to decode challenge: %v", err)
+	}
+	return &challenge, nil
+}
 
-	// Check response status
-	if resp.StatusCode == http.StatusCreated {
-		return "Claim sent!", nil
-	} else {
-		return "", fmt.Errorf("server returned status: %d", resp.StatusCode)
+// SendClaim sends a proof of work claim for an IP via HTTP API, waiting for
+// the proof of work to be solved
+func (m *Model) SendClaim(ip string) (string, error) {
+	return m.sender().send(context.Background(), ip)
+}
+
+// formatHashRate formats a proof of work hash rate with a metric prefix
+func formatHashRate(rate float64) string {
+	switch {
+	case rate >= 1e9:
+		return fmt.Sprintf("%.2f GH/s", rate/1e9)
+	case rate >= 1e6:
+		return fmt.Sprintf("%.2f MH/s", rate/1e6)
+	case rate >= 1e3:
+		return fmt.Sprintf("%.2f kH/s", rate/1e3)
 	}
+	return fmt.Sprintf("%.0f H/s", rate)
 }
 
-// PopulateTable populates a table with 2^16 rows
+// PopulateTable populates a table with 2^16 rows, left blank until they come
+// into view
 func (m *Model) PopulateTable(prefix string, level level) {
-	rows := make([]table.Row, 0, 1<<16)
-	shadowRows := make([]table.Row, 0, 1<<16)
-	for i := range 1 << 16 {
-		addr, subnet := makeIPv6Full(i, prefix, level)
-		name, err := GenerateName(addr, subnet)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to generate name for %s: %v", addr, err))
-		}
-		rows = append(rows, table.Row{
-			name,
-			"", // Placeholder for owner,
-			"", // Placeholder for percentage
-		})
-		shadowRows = append(shadowRows, table.Row{
-			fmt.Sprintf("%s/%d", addr, subnet),
-		})
+	rows := make([]table.Row, 1<<16)
+	shadowRows := make([]table.Row, 1<<16)
+	cells := make([]string, 4<<16) // Name, owner and percentage, then subnet
+	for i := range rows {
+		rows[i] = cells[4*i : 4*i+3 : 4*i+3]
+		shadowRows[i] = cells[4*i+3 : 4*i+4 : 4*i+4]
 	}
+	m.forgetRows(level)
+	m.rowPrefixes[level] = prefix
 	m.unitTables[level].SetRows(rows)
 	m.shadowTables[level].SetRows(shadowRows)
+	m.views[level] = tableView{}
+	m.lastClaims = make(map[string]api.SubnetResponse)
+	m.changes = make(map[string]rowChange)
 }
 
-// FetchClaims fetches claims for a range of subnets
-func (m *Model) FetchClaims(prefix string, level level, start, end int) {
-	for i := max(start, 0); i < min(end, 1<<16); i++ {
-		addr, subnet := makeIPv6Full(i, prefix, level)
-		serverUrl := fmt.Sprintf("http://%s:%d/api/subnet/%s/%d", m.serverAddr, m.httpPort, addr, subnet)
-
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", serverUrl, nil)
-		if err != nil {
-			log.Printf("Error creating request: %v", err)
-			return
-		}
-		resp, err := client.Do(req)
+// FetchClaims fetches claims for a range of subnets in the table of a level,
+// waiting for them. The browser fetches them in the background instead.
+func (m *Model) FetchClaims(level level, start, end int) {
+	m.materializeRows(level, start, end)
+	if !m.tracked[subnetMappings[level]] {
+		return
+	}
+	for i := max(start, 0); i < min(end, len(m.shadowTables[level].Rows())); i++ {
+		cidr := m.shadowTables[level].Rows()[i][0]
+		subnetResp, err := fetchSubnetClaim(context.Background(), m.client, m.serverHost(), cidr)
 		if err != nil {
 			log.Printf("Error fetching claims: %v", err)
 			return
 		}
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				log.Printf("Error closing response body: %v", err)
-			}
-		}()
 
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Error fetching claims: %s %v", serverUrl, resp.StatusCode)
-			return
-		}
+		m.setRowClaim(m.unitTables[level].Rows()[i], cidr, *subnetResp)
+		m.unitTables[level].SetRows(m.unitTables[level].Rows())
+	}
+}
 
-		// Process the response
-		subnetResp := &api.SubnetResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(subnetResp); err != nil {
-			log.Printf("Error decoding response: %v", err)
-			return
-		}
+// setRowClaim updates a table row with the claim on its subnet, noting
+// whether it changed since the last refresh
+func (m *Model) setRowClaim(row table.Row, cidr string, subnetResp api.SubnetResponse) {
+	m.noteChange(cidr, subnetResp)
+	m.renderRowClaim(row, cidr, subnetResp)
+}
+
+// renderRowClaim fills a table row with the claim on its subnet, marking
+// owners that changed at the last refresh and highlighting recent changes
+func (m *Model) renderRowClaim(row table.Row, cidr string, subnetResp api.SubnetResponse) {
+	row[0] = subnetResp.Name
+	if row[0] == "" {
+		row[0] = m.generatedName(cidr)
+	}
+	row[1] = subnetResp.Owner
+	if m.spectate && m.changes[cidr].owner {
+		row[1] = changedOwnerMarker + row[1]
+	}
+	if subnetResp.Artifact {
+		row[1] = artifactMarker + row[1]
+	}
+	if style, ok := m.highlight(cidr); ok {
+		columns := m.unitTables[t16].Columns()
+		row[0] = styleCell(style, row[0], columns[0].Width)
+		row[1] = styleCell(style, row[1], columns[1].Width)
+	} else if color, ok := ownerColor(subnetResp.Owner); ok {
+		row[1] = styleCell(lipgloss.NewStyle().Foreground(color), row[1], m.unitTables[t16].Columns()[1].Width)
+	}
+	row[2] = ""
+	if subnetResp.Percentage > 0 {
+		row[2] = m.formatPercentage(subnetResp.Percentage, subnetResp.Owner)
+	}
+}
+
+// generatedName returns the generated name of a subnet in CIDR notation, the
+// name shown unless the server gives the subnet a sector name
+func (m *Model) generatedName(cidr string) string {
+	ipNet, err := api.ParseSubnet(cidr)
+	if err != nil {
+		return cidr
+	}
+	ones, _ := ipNet.Mask.Size()
+	name, err := m.names.name(m.locale, ipNet.IP, ones)
+	if err != nil {
+		return cidr
+	}
+	return name
+}
+
+// FetchClaim fetches the claim on an address, nil if it is unclaimed
+func (m *Model) FetchClaim(ip string) (*api.ClaimResponse, error) {
+	return fetchClaim(context.Background(), m.client, m.serverHost(), ip)
+}
 
-		// Update the table with the claim
-		row := m.unitTables[level].Rows()[i]
-		row[1] = subnetResp.Owner
-		if subnetResp.Percentage > 0 {
-			row[2] = strconv.FormatFloat(subnetResp.Percentage, 'f', 2, 64) + "%"
+// fetchClaim is FetchClaim, giving up once the context is cancelled
+func fetchClaim(ctx context.Context, client *conditionalClient, host, ip string) (*api.ClaimResponse, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/ip/%s", host, ip)
+	status, body, err := client.GetContext(ctx, serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch claim: %v", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %d", status)
+	}
+
+	var claimResp api.ClaimResponse
+	if err := json.Unmarshal(body, &claimResp); err != nil {
+		return nil, fmt.Errorf("failed to decode claim: %v", err)
+	}
+	return &claimResp, nil
+}
+
+// claimInfoMsg delivers how entrenched the selected address is
+type claimInfoMsg struct {
+	id   int
+	info string
+}
+
+// FetchClaimInfo describes how entrenched the claim on an address is
+func (m *Model) FetchClaimInfo(ip string) string {
+	return fetchClaimInfo(context.Background(), m.client, m.serverHost(), ip)
+}
+
+// fetchClaimInfo is FetchClaimInfo, giving up once the context is cancelled
+func fetchClaimInfo(ctx context.Context, client *conditionalClient, host, ip string) string {
+	claimResp, err := fetchClaim(ctx, client, host, ip)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error fetching claim of %s: %v", ip, err)
 		}
-		m.unitTables[level].SetRows(m.unitTables[level].Rows())
+		return ""
+	}
+	if claimResp == nil {
+		return fmt.Sprintf("%s is unclaimed", ip)
+	}
+
+	info := fmt.Sprintf("%s held by %s", ip, claimResp.Name)
+	if claimResp.ClaimedAt != nil {
+		info += fmt.Sprintf(" for %s", time.Since(*claimResp.ClaimedAt).Truncate(time.Second))
 	}
+	info += fmt.Sprintf(", taken over %d times, difficulty %d", claimResp.TakeoverCount, claimResp.Difficulty)
+	if claimResp.Fortification > 0 {
+		info += fmt.Sprintf(", fortified to level %d", claimResp.Fortification)
+	}
+	return info
+}
+
+// refreshClaimInfo fetches how entrenched the selected address is in the
+// background, clearing it above the last level. The description shown stays
+// while the same address is fetched again.
+func (m *Model) refreshClaimInfo(ip string) tea.Cmd {
+	if ip != m.claimInfoFetch.ip {
+		m.claimInfo = ""
+	}
+	if ip == "" {
+		m.claimInfoFetch.stop()
+		return nil
+	}
+
+	client, host := m.client, m.serverHost()
+	return m.fetchSelected(&m.claimInfoFetch, ip, func(ctx context.Context, id int) tea.Msg {
+		return claimInfoMsg{id: id, info: fetchClaimInfo(ctx, client, host, ip)}
+	})
 }
 
 // GetParentSelection returns the parent selection for a given level
@@ -263,55 +555,300 @@ func (m *Model) GetParentSelection(level level) string {
 	return m.selections[parentLevel]
 }
 
+// inCurrentTable reports whether an address is in the subnet the current
+// table lists
+func (m *Model) inCurrentTable(ip string) bool {
+	return strings.HasPrefix(api.ExpandAddress(net.ParseIP(ip)), m.GetParentSelection(m.viewing))
+}
+
 // Init initializes the application
 func (m *Model) Init() tea.Cmd {
+	m.startEvents()
+
+	cmds := []tea.Cmd{waitForEvent(m.events), waitForViewport(m.viewports)}
+	if m.refreshing() {
+		cmds = append(cmds, m.scheduleRefresh())
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleClaimEvent raises an alert when another player takes over one of our addresses
+func (m *Model) handleClaimEvent(event api.ClaimEvent) tea.Cmd {
+	if event.Type != api.EventTypeClaim || event.PreviousClaimant != m.name || event.Claimant == m.name {
+		return nil
+	}
+
+	m.contestedAddr = event.IP
+	m.alertMessage = alertMessageStyle.Render(fmt.Sprintf("%s took %s from you! (t: jump to address)", event.Claimant, event.IP))
+	m.refreshClaims = true
+
+	if m.bell {
+		return ringBell
+	}
+	return nil
+}
+
+// ringBell rings the terminal bell
+func ringBell() tea.Msg {
+	if _, err := os.Stdout.WriteString("\a"); err != nil {
+		log.Printf("Error ringing bell: %v", err)
+	}
 	return nil
 }
 
-// Update handles user input and updates the model
+// JumpTo navigates every level of the browser to the given address,
+// leaving the cursor on the subnet containing it in the last table
+func (m *Model) JumpTo(ip string) error {
+	addr, err := api.ParseAddress(ip)
+	if err != nil {
+		return err
+	}
+	m.jumpTo(addr.To16(), m.depth)
+	return nil
+}
+
+// JumpToSubnet navigates the browser to a subnet in CIDR notation, leaving
+// the cursor on the subnet in the table of its level
+func (m *Model) JumpToSubnet(subnet string) error {
+	ipNet, lvl, err := m.parseSubnetLevel(subnet)
+	if err != nil {
+		return err
+	}
+	m.jumpTo(ipNet.IP.To16(), lvl)
+	return nil
+}
+
+// parseSubnetLevel parses a subnet in CIDR notation, ignoring a zone, and
+// returns the level of the table it is browsed in
+func (m *Model) parseSubnetLevel(subnet string) (*net.IPNet, level, error) {
+	ipNet, err := api.ParseSubnet(api.StripZone(strings.TrimSpace(subnet)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid IPv6 subnet: %s", subnet)
+	}
+	ones, _ := ipNet.Mask.Size()
+	if ones%16 != 0 || ones == 0 || level(ones/16-1) > m.depth {
+		return nil, 0, fmt.Errorf("subnet is not a browsable level: %s", subnet)
+	}
+	return ipNet, level(ones/16 - 1), nil
+}
+
+// jumpTo populates the tables down to a level, selecting the blocks of addr
+func (m *Model) jumpTo(addr net.IP, target level) {
+	for lvl := t16; lvl <= target; lvl++ {
+		block := int(addr[2*lvl])<<8 | int(addr[2*lvl+1])
+		m.PopulateTable(m.GetParentSelection(lvl), lvl)
+		m.unitTables[lvl].SetCursor(block)
+		if lvl < target {
+			m.selections[lvl] = blockPrefix(addr, lvl)
+		}
+	}
+
+	m.viewing = target
+	m.refreshClaims = true
+}
+
+// Resolve looks up the claimed subnets with a generated name
+func (m *Model) Resolve(name string) ([]string, error) {
+	serverURL := fmt.Sprintf("http://%s/api/v1/resolve?name=%s", m.serverHost(), url.QueryEscape(name))
+	status, body, err := m.client.Get(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve name: %v", err)
+	}
+
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("no claimed subnet is named %q", name)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %d", status)
+	}
+
+	var resolveResp api.ResolveResponse
+	if err := json.Unmarshal(body, &resolveResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resolveResp.Subnets, nil
+}
+
+// Update handles user input and updates the model, refreshing the claims
+// around the cursor and fading the highlights of changed rows after every update
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	return model, tea.Batch(cmd, m.refreshVisible(), m.fade())
+}
+
+// refreshVisible fetches the claims around the cursor if they need refreshing,
+// along with how entrenched the selected address is and its history
+func (m *Model) refreshVisible() tea.Cmd {
+	if !m.refreshClaims {
+		return nil
+	}
+	m.refreshClaims = false
+	cmd := m.fetchVisible()
+
+	selected := ""
+	cursor := m.unitTables[m.viewing].Cursor()
+	if rows := m.shadowTables[m.depth].Rows(); m.viewing == m.depth && cursor < len(rows) {
+		if selection, err := api.ParseSubnet(rows[cursor][0]); err == nil {
+			selected = selection.IP.String()
+		}
+	}
+	return tea.Batch(cmd, m.refreshClaimInfo(selected), m.refreshHistory(selected))
+}
+
+// update handles a message for Update
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
+	case refreshTickMsg:
+		return m, m.handleRefreshTick(msg)
+
+	case discoveredMsg:
+		m.handleDiscovered(msg)
+		return m, nil
+
+	case tickerTickMsg:
+		return m, m.handleTickerTick()
+
+	case fadeTickMsg:
+		m.handleFadeTick()
+		return m, nil
+
+	case claimDoneMsg:
+		m.handleClaimDone(msg)
+		return m, nil
+
+	case claimsFetchedMsg:
+		m.handleClaimsFetched(msg)
+		return m, nil
+
+	case claimInfoMsg:
+		if m.claimInfoFetch.finish(msg.id) {
+			m.claimInfo = msg.info
+		}
+		return m, nil
+
+	case historyMsg:
+		m.handleHistory(msg)
+		return m, nil
+
+	case viewportMsg:
+		return m, m.handleViewport(msg)
+
+	case claimEventMsg:
+		event := api.ClaimEvent(msg)
+		return m, tea.Batch(m.handleClaimEvent(event), m.recordTakeover(event), m.speedUpRefresh(event), waitForEvent(m.events))
+
 	case tea.WindowSizeMsg:
-		reserved := 6
+		reserved := 7
 		m.unitTables.SetHeight(msg.Height - reserved)
-		m.unitTables.SetWidth(msg.Width - 4)
+		m.width = msg.Width
+		m.resizeTables()
 
 	case tea.KeyMsg:
 		m.statusMessage = ""
 		m.errorMessage = ""
 
-		switch msg.String() {
-		case "ctrl+c", "q":
+		if m.confirming != nil {
+			return m, m.handleConfirmKey(msg)
+		}
+		if m.prompt != nil {
+			return m, m.handlePromptKey(msg)
+		}
+		if m.switcher != nil {
+			m.handleSwitcherKey(msg)
+			return m, nil
+		}
+		if m.discover != nil {
+			return m, m.handleDiscoverKey(msg)
+		}
+		if m.showHelp {
+			m.showHelp = false
+			return m, nil
+		}
+
+		switch keyStr := msg.String(); {
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 
-		case "esc":
+		case key.Matches(msg, m.keys.Back) && m.claims.busy():
+			m.cancelClaims()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Takeover):
+			if m.contestedAddr != "" {
+				if err := m.JumpTo(m.contestedAddr); err != nil {
+					m.errorMessage = errorMessageStyle.Render(err.Error())
+				}
+				m.contestedAddr = ""
+				m.alertMessage = ""
+			}
+
+		case len(keyStr) == 1 && keyStr >= "1" && keyStr <= "8":
+			// Jump back to a level of the breadcrumbs
+			if lvl := level(keyStr[0] - '1'); lvl < m.viewing {
+				m.viewing = lvl
+				m.refreshClaims = true
+			}
+
+		case key.Matches(msg, m.keys.Back):
 			if m.viewing > 0 {
 				m.viewing--
 				m.refreshClaims = true
 			}
 
-		case "enter":
-			cursor := m.unitTables[m.viewing].Cursor()
-			selection := m.shadowTables[m.viewing].Rows()[cursor][0]
-			if m.viewing < t128 {
-				selection = selection[:5*(m.viewing+1)] // Adjust for the level
-				m.selections[m.viewing] = selection
-				m.viewing++
-				m.PopulateTable(m.selections[m.viewing-1], m.viewing)
+		case key.Matches(msg, m.keys.Select):
+			selection, ok := m.selectedSubnet()
+			if !ok {
+				break // No subnet matches the view
+			}
+			if m.viewing < m.depth {
+				m.openSubnet(selection)
 			} else {
-				// At the last level, send a claim
-				ip := strings.Split(selection, "/")[0] // Get the IP part before the prefix
-				if msg, err := m.SendClaim(ip); err == nil {
-					m.statusMessage = statusMessageStyle.Render(msg)
-					m.errorMessage = ""
-				} else {
-					m.errorMessage = errorMessageStyle.Render("Failed to send claim: " + err.Error())
-					m.statusMessage = ""
-				}
+				// At the last level, send a claim for the first address of the subnet
+				cmds = append(cmds, m.claim(selection.IP.String()))
 			}
 			m.refreshClaims = true
+
+		case key.Matches(msg, m.keys.Command):
+			m.openPalette()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Address):
+			m.openAddressInput()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+
+		case key.Matches(msg, m.keys.History):
+			m.toggleHistory()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Refresh):
+			return m, m.refreshNow()
+
+		case key.Matches(msg, m.keys.Bars):
+			m.toggleBars()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Pause):
+			return m, m.togglePauseTicker()
+
+		case key.Matches(msg, m.keys.Nearby):
+			m.toggleNearbyTicker()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Servers):
+			m.openSwitcher()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Discover):
+			return m, m.openDiscover()
+
+		default:
+			m.handleViewKey(msg)
 		}
 	}
 
@@ -328,31 +865,245 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// resizeTables fits the tables to the terminal, beside the claim history
+// panel if it is shown
+func (m *Model) resizeTables() {
+	if m.width == 0 {
+		return // Not sized yet
+	}
+	width := m.width - 4
+	if m.showHistory {
+		width -= historyPanelWidth
+	}
+	m.unitTables.SetWidth(width)
+}
+
+// openSubnet shows the table of the subnets of a subnet in the current table
+func (m *Model) openSubnet(selection *net.IPNet) {
+	m.selections[m.viewing] = blockPrefix(selection.IP, m.viewing)
+	m.viewing++
+	m.PopulateTable(m.selections[m.viewing-1], m.viewing)
+}
+
+// selectedSubnet returns the subnet under the cursor of the current table,
+// false if no subnet matches the table's view
+func (m *Model) selectedSubnet() (*net.IPNet, bool) {
+	m.materializeCursor(m.viewing)
+	rows := m.shadowTables[m.viewing].Rows()
+	cursor := m.unitTables[m.viewing].Cursor()
+	if cursor < 0 || cursor >= len(rows) {
+		return nil, false
+	}
+	selection, err := api.ParseSubnet(rows[cursor][0])
+	if err != nil {
+		panic(fmt.Sprintf("Invalid subnet in table: %v", err))
+	}
+	return selection, true
+}
+
+// refresh fetches the visible claims again, and the rows of a sorted or
+// filtered table, whose subnets may have started or stopped matching
+func (m *Model) refresh() {
+	if m.views[m.viewing].active() {
+		if err := m.applyView(m.viewing); err != nil {
+			m.errorMessage = errorMessageStyle.Render(err.Error())
+		}
+	}
+	m.refreshClaims = true
+}
+
+// breadcrumbs renders the names of the selected subnets from /16 down to the
+// cursor in the current table, numbered by the keys that jump to their level
+func (m *Model) breadcrumbs() string {
+	crumbs := make([]string, 0, m.viewing+1)
+	for lvl := t16; lvl <= m.viewing; lvl++ {
+		m.materializeCursor(lvl)
+		rows := m.unitTables[lvl].Rows()
+		cursor := m.unitTables[lvl].Cursor()
+		if cursor < 0 || cursor >= len(rows) {
+			continue
+		}
+		name := rows[cursor][0]
+		if runes := []rune(name); len(runes) > maxCrumbLength {
+			name = string(runes[:maxCrumbLength-1]) + ellipsis
+		}
+		crumbs = append(crumbs, fmt.Sprintf("%d %s", lvl+1, name))
+	}
+	return strings.Join(crumbs, crumbSeparator)
+}
+
 // View renders the current state of the model
 func (m *Model) View() string {
-	if m.refreshClaims {
-		activeTable := m.unitTables[m.viewing]
-		m.FetchClaims(m.GetParentSelection(m.viewing), m.viewing, activeTable.Cursor()-activeTable.Height(), activeTable.Cursor()+activeTable.Height())
-		m.refreshClaims = false
-	}
+	m.materializeVisible()
 
 	msg := m.statusMessage
-	if m.errorMessage != "" {
+	if m.prompt != nil {
+		msg = statusMessageStyle.Render(m.prompt.label + m.prompt.input + inputCursor)
+		if m.prompt.hint != nil {
+			msg += "  " + m.prompt.hint(m, m.prompt.input)
+		}
+	} else if m.errorMessage != "" {
 		msg = m.errorMessage
+	} else if m.alertMessage != "" {
+		msg = m.alertMessage
+	} else if msg == "" && m.claims.busy() {
+		msg = statusMessageStyle.Render(m.claims.status(keyName(m.keys.Back)))
+	} else if msg == "" {
+		msg = helpStyle(m.claimInfo)
+	}
+
+	title := "SpaceNet Browser"
+	help := fmt.Sprintf("%s: select subnet, %s: back, 1-8: jump to level, %s: commands, %s: help, %s: quit",
+		keyName(m.keys.Select), keyName(m.keys.Back), keyName(m.keys.Command), keyName(m.keys.Help), keyName(m.keys.Quit))
+	if m.spectate {
+		title += " (spectating)"
+	}
+	if m.refreshing() {
+		help += fmt.Sprintf(", refreshing every %s", m.refreshDelay)
+	}
+	if view := m.views[m.viewing]; view.active() {
+		title += " [" + view.String() + "]"
+	}
+
+	body := tableStyle.Render(m.unitTables[m.viewing].View())
+	if m.showHistory {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, m.historyView(lipgloss.Height(body)))
+	}
+	if m.confirming != nil {
+		body = m.confirmDialog()
+	} else if m.switcher != nil {
+		body = m.switcherView()
+	} else if m.discover != nil {
+		body = m.discoverView()
+	} else if m.showHelp {
+		body = m.helpOverlay()
 	}
 
-	return titleStyle.Render("SpaceNet Browser") + "\n\n" +
-		tableStyle.Render(m.unitTables[m.viewing].View()) + "\n" + msg + "\n" +
-		helpStyle("enter: select subnet, esc: back, q: quit")
+	return titleStyle.Render(title) + " " + m.connectionView() + "\n" + helpStyle(m.breadcrumbs()) + "\n" +
+		body + "\n" + msg + "\n" +
+		m.tickerView(cmp.Or(m.width, 80)-2) + "\n" +
+		helpStyle(help)
 }
 
 func main() {
-	// Parse command line flags
-	server := flag.String("server", "::1", "IPv6 address of the server")
-	httpPort := flag.Int("http-port", 8080, "HTTP port for the server's API")
-	name := flag.String("name", "Anonymous", "Name to use for claims")
+	// Parse command line flags, which override the config file
+	defaults := DefaultConfig()
+	configPath := flag.String("config", DefaultConfigPath(), "TOML file of settings")
+	profile := flag.String("profile", "", "Server profile from the config file to start with")
+	demoMode := flag.Bool("demo", false, "Play against an in-process server with synthetic claims, needing no network")
+	discover := flag.Bool("discover", false, "Play on a server found on the local network, choosing between them if there are several")
+	server := flag.String("server", defaults.Server, "IPv6 address of the server")
+	httpPort := flag.Int("http-port", defaults.HTTPPort, "HTTP port for the server's API")
+	name := flag.String("name", defaults.Name, "Name to use for claims")
+	spectate := flag.Bool("spectate", false, "Read-only mode that auto-refreshes and highlights ownership changes")
+	refreshInterval := flag.Duration("refresh", defaults.Refresh, "Shortest interval between refreshes of the visible claims")
+	maxRefresh := flag.Duration("max-refresh", defaults.MaxRefresh, "Longest interval refreshes back off to while nothing changes")
+	autoRefresh := flag.Bool("auto-refresh", defaults.AutoRefresh, "Refresh the visible claims periodically when not spectating")
+	bell := flag.Bool("bell", defaults.Bell, "Ring the terminal bell when another player takes over your address")
+	find := flag.String("find", "", "Start at the claimed subnet with this generated name")
+	lang := flag.String("lang", defaults.Lang, fmt.Sprintf("Language of subnet names (%s)", strings.Join(names.Locales(), ", ")))
+	namePack := flag.String("name-pack", defaults.NamePack, "JSON or YAML word lists to generate subnet names with, unless the server has its own pack")
+	theme := flag.String("theme", defaults.Theme.Mode, fmt.Sprintf("Theme mode (%s, %s, %s)", themeColor, themeNoColor, themeASCII))
+	plain := flag.Bool("plain", false, "Print pages of text read by commands on standard input instead of drawing the screen, for screen readers and pipes")
 	flag.Parse()
 
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	cfg, err := LoadConfig(*configPath, set["config"])
+	if err != nil {
+		fmt.Println("Fatal:", err)
+		os.Exit(1)
+	}
+	if set["profile"] {
+		cfg.Profile = *profile
+	}
+	profiles := cfg.profiles()
+	if err := cfg.applyProfile(); err != nil {
+		fmt.Println("Fatal:", err)
+		os.Exit(1)
+	}
+	if set["server"] {
+		cfg.Server = *server
+	}
+	if set["http-port"] {
+		cfg.HTTPPort = *httpPort
+	}
+	if set["name"] {
+		cfg.Name = *name
+	}
+	if set["refresh"] {
+		cfg.Refresh = *refreshInterval
+	}
+	if set["max-refresh"] {
+		cfg.MaxRefresh = *maxRefresh
+	}
+	if set["auto-refresh"] {
+		cfg.AutoRefresh = *autoRefresh
+	}
+	if set["bell"] {
+		cfg.Bell = *bell
+	}
+	if set["lang"] {
+		cfg.Lang = *lang
+	}
+	if set["name-pack"] {
+		cfg.NamePack = *namePack
+	}
+	if set["theme"] {
+		cfg.Theme.Mode = *theme
+	} else if os.Getenv("NO_COLOR") != "" && cfg.Theme.Mode == themeColor {
+		// Honor https://no-color.org unless a theme was asked for
+		cfg.Theme.Mode = themeNoColor
+	}
+	if *plain {
+		// Plain text only, without color, box drawing or bars
+		cfg.Theme.Mode = themeASCII
+		cfg.PercentageBars = false
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Println("Fatal:", err)
+		os.Exit(1)
+	}
+	applyTheme(cfg.Theme)
+
+	if *demoMode {
+		demoServer, err := demo.Start()
+		if err != nil {
+			fmt.Println("Fatal:", err)
+			os.Exit(1)
+		}
+		defer demoServer.Stop()
+
+		// The demo is fresh every run and the only server to play on
+		cfg.Server, cfg.HTTPPort = demoServer.Host, demoServer.Port
+		cfg.RestoreSession = false
+		profiles = []Profile{{Name: "demo", Server: cfg.Server, HTTPPort: cfg.HTTPPort, Player: cfg.Name}}
+	}
+
+	var discovered []discoveredServer
+	if *discover && !*demoMode {
+		discovered, err = findServers(discoverTimeout)
+		if err == nil && len(discovered) == 0 {
+			err = errors.New("no servers found on the local network")
+		}
+		if err != nil {
+			fmt.Println("Fatal:", err)
+			os.Exit(1)
+		}
+		cfg.Server, cfg.HTTPPort = discovered[0].addr, discovered[0].httpPort
+	}
+
+	locale, _ := names.LookupLocale(cfg.Lang)
+	if cfg.NamePack != "" {
+		pack, err := names.LoadPackFile(cfg.NamePack)
+		if err != nil {
+			fmt.Println("Fatal:", err)
+			os.Exit(1)
+		}
+		locale = pack
+	}
+
 	// Set up logging
 	f, err := tea.LogToFile("debug.log", "debug")
 	if err != nil {
@@ -366,8 +1117,66 @@ func main() {
 	}()
 
 	// Initialize the TUI
-	p := tea.NewProgram(Initialize(*server, *httpPort, *name), tea.WithAltScreen())
+	m := Initialize(cfg.Server, cfg.HTTPPort, cfg.Name, locale, *spectate, cfg.Refresh, cfg.Bell, newKeyMap(cfg.Keys))
+	m.confirmClaims = cfg.ConfirmClaims
+	m.showBars = cfg.PercentageBars
+	m.autoRefresh = cfg.AutoRefresh
+	m.maxRefresh = cfg.MaxRefresh
+	m.profiles = profiles
+	if cfg.RestoreSession {
+		m.statePath = DefaultStatePath()
+	}
+	if err := m.FetchConfig(); err != nil {
+		fmt.Println("Fatal:", err)
+		os.Exit(1)
+	}
+	if *find != "" {
+		subnets, err := m.Resolve(*find)
+		if err == nil {
+			err = m.JumpToSubnet(subnets[0])
+		}
+		if err != nil {
+			fmt.Println("Fatal:", err)
+			os.Exit(1)
+		}
+		if len(subnets) > 1 {
+			m.statusMessage = statusMessageStyle.Render(fmt.Sprintf("%d subnets are named %q, showing %s", len(subnets), *find, subnets[0]))
+		}
+	} else if *demoMode {
+		if err := m.JumpToSubnet(demo.Prefix); err != nil {
+			fmt.Println("Fatal:", err)
+			os.Exit(1)
+		}
+	} else if m.statePath != "" {
+		// A session the server's levels no longer fit starts over from /16
+		if err := m.RestoreSession(m.statePath); err != nil {
+			log.Printf("Error restoring session: %v", err)
+		}
+	}
+
+	if *plain {
+		if err := m.runPlain(os.Stdin, os.Stdout); err != nil {
+			log.Printf("Error reading commands: %v", err)
+		}
+		if m.statePath != "" {
+			if err := m.SaveSession(m.statePath); err != nil {
+				log.Printf("Error saving session: %v", err)
+			}
+		}
+		return
+	}
+
+	if len(discovered) > 1 {
+		m.discover = &discoverScreen{servers: discovered}
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		log.Fatalf("Error running program: %v", err)
 	}
+	if m.statePath != "" {
+		if err := m.SaveSession(m.statePath); err != nil {
+			log.Printf("Error saving session: %v", err)
+		}
+	}
 }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"container/list"
+	"net"
+	"sync"
+
+	"github.com/bjia56/spacenet/server/names"
+)
+
+// nameCacheSize is the number of generated names kept, a few screens of rows
+// around the cursor at every level
+const nameCacheSize = 1 << 14
+
+// precomputeRows is the number of rows on either side of the cursor whose
+// names are generated in the background
+const precomputeRows = 512
+
+// nameKey identifies the generated name of a subnet
+type nameKey struct {
+	addr   [16]byte
+	subnet int
+}
+
+// nameCacheEntry is a cached name
+type nameCacheEntry struct {
+	key  nameKey
+	name string
+}
+
+// nameRange is a range of rows of an unfiltered table to generate names for,
+// nearest the cursor first
+type nameRange struct {
+	locale *names.Locale
+	prefix string // Parent selection of the table
+	level  level
+	cursor int
+}
+
+// nameCache is an LRU cache of generated subnet names in one locale, filled
+// ahead of the cursor by a background worker so scrolling rarely hashes
+type nameCache struct {
+	mutex    sync.Mutex
+	capacity int
+	locale   *names.Locale // Locale of the cached names
+	entries  map[nameKey]*list.Element
+	order    *list.List // Most recently used first
+
+	requests chan nameRange // Latest range for the worker, replaced rather than queued
+	last     nameRange      // Range last requested
+}
+
+// newNameCache creates a cache holding up to capacity names and starts its
+// worker, which runs for the life of the program
+func newNameCache(capacity int) *nameCache {
+	c := &nameCache{
+		capacity: capacity,
+		entries:  make(map[nameKey]*list.Element),
+		order:    list.New(),
+		requests: make(chan nameRange, 1),
+	}
+	go c.precomputeLoop()
+	return c
+}
+
+// name returns the generated name of a subnet in a locale, generating and
+// caching it if needed. Names cached in another locale are dropped.
+func (c *nameCache) name(locale *names.Locale, ip net.IP, subnet int) (string, error) {
+	c.use(locale)
+	return c.generate(locale, ip, subnet)
+}
+
+// generate returns the cached name of a subnet, generating and caching it
+// if needed
+func (c *nameCache) generate(locale *names.Locale, ip net.IP, subnet int) (string, error) {
+	key := nameKey{subnet: subnet}
+	copy(key.addr[:], ip.To16())
+	if name, ok := c.get(key); ok {
+		return name, nil
+	}
+
+	name, err := locale.GenerateName(ip.String(), subnet)
+	if err != nil {
+		return "", err
+	}
+	c.add(locale, key, name)
+	return name, nil
+}
+
+// use switches the cache to a locale, dropping every name if it changed
+func (c *nameCache) use(locale *names.Locale) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if locale != c.locale {
+		c.entries = make(map[nameKey]*list.Element)
+		c.order.Init()
+		c.locale = locale
+	}
+}
+
+// inUse reports whether names are cached in a locale
+func (c *nameCache) inUse(locale *names.Locale) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return locale == c.locale
+}
+
+// get returns a cached name
+func (c *nameCache) get(key nameKey) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*nameCacheEntry).name, true
+}
+
+// add caches a name, evicting the least recently used name if full
+func (c *nameCache) add(locale *names.Locale, key nameKey, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if locale != c.locale {
+		return // Generated for a locale no longer in use
+	}
+	if element, exists := c.entries[key]; exists {
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&nameCacheEntry{key: key, name: name})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*nameCacheEntry).key)
+	}
+}
+
+// precompute asks the worker to generate the names around a cursor,
+// replacing any range it has not finished
+func (c *nameCache) precompute(r nameRange) {
+	if r == c.last {
+		return
+	}
+	c.last = r
+	select {
+	case <-c.requests:
+	default:
+	}
+	c.requests <- r
+}
+
+// precomputeLoop generates the names of requested ranges, outward from the
+// cursor, moving on as soon as a newer range is requested or the locale changes
+func (c *nameCache) precomputeLoop() {
+	for r := range c.requests {
+		for offset := 0; offset < precomputeRows && len(c.requests) == 0 && c.inUse(r.locale); offset++ {
+			for _, i := range []int{r.cursor + offset, r.cursor - offset - 1} {
+				if i < 0 || i >= 1<<16 {
+					continue
+				}
+				addr, subnet := makeIPv6Full(i, r.prefix, r.level)
+				c.generate(r.locale, net.ParseIP(addr), subnet) // Failures show when the row is drawn
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/bjia56/spacenet/server/names"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNameCache_Eviction tests that the least recently used names are
+// evicted once the cache is full
+func TestNameCache_Eviction(t *testing.T) {
+	locale, ok := names.LookupLocale(names.DefaultLocale)
+	require.True(t, ok)
+	cache := newNameCache(2)
+	cache.use(locale)
+
+	key := func(ip string) nameKey {
+		k := nameKey{subnet: 128}
+		copy(k.addr[:], net.ParseIP(ip).To16())
+		return k
+	}
+	cache.add(locale, key("2001:db8::1"), "one")
+	cache.add(locale, key("2001:db8::2"), "two")
+	_, ok = cache.get(key("2001:db8::1")) // Now the most recently used
+	require.True(t, ok)
+	cache.add(locale, key("2001:db8::3"), "three")
+
+	testCases := map[string]bool{"2001:db8::1": true, "2001:db8::2": false, "2001:db8::3": true}
+	for ip, cached := range testCases {
+		_, ok := cache.get(key(ip))
+		assert.Equal(t, cached, ok, "%s cached", ip)
+	}
+	assert.Equal(t, 2, cache.order.Len())
+}
+
+// TestNameCache_Locale tests that switching locales drops every cached name
+func TestNameCache_Locale(t *testing.T) {
+	locales := names.Locales()
+	require.GreaterOrEqual(t, len(locales), 2, "Should have two locales to switch between")
+	first, _ := names.LookupLocale(locales[0])
+	second, _ := names.LookupLocale(locales[1])
+	cache := newNameCache(nameCacheSize)
+
+	ip := net.ParseIP("2001:db8::1")
+	name, err := cache.name(first, ip, 128)
+	require.NoError(t, err)
+	expected, err := first.GenerateName(ip.String(), 128)
+	require.NoError(t, err)
+	assert.Equal(t, expected, name)
+	assert.Equal(t, 1, cache.order.Len())
+
+	_, err = cache.name(second, ip, 128)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cache.order.Len(), "Names of the previous locale should be dropped")
+	assert.True(t, cache.inUse(second))
+
+	cache.add(first, nameKey{subnet: 64}, "stale")
+	assert.Equal(t, 1, cache.order.Len(), "Names generated for a locale no longer in use should not be cached")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bjia56/spacenet/server/api"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// prompt is a line of text being typed in place of the status message, such
+// as a command or an owner to filter by
+type prompt struct {
+	label  string
+	input  string
+	submit func(m *Model, input string) tea.Cmd // Called with the input on enter
+	hint   func(m *Model, input string) string  // Feedback shown after the input as it is typed, if set
+	tab    func(p *prompt)                      // Called on tab, if set
+}
+
+// handlePromptKey edits the text of the prompt, submitting it on enter and
+// dismissing it on esc
+func (m *Model) handlePromptKey(msg tea.KeyMsg) tea.Cmd {
+	p := m.prompt
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.prompt = nil
+		return p.submit(m, strings.TrimSpace(p.input))
+	case tea.KeyEsc:
+		m.prompt = nil
+	case tea.KeyBackspace:
+		if runes := []rune(p.input); len(runes) > 0 {
+			p.input = string(runes[:len(runes)-1])
+		} else {
+			m.prompt = nil
+		}
+	case tea.KeyTab:
+		if p.tab != nil {
+			p.tab(p)
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		p.input += string(msg.Runes)
+	}
+	return nil
+}
+
+// command is an action of the command palette
+type command struct {
+	name  string
+	args  string // Arguments, for the help
+	usage string
+	run   func(m *Model, args []string) (tea.Cmd, error)
+}
+
+// commands are the actions of the command palette, for functions without keys
+var commands = []command{
+	{"claim", "[ip]", "claim an address, or the selected subnet's first address", (*Model).claimCommand},
+	{"goto", "<ip|subnet>", "jump to an address or subnet", (*Model).gotoCommand},
+	{"refresh", "", "refresh the visible claims", (*Model).refreshCommand},
+	{"spectate", "", "toggle spectator mode", (*Model).spectateCommand},
+	{"set-name", "<name>", "change the name claims are made with", (*Model).setNameCommand},
+	{"server", "<profile>", "switch to a server profile", (*Model).serverCommand},
+	{"discover", "", "find servers on the local network", (*Model).discoverCommand},
+}
+
+// openPalette starts typing a command
+func (m *Model) openPalette() {
+	m.prompt = &prompt{label: ":", submit: (*Model).runCommand}
+}
+
+// runCommand runs a line typed in the command palette
+func (m *Model) runCommand(line string) tea.Cmd {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	for _, c := range commands {
+		if c.name != fields[0] {
+			continue
+		}
+		cmd, err := c.run(m, fields[1:])
+		if err != nil {
+			m.errorMessage = errorMessageStyle.Render(c.name + ": " + err.Error())
+		}
+		return cmd
+	}
+	m.errorMessage = errorMessageStyle.Render(fmt.Sprintf("Unknown command %q, press %s for help", fields[0], keyName(m.keys.Help)))
+	return nil
+}
+
+// claimCommand claims an address, or the first address of the selected
+// subnet at the last level
+func (m *Model) claimCommand(args []string) (tea.Cmd, error) {
+	switch {
+	case len(args) > 1:
+		return nil, fmt.Errorf("usage: claim [ip]")
+	case len(args) == 1:
+		ip, err := api.ParseAddress(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return m.claim(ip.String()), nil
+	case m.viewing < m.depth:
+		return nil, fmt.Errorf("select a subnet of the last level or give an address")
+	default:
+		selection, ok := m.selectedSubnet()
+		if !ok {
+			return nil, fmt.Errorf("no subnet is selected")
+		}
+		return m.claim(selection.IP.String()), nil
+	}
+}
+
+// gotoCommand jumps to an address or subnet
+func (m *Model) gotoCommand(args []string) (tea.Cmd, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: goto <ip|subnet>")
+	}
+	return nil, m.jumpToInput(args[0])
+}
+
+// refreshCommand refreshes the visible claims
+func (m *Model) refreshCommand(args []string) (tea.Cmd, error) {
+	return m.refreshNow(), nil
+}
+
+// spectateCommand switches spectator mode on or off
+func (m *Model) spectateCommand(args []string) (tea.Cmd, error) {
+	m.spectate = !m.spectate
+	if !m.spectate {
+		m.statusMessage = statusMessageStyle.Render("Stopped spectating")
+		return nil, nil
+	}
+
+	m.statusMessage = statusMessageStyle.Render(fmt.Sprintf("Spectating, refreshing every %s", m.refreshInterval))
+	m.refreshDelay = m.refreshInterval
+	return m.scheduleRefresh(), nil
+}
+
+// setNameCommand changes the name claims are made with
+func (m *Model) setNameCommand(args []string) (tea.Cmd, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("usage: set-name <name>")
+	}
+	m.name = strings.Join(args, " ")
+	m.statusMessage = statusMessageStyle.Render(fmt.Sprintf("Claiming as %q", m.name))
+	return nil, nil
+}
+
+// helpOverlay renders every key binding and command of the palette
+func (m *Model) helpOverlay() string {
+	var b strings.Builder
+	row := func(keys, desc string) {
+		fmt.Fprintf(&b, "  %-16s %s\n", keys, desc)
+	}
+
+	b.WriteString("Keys\n")
+	for _, binding := range m.keys.bindings() {
+		row(binding.Help().Key, binding.Help().Desc)
+	}
+	row("1-8", "jump to a level of the breadcrumbs")
+	row("j/k, b/f, g/G", "move the cursor by rows, pages or to either end")
+
+	fmt.Fprintf(&b, "\nCommands, typed after %s\n", keyName(m.keys.Command))
+	for _, c := range commands {
+		row(strings.TrimSpace(c.name+" "+c.args), c.usage)
+	}
+
+	b.WriteString("\n" + helpStyle("Press any key to close"))
+	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+}
+
+// bindings returns the key bindings in the order of the help overlay
+func (k keyMap) bindings() []key.Binding {
+	return []key.Binding{
+		k.Up, k.Down, k.Select, k.Back, k.Takeover, k.Sort, k.Claimed, k.Mine,
+		k.Filter, k.Clear, k.Bars, k.Refresh, k.Address, k.History, k.Pause, k.Nearby, k.Servers,
+		k.Discover, k.Command, k.Help, k.Quit,
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// plainPageSize is the number of rows printed per page in plain mode
+const plainPageSize = 20
+
+// plainHelp lists the commands of plain mode
+const plainHelp = `Commands:
+  n, enter       next page
+  p              previous page
+  <row>          open the subnet of a row, or show the claim on an address
+  b              back to the parent subnet
+  g <ip|subnet>  go to an address or subnet
+  c <ip>         claim an address
+  r              refresh the page
+  h              show this help
+  q              quit`
+
+// runPlain browses as lines of text instead of the full screen TUI, for
+// screen readers and pipes. Commands are read a line at a time until quit
+// or the end of the input.
+func (m *Model) runPlain(in io.Reader, out io.Writer) error {
+	m.plain = true
+	m.printPage(out)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && (fields[0] == "q" || fields[0] == "quit") {
+			return nil
+		}
+		m.runPlainCommand(out, fields)
+	}
+}
+
+// runPlainCommand runs a line typed in plain mode
+func (m *Model) runPlainCommand(out io.Writer, fields []string) {
+	table := &m.unitTables[m.viewing]
+	page := table.Cursor() / plainPageSize
+	command := ""
+	if len(fields) > 0 {
+		command = fields[0]
+	}
+
+	switch command {
+	case "", "n":
+		if (page+1)*plainPageSize >= len(table.Rows()) {
+			fmt.Fprintln(out, "This is the last page")
+			return
+		}
+		table.SetCursor((page + 1) * plainPageSize)
+	case "p":
+		if page == 0 {
+			fmt.Fprintln(out, "This is the first page")
+			return
+		}
+		table.SetCursor((page - 1) * plainPageSize)
+	case "b":
+		if m.viewing == 0 {
+			fmt.Fprintln(out, "This is the top level")
+			return
+		}
+		m.viewing--
+	case "g":
+		if len(fields) != 2 {
+			fmt.Fprintln(out, "Usage: g <ip|subnet>")
+			return
+		}
+		if err := m.jumpToInput(fields[1]); err != nil {
+			fmt.Fprintln(out, err)
+			return
+		}
+	case "c":
+		if len(fields) != 2 {
+			fmt.Fprintln(out, "Usage: c <ip>")
+			return
+		}
+		m.plainClaim(out, fields[1])
+		return
+	case "r":
+		m.refresh()
+	case "h", "help":
+		fmt.Fprintln(out, plainHelp)
+		return
+	default:
+		row, err := strconv.Atoi(command)
+		if err != nil || row < 1 || row > len(table.Rows()) {
+			fmt.Fprintf(out, "Unknown command %q, type h for help\n", command)
+			return
+		}
+		table.SetCursor(row - 1)
+		selection, ok := m.selectedSubnet()
+		if !ok {
+			return
+		}
+		if m.viewing == m.depth {
+			fmt.Fprintln(out, m.FetchClaimInfo(selection.IP.String()))
+			return
+		}
+		m.openSubnet(selection)
+	}
+	m.printPage(out)
+}
+
+// plainClaim claims an address, printing the outcome
+func (m *Model) plainClaim(out io.Writer, input string) {
+	if m.spectate {
+		fmt.Fprintln(out, "Claiming is disabled in spectator mode")
+		return
+	}
+	fmt.Fprintf(out, "Solving the proof of work for %s...\n", input)
+	msg, err := m.SendClaim(input)
+	if err != nil {
+		fmt.Fprintln(out, "Failed to send claim:", err)
+		return
+	}
+	fmt.Fprintln(out, msg)
+}
+
+// printPage prints the page of the current table holding its cursor
+func (m *Model) printPage(out io.Writer) {
+	table := &m.unitTables[m.viewing]
+	rows, shadowRows := table.Rows(), m.shadowTables[m.viewing].Rows()
+	start := table.Cursor() / plainPageSize * plainPageSize
+	end := min(start+plainPageSize, len(rows))
+	m.materializeRows(m.viewing, start, end)
+	m.FetchClaims(m.viewing, start, end)
+
+	header := fmt.Sprintf("/%d subnets", subnetMappings[m.viewing])
+	if parent := m.parentSubnet(m.viewing); parent != nil {
+		header += fmt.Sprintf(" of %s (%s)", m.generatedName(parent.String()), parent)
+	}
+	fmt.Fprintf(out, "%s, rows %d to %d of %d\n", header, start+1, end, len(rows))
+	tracked := m.tracked[subnetMappings[m.viewing]]
+	for i := start; i < end; i++ {
+		line := fmt.Sprintf("%d. %s (%s)", i+1, rows[i][0], shadowRows[i][0])
+		switch {
+		case !tracked:
+			// The server keeps no owners of this level
+		case rows[i][1] == "":
+			line += ": unclaimed"
+		default:
+			line += fmt.Sprintf(": %s, %s", rows[i][1], rows[i][2])
+		}
+		fmt.Fprintln(out, line)
+	}
+}
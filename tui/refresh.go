@@ -0,0 +1,69 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// refreshTickMsg triggers a periodic refresh of the visible claims. Ticks of
+// earlier generations were superseded by a rescheduled refresh.
+type refreshTickMsg struct {
+	gen int
+}
+
+// refreshing reports whether the visible claims are refreshed periodically
+func (m *Model) refreshing() bool {
+	return m.spectate || m.autoRefresh
+}
+
+// scheduleRefresh schedules the next refresh after the current delay,
+// superseding any pending refresh
+func (m *Model) scheduleRefresh() tea.Cmd {
+	m.refreshGen++
+	gen := m.refreshGen
+	return tea.Tick(m.refreshDelay, func(time.Time) tea.Msg {
+		return refreshTickMsg{gen: gen}
+	})
+}
+
+// handleRefreshTick refreshes the visible claims, backing off while nothing
+// changes between refreshes and returning to the shortest interval when
+// something does
+func (m *Model) handleRefreshTick(msg refreshTickMsg) tea.Cmd {
+	if msg.gen != m.refreshGen || !m.refreshing() {
+		return nil // Superseded, or refreshing stopped
+	}
+	if m.lastChange.After(m.lastRefresh) {
+		m.refreshDelay = m.refreshInterval
+	} else {
+		m.refreshDelay = min(m.refreshDelay*2, max(m.maxRefresh, m.refreshInterval))
+	}
+	m.lastRefresh = time.Now()
+	m.refresh()
+	return m.scheduleRefresh()
+}
+
+// speedUpRefresh returns to the shortest interval when a claim lands in the
+// subnet the current table lists, if refreshing has backed off
+func (m *Model) speedUpRefresh(event api.ClaimEvent) tea.Cmd {
+	if !m.refreshing() || m.refreshDelay <= m.refreshInterval || !m.inCurrentTable(event.IP) {
+		return nil
+	}
+	m.refreshDelay = m.refreshInterval
+	return m.scheduleRefresh()
+}
+
+// refreshNow refreshes the visible claims at once, restarting the periodic
+// refresh from the shortest interval
+func (m *Model) refreshNow() tea.Cmd {
+	m.refresh()
+	m.statusMessage = statusMessageStyle.Render("Refreshed")
+	m.refreshDelay = m.refreshInterval
+	if !m.refreshing() {
+		return nil
+	}
+	m.lastRefresh = time.Now()
+	return m.scheduleRefresh()
+}
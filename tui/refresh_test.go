@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleRefreshTick tests that refreshing backs off while nothing
+// changes, up to the longest interval, and speeds up when something does
+func TestHandleRefreshTick(t *testing.T) {
+	m := &Model{
+		autoRefresh:     true,
+		refreshInterval: 5 * time.Second,
+		maxRefresh:      15 * time.Second,
+		refreshDelay:    5 * time.Second,
+	}
+	var delays []time.Duration
+	for range 3 {
+		require.NotNil(t, m.handleRefreshTick(refreshTickMsg{gen: m.refreshGen}))
+		delays = append(delays, m.refreshDelay)
+	}
+	assert.Equal(t, []time.Duration{10 * time.Second, 15 * time.Second, 15 * time.Second}, delays,
+		"Refreshing should back off while nothing changes")
+	assert.True(t, m.refreshClaims)
+
+	m.lastChange = time.Now().Add(time.Second)
+	m.handleRefreshTick(refreshTickMsg{gen: m.refreshGen})
+	assert.Equal(t, 5*time.Second, m.refreshDelay, "Changes should return to the shortest interval")
+
+	gen := m.refreshGen
+	m.scheduleRefresh()
+	assert.Nil(t, m.handleRefreshTick(refreshTickMsg{gen: gen}), "Superseded ticks should be ignored")
+
+	m.autoRefresh = false
+	assert.Nil(t, m.handleRefreshTick(refreshTickMsg{gen: m.refreshGen}), "Ticks should stop once refreshing stops")
+}
+
+// TestHandleRefreshTick_MaxBelowInterval tests that a longest interval
+// below the shortest one leaves refreshing at the shortest
+func TestHandleRefreshTick_MaxBelowInterval(t *testing.T) {
+	m := &Model{spectate: true, refreshInterval: 5 * time.Second, refreshDelay: 5 * time.Second}
+	m.handleRefreshTick(refreshTickMsg{gen: m.refreshGen})
+	assert.Equal(t, 5*time.Second, m.refreshDelay)
+}
+
+// TestRefreshNow tests that refreshing by hand restarts from the shortest interval
+func TestRefreshNow(t *testing.T) {
+	m := &Model{autoRefresh: true, refreshInterval: 5 * time.Second, refreshDelay: time.Minute}
+	assert.NotNil(t, m.refreshNow())
+	assert.Equal(t, 5*time.Second, m.refreshDelay)
+	assert.True(t, m.refreshClaims)
+	assert.Contains(t, m.statusMessage, "Refreshed")
+
+	m.autoRefresh = false
+	assert.Nil(t, m.refreshNow(), "Refreshing by hand should not schedule refreshes when they are off")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// materializeRows fills in the subnets and names of the rows of a level's
+// table from start to end that are still blank, reporting whether any were.
+// Unfiltered tables are populated blank, since naming all 2^16 subnets of a
+// level takes a noticeable time.
+func (m *Model) materializeRows(lvl level, start, end int) bool {
+	rows, shadowRows := m.unitTables[lvl].Rows(), m.shadowTables[lvl].Rows()
+	filled := false
+	for i := max(start, 0); i < min(end, len(shadowRows)); i++ {
+		if shadowRows[i][0] != "" {
+			continue
+		}
+		addr, subnet := makeIPv6Full(i, m.rowPrefixes[lvl], lvl)
+		cidr := api.CanonicalSubnet(net.ParseIP(addr), subnet)
+		shadowRows[i][0] = cidr
+		rows[i][0] = m.generatedName(cidr)
+		filled = true
+	}
+	return filled
+}
+
+// materializeVisible fills in the rows of the current table around its
+// cursor, redrawing the table if any were blank, and has the names of the
+// rows further on generated in the background
+func (m *Model) materializeVisible() {
+	table := &m.unitTables[m.viewing]
+	if m.materializeRows(m.viewing, table.Cursor()-table.Height(), table.Cursor()+table.Height()) {
+		table.UpdateViewport()
+	}
+	if !m.views[m.viewing].active() {
+		m.names.precompute(nameRange{locale: m.locale, prefix: m.rowPrefixes[m.viewing], level: m.viewing, cursor: table.Cursor()})
+	}
+}
+
+// materializeCursor fills in the row under the cursor of a level's table
+func (m *Model) materializeCursor(lvl level) {
+	cursor := m.unitTables[lvl].Cursor()
+	m.materializeRows(lvl, cursor, cursor+1)
+}
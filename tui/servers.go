@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// serverSwitcher is the screen listing the server profiles to switch to
+type serverSwitcher struct {
+	cursor int // Index of the selected profile
+}
+
+// openSwitcher shows the server profiles with the cursor on the current one
+func (m *Model) openSwitcher() {
+	m.switcher = &serverSwitcher{}
+	for i, p := range m.profiles {
+		if m.isCurrent(p) {
+			m.switcher.cursor = i
+		}
+	}
+}
+
+// isCurrent reports whether the browser is on a profile's server as its player
+func (m *Model) isCurrent(p Profile) bool {
+	return p.Server == m.serverAddr && p.HTTPPort == m.httpPort && p.Player == m.name
+}
+
+// handleSwitcherKey moves through the profiles, switching to the selected
+// one on select and closing the screen on back
+func (m *Model) handleSwitcherKey(msg tea.KeyMsg) {
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		m.switcher.cursor = max(m.switcher.cursor-1, 0)
+	case key.Matches(msg, m.keys.Down):
+		m.switcher.cursor = min(m.switcher.cursor+1, len(m.profiles)-1)
+	case key.Matches(msg, m.keys.Back):
+		m.switcher = nil
+	case key.Matches(msg, m.keys.Select):
+		p := m.profiles[m.switcher.cursor]
+		m.switcher = nil
+		if err := m.SwitchServer(p); err != nil {
+			m.errorMessage = errorMessageStyle.Render(fmt.Sprintf("Failed to switch to %s: %v", p.Name, err))
+			return
+		}
+		m.statusMessage = statusMessageStyle.Render(fmt.Sprintf("Playing on %s as %s", p.Name, p.Player))
+	}
+}
+
+// switcherView renders the server profiles, marking the current one
+func (m *Model) switcherView() string {
+	var b strings.Builder
+	b.WriteString("Servers\n\n")
+	for i, p := range m.profiles {
+		marker := "  "
+		if m.isCurrent(p) {
+			marker = "* "
+		}
+		line := fmt.Sprintf("%s%-16s [%s]:%d as %s", marker, p.Name, p.Server, p.HTTPPort, p.Player)
+		if i == m.switcher.cursor {
+			line = tableStyles.Selected.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n" + helpStyle(fmt.Sprintf("%s: switch, %s: cancel", keyName(m.keys.Select), keyName(m.keys.Back))))
+	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+}
+
+// serverCommand switches to the profile with a name
+func (m *Model) serverCommand(args []string) (tea.Cmd, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: server <profile>")
+	}
+	for _, p := range m.profiles {
+		if p.Name == args[0] {
+			if err := m.SwitchServer(p); err != nil {
+				return nil, err
+			}
+			m.statusMessage = statusMessageStyle.Render(fmt.Sprintf("Playing on %s as %s", p.Name, p.Player))
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown profile %q", args[0])
+}
+
+// SwitchServer moves the browser to a profile's server, saving the session
+// on the current server and resuming the one on the new server. The browser
+// stays on the current server if the new one cannot be reached.
+func (m *Model) SwitchServer(p Profile) error {
+	if m.statePath != "" {
+		if err := m.SaveSession(m.statePath); err != nil {
+			log.Printf("Error saving session: %v", err)
+		}
+	}
+
+	addr, port, name, client := m.serverAddr, m.httpPort, m.name, m.client
+	m.serverAddr, m.httpPort, m.name = p.Server, p.HTTPPort, p.Player
+	m.client = newConditionalClient()
+	levels, locale, err := m.fetchGameConfig(m.baseLocale)
+	if err != nil {
+		m.serverAddr, m.httpPort, m.name, m.client = addr, port, name, client
+		return err
+	}
+
+	m.locale = locale
+	m.setLevels(levels)
+	m.selections = [8]string{}
+	m.contestedAddr, m.alertMessage = "", ""
+	m.clearTicker()
+	m.claimInfoFetch.stop() // Requests about an address on the previous server
+	m.historyFetch.stop()
+	m.PopulateTable("", t16)
+	m.viewing = t16
+	m.refreshClaims = true
+	if m.statePath != "" {
+		if err := m.RestoreSession(m.statePath); err != nil {
+			log.Printf("Error restoring session: %v", err)
+		}
+	}
+	m.startEvents()
+	return nil
+}
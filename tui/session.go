@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+)
+
+// session is where the browser was left on a server, restored on the next run
+type session struct {
+	Subnet  string    `json:"subnet"` // Subnet under the cursor of the viewed table, in CIDR notation
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// DefaultStatePath returns ~/.local/state/spacenet/state.json, or its
+// equivalent under $XDG_STATE_HOME
+func DefaultStatePath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "spacenet", "state.json")
+}
+
+// loadSessions reads the sessions of every server by host:port, empty if
+// none were saved
+func loadSessions(path string) (map[string]session, error) {
+	sessions := make(map[string]session)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	return sessions, nil
+}
+
+// RestoreSession returns the browser to where it was left on the server,
+// doing nothing if it was never left there
+func (m *Model) RestoreSession(path string) error {
+	sessions, err := loadSessions(path)
+	if err != nil {
+		return err
+	}
+	saved, ok := sessions[m.serverHost()]
+	if !ok {
+		return nil
+	}
+	return m.JumpToSubnet(saved.Subnet)
+}
+
+// SaveSession records where the browser is on the server, keeping the
+// sessions of other servers
+func (m *Model) SaveSession(path string) error {
+	selection, ok := m.selectedSubnet()
+	if !ok {
+		return nil // No subnet matches the table's view
+	}
+
+	sessions, err := loadSessions(path)
+	if err != nil {
+		return err
+	}
+	prefixLen, _ := selection.Mask.Size()
+	sessions[m.serverHost()] = session{
+		Subnet:  api.CanonicalSubnet(selection.IP, prefixLen),
+		SavedAt: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial state
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+	return nil
+}
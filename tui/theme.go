@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bjia56/spacenet/server/colors"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme modes
+const (
+	themeColor   = "color"    // Colors from the theme's palette
+	themeNoColor = "no-color" // Bold and reversed text only
+	themeASCII   = "ascii"    // No color and ASCII only, for limited terminals
+)
+
+// ThemeConfig chooses how the TUI is drawn. Colors are hex codes such as
+// "#04B575" or ANSI color numbers such as "212", used in color mode only.
+type ThemeConfig struct {
+	Mode     string `toml:"mode"`
+	Status   string `toml:"status"`   // Status messages
+	Alert    string `toml:"alert"`    // Takeover alerts
+	Error    string `toml:"error"`    // Error messages
+	Border   string `toml:"border"`   // Table border
+	Help     string `toml:"help"`     // Help, breadcrumbs and claim details
+	Selected string `toml:"selected"` // Selected row
+	Owners   string `toml:"owners"`   // Palette owners are colored from, standard or color-blind
+}
+
+// DefaultThemeConfig returns the standard colors
+func DefaultThemeConfig() ThemeConfig {
+	return ThemeConfig{
+		Mode:     themeColor,
+		Status:   "#04B575",
+		Alert:    "#FFA500",
+		Error:    "#FF0000",
+		Border:   "240",
+		Help:     "241",
+		Selected: "212",
+		Owners:   colors.NameStandard,
+	}
+}
+
+// colorPattern matches hex colors
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate checks that the theme's mode and colors are known
+func (t ThemeConfig) Validate() error {
+	switch t.Mode {
+	case themeColor, themeNoColor, themeASCII:
+	default:
+		return fmt.Errorf("unknown theme mode %q, choose one of %s, %s or %s", t.Mode, themeColor, themeNoColor, themeASCII)
+	}
+	if _, err := colors.Lookup(t.Owners); err != nil {
+		return fmt.Errorf("invalid owners: %w", err)
+	}
+	for name, color := range map[string]string{
+		"status": t.Status, "alert": t.Alert, "error": t.Error,
+		"border": t.Border, "help": t.Help, "selected": t.Selected,
+	} {
+		if colorPattern.MatchString(color) {
+			continue
+		}
+		if n, err := strconv.Atoi(color); err != nil || n < 0 || n > 255 {
+			return fmt.Errorf("invalid %s color %q, use a hex code or an ANSI color number", name, color)
+		}
+	}
+	return nil
+}
+
+// applyTheme replaces the styles and markers the TUI is drawn with
+func applyTheme(t ThemeConfig) {
+	if t.Mode == themeColor {
+		statusMessageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Status))
+		alertMessageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Alert)).Bold(true)
+		errorMessageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Error))
+		tableStyle = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(t.Border))
+		helpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Help)).Render
+		tableStyles = table.DefaultStyles()
+		tableStyles.Selected = tableStyles.Selected.Foreground(lipgloss.Color(t.Selected))
+		ownerColors, _ = colors.Lookup(t.Owners)
+		return
+	}
+
+	// Without color, alerts and errors stand out by weight instead
+	statusMessageStyle = lipgloss.NewStyle()
+	alertMessageStyle = lipgloss.NewStyle().Bold(true)
+	errorMessageStyle = lipgloss.NewStyle().Bold(true)
+	tableStyle = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder())
+	helpStyle = lipgloss.NewStyle().Render
+	tableStyles = table.DefaultStyles()
+	tableStyles.Selected = lipgloss.NewStyle().Reverse(true)
+	ownerColors = nil
+	fadeColors = nil
+	if t.Mode == themeNoColor {
+		return
+	}
+
+	tableStyle = tableStyle.BorderStyle(lipgloss.ASCIIBorder())
+	changedOwnerMarker = "> "
+	artifactMarker = "* "
+	crumbSeparator = " > "
+	tickerSeparator = " | "
+	barBlock, barPartialBlocks = "#", nil
+	ellipsis = "~"
+	inputCursor = "_"
+	connMarker = "*"
+}
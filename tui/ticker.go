@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Limits of the activity ticker
+const (
+	tickerLength   = 20                     // Most recent takeovers scrolled through
+	tickerInterval = 150 * time.Millisecond // Time between scrolling one character
+)
+
+// tickerSeparator separates takeovers in the ticker, replaced in the ascii theme
+var tickerSeparator = " · "
+
+// activityTicker scrolls the latest takeovers on the server across the footer
+type activityTicker struct {
+	takeovers []api.ClaimEvent // Latest takeovers, oldest first
+	offset    int              // Characters scrolled past
+	paused    bool             // Whether scrolling is paused
+	nearby    bool             // Whether only takeovers under the current subnet are shown
+	scrolling bool             // Whether a scroll tick is pending
+}
+
+// tickerTickMsg scrolls the activity ticker by a character
+type tickerTickMsg struct{}
+
+// tickerTick schedules the next scroll of the activity ticker
+func tickerTick() tea.Cmd {
+	return tea.Tick(tickerInterval, func(time.Time) tea.Msg {
+		return tickerTickMsg{}
+	})
+}
+
+// recordTakeover adds a takeover to the ticker, starting it scrolling
+func (m *Model) recordTakeover(event api.ClaimEvent) tea.Cmd {
+	if event.Type != api.EventTypeClaim || event.PreviousClaimant == "" {
+		return nil
+	}
+	t := &m.ticker
+	t.takeovers = append(t.takeovers, event)
+	if len(t.takeovers) > tickerLength {
+		t.takeovers = t.takeovers[len(t.takeovers)-tickerLength:]
+	}
+	return m.scrollTicker()
+}
+
+// scrollTicker schedules a scroll unless one is pending or the ticker is paused
+func (m *Model) scrollTicker() tea.Cmd {
+	t := &m.ticker
+	if t.scrolling || t.paused || len(t.takeovers) == 0 {
+		return nil
+	}
+	t.scrolling = true
+	return tickerTick()
+}
+
+// handleTickerTick scrolls the ticker by a character
+func (m *Model) handleTickerTick() tea.Cmd {
+	m.ticker.scrolling = false
+	if m.ticker.paused {
+		return nil
+	}
+	m.ticker.offset++
+	return m.scrollTicker()
+}
+
+// togglePauseTicker stops or resumes scrolling the ticker
+func (m *Model) togglePauseTicker() tea.Cmd {
+	m.ticker.paused = !m.ticker.paused
+	return m.scrollTicker()
+}
+
+// toggleNearbyTicker switches the ticker between every takeover and those
+// under the subnet the current table lists
+func (m *Model) toggleNearbyTicker() {
+	m.ticker.nearby = !m.ticker.nearby
+	m.ticker.offset = 0
+}
+
+// clearTicker forgets the takeovers of the previous server
+func (m *Model) clearTicker() {
+	m.ticker.takeovers = nil
+	m.ticker.offset = 0
+}
+
+// tickerText joins the takeovers shown in the ticker, oldest first so new
+// takeovers join the end without moving the text scrolled to
+func (m *Model) tickerText() string {
+	var items []string
+	for _, event := range m.ticker.takeovers {
+		if m.ticker.nearby && !m.inCurrentTable(event.IP) {
+			continue
+		}
+		items = append(items, fmt.Sprintf("%s took %s from %s", event.Claimant, event.IP, event.PreviousClaimant))
+	}
+	return strings.Join(items, tickerSeparator)
+}
+
+// tickerView renders the window of the ticker scrolled to, as wide as the
+// table
+func (m *Model) tickerView(width int) string {
+	label := "Takeovers: "
+	if m.ticker.nearby {
+		label = "Takeovers here: "
+	}
+	text := m.tickerText()
+	if text == "" {
+		return helpStyle(label + "none yet")
+	}
+	status := ""
+	if m.ticker.paused {
+		status = " (paused)"
+	}
+
+	width -= len(label) + len(status)
+	runes := []rune(text + tickerSeparator)
+	if len(runes)-len([]rune(tickerSeparator)) <= width {
+		return helpStyle(label) + text + helpStyle(status) // Fits without scrolling
+	}
+	start := m.ticker.offset % len(runes)
+	window := make([]rune, 0, width)
+	for i := range max(width, 0) {
+		window = append(window, runes[(start+i)%len(runes)])
+	}
+	return helpStyle(label) + string(window) + helpStyle(status)
+}
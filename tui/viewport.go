@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/bjia56/spacenet/server/api"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/net/websocket"
+)
+
+// viewportMsg delivers the claims the server pushed for the watched rows, or
+// news that the server started or stopped pushing them
+type viewportMsg struct {
+	update *api.ViewportUpdate // Nil when the connection opened or dropped
+	live   bool                // Whether the server is pushing the watched rows
+}
+
+// viewportFeed carries subscriptions to the connection to the server's
+// viewport subscriptions, and what the server pushes back
+type viewportFeed struct {
+	subs chan api.ViewportSubscription // Latest subscription not yet sent
+	msgs chan viewportMsg
+}
+
+// newViewportFeed creates a feed with nothing watched
+func newViewportFeed() *viewportFeed {
+	return &viewportFeed{
+		subs: make(chan api.ViewportSubscription, 1),
+		msgs: make(chan viewportMsg),
+	}
+}
+
+// watch subscribes to rows, replacing any subscription not yet sent
+func (f *viewportFeed) watch(sub api.ViewportSubscription) {
+	select {
+	case <-f.subs:
+	default:
+	}
+	f.subs <- sub
+}
+
+// waitForViewport waits for the next message from the viewport feed
+func waitForViewport(feed *viewportFeed) tea.Cmd {
+	return func() tea.Msg {
+		return <-feed.msgs
+	}
+}
+
+// followViewports keeps a connection to a server's viewport subscriptions,
+// reconnecting with exponential backoff whenever it drops and subscribing
+// again to the rows last watched, until the context is done
+func followViewports(ctx context.Context, host string, feed *viewportFeed) {
+	var current api.ViewportSubscription
+	delay := minEventReconnectDelay
+	for {
+		connected, err := watchViewports(ctx, host, feed, &current)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minEventReconnectDelay
+			select {
+			case feed.msgs <- viewportMsg{live: false}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		log.Printf("Viewport subscription disconnected: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxEventReconnectDelay)
+	}
+}
+
+// watchViewports sends subscriptions and forwards updates until the
+// connection ends, reporting whether it was established
+func watchViewports(ctx context.Context, host string, feed *viewportFeed, current *api.ViewportSubscription) (bool, error) {
+	config, err := websocket.NewConfig(fmt.Sprintf("ws://%s/api/v1/subscribe", host), fmt.Sprintf("http://%s/", host))
+	if err != nil {
+		return false, err
+	}
+	config.Dialer = &net.Dialer{Timeout: dialTimeout}
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = ws.Close() }()
+
+	select {
+	case feed.msgs <- viewportMsg{live: true}:
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+	if current.End > current.Start {
+		if err := websocket.JSON.Send(ws, *current); err != nil {
+			return true, err
+		}
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			var update api.ViewportUpdate
+			if err := websocket.JSON.Receive(ws, &update); err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case feed.msgs <- viewportMsg{update: &update, live: true}:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case err := <-errs:
+			return true, err
+		case sub := <-feed.subs:
+			*current = sub
+			if err := websocket.JSON.Send(ws, sub); err != nil {
+				return true, err
+			}
+		}
+	}
+}
+
+// watchRows has the server push the claims on the rows around the cursor,
+// watching further around it so scrolling a little needs no new subscription
+func (m *Model) watchRows(lvl level, want rowSpan) {
+	if want.end <= want.start {
+		return
+	}
+	first, err := api.ParseSubnet(m.shadowTables[lvl].Rows()[want.start][0])
+	if err != nil {
+		return
+	}
+	prefix := subnetMappings[lvl]
+	parent := api.CanonicalSubnet(first.IP, prefix-16)
+	if w := m.watching; w.Parent == parent && w.Prefix == prefix && w.Start <= want.start && want.end <= w.End {
+		return // Already watched
+	}
+
+	margin := (want.end - want.start) / 2
+	start, end := max(want.start-margin, 0), min(want.end+margin, len(m.shadowTables[lvl].Rows()))
+	m.materializeRows(lvl, start, end)
+	m.fetchID++
+	m.watching = api.ViewportSubscription{Seq: m.fetchID, Parent: parent, Prefix: prefix, Start: start, End: end}
+	m.watchLevel = lvl
+	m.viewports.watch(m.watching)
+}
+
+// unwatchRows stops the server pushing claims, while the rows are fetched
+// instead
+func (m *Model) unwatchRows() {
+	if m.watching.End == m.watching.Start {
+		return
+	}
+	m.fetchID++
+	m.watching = api.ViewportSubscription{Seq: m.fetchID}
+	m.viewports.watch(m.watching)
+}
+
+// handleViewport fills in the claims the server pushed, or switches between
+// watching and fetching the rows as the server starts and stops pushing them
+func (m *Model) handleViewport(msg viewportMsg) tea.Cmd {
+	wait := waitForViewport(m.viewports)
+	update := msg.update
+	if update == nil {
+		m.viewportLive = msg.live
+		m.refreshClaims = true // Fetch the rows meanwhile, or watch them again
+		return wait
+	}
+	if update.Seq != m.watching.Seq {
+		return wait // Superseded
+	}
+	if update.Error != "" {
+		log.Printf("Viewport subscription refused: %s", update.Error)
+		m.viewportLive = false // Fetch the rows until reconnected
+		m.refreshClaims = true
+		return wait
+	}
+
+	rows, shadowRows := m.unitTables[m.watchLevel].Rows(), m.shadowTables[m.watchLevel].Rows()
+	for _, row := range update.Rows {
+		if row.Row < len(shadowRows) && shadowRows[row.Row][0] == row.Subnet {
+			m.setRowClaim(rows[row.Row], row.Subnet, row.Claim)
+		}
+	}
+	m.unitTables[m.watchLevel].SetRows(rows)
+	return wait
+}
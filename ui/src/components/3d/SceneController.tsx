@@ -8,13 +8,15 @@ import { Supercluster3D } from './Supercluster3D';
 import { GalaxyGroup3D } from './GalaxyGroup3D';
 import { Galaxy3D } from './Galaxy3D';
 import { StarCluster3D } from './StarCluster3D';
-import { SolarSystem3D } from './SolarSystem3D';
+import { SolarSystem3D, ClaimedChild } from './SolarSystem3D';
 import { Planet3D } from './Planet3D';
 import { City3D } from './City3D';
 
 interface SceneControllerProps {
   level: SubnetLevel;
   selectedIP: string;
+  claimedChildren?: ClaimedChild[]; // Claimed children of the selected subnet, if fetched
+  onSelectChild?: (index: number) => void;
 }
 
 export interface SceneControllerRef {
@@ -22,7 +24,7 @@ export interface SceneControllerRef {
 }
 
 export const SceneController = forwardRef<SceneControllerRef, SceneControllerProps>(
-  ({ level, selectedIP }, ref) => {
+  ({ level, selectedIP, claimedChildren, onSelectChild }, ref) => {
     // Create a seed from the IP address for deterministic randomization
     const ipSeed = useMemo(() => {
       if (!selectedIP) return 0;
@@ -58,7 +60,7 @@ export const SceneController = forwardRef<SceneControllerRef, SceneControllerPro
         case 4: // Star Cluster (/80)
           return <StarCluster3D {...commonProps} />;
         case 5: // Solar System (/96)
-          return <SolarSystem3D {...commonProps} />;
+          return <SolarSystem3D {...commonProps} claimedChildren={claimedChildren} onSelectChild={onSelectChild} />;
         case 6: // Planet (/112)
           return <Planet3D {...commonProps} />;
         case 7: // City (/128)
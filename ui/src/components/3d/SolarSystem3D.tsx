@@ -5,6 +5,22 @@ import { useFrame } from '@react-three/fiber';
 import { Sphere, Ring, Points, PointMaterial } from '@react-three/drei';
 import * as THREE from 'three';
 import { SeededRandom } from '@/lib/seededRandom';
+import { claimantColor } from '@/lib/claimantColors';
+
+// Most planets drawn, the children with the most claims
+const MAX_PLANETS = 24;
+
+// Addresses in a /112, the most claims a planet can have
+const ADDRESSES_PER_PLANET = 65536;
+
+/**
+ * Claimed /112 child of the solar system's /96
+ */
+export interface ClaimedChild {
+  index: number; // Position among the /96's children
+  owner: string;
+  claims: number;
+}
 
 interface Planet {
   size: number;
@@ -15,13 +31,16 @@ interface Planet {
   hasRings: boolean;
   type: 'rocky' | 'gas' | 'ice';
   color: THREE.Color;
+  child?: number; // Index of the claimed child the planet stands for
 }
 
 interface SolarSystem3DProps {
   ipSeed: number;
+  claimedChildren?: ClaimedChild[]; // Planets are generated from the seed until given
+  onSelectChild?: (index: number) => void;
 }
 
-export function SolarSystem3D({ ipSeed }: SolarSystem3DProps) {
+export function SolarSystem3D({ ipSeed, claimedChildren, onSelectChild }: SolarSystem3DProps) {
   const groupRef = useRef<THREE.Group>(null);
   const planetRefs = useRef<THREE.Group[]>([]);
   
@@ -29,7 +48,14 @@ export function SolarSystem3D({ ipSeed }: SolarSystem3DProps) {
   const systemParams = useMemo(() => {
     const rng = new SeededRandom(ipSeed);
     
-    const numPlanets = 4 + Math.floor(rng.random() * 8); // 4-12 planets
+    // With claims known, every claimed child is a planet, in address order
+    const children = claimedChildren &&
+      [...claimedChildren]
+        .sort((a, b) => b.claims - a.claims)
+        .slice(0, MAX_PLANETS)
+        .sort((a, b) => a.index - b.index);
+    
+    const numPlanets = children ? children.length : 4 + Math.floor(rng.random() * 8); // 4-12 planets
     const planets: Planet[] = [];
     
     // Generate planets
@@ -53,6 +79,13 @@ export function SolarSystem3D({ ipSeed }: SolarSystem3DProps) {
         color = new THREE.Color().setHSL(0.55 + rng.random() * 0.1, 0.7, 0.7); // Light blue
       }
       
+      if (children) {
+        // Size by claims on a log scale, up to 2.5 with every address claimed
+        const child = children[i];
+        baseSize = 0.4 + 2.1 * Math.log(1 + child.claims) / Math.log(1 + ADDRESSES_PER_PLANET);
+        color = new THREE.Color(claimantColor(child.owner) ?? '#888888');
+      }
+      
       const orbitDistance = 3 + i * 2.5 + rng.random() * 1.5;
       const orbitSpeed = 0.5 / Math.pow(orbitDistance / 3, 1.5); // Kepler's laws approximation
       
@@ -71,7 +104,8 @@ export function SolarSystem3D({ ipSeed }: SolarSystem3DProps) {
         moons,
         hasRings,
         type,
-        color
+        color,
+        child: children?.[i].index
       });
     }
     
@@ -85,7 +119,7 @@ export function SolarSystem3D({ ipSeed }: SolarSystem3DProps) {
         particles: 200
       }
     };
-  }, [ipSeed]);
+  }, [ipSeed, claimedChildren]);
 
   // Generate asteroid belt
   const asteroidGeometry = useMemo(() => {
@@ -168,6 +202,17 @@ export function SolarSystem3D({ ipSeed }: SolarSystem3DProps) {
           ref={(ref) => {
             if (ref) planetRefs.current[index] = ref;
           }}
+          onClick={(e) => {
+            if (planet.child === undefined || !onSelectChild) return;
+            e.stopPropagation();
+            onSelectChild(planet.child);
+          }}
+          onPointerOver={() => {
+            if (planet.child !== undefined && onSelectChild) document.body.style.cursor = 'pointer';
+          }}
+          onPointerOut={() => {
+            document.body.style.cursor = 'auto';
+          }}
         >
           {/* Planet sphere */}
           <Sphere args={[planet.size, 16, 16]}>
@@ -1,11 +1,21 @@
 'use client';
 
-import { useState, useRef, useCallback } from 'react';
+import { useState, useRef, useCallback, useEffect } from 'react';
 import { Canvas } from '@react-three/fiber';
 import { OrbitControls, Stats } from '@react-three/drei';
-import { SubnetLevel, levelNames, generateName, makeIPv6Full } from '@/lib/ipv6names';
+import { SubnetLevel, levelNames, generateName, makeIPv6Full, expandIPv6 } from '@/lib/ipv6names';
 import { SubnetTable } from './SubnetTable';
 import { SceneController } from './3d/SceneController';
+import { ClaimedChild } from './3d/SolarSystem3D';
+
+// Level listing the solar systems (/96) whose claimed /112 children are planets
+const SOLAR_SYSTEM_LEVEL = 5;
+
+interface SubnetListEntry {
+  subnet: string;
+  owner?: string;
+  claims?: number;
+}
 
 export interface SubnetRow {
   name: string;
@@ -32,15 +42,16 @@ export function SpaceNetBrowser({
   const [subnets, setSubnets] = useState<SubnetRow[]>([]);
   const [statusMessage, setStatusMessage] = useState('');
   const [errorMessage, setErrorMessage] = useState('');
+  const [claimedChildren, setClaimedChildren] = useState<ClaimedChild[]>();
 
   const sceneRef = useRef<{ animateForIP: (ip: string) => void }>(null);
 
   // Generate subnets for current level
-  const generateSubnets = useCallback((prefix: string, level: SubnetLevel) => {
+  const generateSubnets = useCallback((prefix: string, level: SubnetLevel, minRows = 0) => {
     const newSubnets: SubnetRow[] = [];
 
-    // Generate first 100 subnets for performance (in real app, use virtualization)
-    for (let i = 0; i < Math.min(1000, 1 << 16); i++) {
+    // Generate first 1000 subnets for performance, or up to a row being jumped to (in real app, use virtualization)
+    for (let i = 0; i < Math.min(Math.max(1000, minRows), 1 << 16); i++) {
       const { addr, subnet } = makeIPv6Full(i, prefix, level);
       const name = generateName(addr, subnet);
 
@@ -56,6 +67,19 @@ export function SpaceNetBrowser({
     setSubnets(newSubnets);
   }, []);
 
+  // Navigate into a subnet of the current level, listing at least minRows of its children
+  const enterSubnet = useCallback((subnet: SubnetRow, minRows = 0) => {
+    const addr = subnet.addr.split('/')[0];
+    const newPrefix = addr.substring(0, 5 * (currentLevel + 1));
+    const newSelections = [...selections];
+    newSelections[currentLevel] = newPrefix;
+    setSelections(newSelections);
+
+    const newLevel = (currentLevel + 1) as SubnetLevel;
+    setCurrentLevel(newLevel);
+    generateSubnets(newPrefix, newLevel, minRows);
+  }, [currentLevel, selections, generateSubnets]);
+
   // Handle subnet selection
   const handleSubnetSelect = useCallback((index: number) => {
     setSelectedIndex(index);
@@ -63,15 +87,7 @@ export function SpaceNetBrowser({
 
     if (currentLevel < 7) {
       // Navigate deeper
-      const addr = subnet.addr.split('/')[0];
-      const newPrefix = addr.substring(0, 5 * (currentLevel + 1));
-      const newSelections = [...selections];
-      newSelections[currentLevel] = newPrefix;
-      setSelections(newSelections);
-
-      const newLevel = (currentLevel + 1) as SubnetLevel;
-      setCurrentLevel(newLevel);
-      generateSubnets(newPrefix, newLevel);
+      enterSubnet(subnet);
     } else {
       // At deepest level - send claim
       const ip = subnet.addr.split('/')[0];
@@ -83,7 +99,53 @@ export function SpaceNetBrowser({
       const ip = subnet.addr.split('/')[0];
       sceneRef.current.animateForIP(ip);
     }
-  }, [currentLevel, selections, subnets, generateSubnets]);
+  }, [currentLevel, subnets, enterSubnet]);
+
+  // Jump from a planet of the solar system to its /112
+  const handleSelectChild = useCallback((childIndex: number) => {
+    const subnet = subnets[selectedIndex];
+    if (!subnet) return;
+    enterSubnet(subnet, childIndex + 1);
+    setSelectedIndex(childIndex);
+  }, [subnets, selectedIndex, enterSubnet]);
+
+  // Fetch the claimed /112 children of the selected /96 for its solar system
+  const selectedAddr = subnets[selectedIndex]?.addr;
+  useEffect(() => {
+    setClaimedChildren(undefined);
+    if (currentLevel !== SOLAR_SYSTEM_LEVEL || !selectedAddr) return;
+
+    let cancelled = false;
+    const fetchChildren = async () => {
+      try {
+        const within = encodeURIComponent(selectedAddr);
+        const response = await fetch(`http://[${serverAddr}]:${httpPort}/api/v1/subnets/112?within=${within}`);
+        if (!response.ok) {
+          throw new Error(`Server returned status: ${response.status}`);
+        }
+        const entries: SubnetListEntry[] = await response.json();
+
+        const children: ClaimedChild[] = [];
+        for (const entry of entries) {
+          // The seventh hextet numbers the /112 within its /96
+          const addr = expandIPv6(entry.subnet.split('/')[0]);
+          children.push({
+            index: parseInt(addr.substring(30, 34), 16),
+            owner: entry.owner ?? '',
+            claims: entry.claims ?? 0
+          });
+        }
+        if (!cancelled) setClaimedChildren(children);
+      } catch (error) {
+        if (!cancelled) setErrorMessage('Failed to fetch claims: ' + (error as Error).message);
+      }
+    };
+    fetchChildren();
+
+    return () => {
+      cancelled = true;
+    };
+  }, [currentLevel, selectedAddr, serverAddr, httpPort]);
 
   // Handle going back to parent level
   const handleBack = useCallback(() => {
@@ -101,7 +163,7 @@ export function SpaceNetBrowser({
     try {
       // TODO: Implement proof-of-work solving in browser
       // For now, use a placeholder nonce - this will fail validation
-      const response = await fetch(`http://[${serverAddr}]:${httpPort}/api/claim/${ip}`, {
+      const response = await fetch(`http://[${serverAddr}]:${httpPort}/api/v1/claim/${ip}`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
@@ -163,6 +225,8 @@ export function SpaceNetBrowser({
               ref={sceneRef}
               level={currentLevel}
               selectedIP={subnets[selectedIndex]?.addr.split('/')[0] || '::'}
+              claimedChildren={claimedChildren}
+              onSelectChild={handleSelectChild}
             />
             <OrbitControls
               enablePan={true}
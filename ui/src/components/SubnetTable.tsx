@@ -2,6 +2,7 @@
 
 import { useEffect, useRef } from 'react';
 import { SubnetRow } from './SpaceNetBrowser';
+import { claimantColor } from '@/lib/claimantColors';
 
 interface SubnetTableProps {
   subnets: SubnetRow[];
@@ -97,7 +98,10 @@ export function SubnetTable({
             <div className="col-span-6 truncate font-mono text-xs">
               {subnet.name}
             </div>
-            <div className="col-span-3 truncate">
+            <div
+              className="col-span-3 truncate"
+              style={index === selectedIndex ? undefined : { color: claimantColor(subnet.owner) }}
+            >
               {subnet.owner || '-'}
             </div>
             <div className="col-span-3 truncate">
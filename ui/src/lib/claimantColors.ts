@@ -0,0 +1,38 @@
+/**
+ * Claimant colors, matching the palettes and hash of the server's colors
+ * package so a player has the same color in the dashboard and the TUI
+ */
+
+export const standardPalette = [
+  '#00afff', '#ff8700', '#d75fd7', '#5fd700', '#ffd700', '#0087ff',
+  '#ff5f5f', '#af87ff', '#00d7af', '#ffaf00', '#875fff', '#87ff00',
+];
+
+/**
+ * Okabe-Ito palette without black, told apart with the common forms of color blindness
+ */
+export const colorBlindPalette = [
+  '#E69F00', '#56B4E9', '#009E73', '#F0E442', '#0072B2', '#D55E00', '#CC79A7',
+];
+
+/**
+ * 32-bit FNV-1a hash of the UTF-8 bytes of a string
+ */
+function fnv1a32(text: string): number {
+  let hash = 0x811c9dc5;
+  for (const byte of new TextEncoder().encode(text)) {
+    hash ^= byte;
+    hash = Math.imul(hash, 0x01000193);
+  }
+  return hash >>> 0;
+}
+
+/**
+ * Color of a claimant, undefined for nobody
+ */
+export function claimantColor(claimant: string, palette: string[] = standardPalette): string | undefined {
+  if (!claimant || palette.length === 0) {
+    return undefined;
+  }
+  return palette[fnv1a32(claimant) % palette.length];
+}
@@ -1,5 +1,5 @@
 import { createHash } from 'crypto';
-import ipv6NamesData from '../../../tui/ipv6names.json';
+import ipv6NamesData from '../../../server/names/ipv6names.json';
 
 interface IPv6NamesData {
   adjectives: Record<string, string[]>;
@@ -35,11 +35,9 @@ for (const [strKey, value] of Object.entries(namesData.subnetMappings)) {
 
 export const levelNames = namesData.levelNames;
 
-// Helper function to parse IPv6 address and create truncated version
-function truncateIPv6(addr: string, bits: number): Buffer {
-  // Parse IPv6 address to bytes
+// Expand an IPv6 address to eight hextets of four digits, as made by makeIPv6Full
+export function expandIPv6(addr: string): string {
   const parts = addr.split(':');
-  const bytes = Buffer.alloc(16);
   
   // Handle compressed notation
   let expandedParts: string[] = [];
@@ -64,6 +62,15 @@ function truncateIPv6(addr: string, bits: number): Buffer {
     expandedParts.push('0000');
   }
   
+  return expandedParts.map(part => part.padStart(4, '0')).join(':');
+}
+
+// Helper function to parse IPv6 address and create truncated version
+function truncateIPv6(addr: string, bits: number): Buffer {
+  // Parse IPv6 address to bytes
+  const expandedParts = expandIPv6(addr).split(':');
+  const bytes = Buffer.alloc(16);
+  
   // Convert to bytes
   for (let i = 0; i < 8; i++) {
     const part = parseInt(expandedParts[i] || '0000', 16);